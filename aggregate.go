@@ -42,6 +42,29 @@ type Aggregate interface {
 	CommandHandler
 }
 
+// DeletableAggregate is an aggregate that can be deleted by a tombstone event,
+// while keeping its history. Commands for a deleted aggregate are rejected with
+// ErrAggregateDeleted by the aggregate command handler.
+type DeletableAggregate interface {
+	Aggregate
+
+	// IsDeleted returns true if the last applied event was a tombstone.
+	IsDeleted() bool
+	// SetDeleted sets if the aggregate is deleted, it is called by the
+	// aggregate store when applying events.
+	SetDeleted(bool)
+}
+
+// RestorableAggregate is a deletable aggregate that allows explicit commands
+// to be handled while deleted, for example to undelete it.
+type RestorableAggregate interface {
+	DeletableAggregate
+
+	// CanHandleDeleted returns true if the command is allowed to be handled
+	// while the aggregate is deleted.
+	CanHandleDeleted(Command) bool
+}
+
 // AggregateType is the type of an aggregate.
 type AggregateType string
 
@@ -71,6 +94,8 @@ var (
 	ErrAggregateNotFound = errors.New("aggregate not found")
 	// ErrAggregateNotRegistered is when no aggregate factory was registered.
 	ErrAggregateNotRegistered = errors.New("aggregate not registered")
+	// ErrAggregateDeleted is when a command is handled for a deleted aggregate.
+	ErrAggregateDeleted = errors.New("aggregate is deleted")
 )
 
 // AggregateStoreOperation is the operation done when an error happened.
@@ -153,7 +178,9 @@ func (e *AggregateError) Cause() error {
 }
 
 // RegisterAggregate registers an aggregate factory for a type. The factory is
-// used to create concrete aggregate types when loading from the database.
+// used to create concrete aggregate types when loading from the database. The
+// factory is called with the ID of the aggregate before any events are applied,
+// which can be used to set up invariants that depend on the ID.
 //
 // An example would be:
 //
@@ -15,6 +15,7 @@
 package events
 
 import (
+	"context"
 	"time"
 
 	eh "github.com/looplab/eventhorizon"
@@ -57,10 +58,11 @@ import (
 // See the examples folder for a complete use case.
 //
 type AggregateBase struct {
-	id     uuid.UUID
-	t      eh.AggregateType
-	v      int
-	events []eh.Event
+	id      uuid.UUID
+	t       eh.AggregateType
+	v       int
+	events  []eh.Event
+	deleted bool
 }
 
 // NewAggregateBase creates an aggregate.
@@ -91,6 +93,16 @@ func (a *AggregateBase) SetAggregateVersion(v int) {
 	a.v = v
 }
 
+// IsDeleted implements the IsDeleted method of the eh.DeletableAggregate interface.
+func (a *AggregateBase) IsDeleted() bool {
+	return a.deleted
+}
+
+// SetDeleted implements the SetDeleted method of the eh.DeletableAggregate interface.
+func (a *AggregateBase) SetDeleted(deleted bool) {
+	a.deleted = deleted
+}
+
 // UncommittedEvents implements the UncommittedEvents method of the eh.EventSource
 // interface.
 func (a *AggregateBase) UncommittedEvents() []eh.Event {
@@ -115,3 +127,12 @@ func (a *AggregateBase) AppendEvent(t eh.EventType, data eh.EventData, timestamp
 
 	return e
 }
+
+// AppendEventCtx appends an event like AppendEvent, with the timestamp from the
+// clock of the context, see eh.Now, and the registered context values as
+// metadata, see eh.WithContextMetadata.
+func (a *AggregateBase) AppendEventCtx(ctx context.Context, t eh.EventType, data eh.EventData, options ...eh.EventOption) eh.Event {
+	options = append(options, eh.WithContextMetadata(ctx))
+
+	return a.AppendEvent(t, data, eh.Now(ctx), options...)
+}
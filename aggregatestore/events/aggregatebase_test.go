@@ -58,6 +58,38 @@ func TestAggregateVersion(t *testing.T) {
 	}
 }
 
+func TestAggregateEvents_Clock(t *testing.T) {
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	eh.SetClock(eh.ClockFunc(func() time.Time { return timestamp }))
+	defer eh.SetClock(nil)
+
+	agg := NewTestAggregate(uuid.New())
+
+	// The timestamp is set by the clock.
+	event := agg.AppendEventCtx(context.Background(), TestAggregateEventType, &TestEventData{"event1"})
+	if !event.Timestamp().Equal(timestamp) {
+		t.Error("the timestamp should be from the clock:", event.Timestamp())
+	}
+
+	// The clock of the context takes precedence.
+	ctxTimestamp := timestamp.Add(time.Minute)
+	ctx := eh.NewContextWithClock(context.Background(), eh.ClockFunc(func() time.Time { return ctxTimestamp }))
+
+	event = agg.AppendEventCtx(ctx, TestAggregateEventType, &TestEventData{"event2"})
+	if !event.Timestamp().Equal(ctxTimestamp) {
+		t.Error("the timestamp should be from the context clock:", event.Timestamp())
+	}
+
+	// A provided timestamp is kept.
+	imported := timestamp.Add(-time.Hour)
+
+	event = agg.AppendEvent(TestAggregateEventType, &TestEventData{"event3"}, imported)
+	if !event.Timestamp().Equal(imported) {
+		t.Error("the timestamp should be preserved:", event.Timestamp())
+	}
+}
+
 func TestAggregateEvents(t *testing.T) {
 	id := uuid.New()
 	agg := NewTestAggregate(id)
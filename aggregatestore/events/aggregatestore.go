@@ -32,6 +32,8 @@ type AggregateStore struct {
 	snapshotStore    eh.SnapshotStore
 	isSnapshotStore  bool
 	snapshotStrategy eh.SnapshotStrategy
+	tombstoneEvent   eh.EventType
+	loadBatchSize    int
 }
 
 var (
@@ -80,6 +82,34 @@ func WithSnapshotStrategy(s eh.SnapshotStrategy) Option {
 	}
 }
 
+// WithTombstoneEvent sets the event type that marks an aggregate as deleted.
+// Aggregates implementing eh.DeletableAggregate are marked as deleted when the
+// last applied event is of this type, any later event (for example a restore
+// event) will clear the mark.
+func WithTombstoneEvent(t eh.EventType) Option {
+	return func(as *AggregateStore) error {
+		as.tombstoneEvent = t
+
+		return nil
+	}
+}
+
+// WithLoadBatchSize loads and applies the events of aggregates in batches of at
+// most n events, to bound the memory used when loading aggregates with many
+// events. The event store must implement eh.EventStoreBatchLoader, else all
+// events are loaded at once.
+func WithLoadBatchSize(n int) Option {
+	return func(as *AggregateStore) error {
+		if n < 1 {
+			return fmt.Errorf("invalid load batch size: %d", n)
+		}
+
+		as.loadBatchSize = n
+
+		return nil
+	}
+}
+
 // Load implements the Load method of the eventhorizon.AggregateStore interface.
 // It loads an aggregate from the event store by creating a new aggregate of the
 // type with the ID and then applies all events to it, thus making it the most
@@ -124,6 +154,23 @@ func (r *AggregateStore) Load(ctx context.Context, aggregateType eh.AggregateTyp
 		}
 	}
 
+	if loader, ok := r.store.(eh.EventStoreBatchLoader); ok && r.loadBatchSize > 0 {
+		if err := loader.LoadInBatches(ctx, a.EntityID(), fromVersion, r.loadBatchSize,
+			func(ctx context.Context, events []eh.Event) error {
+				return r.applyEvents(ctx, a, events)
+			},
+		); err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+			return nil, &eh.AggregateStoreError{
+				Err:           err,
+				Op:            eh.AggregateStoreOpLoad,
+				AggregateType: aggregateType,
+				AggregateID:   id,
+			}
+		}
+
+		return a, nil
+	}
+
 	events, err := r.store.LoadFrom(ctx, a.EntityID(), fromVersion)
 	if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
 		return nil, &eh.AggregateStoreError{
@@ -146,6 +193,68 @@ func (r *AggregateStore) Load(ctx context.Context, aggregateType eh.AggregateTyp
 	return a, nil
 }
 
+// LoadAggregateAt loads an aggregate as it was at a point in time, by applying
+// only the events with a timestamp before or at t. Snapshots are not used as
+// they can be newer than t. Returns ErrAggregateNotFound if the aggregate had
+// no events at t.
+func (r *AggregateStore) LoadAggregateAt(ctx context.Context, aggregateType eh.AggregateType, id uuid.UUID, t time.Time) (eh.Aggregate, error) {
+	agg, err := eh.CreateAggregate(aggregateType, id)
+	if err != nil {
+		return nil, &eh.AggregateStoreError{
+			Err:           err,
+			Op:            eh.AggregateStoreOpLoad,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	a, ok := agg.(VersionedAggregate)
+	if !ok {
+		return nil, &eh.AggregateStoreError{
+			Err:           ErrAggregateNotVersioned,
+			Op:            eh.AggregateStoreOpLoad,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	events, err := r.store.LoadFrom(ctx, id, 1)
+	if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+		return nil, &eh.AggregateStoreError{
+			Err:           err,
+			Op:            eh.AggregateStoreOpLoad,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	// Events are in version order, stop at the first event after t.
+	n := 0
+	for n < len(events) && !events[n].Timestamp().After(t) {
+		n++
+	}
+
+	if n == 0 {
+		return nil, &eh.AggregateStoreError{
+			Err:           eh.ErrAggregateNotFound,
+			Op:            eh.AggregateStoreOpLoad,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	if err := r.applyEvents(ctx, a, events[:n]); err != nil {
+		return nil, &eh.AggregateStoreError{
+			Err:           err,
+			Op:            eh.AggregateStoreOpLoad,
+			AggregateType: aggregateType,
+			AggregateID:   id,
+		}
+	}
+
+	return a, nil
+}
+
 // Save implements the Save method of the eventhorizon.AggregateStore interface.
 // It saves all uncommitted events from an aggregate to the event store.
 func (r *AggregateStore) Save(ctx context.Context, agg eh.Aggregate) error {
@@ -196,6 +305,13 @@ func (r *AggregateStore) takeSnapshot(ctx context.Context, agg eh.Aggregate, las
 		return nil
 	}
 
+	// Deleted aggregates are not snapshotted, as the deleted state is not part
+	// of the snapshot. It is restored by applying the tombstone event after the
+	// last snapshot when loading.
+	if d, ok := agg.(eh.DeletableAggregate); ok && d.IsDeleted() {
+		return nil
+	}
+
 	s, err := r.snapshotStore.LoadSnapshot(ctx, agg.EntityID())
 	if err != nil {
 		return &eh.AggregateStoreError{
@@ -239,6 +355,10 @@ func (r *AggregateStore) applyEvents(ctx context.Context, a VersionedAggregate,
 		}
 
 		a.SetAggregateVersion(event.Version())
+
+		if d, ok := a.(eh.DeletableAggregate); ok && r.tombstoneEvent != "" {
+			d.SetDeleted(event.EventType() == r.tombstoneEvent)
+		}
 	}
 
 	return nil
@@ -150,6 +150,130 @@ func TestAggregateStore_LoadEvents(t *testing.T) {
 	eventStore.Err = nil
 }
 
+func TestAggregateStore_LoadAggregateAt(t *testing.T) {
+	store, eventStore := createStore(t)
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregate(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp)
+	event2 := agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp.Add(time.Hour))
+
+	if err := eventStore.Save(ctx, []eh.Event{event1, event2}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Between the events.
+	loaded, err := store.LoadAggregateAt(ctx, TestAggregateType, id, timestamp.Add(time.Minute))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	a, ok := loaded.(*TestAggregate)
+	if !ok {
+		t.Fatal("the aggregate should be of correct type")
+	}
+
+	if a.AggregateVersion() != 1 {
+		t.Error("the aggregate version should be 1:", a.AggregateVersion())
+	}
+
+	if !reflect.DeepEqual(a.event, event1) {
+		t.Error("the event should be correct:", a.event)
+	}
+
+	// At the last event.
+	loaded, err = store.LoadAggregateAt(ctx, TestAggregateType, id, timestamp.Add(time.Hour))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loaded.(*TestAggregate).AggregateVersion() != 2 {
+		t.Error("the aggregate version should be 2:", loaded.(*TestAggregate).AggregateVersion())
+	}
+
+	// Before the aggregate existed.
+	_, err = store.LoadAggregateAt(ctx, TestAggregateType, id, timestamp.Add(-time.Minute))
+	if !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("there should be a aggregate not found error:", err)
+	}
+
+	// Store error.
+	storeErr := errors.New("error")
+	eventStore.Err = storeErr
+
+	_, err = store.LoadAggregateAt(ctx, TestAggregateType, id, timestamp)
+	if !errors.Is(err, storeErr) {
+		t.Error("the error should be correct:", err)
+	}
+
+	eventStore.Err = nil
+}
+
+func TestAggregateStore_LoadInBatches(t *testing.T) {
+	eventStore := &batchingEventStore{EventStore: &mocks.EventStore{}}
+
+	if _, err := NewAggregateStore(eventStore, WithLoadBatchSize(0)); err == nil {
+		t.Error("there should be an error for an invalid batch size")
+	}
+
+	store, err := NewAggregateStore(eventStore, WithLoadBatchSize(100))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	id := uuid.New()
+
+	// A large history.
+	const n = 10000
+
+	agg := NewTestAggregate(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	for i := 0; i < n; i++ {
+		eventStore.Events = append(eventStore.Events,
+			agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprint(i)}, timestamp))
+	}
+
+	loaded, err := store.Load(ctx, TestAggregateType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	a, ok := loaded.(*TestAggregate)
+	if !ok {
+		t.Fatal("the aggregate should be of correct type")
+	}
+
+	if a.AggregateVersion() != n {
+		t.Error("the aggregate version should be correct:", a.AggregateVersion())
+	}
+
+	if !reflect.DeepEqual(a.event, eventStore.Events[n-1]) {
+		t.Error("the last event should be applied:", a.event)
+	}
+
+	if len(eventStore.batches) != n/100 {
+		t.Error("the events should be loaded in batches:", len(eventStore.batches))
+	}
+
+	for _, size := range eventStore.batches {
+		if size > 100 {
+			t.Error("the batches should be bounded:", size)
+		}
+	}
+
+	// Errors from applying events.
+	eventStore.Events = append(eventStore.Events,
+		eh.NewEvent(mocks.EventType, nil, timestamp, eh.ForAggregate(TestAggregateOtherType, id, n+1)))
+
+	if _, err := store.Load(ctx, TestAggregateType, id); !errors.Is(err, ErrMismatchedEventType) {
+		t.Error("there should be a mismatched event type error:", err)
+	}
+}
+
 func TestAggregateStore_LoadEvents_MismatchedEventType(t *testing.T) {
 	store, eventStore := createStore(t)
 
@@ -311,6 +435,108 @@ func TestAggregateStore_AggregateNotRegistered(t *testing.T) {
 	}
 }
 
+func TestAggregateStore_TombstoneEvent(t *testing.T) {
+	eventStore := &mocks.EventStore{
+		Events: make([]eh.Event, 0),
+	}
+
+	store, err := NewAggregateStore(eventStore, WithTombstoneEvent(mocks.EventOtherType))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregate(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp)
+	event2 := agg.AppendEvent(mocks.EventOtherType, nil, timestamp)
+
+	if err := eventStore.Save(ctx, []eh.Event{event1, event2}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loaded, err := store.Load(ctx, TestAggregateType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !loaded.(eh.DeletableAggregate).IsDeleted() {
+		t.Error("the aggregate should be deleted")
+	}
+
+	// Restore with a later event.
+	agg = NewTestAggregate(id)
+	agg.SetAggregateVersion(2)
+	event3 := agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp)
+
+	if err := eventStore.Save(ctx, []eh.Event{event3}, 2); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	loaded, err = store.Load(ctx, TestAggregateType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if loaded.(eh.DeletableAggregate).IsDeleted() {
+		t.Error("the aggregate should not be deleted")
+	}
+}
+
+func TestAggregateStore_TombstoneEventSnapshot(t *testing.T) {
+	eventStore := &mocks.EventStore{
+		Events: make([]eh.Event, 0),
+	}
+
+	store, err := NewAggregateStore(eventStore,
+		WithSnapshotStrategy(NewEveryNumberEventSnapshotStrategy(1)),
+		WithTombstoneEvent(mocks.EventOtherType))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	id := uuid.New()
+	agg := NewTestAggregateOther(id)
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	agg.AppendEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp)
+
+	if err := store.Save(ctx, agg); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if eventStore.Snapshot.Version != 1 {
+		t.Error("there should be a snapshot:", eventStore.Snapshot.Version)
+	}
+
+	// No snapshot is taken after the tombstone.
+	agg.AppendEvent(mocks.EventOtherType, nil, timestamp)
+
+	if err := store.Save(ctx, agg); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !agg.IsDeleted() {
+		t.Error("the aggregate should be deleted")
+	}
+
+	if eventStore.Snapshot.Version != 1 {
+		t.Error("there should be no snapshot of the deleted aggregate:", eventStore.Snapshot.Version)
+	}
+
+	// The tombstone is applied after the snapshot when loading.
+	loaded, err := store.Load(ctx, TestAggregateOtherType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !loaded.(eh.DeletableAggregate).IsDeleted() {
+		t.Error("the loaded aggregate should be deleted")
+	}
+}
+
 func createStore(t *testing.T) (*AggregateStore, *mocks.EventStore) {
 	eventStore := &mocks.EventStore{
 		Events: make([]eh.Event, 0),
@@ -377,3 +603,30 @@ func (a *TestAggregateOther) ApplySnapshot(snapshot *eh.Snapshot) {
 	agg := snapshot.State.(*TestAggregateOther)
 	a.id = agg.id
 }
+
+// batchingEventStore loads the events of the mocked store in batches.
+type batchingEventStore struct {
+	*mocks.EventStore
+	batches []int
+}
+
+func (s *batchingEventStore) LoadInBatches(ctx context.Context, id uuid.UUID, version, batchSize int, f func(ctx context.Context, events []eh.Event) error) error {
+	events := s.Events[version-1:]
+
+	for len(events) > 0 {
+		n := batchSize
+		if n > len(events) {
+			n = len(events)
+		}
+
+		s.batches = append(s.batches, n)
+
+		if err := f(ctx, events[:n]); err != nil {
+			return err
+		}
+
+		events = events[n:]
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// AuditLog is an append-only log of both the handled commands and the events
+// that they resulted in, with a shared sequence that keeps the order in which
+// they happened. It can be used to audit a system and to replay it, either by
+// handling the commands again or by applying the events, see the auditlog
+// package.
+type AuditLog interface {
+	// Append appends a record to the log and sets its sequence number, which
+	// is one more than the sequence number of the previous record. Command
+	// records should have the pending status until the command has been handled.
+	Append(context.Context, *AuditRecord) error
+
+	// SetStatus sets the outcome of a handled command for a record by its
+	// sequence number. Returns ErrAuditRecordNotFound if there is no such
+	// command record.
+	SetStatus(ctx context.Context, seq int, status CommandLogStatus, errMsg string) error
+
+	// Records returns all records in sequence order.
+	Records(context.Context) ([]*AuditRecord, error)
+
+	// Close closes the AuditLog.
+	Close() error
+}
+
+// AuditRecordKind is the kind of a record in the audit log.
+type AuditRecordKind int
+
+const (
+	// AuditCommand is for records of commands.
+	AuditCommand AuditRecordKind = iota + 1
+	// AuditEvent is for records of events.
+	AuditEvent
+)
+
+// String returns the string representation of an audit record kind.
+func (k AuditRecordKind) String() string {
+	switch k {
+	case AuditCommand:
+		return "command"
+	case AuditEvent:
+		return "event"
+	default:
+		return "unknown"
+	}
+}
+
+// AuditRecord is a record of a command or an event in the audit log.
+type AuditRecord struct {
+	// Sequence is the position of the record in the log, starting at 1.
+	Sequence int
+	// Kind is if the record is of a command or an event.
+	Kind AuditRecordKind
+	// Type is the command or event type.
+	Type string
+	// AggregateType is the type of the aggregate of the command or event.
+	AggregateType AggregateType
+	// AggregateID is the ID of the aggregate of the command or event.
+	AggregateID uuid.UUID
+	// Version is the version of the event, 0 for commands.
+	Version int
+	// Data is the command or event (and supported parts of the context)
+	// marshaled with a CommandCodec or EventCodec.
+	Data []byte
+	// Timestamp is when the record was logged.
+	Timestamp time.Time
+	// Status is the outcome of handling a command, only used for commands.
+	Status CommandLogStatus
+	// Err is the error message if a command failed.
+	Err string
+}
+
+// ErrAuditRecordNotFound is when a record could not be found in an audit log.
+var ErrAuditRecordNotFound = errors.New("audit record not found")
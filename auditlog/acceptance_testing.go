@@ -0,0 +1,143 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// AcceptanceTest is the acceptance test that all implementations of AuditLog
+// should pass. It should manually be called from a test case in each
+// implementation:
+//
+//	func TestAuditLog(t *testing.T) {
+//	    l := NewAuditLog()
+//	    auditlog.AcceptanceTest(t, l, context.Background())
+//	}
+func AcceptanceTest(t *testing.T, l eh.AuditLog, ctx context.Context) {
+	// No records.
+	records, err := l.Records(ctx)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(records) != 0 {
+		t.Error("there should be no records:", records)
+	}
+
+	// Set status on a non-existing record.
+	if err := l.SetStatus(ctx, 1, eh.CommandSucceeded, ""); !errors.Is(err, eh.ErrAuditRecordNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	id := uuid.New()
+	record1 := &eh.AuditRecord{
+		Kind:          eh.AuditCommand,
+		Type:          mocks.CommandType.String(),
+		AggregateType: mocks.AggregateType,
+		AggregateID:   id,
+		Data:          []byte("command"),
+		Timestamp:     timestamp,
+		Status:        eh.CommandPending,
+	}
+
+	if err := l.Append(ctx, record1); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	record2 := &eh.AuditRecord{
+		Kind:          eh.AuditEvent,
+		Type:          mocks.EventType.String(),
+		AggregateType: mocks.AggregateType,
+		AggregateID:   id,
+		Version:       1,
+		Data:          []byte("event"),
+		Timestamp:     timestamp.Add(time.Second),
+	}
+
+	if err := l.Append(ctx, record2); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	record3 := &eh.AuditRecord{
+		Kind:          eh.AuditCommand,
+		Type:          mocks.CommandOtherType.String(),
+		AggregateType: mocks.AggregateType,
+		AggregateID:   id,
+		Data:          []byte("command2"),
+		Timestamp:     timestamp.Add(2 * time.Second),
+		Status:        eh.CommandPending,
+	}
+
+	if err := l.Append(ctx, record3); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// The sequence is shared by commands and events.
+	if record1.Sequence != 1 || record2.Sequence != 2 || record3.Sequence != 3 {
+		t.Error("the sequence numbers should be set:", record1.Sequence, record2.Sequence, record3.Sequence)
+	}
+
+	// Only commands have a status.
+	if err := l.SetStatus(ctx, record2.Sequence, eh.CommandSucceeded, ""); !errors.Is(err, eh.ErrAuditRecordNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	// Set the outcome of both commands.
+	if err := l.SetStatus(ctx, record1.Sequence, eh.CommandSucceeded, ""); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := l.SetStatus(ctx, record3.Sequence, eh.CommandFailed, "command error"); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	records, err = l.Records(ctx)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	expected1 := *record1
+	expected1.Status = eh.CommandSucceeded
+	expected2 := *record2
+	expected3 := *record3
+	expected3.Status = eh.CommandFailed
+	expected3.Err = "command error"
+
+	if len(records) != 3 {
+		t.Fatal("there should be three records:", len(records))
+	}
+
+	for i, expected := range []*eh.AuditRecord{&expected1, &expected2, &expected3} {
+		if !records[i].Timestamp.Equal(expected.Timestamp) {
+			t.Error("the timestamp should be correct:", records[i].Timestamp)
+		}
+
+		records[i].Timestamp = expected.Timestamp
+
+		if !reflect.DeepEqual(records[i], expected) {
+			t.Errorf("the record should be correct:\ngot:  %+v\nwant: %+v", records[i], expected)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// NewCommandMiddleware returns a new middleware that marshals every command
+// with the codec and appends it to the audit log before handling it. The
+// outcome of the handling is recorded on the record afterwards. Commands that
+// can not be logged will not be handled.
+//
+// To keep the records of the resulting events right after their command in the
+// log, add the EventHandler as an inline projector of the aggregate command
+// handler, see aggregate.WithInlineProjectors. Events handled asynchronously,
+// for example from an event bus, are logged when they are handled.
+func NewCommandMiddleware(l eh.AuditLog, codec eh.CommandCodec) eh.CommandHandlerMiddleware {
+	return eh.CommandHandlerMiddleware(func(h eh.CommandHandler) eh.CommandHandler {
+		return eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+			data, err := codec.MarshalCommand(ctx, cmd)
+			if err != nil {
+				return fmt.Errorf("could not marshal command for the audit log: %w", err)
+			}
+
+			record := &eh.AuditRecord{
+				Kind:          eh.AuditCommand,
+				Type:          cmd.CommandType().String(),
+				AggregateType: cmd.AggregateType(),
+				AggregateID:   cmd.AggregateID(),
+				Data:          data,
+				Timestamp:     time.Now(),
+				Status:        eh.CommandPending,
+			}
+
+			if err := l.Append(ctx, record); err != nil {
+				return fmt.Errorf("could not append command to the audit log: %w", err)
+			}
+
+			handleErr := h.HandleCommand(ctx, cmd)
+
+			status, errMsg := eh.CommandSucceeded, ""
+			if handleErr != nil {
+				status, errMsg = eh.CommandFailed, handleErr.Error()
+			}
+
+			// The command has already been handled at this point, only log
+			// the failure to not hide the outcome from the caller.
+			if err := l.SetStatus(ctx, record.Sequence, status, errMsg); err != nil {
+				log.Printf("eventhorizon: could not set status for command %d in the audit log: %s", record.Sequence, err)
+			}
+
+			return handleErr
+		})
+	})
+}
+
+// EventHandler is an event handler that marshals every event with a codec and
+// appends it to an audit log.
+type EventHandler struct {
+	log   eh.AuditLog
+	codec eh.EventCodec
+}
+
+var _ = eh.EventHandler(&EventHandler{})
+
+// NewEventHandler creates a new EventHandler that appends events to the log.
+func NewEventHandler(l eh.AuditLog, codec eh.EventCodec) *EventHandler {
+	return &EventHandler{
+		log:   l,
+		codec: codec,
+	}
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (h *EventHandler) HandlerType() eh.EventHandlerType {
+	return "auditlog"
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (h *EventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	data, err := h.codec.MarshalEvent(ctx, event)
+	if err != nil {
+		return fmt.Errorf("could not marshal event for the audit log: %w", err)
+	}
+
+	record := &eh.AuditRecord{
+		Kind:          eh.AuditEvent,
+		Type:          event.EventType().String(),
+		AggregateType: event.AggregateType(),
+		AggregateID:   event.AggregateID(),
+		Version:       event.Version(),
+		Data:          data,
+		Timestamp:     time.Now(),
+	}
+
+	if err := h.log.Append(ctx, record); err != nil {
+		return fmt.Errorf("could not append event to the audit log: %w", err)
+	}
+
+	return nil
+}
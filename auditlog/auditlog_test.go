@@ -0,0 +1,356 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/aggregatestore/events"
+	"github.com/looplab/eventhorizon/auditlog/memory"
+	"github.com/looplab/eventhorizon/codec/json"
+	"github.com/looplab/eventhorizon/commandhandler/aggregate"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	l := memory.NewAuditLog()
+
+	// Handle commands for two counters, with the events logged inline.
+	original := newCounterSystem(t, NewEventHandler(l, &json.EventCodec{}))
+	h := eh.UseCommandHandlerMiddleware(original.handler,
+		NewCommandMiddleware(l, json.CommandCodec{}))
+
+	id1, id2 := uuid.New(), uuid.New()
+	cmds := []*addCommand{
+		{ID: id1, N: 1},
+		{ID: id2, N: 10},
+		{ID: id1, N: -1},
+		{ID: id1, N: 2},
+		{ID: id2, N: 20},
+	}
+
+	for _, cmd := range cmds {
+		err := h.HandleCommand(ctx, cmd)
+		if cmd.N < 0 && !errors.Is(err, errNegative) {
+			t.Error("there should be a negative number error:", err)
+		} else if cmd.N >= 0 && err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	// The commands and their events are interleaved in the log.
+	records, err := l.Records(ctx)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := []struct {
+		kind   eh.AuditRecordKind
+		status eh.CommandLogStatus
+	}{
+		{eh.AuditCommand, eh.CommandSucceeded},
+		{eh.AuditEvent, eh.CommandPending},
+		{eh.AuditCommand, eh.CommandSucceeded},
+		{eh.AuditEvent, eh.CommandPending},
+		{eh.AuditCommand, eh.CommandFailed},
+		{eh.AuditCommand, eh.CommandSucceeded},
+		{eh.AuditEvent, eh.CommandPending},
+		{eh.AuditCommand, eh.CommandSucceeded},
+		{eh.AuditEvent, eh.CommandPending},
+	}
+
+	if len(records) != len(expected) {
+		t.Fatal("there should be records for all commands and events:", len(records))
+	}
+
+	for i, r := range records {
+		if r.Sequence != i+1 || r.Kind != expected[i].kind || r.Status != expected[i].status {
+			t.Errorf("the record should be correct: %+v", r)
+		}
+	}
+
+	// Replaying the events reproduces the same state, and the same events.
+	replayed := newCounterSystem(t, nil)
+
+	if err := Replay(ctx, l, ReplayEvents, WithEventHandler(replayed.saver(), &json.EventCodec{})); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	for _, id := range []uuid.UUID{id1, id2} {
+		assertSameState(t, original, replayed, id)
+
+		originalEvents, replayedEvents := original.store.aggregateEvents(id), replayed.store.aggregateEvents(id)
+		if len(replayedEvents) != len(originalEvents) {
+			t.Fatal("the same number of events should be replayed:", len(replayedEvents))
+		}
+
+		for i := range originalEvents {
+			if err := eh.CompareEvents(replayedEvents[i], originalEvents[i]); err != nil {
+				t.Error("the replayed event should be the same:", err)
+			}
+		}
+	}
+
+	// Replaying the commands also reproduces the same state, without handling
+	// the command that failed.
+	rehandled := newCounterSystem(t, nil)
+
+	if err := Replay(ctx, l, ReplayCommands, WithCommandHandler(rehandled.handler, json.CommandCodec{})); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	for _, id := range []uuid.UUID{id1, id2} {
+		assertSameState(t, original, rehandled, id)
+	}
+
+	// Replaying up to a point reproduces the state at that point.
+	partial := newCounterSystem(t, nil)
+
+	if err := Replay(ctx, l, ReplayEvents, WithEventHandler(partial.saver(), &json.EventCodec{}), WithUntil(6)); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if c := partial.load(t, id1); c.total != 1 {
+		t.Error("the partial state should be correct:", c.total)
+	}
+
+	if c := partial.load(t, id2); c.total != 10 {
+		t.Error("the partial state should be correct:", c.total)
+	}
+}
+
+func TestReplay_Errors(t *testing.T) {
+	ctx := context.Background()
+	l := memory.NewAuditLog()
+
+	if err := Replay(ctx, l, ReplayCommands); !errors.Is(err, ErrMissingHandler) {
+		t.Error("there should be a missing handler error:", err)
+	}
+
+	if err := Replay(ctx, l, ReplayEvents); !errors.Is(err, ErrMissingHandler) {
+		t.Error("there should be a missing handler error:", err)
+	}
+
+	if err := Replay(ctx, l, Mode(-1)); !errors.Is(err, ErrUnknownMode) {
+		t.Error("there should be an unknown mode error:", err)
+	}
+
+	// Replaying stops at the first error.
+	for i := 0; i < 2; i++ {
+		if err := l.Append(ctx, &eh.AuditRecord{Kind: eh.AuditEvent, Data: []byte("invalid")}); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	handled := 0
+	h := eh.EventHandlerFunc(func(ctx context.Context, event eh.Event) error {
+		handled++
+
+		return nil
+	})
+
+	if err := Replay(ctx, l, ReplayEvents, WithEventHandler(h, &json.EventCodec{})); err == nil || handled != 0 {
+		t.Error("there should be an unmarshal error:", err, handled)
+	}
+}
+
+func assertSameState(t *testing.T, expected, actual *counterSystem, id uuid.UUID) {
+	t.Helper()
+
+	e, a := expected.load(t, id), actual.load(t, id)
+	if a.total != e.total || a.AggregateVersion() != e.AggregateVersion() {
+		t.Errorf("the state should be the same: total %d at version %d (should be %d at version %d)",
+			a.total, a.AggregateVersion(), e.total, e.AggregateVersion())
+	}
+}
+
+const (
+	counterAggregateType eh.AggregateType = "AuditLogCounter"
+	addCommandType       eh.CommandType   = "AuditLogAdd"
+	addedEventType       eh.EventType     = "AuditLogAdded"
+)
+
+var errNegative = errors.New("negative number")
+
+func init() {
+	eh.RegisterAggregate(func(id uuid.UUID) eh.Aggregate {
+		return &counter{AggregateBase: events.NewAggregateBase(counterAggregateType, id)}
+	})
+	eh.RegisterCommand(func() eh.Command { return &addCommand{} })
+	eh.RegisterEventData(addedEventType, func() eh.EventData { return &addedData{} })
+}
+
+type addCommand struct {
+	ID uuid.UUID
+	N  int
+}
+
+func (c *addCommand) AggregateID() uuid.UUID          { return c.ID }
+func (c *addCommand) AggregateType() eh.AggregateType { return counterAggregateType }
+func (c *addCommand) CommandType() eh.CommandType     { return addCommandType }
+
+type addedData struct {
+	N int
+}
+
+type counter struct {
+	*events.AggregateBase
+	total int
+}
+
+func (a *counter) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	c, ok := cmd.(*addCommand)
+	if !ok {
+		return fmt.Errorf("unknown command: %s", cmd.CommandType())
+	}
+
+	if c.N < 0 {
+		return errNegative
+	}
+
+	a.AppendEvent(addedEventType, &addedData{N: c.N}, eh.Now(ctx))
+
+	return nil
+}
+
+func (a *counter) ApplyEvent(ctx context.Context, event eh.Event) error {
+	data, ok := event.Data().(*addedData)
+	if !ok {
+		return fmt.Errorf("unknown event data: %T", event.Data())
+	}
+
+	a.total += data.N
+
+	return nil
+}
+
+// counterSystem is a command handler for counters with its own event store.
+type counterSystem struct {
+	store   *eventStore
+	aggs    *events.AggregateStore
+	handler eh.CommandHandler
+}
+
+func newCounterSystem(t *testing.T, inline eh.EventHandler) *counterSystem {
+	t.Helper()
+
+	store := &eventStore{byID: map[uuid.UUID][]eh.Event{}}
+
+	aggs, err := events.NewAggregateStore(store)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var options []aggregate.Option
+	if inline != nil {
+		options = append(options, aggregate.WithInlineProjectors(inline))
+	}
+
+	h, err := aggregate.NewCommandHandler(counterAggregateType, aggs, options...)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	return &counterSystem{store: store, aggs: aggs, handler: h}
+}
+
+// saver returns an event handler that saves replayed events in the store.
+func (s *counterSystem) saver() eh.EventHandler {
+	return eh.EventHandlerFunc(func(ctx context.Context, event eh.Event) error {
+		return s.store.Save(ctx, []eh.Event{event}, event.Version()-1)
+	})
+}
+
+func (s *counterSystem) load(t *testing.T, id uuid.UUID) *counter {
+	t.Helper()
+
+	agg, err := s.aggs.Load(context.Background(), counterAggregateType, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	c, ok := agg.(*counter)
+	if !ok {
+		t.Fatal("the aggregate should be of correct type")
+	}
+
+	return c
+}
+
+// eventStore is a minimal event store that keeps the events of all aggregates
+// in memory, as the tests only need to save and load events.
+type eventStore struct {
+	byID map[uuid.UUID][]eh.Event
+	mu   sync.RWMutex
+}
+
+func (s *eventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	id := events[0].AggregateID()
+	if len(s.byID[id]) != originalVersion {
+		return eh.ErrEventConflictFromOtherSave
+	}
+
+	s.byID[id] = append(s.byID[id], events...)
+
+	return nil
+}
+
+func (s *eventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
+	return s.LoadFrom(ctx, id, 1)
+}
+
+func (s *eventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	saved, ok := s.byID[id]
+	if !ok {
+		return nil, eh.ErrAggregateNotFound
+	}
+
+	if version > len(saved) {
+		return nil, nil
+	}
+
+	return append([]eh.Event(nil), saved[version-1:]...), nil
+}
+
+func (s *eventStore) Replace(ctx context.Context, event eh.Event) error {
+	return errors.New("replace not supported")
+}
+
+func (s *eventStore) Close() error {
+	return nil
+}
+
+func (s *eventStore) aggregateEvents(id uuid.UUID) []eh.Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]eh.Event(nil), s.byID[id]...)
+}
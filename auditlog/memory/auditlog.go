@@ -0,0 +1,89 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// AuditLog is an eventhorizon.AuditLog where all records are stored in memory
+// and not persisted. Useful for testing and experimenting.
+type AuditLog struct {
+	records []*eh.AuditRecord
+	mu      sync.RWMutex
+}
+
+var _ = eh.AuditLog(&AuditLog{})
+
+// NewAuditLog creates a new AuditLog.
+func NewAuditLog() *AuditLog {
+	return &AuditLog{}
+}
+
+// Append implements the Append method of the eventhorizon.AuditLog interface.
+func (l *AuditLog) Append(ctx context.Context, record *eh.AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.Sequence = len(l.records) + 1
+	l.records = append(l.records, copyRecord(record))
+
+	return nil
+}
+
+// SetStatus implements the SetStatus method of the eventhorizon.AuditLog interface.
+func (l *AuditLog) SetStatus(ctx context.Context, seq int, status eh.CommandLogStatus, errMsg string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if seq < 1 || seq > len(l.records) || l.records[seq-1].Kind != eh.AuditCommand {
+		return eh.ErrAuditRecordNotFound
+	}
+
+	r := l.records[seq-1]
+	r.Status = status
+	r.Err = errMsg
+
+	return nil
+}
+
+// Records implements the Records method of the eventhorizon.AuditLog interface.
+func (l *AuditLog) Records(ctx context.Context) ([]*eh.AuditRecord, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	records := make([]*eh.AuditRecord, len(l.records))
+	for i, r := range l.records {
+		records[i] = copyRecord(r)
+	}
+
+	return records, nil
+}
+
+// Close implements the Close method of the eventhorizon.AuditLog interface.
+func (l *AuditLog) Close() error {
+	return nil
+}
+
+// copyRecord duplicates a record, including its data.
+func copyRecord(record *eh.AuditRecord) *eh.AuditRecord {
+	r := *record
+	r.Data = append([]byte(nil), record.Data...)
+
+	return &r
+}
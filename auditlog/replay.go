@@ -0,0 +1,171 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// Mode selects what to replay from an audit log.
+type Mode int
+
+const (
+	// ReplayCommands re-drives a system by handling the logged commands again,
+	// which runs all the domain logic and produces new events. Only commands
+	// that succeeded when originally handled are replayed, as the failed (or
+	// never completed) commands did not change the state.
+	ReplayCommands Mode = iota
+	// ReplayEvents re-drives a system by applying the logged events, which
+	// reproduces the original state without running any command handling.
+	ReplayEvents
+)
+
+// String returns the string representation of a replay mode.
+func (m Mode) String() string {
+	switch m {
+	case ReplayCommands:
+		return "commands"
+	case ReplayEvents:
+		return "events"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	// ErrUnknownMode is when replaying with an unknown mode.
+	ErrUnknownMode = errors.New("unknown replay mode")
+	// ErrMissingHandler is when there is no handler for the replay mode.
+	ErrMissingHandler = errors.New("missing handler for replay mode")
+)
+
+// ReplayOption is an option when replaying an audit log.
+type ReplayOption func(*replayer)
+
+// WithCommandHandler sets the command handler and codec used when replaying
+// commands, required for ReplayCommands.
+func WithCommandHandler(h eh.CommandHandler, codec eh.CommandCodec) ReplayOption {
+	return func(r *replayer) {
+		r.commandHandler = h
+		r.commandCodec = codec
+	}
+}
+
+// WithEventHandler sets the event handler and codec used when replaying
+// events, required for ReplayEvents. To rebuild the aggregates, use an event
+// handler that saves the events in a new event store.
+func WithEventHandler(h eh.EventHandler, codec eh.EventCodec) ReplayOption {
+	return func(r *replayer) {
+		r.eventHandler = h
+		r.eventCodec = codec
+	}
+}
+
+// WithUntil only replays the records up to and including a sequence number,
+// to reconstruct the state of a system at a point in its history.
+func WithUntil(seq int) ReplayOption {
+	return func(r *replayer) {
+		r.until = seq
+	}
+}
+
+type replayer struct {
+	commandHandler eh.CommandHandler
+	commandCodec   eh.CommandCodec
+	eventHandler   eh.EventHandler
+	eventCodec     eh.EventCodec
+	until          int
+}
+
+// Replay replays the records of an audit log in sequence order, either
+// re-handling the commands or applying the events depending on the mode.
+// Replaying stops at the first error.
+func Replay(ctx context.Context, l eh.AuditLog, mode Mode, options ...ReplayOption) error {
+	r := &replayer{}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(r)
+	}
+
+	var kind eh.AuditRecordKind
+
+	switch mode {
+	case ReplayCommands:
+		if r.commandHandler == nil || r.commandCodec == nil {
+			return fmt.Errorf("%w: %s", ErrMissingHandler, mode)
+		}
+
+		kind = eh.AuditCommand
+	case ReplayEvents:
+		if r.eventHandler == nil || r.eventCodec == nil {
+			return fmt.Errorf("%w: %s", ErrMissingHandler, mode)
+		}
+
+		kind = eh.AuditEvent
+	default:
+		return fmt.Errorf("%w: %d", ErrUnknownMode, mode)
+	}
+
+	records, err := l.Records(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get records from the audit log: %w", err)
+	}
+
+	for _, record := range records {
+		if r.until > 0 && record.Sequence > r.until {
+			break
+		}
+
+		if record.Kind != kind {
+			continue
+		}
+
+		if err := r.replay(ctx, record); err != nil {
+			return fmt.Errorf("could not replay %s %d: %w", record.Kind, record.Sequence, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *replayer) replay(ctx context.Context, record *eh.AuditRecord) error {
+	if record.Kind == eh.AuditCommand {
+		if record.Status != eh.CommandSucceeded {
+			return nil
+		}
+
+		cmd, ctx, err := r.commandCodec.UnmarshalCommand(ctx, record.Data)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal command: %w", err)
+		}
+
+		return r.commandHandler.HandleCommand(ctx, cmd)
+	}
+
+	event, ctx, err := r.eventCodec.UnmarshalEvent(ctx, record.Data)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal event: %w", err)
+	}
+
+	return r.eventHandler.HandleEvent(ctx, event)
+}
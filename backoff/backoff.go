@@ -0,0 +1,146 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backoff contains a jittered exponential backoff, used when retrying
+// operations such as handling events or commands.
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff is a jittered exponential backoff. Each duration is the previous
+// multiplied by the multiplier, starting from the base and capped by the max.
+// A random jitter is subtracted from each duration, up to the jitter fraction
+// of the duration. A Backoff is not safe for concurrent use.
+type Backoff struct {
+	base       time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+	attempt    int
+
+	// random returns a number in [0.0, 1.0), can be replaced in tests.
+	random func() float64
+}
+
+// NewBackoff creates a new Backoff. The default is a base of 100ms, a max of
+// 30s, a multiplier of 2 and a jitter of 0.2.
+func NewBackoff(options ...Option) *Backoff {
+	b := &Backoff{
+		base:       100 * time.Millisecond,
+		max:        30 * time.Second,
+		multiplier: 2,
+		jitter:     0.2,
+		random:     rand.Float64,
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(b)
+	}
+
+	return b
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Backoff)
+
+// WithBase sets the first duration.
+func WithBase(d time.Duration) Option {
+	return func(b *Backoff) {
+		b.base = d
+	}
+}
+
+// WithMax sets the max duration.
+func WithMax(d time.Duration) Option {
+	return func(b *Backoff) {
+		b.max = d
+	}
+}
+
+// WithMultiplier sets the multiplier used to increase the durations, should
+// be at least 1.
+func WithMultiplier(m float64) Option {
+	return func(b *Backoff) {
+		if m < 1 {
+			m = 1
+		}
+
+		b.multiplier = m
+	}
+}
+
+// WithJitter sets the max fraction of each duration to randomly subtract, in
+// the range 0 (no jitter) to 1.
+func WithJitter(j float64) Option {
+	return func(b *Backoff) {
+		b.jitter = math.Max(0, math.Min(1, j))
+	}
+}
+
+// Next returns the next duration in the sequence.
+func (b *Backoff) Next() time.Duration {
+	d := float64(b.base) * math.Pow(b.multiplier, float64(b.attempt))
+	if d > float64(b.max) {
+		d = float64(b.max)
+	}
+
+	b.attempt++
+
+	if b.jitter > 0 {
+		d -= d * b.jitter * b.random()
+	}
+
+	return time.Duration(d)
+}
+
+// Attempt returns the number of durations returned since creation or the last
+// reset.
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Reset restarts the sequence from the base, for example after a successful try.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+// Wait waits for the next duration. Returns the error of the context if it is
+// done before, or context.DeadlineExceeded directly if the deadline of the
+// context would be exceeded while waiting.
+func (b *Backoff) Wait(ctx context.Context) error {
+	d := b.Next()
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return context.DeadlineExceeded
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(
+		WithBase(10*time.Millisecond),
+		WithMax(100*time.Millisecond),
+		WithMultiplier(3),
+		WithJitter(0),
+	)
+
+	expected := []time.Duration{
+		10 * time.Millisecond,
+		30 * time.Millisecond,
+		90 * time.Millisecond,
+		100 * time.Millisecond,
+		100 * time.Millisecond,
+	}
+
+	for i, e := range expected {
+		if d := b.Next(); d != e {
+			t.Errorf("the duration %d should be correct: %s (should be %s)", i, d, e)
+		}
+	}
+
+	if b.Attempt() != len(expected) {
+		t.Error("the attempt should be correct:", b.Attempt())
+	}
+
+	b.Reset()
+
+	if d := b.Next(); d != 10*time.Millisecond {
+		t.Error("the duration should be reset:", d)
+	}
+}
+
+func TestBackoff_Defaults(t *testing.T) {
+	b := NewBackoff(WithJitter(0))
+
+	var prev time.Duration
+
+	for i := 0; i < 20; i++ {
+		d := b.Next()
+		if i == 0 && d != 100*time.Millisecond {
+			t.Error("the first duration should be the base:", d)
+		}
+
+		if d < prev {
+			t.Error("the durations should be increasing:", d, prev)
+		}
+
+		if d > 30*time.Second {
+			t.Error("the duration should be capped by the max:", d)
+		}
+
+		prev = d
+	}
+
+	if prev != 30*time.Second {
+		t.Error("the duration should reach the max:", prev)
+	}
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	b := NewBackoff(
+		WithBase(100*time.Millisecond),
+		WithMax(time.Second),
+		WithJitter(0.5),
+	)
+
+	for i := 0; i < 1000; i++ {
+		b.Reset()
+
+		if d := b.Next(); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatal("the jittered duration should be in range:", d)
+		}
+	}
+
+	// The max jitter of the max duration.
+	b = NewBackoff(
+		WithBase(time.Second),
+		WithMax(time.Second),
+		WithJitter(0.5),
+	)
+	b.random = func() float64 { return 0.999999 }
+
+	if d := b.Next(); d < 500*time.Millisecond || d > 501*time.Millisecond {
+		t.Error("the jittered duration should be in range:", d)
+	}
+
+	b.random = func() float64 { return 0 }
+
+	if d := b.Next(); d != time.Second {
+		t.Error("the duration should not be jittered:", d)
+	}
+}
+
+func TestBackoff_Wait(t *testing.T) {
+	b := NewBackoff(WithBase(10*time.Millisecond), WithJitter(0))
+
+	start := time.Now()
+
+	if err := b.Wait(context.Background()); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Error("the wait should be for the duration:", elapsed)
+	}
+
+	// Cancelled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := b.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Error("there should be a canceled error:", err)
+	}
+
+	// A deadline before the next duration returns directly.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	b = NewBackoff(WithBase(time.Second), WithJitter(0))
+	start = time.Now()
+
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("there should be a deadline exceeded error:", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Error("the wait should return directly:", elapsed)
+	}
+}
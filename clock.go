@@ -0,0 +1,133 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the source of the current time, used for the timestamps of events.
+// It must be safe for concurrent use.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is a function that can be used as a clock.
+type ClockFunc func() time.Time
+
+// Now implements the Now method of the Clock interface.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// realClock is the default clock.
+var realClock = ClockFunc(time.Now)
+
+var (
+	clock   Clock = realClock
+	clockMu sync.RWMutex
+)
+
+// SetClock sets the clock used by Now when there is no clock in the context,
+// for example to create deterministic timestamps in tests. Use nil to restore
+// the real clock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock
+	}
+
+	clockMu.Lock()
+	defer clockMu.Unlock()
+
+	clock = c
+}
+
+// NewContextWithClock adds a clock on the context, which takes precedence over
+// the clock set with SetClock.
+func NewContextWithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey, c)
+}
+
+// ClockFromContext returns the clock from the context.
+func ClockFromContext(ctx context.Context) (Clock, bool) {
+	c, ok := ctx.Value(clockKey).(Clock)
+
+	return c, ok
+}
+
+// Now returns the current time of the clock in the context, or of the clock set
+// with SetClock. Use it instead of time.Now when creating events:
+//
+//	a.AppendEvent(UserCreatedEvent, &UserCreatedData{Name: "Alice"}, eh.Now(ctx))
+//
+// Aggregates embedding events.AggregateBase can use AppendEventCtx, which sets
+// the timestamp in the same way. Events that are imported should instead keep
+// their original timestamps.
+func Now(ctx context.Context) time.Time {
+	if ctx != nil {
+		if c, ok := ClockFromContext(ctx); ok && c != nil {
+			return c.Now()
+		}
+	}
+
+	clockMu.RLock()
+	c := clock
+	clockMu.RUnlock()
+
+	return c.Now()
+}
+
+// StartProcessing starts timing the processing of a command (or event) using
+// the clock of the context, unless already started. It should be called at
+// ingress, for example when receiving a command over HTTP, so that all
+// middleware and handlers downstream can read the cumulative processing time
+// with ProcessingElapsed. The start time is propagated with the context to
+// events handled by other processes, which can include clock skew between
+// hosts.
+func StartProcessing(ctx context.Context) context.Context {
+	if _, ok := ProcessingStartFromContext(ctx); ok {
+		return ctx
+	}
+
+	return NewContextWithProcessingStart(ctx, Now(ctx))
+}
+
+// NewContextWithProcessingStart adds the start time of processing on the
+// context, replacing any existing start time.
+func NewContextWithProcessingStart(ctx context.Context, start time.Time) context.Context {
+	return context.WithValue(ctx, processingKey, start)
+}
+
+// ProcessingStartFromContext returns the start time of processing from the
+// context.
+func ProcessingStartFromContext(ctx context.Context) (time.Time, bool) {
+	start, ok := ctx.Value(processingKey).(time.Time)
+
+	return start, ok
+}
+
+// ProcessingElapsed returns the time elapsed since processing started, as
+// measured by the clock of the context. Returns 0 if processing has not been
+// started, see StartProcessing.
+func ProcessingElapsed(ctx context.Context) time.Duration {
+	start, ok := ProcessingStartFromContext(ctx)
+	if !ok {
+		return 0
+	}
+
+	return Now(ctx).Sub(start)
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// The real clock by default.
+	before := time.Now()
+	if now := Now(context.Background()); now.Before(before) || now.After(time.Now()) {
+		t.Error("the time should be from the real clock:", now)
+	}
+
+	// A fake clock gives deterministic timestamps.
+	SetClock(ClockFunc(func() time.Time { return timestamp }))
+	defer SetClock(nil)
+
+	for i := 0; i < 2; i++ {
+		if now := Now(context.Background()); !now.Equal(timestamp) {
+			t.Error("the time should be from the fake clock:", now)
+		}
+	}
+
+	// The clock in the context takes precedence.
+	other := timestamp.Add(time.Hour)
+	ctx := NewContextWithClock(context.Background(), ClockFunc(func() time.Time { return other }))
+
+	if c, ok := ClockFromContext(ctx); !ok || !c.Now().Equal(other) {
+		t.Error("the clock should be in the context:", c)
+	}
+
+	if now := Now(ctx); !now.Equal(other) {
+		t.Error("the time should be from the context clock:", now)
+	}
+
+	// Setting and using the clock concurrently.
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			SetClock(ClockFunc(func() time.Time { return timestamp }))
+		}()
+
+		go func() {
+			defer wg.Done()
+			Now(context.Background())
+		}()
+	}
+
+	wg.Wait()
+
+	// Restoring the real clock.
+	SetClock(nil)
+
+	before = time.Now()
+	if now := Now(context.Background()); now.Before(before) || now.After(time.Now()) {
+		t.Error("the time should be from the real clock:", now)
+	}
+}
+
+func TestClock_ImportedTimestamp(t *testing.T) {
+	SetClock(ClockFunc(func() time.Time { return time.Now().Add(time.Hour) }))
+	defer SetClock(nil)
+
+	// Imported events keep their provided timestamps.
+	imported := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	e := NewEvent("Imported", nil, Now(context.Background()), WithTimestamp(imported))
+
+	if !e.Timestamp().Equal(imported) {
+		t.Error("the timestamp should be preserved:", e.Timestamp())
+	}
+}
+
+func TestProcessingElapsed(t *testing.T) {
+	now := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	ctx := NewContextWithClock(context.Background(), ClockFunc(func() time.Time { return now }))
+
+	if elapsed := ProcessingElapsed(ctx); elapsed != 0 {
+		t.Error("there should be no elapsed time before starting:", elapsed)
+	}
+
+	ctx = StartProcessing(ctx)
+	now = now.Add(time.Second)
+
+	if elapsed := ProcessingElapsed(ctx); elapsed != time.Second {
+		t.Error("the elapsed time should be correct:", elapsed)
+	}
+
+	// Starting again keeps the original start.
+	ctx = StartProcessing(ctx)
+	now = now.Add(time.Second)
+
+	if elapsed := ProcessingElapsed(ctx); elapsed != 2*time.Second {
+		t.Error("the elapsed time should be correct:", elapsed)
+	}
+
+	// The start is propagated when marshaling the context.
+	start, _ := ProcessingStartFromContext(ctx)
+	unmarshaled := UnmarshalContext(context.Background(), MarshalContext(ctx))
+
+	if s, ok := ProcessingStartFromContext(unmarshaled); !ok || !s.Equal(start) {
+		t.Error("the start should be unmarshaled:", s)
+	}
+}
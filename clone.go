@@ -0,0 +1,160 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"reflect"
+	"time"
+)
+
+// WithTimestamp sets the timestamp of an event, useful when cloning or importing
+// events to keep their original timestamps.
+func WithTimestamp(timestamp time.Time) EventOption {
+	return func(e Event) {
+		if evt, ok := e.(*event); ok {
+			evt.timestamp = timestamp
+		}
+	}
+}
+
+// WithVersion sets the aggregate version of an event, useful when cloning events.
+func WithVersion(version int) EventOption {
+	return func(e Event) {
+		if evt, ok := e.(*event); ok {
+			evt.version = version
+		}
+	}
+}
+
+// CloneEvent creates a copy of an event, with the data and metadata deep copied
+// so that the copy can be changed without affecting the original event. Any
+// options are applied to the copy, for example to override the metadata,
+// version or timestamp. Useful when enriching or migrating events.
+//
+// The data is copied using reflection, exported and unexported fields alike.
+// Channels and functions are not copied, and cyclic data is not supported.
+func CloneEvent(e Event, options ...EventOption) Event {
+	var metadata map[string]interface{}
+	if md := e.Metadata(); md != nil {
+		metadata, _ = deepCopy(md).(map[string]interface{})
+	}
+
+	clone := &event{
+		eventType:     e.EventType(),
+		data:          deepCopy(e.Data()),
+		timestamp:     e.Timestamp(),
+		aggregateType: e.AggregateType(),
+		aggregateID:   e.AggregateID(),
+		version:       e.Version(),
+		metadata:      metadata,
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(clone)
+	}
+
+	return clone
+}
+
+// CloneCommand creates a deep copy of a command, so that the copy can be
+// changed without affecting the original command. See CloneEvent for how the
+// command is copied.
+func CloneCommand(cmd Command) Command {
+	if c, ok := deepCopy(cmd).(Command); ok {
+		return c
+	}
+
+	return cmd
+}
+
+// deepCopy copies a value using reflection.
+func deepCopy(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	original := reflect.ValueOf(v)
+	clone := reflect.New(original.Type()).Elem()
+	copyValue(clone, original)
+
+	return clone.Interface()
+}
+
+// copyValue recursively copies the original value into the settable clone.
+func copyValue(clone, original reflect.Value) {
+	switch original.Kind() {
+	case reflect.Ptr:
+		if original.IsNil() {
+			return
+		}
+
+		c := reflect.New(original.Elem().Type())
+		copyValue(c.Elem(), original.Elem())
+		clone.Set(c)
+	case reflect.Interface:
+		if original.IsNil() {
+			return
+		}
+
+		c := reflect.New(original.Elem().Type()).Elem()
+		copyValue(c, original.Elem())
+		clone.Set(c)
+	case reflect.Struct:
+		// Copy all fields, including unexported, then deep copy the fields
+		// that can be set.
+		clone.Set(original)
+
+		for i := 0; i < original.NumField(); i++ {
+			if clone.Field(i).CanSet() {
+				copyValue(clone.Field(i), original.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if original.IsNil() {
+			return
+		}
+
+		c := reflect.MakeSlice(original.Type(), original.Len(), original.Len())
+		for i := 0; i < original.Len(); i++ {
+			copyValue(c.Index(i), original.Index(i))
+		}
+
+		clone.Set(c)
+	case reflect.Array:
+		for i := 0; i < original.Len(); i++ {
+			copyValue(clone.Index(i), original.Index(i))
+		}
+	case reflect.Map:
+		if original.IsNil() {
+			return
+		}
+
+		c := reflect.MakeMapWithSize(original.Type(), original.Len())
+		iter := original.MapRange()
+		for iter.Next() {
+			v := reflect.New(iter.Value().Type()).Elem()
+			copyValue(v, iter.Value())
+			c.SetMapIndex(iter.Key(), v)
+		}
+
+		clone.Set(c)
+	default:
+		clone.Set(original)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestCloneEvent(t *testing.T) {
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	data := &cloneTestData{
+		Items:  []string{"a", "b"},
+		Nested: &cloneTestNested{Content: "nested"},
+		Tags:   map[string]int{"tag": 1},
+		At:     timestamp,
+		secret: "secret",
+	}
+	event := NewEvent(TestEventType, data, timestamp,
+		ForAggregate(TestAggregateType, id, 3),
+		WithMetadata(map[string]interface{}{
+			"meta": "data",
+			"list": []interface{}{"a"},
+		}),
+	)
+
+	clone := CloneEvent(event)
+	if err := CompareEvents(clone, event); err != nil {
+		t.Error("the clone should be equal:", err)
+	}
+
+	// Mutating the clone should not affect the original.
+	clone.Metadata()["meta"] = "changed"
+	clone.Metadata()["list"].([]interface{})[0] = "changed"
+
+	if event.Metadata()["meta"] != "data" || event.Metadata()["list"].([]interface{})[0] != "a" {
+		t.Error("the original metadata should not change:", event.Metadata())
+	}
+
+	cloneData, ok := clone.Data().(*cloneTestData)
+	if !ok {
+		t.Fatal("the data should be of correct type:", clone.Data())
+	}
+
+	if cloneData == data || cloneData.Nested == data.Nested {
+		t.Error("the data should be copied")
+	}
+
+	if cloneData.secret != "secret" {
+		t.Error("the unexported fields should be copied:", cloneData.secret)
+	}
+
+	cloneData.Items[0] = "changed"
+	cloneData.Nested.Content = "changed"
+	cloneData.Tags["tag"] = 2
+
+	expected := &cloneTestData{
+		Items:  []string{"a", "b"},
+		Nested: &cloneTestNested{Content: "nested"},
+		Tags:   map[string]int{"tag": 1},
+		At:     timestamp,
+		secret: "secret",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Error("the original data should not change:", data)
+	}
+}
+
+func TestCloneEvent_Options(t *testing.T) {
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := NewEvent(TestEventType, &TestEventData{"event"}, timestamp,
+		ForAggregate(TestAggregateType, id, 3),
+		WithMetadata(map[string]interface{}{"meta": "data"}),
+	)
+
+	clone := CloneEvent(event,
+		WithVersion(4),
+		WithTimestamp(timestamp.Add(time.Second)),
+		WithMetadata(map[string]interface{}{"meta": "enriched", "other": "value"}),
+	)
+
+	if clone.Version() != 4 || event.Version() != 3 {
+		t.Error("the version should be overridden:", clone.Version())
+	}
+
+	if !clone.Timestamp().Equal(timestamp.Add(time.Second)) || !event.Timestamp().Equal(timestamp) {
+		t.Error("the timestamp should be overridden:", clone.Timestamp())
+	}
+
+	if !reflect.DeepEqual(clone.Metadata(), map[string]interface{}{"meta": "enriched", "other": "value"}) {
+		t.Error("the metadata should be overridden:", clone.Metadata())
+	}
+
+	if !reflect.DeepEqual(event.Metadata(), map[string]interface{}{"meta": "data"}) {
+		t.Error("the original metadata should not change:", event.Metadata())
+	}
+
+	// Events without data and metadata.
+	event = NewEvent(TestEventType, nil, timestamp)
+	if err := CompareEvents(CloneEvent(event), event); err != nil {
+		t.Error("the clone should be equal:", err)
+	}
+}
+
+func TestCloneCommand(t *testing.T) {
+	cmd := &cloneTestCommand{ID: uuid.New(), Items: []string{"a"}}
+
+	clone, ok := CloneCommand(cmd).(*cloneTestCommand)
+	if !ok {
+		t.Fatal("the clone should be of correct type")
+	}
+
+	if !reflect.DeepEqual(clone, cmd) {
+		t.Error("the clone should be equal:", clone)
+	}
+
+	clone.Items[0] = "changed"
+
+	if cmd.Items[0] != "a" {
+		t.Error("the original command should not change:", cmd.Items)
+	}
+}
+
+type cloneTestData struct {
+	Items  []string
+	Nested *cloneTestNested
+	Tags   map[string]int
+	At     time.Time
+	secret string
+}
+
+type cloneTestNested struct {
+	Content string
+}
+
+type cloneTestCommand struct {
+	ID    uuid.UUID
+	Items []string
+}
+
+func (c *cloneTestCommand) AggregateID() uuid.UUID       { return c.ID }
+func (c *cloneTestCommand) AggregateType() AggregateType { return TestAggregateType }
+func (c *cloneTestCommand) CommandType() CommandType     { return "CloneTestCommand" }
@@ -14,7 +14,11 @@
 
 package eventhorizon
 
-import "context"
+import (
+	"context"
+
+	"github.com/looplab/eventhorizon/uuid"
+)
 
 // EventCodec is a codec for marshaling and unmarshaling events to and from bytes.
 type EventCodec interface {
@@ -31,3 +35,24 @@ type CommandCodec interface {
 	// UnmarshalCommand unmarshals a command and supported parts of context from bytes.
 	UnmarshalCommand(context.Context, []byte) (Command, context.Context, error)
 }
+
+// SnapshotCodec is a codec for marshaling and unmarshaling snapshots of
+// aggregates to and from bytes.
+type SnapshotCodec interface {
+	// MarshalSnapshot marshals a snapshot of the aggregate into bytes.
+	MarshalSnapshot(ctx context.Context, id uuid.UUID, snapshot Snapshot) ([]byte, error)
+	// UnmarshalSnapshot unmarshals a snapshot and the ID of its aggregate from
+	// bytes. The state is created using the snapshot data registered for the
+	// aggregate type.
+	UnmarshalSnapshot(ctx context.Context, b []byte) (uuid.UUID, *Snapshot, error)
+}
+
+// EntityCodec is a codec for marshaling and unmarshaling entities of read
+// repositories to and from bytes, to control the format used when storing them.
+type EntityCodec interface {
+	// MarshalEntity marshals an entity into bytes.
+	MarshalEntity(context.Context, Entity) ([]byte, error)
+	// UnmarshalEntity unmarshals bytes into an entity, which is created by the
+	// entity factory of the repo.
+	UnmarshalEntity(context.Context, []byte, Entity) error
+}
@@ -16,6 +16,7 @@ package codec
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -29,6 +30,8 @@ func init() {
 	eh.RegisterEventData(EventType, func() eh.EventData { return &EventData{} })
 
 	eh.RegisterCommand(func() eh.Command { return &Command{} })
+
+	eh.RegisterSnapshotData(AggregateType, func(id uuid.UUID) eh.SnapshotData { return &SnapshotState{} })
 }
 
 const (
@@ -125,6 +128,95 @@ type Nested struct {
 	Number float64
 }
 
+// SnapshotCodecAcceptanceTest is the acceptance test that all implementations
+// of SnapshotCodec should pass. It should manually be called from a test case in
+// each implementation:
+//
+//   func TestSnapshotCodec(t *testing.T) {
+//       c := EventCodec{}
+//       codec.SnapshotCodecAcceptanceTest(t, c)
+//   }
+//
+func SnapshotCodecAcceptanceTest(t *testing.T, c eh.SnapshotCodec) {
+	ctx := context.Background()
+	id := uuid.MustParse("10a7ec0f-7f2b-46f5-bca1-877b6e33c9fd")
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	snapshot := eh.Snapshot{
+		Version:       3,
+		AggregateType: AggregateType,
+		Timestamp:     timestamp,
+		State: &SnapshotState{
+			Name: "name",
+			Items: []Nested{
+				{Bool: true, String: "a", Number: 1.0},
+				{String: "b", Number: 2.0},
+			},
+			Counts: map[string]int{"key": 42},
+			Nested: &Nested{
+				Bool:   true,
+				String: "string",
+				Number: 42.0,
+			},
+			Time: timestamp,
+		},
+	}
+
+	// Round trip with nested state.
+	b, err := c.MarshalSnapshot(ctx, id, snapshot)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	decodedID, decodedSnapshot, err := c.UnmarshalSnapshot(ctx, b)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if decodedID != id {
+		t.Error("the decoded aggregate ID should be correct:", decodedID)
+	}
+
+	if !reflect.DeepEqual(decodedSnapshot, &snapshot) {
+		t.Error("the decoded snapshot was incorrect:", decodedSnapshot)
+	}
+
+	// Round trip without state.
+	snapshot.State = nil
+
+	if b, err = c.MarshalSnapshot(ctx, id, snapshot); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if _, decodedSnapshot, err = c.UnmarshalSnapshot(ctx, b); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(decodedSnapshot, &snapshot) {
+		t.Error("the decoded snapshot was incorrect:", decodedSnapshot)
+	}
+
+	// Unregistered snapshot state.
+	snapshot.AggregateType = mocks.AggregateType
+	snapshot.State = &SnapshotState{Name: "name"}
+
+	if b, err = c.MarshalSnapshot(ctx, id, snapshot); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if _, _, err = c.UnmarshalSnapshot(ctx, b); !errors.Is(err, eh.ErrSnapshotDataNotRegistered) {
+		t.Error("there should be a snapshot data not registered error:", err)
+	}
+}
+
+// SnapshotState is a mocked snapshot state, useful in testing.
+type SnapshotState struct {
+	Name   string
+	Items  []Nested
+	Counts map[string]int
+	Nested *Nested
+	Time   time.Time
+}
+
 // CommandCodecAcceptanceTest is the acceptance test that all implementations of
 // CommandCodec should pass. It should manually be called from a test case in each
 // implementation:
@@ -34,6 +34,10 @@ func (_ CommandCodec) MarshalCommand(ctx context.Context, cmd eh.Command) ([]byt
 		Context:     eh.MarshalContext(ctx),
 	}
 
+	if err := eh.CheckContextValues(c.Context); err != nil {
+		return nil, fmt.Errorf("could not marshal context: %w", err)
+	}
+
 	var err error
 	if c.Command, err = bson.Marshal(cmd); err != nil {
 		return nil, fmt.Errorf("could not marshal command data: %w", err)
@@ -20,14 +20,74 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
 
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/uuid"
 )
 
 // EventCodec is a codec for marshaling and unmarshaling events
-// to and from bytes in BSON format.
-type EventCodec struct{}
+// to and from bytes in BSON format. The event data is marshaled as a nested
+// BSON document in the "data" field, which can be queried when stored in
+// MongoDB, and unmarshaled into the event data registered for the event type.
+//
+// By default events without data (a nil Data()) are unmarshaled without data,
+// while events with empty data (for example an empty struct) are unmarshaled
+// with the registered data, see WithAlwaysCreateEventData to change this.
+//
+// Empty metadata and context are omitted from the document, and are
+// unmarshaled as nil.
+type EventCodec struct {
+	// registry is used for the event data, the default registry is used if nil.
+	registry *bsoncodec.Registry
+	// alwaysCreateData creates the registered event data also for events
+	// without data.
+	alwaysCreateData bool
+}
+
+// NewEventCodec creates a new EventCodec, the zero value of EventCodec can also
+// be used directly when no options are needed.
+func NewEventCodec(options ...Option) *EventCodec {
+	c := &EventCodec{}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(c)
+	}
+
+	return c
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*EventCodec)
+
+// WithTimeAsNanos marshals all time.Time fields in the event data as int64
+// nanoseconds since the Unix epoch instead of as BSON datetimes, which only has
+// millisecond precision. Zero times are marshaled as null. Unmarshaling also
+// accepts BSON datetimes, to be able to read events stored before the option was
+// used. The timestamp of the event itself is not affected.
+//
+// Note that the field is a plain integer in the BSON document, which consumers
+// in other languages (or queries in MongoDB) must know to convert, and that
+// only times between the years 1678 and 2262 can be represented.
+func WithTimeAsNanos() Option {
+	return func(c *EventCodec) {
+		c.registry = timeAsNanosRegistry
+	}
+}
+
+// WithAlwaysCreateEventData unmarshals events without data into the event data
+// registered for the event type, with all fields empty, instead of leaving the
+// event data nil. Event types without registered event data are unmarshaled
+// without data.
+func WithAlwaysCreateEventData() Option {
+	return func(c *EventCodec) {
+		c.alwaysCreateData = true
+	}
+}
 
 // MarshalEvent marshals an event into bytes in BSON format.
 func (c *EventCodec) MarshalEvent(ctx context.Context, event eh.Event) ([]byte, error) {
@@ -41,10 +101,14 @@ func (c *EventCodec) MarshalEvent(ctx context.Context, event eh.Event) ([]byte,
 		Context:       eh.MarshalContext(ctx),
 	}
 
+	if err := eh.CheckContextValues(e.Context); err != nil {
+		return nil, fmt.Errorf("could not marshal context: %w", err)
+	}
+
 	// Marshal event data if there is any.
 	if event.Data() != nil {
 		var err error
-		if e.RawData, err = bson.Marshal(event.Data()); err != nil {
+		if e.RawData, err = bson.MarshalWithRegistry(c.dataRegistry(), event.Data()); err != nil {
 			return nil, fmt.Errorf("could not marshal event data: %w", err)
 		}
 	}
@@ -73,11 +137,14 @@ func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, co
 			return nil, nil, fmt.Errorf("could not create event data: %w", err)
 		}
 
-		if err := bson.Unmarshal(e.RawData, e.data); err != nil {
+		if err := bson.UnmarshalWithRegistry(c.dataRegistry(), e.RawData, e.data); err != nil {
 			return nil, nil, fmt.Errorf("could not unmarshal event data: %w", err)
 		}
 
 		e.RawData = nil
+	} else if c.alwaysCreateData {
+		// Ignore unregistered event data, the event is without data.
+		e.data, _ = eh.CreateEventData(e.EventType)
 	}
 
 	// Build the event.
@@ -87,7 +154,7 @@ func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, co
 	}
 
 	event := eh.NewEvent(
-		e.EventType,
+		eh.ResolveEventType(e.EventType),
 		e.data,
 		e.Timestamp,
 		eh.ForAggregate(
@@ -104,6 +171,14 @@ func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, co
 	return event, ctx, nil
 }
 
+func (c *EventCodec) dataRegistry() *bsoncodec.Registry {
+	if c.registry == nil {
+		return bson.DefaultRegistry
+	}
+
+	return c.registry
+}
+
 // evt is the internal event used on the wire only.
 type evt struct {
 	EventType     eh.EventType           `bson:"event_type"`
@@ -113,6 +188,6 @@ type evt struct {
 	AggregateType eh.AggregateType       `bson:"aggregate_type"`
 	AggregateID   string                 `bson:"_id"`
 	Version       int                    `bson:"version"`
-	Metadata      map[string]interface{} `bson:"metadata"`
-	Context       map[string]interface{} `bson:"context"`
+	Metadata      map[string]interface{} `bson:"metadata,omitempty"`
+	Context       map[string]interface{} `bson:"context,omitempty"`
 }
@@ -15,10 +15,18 @@
 package bson
 
 import (
+	"context"
 	"encoding/base64"
+	"reflect"
 	"testing"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/codec"
+	"github.com/looplab/eventhorizon/uuid"
 )
 
 func TestEventCodec(t *testing.T) {
@@ -31,3 +39,199 @@ func TestEventCodec(t *testing.T) {
 
 	codec.EventCodecAcceptanceTest(t, c, expectedBytes)
 }
+
+func TestEventCodec_TimeAsNanos(t *testing.T) {
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 123456000, time.UTC)
+	data := &TimeEventData{
+		Time:    timestamp,
+		TimeRef: &timestamp,
+	}
+	event := eh.NewEvent(TimeEventType, data, timestamp,
+		eh.ForAggregate("Aggregate", uuid.New(), 1))
+
+	c := NewEventCodec(WithTimeAsNanos())
+
+	b, err := c.MarshalEvent(ctx, event)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	decodedEvent, _, err := c.UnmarshalEvent(ctx, b)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	decoded, ok := decodedEvent.Data().(*TimeEventData)
+	if !ok {
+		t.Fatal("the event data should be correct:", decodedEvent.Data())
+	}
+
+	if !decoded.Time.Equal(timestamp) {
+		t.Error("the time should be correct:", decoded.Time)
+	}
+
+	if decoded.TimeRef == nil || !decoded.TimeRef.Equal(timestamp) {
+		t.Error("the time ref should be correct:", decoded.TimeRef)
+	}
+
+	if !decoded.NullTime.IsZero() {
+		t.Error("the null time should be zero:", decoded.NullTime)
+	}
+
+	// The default codec only has millisecond precision.
+	b, err = (&EventCodec{}).MarshalEvent(ctx, event)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Events marshaled with datetimes can still be unmarshaled.
+	decodedEvent, _, err = c.UnmarshalEvent(ctx, b)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	decoded, ok = decodedEvent.Data().(*TimeEventData)
+	if !ok {
+		t.Fatal("the event data should be correct:", decodedEvent.Data())
+	}
+
+	if !decoded.Time.Equal(timestamp.Truncate(time.Millisecond)) {
+		t.Error("the time should be correct:", decoded.Time)
+	}
+}
+
+func TestEventCodec_AlwaysCreateEventData(t *testing.T) {
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		event    eh.Event
+		options  []Option
+		expected eh.EventData
+	}{
+		"without data": {
+			eh.NewEvent(EmptyEventType, nil, timestamp,
+				eh.ForAggregate("Aggregate", uuid.New(), 1)),
+			nil,
+			nil,
+		},
+		"empty data": {
+			eh.NewEvent(EmptyEventType, &EmptyEventData{}, timestamp,
+				eh.ForAggregate("Aggregate", uuid.New(), 1)),
+			nil,
+			&EmptyEventData{},
+		},
+		"always created without data": {
+			eh.NewEvent(EmptyEventType, nil, timestamp,
+				eh.ForAggregate("Aggregate", uuid.New(), 1)),
+			[]Option{WithAlwaysCreateEventData()},
+			&EmptyEventData{},
+		},
+		"always created with empty data": {
+			eh.NewEvent(EmptyEventType, &EmptyEventData{}, timestamp,
+				eh.ForAggregate("Aggregate", uuid.New(), 1)),
+			[]Option{WithAlwaysCreateEventData()},
+			&EmptyEventData{},
+		},
+		"always created unregistered": {
+			eh.NewEvent("Unregistered", nil, timestamp,
+				eh.ForAggregate("Aggregate", uuid.New(), 1)),
+			[]Option{WithAlwaysCreateEventData()},
+			nil,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := NewEventCodec(tc.options...)
+
+			b, err := c.MarshalEvent(ctx, tc.event)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			decoded, _, err := c.UnmarshalEvent(ctx, b)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			if !reflect.DeepEqual(decoded.Data(), tc.expected) {
+				t.Errorf("the event data should be correct: %#v", decoded.Data())
+			}
+		})
+	}
+}
+
+func TestEventCodec_OmitEmpty(t *testing.T) {
+	ctx := context.Background()
+	c := &EventCodec{}
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := eh.NewEvent(EmptyEventType, nil, timestamp,
+		eh.ForAggregate("Aggregate", id, 1))
+
+	b, err := c.MarshalEvent(ctx, event)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := bson.M{
+		"event_type":     string(EmptyEventType),
+		"timestamp":      primitive.NewDateTimeFromTime(timestamp),
+		"aggregate_type": "Aggregate",
+		"_id":            id.String(),
+		"version":        int32(1),
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("the document should be minimal: %#v", doc)
+	}
+
+	decoded, decodedCtx, err := c.UnmarshalEvent(ctx, b)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := eh.CompareEvents(decoded, event); err != nil {
+		t.Error("the event should be correct:", err)
+	}
+
+	if decoded.Metadata() != nil {
+		t.Error("the metadata should be nil:", decoded.Metadata())
+	}
+
+	if decodedCtx != ctx {
+		t.Error("the context should not be changed")
+	}
+}
+
+func init() {
+	eh.RegisterEventData(TimeEventType, func() eh.EventData {
+		return &TimeEventData{}
+	})
+	eh.RegisterEventData(EmptyEventType, func() eh.EventData {
+		return &EmptyEventData{}
+	})
+}
+
+// EmptyEventType is an event with data without fields.
+const EmptyEventType eh.EventType = "EmptyEvent"
+
+// EmptyEventData is event data without fields.
+type EmptyEventData struct{}
+
+// TimeEventType is an event with times in the data.
+const TimeEventType eh.EventType = "TimeEvent"
+
+// TimeEventData is event data with times.
+type TimeEventData struct {
+	Time     time.Time  `bson:"time"`
+	TimeRef  *time.Time `bson:"timeref"`
+	NullTime time.Time  `bson:"nulltime"`
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// MarshalSnapshot marshals a snapshot into bytes in BSON format, in the same way
+// as the data of events.
+func (c *EventCodec) MarshalSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) ([]byte, error) {
+	s := snap{
+		AggregateType: snapshot.AggregateType,
+		AggregateID:   id.String(),
+		Version:       snapshot.Version,
+		Timestamp:     snapshot.Timestamp,
+	}
+
+	// Marshal the state if there is any.
+	if snapshot.State != nil {
+		var err error
+		if s.RawState, err = bson.MarshalWithRegistry(c.dataRegistry(), snapshot.State); err != nil {
+			return nil, fmt.Errorf("could not marshal snapshot state: %w", err)
+		}
+	}
+
+	b, err := bson.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal snapshot: %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalSnapshot unmarshals a snapshot from bytes in BSON format, the state is
+// unmarshaled into the snapshot data registered for the aggregate type.
+func (c *EventCodec) UnmarshalSnapshot(ctx context.Context, b []byte) (uuid.UUID, *eh.Snapshot, error) {
+	var s snap
+	if err := bson.Unmarshal(b, &s); err != nil {
+		return uuid.Nil, nil, fmt.Errorf("could not unmarshal snapshot: %w", err)
+	}
+
+	id, err := uuid.Parse(s.AggregateID)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("could not parse aggregate ID: %w", err)
+	}
+
+	snapshot := &eh.Snapshot{
+		AggregateType: s.AggregateType,
+		Version:       s.Version,
+		Timestamp:     s.Timestamp,
+	}
+
+	// Create the state of the correct type and decode from raw BSON.
+	if len(s.RawState) > 0 {
+		state, err := eh.CreateSnapshotData(id, s.AggregateType)
+		if err != nil {
+			return uuid.Nil, nil, fmt.Errorf("could not create snapshot state: %w", err)
+		}
+
+		if err := bson.UnmarshalWithRegistry(c.dataRegistry(), s.RawState, state); err != nil {
+			return uuid.Nil, nil, fmt.Errorf("could not unmarshal snapshot state: %w", err)
+		}
+
+		snapshot.State = state
+	}
+
+	return id, snapshot, nil
+}
+
+// snap is the internal snapshot used on the wire only.
+type snap struct {
+	AggregateType eh.AggregateType `bson:"aggregate_type"`
+	AggregateID   string           `bson:"aggregate_id"`
+	Version       int              `bson:"version"`
+	Timestamp     time.Time        `bson:"timestamp"`
+	RawState      bson.Raw         `bson:"state,omitempty"`
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// The range of times that can be represented as int64 nanoseconds.
+var (
+	minNanosTime = time.Unix(0, math.MinInt64)
+	maxNanosTime = time.Unix(0, math.MaxInt64)
+)
+
+// timeAsNanosRegistry is a registry with the UUID codecs that handles time.Time
+// types as int64 nanoseconds, used by WithTimeAsNanos.
+var timeAsNanosRegistry = newTimeAsNanosRegistry()
+
+func newTimeAsNanosRegistry() *bsoncodec.Registry {
+	rb := newRegistryBuilder()
+	timeType := reflect.TypeOf(time.Time{})
+
+	rb.RegisterTypeEncoder(timeType, bsoncodec.ValueEncoderFunc(
+		func(ec bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+			if !val.IsValid() || val.Type() != timeType {
+				return bsoncodec.ValueEncoderError{
+					Name:     "time.Time",
+					Types:    []reflect.Type{timeType},
+					Received: val,
+				}
+			}
+
+			t := val.Interface().(time.Time)
+			if t.IsZero() {
+				return vw.WriteNull()
+			}
+
+			if t.Before(minNanosTime) || t.After(maxNanosTime) {
+				return fmt.Errorf("time out of range for nanoseconds: %s", t)
+			}
+
+			return vw.WriteInt64(t.UnixNano())
+		},
+	))
+
+	rb.RegisterTypeDecoder(timeType, bsoncodec.ValueDecoderFunc(
+		func(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+			if !val.IsValid() || !val.CanSet() || val.Type() != timeType {
+				return bsoncodec.ValueDecoderError{
+					Name:     "time.Time",
+					Types:    []reflect.Type{timeType},
+					Received: val,
+				}
+			}
+
+			var t time.Time
+			switch vr.Type() {
+			case bsontype.Int64:
+				ns, err := vr.ReadInt64()
+				if err != nil {
+					return err
+				}
+
+				t = time.Unix(0, ns).UTC()
+			case bsontype.DateTime:
+				ms, err := vr.ReadDateTime()
+				if err != nil {
+					return err
+				}
+
+				t = time.Unix(ms/1e3, ms%1e3*1e6).UTC()
+			case bsontype.Null:
+				if err := vr.ReadNull(); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("received invalid BSON type to decode into time: %s", vr.Type())
+			}
+
+			val.Set(reflect.ValueOf(t))
+
+			return nil
+		},
+	))
+
+	return rb.Build()
+}
@@ -13,6 +13,12 @@ import (
 
 // Update the default BSON registry to be able to handle UUID types as strings.
 func init() {
+	bson.DefaultRegistry = newRegistryBuilder().Build()
+}
+
+// newRegistryBuilder creates a registry builder with the default codecs and
+// the UUID codecs.
+func newRegistryBuilder() *bsoncodec.RegistryBuilder {
 	rb := bson.NewRegistryBuilder()
 	id := uuid.Nil
 	uuidType := reflect.TypeOf(id)
@@ -73,5 +79,5 @@ func init() {
 		},
 	))
 
-	bson.DefaultRegistry = rb.Build()
+	return rb
 }
@@ -0,0 +1,161 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cbor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// uuidTag is the CBOR tag number for UUIDs.
+const uuidTag = 37
+
+var (
+	encMode cbor.EncMode
+	decMode cbor.DecMode
+)
+
+func init() {
+	tags := cbor.NewTagSet()
+	if err := tags.Add(cbor.TagOptions{EncTag: cbor.EncTagRequired, DecTag: cbor.DecTagOptional},
+		reflect.TypeOf(uuid.Nil), uuidTag); err != nil {
+		panic(fmt.Sprintf("eventhorizon: could not add UUID tag: %s", err))
+	}
+
+	var err error
+	if encMode, err = (cbor.EncOptions{
+		Time:    cbor.TimeRFC3339Nano,
+		TimeTag: cbor.EncTagRequired,
+	}).EncModeWithTags(tags); err != nil {
+		panic(fmt.Sprintf("eventhorizon: could not create CBOR encoding mode: %s", err))
+	}
+
+	if decMode, err = (cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+	}).DecModeWithTags(tags); err != nil {
+		panic(fmt.Sprintf("eventhorizon: could not create CBOR decoding mode: %s", err))
+	}
+}
+
+// EventCodec is a codec for marshaling and unmarshaling events to and from
+// bytes in CBOR format (RFC 8949), a compact binary format for constrained
+// clients. The envelope mirrors the BSON codec, with the event data marshaled
+// as a nested CBOR map in the "data" field and unmarshaled into the event data
+// registered for the event type.
+//
+// Times are marshaled as RFC 3339 strings with nanoseconds (tag 0), as in the
+// JSON codec, and zero times as null. The aggregate ID is marshaled as a
+// string as in the other codecs, while UUIDs in the event data are marshaled as
+// 16 byte strings with the standard UUID tag 37. Struct fields are named as in
+// the JSON codec, also using any json tags.
+type EventCodec struct{}
+
+// MarshalEvent marshals an event into bytes in CBOR format.
+func (c *EventCodec) MarshalEvent(ctx context.Context, event eh.Event) ([]byte, error) {
+	e := evt{
+		EventType:     event.EventType(),
+		Timestamp:     event.Timestamp(),
+		AggregateType: event.AggregateType(),
+		AggregateID:   event.AggregateID().String(),
+		Version:       event.Version(),
+		Metadata:      event.Metadata(),
+		Context:       eh.MarshalContext(ctx),
+	}
+
+	if err := eh.CheckContextValues(e.Context); err != nil {
+		return nil, fmt.Errorf("could not marshal context: %w", err)
+	}
+
+	// Marshal event data if there is any.
+	if event.Data() != nil {
+		var err error
+		if e.RawData, err = encMode.Marshal(event.Data()); err != nil {
+			return nil, fmt.Errorf("could not marshal event data: %w", err)
+		}
+	}
+
+	b, err := encMode.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal event: %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalEvent unmarshals an event from bytes in CBOR format.
+func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, context.Context, error) {
+	// Decode the raw CBOR event data.
+	var e evt
+	if err := decMode.Unmarshal(b, &e); err != nil {
+		return nil, nil, fmt.Errorf("could not unmarshal event: %w", err)
+	}
+
+	// Create an event of the correct type and decode from raw CBOR.
+	if len(e.RawData) > 0 {
+		var err error
+		if e.data, err = eh.CreateEventData(e.EventType); err != nil {
+			return nil, nil, fmt.Errorf("could not create event data: %w", err)
+		}
+
+		if err := decMode.Unmarshal(e.RawData, e.data); err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshal event data: %w", err)
+		}
+
+		e.RawData = nil
+	}
+
+	// Build the event.
+	aggregateID, err := uuid.Parse(e.AggregateID)
+	if err != nil {
+		aggregateID = uuid.Nil
+	}
+
+	event := eh.NewEvent(
+		eh.ResolveEventType(e.EventType),
+		e.data,
+		e.Timestamp,
+		eh.ForAggregate(
+			e.AggregateType,
+			aggregateID,
+			e.Version,
+		),
+		eh.WithMetadata(e.Metadata),
+	)
+
+	// Unmarshal the context.
+	ctx = eh.UnmarshalContext(ctx, e.Context)
+
+	return event, ctx, nil
+}
+
+// evt is the internal event used on the wire only.
+type evt struct {
+	EventType     eh.EventType           `cbor:"event_type"`
+	RawData       cbor.RawMessage        `cbor:"data,omitempty"`
+	data          eh.EventData           `cbor:"-"`
+	Timestamp     time.Time              `cbor:"timestamp"`
+	AggregateType eh.AggregateType       `cbor:"aggregate_type"`
+	AggregateID   string                 `cbor:"aggregate_id"`
+	Version       int                    `cbor:"version"`
+	Metadata      map[string]interface{} `cbor:"metadata"`
+	Context       map[string]interface{} `cbor:"context"`
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cbor
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/codec"
+	"github.com/looplab/eventhorizon/codec/json"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestEventCodec(t *testing.T) {
+	c := &EventCodec{}
+
+	expectedBytes, err := base64.StdEncoding.DecodeString("qGpldmVudF90eXBlakNvZGVjRXZlbnRkZGF0YatkQm9vbPVmU3RyaW5nZnN0cmluZ2ZOdW1iZXL7QEUAAAAAAABlU2xpY2WCYWFhYmNNYXChY2tleWV2YWx1ZWRUaW1lwHQyMDA5LTExLTEwVDIzOjAwOjAwWmdUaW1lUmVmwHQyMDA5LTExLTEwVDIzOjAwOjAwWmhOdWxsVGltZfZmU3RydWN0o2RCb29s9WZTdHJpbmdmc3RyaW5nZk51bWJlcvtARQAAAAAAAGlTdHJ1Y3RSZWajZEJvb2z1ZlN0cmluZ2ZzdHJpbmdmTnVtYmVy+0BFAAAAAAAAak51bGxTdHJ1Y3T2aXRpbWVzdGFtcMB0MjAwOS0xMS0xMFQyMzowMDowMFpuYWdncmVnYXRlX3R5cGVpQWdncmVnYXRlbGFnZ3JlZ2F0ZV9pZHgkMTBhN2VjMGYtN2YyYi00NmY1LWJjYTEtODc3YjZlMzNjOWZkZ3ZlcnNpb24BaG1ldGFkYXRhoWNudW37QEUAAAAAAABnY29udGV4dKFrY29udGV4dF9vbmVndGVzdHZhbA==")
+	if err != nil {
+		t.Error("could not decode expected bytes:", err)
+	}
+
+	codec.EventCodecAcceptanceTest(t, c, expectedBytes)
+}
+
+func TestEventCodec_JSONEquivalence(t *testing.T) {
+	ctx := mocks.WithContextOne(context.Background(), "testval")
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 123456789, time.UTC)
+	id := uuid.New()
+
+	events := []eh.Event{
+		eh.NewEvent(UUIDEventType, &UUIDEventData{
+			ID:       uuid.New(),
+			IDs:      []uuid.UUID{uuid.New(), uuid.New()},
+			Time:     timestamp,
+			TimeRef:  &timestamp,
+			NullTime: nil,
+		}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 1),
+			eh.WithMetadata(map[string]interface{}{"correlation_id": "abc"})),
+		eh.NewEvent(UUIDEventType, nil, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 2)),
+	}
+
+	for _, event := range events {
+		b, err := (&EventCodec{}).MarshalEvent(ctx, event)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		decoded, decodedCtx, err := (&EventCodec{}).UnmarshalEvent(context.Background(), b)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		jb, err := (&json.EventCodec{}).MarshalEvent(ctx, event)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		jsonDecoded, jsonCtx, err := (&json.EventCodec{}).UnmarshalEvent(context.Background(), jb)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if err := eh.CompareEvents(decoded, jsonDecoded); err != nil {
+			t.Error("the decoded event should be the same as from the JSON codec:", err)
+		}
+
+		if err := eh.CompareEvents(decoded, event); err != nil {
+			t.Error("the decoded event should be correct:", err)
+		}
+
+		if val, ok := mocks.ContextOne(decodedCtx); !ok || val != "testval" {
+			t.Error("the decoded context should be correct:", decodedCtx)
+		}
+
+		if val, _ := mocks.ContextOne(jsonCtx); val != "testval" {
+			t.Error("the decoded JSON context should be correct:", jsonCtx)
+		}
+	}
+}
+
+func TestEventCodec_UUIDTag(t *testing.T) {
+	id := uuid.MustParse("10a7ec0f-7f2b-46f5-bca1-877b6e33c9fd")
+
+	b, err := encMode.Marshal(id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Tag 37 (0xd8 0x25) with a 16 byte string (0x50).
+	expected := append([]byte{0xd8, 0x25, 0x50}, id[:]...)
+	if string(b) != string(expected) {
+		t.Errorf("the UUID should be tagged: %x", b)
+	}
+
+	// Untagged UUIDs can also be unmarshaled.
+	var decoded uuid.UUID
+	if err := decMode.Unmarshal(expected[2:], &decoded); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if decoded != id {
+		t.Error("the UUID should be correct:", decoded)
+	}
+}
+
+func BenchmarkEventCodec_Size(b *testing.B) {
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := eh.NewEvent(UUIDEventType, &UUIDEventData{
+		ID:      uuid.New(),
+		IDs:     []uuid.UUID{uuid.New(), uuid.New()},
+		Time:    timestamp,
+		TimeRef: &timestamp,
+	}, timestamp, eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+
+	codecs := map[string]eh.EventCodec{
+		"CBOR": &EventCodec{},
+		"JSON": &json.EventCodec{},
+	}
+
+	for name, c := range codecs {
+		b.Run(name, func(b *testing.B) {
+			var size int
+
+			for i := 0; i < b.N; i++ {
+				data, err := c.MarshalEvent(ctx, event)
+				if err != nil {
+					b.Fatal("there should be no error:", err)
+				}
+
+				size = len(data)
+			}
+
+			b.ReportMetric(float64(size), "bytes/event")
+		})
+	}
+}
+
+func init() {
+	eh.RegisterEventData(UUIDEventType, func() eh.EventData {
+		return &UUIDEventData{}
+	})
+}
+
+// UUIDEventType is an event with UUIDs and times in the data.
+const UUIDEventType eh.EventType = "UUIDEvent"
+
+// UUIDEventData is event data with UUIDs and times.
+type UUIDEventData struct {
+	ID       uuid.UUID
+	IDs      []uuid.UUID
+	Time     time.Time
+	TimeRef  *time.Time
+	NullTime *time.Time
+}
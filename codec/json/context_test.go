@@ -0,0 +1,54 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func init() {
+	eh.RegisterContextMarshaler(func(ctx context.Context, vals map[string]interface{}) {
+		if v := ctx.Value(invalidContextKey); v != nil {
+			vals["invalid"] = v
+		}
+	})
+}
+
+type contextKey int
+
+const invalidContextKey contextKey = iota
+
+func TestCodecs_InvalidContextValue(t *testing.T) {
+	ctx := context.WithValue(context.Background(), invalidContextKey, struct{ A string }{"a"})
+	id := uuid.New()
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+	if _, err := (&EventCodec{}).MarshalEvent(ctx, event); !errors.Is(err, eh.ErrInvalidContextValue) {
+		t.Error("there should be an invalid context value error:", err)
+	}
+
+	cmd := &mocks.Command{ID: id, Content: "command"}
+	if _, err := (&CommandCodec{}).MarshalCommand(ctx, cmd); !errors.Is(err, eh.ErrInvalidContextValue) {
+		t.Error("there should be an invalid context value error:", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// EntityCodec is a codec for marshaling and unmarshaling entities
+// to and from bytes in JSON format.
+type EntityCodec struct{}
+
+// MarshalEntity marshals an entity into bytes in JSON format.
+func (_ EntityCodec) MarshalEntity(ctx context.Context, entity eh.Entity) ([]byte, error) {
+	b, err := json.Marshal(entity)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal entity: %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalEntity unmarshals an entity from bytes in JSON format.
+func (_ EntityCodec) UnmarshalEntity(ctx context.Context, b []byte, entity eh.Entity) error {
+	if err := json.Unmarshal(b, entity); err != nil {
+		return fmt.Errorf("could not unmarshal entity: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestEntityCodec(t *testing.T) {
+	c := EntityCodec{}
+	ctx := context.Background()
+
+	id := uuid.MustParse("10a7ec0f-7f2b-46f5-bca1-877b6e33c9fd")
+	entity := &mocks.Model{
+		ID:        id,
+		Version:   1,
+		Content:   "content",
+		CreatedAt: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+	}
+
+	b, err := c.MarshalEntity(ctx, entity)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	expected := `{"id":"10a7ec0f-7f2b-46f5-bca1-877b6e33c9fd","version":1,"content":"content","created_at":"2009-11-10T23:00:00Z"}`
+	if string(b) != expected {
+		t.Error("the encoded bytes should be correct:", string(b))
+	}
+
+	decoded := &mocks.Model{}
+	if err := c.UnmarshalEntity(ctx, b, decoded); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(decoded, entity) {
+		t.Error("the decoded entity should be correct:", decoded)
+	}
+
+	if err := c.UnmarshalEntity(ctx, []byte("invalid"), decoded); err == nil {
+		t.Error("there should be an error")
+	}
+}
@@ -40,6 +40,10 @@ func (c *EventCodec) MarshalEvent(ctx context.Context, event eh.Event) ([]byte,
 		Context:       eh.MarshalContext(ctx),
 	}
 
+	if err := eh.CheckContextValues(e.Context); err != nil {
+		return nil, fmt.Errorf("could not marshal context: %w", err)
+	}
+
 	// Marshal event data if there is any.
 	if event.Data() != nil {
 		var err error
@@ -86,7 +90,7 @@ func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, co
 	}
 
 	event := eh.NewEvent(
-		e.EventType,
+		eh.ResolveEventType(e.EventType),
 		e.data,
 		e.Timestamp,
 		eh.ForAggregate(
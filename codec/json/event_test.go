@@ -15,9 +15,11 @@
 package json
 
 import (
+	"context"
 	"strings"
 	"testing"
 
+	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/codec"
 )
 
@@ -50,3 +52,37 @@ func TestEventCodec(t *testing.T) {
 
 	codec.EventCodecAcceptanceTest(t, c, []byte(expectedBytes))
 }
+
+func TestEventCodec_Alias(t *testing.T) {
+	c := &EventCodec{}
+
+	// An event stored before the event type was renamed.
+	eh.RegisterEventTypeAlias("OldCodecEvent", codec.EventType)
+
+	b := []byte(`{
+		"event_type": "OldCodecEvent",
+		"data": { "String": "string", "Number": 42 },
+		"timestamp": "2009-11-10T23:00:00Z",
+		"aggregate_type": "Aggregate",
+		"aggregate_id": "10a7ec0f-7f2b-46f5-bca1-877b6e33c9fd",
+		"version": 1
+	}`)
+
+	event, _, err := c.UnmarshalEvent(context.Background(), b)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if event.EventType() != codec.EventType {
+		t.Error("the event type should be renamed:", event.EventType())
+	}
+
+	data, ok := event.Data().(*codec.EventData)
+	if !ok {
+		t.Fatalf("the event data should be of the renamed type: %T", event.Data())
+	}
+
+	if data.String != "string" || data.Number != 42 {
+		t.Error("the event data should be correct:", data)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"encoding/json"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// MarshalSnapshot marshals a snapshot into bytes in JSON format, in the same way
+// as the data of events.
+func (c *EventCodec) MarshalSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) ([]byte, error) {
+	s := snap{
+		AggregateType: snapshot.AggregateType,
+		AggregateID:   id.String(),
+		Version:       snapshot.Version,
+		Timestamp:     snapshot.Timestamp,
+	}
+
+	// Marshal the state if there is any.
+	if snapshot.State != nil {
+		var err error
+		if s.RawState, err = json.Marshal(snapshot.State); err != nil {
+			return nil, fmt.Errorf("could not marshal snapshot state: %w", err)
+		}
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal snapshot: %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalSnapshot unmarshals a snapshot from bytes in JSON format, the state is
+// unmarshaled into the snapshot data registered for the aggregate type.
+func (c *EventCodec) UnmarshalSnapshot(ctx context.Context, b []byte) (uuid.UUID, *eh.Snapshot, error) {
+	var s snap
+	if err := json.Unmarshal(b, &s); err != nil {
+		return uuid.Nil, nil, fmt.Errorf("could not unmarshal snapshot: %w", err)
+	}
+
+	id, err := uuid.Parse(s.AggregateID)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("could not parse aggregate ID: %w", err)
+	}
+
+	snapshot := &eh.Snapshot{
+		AggregateType: s.AggregateType,
+		Version:       s.Version,
+		Timestamp:     s.Timestamp,
+	}
+
+	// Create the state of the correct type and decode from raw JSON.
+	if len(s.RawState) > 0 {
+		state, err := eh.CreateSnapshotData(id, s.AggregateType)
+		if err != nil {
+			return uuid.Nil, nil, fmt.Errorf("could not create snapshot state: %w", err)
+		}
+
+		if err := json.Unmarshal(s.RawState, state); err != nil {
+			return uuid.Nil, nil, fmt.Errorf("could not unmarshal snapshot state: %w", err)
+		}
+
+		snapshot.State = state
+	}
+
+	return id, snapshot, nil
+}
+
+// snap is the internal snapshot used on the wire only.
+type snap struct {
+	AggregateType eh.AggregateType `json:"aggregate_type"`
+	AggregateID   string           `json:"aggregate_id"`
+	Version       int              `json:"version"`
+	Timestamp     time.Time        `json:"timestamp"`
+	RawState      json.RawMessage  `json:"state,omitempty"`
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+	jsoncodec "github.com/looplab/eventhorizon/codec/json"
+)
+
+var (
+	// ErrNilRegistry is when a codec is created without a registry.
+	ErrNilRegistry = errors.New("registry is nil")
+	// ErrInvalidPayload is when an event payload is not in the wire format
+	// of the schema registry.
+	ErrInvalidPayload = errors.New("invalid payload")
+)
+
+// The magic byte used as the first byte of the wire format.
+const magicByte = 0
+
+// The length of the header of the wire format: the magic byte and the
+// big-endian schema ID.
+const headerLen = 5
+
+// EventCodec is a codec for marshaling and unmarshaling events to and from
+// bytes in the wire format of Confluent compatible schema registries. Events are
+// encoded as JSON (the same as the JSON event codec), prefixed with a magic byte
+// and the ID of the JSON schema of the event type, allowing consumers in other
+// languages to decode them.
+//
+// Schemas are registered in the registry on the first marshaling of each event
+// type, and schemas of unknown IDs are fetched on unmarshaling. Note that the
+// payload is not validated against the schema.
+type EventCodec struct {
+	registry    Registry
+	codec       jsoncodec.EventCodec
+	subjectName func(eh.EventType) string
+	dataSchemas map[eh.EventType]json.RawMessage
+
+	ids      map[eh.EventType]int
+	knownIDs map[int]struct{}
+	mu       sync.RWMutex
+}
+
+var _ = eh.EventCodec(&EventCodec{})
+
+// NewEventCodec creates a new EventCodec using a schema registry.
+func NewEventCodec(registry Registry, options ...Option) (*EventCodec, error) {
+	if registry == nil {
+		return nil, ErrNilRegistry
+	}
+
+	c := &EventCodec{
+		registry: registry,
+		subjectName: func(t eh.EventType) string {
+			return t.String() + "-value"
+		},
+		dataSchemas: map[eh.EventType]json.RawMessage{},
+		ids:         map[eh.EventType]int{},
+		knownIDs:    map[int]struct{}{},
+	}
+
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*EventCodec) error
+
+// WithSubjectName sets the function used to get the subject to register the
+// schema of an event type under, the default is "<event type>-value".
+func WithSubjectName(f func(eh.EventType) string) Option {
+	return func(c *EventCodec) error {
+		c.subjectName = f
+
+		return nil
+	}
+}
+
+// WithDataSchema sets the JSON schema of the event data for an event type, used
+// in the schema of the event. The default allows any JSON object.
+func WithDataSchema(t eh.EventType, schema string) Option {
+	return func(c *EventCodec) error {
+		if !json.Valid([]byte(schema)) {
+			return fmt.Errorf("invalid data schema for %s", t)
+		}
+
+		c.dataSchemas[t] = json.RawMessage(schema)
+
+		return nil
+	}
+}
+
+// MarshalEvent marshals an event into bytes, prefixed by the ID of the schema.
+func (c *EventCodec) MarshalEvent(ctx context.Context, event eh.Event) ([]byte, error) {
+	id, err := c.schemaID(ctx, event.EventType())
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.codec.MarshalEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, headerLen, headerLen+len(b))
+	payload[0] = magicByte
+	binary.BigEndian.PutUint32(payload[1:headerLen], uint32(id))
+
+	return append(payload, b...), nil
+}
+
+// UnmarshalEvent unmarshals an event from bytes, fetching the schema if its ID
+// has not been seen before.
+func (c *EventCodec) UnmarshalEvent(ctx context.Context, b []byte) (eh.Event, context.Context, error) {
+	if len(b) < headerLen || b[0] != magicByte {
+		return nil, nil, ErrInvalidPayload
+	}
+
+	id := int(binary.BigEndian.Uint32(b[1:headerLen]))
+
+	c.mu.RLock()
+	_, ok := c.knownIDs[id]
+	c.mu.RUnlock()
+
+	if !ok {
+		if _, err := c.registry.Schema(ctx, id); err != nil {
+			return nil, nil, fmt.Errorf("could not get schema: %w", err)
+		}
+
+		c.mu.Lock()
+		c.knownIDs[id] = struct{}{}
+		c.mu.Unlock()
+	}
+
+	return c.codec.UnmarshalEvent(ctx, b[headerLen:])
+}
+
+// schemaID returns the schema ID for an event type, registering the schema
+// if it has not been done before.
+func (c *EventCodec) schemaID(ctx context.Context, t eh.EventType) (int, error) {
+	c.mu.RLock()
+	id, ok := c.ids[t]
+	c.mu.RUnlock()
+
+	if ok {
+		return id, nil
+	}
+
+	schema, err := c.schema(t)
+	if err != nil {
+		return 0, err
+	}
+
+	if id, err = c.registry.Register(ctx, c.subjectName(t), schema); err != nil {
+		return 0, fmt.Errorf("could not register schema: %w", err)
+	}
+
+	c.mu.Lock()
+	c.ids[t] = id
+	c.knownIDs[id] = struct{}{}
+	c.mu.Unlock()
+
+	return id, nil
+}
+
+// schema returns the JSON schema for an event type, describing the events as
+// encoded by the JSON event codec.
+func (c *EventCodec) schema(t eh.EventType) (string, error) {
+	dataSchema, ok := c.dataSchemas[t]
+	if !ok {
+		dataSchema = json.RawMessage(`{"type":"object"}`)
+	}
+
+	schema := map[string]interface{}{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title":   t.String(),
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"event_type":     map[string]interface{}{"const": t.String()},
+			"data":           dataSchema,
+			"timestamp":      map[string]interface{}{"type": "string", "format": "date-time"},
+			"aggregate_type": map[string]interface{}{"type": "string"},
+			"aggregate_id":   map[string]interface{}{"type": "string"},
+			"version":        map[string]interface{}{"type": "integer"},
+			"metadata":       map[string]interface{}{"type": []string{"object", "null"}},
+			"context":        map[string]interface{}{"type": []string{"object", "null"}},
+		},
+		"required": []string{"event_type", "timestamp", "aggregate_type", "aggregate_id", "version"},
+	}
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal schema: %w", err)
+	}
+
+	return string(b), nil
+}
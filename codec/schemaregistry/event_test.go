@@ -0,0 +1,191 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/codec"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestEventCodec(t *testing.T) {
+	r := newMockRegistry()
+	defer r.Close()
+
+	c, err := NewEventCodec(NewClient(r.URL))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expectedBytes := strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(`
+	{
+		"event_type": "CodecEvent",
+		"data": {
+		  "Bool": true,
+		  "String": "string",
+		  "Number": 42,
+		  "Slice": ["a", "b"],
+		  "Map": { "key": "value" },
+		  "Time": "2009-11-10T23:00:00Z",
+		  "TimeRef": "2009-11-10T23:00:00Z",
+		  "NullTime": null,
+		  "Struct": { "Bool": true, "String": "string", "Number": 42 },
+		  "StructRef": { "Bool": true, "String": "string", "Number": 42 },
+		  "NullStruct": null
+		},
+		"timestamp": "2009-11-10T23:00:00Z",
+		"aggregate_type": "Aggregate",
+		"aggregate_id": "10a7ec0f-7f2b-46f5-bca1-877b6e33c9fd",
+		"version": 1,
+		"metadata": { "num": 42 },
+		"context": { "context_one": "testval" }
+	}`, " ", ""), "\n", ""), "\t", "")
+
+	// The magic byte and the schema ID.
+	header := []byte{0, 0, 0, 0, 1}
+
+	codec.EventCodecAcceptanceTest(t, c, append(header, []byte(expectedBytes)...))
+}
+
+func TestEventCodec_RegisterOnMarshal(t *testing.T) {
+	r := newMockRegistry()
+	defer r.Close()
+
+	c, err := NewEventCodec(NewClient(r.URL),
+		WithDataSchema(codec.EventType, `{"type":"object","properties":{"String":{"type":"string"}}}`),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	event := eh.NewEvent(codec.EventType, &codec.EventData{String: "string"}, time.Now(),
+		eh.ForAggregate(codec.AggregateType, uuid.New(), 1))
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.MarshalEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	if r.registerRequests != 1 {
+		t.Error("the schema should be registered once:", r.registerRequests)
+	}
+
+	if len(r.schemas) != 1 {
+		t.Fatal("there should be one schema:", len(r.schemas))
+	}
+
+	var schema struct {
+		Title      string
+		Properties map[string]json.RawMessage
+	}
+	if err := json.Unmarshal([]byte(r.schemas[0]), &schema); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if schema.Title != codec.EventType.String() {
+		t.Error("the schema title should be correct:", schema.Title)
+	}
+
+	if string(schema.Properties["data"]) != `{"type":"object","properties":{"String":{"type":"string"}}}` {
+		t.Error("the data schema should be correct:", string(schema.Properties["data"]))
+	}
+
+	if _, err := NewEventCodec(NewClient(r.URL), WithDataSchema(codec.EventType, "not json")); err == nil {
+		t.Error("there should be an error for an invalid data schema")
+	}
+}
+
+func TestEventCodec_FetchOnUnmarshal(t *testing.T) {
+	r := newMockRegistry()
+	defer r.Close()
+
+	producer, err := NewEventCodec(NewClient(r.URL))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	consumer, err := NewEventCodec(NewClient(r.URL))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := mocks.WithContextOne(context.Background(), "testval")
+	event := eh.NewEvent(codec.EventType, &codec.EventData{String: "string"},
+		time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+		eh.ForAggregate(codec.AggregateType, uuid.New(), 1))
+
+	b, err := producer.MarshalEvent(ctx, event)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		decoded, decodedCtx, err := consumer.UnmarshalEvent(context.Background(), b)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if err := eh.CompareEvents(decoded, event); err != nil {
+			t.Error("the event should be correct:", err)
+		}
+
+		if val, ok := mocks.ContextOne(decodedCtx); !ok || val != "testval" {
+			t.Error("the context should be correct:", val)
+		}
+	}
+
+	if r.schemaRequests != 1 {
+		t.Error("the schema should be fetched once:", r.schemaRequests)
+	}
+}
+
+func TestEventCodec_UnknownSchema(t *testing.T) {
+	r := newMockRegistry()
+	defer r.Close()
+
+	c, err := NewEventCodec(NewClient(r.URL))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+
+	b := append([]byte{0, 0, 0, 0, 42}, []byte(`{"event_type":"CodecEvent"}`)...)
+	if _, _, err := c.UnmarshalEvent(ctx, b); !errors.Is(err, ErrSchemaNotFound) {
+		t.Error("there should be a schema not found error:", err)
+	}
+
+	if _, _, err := c.UnmarshalEvent(ctx, []byte(`{"event_type":"CodecEvent"}`)); !errors.Is(err, ErrInvalidPayload) {
+		t.Error("there should be an invalid payload error:", err)
+	}
+
+	if _, _, err := c.UnmarshalEvent(ctx, []byte{0, 0}); !errors.Is(err, ErrInvalidPayload) {
+		t.Error("there should be an invalid payload error:", err)
+	}
+
+	if _, err := NewEventCodec(nil); !errors.Is(err, ErrNilRegistry) {
+		t.Error("there should be a nil registry error:", err)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrSchemaNotFound is when a schema ID is not known by the registry.
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// Registry is a schema registry used to register and fetch schemas by ID.
+type Registry interface {
+	// Register registers a schema under a subject and returns its ID. Registering
+	// an already registered schema returns the existing ID.
+	Register(ctx context.Context, subject, schema string) (int, error)
+
+	// Schema returns a schema by its ID.
+	// Returns ErrSchemaNotFound if there is no such schema.
+	Schema(ctx context.Context, id int) (string, error)
+}
+
+// Client is a client for the REST API of a Confluent compatible schema
+// registry, only JSON schemas are supported.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+var _ = Registry(&Client{})
+
+// NewClient creates a new schema registry client for the registry at the URL.
+func NewClient(url string, options ...ClientOption) *Client {
+	c := &Client{
+		url:    strings.TrimRight(url, "/"),
+		client: http.DefaultClient,
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(c)
+	}
+
+	return c
+}
+
+// ClientOption is an option setter used to configure creation.
+type ClientOption func(*Client)
+
+// WithHTTPClient uses a custom HTTP client for the requests to the registry.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+// The content type used by the schema registry API.
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// The error code used by the registry when a schema is not found.
+const errorCodeSchemaNotFound = 40403
+
+type schemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+type registerResponse struct {
+	ID int `json:"id"`
+}
+
+type schemaResponse struct {
+	Schema string `json:"schema"`
+}
+
+type errorResponse struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// Register implements the Register method of the Registry interface.
+func (c *Client) Register(ctx context.Context, subject, schema string) (int, error) {
+	body, err := json.Marshal(schemaRequest{
+		Schema:     schema,
+		SchemaType: "JSON",
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not marshal schema: %w", err)
+	}
+
+	var res registerResponse
+	if err := c.do(ctx, "POST", "/subjects/"+url.PathEscape(subject)+"/versions", body, &res); err != nil {
+		return 0, fmt.Errorf("could not register schema: %w", err)
+	}
+
+	return res.ID, nil
+}
+
+// Schema implements the Schema method of the Registry interface.
+func (c *Client) Schema(ctx context.Context, id int) (string, error) {
+	var res schemaResponse
+	if err := c.do(ctx, "GET", "/schemas/ids/"+strconv.Itoa(id), nil, &res); err != nil {
+		return "", fmt.Errorf("could not fetch schema %d: %w", id, err)
+	}
+
+	return res.Schema, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Accept", contentType)
+
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e errorResponse
+		if err := json.Unmarshal(b, &e); err != nil || e.Message == "" {
+			e.Message = resp.Status
+		}
+
+		if e.ErrorCode == errorCodeSchemaNotFound {
+			return ErrSchemaNotFound
+		}
+
+		return fmt.Errorf("schema registry error %d: %s", e.ErrorCode, e.Message)
+	}
+
+	if err := json.Unmarshal(b, result); err != nil {
+		return fmt.Errorf("could not unmarshal response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	r := newMockRegistry()
+	defer r.Close()
+
+	c := NewClient(r.URL + "/")
+	ctx := context.Background()
+
+	id, err := c.Register(ctx, "subject", `{"type":"object"}`)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if id != 1 {
+		t.Error("the ID should be correct:", id)
+	}
+
+	// Registering the same schema again should give the same ID.
+	if id, err = c.Register(ctx, "other", `{"type":"object"}`); err != nil {
+		t.Fatal("there should be no error:", err)
+	} else if id != 1 {
+		t.Error("the ID should be correct:", id)
+	}
+
+	if id, err = c.Register(ctx, "subject", `{"type":"string"}`); err != nil {
+		t.Fatal("there should be no error:", err)
+	} else if id != 2 {
+		t.Error("the ID should be correct:", id)
+	}
+
+	schema, err := c.Schema(ctx, 2)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if schema != `{"type":"string"}` {
+		t.Error("the schema should be correct:", schema)
+	}
+
+	if _, err := c.Schema(ctx, 3); !errors.Is(err, ErrSchemaNotFound) {
+		t.Error("there should be a schema not found error:", err)
+	}
+
+	if _, err := c.Register(ctx, "subject", `not json`); err == nil || errors.Is(err, ErrSchemaNotFound) {
+		t.Error("there should be an error:", err)
+	}
+}
+
+// mockRegistry is a minimal in-memory schema registry server.
+type mockRegistry struct {
+	*httptest.Server
+
+	mu               sync.Mutex
+	schemas          []string
+	registerRequests int
+	schemaRequests   int
+}
+
+func newMockRegistry() *mockRegistry {
+	r := &mockRegistry{}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+
+	return r
+}
+
+func (r *mockRegistry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w.Header().Set("Content-Type", contentType)
+
+	switch {
+	case req.Method == "POST" && strings.HasPrefix(req.URL.Path, "/subjects/") &&
+		strings.HasSuffix(req.URL.Path, "/versions"):
+		r.registerRequests++
+
+		var s schemaRequest
+		if err := json.NewDecoder(req.Body).Decode(&s); err != nil ||
+			s.SchemaType != "JSON" || !json.Valid([]byte(s.Schema)) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error_code":42201,"message":"Invalid schema"}`))
+
+			return
+		}
+
+		for i, schema := range r.schemas {
+			if schema == s.Schema {
+				json.NewEncoder(w).Encode(registerResponse{ID: i + 1})
+
+				return
+			}
+		}
+
+		r.schemas = append(r.schemas, s.Schema)
+		json.NewEncoder(w).Encode(registerResponse{ID: len(r.schemas)})
+	case req.Method == "GET" && strings.HasPrefix(req.URL.Path, "/schemas/ids/"):
+		r.schemaRequests++
+
+		id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/schemas/ids/"))
+		if err != nil || id < 1 || id > len(r.schemas) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+
+			return
+		}
+
+		json.NewEncoder(w).Encode(schemaResponse{Schema: r.schemas[id-1]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error_code":404,"message":"HTTP 404 Not Found"}`))
+	}
+}
@@ -15,9 +15,11 @@
 package eventhorizon
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/looplab/eventhorizon/uuid"
 )
@@ -57,6 +59,29 @@ type CommandIDer interface {
 	CommandID() uuid.UUID
 }
 
+// LookupCommand is a command that can address its aggregate by a secondary key,
+// for example an order number, instead of the aggregate ID. A command handler
+// that loads aggregates looks up the ID of the aggregate before handling a
+// command that is missing it, see aggregate.WithLookupRepo. Note that the
+// AggregateID method still returns the missing ID for the looked up command.
+type LookupCommand interface {
+	Command
+
+	// Lookup returns the ID of the aggregate that the command should be handled
+	// by, typically by finding it in a read model indexed by the secondary key.
+	Lookup(ctx context.Context, repo ReadRepo) (uuid.UUID, error)
+}
+
+// Timeouter is a command that declares the timeout for handling it, for
+// commands that are expected to take a much longer or shorter time than others.
+// The command bus applies the timeout instead of its default, see
+// bus.WithDefaultTimeout.
+type Timeouter interface {
+	// Timeout returns the max duration of handling the command, or 0 to use the
+	// default timeout.
+	Timeout() time.Duration
+}
+
 // ErrCommandNotRegistered is when no command factory was registered.
 var ErrCommandNotRegistered = errors.New("command not registered")
 
@@ -64,7 +89,8 @@ var ErrCommandNotRegistered = errors.New("command not registered")
 // used to create concrete command types.
 //
 // An example would be:
-//     RegisterCommand(func() Command { return &MyCommand{} })
+//
+//	RegisterCommand(func() Command { return &MyCommand{} })
 func RegisterCommand(factory func() Command) {
 	// Check that the created command matches the type registered.
 	// TODO: Explore the use of reflect/gob for creating concrete types without
@@ -45,13 +45,14 @@ func (c *CommandFieldError) Error() string {
 	return "missing field: " + c.Field
 }
 
-// CheckCommand checks a command for errors.
+// CheckCommand checks a command for errors. A LookupCommand may be missing its
+// aggregate ID, which is then looked up when handling the command.
 func CheckCommand(cmd Command) error {
 	if cmd == nil {
 		return ErrMissingCommand
 	}
 
-	if cmd.AggregateID() == uuid.Nil {
+	if _, ok := cmd.(LookupCommand); !ok && cmd.AggregateID() == uuid.Nil {
 		return ErrMissingAggregateID
 	}
 
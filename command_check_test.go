@@ -15,6 +15,7 @@
 package eventhorizon
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -41,6 +42,12 @@ func TestCheckCommand(t *testing.T) {
 		t.Error("there should be a missing aggregate ID error:", err)
 	}
 
+	// Missing Aggregate ID that is looked up.
+	err = CheckCommand(&TestCommandLookup{Key: "key"})
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
 	// Missing required UUID value.
 	err = CheckCommand(&TestCommandUUIDValue{TestID: uuid.New()})
 	if err == nil || err.Error() != "missing field: Content" {
@@ -159,6 +166,22 @@ func (t TestCommandFields) CommandType() CommandType {
 	return CommandType("TestCommandFields")
 }
 
+type TestCommandLookup struct {
+	TestID uuid.UUID `eh:"optional"`
+	Key    string
+}
+
+var _ = LookupCommand(TestCommandLookup{})
+
+func (t TestCommandLookup) AggregateID() uuid.UUID       { return t.TestID }
+func (t TestCommandLookup) AggregateType() AggregateType { return AggregateType("Test") }
+func (t TestCommandLookup) CommandType() CommandType {
+	return CommandType("TestCommandLookup")
+}
+func (t TestCommandLookup) Lookup(ctx context.Context, repo ReadRepo) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+
 type TestCommandUUIDValue struct {
 	TestID  uuid.UUID
 	Content uuid.UUID
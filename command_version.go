@@ -0,0 +1,123 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnsupportedCommandVersion is when a command has a schema version that is
+// not supported.
+var ErrUnsupportedCommandVersion = errors.New("unsupported command version")
+
+// CommandVersions is an inclusive range of supported schema versions.
+type CommandVersions struct {
+	Min int
+	Max int
+}
+
+// Contains returns true if the version is in the range.
+func (v CommandVersions) Contains(version int) bool {
+	return version >= v.Min && version <= v.Max
+}
+
+// String implements the String method of the Stringer interface.
+func (v CommandVersions) String() string {
+	return fmt.Sprintf("%d-%d", v.Min, v.Max)
+}
+
+// CommandVersionError is an error for a command with an unsupported schema
+// version, with the supported versions.
+type CommandVersionError struct {
+	// CommandType is the type of the command.
+	CommandType CommandType
+	// Version is the unsupported version.
+	Version int
+	// Supported is the range of supported versions.
+	Supported CommandVersions
+}
+
+// Error implements the Error method of the errors.Error interface.
+func (e *CommandVersionError) Error() string {
+	return fmt.Sprintf("%s: %s v%d (supported: %s)",
+		ErrUnsupportedCommandVersion, e.CommandType, e.Version, e.Supported)
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *CommandVersionError) Unwrap() error {
+	return ErrUnsupportedCommandVersion
+}
+
+// RegisterCommandVersions registers the range of schema versions that are
+// supported for a command type, used to negotiate the version with clients
+// as the command evolves. The versions of command types without registered
+// versions are not checked.
+//
+// An example would be:
+//
+//	RegisterCommandVersions(MyCommandType, 1, 2)
+func RegisterCommandVersions(commandType CommandType, min, max int) {
+	if commandType == CommandType("") {
+		panic("eventhorizon: attempt to register versions for empty command type")
+	}
+
+	if min > max {
+		panic(fmt.Sprintf("eventhorizon: invalid versions for %q: %d-%d", commandType, min, max))
+	}
+
+	commandVersionsMu.Lock()
+	defer commandVersionsMu.Unlock()
+
+	commandVersions[commandType] = CommandVersions{Min: min, Max: max}
+}
+
+// UnregisterCommandVersions removes the registered versions of a command type.
+func UnregisterCommandVersions(commandType CommandType) {
+	commandVersionsMu.Lock()
+	defer commandVersionsMu.Unlock()
+
+	delete(commandVersions, commandType)
+}
+
+// SupportedCommandVersions returns the supported versions of a command type,
+// or false if no versions are registered.
+func SupportedCommandVersions(commandType CommandType) (CommandVersions, bool) {
+	commandVersionsMu.RLock()
+	defer commandVersionsMu.RUnlock()
+
+	v, ok := commandVersions[commandType]
+
+	return v, ok
+}
+
+// CheckCommandVersion checks that a schema version is supported for a command
+// type, returns a *CommandVersionError if not.
+func CheckCommandVersion(commandType CommandType, version int) error {
+	supported, ok := SupportedCommandVersions(commandType)
+	if !ok || supported.Contains(version) {
+		return nil
+	}
+
+	return &CommandVersionError{
+		CommandType: commandType,
+		Version:     version,
+		Supported:   supported,
+	}
+}
+
+var commandVersions = make(map[CommandType]CommandVersions)
+var commandVersionsMu sync.RWMutex
@@ -0,0 +1,65 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckCommandVersion(t *testing.T) {
+	const commandType CommandType = "VersionedCommand"
+
+	if err := CheckCommandVersion(commandType, 42); err != nil {
+		t.Error("there should be no error for unregistered versions:", err)
+	}
+
+	RegisterCommandVersions(commandType, 1, 2)
+	defer UnregisterCommandVersions(commandType)
+
+	if v, ok := SupportedCommandVersions(commandType); !ok || v != (CommandVersions{Min: 1, Max: 2}) {
+		t.Error("the supported versions should be correct:", v, ok)
+	}
+
+	for _, version := range []int{1, 2} {
+		if err := CheckCommandVersion(commandType, version); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	err := CheckCommandVersion(commandType, 3)
+	if !errors.Is(err, ErrUnsupportedCommandVersion) {
+		t.Error("there should be an unsupported command version error:", err)
+	}
+
+	var versionErr *CommandVersionError
+	if !errors.As(err, &versionErr) || versionErr.Version != 3 || versionErr.Supported.String() != "1-2" {
+		t.Error("the error should be correct:", err)
+	}
+
+	if err.Error() != "unsupported command version: VersionedCommand v3 (supported: 1-2)" {
+		t.Error("the error message should be correct:", err.Error())
+	}
+}
+
+func TestRegisterCommandVersions_Invalid(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("there should be a panic")
+		}
+	}()
+
+	RegisterCommandVersions("VersionedCommand", 2, 1)
+}
@@ -16,8 +16,28 @@ package eventhorizon
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/looplab/eventhorizon/uuid"
 )
 
+// ErrCommandInternal is when a command could not be handled because of an
+// internal error, for example a panic recovered by a middleware. Transports
+// should not show the details of errors wrapping it to clients.
+var ErrCommandInternal = errors.New("internal error")
+
+// RetryableCommandError is an error from handling a command that was rejected
+// but can be retried after a duration, for example by a rate limit middleware.
+// Transports can use it to tell clients when to retry, like the Retry-After
+// header of httputils.
+type RetryableCommandError interface {
+	error
+
+	// RetryAfter returns the duration until the command can be retried.
+	RetryAfter() time.Duration
+}
+
 // CommandHandler is an interface that all handlers of commands should implement.
 type CommandHandler interface {
 	HandleCommand(context.Context, Command) error
@@ -30,3 +50,53 @@ type CommandHandlerFunc func(context.Context, Command) error
 func (h CommandHandlerFunc) HandleCommand(ctx context.Context, cmd Command) error {
 	return h(ctx, cmd)
 }
+
+// CommandReply is a reply to a command that has been accepted by an aggregate.
+type CommandReply struct {
+	// AggregateID is the ID of the aggregate that accepted the command, for
+	// example the ID of a new aggregate.
+	AggregateID uuid.UUID
+	// Provisional is true for replies sent when the command is accepted, before
+	// the events are saved and published, see NewContextWithProvisionalReply.
+	Provisional bool
+	// Data is the reply of the aggregate, if it implements CommandReplier.
+	Data interface{}
+}
+
+// CommandHandlerWithReply is a command handler that also returns a reply to the
+// command after it has been handled, with the events saved and published.
+type CommandHandlerWithReply interface {
+	CommandHandler
+
+	// HandleCommandWithReply handles a command and returns the reply.
+	HandleCommandWithReply(context.Context, Command) (*CommandReply, error)
+}
+
+// CommandReplier is an aggregate that replies to the commands it accepts, for
+// example with the accepted status of an order.
+type CommandReplier interface {
+	// ReplyToCommand returns the reply data for a command, called right after
+	// the command has been handled by the aggregate without error and before
+	// the events are saved.
+	ReplyToCommand(ctx context.Context, cmd Command) interface{}
+}
+
+// NewContextWithProvisionalReply adds a function on the context that is called
+// synchronously with a provisional reply as soon as the command is accepted by
+// the aggregate, before the events are saved and published, for example to
+// give a UI an optimistic reply before any projectors have run. The function is
+// passed through command buses and middleware with the context. Note that the
+// command can still fail after the provisional reply, for example on a version
+// conflict when saving the events, which is returned by the command handler.
+// The function is called again if the command is retried.
+func NewContextWithProvisionalReply(ctx context.Context, f func(*CommandReply)) context.Context {
+	return context.WithValue(ctx, provisionalReplyKey, f)
+}
+
+// ProvisionalReplyFromContext returns the provisional reply function from the
+// context, see NewContextWithProvisionalReply.
+func ProvisionalReplyFromContext(ctx context.Context) (func(*CommandReply), bool) {
+	f, ok := ctx.Value(provisionalReplyKey).(func(*CommandReply))
+
+	return f, ok
+}
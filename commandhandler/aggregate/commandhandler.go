@@ -17,29 +17,43 @@ package aggregate
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
 )
 
-// ErrNilAggregateStore is when a dispatcher is created with a nil aggregate store.
-var ErrNilAggregateStore = errors.New("aggregate store is nil")
+var (
+	// ErrNilAggregateStore is when a dispatcher is created with a nil aggregate store.
+	ErrNilAggregateStore = errors.New("aggregate store is nil")
+	// ErrMissingLookupRepo is when a command must be looked up but no repo to
+	// look it up in is set, see WithLookupRepo.
+	ErrMissingLookupRepo = errors.New("missing lookup repo")
+)
 
 // CommandHandler dispatches commands to an aggregate.
 //
 // The dispatch process is as follows:
-// 1. The handler receives a command.
+// 1. The handler receives a command (and looks up its aggregate ID if needed).
 // 2. An aggregate is created or loaded using an aggregate store.
-// 3. The aggregate's command handler is called.
+// 3. The aggregate's command handler is called, and any provisional reply is
+// sent, see eventhorizon.NewContextWithProvisionalReply.
 // 4. The aggregate stores events in response to the command.
 // 5. The new events are stored in the event store.
-// 6. The events are published on the event bus after a successful store.
+// 6. Any inline projectors handle the events, see WithInlineProjectors.
+// 7. The events are published on the event bus after a successful store.
 type CommandHandler struct {
-	t     eh.AggregateType
-	store eh.AggregateStore
+	t          eh.AggregateType
+	store      eh.AggregateStore
+	recorder   *EventRecorder
+	txStore    eh.EventStore
+	transactor eh.EventStoreTransactor
+	inline     []eh.EventHandler
+	lookupRepo eh.ReadRepo
 }
 
 // NewCommandHandler creates a new CommandHandler for an aggregate type.
-func NewCommandHandler(t eh.AggregateType, store eh.AggregateStore) (*CommandHandler, error) {
+func NewCommandHandler(t eh.AggregateType, store eh.AggregateStore, options ...Option) (*CommandHandler, error) {
 	if store == nil {
 		return nil, ErrNilAggregateStore
 	}
@@ -49,32 +63,208 @@ func NewCommandHandler(t eh.AggregateType, store eh.AggregateStore) (*CommandHan
 		store: store,
 	}
 
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(h)
+	}
+
+	if h.txStore != nil {
+		t, ok := h.txStore.(eh.EventStoreTransactor)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T", eh.ErrTransactionsNotSupported, h.txStore)
+		}
+
+		h.transactor = t
+	}
+
 	return h, nil
 }
 
+// Option is an option setter used to configure creation.
+type Option func(*CommandHandler)
+
+// WithEventRecorder records the uncommitted events produced by the aggregate
+// for each handled command, see EventRecorder.
+func WithEventRecorder(r *EventRecorder) Option {
+	return func(h *CommandHandler) {
+		h.recorder = r
+	}
+}
+
+// WithTransactions handles each command in a transaction of the event store,
+// which must implement eventhorizon.EventStoreTransactor, otherwise creating
+// the handler fails with eventhorizon.ErrTransactionsNotSupported. Looking up
+// the aggregate ID, loading the aggregate, handling the command and saving the
+// events are all done with the context of the transaction, which the aggregate
+// can use to read and write other stores using the same database, for example
+// a MongoDB read repository using the same client as the event store. All
+// changes are committed together or rolled back if there is an error.
+func WithTransactions(store eh.EventStore) Option {
+	return func(h *CommandHandler) {
+		h.txStore = store
+	}
+}
+
+// WithInlineProjectors adds projectors (or other event handlers) that handle the
+// events produced by each command inline, after the events are saved and before
+// the command handling returns, for read models that must be consistent with
+// the events (read-your-writes). An error from an inline projector is returned
+// as the error of the command. Combine with WithTransactions to save the events
+// and write the projections atomically, the projectors are then called with
+// the context of the transaction, before it is committed. Without transactions
+// the events are already saved when a projector fails. Projectors added to an
+// event bus are handled asynchronously as before.
+func WithInlineProjectors(projectors ...eh.EventHandler) Option {
+	return func(h *CommandHandler) {
+		h.inline = append(h.inline, projectors...)
+	}
+}
+
+// WithLookupRepo sets the repo used to look up the aggregate ID of commands
+// implementing eventhorizon.LookupCommand that are missing the ID, for example a
+// read model of orders by order number. Commands with an aggregate ID are not
+// looked up.
+func WithLookupRepo(repo eh.ReadRepo) Option {
+	return func(h *CommandHandler) {
+		h.lookupRepo = repo
+	}
+}
+
 // HandleCommand handles a command with the registered aggregate.
-// Returns ErrAggregateNotFound if no aggregate could be found.
+// Returns ErrAggregateNotFound if no aggregate could be found and
+// ErrAggregateDeleted if the aggregate is deleted, unless the aggregate allows
+// the command to be handled while deleted.
 func (h *CommandHandler) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	_, err := h.HandleCommandWithReply(ctx, cmd)
+
+	return err
+}
+
+// HandleCommandWithReply implements the HandleCommandWithReply method of the
+// eventhorizon.CommandHandlerWithReply interface. It handles a command like
+// HandleCommand and returns the reply after the events are saved and published.
+func (h *CommandHandler) HandleCommandWithReply(ctx context.Context, cmd eh.Command) (*eh.CommandReply, error) {
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return nil, ctx.Err()
 	default:
 	}
 
 	if err := eh.CheckCommand(cmd); err != nil {
-		return err
+		return nil, err
+	}
+
+	var (
+		events []eh.Event
+		reply  *eh.CommandReply
+		err    error
+	)
+
+	if h.transactor != nil {
+		err = h.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			events, reply, err = h.handle(ctx, cmd)
+
+			return err
+		})
+	} else {
+		events, reply, err = h.handle(ctx, cmd)
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	a, err := h.store.Load(ctx, h.t, cmd.AggregateID())
+	if h.recorder != nil {
+		h.recorder.record(events)
+	}
+
+	return reply, nil
+}
+
+// aggregateID returns the aggregate ID of the command, looking it up for
+// commands that are missing it.
+func (h *CommandHandler) aggregateID(ctx context.Context, cmd eh.Command) (uuid.UUID, error) {
+	if id := cmd.AggregateID(); id != uuid.Nil {
+		return id, nil
+	}
+
+	lc, ok := cmd.(eh.LookupCommand)
+	if !ok {
+		return uuid.Nil, eh.ErrMissingAggregateID
+	}
+
+	if h.lookupRepo == nil {
+		return uuid.Nil, ErrMissingLookupRepo
+	}
+
+	id, err := lc.Lookup(ctx, h.lookupRepo)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("could not look up aggregate ID of %s: %w", cmd.CommandType(), err)
+	} else if id == uuid.Nil {
+		return uuid.Nil, eh.ErrMissingAggregateID
+	}
+
+	return id, nil
+}
+
+// handle looks up the aggregate ID if needed, loads the aggregate, handles the
+// command, sends any provisional reply, saves the aggregate and handles the
+// events by the inline projectors, returning the reply and the saved events if
+// recording or projecting inline.
+func (h *CommandHandler) handle(ctx context.Context, cmd eh.Command) ([]eh.Event, *eh.CommandReply, error) {
+	id, err := h.aggregateID(ctx, cmd)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+
+	a, err := h.store.Load(ctx, h.t, id)
+	if err != nil {
+		return nil, nil, err
 	} else if a == nil {
-		return eh.ErrAggregateNotFound
+		return nil, nil, eh.ErrAggregateNotFound
+	}
+
+	if d, ok := a.(eh.DeletableAggregate); ok && d.IsDeleted() {
+		if r, ok := a.(eh.RestorableAggregate); !ok || !r.CanHandleDeleted(cmd) {
+			return nil, nil, eh.ErrAggregateDeleted
+		}
 	}
 
 	if err = a.HandleCommand(ctx, cmd); err != nil {
-		return &eh.AggregateError{Err: err}
+		return nil, nil, &eh.AggregateError{Err: err}
+	}
+
+	reply := &eh.CommandReply{AggregateID: a.EntityID()}
+	if r, ok := a.(eh.CommandReplier); ok {
+		reply.Data = r.ReplyToCommand(ctx, cmd)
+	}
+
+	if f, ok := eh.ProvisionalReplyFromContext(ctx); ok {
+		provisional := *reply
+		provisional.Provisional = true
+		f(&provisional)
+	}
+
+	// Keep the uncommitted events before they are cleared by the save.
+	var events []eh.Event
+	if es, ok := a.(eh.EventSource); ok && (h.recorder != nil || len(h.inline) > 0) {
+		events = append(events, es.UncommittedEvents()...)
+	}
+
+	if err := h.store.Save(ctx, a); err != nil {
+		return nil, nil, err
+	}
+
+	for _, event := range events {
+		for _, p := range h.inline {
+			if err := p.HandleEvent(ctx, event); err != nil {
+				return nil, nil, fmt.Errorf("could not handle event %s inline by %s: %w", event, p.HandlerType(), err)
+			}
+		}
 	}
 
-	return h.store.Save(ctx, a)
+	return events, reply, nil
 }
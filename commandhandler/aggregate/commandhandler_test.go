@@ -21,7 +21,9 @@ import (
 	"testing"
 
 	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/aggregatestore/events"
 	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo/memory"
 	"github.com/looplab/eventhorizon/uuid"
 )
 
@@ -151,6 +153,407 @@ func TestCommandHandler_NoHandlers(t *testing.T) {
 	}
 }
 
+func TestCommandHandler_DeletedAggregate(t *testing.T) {
+	eventStore := &mocks.EventStore{}
+
+	store, err := events.NewAggregateStore(eventStore,
+		events.WithTombstoneEvent(deletableAggregateDeletedEvent))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	h, err := NewCommandHandler(deletableAggregateType, store)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	id := uuid.New()
+
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: id, Content: "update"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Delete the aggregate.
+	if err := h.HandleCommand(ctx, &mocks.CommandOther{ID: id, Content: "delete"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(eventStore.Events) != 2 {
+		t.Fatal("there should be two events:", len(eventStore.Events))
+	}
+
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: id, Content: "update"}); !errors.Is(err, eh.ErrAggregateDeleted) {
+		t.Error("there should be a ErrAggregateDeleted error:", err)
+	}
+
+	if err := h.HandleCommand(ctx, &mocks.CommandOther{ID: id, Content: "delete"}); !errors.Is(err, eh.ErrAggregateDeleted) {
+		t.Error("there should be a ErrAggregateDeleted error:", err)
+	}
+
+	// The history should be kept.
+	if len(eventStore.Events) != 2 {
+		t.Error("there should be two events:", len(eventStore.Events))
+	}
+
+	// Restore the aggregate.
+	if err := h.HandleCommand(ctx, &mocks.CommandOther2{ID: id, Content: "restore"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: id, Content: "update"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(eventStore.Events) != 4 {
+		t.Error("there should be four events:", len(eventStore.Events))
+	}
+}
+
+func TestCommandHandler_ContextMetadata(t *testing.T) {
+	eventStore := &mocks.EventStore{}
+
+	store, err := events.NewAggregateStore(eventStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	projector := mocks.NewEventHandler("projector")
+
+	h, err := NewCommandHandler(deletableAggregateType, store, WithInlineProjectors(projector))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eh.RegisterContextMetadataKeys("context_one")
+
+	ctx := mocks.WithContextOne(context.Background(), "user")
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: uuid.New(), Content: "update"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(eventStore.Events) != 1 {
+		t.Fatal("there should be one event:", len(eventStore.Events))
+	}
+
+	expected := map[string]interface{}{"context_one": "user"}
+	if md := eventStore.Events[0].Metadata(); !reflect.DeepEqual(md, expected) {
+		t.Error("the stored metadata should be correct:", md)
+	}
+
+	// The inline projectors should see the same events as the ones saved.
+	if !reflect.DeepEqual(projector.Events, eventStore.Events) {
+		t.Error("the projected events should be the saved events:", projector.Events, eventStore.Events)
+	}
+}
+
+func TestCommandHandler_WithTransactions(t *testing.T) {
+	eventStore := &transactionalEventStore{EventStore: &mocks.EventStore{}}
+
+	store, err := events.NewAggregateStore(eventStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	h, err := NewCommandHandler(deletableAggregateType, store, WithTransactions(eventStore))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Committed.
+	if err := h.HandleCommand(context.Background(), &mocks.Command{ID: uuid.New(), Content: "update"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if eventStore.committed != 1 || len(eventStore.Events) != 1 {
+		t.Error("the transaction should be committed:", eventStore.committed, eventStore.Events)
+	}
+
+	if eventStore.Context == nil || eventStore.Context.Value(transactionKey{}) == nil {
+		t.Error("the events should be saved in the transaction")
+	}
+
+	// Rolled back.
+	saveErr := errors.New("save error")
+	eventStore.Err = saveErr
+
+	if err := h.HandleCommand(context.Background(), &mocks.Command{ID: uuid.New(), Content: "update"}); !errors.Is(err, saveErr) {
+		t.Error("there should be a save error:", err)
+	}
+
+	if eventStore.rolledBack != 1 || len(eventStore.Events) != 1 {
+		t.Error("the transaction should be rolled back:", eventStore.rolledBack, eventStore.Events)
+	}
+
+	// Not supported.
+	if _, err := NewCommandHandler(deletableAggregateType, store, WithTransactions(&mocks.EventStore{})); !errors.Is(err, eh.ErrTransactionsNotSupported) {
+		t.Error("there should be a transactions not supported error:", err)
+	}
+}
+
+func TestCommandHandler_InlineProjectors(t *testing.T) {
+	eventStore := &transactionalEventStore{EventStore: &mocks.EventStore{}}
+
+	store, err := events.NewAggregateStore(eventStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	repo := memory.NewRepo()
+	repo.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	failing := mocks.NewEventHandler("failing")
+
+	h, err := NewCommandHandler(deletableAggregateType, store,
+		WithTransactions(eventStore),
+		WithInlineProjectors(&modelProjector{repo: repo}, failing),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The projection can be read after handling the command.
+	ctx := context.Background()
+	id := uuid.New()
+
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: id, Content: "update"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	entity, err := repo.Find(ctx, id)
+	if err != nil {
+		t.Fatal("the projection should be written:", err)
+	}
+
+	if m := entity.(*mocks.Model); m.Version != 1 || m.Content != string(deletableAggregateUpdatedEvent) {
+		t.Error("the projection should be correct:", m)
+	}
+
+	if len(failing.Events) != 1 || failing.Context.Value(transactionKey{}) == nil {
+		t.Error("the projectors should handle the events in the transaction:", failing.Events)
+	}
+
+	// Errors from inline projectors roll back the transaction.
+	projectErr := errors.New("project error")
+	failing.Err = projectErr
+
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: id, Content: "update"}); !errors.Is(err, projectErr) {
+		t.Error("there should be a project error:", err)
+	}
+
+	if eventStore.rolledBack != 1 || len(eventStore.Events) != 1 {
+		t.Error("the transaction should be rolled back:", eventStore.rolledBack, eventStore.Events)
+	}
+}
+
+func TestCommandHandler_Lookup(t *testing.T) {
+	a, h, _ := createAggregateAndHandler(t)
+
+	// A read model of the order numbers of the aggregates.
+	repo := memory.NewRepo()
+	repo.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	ctx := context.Background()
+	if err := repo.Save(ctx, &mocks.Model{ID: uuid.New(), Content: "order-1"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := repo.Save(ctx, &mocks.Model{ID: a.EntityID(), Content: "order-2"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	cmd := &orderCommand{OrderNumber: "order-2"}
+
+	if err := h.HandleCommand(ctx, cmd); !errors.Is(err, ErrMissingLookupRepo) {
+		t.Error("there should be a missing lookup repo error:", err)
+	}
+
+	WithLookupRepo(repo)(h)
+
+	if err := h.HandleCommand(ctx, cmd); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(a.Commands, []eh.Command{cmd}) {
+		t.Error("the command should be handled by the looked up aggregate:", a.Commands)
+	}
+
+	if err := h.HandleCommand(ctx, &orderCommand{OrderNumber: "order-3"}); !errors.Is(err, eh.ErrEntityNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	// Commands with an aggregate ID are not looked up.
+	cmd = &orderCommand{ID: a.EntityID(), OrderNumber: "order-1"}
+	if err := h.HandleCommand(ctx, cmd); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(a.Commands) != 2 || a.Commands[1] != cmd {
+		t.Error("the command should be handled by the aggregate of the ID:", a.Commands)
+	}
+
+	// The aggregate ID is looked up in the transaction.
+	_, _, store := createAggregateAndHandler(t)
+	store.Aggregates[a.EntityID()] = a
+
+	h, err := NewCommandHandler(mocks.AggregateType, store,
+		WithTransactions(&transactionalEventStore{EventStore: &mocks.EventStore{}}),
+		WithLookupRepo(&transactionalRepo{ReadWriteRepo: repo}),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := h.HandleCommand(ctx, &orderCommand{OrderNumber: "order-2"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(a.Commands) != 3 {
+		t.Error("the command should be handled by the looked up aggregate:", a.Commands)
+	}
+}
+
+func TestCommandHandler_ProvisionalReply(t *testing.T) {
+	store, err := events.NewAggregateStore(&mocks.EventStore{})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var order []string
+
+	projector := eh.EventHandlerFunc(func(ctx context.Context, event eh.Event) error {
+		order = append(order, "projected")
+
+		return nil
+	})
+
+	h, err := NewCommandHandler(replyingAggregateType, store,
+		WithInlineProjectors(projector),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var provisional *eh.CommandReply
+
+	ctx := eh.NewContextWithProvisionalReply(context.Background(), func(r *eh.CommandReply) {
+		order = append(order, "replied")
+		provisional = r
+	})
+
+	id := uuid.New()
+
+	reply, err := h.HandleCommandWithReply(ctx, &mocks.Command{ID: id, Content: "accepted"})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(order, []string{"replied", "projected"}) {
+		t.Error("the provisional reply should be sent before the projector runs:", order)
+	}
+
+	expected := &eh.CommandReply{AggregateID: id, Provisional: true, Data: "accepted"}
+	if !reflect.DeepEqual(provisional, expected) {
+		t.Error("the provisional reply should be correct:", provisional)
+	}
+
+	expected.Provisional = false
+	if !reflect.DeepEqual(reply, expected) {
+		t.Error("the reply should be correct:", reply)
+	}
+
+	// No provisional reply for commands that are not accepted.
+	order = nil
+
+	if _, err := h.HandleCommandWithReply(ctx, &mocks.CommandOther{ID: id, Content: "rejected"}); err == nil {
+		t.Error("there should be an error")
+	}
+
+	if order != nil {
+		t.Error("there should be no provisional reply:", order)
+	}
+}
+
+const (
+	deletableAggregateType          eh.AggregateType = "DeletableAggregate"
+	deletableAggregateUpdatedEvent  eh.EventType     = "DeletableAggregateUpdated"
+	deletableAggregateDeletedEvent  eh.EventType     = "DeletableAggregateDeleted"
+	deletableAggregateRestoredEvent eh.EventType     = "DeletableAggregateRestored"
+)
+
+func init() {
+	eh.RegisterAggregate(func(id uuid.UUID) eh.Aggregate {
+		return &deletableAggregate{
+			AggregateBase: events.NewAggregateBase(deletableAggregateType, id),
+		}
+	})
+}
+
+const replyingAggregateType eh.AggregateType = "ReplyingAggregate"
+
+func init() {
+	eh.RegisterAggregate(func(id uuid.UUID) eh.Aggregate {
+		return &replyingAggregate{
+			deletableAggregate: &deletableAggregate{
+				AggregateBase: events.NewAggregateBase(replyingAggregateType, id),
+			},
+		}
+	})
+}
+
+// replyingAggregate replies with the content of the mocks.Command it accepts
+// and rejects other commands.
+type replyingAggregate struct {
+	*deletableAggregate
+}
+
+var _ = eh.CommandReplier(&replyingAggregate{})
+
+func (a *replyingAggregate) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	if _, ok := cmd.(*mocks.Command); !ok {
+		return errors.New("rejected command")
+	}
+
+	return a.deletableAggregate.HandleCommand(ctx, cmd)
+}
+
+func (a *replyingAggregate) ReplyToCommand(ctx context.Context, cmd eh.Command) interface{} {
+	return cmd.(*mocks.Command).Content
+}
+
+// deletableAggregate is an aggregate that can be deleted with CommandOther
+// and restored with CommandOther2.
+type deletableAggregate struct {
+	*events.AggregateBase
+}
+
+var _ = eh.RestorableAggregate(&deletableAggregate{})
+
+func (a *deletableAggregate) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	switch cmd.(type) {
+	case *mocks.Command:
+		a.AppendEventCtx(ctx, deletableAggregateUpdatedEvent, nil)
+	case *mocks.CommandOther:
+		a.AppendEventCtx(ctx, deletableAggregateDeletedEvent, nil)
+	case *mocks.CommandOther2:
+		a.AppendEventCtx(ctx, deletableAggregateRestoredEvent, nil)
+	default:
+		return errors.New("unknown command")
+	}
+
+	return nil
+}
+
+func (a *deletableAggregate) ApplyEvent(ctx context.Context, event eh.Event) error {
+	return nil
+}
+
+func (a *deletableAggregate) CanHandleDeleted(cmd eh.Command) bool {
+	_, ok := cmd.(*mocks.CommandOther2)
+
+	return ok
+}
+
 func BenchmarkCommandHandler(b *testing.B) {
 	a := mocks.NewAggregate(uuid.New())
 	store := &mocks.AggregateStore{
@@ -196,3 +599,86 @@ func createAggregateAndHandler(t *testing.T) (*mocks.Aggregate, *CommandHandler,
 
 	return a, h, store
 }
+
+type transactionKey struct{}
+
+// transactionalEventStore is an event store that simulates transactions by
+// removing the saved events on errors.
+type transactionalEventStore struct {
+	*mocks.EventStore
+	committed  int
+	rolledBack int
+}
+
+func (s *transactionalEventStore) WithTransaction(ctx context.Context, f func(context.Context) error) error {
+	n := len(s.Events)
+
+	if err := f(context.WithValue(ctx, transactionKey{}, true)); err != nil {
+		s.Events = s.Events[:n]
+		s.rolledBack++
+
+		return err
+	}
+
+	s.committed++
+
+	return nil
+}
+
+// transactionalRepo is a repo that can only be read in a transaction.
+type transactionalRepo struct {
+	eh.ReadWriteRepo
+}
+
+func (r *transactionalRepo) FindAll(ctx context.Context) ([]eh.Entity, error) {
+	if ctx.Value(transactionKey{}) == nil {
+		return nil, errors.New("not in a transaction")
+	}
+
+	return r.ReadWriteRepo.FindAll(ctx)
+}
+
+// modelProjector projects the type of the latest event on a mocks.Model.
+type modelProjector struct {
+	repo eh.ReadWriteRepo
+}
+
+func (p *modelProjector) HandlerType() eh.EventHandlerType {
+	return "modelProjector"
+}
+
+func (p *modelProjector) HandleEvent(ctx context.Context, event eh.Event) error {
+	return p.repo.Save(ctx, &mocks.Model{
+		ID:      event.AggregateID(),
+		Version: event.Version(),
+		Content: string(event.EventType()),
+	})
+}
+
+// orderCommand is a command for an aggregate by ID or by order number, which is
+// looked up in a repo of mocks.Model with the order number as content.
+type orderCommand struct {
+	ID          uuid.UUID `eh:"optional"`
+	OrderNumber string
+}
+
+var _ = eh.LookupCommand(&orderCommand{})
+
+func (c *orderCommand) AggregateID() uuid.UUID          { return c.ID }
+func (c *orderCommand) AggregateType() eh.AggregateType { return mocks.AggregateType }
+func (c *orderCommand) CommandType() eh.CommandType     { return "OrderCommand" }
+
+func (c *orderCommand) Lookup(ctx context.Context, repo eh.ReadRepo) (uuid.UUID, error) {
+	entities, err := repo.FindAll(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, e := range entities {
+		if m, ok := e.(*mocks.Model); ok && m.Content == c.OrderNumber {
+			return m.ID, nil
+		}
+	}
+
+	return uuid.Nil, eh.ErrEntityNotFound
+}
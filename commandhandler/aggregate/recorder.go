@@ -0,0 +1,58 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// EventRecorder captures the uncommitted events that an aggregate produced
+// when handling the last successfully handled command. It is mainly useful in
+// tests to assert on the produced events without loading them from the store.
+type EventRecorder struct {
+	events []eh.Event
+	mu     sync.RWMutex
+}
+
+// NewEventRecorder creates a new EventRecorder, to be used with the
+// WithEventRecorder option.
+func NewEventRecorder() *EventRecorder {
+	return &EventRecorder{}
+}
+
+// Events returns the events produced by the last handled command.
+func (r *EventRecorder) Events() []eh.Event {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]eh.Event(nil), r.events...)
+}
+
+// Reset clears the recorded events.
+func (r *EventRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = nil
+}
+
+func (r *EventRecorder) record(events []eh.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = events
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestCommandHandler_WithEventRecorder(t *testing.T) {
+	a := &eventSourcedAggregate{Aggregate: mocks.NewAggregate(uuid.New())}
+	store := &committingAggregateStore{&mocks.AggregateStore{
+		Aggregates: map[uuid.UUID]eh.Aggregate{
+			a.EntityID(): a,
+		},
+		Snapshots: make(map[uuid.UUID]eh.Snapshot),
+	}}
+	r := NewEventRecorder()
+
+	h, err := NewCommandHandler(mocks.AggregateType, store, WithEventRecorder(r))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: a.EntityID(), Content: "command1"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: a.EntityID(), Content: "command2"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Only the events of the last command should be recorded.
+	events := r.Events()
+	if len(events) != 1 {
+		t.Fatal("there should be one recorded event:", events)
+	}
+
+	if events[0].EventType() != mocks.EventType {
+		t.Error("the event type should be correct:", events[0].EventType())
+	}
+
+	if !reflect.DeepEqual(events[0].Data(), &mocks.EventData{Content: "command2"}) {
+		t.Error("the event data should be correct:", events[0].Data())
+	}
+
+	// A failed save should not replace the recorded events.
+	store.Err = errors.New("save error")
+
+	if err := h.HandleCommand(ctx, &mocks.Command{ID: a.EntityID(), Content: "command3"}); err == nil {
+		t.Error("there should be an error")
+	}
+
+	if events := r.Events(); len(events) != 1 ||
+		!reflect.DeepEqual(events[0].Data(), &mocks.EventData{Content: "command2"}) {
+		t.Error("the recorded events should not have changed:", events)
+	}
+
+	r.Reset()
+
+	if events := r.Events(); len(events) != 0 {
+		t.Error("there should be no recorded events:", events)
+	}
+}
+
+// eventSourcedAggregate is an aggregate producing one event per command.
+type eventSourcedAggregate struct {
+	*mocks.Aggregate
+	events []eh.Event
+}
+
+func (a *eventSourcedAggregate) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	c, ok := cmd.(*mocks.Command)
+	if !ok {
+		return errors.New("unknown command")
+	}
+
+	a.events = append(a.events, eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: c.Content}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, a.ID, len(a.events)+1)))
+
+	return nil
+}
+
+func (a *eventSourcedAggregate) UncommittedEvents() []eh.Event {
+	return a.events
+}
+
+func (a *eventSourcedAggregate) ClearUncommittedEvents() {
+	a.events = nil
+}
+
+// committingAggregateStore clears the uncommitted events on save, like the
+// event sourced aggregate store.
+type committingAggregateStore struct {
+	*mocks.AggregateStore
+}
+
+func (s *committingAggregateStore) Save(ctx context.Context, a eh.Aggregate) error {
+	if err := s.AggregateStore.Save(ctx, a); err != nil {
+		return err
+	}
+
+	if es, ok := a.(eh.EventSource); ok {
+		es.ClearUncommittedEvents()
+	}
+
+	return nil
+}
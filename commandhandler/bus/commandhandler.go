@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	eh "github.com/looplab/eventhorizon"
 )
@@ -31,19 +32,73 @@ var (
 
 // CommandHandler is a command handler that handles commands by routing to the
 // registered CommandHandlers.
+//
+// Middleware can be applied to all handlers with WithMiddleware, for example
+// for logging or tracing, and to single handlers when setting them with
+// SetHandler, for example for validation. By default the global middleware is
+// applied outside of the per-handler middleware, see WithGlobalMiddlewareInner.
+//
+// Commands are handled with a timeout on the context, either declared by the
+// command with eventhorizon.Timeouter or the default set with
+// WithDefaultTimeout.
 type CommandHandler struct {
-	handlers   map[eh.CommandType]eh.CommandHandler
-	handlersMu sync.RWMutex
+	handlers       map[eh.CommandType]eh.CommandHandler
+	handlersMu     sync.RWMutex
+	middleware     []eh.CommandHandlerMiddleware
+	globalInner    bool
+	defaultTimeout time.Duration
 }
 
 // NewCommandHandler creates a CommandHandler.
-func NewCommandHandler() *CommandHandler {
-	return &CommandHandler{
+func NewCommandHandler(options ...Option) *CommandHandler {
+	h := &CommandHandler{
 		handlers: make(map[eh.CommandType]eh.CommandHandler),
 	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(h)
+	}
+
+	return h
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*CommandHandler)
+
+// WithMiddleware adds middleware that is applied to all handlers set with
+// SetHandler, in the same order as eventhorizon.UseCommandHandlerMiddleware
+// (the first middleware is the outermost).
+func WithMiddleware(middleware ...eh.CommandHandlerMiddleware) Option {
+	return func(h *CommandHandler) {
+		h.middleware = append(h.middleware, middleware...)
+	}
+}
+
+// WithGlobalMiddlewareInner applies the global middleware inside of the
+// per-handler middleware, instead of outside, for example to only log and
+// trace commands that have passed the validation of a handler.
+func WithGlobalMiddlewareInner() Option {
+	return func(h *CommandHandler) {
+		h.globalInner = true
+	}
+}
+
+// WithDefaultTimeout sets the timeout for handling commands that don't declare
+// their own timeout with eventhorizon.Timeouter. No timeout is used by default.
+// Note that a shorter deadline of the context passed to HandleCommand is kept.
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(h *CommandHandler) {
+		h.defaultTimeout = d
+	}
 }
 
 // HandleCommand handles a command with a handler capable of handling it.
+// Processing is started on the context unless already started at ingress, see
+// eventhorizon.StartProcessing.
 func (h *CommandHandler) HandleCommand(ctx context.Context, cmd eh.Command) error {
 	select {
 	case <-ctx.Done():
@@ -51,10 +106,24 @@ func (h *CommandHandler) HandleCommand(ctx context.Context, cmd eh.Command) erro
 	default:
 	}
 
+	ctx = eh.StartProcessing(ctx)
+
 	if err := eh.CheckCommand(cmd); err != nil {
 		return err
 	}
 
+	timeout := h.defaultTimeout
+	if t, ok := cmd.(eh.Timeouter); ok && t.Timeout() > 0 {
+		timeout = t.Timeout()
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+
+		defer cancel()
+	}
+
 	h.handlersMu.RLock()
 	defer h.handlersMu.RUnlock()
 
@@ -65,8 +134,9 @@ func (h *CommandHandler) HandleCommand(ctx context.Context, cmd eh.Command) erro
 	return ErrHandlerNotFound
 }
 
-// SetHandler adds a handler for a specific command.
-func (h *CommandHandler) SetHandler(handler eh.CommandHandler, cmdType eh.CommandType) error {
+// SetHandler adds a handler for a specific command, wrapped in the optional
+// middleware for the handler and the global middleware.
+func (h *CommandHandler) SetHandler(handler eh.CommandHandler, cmdType eh.CommandType, middleware ...eh.CommandHandlerMiddleware) error {
 	h.handlersMu.Lock()
 	defer h.handlersMu.Unlock()
 
@@ -74,6 +144,14 @@ func (h *CommandHandler) SetHandler(handler eh.CommandHandler, cmdType eh.Comman
 		return ErrHandlerAlreadySet
 	}
 
+	if h.globalInner {
+		handler = eh.UseCommandHandlerMiddleware(handler, h.middleware...)
+		handler = eh.UseCommandHandlerMiddleware(handler, middleware...)
+	} else {
+		handler = eh.UseCommandHandlerMiddleware(handler, middleware...)
+		handler = eh.UseCommandHandlerMiddleware(handler, h.middleware...)
+	}
+
 	h.handlers[cmdType] = handler
 
 	return nil
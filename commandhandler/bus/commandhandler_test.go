@@ -19,6 +19,7 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/mocks"
@@ -71,3 +72,185 @@ func TestCommandHandler(t *testing.T) {
 		t.Error("there should be a ErrHandlerAlreadySet error:", err)
 	}
 }
+
+func TestCommandHandler_Middleware(t *testing.T) {
+	testCases := map[string]struct {
+		options  []Option
+		expected []string
+	}{
+		"global outer": {
+			options:  []Option{WithMiddleware(recordingMiddleware("global1"), recordingMiddleware("global2"))},
+			expected: []string{"global1", "global2", "handler1", "handler2"},
+		},
+		"global inner": {
+			options: []Option{
+				WithMiddleware(recordingMiddleware("global1"), recordingMiddleware("global2")),
+				WithGlobalMiddlewareInner(),
+			},
+			expected: []string{"handler1", "handler2", "global1", "global2"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			bus := NewCommandHandler(tc.options...)
+
+			handler := &mocks.CommandHandler{}
+			if err := bus.SetHandler(handler, mocks.CommandType,
+				recordingMiddleware("handler1"), recordingMiddleware("handler2")); err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			// The global middleware is also applied to handlers without middleware.
+			other := &mocks.CommandHandler{}
+			if err := bus.SetHandler(other, mocks.CommandOtherType); err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			var order []string
+
+			ctx := context.WithValue(context.Background(), orderKey{}, &order)
+
+			if err := bus.HandleCommand(ctx, &mocks.Command{ID: uuid.New(), Content: "command"}); err != nil {
+				t.Error("there should be no error:", err)
+			}
+
+			if len(handler.Commands) != 1 {
+				t.Error("the command should be handled:", handler.Commands)
+			}
+
+			if !reflect.DeepEqual(order, tc.expected) {
+				t.Error("the middleware should run in order:", order)
+			}
+
+			order = nil
+
+			if err := bus.HandleCommand(ctx, &mocks.CommandOther{ID: uuid.New(), Content: "command"}); err != nil {
+				t.Error("there should be no error:", err)
+			}
+
+			if !reflect.DeepEqual(order, []string{"global1", "global2"}) {
+				t.Error("the global middleware should run:", order)
+			}
+		})
+	}
+}
+
+type orderKey struct{}
+
+// recordingMiddleware records its name in the order on the context.
+func recordingMiddleware(name string) eh.CommandHandlerMiddleware {
+	return func(h eh.CommandHandler) eh.CommandHandler {
+		return eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+			if order, ok := ctx.Value(orderKey{}).(*[]string); ok {
+				*order = append(*order, name)
+			}
+
+			return h.HandleCommand(ctx, cmd)
+		})
+	}
+}
+
+func TestCommandHandler_ProcessingElapsed(t *testing.T) {
+	var elapsed []time.Duration
+
+	// Each middleware records the elapsed time and takes some time.
+	timing := func(h eh.CommandHandler) eh.CommandHandler {
+		return eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+			elapsed = append(elapsed, eh.ProcessingElapsed(ctx))
+			time.Sleep(10 * time.Millisecond)
+
+			return h.HandleCommand(ctx, cmd)
+		})
+	}
+
+	bus := NewCommandHandler(WithMiddleware(timing, timing))
+
+	handler := &mocks.CommandHandler{}
+	if err := bus.SetHandler(handler, mocks.CommandType, timing); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := bus.HandleCommand(context.Background(), &mocks.Command{ID: uuid.New(), Content: "command"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(elapsed) != 3 {
+		t.Fatal("all middleware should record the elapsed time:", elapsed)
+	}
+
+	for i := 1; i < len(elapsed); i++ {
+		if elapsed[i] < elapsed[i-1]+10*time.Millisecond {
+			t.Error("the elapsed time should grow across the middleware:", elapsed)
+		}
+	}
+
+	if eh.ProcessingElapsed(handler.Context) < 30*time.Millisecond {
+		t.Error("the handler should see the total elapsed time:", eh.ProcessingElapsed(handler.Context))
+	}
+
+	// Processing started at ingress is kept.
+	elapsed = nil
+	start := time.Now().Add(-time.Second)
+	ctx := eh.NewContextWithProcessingStart(context.Background(), start)
+
+	if err := bus.HandleCommand(ctx, &mocks.Command{ID: uuid.New(), Content: "command"}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(elapsed) == 0 || elapsed[0] < time.Second {
+		t.Error("the elapsed time should be from ingress:", elapsed)
+	}
+}
+
+func TestCommandHandler_Timeout(t *testing.T) {
+	bus := NewCommandHandler(WithDefaultTimeout(10 * time.Millisecond))
+
+	// The handler takes longer than the default timeout.
+	handler := eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	if err := bus.SetHandler(handler, mocks.CommandType); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := bus.SetHandler(handler, slowCommandType); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+
+	if err := bus.HandleCommand(ctx, &mocks.Command{ID: uuid.New(), Content: "command"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("there should be a deadline exceeded error:", err)
+	}
+
+	// The declared timeout is used instead of the default.
+	if err := bus.HandleCommand(ctx, &slowCommand{ID: uuid.New(), timeout: time.Second}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// A zero declared timeout uses the default.
+	if err := bus.HandleCommand(ctx, &slowCommand{ID: uuid.New()}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("there should be a deadline exceeded error:", err)
+	}
+}
+
+const slowCommandType eh.CommandType = "SlowCommand"
+
+type slowCommand struct {
+	ID      uuid.UUID
+	timeout time.Duration
+}
+
+var _ = eh.Timeouter(&slowCommand{})
+
+func (c *slowCommand) AggregateID() uuid.UUID          { return c.ID }
+func (c *slowCommand) AggregateType() eh.AggregateType { return mocks.AggregateType }
+func (c *slowCommand) CommandType() eh.CommandType     { return slowCommandType }
+func (c *slowCommand) Timeout() time.Duration          { return c.timeout }
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+var (
+	// ErrHandlerAlreadySet is when a handler is already set for an aggregate type.
+	ErrHandlerAlreadySet = errors.New("handler is already set")
+	// ErrNoHandler is when no handler is set for the aggregate type of a command.
+	ErrNoHandler = errors.New("no handler for aggregate type")
+)
+
+// CommandHandler is a command handler that routes commands to handlers by the
+// aggregate type of the command, for example to route commands to the bounded
+// context owning the aggregate in a modular monolith. Commands for aggregate
+// types without a handler are rejected with ErrNoHandler.
+type CommandHandler struct {
+	handlers   map[eh.AggregateType]eh.CommandHandler
+	handlersMu sync.RWMutex
+}
+
+var _ = eh.CommandHandler(&CommandHandler{})
+
+// NewCommandHandler creates a CommandHandler.
+func NewCommandHandler() *CommandHandler {
+	return &CommandHandler{
+		handlers: make(map[eh.AggregateType]eh.CommandHandler),
+	}
+}
+
+// HandleCommand implements the HandleCommand method of the
+// eventhorizon.CommandHandler interface.
+func (h *CommandHandler) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	at := cmd.AggregateType()
+
+	h.handlersMu.RLock()
+	handler, ok := h.handlers[at]
+	h.handlersMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoHandler, at)
+	}
+
+	return handler.HandleCommand(ctx, cmd)
+}
+
+// SetHandler sets the handler for commands of an aggregate type, wrapped in
+// the optional middleware.
+func (h *CommandHandler) SetHandler(handler eh.CommandHandler, aggregateType eh.AggregateType, middleware ...eh.CommandHandlerMiddleware) error {
+	h.handlersMu.Lock()
+	defer h.handlersMu.Unlock()
+
+	if _, ok := h.handlers[aggregateType]; ok {
+		return ErrHandlerAlreadySet
+	}
+
+	h.handlers[aggregateType] = eh.UseCommandHandlerMiddleware(handler, middleware...)
+
+	return nil
+}
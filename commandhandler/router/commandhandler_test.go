@@ -0,0 +1,124 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/commandhandler/bus"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestCommandHandler(t *testing.T) {
+	router := NewCommandHandler()
+	ctx := context.Background()
+
+	handler := &mocks.CommandHandler{}
+	if err := router.SetHandler(handler, mocks.AggregateType); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	otherHandler := &mocks.CommandHandler{}
+	if err := router.SetHandler(otherHandler, otherAggregateType); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := router.SetHandler(handler, mocks.AggregateType); !errors.Is(err, ErrHandlerAlreadySet) {
+		t.Error("there should be a handler already set error:", err)
+	}
+
+	cmd := &mocks.Command{ID: uuid.New(), Content: "command"}
+	if err := router.HandleCommand(ctx, cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	otherCmd := &otherCommand{ID: uuid.New()}
+	if err := router.HandleCommand(ctx, otherCmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(handler.Commands, []eh.Command{cmd}) {
+		t.Error("the command should be routed by aggregate type:", handler.Commands)
+	}
+
+	if !reflect.DeepEqual(otherHandler.Commands, []eh.Command{otherCmd}) {
+		t.Error("the other command should be routed by aggregate type:", otherHandler.Commands)
+	}
+
+	if err := router.HandleCommand(ctx, &unroutedCommand{ID: uuid.New()}); !errors.Is(err, ErrNoHandler) {
+		t.Error("there should be a no handler error:", err)
+	}
+}
+
+func TestCommandHandler_Bus(t *testing.T) {
+	router := NewCommandHandler()
+	ctx := context.Background()
+
+	// Commands of all types are routed by the aggregate type.
+	handler := &mocks.CommandHandler{}
+	if err := router.SetHandler(handler, otherAggregateType); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	b := bus.NewCommandHandler()
+	if err := b.SetHandler(router, otherCommandType); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := b.SetHandler(router, unroutedCommandType); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	cmd := &otherCommand{ID: uuid.New()}
+	if err := b.HandleCommand(ctx, cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(handler.Commands, []eh.Command{cmd}) {
+		t.Error("the command should be routed by aggregate type:", handler.Commands)
+	}
+
+	if err := b.HandleCommand(ctx, &unroutedCommand{ID: uuid.New()}); !errors.Is(err, ErrNoHandler) {
+		t.Error("there should be a no handler error:", err)
+	}
+}
+
+const (
+	otherAggregateType    eh.AggregateType = "OtherAggregate"
+	unroutedAggregateType eh.AggregateType = "UnroutedAggregate"
+	otherCommandType      eh.CommandType   = "OtherCommand"
+	unroutedCommandType   eh.CommandType   = "UnroutedCommand"
+)
+
+type otherCommand struct {
+	ID uuid.UUID
+}
+
+func (c *otherCommand) AggregateID() uuid.UUID          { return c.ID }
+func (c *otherCommand) AggregateType() eh.AggregateType { return otherAggregateType }
+func (c *otherCommand) CommandType() eh.CommandType     { return otherCommandType }
+
+type unroutedCommand struct {
+	ID uuid.UUID
+}
+
+func (c *unroutedCommand) AggregateID() uuid.UUID          { return c.ID }
+func (c *unroutedCommand) AggregateType() eh.AggregateType { return unroutedAggregateType }
+func (c *unroutedCommand) CommandType() eh.CommandType     { return unroutedCommandType }
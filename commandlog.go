@@ -0,0 +1,92 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// CommandLog is an append-only log of handled commands, useful for auditing
+// and debugging what was requested of the system (as opposed to what happened,
+// which is recorded by the events).
+type CommandLog interface {
+	// Append appends an entry to the log. The entry should have the pending
+	// status until the command has been handled.
+	Append(context.Context, *CommandLogEntry) error
+
+	// SetStatus sets the outcome of a handled command for an entry by its ID.
+	// Returns ErrCommandLogEntryNotFound if there is no such entry.
+	SetStatus(ctx context.Context, id uuid.UUID, status CommandLogStatus, errMsg string) error
+
+	// Entries returns all entries in the order they were appended.
+	Entries(context.Context) ([]*CommandLogEntry, error)
+
+	// Close closes the CommandLog.
+	Close() error
+}
+
+// CommandLogStatus is the status of a logged command.
+type CommandLogStatus int
+
+const (
+	// CommandPending is for commands that are being handled.
+	CommandPending CommandLogStatus = iota
+	// CommandSucceeded is for commands that was successfully handled.
+	CommandSucceeded
+	// CommandFailed is for commands that failed to be handled.
+	CommandFailed
+)
+
+// String returns the string representation of a command log status.
+func (s CommandLogStatus) String() string {
+	switch s {
+	case CommandPending:
+		return "pending"
+	case CommandSucceeded:
+		return "succeeded"
+	case CommandFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// CommandLogEntry is an entry in the command log.
+type CommandLogEntry struct {
+	// ID is the unique ID of the entry.
+	ID uuid.UUID
+	// CommandType is the type of the logged command.
+	CommandType CommandType
+	// AggregateType is the type of the aggregate that the command targeted.
+	AggregateType AggregateType
+	// AggregateID is the ID of the aggregate that the command targeted.
+	AggregateID uuid.UUID
+	// Data is the command (and supported parts of the context) marshaled
+	// with a CommandCodec.
+	Data []byte
+	// Timestamp is when the command was logged.
+	Timestamp time.Time
+	// Status is the outcome of handling the command.
+	Status CommandLogStatus
+	// Err is the error message if the command failed.
+	Err string
+}
+
+// ErrCommandLogEntryNotFound is when an entry could not be found in a command log.
+var ErrCommandLogEntryNotFound = errors.New("command log entry not found")
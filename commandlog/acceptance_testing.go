@@ -0,0 +1,117 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commandlog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// AcceptanceTest is the acceptance test that all implementations of CommandLog
+// should pass. It should manually be called from a test case in each
+// implementation:
+//
+//	func TestCommandLog(t *testing.T) {
+//	    l := NewCommandLog()
+//	    commandlog.AcceptanceTest(t, l, context.Background())
+//	}
+func AcceptanceTest(t *testing.T, l eh.CommandLog, ctx context.Context) {
+	// No entries.
+	entries, err := l.Entries(ctx)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(entries) != 0 {
+		t.Error("there should be no entries:", entries)
+	}
+
+	// Set status on a non-existing entry.
+	if err := l.SetStatus(ctx, uuid.New(), eh.CommandSucceeded, ""); !errors.Is(err, eh.ErrCommandLogEntryNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	entry1 := &eh.CommandLogEntry{
+		ID:            uuid.New(),
+		CommandType:   mocks.CommandType,
+		AggregateType: mocks.AggregateType,
+		AggregateID:   uuid.New(),
+		Data:          []byte("command1"),
+		Timestamp:     timestamp,
+		Status:        eh.CommandPending,
+	}
+
+	if err := l.Append(ctx, entry1); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	entry2 := &eh.CommandLogEntry{
+		ID:            uuid.New(),
+		CommandType:   mocks.CommandOtherType,
+		AggregateType: mocks.AggregateType,
+		AggregateID:   uuid.New(),
+		Data:          []byte("command2"),
+		Timestamp:     timestamp.Add(time.Second),
+		Status:        eh.CommandPending,
+	}
+
+	if err := l.Append(ctx, entry2); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Set the outcome of both commands.
+	if err := l.SetStatus(ctx, entry1.ID, eh.CommandSucceeded, ""); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := l.SetStatus(ctx, entry2.ID, eh.CommandFailed, "command error"); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	entries, err = l.Entries(ctx)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	expected1 := *entry1
+	expected1.Status = eh.CommandSucceeded
+	expected2 := *entry2
+	expected2.Status = eh.CommandFailed
+	expected2.Err = "command error"
+
+	if len(entries) != 2 {
+		t.Fatal("there should be two entries:", len(entries))
+	}
+
+	for i, expected := range []*eh.CommandLogEntry{&expected1, &expected2} {
+		if !entries[i].Timestamp.Equal(expected.Timestamp) {
+			t.Error("the timestamp should be correct:", entries[i].Timestamp)
+		}
+
+		entries[i].Timestamp = expected.Timestamp
+
+		if !reflect.DeepEqual(entries[i], expected) {
+			t.Errorf("the entry should be correct:\ngot:  %+v\nwant: %+v", entries[i], expected)
+		}
+	}
+}
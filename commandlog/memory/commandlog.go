@@ -0,0 +1,94 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// CommandLog is an eventhorizon.CommandLog where all entries are stored in
+// memory and not persisted. Useful for testing and experimenting.
+type CommandLog struct {
+	entries []*eh.CommandLogEntry
+	index   map[uuid.UUID]*eh.CommandLogEntry
+	mu      sync.RWMutex
+}
+
+var _ = eh.CommandLog(&CommandLog{})
+
+// NewCommandLog creates a new CommandLog.
+func NewCommandLog() *CommandLog {
+	return &CommandLog{
+		index: map[uuid.UUID]*eh.CommandLogEntry{},
+	}
+}
+
+// Append implements the Append method of the eventhorizon.CommandLog interface.
+func (l *CommandLog) Append(ctx context.Context, entry *eh.CommandLogEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := copyEntry(entry)
+	l.entries = append(l.entries, e)
+	l.index[e.ID] = e
+
+	return nil
+}
+
+// SetStatus implements the SetStatus method of the eventhorizon.CommandLog interface.
+func (l *CommandLog) SetStatus(ctx context.Context, id uuid.UUID, status eh.CommandLogStatus, errMsg string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.index[id]
+	if !ok {
+		return eh.ErrCommandLogEntryNotFound
+	}
+
+	e.Status = status
+	e.Err = errMsg
+
+	return nil
+}
+
+// Entries implements the Entries method of the eventhorizon.CommandLog interface.
+func (l *CommandLog) Entries(ctx context.Context) ([]*eh.CommandLogEntry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := make([]*eh.CommandLogEntry, len(l.entries))
+	for i, e := range l.entries {
+		entries[i] = copyEntry(e)
+	}
+
+	return entries, nil
+}
+
+// Close implements the Close method of the eventhorizon.CommandLog interface.
+func (l *CommandLog) Close() error {
+	return nil
+}
+
+// copyEntry duplicates an entry, including its data.
+func copyEntry(entry *eh.CommandLogEntry) *eh.CommandLogEntry {
+	e := *entry
+	e.Data = append([]byte(nil), entry.Data...)
+
+	return &e
+}
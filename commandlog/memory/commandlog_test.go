@@ -0,0 +1,35 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/looplab/eventhorizon/commandlog"
+)
+
+func TestCommandLog(t *testing.T) {
+	l := NewCommandLog()
+	if l == nil {
+		t.Fatal("there should be a command log")
+	}
+
+	commandlog.AcceptanceTest(t, l, context.Background())
+
+	if err := l.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+
+	// Register uuid.UUID as BSON type.
+	_ "github.com/looplab/eventhorizon/codec/bson"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mongoutils"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// CommandLog implements an eventhorizon.CommandLog for MongoDB, using one
+// document per logged command.
+type CommandLog struct {
+	client          *mongo.Client
+	clientOwnership clientOwnership
+	entries         *mongo.Collection
+}
+
+var _ = eh.CommandLog(&CommandLog{})
+
+type clientOwnership int
+
+const (
+	internalClient clientOwnership = iota
+	externalClient
+)
+
+// NewCommandLog creates a new CommandLog with a MongoDB URI: `mongodb://hostname`.
+func NewCommandLog(uri, dbName string, options ...Option) (*CommandLog, error) {
+	opts := mongoOptions.Client().ApplyURI(uri)
+	opts.SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
+	opts.SetReadConcern(readconcern.Majority())
+	opts.SetReadPreference(readpref.Primary())
+
+	client, err := mongo.Connect(context.TODO(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to DB: %w", err)
+	}
+
+	return newCommandLogWithClient(client, internalClient, dbName, options...)
+}
+
+// NewCommandLogWithClient creates a new CommandLog with a client.
+func NewCommandLogWithClient(client *mongo.Client, dbName string, options ...Option) (*CommandLog, error) {
+	return newCommandLogWithClient(client, externalClient, dbName, options...)
+}
+
+func newCommandLogWithClient(client *mongo.Client, clientOwnership clientOwnership, dbName string, options ...Option) (*CommandLog, error) {
+	if client == nil {
+		return nil, fmt.Errorf("missing DB client")
+	}
+
+	l := &CommandLog{
+		client:          client,
+		clientOwnership: clientOwnership,
+		entries:         client.Database(dbName).Collection("commands"),
+	}
+
+	for _, option := range options {
+		if err := option(l); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	if err := l.client.Ping(context.Background(), readpref.Primary()); err != nil {
+		return nil, fmt.Errorf("could not connect to MongoDB: %w", err)
+	}
+
+	if _, err := l.entries.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.M{"entry_id": 1},
+		Options: mongoOptions.Index().SetUnique(true),
+	}); err != nil {
+		return nil, fmt.Errorf("could not ensure entry ID index: %w", err)
+	}
+
+	return l, nil
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*CommandLog) error
+
+// WithCollectionName uses a different collection than the default "commands".
+func WithCollectionName(entriesColl string) Option {
+	return func(l *CommandLog) error {
+		if err := mongoutils.CheckCollectionName(entriesColl); err != nil {
+			return fmt.Errorf("command log collection: %w", err)
+		}
+
+		l.entries = l.entries.Database().Collection(entriesColl)
+
+		return nil
+	}
+}
+
+// Append implements the Append method of the eventhorizon.CommandLog interface.
+func (l *CommandLog) Append(ctx context.Context, entry *eh.CommandLogEntry) error {
+	doc := entryDoc{
+		EntryID:       entry.ID,
+		CommandType:   entry.CommandType,
+		AggregateType: entry.AggregateType,
+		AggregateID:   entry.AggregateID,
+		Data:          entry.Data,
+		Timestamp:     entry.Timestamp,
+		Status:        entry.Status,
+		Err:           entry.Err,
+	}
+
+	if _, err := l.entries.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("could not append entry: %w", err)
+	}
+
+	return nil
+}
+
+// SetStatus implements the SetStatus method of the eventhorizon.CommandLog interface.
+func (l *CommandLog) SetStatus(ctx context.Context, id uuid.UUID, status eh.CommandLogStatus, errMsg string) error {
+	r, err := l.entries.UpdateOne(ctx,
+		bson.M{"entry_id": id},
+		bson.M{"$set": bson.M{
+			"status": status,
+			"error":  errMsg,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("could not set status: %w", err)
+	} else if r.MatchedCount == 0 {
+		return eh.ErrCommandLogEntryNotFound
+	}
+
+	return nil
+}
+
+// Entries implements the Entries method of the eventhorizon.CommandLog interface.
+func (l *CommandLog) Entries(ctx context.Context) ([]*eh.CommandLogEntry, error) {
+	// Sort by the object ID to get the entries in the order they were appended.
+	opts := mongoOptions.Find().SetSort(bson.M{"_id": 1})
+
+	cursor, err := l.entries.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not find entries: %w", err)
+	}
+
+	entries := []*eh.CommandLogEntry{}
+
+	for cursor.Next(ctx) {
+		var doc entryDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("could not unmarshal entry: %w", err)
+		}
+
+		entries = append(entries, &eh.CommandLogEntry{
+			ID:            doc.EntryID,
+			CommandType:   doc.CommandType,
+			AggregateType: doc.AggregateType,
+			AggregateID:   doc.AggregateID,
+			Data:          doc.Data,
+			Timestamp:     doc.Timestamp,
+			Status:        doc.Status,
+			Err:           doc.Err,
+		})
+	}
+
+	if err := cursor.Close(ctx); err != nil {
+		return nil, fmt.Errorf("could not close cursor: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Clear clears the command log.
+func (l *CommandLog) Clear(ctx context.Context) error {
+	if err := l.entries.Drop(ctx); err != nil {
+		return fmt.Errorf("could not drop collection: %w", err)
+	}
+
+	return nil
+}
+
+// Close implements the Close method of the eventhorizon.CommandLog interface.
+func (l *CommandLog) Close() error {
+	if l.clientOwnership == externalClient {
+		// Don't close a client we don't own.
+		return nil
+	}
+
+	return l.client.Disconnect(context.Background())
+}
+
+// entryDoc is the DB representation of a command log entry.
+type entryDoc struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty"`
+	EntryID       uuid.UUID           `bson:"entry_id"`
+	CommandType   eh.CommandType      `bson:"command_type"`
+	AggregateType eh.AggregateType    `bson:"aggregate_type"`
+	AggregateID   uuid.UUID           `bson:"aggregate_id"`
+	Data          []byte              `bson:"data"`
+	Timestamp     time.Time           `bson:"timestamp"`
+	Status        eh.CommandLogStatus `bson:"status"`
+	Err           string              `bson:"error,omitempty"`
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/looplab/eventhorizon/commandlog"
+)
+
+func TestCommandLogIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	l, err := NewCommandLog(url, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if l == nil {
+		t.Fatal("there should be a command log")
+	}
+
+	defer l.Close()
+
+	commandlog.AcceptanceTest(t, l, context.Background())
+}
+
+func TestWithCollectionNameIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	l, err := NewCommandLog(url, db, WithCollectionName("foo-commands"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer l.Close()
+
+	if l.entries.Name() != "foo-commands" {
+		t.Fatal("collection name should use custom collection name")
+	}
+}
+
+func makeDB(t *testing.T) (string, string) {
+	// Use MongoDB in Docker with fallback to localhost.
+	url := os.Getenv("MONGODB_ADDR")
+	if url == "" {
+		url = "localhost:27017"
+	}
+
+	url = "mongodb://" + url
+
+	// Get a random DB name.
+	bs := make([]byte, 4)
+	if _, err := rand.Read(bs); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(bs)
+
+	t.Log("using DB:", db)
+
+	return url, db
+}
@@ -16,6 +16,11 @@ package eventhorizon
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,6 +36,7 @@ const (
 	aggregateIDKeyStr   = "eh_aggregate_id"
 	aggregateTypeKeyStr = "eh_aggregate_type"
 	commandTypeKeyStr   = "eh_command_type"
+	processingKeyStr    = "eh_processing_start"
 )
 
 func init() {
@@ -46,7 +52,11 @@ func init() {
 		if commandType, ok := CommandTypeFromContext(ctx); ok {
 			vals[commandTypeKeyStr] = string(commandType)
 		}
-	})
+
+		if start, ok := ProcessingStartFromContext(ctx); ok {
+			vals[processingKeyStr] = start.Format(time.RFC3339Nano)
+		}
+	}, aggregateIDKeyStr, aggregateTypeKeyStr, commandTypeKeyStr, processingKeyStr)
 
 	RegisterContextUnmarshaler(func(ctx context.Context, vals map[string]interface{}) context.Context {
 		if aggregateIDStr, ok := vals[aggregateIDKeyStr].(string); ok {
@@ -63,6 +73,12 @@ func init() {
 			ctx = NewContextWithCommandType(ctx, CommandType(commandType))
 		}
 
+		if startStr, ok := vals[processingKeyStr].(string); ok {
+			if start, err := time.Parse(time.RFC3339Nano, startStr); err == nil {
+				ctx = NewContextWithProcessingStart(ctx, start)
+			}
+		}
+
 		return ctx
 	})
 }
@@ -73,6 +89,9 @@ const (
 	aggregateIDKey contextKey = iota
 	aggregateTypeKey
 	commandTypeKey
+	clockKey
+	processingKey
+	provisionalReplyKey
 )
 
 // AggregateIDFromContext return the command type from the context.
@@ -114,6 +133,7 @@ func NewContextWithCommandType(ctx context.Context, commandType CommandType) con
 // Private context marshaling funcs.
 var (
 	contextMarshalFuncs   = []ContextMarshalFunc{}
+	contextMarshalKeys    = map[string]struct{}{}
 	contextMarshalFuncsMu = sync.RWMutex{}
 
 	contextUnmarshalFuncs   = []ContextUnmarshalFunc{}
@@ -122,14 +142,57 @@ var (
 
 // ContextMarshalFunc is a function that marshalls any context values to a map,
 // used for sending context on the wire.
+//
+// The values must be of basic types (bools, numbers and strings) or slices and
+// maps with string keys of basic types, which can be sent with all codecs, see
+// CheckContextValues. Note that the unmarshaled values may be of other types,
+// for example numbers are unmarshaled as float64 from JSON, and that named
+// types are unmarshaled as the basic type.
 type ContextMarshalFunc func(context.Context, map[string]interface{})
 
 // RegisterContextMarshaler registers a marshaler function used by MarshalContext.
-func RegisterContextMarshaler(f ContextMarshalFunc) {
+// The keys of the values set by the function should be declared, to be listed
+// by RegisteredContextKeys.
+func RegisterContextMarshaler(f ContextMarshalFunc, keys ...string) {
 	contextMarshalFuncsMu.Lock()
 	defer contextMarshalFuncsMu.Unlock()
 
 	contextMarshalFuncs = append(contextMarshalFuncs, f)
+
+	for _, key := range keys {
+		contextMarshalKeys[key] = struct{}{}
+	}
+}
+
+// RegisteredContextKeys returns the sorted keys of the context values that are
+// marshaled, as declared when registering the marshalers, for example to debug
+// why a value is not propagated between services.
+func RegisteredContextKeys() []string {
+	contextMarshalFuncsMu.RLock()
+	defer contextMarshalFuncsMu.RUnlock()
+
+	keys := make([]string, 0, len(contextMarshalKeys))
+	for key := range contextMarshalKeys {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// RegisteredContextMarshalers returns the function names of the registered
+// marshalers, in the order they are called by MarshalContext.
+func RegisteredContextMarshalers() []string {
+	contextMarshalFuncsMu.RLock()
+	defer contextMarshalFuncsMu.RUnlock()
+
+	names := make([]string, 0, len(contextMarshalFuncs))
+	for _, f := range contextMarshalFuncs {
+		names = append(names, funcName(f))
+	}
+
+	return names
 }
 
 // MarshalContext marshals a context into a map.
@@ -155,6 +218,57 @@ func MarshalContext(ctx context.Context) map[string]interface{} {
 	return allVals
 }
 
+// ErrInvalidContextValue is when a marshaled context value is not of a basic type.
+var ErrInvalidContextValue = errors.New("invalid context value")
+
+// CheckContextValues checks that all marshaled context values are of basic
+// types, see ContextMarshalFunc. Codecs should use it before marshaling the
+// values, to not silently corrupt other values such as structs.
+func CheckContextValues(vals map[string]interface{}) error {
+	for key, val := range vals {
+		if val != nil && !isBasicValue(reflect.ValueOf(val)) {
+			return fmt.Errorf("%w: %s (%T)", ErrInvalidContextValue, key, val)
+		}
+	}
+
+	return nil
+}
+
+func isBasicValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Interface:
+		return v.IsNil() || isBasicValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isBasicValue(v.Index(i)) {
+				return false
+			}
+		}
+
+		return true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return false
+		}
+
+		iter := v.MapRange()
+		for iter.Next() {
+			if !isBasicValue(iter.Value()) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return false
+	}
+}
+
 // ContextUnmarshalFunc is a function that marshalls any context values to a map,
 // used for sending context on the wire.
 type ContextUnmarshalFunc func(context.Context, map[string]interface{}) context.Context
@@ -167,6 +281,27 @@ func RegisterContextUnmarshaler(f ContextUnmarshalFunc) {
 	contextUnmarshalFuncs = append(contextUnmarshalFuncs, f)
 }
 
+// RegisteredContextUnmarshalers returns the function names of the registered
+// unmarshalers, in the order they are called by UnmarshalContext.
+func RegisteredContextUnmarshalers() []string {
+	contextUnmarshalFuncsMu.RLock()
+	defer contextUnmarshalFuncsMu.RUnlock()
+
+	names := make([]string, 0, len(contextUnmarshalFuncs))
+	for _, f := range contextUnmarshalFuncs {
+		names = append(names, funcName(f))
+	}
+
+	return names
+}
+
+// funcName returns the full name of a function, for example
+// "github.com/looplab/eventhorizon/namespace.init.0.func1" for a function
+// registered in an init function.
+func funcName(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
 // UnmarshalContext unmarshals a context from a map.
 func UnmarshalContext(ctx context.Context, vals map[string]interface{}) context.Context {
 	contextUnmarshalFuncsMu.RLock()
@@ -183,6 +318,77 @@ func UnmarshalContext(ctx context.Context, vals map[string]interface{}) context.
 	return ctx
 }
 
+// Private context metadata keys.
+var (
+	contextMetadataKeys   = map[string]struct{}{}
+	contextMetadataKeysMu = sync.RWMutex{}
+)
+
+// RegisterContextMetadataKeys registers keys of marshaled context values (as
+// set by the funcs registered with RegisterContextMarshaler) that should be
+// propagated as metadata to events, for example the acting user of a command.
+// They are added when creating events with the WithContextMetadata option, which
+// AggregateBase.AppendEventCtx in aggregatestore/events does for all appended
+// events.
+func RegisterContextMetadataKeys(keys ...string) {
+	contextMetadataKeysMu.Lock()
+	defer contextMetadataKeysMu.Unlock()
+
+	for _, key := range keys {
+		contextMetadataKeys[key] = struct{}{}
+	}
+}
+
+// ContextMetadata returns the marshaled context values for the keys registered
+// with RegisterContextMetadataKeys, or nil if there are none.
+func ContextMetadata(ctx context.Context) map[string]interface{} {
+	contextMetadataKeysMu.RLock()
+	defer contextMetadataKeysMu.RUnlock()
+
+	if len(contextMetadataKeys) == 0 {
+		return nil
+	}
+
+	var metadata map[string]interface{}
+
+	for key, val := range MarshalContext(ctx) {
+		if _, ok := contextMetadataKeys[key]; !ok {
+			continue
+		}
+
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+
+		metadata[key] = val
+	}
+
+	return metadata
+}
+
+// ContextFromMetadata unmarshals the metadata of an event for the keys
+// registered with RegisterContextMetadataKeys into the context, the reverse of
+// ContextMetadata. Useful when handling events to have the same context values
+// as when the events were created.
+func ContextFromMetadata(ctx context.Context, metadata map[string]interface{}) context.Context {
+	contextMetadataKeysMu.RLock()
+	defer contextMetadataKeysMu.RUnlock()
+
+	vals := map[string]interface{}{}
+
+	for key := range contextMetadataKeys {
+		if val, ok := metadata[key]; ok {
+			vals[key] = val
+		}
+	}
+
+	if len(vals) == 0 {
+		return ctx
+	}
+
+	return UnmarshalContext(ctx, vals)
+}
+
 // CopyContext copies all values that are registered and exists in the `from`
 // context to the `to` context. It basically runs a marshal/unmarshal back-to-back.
 func CopyContext(from, to context.Context) context.Context {
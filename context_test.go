@@ -16,7 +16,12 @@ package eventhorizon
 
 import (
 	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestContextMarshaler(t *testing.T) {
@@ -99,6 +104,117 @@ func TestCopyContext(t *testing.T) {
 	}
 }
 
+func TestContextMetadata(t *testing.T) {
+	ctx := WithContextTestOne(context.Background(), "testval")
+
+	if md := ContextMetadata(ctx); md != nil {
+		t.Error("there should be no metadata:", md)
+	}
+
+	RegisterContextMetadataKeys(contextTestKeyOneStr)
+
+	if md := ContextMetadata(context.Background()); md != nil {
+		t.Error("there should be no metadata:", md)
+	}
+
+	expected := map[string]interface{}{contextTestKeyOneStr: "testval"}
+	if md := ContextMetadata(ctx); !reflect.DeepEqual(md, expected) {
+		t.Error("the metadata should be correct:", md)
+	}
+
+	e := NewEvent("test", nil, time.Now(), WithContextMetadata(ctx))
+	if !reflect.DeepEqual(e.Metadata(), expected) {
+		t.Error("the event metadata should be correct:", e.Metadata())
+	}
+
+	// Metadata set on the event takes precedence.
+	e = NewEvent("test", nil, time.Now(),
+		WithMetadata(map[string]interface{}{contextTestKeyOneStr: "other", "num": 1}),
+		WithContextMetadata(ctx),
+	)
+	expected = map[string]interface{}{contextTestKeyOneStr: "other", "num": 1}
+
+	if !reflect.DeepEqual(e.Metadata(), expected) {
+		t.Error("the event metadata should be correct:", e.Metadata())
+	}
+
+	// The metadata can be added back to a context.
+	if val, ok := ContextTestOne(ContextFromMetadata(context.Background(), e.Metadata())); !ok || val != "other" {
+		t.Error("the context should be correct:", val)
+	}
+
+	if _, ok := ContextTestOne(ContextFromMetadata(context.Background(), nil)); ok {
+		t.Error("the context should have no value")
+	}
+}
+
+func TestCheckContextValues(t *testing.T) {
+	type namedString string
+
+	testCases := map[string]struct {
+		val   interface{}
+		valid bool
+	}{
+		"nil":           {nil, true},
+		"bool":          {true, true},
+		"string":        {"string", true},
+		"named string":  {namedString("string"), true},
+		"int":           {42, true},
+		"float":         {4.2, true},
+		"slice":         {[]interface{}{"a", 1, nil}, true},
+		"string slice":  {[]string{"a", "b"}, true},
+		"map":           {map[string]interface{}{"a": 1, "b": []int{1}}, true},
+		"struct":        {struct{ A string }{"a"}, false},
+		"time":          {time.Now(), false},
+		"pointer":       {&struct{}{}, false},
+		"int map":       {map[int]string{1: "a"}, false},
+		"nested struct": {[]interface{}{"a", struct{}{}}, false},
+		"nested map":    {map[string]interface{}{"a": map[int]int{}}, false},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := CheckContextValues(map[string]interface{}{
+				"ok":  "value",
+				"val": tc.val,
+			})
+			if tc.valid && err != nil {
+				t.Error("there should be no error:", err)
+			} else if !tc.valid && !errors.Is(err, ErrInvalidContextValue) {
+				t.Error("there should be an invalid context value error:", err)
+			}
+		})
+	}
+}
+
+func TestRegisteredContextKeys(t *testing.T) {
+	RegisterContextMarshaler(marshalTestRegistry, "context_test_b", "context_test_a")
+
+	keys := RegisteredContextKeys()
+
+	for _, key := range []string{aggregateIDKeyStr, processingKeyStr, "context_test_a", "context_test_b"} {
+		if !containsString(keys, key) {
+			t.Error("the key should be registered:", key, keys)
+		}
+	}
+
+	if !sort.StringsAreSorted(keys) {
+		t.Error("the keys should be sorted:", keys)
+	}
+
+	marshalers := RegisteredContextMarshalers()
+	if len(marshalers) != len(contextMarshalFuncs) ||
+		marshalers[len(marshalers)-1] != "github.com/looplab/eventhorizon.marshalTestRegistry" {
+		t.Error("the marshalers should be listed in order:", marshalers)
+	}
+
+	unmarshalers := RegisteredContextUnmarshalers()
+	if len(unmarshalers) != len(contextUnmarshalFuncs) ||
+		!strings.HasPrefix(unmarshalers[0], "github.com/looplab/eventhorizon.init.") {
+		t.Error("the unmarshalers should be listed in order:", unmarshalers)
+	}
+}
+
 type contextTestKey int
 
 const (
@@ -121,3 +237,15 @@ func ContextTestOne(ctx context.Context) (string, bool) {
 
 	return val, ok
 }
+
+func marshalTestRegistry(ctx context.Context, vals map[string]interface{}) {}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
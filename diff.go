@@ -0,0 +1,183 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Difference is a difference between two event streams, see DiffStreams.
+type Difference struct {
+	// Version is the version of the differing events.
+	Version int
+	// Field is the path of the differing field, for example "event_type",
+	// "data.Address.City", "data.Items[2]" or "metadata.key". It is "event" if
+	// the event is missing in one of the streams.
+	Field string
+	// A is the value in the first stream, nil if missing.
+	A interface{}
+	// B is the value in the second stream, nil if missing.
+	B interface{}
+}
+
+// String implements the String method of the fmt.Stringer interface.
+func (d Difference) String() string {
+	return fmt.Sprintf("v%d %s: %v != %v", d.Version, d.Field, d.A, d.B)
+}
+
+// DiffStreams compares the events of two streams of an aggregate by version,
+// for example to debug diverging histories in different environments. The
+// differences are ordered by version, the first difference is at the first
+// diverging version. Event data and metadata are compared field by field using
+// reflection. Events that are only in one of the streams, for example extra
+// trailing events, are reported as a difference of the "event" field. Returns
+// no differences if the streams are equal.
+func DiffStreams(a, b []Event) []Difference {
+	var diffs []Difference
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(a):
+			diffs = append(diffs, Difference{Version: b[i].Version(), Field: "event", B: b[i]})
+		case i >= len(b):
+			diffs = append(diffs, Difference{Version: a[i].Version(), Field: "event", A: a[i]})
+		default:
+			diffs = append(diffs, diffEvents(a[i], b[i])...)
+		}
+	}
+
+	return diffs
+}
+
+// diffEvents returns the differences between two events.
+func diffEvents(a, b Event) []Difference {
+	d := &differ{version: a.Version()}
+
+	d.compare("event_type", a.EventType(), b.EventType())
+	d.compare("version", a.Version(), b.Version())
+	d.compare("aggregate_type", a.AggregateType(), b.AggregateType())
+	d.compare("aggregate_id", a.AggregateID(), b.AggregateID())
+
+	if !a.Timestamp().Equal(b.Timestamp()) {
+		d.add("timestamp", a.Timestamp(), b.Timestamp())
+	}
+
+	d.diff("data", reflect.ValueOf(a.Data()), reflect.ValueOf(b.Data()))
+	d.diff("metadata", reflect.ValueOf(a.Metadata()), reflect.ValueOf(b.Metadata()))
+
+	return d.diffs
+}
+
+// differ collects the field differences of two events.
+type differ struct {
+	version int
+	diffs   []Difference
+}
+
+func (d *differ) add(field string, a, b interface{}) {
+	d.diffs = append(d.diffs, Difference{Version: d.version, Field: field, A: a, B: b})
+}
+
+func (d *differ) compare(field string, a, b interface{}) {
+	if !reflect.DeepEqual(a, b) {
+		d.add(field, a, b)
+	}
+}
+
+// diff compares two values recursively, adding a difference for each differing
+// field, map key or slice element.
+func (d *differ) diff(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.add(path, valueInterface(a), valueInterface(b))
+		}
+
+		return
+	}
+
+	if a.Type() != b.Type() {
+		d.add(path, valueInterface(a), valueInterface(b))
+
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(path, valueInterface(a), valueInterface(b))
+			}
+
+			return
+		}
+
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		// Structs with unexported fields, like time.Time, are compared as a whole.
+		for i := 0; i < a.NumField(); i++ {
+			if a.Type().Field(i).PkgPath != "" {
+				d.compare(path, valueInterface(a), valueInterface(b))
+
+				return
+			}
+		}
+
+		for i := 0; i < a.NumField(); i++ {
+			d.diff(path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, k := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			d.diff(path+"."+name, a.MapIndex(keys[name]), b.MapIndex(keys[name]))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < a.Len() || i < b.Len(); i++ {
+			var ai, bi reflect.Value
+			if i < a.Len() {
+				ai = a.Index(i)
+			}
+
+			if i < b.Len() {
+				bi = b.Index(i)
+			}
+
+			d.diff(fmt.Sprintf("%s[%d]", path, i), ai, bi)
+		}
+	default:
+		d.compare(path, valueInterface(a), valueInterface(b))
+	}
+}
+
+// valueInterface returns the value as an interface, or nil if not valid.
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+
+	return v.Interface()
+}
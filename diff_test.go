@@ -0,0 +1,107 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestDiffStreams(t *testing.T) {
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvent := func(version int, data *TestDiffData) Event {
+		return NewEvent(TestEventType, data, timestamp,
+			ForAggregate(TestAggregateType, id, version),
+			WithMetadata(map[string]interface{}{"env": "prod"}))
+	}
+
+	stream := func() []Event {
+		return []Event{
+			newEvent(1, &TestDiffData{Name: "a", Tags: []string{"x"}}),
+			newEvent(2, &TestDiffData{Name: "b", Address: TestDiffAddress{City: "Stockholm"}}),
+			newEvent(3, &TestDiffData{Name: "c", Counts: map[string]int{"x": 1}}),
+		}
+	}
+
+	// Identical streams.
+	if diffs := DiffStreams(stream(), stream()); len(diffs) != 0 {
+		t.Error("there should be no differences:", diffs)
+	}
+
+	// Extra trailing events.
+	a := stream()
+	b := append(stream(), newEvent(4, &TestDiffData{Name: "d"}))
+
+	diffs := DiffStreams(a, b)
+	if len(diffs) != 1 || diffs[0].Version != 4 || diffs[0].Field != "event" ||
+		diffs[0].A != nil || diffs[0].B != b[3] {
+		t.Error("the extra event should be a difference:", diffs)
+	}
+
+	if diffs := DiffStreams(b, a); len(diffs) != 1 || diffs[0].A != b[3] || diffs[0].B != nil {
+		t.Error("the extra event should be a difference:", diffs)
+	}
+
+	// Mid-stream data differences.
+	b = stream()
+	b[1] = newEvent(2, &TestDiffData{Name: "b", Address: TestDiffAddress{City: "Gothenburg"}})
+	b[2] = newEvent(3, &TestDiffData{Name: "c", Counts: map[string]int{"x": 2, "y": 1}, Tags: []string{"z"}})
+
+	expected := []Difference{
+		{Version: 2, Field: "data.Address.City", A: "Stockholm", B: "Gothenburg"},
+		{Version: 3, Field: "data.Tags[0]", A: nil, B: "z"},
+		{Version: 3, Field: "data.Counts.x", A: 1, B: 2},
+		{Version: 3, Field: "data.Counts.y", A: nil, B: 1},
+	}
+	if diffs := DiffStreams(stream(), b); !reflect.DeepEqual(diffs, expected) {
+		t.Error("the data differences should be correct:", diffs)
+	}
+
+	// Differences of the event.
+	b = stream()
+	b[0] = NewEvent(TestDiffOtherEventType, &TestDiffData{Name: "a", Tags: []string{"x"}}, timestamp.Add(time.Second),
+		ForAggregate(TestAggregateType, id, 1),
+		WithMetadata(map[string]interface{}{"env": "staging"}))
+
+	expected = []Difference{
+		{Version: 1, Field: "event_type", A: TestEventType, B: TestDiffOtherEventType},
+		{Version: 1, Field: "timestamp", A: timestamp, B: timestamp.Add(time.Second)},
+		{Version: 1, Field: "metadata.env", A: "prod", B: "staging"},
+	}
+	if diffs := DiffStreams(stream(), b); !reflect.DeepEqual(diffs, expected) {
+		t.Error("the event differences should be correct:", diffs)
+	}
+
+	if s := expected[0].String(); s != "v1 event_type: TestEvent != TestDiffOtherEvent" {
+		t.Error("the string should be correct:", s)
+	}
+}
+
+type TestDiffData struct {
+	Name    string
+	Address TestDiffAddress
+	Tags    []string
+	Counts  map[string]int
+}
+
+type TestDiffAddress struct {
+	City string
+}
+
+const TestDiffOtherEventType EventType = "TestDiffOtherEvent"
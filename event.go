@@ -15,8 +15,10 @@
 package eventhorizon
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -94,6 +96,27 @@ func WithMetadata(metadata map[string]interface{}) EventOption {
 	}
 }
 
+// WithContextMetadata adds the context values registered with
+// RegisterContextMetadataKeys as metadata when creating an event. Metadata that
+// is already set on the event takes precedence.
+func WithContextMetadata(ctx context.Context) EventOption {
+	md := ContextMetadata(ctx)
+
+	return func(e Event) {
+		if evt, ok := e.(*event); ok && len(md) > 0 {
+			if evt.metadata == nil {
+				evt.metadata = map[string]interface{}{}
+			}
+
+			for k, v := range md {
+				if _, ok := evt.metadata[k]; !ok {
+					evt.metadata[k] = v
+				}
+			}
+		}
+	}
+}
+
 // WithGlobalPosition sets the global event position in the metadata.
 func WithGlobalPosition(position int) EventOption {
 	md := map[string]interface{}{
@@ -211,6 +234,7 @@ var ErrEventDataNotRegistered = errors.New("event data not registered")
 
 // RegisterEventData registers an event data factory for a type. The factory is
 // used to create concrete event data structs when loading from the database.
+// Use ValidateEventData to check that the data can be stored with a codec.
 //
 // An example would be:
 //     RegisterEventData(MyEventType, func() Event { return &MyEventData{} })
@@ -249,18 +273,128 @@ func UnregisterEventData(eventType EventType) {
 	delete(eventDataFactories, eventType)
 }
 
+// RegisterEventTypeAlias registers an old name of a renamed event type, to be
+// able to decode stored events with the old type. CreateEventData creates the
+// data of the new type for the old type and the codecs and event stores load
+// the events with the new type, see ResolveEventType. The old type can not have
+// event data registered.
+//
+// An example would be:
+//     RegisterEventTypeAlias("OldEventType", MyEventType)
+func RegisterEventTypeAlias(oldType, newType EventType) {
+	if oldType == EventType("") || newType == EventType("") {
+		panic("eventhorizon: attempt to register alias of empty event type")
+	}
+
+	eventDataFactoriesMu.Lock()
+	defer eventDataFactoriesMu.Unlock()
+
+	if _, ok := eventDataFactories[oldType]; ok {
+		panic(fmt.Sprintf("eventhorizon: registering alias of registered type %q", oldType))
+	}
+
+	if _, ok := eventTypeAliases[oldType]; ok {
+		panic(fmt.Sprintf("eventhorizon: registering duplicate alias for %q", oldType))
+	}
+
+	if resolveEventType(newType) == oldType {
+		panic(fmt.Sprintf("eventhorizon: registering cyclic alias for %q", oldType))
+	}
+
+	eventTypeAliases[oldType] = newType
+}
+
+// ResolveEventType returns the current type of an event type registered as an
+// alias with RegisterEventTypeAlias, following renames of renamed types. Other
+// event types are returned as is.
+func ResolveEventType(eventType EventType) EventType {
+	eventDataFactoriesMu.RLock()
+	defer eventDataFactoriesMu.RUnlock()
+
+	return resolveEventType(eventType)
+}
+
+// resolveEventType must be called with the lock held.
+func resolveEventType(eventType EventType) EventType {
+	for {
+		newType, ok := eventTypeAliases[eventType]
+		if !ok {
+			return eventType
+		}
+
+		eventType = newType
+	}
+}
+
 // CreateEventData creates an event data of a type using the factory registered
-// with RegisterEventData.
+// with RegisterEventData, or of the current type of an alias.
 func CreateEventData(eventType EventType) (EventData, error) {
 	eventDataFactoriesMu.RLock()
 	defer eventDataFactoriesMu.RUnlock()
 
-	if factory, ok := eventDataFactories[eventType]; ok {
+	if factory, ok := eventDataFactories[resolveEventType(eventType)]; ok {
 		return factory(), nil
 	}
 
 	return nil, ErrEventDataNotRegistered
 }
 
+// ErrInvalidEventData is when the event data of a type can not be marshaled
+// and unmarshaled by a codec.
+var ErrInvalidEventData = errors.New("invalid event data")
+
+// ValidateEventData checks that the event data registered for a type can be
+// marshaled and unmarshaled by the codec, by round-tripping an event with the
+// zero value of the data. Run it in tests or at startup to fail fast on data
+// that can not be stored, for example with channel or func fields, instead of
+// when saving events.
+func ValidateEventData(ctx context.Context, codec EventCodec, eventType EventType) error {
+	data, err := CreateEventData(eventType)
+	if err != nil {
+		return fmt.Errorf("could not create event data for %s: %w", eventType, err)
+	}
+
+	event := NewEvent(eventType, data, time.Now(),
+		ForAggregate(AggregateType("Validation"), uuid.New(), 1))
+
+	b, err := codec.MarshalEvent(ctx, event)
+	if err != nil {
+		return fmt.Errorf("%w for %s: could not marshal: %w", ErrInvalidEventData, eventType, err)
+	}
+
+	if _, _, err := codec.UnmarshalEvent(ctx, b); err != nil {
+		return fmt.Errorf("%w for %s: could not unmarshal: %w", ErrInvalidEventData, eventType, err)
+	}
+
+	return nil
+}
+
+// ValidateAllEventData validates the event data of all registered types, see
+// ValidateEventData, and returns the joined errors of the invalid types.
+func ValidateAllEventData(ctx context.Context, codec EventCodec) error {
+	eventDataFactoriesMu.RLock()
+	eventTypes := make([]EventType, 0, len(eventDataFactories))
+
+	for eventType := range eventDataFactories {
+		eventTypes = append(eventTypes, eventType)
+	}
+	eventDataFactoriesMu.RUnlock()
+
+	sort.Slice(eventTypes, func(i, j int) bool {
+		return eventTypes[i] < eventTypes[j]
+	})
+
+	var errs []error
+
+	for _, eventType := range eventTypes {
+		if err := ValidateEventData(ctx, codec, eventType); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 var eventDataFactories = make(map[EventType]func() EventData)
+var eventTypeAliases = make(map[EventType]EventType)
 var eventDataFactoriesMu sync.RWMutex
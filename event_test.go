@@ -15,8 +15,11 @@
 package eventhorizon
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -170,6 +173,62 @@ func TestUnregisterEventTwice(t *testing.T) {
 	UnregisterEventData(TestEventUnregisterTwiceType)
 }
 
+func TestRegisterEventTypeAlias(t *testing.T) {
+	RegisterEventData(TestEventRenamedType, func() EventData {
+		return &TestEventRegisterData{}
+	})
+	defer UnregisterEventData(TestEventRenamedType)
+
+	RegisterEventTypeAlias(TestEventOldType, TestEventRenamedType)
+	RegisterEventTypeAlias(TestEventOlderType, TestEventOldType)
+
+	for _, eventType := range []EventType{TestEventRenamedType, TestEventOldType, TestEventOlderType} {
+		if resolved := ResolveEventType(eventType); resolved != TestEventRenamedType {
+			t.Error("the event type should be resolved:", eventType, resolved)
+		}
+
+		data, err := CreateEventData(eventType)
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if _, ok := data.(*TestEventRegisterData); !ok {
+			t.Errorf("the event type should be correct: %T", data)
+		}
+	}
+
+	if resolved := ResolveEventType(TestEventType); resolved != TestEventType {
+		t.Error("the event type should not be resolved:", resolved)
+	}
+
+	panics := map[string]func(){
+		"eventhorizon: attempt to register alias of empty event type": func() {
+			RegisterEventTypeAlias("", TestEventRenamedType)
+		},
+		"eventhorizon: registering alias of registered type \"TestEventRenamed\"": func() {
+			RegisterEventTypeAlias(TestEventRenamedType, TestEventType)
+		},
+		"eventhorizon: registering duplicate alias for \"TestEventOld\"": func() {
+			RegisterEventTypeAlias(TestEventOldType, TestEventType)
+		},
+		"eventhorizon: registering cyclic alias for \"TestEventCycleB\"": func() {
+			RegisterEventTypeAlias(TestEventCycleAType, TestEventCycleBType)
+			RegisterEventTypeAlias(TestEventCycleBType, TestEventCycleAType)
+		},
+	}
+
+	for expected, f := range panics {
+		func() {
+			defer func() {
+				if r := recover(); r != expected {
+					t.Error("there should have been a panic:", r, expected)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
 const (
 	TestEventType                EventType = "TestEvent"
 	TestEventRegisterType        EventType = "TestEventRegister"
@@ -177,6 +236,11 @@ const (
 	TestEventRegisterTwiceType   EventType = "TestEventRegisterTwice"
 	TestEventUnregisterEmptyType EventType = ""
 	TestEventUnregisterTwiceType EventType = "TestEventUnregisterTwice"
+	TestEventRenamedType         EventType = "TestEventRenamed"
+	TestEventOldType             EventType = "TestEventOld"
+	TestEventOlderType           EventType = "TestEventOlder"
+	TestEventCycleAType          EventType = "TestEventCycleA"
+	TestEventCycleBType          EventType = "TestEventCycleB"
 )
 
 type TestEventData struct {
@@ -205,3 +269,96 @@ func (t TestCommandID) CommandType() CommandType {
 	return CommandType("TestCommandID")
 }
 func (t TestCommandID) CommandID() uuid.UUID { return t.CmdID }
+
+func TestValidateEventData(t *testing.T) {
+	ctx := context.Background()
+	codec := &dataCodec{}
+
+	RegisterEventData(TestEventValidType, func() EventData {
+		return &TestEventValidData{}
+	})
+	defer UnregisterEventData(TestEventValidType)
+
+	if err := ValidateEventData(ctx, codec, TestEventValidType); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	RegisterEventData(TestEventInvalidType, func() EventData {
+		return &TestEventInvalidData{}
+	})
+
+	err := ValidateEventData(ctx, codec, TestEventInvalidType)
+	if !errors.Is(err, ErrInvalidEventData) {
+		t.Error("there should be an invalid event data error:", err)
+	}
+
+	var typeErr *json.UnsupportedTypeError
+	if !errors.As(err, &typeErr) {
+		t.Error("the error should wrap the codec error:", err)
+	}
+
+	if err := ValidateAllEventData(ctx, codec); !errors.Is(err, ErrInvalidEventData) ||
+		!strings.Contains(err.Error(), string(TestEventInvalidType)) {
+		t.Error("there should be an invalid event data error:", err)
+	}
+
+	UnregisterEventData(TestEventInvalidType)
+
+	if err := ValidateAllEventData(ctx, codec); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := ValidateEventData(ctx, codec, TestEventInvalidType); !errors.Is(err, ErrEventDataNotRegistered) {
+		t.Error("there should be a not registered error:", err)
+	}
+}
+
+const (
+	TestEventValidType   EventType = "TestEventValid"
+	TestEventInvalidType EventType = "TestEventInvalid"
+)
+
+type TestEventValidData struct {
+	Content string
+}
+
+type TestEventInvalidData struct {
+	Content string
+	Updates chan string
+}
+
+// dataCodec is a codec that only marshals the event data as JSON.
+type dataCodec struct{}
+
+func (c *dataCodec) MarshalEvent(ctx context.Context, event Event) ([]byte, error) {
+	b, err := json.Marshal(event.Data())
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"type": event.EventType(),
+		"data": json.RawMessage(b),
+	})
+}
+
+func (c *dataCodec) UnmarshalEvent(ctx context.Context, b []byte) (Event, context.Context, error) {
+	var e struct {
+		Type EventType       `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, nil, err
+	}
+
+	data, err := CreateEventData(e.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := json.Unmarshal(e.Data, data); err != nil {
+		return nil, nil, err
+	}
+
+	return NewEvent(e.Type, data, time.Now()), ctx, nil
+}
@@ -17,17 +17,29 @@ package eventhorizon
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 // EventBus is an EventHandler that distributes published events to all matching
 // handlers that are registered, but only one of each type will handle the event.
-// Events are not guaranteed to be handeled in order.
+// Events are not guaranteed to be handeled in order, unless the event bus
+// reports an ordering guarantee, see OrderingReporter.
+//
+// An event is acknowledged to the broker of an event bus only after a handler
+// has handled it without error. If the handler returns an error, the error is
+// sent on Errors and the event is not acknowledged (or negatively acknowledged
+// if the broker supports it), which makes the broker redeliver the event to the
+// handler later. Handlers must therefore be idempotent, as events are delivered
+// at least once. Event buses without a broker, like the local event bus, do not
+// redeliver events and only send the error on Errors. Use the
+// eventbus.RedeliveryAcceptanceTest to verify the redelivery of an event bus.
 type EventBus interface {
 	EventHandler
 
 	// AddHandler adds a handler for an event. Returns an error if either the
 	// matcher or handler is nil, the handler is already added or there was some
 	// other problem adding the handler (for networked handlers for example).
+	// Events that the handler fails to handle are redelivered, see EventBus.
 	AddHandler(context.Context, EventMatcher, EventHandler) error
 
 	// Errors returns an error channel where async handling errors are sent.
@@ -37,6 +49,80 @@ type EventBus interface {
 	Close() error
 }
 
+// EventBusSyncPublisher is an event bus that can publish an event and wait for
+// all matching handlers to handle it, returning the outcome of each handler.
+// Useful for critical flows that must know that an event has been handled.
+//
+// Only event buses where all handlers run in the same process can implement
+// it, such as the local event bus. Event buses using a broker (Kafka, NATS,
+// Redis or GCP Pub/Sub) deliver events to handlers in other processes, which
+// may be offline, be added later or get events redelivered and replayed, so
+// there is no point in time when all handlers are known to be done with an
+// event. Errors from those event buses are only available from Errors.
+type EventBusSyncPublisher interface {
+	// PublishEventSync publishes an event and waits for all matching handlers
+	// to handle it. Returns an error if the event could not be published or if
+	// the context is done before all handlers are done, in which case the
+	// result has the outcomes of the handlers that are done. If any handlers
+	// failed the error is the errors of all failed handlers, see
+	// DeliveryResult.Err.
+	PublishEventSync(ctx context.Context, event Event) (DeliveryResult, error)
+}
+
+// DeliveryResult is the result of publishing an event synchronously.
+type DeliveryResult struct {
+	// Outcomes are the outcomes of all matching handlers.
+	Outcomes []HandlerOutcome
+}
+
+// Failed returns the outcomes of the handlers that failed to handle the event.
+func (r DeliveryResult) Failed() []HandlerOutcome {
+	var failed []HandlerOutcome
+
+	for _, o := range r.Outcomes {
+		if o.Err != nil {
+			failed = append(failed, o)
+		}
+	}
+
+	return failed
+}
+
+// Err returns the errors of all handlers that failed to handle the event,
+// joined with errors.Join and each prefixed with the handler type, or nil if no
+// handler failed. The errors can be matched with errors.Is and errors.As, or
+// iterated by unwrapping them with Unwrap() []error.
+func (r DeliveryResult) Err() error {
+	var errs []error
+
+	for _, o := range r.Failed() {
+		errs = append(errs, fmt.Errorf("%s: %w", o.HandlerType, o.Err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// HandlerOutcome is the outcome of handling an event by a handler.
+type HandlerOutcome struct {
+	// HandlerType is the type of the handler.
+	HandlerType EventHandlerType
+	// Err is the error from the handler, or nil if the event was handled.
+	Err error
+}
+
+// EventBusPauser is an event bus (or outbox) that can pause and resume the
+// handling of events by an added handler without removing it, for example to
+// stop a projector during a maintenance window. Events published while the
+// handler is paused are kept by the event bus and handled in order after
+// resuming. An event that is being handled when pausing is finished.
+type EventBusPauser interface {
+	// Pause pauses the handling of events by the handler of a type.
+	Pause(ctx context.Context, t EventHandlerType) error
+
+	// Resume resumes the handling of events by the handler of a type.
+	Resume(ctx context.Context, t EventHandlerType) error
+}
+
 var (
 	// ErrMissingMatcher is returned when calling AddHandler without a matcher.
 	ErrMissingMatcher = errors.New("missing matcher")
@@ -44,6 +130,9 @@ var (
 	ErrMissingHandler = errors.New("missing handler")
 	// ErrHandlerAlreadyAdded is returned when calling AddHandler weth the same handler twice.
 	ErrHandlerAlreadyAdded = errors.New("handler already added")
+	// ErrHandlerNotAdded is returned when pausing or resuming a handler that is
+	// not added.
+	ErrHandlerNotAdded = errors.New("handler not added")
 )
 
 // EventBusError is an async error containing the error returned from a handler
@@ -0,0 +1,422 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bustest is a conformance test kit for implementations of
+// eventhorizon.EventBus. Run it from a test case of the implementation to check
+// that it behaves like the built-in event buses:
+//
+//	func TestEventBus(t *testing.T) {
+//		bustest.Run(t, func(t *testing.T) (eh.EventBus, eh.EventBus) {
+//			group := NewGroup()
+//
+//			return NewEventBus(WithGroup(group)), NewEventBus(WithGroup(group))
+//		}, time.Second)
+//	}
+//
+// Each contract is also exported as its own function, for buses that only
+// fulfill some of them. The timeout is used both as the time to wait for
+// handlers to be added, for buses that subscribe asynchronously, and as the
+// time to wait for events to be received.
+//
+// Buses legitimately differ in their ordering guarantee, which is only checked
+// for what the bus reports, see Ordering.
+package bustest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventbus"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// NewBuses creates two new buses in the same group of consumers, for example
+// two local buses sharing a local.Group or two network buses with the same app
+// ID, see GroupFanOut. Buses created by separate calls must not share events.
+type NewBuses func(t *testing.T) (eh.EventBus, eh.EventBus)
+
+// Run runs all contracts of the kit as subtests, each with new buses that are
+// closed after the contract.
+func Run(t *testing.T, newBuses NewBuses, timeout time.Duration) {
+	buses := func(t *testing.T) (eh.EventBus, eh.EventBus) {
+		bus1, bus2 := newBuses(t)
+		t.Cleanup(func() {
+			bus1.Close()
+			bus2.Close()
+		})
+
+		return bus1, bus2
+	}
+
+	t.Run("PublishSubscribe", func(t *testing.T) {
+		bus, _ := buses(t)
+		PublishSubscribe(t, bus, timeout)
+	})
+	t.Run("MatcherFiltering", func(t *testing.T) {
+		bus, _ := buses(t)
+		MatcherFiltering(t, bus, timeout)
+	})
+	t.Run("GroupFanOut", func(t *testing.T) {
+		bus1, bus2 := buses(t)
+		GroupFanOut(t, bus1, bus2, timeout)
+	})
+	t.Run("Ordering", func(t *testing.T) {
+		bus, _ := buses(t)
+		Ordering(t, bus, timeout)
+	})
+	t.Run("Shutdown", func(t *testing.T) {
+		// The first bus is closed by the contract.
+		bus1, bus2 := newBuses(t)
+		t.Cleanup(func() { bus2.Close() })
+		Shutdown(t, bus1, timeout)
+	})
+}
+
+// PublishSubscribe checks that a published event is received by an added
+// handler with its type, data, metadata and aggregate, and with the context
+// values registered with eh.RegisterContextMarshaler. Publishing without any
+// handlers is not an error, and a handler can only be added once per bus
+// (eh.ErrHandlerAlreadyAdded) and requires a matcher and a handler
+// (eh.ErrMissingMatcher and eh.ErrMissingHandler).
+func PublishSubscribe(t *testing.T, bus eh.EventBus, timeout time.Duration) {
+	ctx := mocks.WithContextOne(context.Background(), "testval")
+
+	if err := bus.HandleEvent(ctx, newEvent(uuid.New(), 1)); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := bus.AddHandler(ctx, nil, newRecorder("handler")); !errors.Is(err, eh.ErrMissingMatcher) {
+		t.Error("there should be a missing matcher error:", err)
+	}
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, nil); !errors.Is(err, eh.ErrMissingHandler) {
+		t.Error("there should be a missing handler error:", err)
+	}
+
+	h := newRecorder("handler")
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, newRecorder("handler")); !errors.Is(err, eh.ErrHandlerAlreadyAdded) {
+		t.Error("there should be a handler already added error:", err)
+	}
+
+	time.Sleep(timeout) // Need to wait here for handlers to be added.
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1),
+		eh.WithMetadata(map[string]interface{}{"meta": "data", "num": 42.0}),
+	)
+	if err := bus.HandleEvent(ctx, event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	events, ctxs := h.wait(1, timeout)
+	if len(events) != 1 {
+		t.Fatal("the event should be received once:", events)
+	}
+
+	if err := eh.CompareEvents(events[0], event); err != nil {
+		t.Error("the event was incorrect:", err)
+	}
+
+	if val, ok := mocks.ContextOne(ctxs[0]); !ok || val != "testval" {
+		t.Error("the context should be correct:", ctxs[0])
+	}
+}
+
+// MatcherFiltering checks that handlers only receive the events that match
+// their matcher, and that the events of other matchers do not block them.
+func MatcherFiltering(t *testing.T, bus eh.EventBus, timeout time.Duration) {
+	ctx := context.Background()
+
+	byEvent := newRecorder("by-event")
+	if err := bus.AddHandler(ctx, eh.MatchEvents{mocks.EventOtherType}, byEvent); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	byAggregate := newRecorder("by-aggregate")
+	if err := bus.AddHandler(ctx, eh.MatchAggregates{otherAggregateType}, byAggregate); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	all := newRecorder("all")
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, all); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	time.Sleep(timeout) // Need to wait here for handlers to be added.
+
+	event := newEvent(uuid.New(), 1)
+	otherEvent := eh.NewEvent(mocks.EventOtherType, nil, timestamp,
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+	otherAggregateEvent := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate(otherAggregateType, uuid.New(), 1))
+
+	for _, e := range []eh.Event{event, otherEvent, otherAggregateEvent} {
+		if err := bus.HandleEvent(ctx, e); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	if events, _ := all.wait(3, timeout); len(events) != 3 {
+		t.Error("all events should be received:", events)
+	}
+
+	// Wait for any unexpected events.
+	time.Sleep(timeout / 10)
+
+	if events := byEvent.events(); !eh.CompareEventSlices(events, []eh.Event{otherEvent}) {
+		t.Error("only the matching event type should be received:", events)
+	}
+
+	if events := byAggregate.events(); !eh.CompareEventSlices(events, []eh.Event{otherAggregateEvent}) {
+		t.Error("only the matching aggregate type should be received:", events)
+	}
+}
+
+// GroupFanOut checks how events are delivered to the handlers of buses in the
+// same group. Handlers of different types all receive every event (fan-out),
+// while handlers of the same type on different buses compete for the events,
+// each event is received by exactly one of them. The events can be published
+// on any bus of the group.
+func GroupFanOut(t *testing.T, bus1, bus2 eh.EventBus, timeout time.Duration) {
+	ctx := context.Background()
+
+	competing1 := newRecorder("competing")
+	if err := bus1.AddHandler(ctx, eh.MatchAll{}, competing1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	competing2 := newRecorder("competing")
+	if err := bus2.AddHandler(ctx, eh.MatchAll{}, competing2); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	fanOut1 := newRecorder("fan-out-1")
+	if err := bus1.AddHandler(ctx, eh.MatchAll{}, fanOut1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	fanOut2 := newRecorder("fan-out-2")
+	if err := bus2.AddHandler(ctx, eh.MatchAll{}, fanOut2); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	time.Sleep(timeout) // Need to wait here for handlers to be added.
+
+	const numEvents = 10
+
+	id := uuid.New()
+
+	for v := 1; v <= numEvents; v++ {
+		bus := bus1
+		if v%2 == 0 {
+			bus = bus2
+		}
+
+		if err := bus.HandleEvent(ctx, newEvent(id, v)); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	for _, h := range []*recorder{fanOut1, fanOut2} {
+		if events, _ := h.wait(numEvents, timeout); len(events) != numEvents {
+			t.Errorf("all events should be received by %s: %d", h.handlerType, len(events))
+		}
+	}
+
+	// Wait for the competing handlers to receive all events, and any duplicates.
+	deadline := time.Now().Add(timeout)
+	for len(competing1.events())+len(competing2.events()) < numEvents && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(timeout / 10)
+
+	received := map[int]int{}
+	for _, e := range append(competing1.events(), competing2.events()...) {
+		received[e.Version()]++
+	}
+
+	for v := 1; v <= numEvents; v++ {
+		if received[v] != 1 {
+			t.Errorf("the event v%d should be received once by the competing handlers: %d", v, received[v])
+		}
+	}
+}
+
+// Ordering checks the ordering guarantee reported by the bus, see
+// eh.OrderingReporter. Buses with global ordering must deliver the events to
+// a handler in the order they were published, buses with per aggregate
+// ordering must deliver the events of each aggregate in version order and
+// buses without a report must only deliver all events.
+func Ordering(t *testing.T, bus eh.EventBus, timeout time.Duration) {
+	eventbus.OrderingAcceptanceTest(t, bus, timeout)
+}
+
+// Shutdown checks that Close stops the bus cleanly. Close returns no error
+// within the timeout, after waiting for events that are being handled, and no
+// handlers are called after it returns. Publishing after Close may return an
+// error, but must not block or panic.
+func Shutdown(t *testing.T, bus eh.EventBus, timeout time.Duration) {
+	ctx := context.Background()
+
+	h := &slowHandler{
+		recorder: newRecorder("slow"),
+		started:  make(chan struct{}, 1),
+		delay:    timeout / 10,
+	}
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	time.Sleep(timeout) // Need to wait here for handlers to be added.
+
+	if err := bus.HandleEvent(ctx, newEvent(uuid.New(), 1)); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	select {
+	case <-h.started:
+	case <-time.After(timeout):
+		t.Fatal("did not receive event in time")
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- bus.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+	case <-time.After(timeout):
+		t.Fatal("the bus should be closed in time")
+	}
+
+	if events := h.events(); len(events) != 1 {
+		t.Error("the event being handled should be done before closing:", events)
+	}
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+
+		_ = bus.HandleEvent(ctx, newEvent(uuid.New(), 1))
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(timeout):
+		t.Fatal("publishing after closing should not block")
+	}
+
+	time.Sleep(timeout / 10)
+
+	if events := h.events(); len(events) != 1 {
+		t.Error("there should be no events handled after closing:", events)
+	}
+}
+
+const otherAggregateType eh.AggregateType = "BusTestOtherAggregate"
+
+var timestamp = time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+func newEvent(id uuid.UUID, version int) eh.Event {
+	return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, version))
+}
+
+// recorder records all handled events and their contexts.
+type recorder struct {
+	handlerType eh.EventHandlerType
+
+	mu   sync.Mutex
+	evts []eh.Event
+	ctxs []context.Context
+}
+
+func newRecorder(handlerType eh.EventHandlerType) *recorder {
+	return &recorder{handlerType: handlerType}
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (r *recorder) HandlerType() eh.EventHandlerType {
+	return r.handlerType
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (r *recorder) HandleEvent(ctx context.Context, event eh.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.evts = append(r.evts, event)
+	r.ctxs = append(r.ctxs, ctx)
+
+	return nil
+}
+
+func (r *recorder) events() []eh.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]eh.Event{}, r.evts...)
+}
+
+// wait waits until at least n events are received or the timeout, returning
+// the received events and contexts.
+func (r *recorder) wait(n int, timeout time.Duration) ([]eh.Event, []context.Context) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		r.mu.Lock()
+		if len(r.evts) >= n || time.Now().After(deadline) {
+			defer r.mu.Unlock()
+
+			return append([]eh.Event{}, r.evts...), append([]context.Context{}, r.ctxs...)
+		}
+		r.mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// slowHandler signals when it starts handling an event and takes a while to
+// handle it.
+type slowHandler struct {
+	*recorder
+	started chan struct{}
+	delay   time.Duration
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (h *slowHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	select {
+	case h.started <- struct{}{}:
+	default:
+	}
+
+	time.Sleep(h.delay)
+
+	return h.recorder.HandleEvent(ctx, event)
+}
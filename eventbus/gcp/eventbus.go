@@ -27,10 +27,20 @@ import (
 
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/codec/json"
+	"github.com/looplab/eventhorizon/uuid"
 )
 
-// EventBus is a local event bus that delegates handling of published events
-// to all matching registered handlers, in order of registration.
+// EventBus is an event bus using GCP Pub/Sub that delegates handling of
+// published events to all matching registered handlers.
+//
+// Events are published with the aggregate ID as ordering key and message
+// ordering is enabled on the topic and subscriptions, which makes the events of
+// an aggregate be handled in order. If publishing fails Pub/Sub pauses the
+// publishing for the aggregate, see PublishError. Messages are acked when the
+// event is handled. If a handler fails the message is negatively acked (Nack)
+// and redelivered with a backoff, and the following events of the aggregate are
+// not delivered to the handler until it succeeds. The handler errors are sent
+// on the Errors channel.
 type EventBus struct {
 	appID        string
 	client       *pubsub.Client
@@ -159,12 +169,49 @@ func (b *EventBus) HandleEvent(ctx context.Context, event eh.Event) error {
 	})
 
 	if _, err := res.Get(ctx); err != nil {
-		return fmt.Errorf("could not publish event: %w", err)
+		return &PublishError{
+			AggregateID: event.AggregateID(),
+			Err:         err,
+		}
 	}
 
 	return nil
 }
 
+// PublishError is when an event could not be published, or the context was done
+// while waiting for it to be published. If publishing failed Pub/Sub pauses the
+// publishing of events for the aggregate to not publish them out of order, and
+// later events fail with a pubsub.ErrPublishingPaused error until
+// ResumePublishing is called for the aggregate.
+type PublishError struct {
+	// AggregateID is the aggregate of the event, used as ordering key.
+	AggregateID uuid.UUID
+	// Err is the error.
+	Err error
+}
+
+// Error implements the Error method of the error interface.
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("could not publish event for aggregate %s: %s", e.AggregateID, e.Err)
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *PublishError) Unwrap() error {
+	return e.Err
+}
+
+// ResumePublishing resumes publishing of events for an aggregate after a
+// PublishError, typically after the failed events have been published again.
+func (b *EventBus) ResumePublishing(id uuid.UUID) {
+	b.topic.ResumePublish(id.String())
+}
+
+// OrderingGuarantee implements the OrderingGuarantee method of the
+// eventhorizon.OrderingReporter interface.
+func (b *EventBus) OrderingGuarantee() eh.OrderingGuarantee {
+	return eh.PerAggregateOrdering
+}
+
 // AddHandler implements the AddHandler method of the eventhorizon.EventBus interface.
 func (b *EventBus) AddHandler(ctx context.Context, m eh.EventMatcher, h eh.EventHandler) error {
 	if m == nil {
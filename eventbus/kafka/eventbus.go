@@ -29,8 +29,12 @@ import (
 	"github.com/looplab/eventhorizon/codec/json"
 )
 
-// EventBus is a local event bus that delegates handling of published events
+// EventBus is a Kafka event bus that delegates handling of published events
 // to all matching registered handlers, in order of registration.
+//
+// The offset of a message is committed when the event is handled. If the
+// handler fails the message is retried every second until it succeeds, before
+// any following messages of the partition are handled.
 type EventBus struct {
 	// TODO: Support multiple brokers.
 	addresses       []string
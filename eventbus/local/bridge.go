@@ -0,0 +1,102 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/codec/json"
+)
+
+// BridgeBus connects two event buses in the same process, as if they were
+// running in different services. All events published on one bus are marshaled
+// with a codec and unmarshaled before being published on the other bus, to
+// catch any event data or context values that would not survive being sent
+// over the wire. Useful for testing cross-service flows.
+type BridgeBus struct {
+	name  string
+	to    eh.EventHandler
+	codec eh.EventCodec
+}
+
+// NewBridgeBus creates a BridgeBus which publishes all events from one bus on
+// another bus (or any other event handler).
+func NewBridgeBus(ctx context.Context, from eh.EventBus, to eh.EventHandler, options ...BridgeOption) (*BridgeBus, error) {
+	if from == nil || to == nil {
+		return nil, eh.ErrMissingHandler
+	}
+
+	b := &BridgeBus{
+		name:  "bridge",
+		to:    to,
+		codec: &json.EventCodec{},
+	}
+
+	// Apply configuration options.
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(b)
+	}
+
+	if err := from.AddHandler(ctx, eh.MatchAll{}, b); err != nil {
+		return nil, fmt.Errorf("could not add bridge: %w", err)
+	}
+
+	return b, nil
+}
+
+// BridgeOption is an option setter used to configure creation of a BridgeBus.
+type BridgeOption func(*BridgeBus)
+
+// WithBridgeCodec uses the specified codec for the events sent over the bridge.
+func WithBridgeCodec(codec eh.EventCodec) BridgeOption {
+	return func(b *BridgeBus) {
+		b.codec = codec
+	}
+}
+
+// WithBridgeName uses a name for the bridge, needed when adding more than one
+// bridge to the same bus. The name is used as the handler type.
+func WithBridgeName(name string) BridgeOption {
+	return func(b *BridgeBus) {
+		b.name = name
+	}
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (b *BridgeBus) HandlerType() eh.EventHandlerType {
+	return eh.EventHandlerType(b.name)
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (b *BridgeBus) HandleEvent(ctx context.Context, event eh.Event) error {
+	data, err := b.codec.MarshalEvent(ctx, event)
+	if err != nil {
+		return fmt.Errorf("could not marshal event: %w", err)
+	}
+
+	// Only keep the context values that are sent with the event.
+	event, ctx, err = b.codec.UnmarshalEvent(context.Background(), data)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal event: %w", err)
+	}
+
+	return b.to.HandleEvent(ctx, event)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestBridgeBus(t *testing.T) {
+	busA := NewEventBus()
+	defer busA.Close()
+
+	busB := NewEventBus()
+	defer busB.Close()
+
+	ctx := context.Background()
+
+	if _, err := NewBridgeBus(ctx, busA, busB); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := NewBridgeBus(ctx, busA, busB); !errors.Is(err, eh.ErrHandlerAlreadyAdded) {
+		t.Error("there should be a handler already added error:", err)
+	}
+
+	h := mocks.NewEventHandler("service-b")
+	if err := busB.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Publish with data that does not survive marshaling.
+	data := &leakingData{Content: "event", notSent: "secret"}
+	event := eh.NewEvent(leakingEventType, data, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+
+	if err := busA.HandleEvent(mocks.WithContextOne(ctx, "testval"), event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !h.Wait(time.Second) {
+		t.Fatal("the event should be received over the bridge")
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	received := h.Events[0]
+	if received.EventType() != event.EventType() ||
+		received.AggregateID() != event.AggregateID() ||
+		received.Version() != event.Version() {
+		t.Error("the event should be correct:", received)
+	}
+
+	d, ok := received.Data().(*leakingData)
+	if !ok {
+		t.Fatal("the event data should be correct:", received.Data())
+	}
+
+	if d == data || d.Content != "event" {
+		t.Error("the event data should be round-tripped:", d)
+	}
+
+	if d.notSent != "" {
+		t.Error("the unexported field should not be sent:", d.notSent)
+	}
+
+	if val, ok := mocks.ContextOne(h.Context); !ok || val != "testval" {
+		t.Error("the context should be correct:", val)
+	}
+}
+
+const leakingEventType eh.EventType = "BridgeLeaking"
+
+func init() {
+	eh.RegisterEventData(leakingEventType, func() eh.EventData { return &leakingData{} })
+}
+
+// leakingData has a field that is lost when marshaled.
+type leakingData struct {
+	Content string
+	notSent string
+}
@@ -16,20 +16,38 @@ package local
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/backoff"
 	"github.com/looplab/eventhorizon/codec/json"
 )
 
 // DefaultQueueSize is the default queue size per handler for publishing events.
 var DefaultQueueSize = 1000
 
+var (
+	// ErrQueueFull is when an event could not be published to a handler
+	// because its queue is full.
+	ErrQueueFull = errors.New("publish queue full")
+	// ErrEventBusClosed is when the event bus is closed while publishing.
+	ErrEventBusClosed = errors.New("event bus closed")
+)
+
 // EventBus is a local event bus that delegates handling of published events
-// to all matching registered handlers, in order of registration.
+// to all matching registered handlers. Each handler handles events concurrently
+// with the other handlers, use an ordered.EventHandler to run handlers in a
+// deterministic order. By default each handler handles one event at a time, see
+// WithHandlerConcurrency. Events that fail to be handled are not redelivered,
+// the errors are only sent on Errors.
+//
+// The queue of each handler is bounded, see WithQueueSize. By default events
+// published to a handler with a full queue are dropped for that handler, use
+// WithBackpressure to instead slow down the publishers.
 type EventBus struct {
 	group        *Group
 	registered   map[eh.EventHandlerType]struct{}
@@ -39,6 +57,12 @@ type EventBus struct {
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
 	codec        eh.EventCodec
+	concurrency  map[eh.EventHandlerType]int
+	pausers      map[eh.EventHandlerType]*pauser
+	queueSize    int
+	backpressure bool
+	maxWait      time.Duration
+	maxRetries   int
 }
 
 // NewEventBus creates a EventBus.
@@ -46,12 +70,16 @@ func NewEventBus(options ...Option) *EventBus {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	b := &EventBus{
-		group:      NewGroup(),
-		registered: map[eh.EventHandlerType]struct{}{},
-		errCh:      make(chan error, 100),
-		cctx:       ctx,
-		cancel:     cancel,
-		codec:      &json.EventCodec{},
+		group:       NewGroup(),
+		registered:  map[eh.EventHandlerType]struct{}{},
+		errCh:       make(chan error, 100),
+		cctx:        ctx,
+		cancel:      cancel,
+		codec:       &json.EventCodec{},
+		concurrency: map[eh.EventHandlerType]int{},
+		pausers:     map[eh.EventHandlerType]*pauser{},
+		queueSize:   DefaultQueueSize,
+		maxRetries:  maxBackpressureRetries,
 	}
 
 	// Apply configuration options.
@@ -83,19 +111,130 @@ func WithGroup(g *Group) Option {
 	}
 }
 
+// WithHandlerConcurrency lets the handler of a type, when added, handle up to n
+// events concurrently, the rest of the events are queued. Note that the events
+// are then not handled in order by that handler, and the bus reports no ordering
+// guarantee, see OrderingGuarantee. The default is to handle one event at a time.
+//
+// The limit is set for the bus and keyed by the handler type, as AddHandler of
+// the eventhorizon.EventBus interface takes no options. It is used when a
+// handler of the type is added to the bus.
+func WithHandlerConcurrency(t eh.EventHandlerType, n int) Option {
+	return func(b *EventBus) {
+		b.concurrency[t] = n
+	}
+}
+
+// WithQueueSize sets the max number of queued events per handler, when the
+// handler is added. DefaultQueueSize is used by default. Note that handlers in
+// a shared group use the queue size of the bus that first added them.
+func WithQueueSize(n int) Option {
+	return func(b *EventBus) {
+		if n > 0 {
+			b.queueSize = n
+		}
+	}
+}
+
+// WithBackpressure applies flow control to the publishers when handlers can not
+// keep up, instead of dropping events for handlers with a full queue.
+//
+// Publishing waits for space in the queues of all handlers, for at most maxWait
+// (or until the context is done if 0), after which ErrQueueFull is returned and
+// the event is dropped for the handlers with a full queue. This bounds the
+// memory used for queued events, but also means that publishing is as slow as
+// the slowest handler. Fire-and-forget publishers that ignore the returned
+// error, for example an event store that only logs handler errors after
+// saving, will still lose events after maxWait, but are slowed down until then.
+// Handlers that publish events on the same bus can wait for their own queue,
+// which should be avoided or bounded with maxWait.
+//
+// Handlers can also signal that they can't keep up by returning
+// eventhorizon.ErrBackpressure, in which case the event is delivered to the
+// handler again after a backoff (from 10ms up to 1s), keeping the rest of its
+// events queued. After 64 deliveries, a bit more than a minute, the error is
+// handled as any other handler error and the event is dropped for the handler.
+// Without this option ErrBackpressure is handled as any other error.
+func WithBackpressure(maxWait time.Duration) Option {
+	return func(b *EventBus) {
+		b.backpressure = true
+		b.maxWait = maxWait
+	}
+}
+
 // HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
 func (b *EventBus) HandlerType() eh.EventHandlerType {
 	return "eventbus"
 }
 
-// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler
+// interface. It publishes the event to all handlers, returning ErrQueueFull only
+// when using WithBackpressure.
 func (b *EventBus) HandleEvent(ctx context.Context, event eh.Event) error {
 	data, err := b.codec.MarshalEvent(ctx, event)
 	if err != nil {
 		return fmt.Errorf("could not marshal event: %w", err)
 	}
 
-	return b.group.publish(ctx, data)
+	if _, full := b.publish(ctx, &message{data: data}); b.backpressure && len(full) > 0 {
+		return fmt.Errorf("%w: %v", ErrQueueFull, full)
+	}
+
+	return nil
+}
+
+// publish publishes a message to all handlers, waiting for space in full queues
+// when using WithBackpressure.
+func (b *EventBus) publish(ctx context.Context, msg *message) (int, []eh.EventHandlerType) {
+	if !b.backpressure {
+		return b.group.publish(msg)
+	}
+
+	return b.group.publishWait(ctx, b.cctx.Done(), b.maxWait, msg)
+}
+
+// PublishEventSync implements the PublishEventSync method of the
+// eventhorizon.EventBusSyncPublisher interface. The outcomes are returned
+// instead of being sent on the error channel, together with the joined errors
+// of all failed handlers. Handlers with a full queue are reported as failed
+// with ErrQueueFull.
+func (b *EventBus) PublishEventSync(ctx context.Context, event eh.Event) (eh.DeliveryResult, error) {
+	var result eh.DeliveryResult
+
+	data, err := b.codec.MarshalEvent(ctx, event)
+	if err != nil {
+		return result, fmt.Errorf("could not marshal event: %w", err)
+	}
+
+	// The results are buffered for the current handlers, handlers added while
+	// publishing stop waiting to reply when done is closed after returning.
+	results := make(chan delivery, b.group.size())
+	done := make(chan struct{})
+	defer close(done)
+
+	sent, full := b.publish(ctx, &message{data: data, results: results, done: done})
+
+	for _, t := range full {
+		result.Outcomes = append(result.Outcomes, eh.HandlerOutcome{
+			HandlerType: t,
+			Err:         ErrQueueFull,
+		})
+	}
+
+	for i := 0; i < sent; i++ {
+		select {
+		case d := <-results:
+			if d.matched {
+				result.Outcomes = append(result.Outcomes, d.outcome)
+			}
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-b.cctx.Done():
+			return result, ErrEventBusClosed
+		}
+	}
+
+	return result, result.Err()
 }
 
 // AddHandler implements the AddHandler method of the eventhorizon.EventBus interface.
@@ -118,15 +257,21 @@ func (b *EventBus) AddHandler(ctx context.Context, m eh.EventMatcher, h eh.Event
 
 	// Get or create the channel.
 	id := h.HandlerType().String()
-	ch := b.group.channel(id)
+	ch := b.group.channel(id, b.queueSize)
 
 	// Register handler.
 	b.registered[h.HandlerType()] = struct{}{}
+	p := newPauser()
+	b.pausers[h.HandlerType()] = p
 
 	b.wg.Add(1)
 
 	// Handle until context is cancelled.
-	go b.handle(m, h, ch)
+	if n := b.concurrency[h.HandlerType()]; n > 1 {
+		go b.handleConcurrently(m, h, ch, p, n)
+	} else {
+		go b.handle(m, h, ch, p)
+	}
 
 	return nil
 }
@@ -136,6 +281,80 @@ func (b *EventBus) Errors() <-chan error {
 	return b.errCh
 }
 
+// OrderingGuarantee implements the OrderingGuarantee method of the
+// eventhorizon.OrderingReporter interface. Each handler handles the events one
+// at a time in the order they were published, unless any handler is allowed to
+// handle events concurrently with WithHandlerConcurrency, in which case there
+// is no ordering guarantee.
+func (b *EventBus) OrderingGuarantee() eh.OrderingGuarantee {
+	for _, n := range b.concurrency {
+		if n > 1 {
+			return eh.NoOrdering
+		}
+	}
+
+	return eh.GlobalOrdering
+}
+
+// Pause implements the Pause method of the eventhorizon.EventBusPauser
+// interface. Events published while paused are kept in the queue of the
+// handler, which is bounded by the queue size, see WithQueueSize.
+func (b *EventBus) Pause(ctx context.Context, t eh.EventHandlerType) error {
+	p, err := b.pauser(t)
+	if err != nil {
+		return err
+	}
+
+	p.pause()
+
+	return nil
+}
+
+// Resume implements the Resume method of the eventhorizon.EventBusPauser
+// interface.
+func (b *EventBus) Resume(ctx context.Context, t eh.EventHandlerType) error {
+	p, err := b.pauser(t)
+	if err != nil {
+		return err
+	}
+
+	p.resume()
+
+	return nil
+}
+
+func (b *EventBus) pauser(t eh.EventHandlerType) (*pauser, error) {
+	b.registeredMu.RLock()
+	defer b.registeredMu.RUnlock()
+
+	p, ok := b.pausers[t]
+	if !ok {
+		return nil, eh.ErrHandlerNotAdded
+	}
+
+	return p, nil
+}
+
+// QueueDepth returns the number of events that are published but not yet
+// received by a registered handler, not counting an event being handled.
+// Unregistered handlers have a depth of 0.
+func (b *EventBus) QueueDepth(t eh.EventHandlerType) int {
+	return b.group.queueDepth(t.String())
+}
+
+// QueueDepths returns the queue depth of all registered handlers.
+func (b *EventBus) QueueDepths() map[eh.EventHandlerType]int {
+	b.registeredMu.RLock()
+	defer b.registeredMu.RUnlock()
+
+	depths := make(map[eh.EventHandlerType]int, len(b.registered))
+	for t := range b.registered {
+		depths[t] = b.group.queueDepth(t.String())
+	}
+
+	return depths
+}
+
 // Close implements the Close method of the eventhorizon.EventBus interface.
 func (b *EventBus) Close() error {
 	b.cancel()
@@ -150,62 +369,257 @@ type evt struct {
 	event   eh.Event
 }
 
+// message is a published event, with a channel for the handler outcomes when
+// published synchronously, which is read until done is closed.
+type message struct {
+	data    []byte
+	results chan<- delivery
+	done    <-chan struct{}
+}
+
+// delivery is the outcome of a handler for a message.
+type delivery struct {
+	outcome eh.HandlerOutcome
+	matched bool
+}
+
+// reply sends the outcome of a handler when published synchronously, it
+// returns false if the message was published asynchronously.
+func (m *message) reply(t eh.EventHandlerType, matched bool, err error) bool {
+	if m.results == nil {
+		return false
+	}
+
+	// Don't block when the publisher has stopped reading the outcomes, for
+	// example when its context is done or the bus is closed.
+	select {
+	case m.results <- delivery{
+		outcome: eh.HandlerOutcome{HandlerType: t, Err: err},
+		matched: matched,
+	}:
+	case <-m.done:
+	}
+
+	return true
+}
+
 // Handles all events coming in on the channel.
-func (b *EventBus) handle(m eh.EventMatcher, h eh.EventHandler, ch <-chan []byte) {
+func (b *EventBus) handle(m eh.EventMatcher, h eh.EventHandler, ch <-chan *message, p *pauser) {
 	defer b.wg.Done()
 
 	for {
 		select {
-		case data := <-ch:
-			// Artificial delay to simulate network.
-			time.Sleep(time.Millisecond)
-
-			event, ctx, err := b.codec.UnmarshalEvent(b.cctx, data)
-			if err != nil {
-				err = fmt.Errorf("could not unmarshal event: %w", err)
-				select {
-				case b.errCh <- &eh.EventBusError{Err: err, Ctx: ctx}:
-				default:
-					log.Printf("eventhorizon: missed error in local event bus: %s", err)
-				}
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
 
+			if !p.wait(b.cctx) {
 				return
 			}
 
-			// Ignore non-matching events.
-			if !m.Match(event) {
-				continue
+			if !b.handleMessage(m, h, msg) {
+				return
 			}
+		case <-b.cctx.Done():
+			return
+		}
+	}
+}
 
-			// Handle the event if it did match.
-			if err := h.HandleEvent(ctx, event); err != nil {
-				err = fmt.Errorf("could not handle event (%s): %s", h.HandlerType(), err.Error())
-				select {
-				case b.errCh <- &eh.EventBusError{Err: err, Ctx: ctx, Event: event}:
-				default:
-					log.Printf("eventhorizon: missed error in local event bus: %s", err)
-				}
+// Handles all events coming in on the channel, up to n events concurrently.
+func (b *EventBus) handleConcurrently(m eh.EventMatcher, h eh.EventHandler, ch <-chan *message, p *pauser, n int) {
+	defer b.wg.Done()
+
+	var (
+		wg       sync.WaitGroup
+		stopped  = make(chan struct{})
+		stopOnce sync.Once
+	)
+
+	defer wg.Wait()
+
+	sem := make(chan struct{}, n)
+
+	for {
+		// Wait for a free slot before receiving, to keep the rest queued.
+		select {
+		case sem <- struct{}{}:
+		case <-stopped:
+			return
+		case <-b.cctx.Done():
+			return
+		}
+
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
 			}
+
+			if !p.wait(b.cctx) {
+				return
+			}
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				if !b.handleMessage(m, h, msg) {
+					stopOnce.Do(func() { close(stopped) })
+				}
+			}()
+		case <-stopped:
+			return
 		case <-b.cctx.Done():
 			return
 		}
 	}
 }
 
+// handleMessage handles a message if the event matches, returning false if the
+// handler should stop handling events.
+func (b *EventBus) handleMessage(m eh.EventMatcher, h eh.EventHandler, msg *message) bool {
+	// Artificial delay to simulate network.
+	time.Sleep(time.Millisecond)
+
+	event, ctx, err := b.codec.UnmarshalEvent(b.cctx, msg.data)
+	if err != nil {
+		err = fmt.Errorf("could not unmarshal event: %w", err)
+		msg.reply(h.HandlerType(), true, err)
+
+		select {
+		case b.errCh <- &eh.EventBusError{Err: err, Ctx: ctx}:
+		default:
+			log.Printf("eventhorizon: missed error in local event bus: %s", err)
+		}
+
+		return false
+	}
+
+	// Ignore non-matching events.
+	if !m.Match(event) {
+		msg.reply(h.HandlerType(), false, nil)
+
+		return true
+	}
+
+	// Handle the event if it did match.
+	err = b.handleEvent(ctx, h, event)
+	if msg.reply(h.HandlerType(), true, err) {
+		return true
+	}
+
+	if err != nil {
+		err = fmt.Errorf("could not handle event (%s): %s", h.HandlerType(), err.Error())
+		select {
+		case b.errCh <- &eh.EventBusError{Err: err, Ctx: ctx, Event: event}:
+		default:
+			log.Printf("eventhorizon: missed error in local event bus: %s", err)
+		}
+	}
+
+	return true
+}
+
+const (
+	// minBackpressureDelay is the first delay before delivering an event again
+	// to a handler that returned eventhorizon.ErrBackpressure.
+	minBackpressureDelay = 10 * time.Millisecond
+	// maxBackpressureDelay is the max delay between deliveries.
+	maxBackpressureDelay = time.Second
+	// maxBackpressureRetries is the max number of times an event is delivered
+	// again, which is a bit more than a minute with the delays above.
+	maxBackpressureRetries = 64
+)
+
+// handleEvent handles an event, delivering it again with a backoff while the
+// handler returns eventhorizon.ErrBackpressure, if using WithBackpressure.
+func (b *EventBus) handleEvent(ctx context.Context, h eh.EventHandler, event eh.Event) error {
+	bo := backoff.NewBackoff(
+		backoff.WithBase(minBackpressureDelay),
+		backoff.WithMax(maxBackpressureDelay),
+	)
+
+	for {
+		err := h.HandleEvent(ctx, event)
+		if !b.backpressure || !errors.Is(err, eh.ErrBackpressure) ||
+			bo.Attempt() >= b.maxRetries {
+			return err
+		}
+
+		if bo.Wait(b.cctx) != nil {
+			return err
+		}
+	}
+}
+
+// pauser pauses the handling of events by a handler. When paused, the handler
+// waits with the next received event until resumed, the rest are kept queued.
+type pauser struct {
+	mu      sync.Mutex
+	resumed chan struct{}
+}
+
+func newPauser() *pauser {
+	resumed := make(chan struct{})
+	close(resumed)
+
+	return &pauser{resumed: resumed}
+}
+
+func (p *pauser) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.resumed:
+		p.resumed = make(chan struct{})
+	default: // Already paused.
+	}
+}
+
+func (p *pauser) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.resumed: // Not paused.
+	default:
+		close(p.resumed)
+	}
+}
+
+// wait waits until not paused, returns false if the context is done first.
+func (p *pauser) wait(ctx context.Context) bool {
+	p.mu.Lock()
+	resumed := p.resumed
+	p.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // Group is a publishing group shared by multiple event busses locally, if needed.
 type Group struct {
-	bus   map[string]chan []byte
+	bus   map[string]chan *message
 	busMu sync.RWMutex
 }
 
 // NewGroup creates a Group.
 func NewGroup() *Group {
 	return &Group{
-		bus: map[string]chan []byte{},
+		bus: map[string]chan *message{},
 	}
 }
 
-func (g *Group) channel(id string) <-chan []byte {
+func (g *Group) channel(id string, size int) <-chan *message {
 	g.busMu.Lock()
 	defer g.busMu.Unlock()
 
@@ -213,32 +627,112 @@ func (g *Group) channel(id string) <-chan []byte {
 		return ch
 	}
 
-	ch := make(chan []byte, DefaultQueueSize)
+	ch := make(chan *message, size)
 	g.bus[id] = ch
 
 	return ch
 }
 
-func (g *Group) publish(ctx context.Context, b []byte) error {
+func (g *Group) queueDepth(id string) int {
 	g.busMu.RLock()
 	defer g.busMu.RUnlock()
 
-	for _, ch := range g.bus {
-		// Marshal and unmarshal the context to both simulate only sending data
-		// that would be sent over a network bus and also break any relationship
-		// with the old context.
+	return len(g.bus[id])
+}
+
+func (g *Group) size() int {
+	g.busMu.RLock()
+	defer g.busMu.RUnlock()
+
+	return len(g.bus)
+}
+
+// publish publishes a message to all handlers, returning the number of
+// handlers it was sent to and the handlers that had a full queue.
+func (g *Group) publish(msg *message) (int, []eh.EventHandlerType) {
+	g.busMu.RLock()
+	defer g.busMu.RUnlock()
+
+	var (
+		sent int
+		full []eh.EventHandlerType
+	)
+
+	for id, ch := range g.bus {
+		// Only send the marshaled event and context to both simulate only
+		// sending data that would be sent over a network bus and also break
+		// any relationship with the old context.
 		select {
-		case ch <- b:
+		case ch <- msg:
+			sent++
 		default:
+			full = append(full, eh.EventHandlerType(id))
+
 			log.Printf("eventhorizon: publish queue full in local event bus")
 		}
 	}
 
-	return nil
+	return sent, full
+}
+
+// publishWait publishes a message to all channels, waiting for space in full
+// channels until the context or done is done, or for at most maxWait if set.
+// Channels that are still full are skipped after the first failed wait.
+func (g *Group) publishWait(ctx context.Context, done <-chan struct{}, maxWait time.Duration, msg *message) (int, []eh.EventHandlerType) {
+	g.busMu.RLock()
+	defer g.busMu.RUnlock()
+
+	var (
+		sent    int
+		full    []eh.EventHandlerType
+		timeout <-chan time.Time
+		expired bool
+	)
+
+	if maxWait > 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	for id, ch := range g.bus {
+		select {
+		case ch <- msg:
+			sent++
+
+			continue
+		default:
+		}
+
+		if !expired {
+			select {
+			case ch <- msg:
+				sent++
+
+				continue
+			case <-timeout:
+			case <-ctx.Done():
+			case <-done:
+			}
+
+			expired = true
+		}
+
+		full = append(full, eh.EventHandlerType(id))
+
+		log.Printf("eventhorizon: publish queue full in local event bus")
+	}
+
+	return sent, full
 }
 
 // Closes all the open channels after handling is done.
 func (g *Group) close() {
+	// Wait for any publishers that are waiting for space.
+	g.busMu.Lock()
+	defer g.busMu.Unlock()
+
 	for _, ch := range g.bus {
 		close(ch)
 	}
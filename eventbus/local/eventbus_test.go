@@ -15,10 +15,19 @@
 package local
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/eventbus"
+	"github.com/looplab/eventhorizon/eventbus/bustest"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
 )
 
 // NOTE: Not named "Integration" to enable running with the unit tests.
@@ -55,6 +64,593 @@ func TestEventBus(t *testing.T) {
 	eventbus.AcceptanceTest(t, bus1, bus2, time.Second)
 }
 
+func TestEventBusConformance(t *testing.T) {
+	bustest.Run(t, func(t *testing.T) (eh.EventBus, eh.EventBus) {
+		group := NewGroup()
+
+		return NewEventBus(WithGroup(group)), NewEventBus(WithGroup(group))
+	}, time.Second)
+}
+
+func TestEventBus_Ordering(t *testing.T) {
+	bus := NewEventBus()
+	if bus == nil {
+		t.Fatal("there should be a bus")
+	}
+
+	eventbus.OrderingAcceptanceTest(t, bus, time.Second)
+}
+
+func TestEventBus_OrderingConcurrent(t *testing.T) {
+	bus := NewEventBus(WithHandlerConcurrency(eventbus.OrderingHandlerType, 4))
+	if bus == nil {
+		t.Fatal("there should be a bus")
+	}
+
+	if ordering := bus.OrderingGuarantee(); ordering != eh.NoOrdering {
+		t.Error("there should be no ordering guarantee:", ordering)
+	}
+
+	eventbus.OrderingAcceptanceTest(t, bus, time.Second)
+}
+
+func TestEventBus_QueueDepth(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	ctx := context.Background()
+	h := &blockingHandler{release: make(chan struct{})}
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if d := bus.QueueDepth(h.HandlerType()); d != 0 {
+		t.Error("the queue depth should be 0:", d)
+	}
+
+	for i := 0; i < 5; i++ {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+			eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+		if err := bus.HandleEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// The first event is received by the blocked handler.
+	if !waitForDepth(bus, h.HandlerType(), 4) {
+		t.Error("the queue depth should be 4:", bus.QueueDepth(h.HandlerType()))
+	}
+
+	if d := bus.QueueDepths(); len(d) != 1 || d[h.HandlerType()] != 4 {
+		t.Error("the queue depths should be correct:", d)
+	}
+
+	// The depth decreases as the handler catches up.
+	for expected := 3; expected >= 0; expected-- {
+		h.release <- struct{}{}
+
+		if !waitForDepth(bus, h.HandlerType(), expected) {
+			t.Errorf("the queue depth should be %d: %d", expected, bus.QueueDepth(h.HandlerType()))
+		}
+	}
+
+	h.release <- struct{}{}
+
+	if d := bus.QueueDepth("unregistered"); d != 0 {
+		t.Error("the queue depth should be 0:", d)
+	}
+}
+
+func TestEventBus_PublishEventSync(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	ctx := context.Background()
+
+	handler := mocks.NewEventHandler("handler")
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, handler); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	handlingErr := errors.New("handling error")
+	failing := mocks.NewEventHandler("failing")
+	failing.Err = handlingErr
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, failing); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	other := mocks.NewEventHandler("other")
+	if err := bus.AddHandler(ctx, eh.MatchEvents{mocks.EventOtherType}, other); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+
+	result, err := bus.PublishEventSync(ctx, event)
+	if !errors.Is(err, handlingErr) {
+		t.Fatal("there should be a handling error:", err)
+	}
+
+	// All matching handlers are done when returning.
+	if len(handler.Events) != 1 {
+		t.Error("the event should be handled:", handler.Events)
+	}
+
+	if len(result.Outcomes) != 2 {
+		t.Fatal("there should be outcomes for the matching handlers:", result.Outcomes)
+	}
+
+	failed := result.Failed()
+	if len(failed) != 1 || failed[0].HandlerType != "failing" || !errors.Is(failed[0].Err, handlingErr) {
+		t.Error("the failing handler should be reported:", failed)
+	}
+
+	for _, o := range result.Outcomes {
+		if o.HandlerType == "handler" && o.Err != nil {
+			t.Error("the handler should succeed:", o.Err)
+		}
+	}
+
+	// The outcomes are not sent as async errors.
+	select {
+	case err := <-bus.Errors():
+		t.Error("there should be no async error:", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Waiting for a blocked handler is stopped by the context.
+	blocking := &blockingHandler{release: make(chan struct{})}
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, blocking); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	result, err = bus.PublishEventSync(timeoutCtx, event)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("there should be a deadline exceeded error:", err)
+	}
+
+	if len(result.Outcomes) != 2 {
+		t.Error("there should be outcomes for the handlers that are done:", result.Outcomes)
+	}
+
+	blocking.release <- struct{}{}
+}
+
+func TestMessage_ReplyAfterDone(t *testing.T) {
+	// A handler added while publishing has no buffered slot for its outcome,
+	// the reply should not block after the publisher has stopped reading.
+	done := make(chan struct{})
+	msg := &message{results: make(chan delivery), done: done}
+
+	close(done)
+
+	replied := make(chan bool)
+
+	go func() {
+		replied <- msg.reply("handler", true, nil)
+	}()
+
+	select {
+	case ok := <-replied:
+		if !ok {
+			t.Error("the message should be published synchronously")
+		}
+	case <-time.After(time.Second):
+		t.Error("the reply should not block")
+	}
+}
+
+func TestEventBus_PublishEventSyncErrors(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	ctx := context.Background()
+
+	handler := mocks.NewEventHandler("handler")
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, handler); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	err1 := errors.New("error 1")
+	failing1 := mocks.NewEventHandler("failing1")
+	failing1.Err = err1
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, failing1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	err2 := errors.New("error 2")
+	failing2 := mocks.NewEventHandler("failing2")
+	failing2.Err = err2
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, failing2); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+
+	result, err := bus.PublishEventSync(ctx, event)
+	if !errors.Is(err, err1) || !errors.Is(err, err2) {
+		t.Error("there should be both handler errors:", err)
+	}
+
+	if len(result.Failed()) != 2 {
+		t.Error("there should be two failed handlers:", result.Failed())
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatal("the error should be joined:", err)
+	}
+
+	handlerErrs := map[string]error{}
+
+	for _, err := range joined.Unwrap() {
+		handlerErrs[strings.SplitN(err.Error(), ":", 2)[0]] = err
+	}
+
+	if len(handlerErrs) != 2 ||
+		!errors.Is(handlerErrs["failing1"], err1) ||
+		!errors.Is(handlerErrs["failing2"], err2) {
+		t.Error("the errors should have the handler types:", handlerErrs)
+	}
+
+	// No error if all handlers succeed.
+	if err := (eh.DeliveryResult{Outcomes: []eh.HandlerOutcome{{HandlerType: "handler"}}}).Err(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestEventBus_HandlerConcurrency(t *testing.T) {
+	h := &concurrentHandler{release: make(chan struct{})}
+	bus := NewEventBus(WithHandlerConcurrency(h.HandlerType(), 3))
+	defer bus.Close()
+
+	ctx := context.Background()
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Publish a burst of events.
+	for i := 0; i < 10; i++ {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+			eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+		if err := bus.HandleEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// At most 3 events are handled concurrently, the rest are queued.
+	if !waitForDepth(bus, h.HandlerType(), 7) {
+		t.Error("the queue depth should be 7:", bus.QueueDepth(h.HandlerType()))
+	}
+
+	for i := 0; i < 100; i++ {
+		if active, _, _ := h.stats(); active == 3 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if active, _, _ := h.stats(); active != 3 {
+		t.Error("there should be 3 active handlings:", active)
+	}
+
+	for i := 0; i < 10; i++ {
+		h.release <- struct{}{}
+	}
+
+	for i := 0; i < 100; i++ {
+		if _, _, handled := h.stats(); handled == 10 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, max, handled := h.stats(); max != 3 || handled != 10 {
+		t.Error("all events should be handled with at most 3 concurrently:", max, handled)
+	}
+}
+
+func TestEventBus_Pause(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	ctx := context.Background()
+
+	h := mocks.NewEventHandler("handler")
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := bus.Pause(ctx, h.HandlerType()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var events []eh.Event
+
+	for i := 0; i < 5; i++ {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprint(i)}, time.Now(),
+			eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+		if err := bus.HandleEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		events = append(events, event)
+	}
+
+	if h.Wait(50 * time.Millisecond) {
+		t.Error("the paused handler should not handle events")
+	}
+
+	if err := bus.Resume(ctx, h.HandlerType()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for i := range events {
+		if !h.Wait(time.Second) {
+			t.Fatal("the resumed handler should handle the event:", i)
+		}
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if len(h.Events) != len(events) {
+		t.Fatal("all events should be handled:", h.Events)
+	}
+
+	for i, event := range h.Events {
+		if err := eh.CompareEvents(event, events[i]); err != nil {
+			t.Error("the events should be handled in order:", i, err)
+		}
+	}
+
+	if err := bus.Pause(ctx, "unknown"); !errors.Is(err, eh.ErrHandlerNotAdded) {
+		t.Error("there should be a handler not added error:", err)
+	}
+
+	if err := bus.Resume(ctx, "unknown"); !errors.Is(err, eh.ErrHandlerNotAdded) {
+		t.Error("there should be a handler not added error:", err)
+	}
+}
+
+func TestEventBus_Backpressure(t *testing.T) {
+	bus := NewEventBus(WithQueueSize(2), WithBackpressure(100*time.Millisecond))
+	defer bus.Close()
+
+	ctx := context.Background()
+	h := &blockingHandler{release: make(chan struct{})}
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	newEvent := func() eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+			eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+	}
+
+	// The first event is received by the blocked handler, the rest fill the queue.
+	for i := 0; i < 3; i++ {
+		if err := bus.HandleEvent(ctx, newEvent()); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if i == 0 && !waitForDepth(bus, h.HandlerType(), 0) {
+			t.Fatal("the first event should be received")
+		}
+	}
+
+	// Publishing waits for at most the max wait on a full queue.
+	start := time.Now()
+
+	if err := bus.HandleEvent(ctx, newEvent()); !errors.Is(err, ErrQueueFull) {
+		t.Error("there should be a queue full error:", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Error("publishing should wait for the max wait:", elapsed)
+	}
+
+	if d := bus.QueueDepth(h.HandlerType()); d != 2 {
+		t.Error("the queue should be bounded:", d)
+	}
+
+	// Publishing continues when the handler catches up.
+	published := make(chan error, 1)
+
+	go func() {
+		published <- bus.HandleEvent(ctx, newEvent())
+	}()
+
+	select {
+	case err := <-published:
+		t.Fatal("publishing should wait for the handler:", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	h.release <- struct{}{}
+
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publishing should continue when the handler catches up")
+	}
+
+	// Publishing stops waiting when the context is done.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if err := bus.HandleEvent(cctx, newEvent()); !errors.Is(err, ErrQueueFull) {
+		t.Error("there should be a queue full error:", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		h.release <- struct{}{}
+	}
+}
+
+func TestEventBus_BackpressureFromHandler(t *testing.T) {
+	bus := NewEventBus(WithBackpressure(time.Second))
+	defer bus.Close()
+
+	ctx := context.Background()
+	h := &backpressureHandler{
+		EventHandler: mocks.NewEventHandler("handler"),
+		rejections:   3,
+	}
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+	if err := bus.HandleEvent(ctx, event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !h.Wait(time.Second) {
+		t.Fatal("the event should be delivered again until handled")
+	}
+
+	h.Lock()
+	if err := eh.CompareEvents(h.Events[0], event); err != nil {
+		t.Error("the event should be correct:", err)
+	}
+	h.Unlock()
+
+	select {
+	case err := <-bus.Errors():
+		t.Error("there should be no error:", err)
+	default:
+	}
+
+	// A handler that keeps rejecting gets the error after the max retries.
+	bus3 := NewEventBus(WithBackpressure(time.Second))
+	bus3.maxRetries = 2
+	defer bus3.Close()
+
+	h3 := &backpressureHandler{
+		EventHandler: mocks.NewEventHandler("handler"),
+		rejections:   10,
+	}
+
+	if err := bus3.AddHandler(ctx, eh.MatchAll{}, h3); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := bus3.HandleEvent(ctx, event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	select {
+	case err := <-bus3.Errors():
+		if !strings.Contains(err.Error(), eh.ErrBackpressure.Error()) {
+			t.Error("there should be a backpressure error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("there should be an error")
+	}
+
+	h3.mu.Lock()
+	if h3.rejections != 7 {
+		t.Error("the event should be delivered three times:", 10-h3.rejections)
+	}
+	h3.mu.Unlock()
+
+	// Without backpressure it is handled as any other error.
+	bus2 := NewEventBus()
+	defer bus2.Close()
+
+	h2 := &backpressureHandler{
+		EventHandler: mocks.NewEventHandler("handler"),
+		rejections:   1,
+	}
+
+	if err := bus2.AddHandler(ctx, eh.MatchAll{}, h2); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := bus2.HandleEvent(ctx, event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	select {
+	case err := <-bus2.Errors():
+		if !strings.Contains(err.Error(), eh.ErrBackpressure.Error()) {
+			t.Error("there should be a backpressure error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("there should be an error")
+	}
+
+	if h2.Wait(50 * time.Millisecond) {
+		t.Error("the event should not be delivered again")
+	}
+}
+
+func waitForDepth(bus *EventBus, t eh.EventHandlerType, depth int) bool {
+	for i := 0; i < 100; i++ {
+		if bus.QueueDepth(t) == depth {
+			return true
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return false
+}
+
+// blockingHandler blocks handling of each event until released.
+type blockingHandler struct {
+	release chan struct{}
+}
+
+func (h *blockingHandler) HandlerType() eh.EventHandlerType {
+	return "blocking"
+}
+
+func (h *blockingHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	<-h.release
+
+	return nil
+}
+
+// backpressureHandler returns a backpressure error for the first events.
+type backpressureHandler struct {
+	*mocks.EventHandler
+
+	mu         sync.Mutex
+	rejections int
+}
+
+func (h *backpressureHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	h.mu.Lock()
+	if h.rejections > 0 {
+		h.rejections--
+		h.mu.Unlock()
+
+		return fmt.Errorf("too busy: %w", eh.ErrBackpressure)
+	}
+	h.mu.Unlock()
+
+	return h.EventHandler.HandleEvent(ctx, event)
+}
+
 func TestEventBusLoadtest(t *testing.T) {
 	bus := NewEventBus()
 	if bus == nil {
@@ -72,3 +668,43 @@ func BenchmarkEventBus(b *testing.B) {
 
 	eventbus.Benchmark(b, bus)
 }
+
+// concurrentHandler blocks handling of each event until released, keeping
+// track of the concurrent handlings.
+type concurrentHandler struct {
+	release chan struct{}
+
+	mu      sync.Mutex
+	active  int
+	max     int
+	handled int
+}
+
+func (h *concurrentHandler) HandlerType() eh.EventHandlerType {
+	return "concurrent"
+}
+
+func (h *concurrentHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	h.mu.Lock()
+	h.active++
+	if h.active > h.max {
+		h.max = h.active
+	}
+	h.mu.Unlock()
+
+	<-h.release
+
+	h.mu.Lock()
+	h.active--
+	h.handled++
+	h.mu.Unlock()
+
+	return nil
+}
+
+func (h *concurrentHandler) stats() (active, max, handled int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.active, h.max, h.handled
+}
@@ -30,6 +30,11 @@ import (
 
 // EventBus is a NATS Jetstream event bus that delegates handling of published
 // events to all matching registered handlers.
+//
+// Messages are acked when the event is handled and negatively acked (Nak) if
+// the handler fails, which makes NATS redeliver the message. A message that is
+// not acked within 60 seconds is also redelivered, up to 10 deliveries in
+// total.
 type EventBus struct {
 	appID        string
 	streamName   string
@@ -0,0 +1,152 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// OrderingHandlerType is the type of the handler added by OrderingAcceptanceTest,
+// for buses that are configured per handler type.
+const OrderingHandlerType eh.EventHandlerType = "ordering"
+
+// OrderingAcceptanceTest is the acceptance test that all implementations of
+// EventBus should pass for the ordering guarantee they report, see
+// eventhorizon.OrderingReporter. It should manually be called from a test
+// case in each implementation:
+//
+//   func TestEventBusOrdering(t *testing.T) {
+//       bus := NewEventBus()
+//       eventbus.OrderingAcceptanceTest(t, bus, time.Second)
+//   }
+//
+func OrderingAcceptanceTest(t *testing.T, bus eh.EventBus, timeout time.Duration) {
+	ctx := context.Background()
+	ordering := eh.OrderingOf(bus)
+
+	t.Log("ordering guarantee:", ordering)
+
+	h := &orderingHandler{
+		done: make(chan struct{}),
+	}
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	time.Sleep(timeout) // Need to wait here for handlers to be added.
+
+	// Publish events interleaved for a few aggregates.
+	const numAggregates, numVersions = 3, 10
+
+	ids := make([]uuid.UUID, numAggregates)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	var published []eh.Event
+
+	for v := 1; v <= numVersions; v++ {
+		for _, id := range ids {
+			event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+				eh.ForAggregate(mocks.AggregateType, id, v))
+			published = append(published, event)
+		}
+	}
+
+	h.expect(len(published))
+
+	for _, event := range published {
+		if err := bus.HandleEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	select {
+	case <-h.done:
+	case <-time.After(timeout + time.Duration(len(published))*10*time.Millisecond):
+		t.Fatal("did not receive all events in time:", len(h.received()))
+	}
+
+	received := h.received()
+	if len(received) != len(published) {
+		t.Fatal("the events should be received once:", len(received))
+	}
+
+	switch ordering {
+	case eh.GlobalOrdering:
+		for i, event := range received {
+			if event.AggregateID() != published[i].AggregateID() ||
+				event.Version() != published[i].Version() {
+				t.Error("the events should be in the published order:", i, event)
+			}
+		}
+	case eh.PerAggregateOrdering:
+		versions := map[uuid.UUID]int{}
+		for _, event := range received {
+			if event.Version() != versions[event.AggregateID()]+1 {
+				t.Error("the events should be in version order for the aggregate:", event)
+			}
+
+			versions[event.AggregateID()] = event.Version()
+		}
+	}
+}
+
+// orderingHandler records all handled events.
+type orderingHandler struct {
+	mu     sync.Mutex
+	events []eh.Event
+	count  int
+	done   chan struct{}
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (h *orderingHandler) HandlerType() eh.EventHandlerType {
+	return OrderingHandlerType
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (h *orderingHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events = append(h.events, event)
+	if len(h.events) == h.count {
+		close(h.done)
+	}
+
+	return nil
+}
+
+func (h *orderingHandler) expect(count int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.count = count
+}
+
+func (h *orderingHandler) received() []eh.Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]eh.Event{}, h.events...)
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// RedeliveryAcceptanceTest is the acceptance test that all implementations of
+// EventBus using a broker should pass, see the eventhorizon.EventBus interface.
+// An event that a handler fails to handle should be redelivered until it is
+// handled, and an event that is handled should not be redelivered. It should
+// manually be called from a test case in each implementation:
+//
+//	func TestEventBusRedelivery(t *testing.T) {
+//		bus := NewEventBus()
+//		eventbus.RedeliveryAcceptanceTest(t, bus, time.Second)
+//	}
+func RedeliveryAcceptanceTest(t *testing.T, bus eh.EventBus, timeout time.Duration) {
+	ctx := context.Background()
+	id := uuid.New()
+	failing := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "failing"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+	handled := eh.NewEvent(mocks.EventOtherType, &mocks.EventData{Content: "handled"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+
+	h := &redeliveryHandler{
+		failures: 2,
+		done:     make(chan struct{}),
+	}
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	time.Sleep(timeout) // Need to wait here for handlers to be added.
+
+	if err := bus.HandleEvent(ctx, failing); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := bus.HandleEvent(ctx, handled); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The failing event is redelivered after each failure.
+	select {
+	case <-h.done:
+	case <-time.After(5 * timeout):
+		t.Fatal("the failed event should be redelivered:", h.counts())
+	}
+
+	// The handler errors are sent on the error channel.
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-bus.Errors():
+			if !errors.Is(err, errRedelivery) {
+				t.Error("there should be a handler error:", err)
+			}
+		case <-time.After(timeout):
+			t.Error("there should be a handler error")
+		}
+	}
+
+	// Wait for any redelivery of the acked events.
+	time.Sleep(timeout)
+
+	counts := h.counts()
+	if counts["failing"] != 3 {
+		t.Error("the failed event should be delivered until handled:", counts)
+	}
+
+	if counts["handled"] != 1 {
+		t.Error("the handled event should not be redelivered:", counts)
+	}
+}
+
+var errRedelivery = errors.New("redelivery error")
+
+// redeliveryHandler fails to handle the "failing" event a number of times.
+type redeliveryHandler struct {
+	mu         sync.Mutex
+	failures   int
+	deliveries map[string]int
+	done       chan struct{}
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (h *redeliveryHandler) HandlerType() eh.EventHandlerType {
+	return "redelivery"
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (h *redeliveryHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, ok := event.Data().(*mocks.EventData)
+	if !ok {
+		return nil
+	}
+
+	if h.deliveries == nil {
+		h.deliveries = map[string]int{}
+	}
+
+	h.deliveries[data.Content]++
+
+	if data.Content != "failing" {
+		return nil
+	}
+
+	if h.deliveries[data.Content] <= h.failures {
+		return errRedelivery
+	}
+
+	if h.deliveries[data.Content] == h.failures+1 {
+		close(h.done)
+	}
+
+	return nil
+}
+
+func (h *redeliveryHandler) counts() map[string]int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := map[string]int{}
+	for k, v := range h.deliveries {
+		counts[k] = v
+	}
+
+	return counts
+}
@@ -26,11 +26,25 @@ import (
 	"github.com/go-redis/redis/v8"
 
 	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/backoff"
 	"github.com/looplab/eventhorizon/codec/json"
 )
 
-// EventBus is a local event bus that delegates handling of published events
-// to all matching registered handlers, in order of registration.
+// ErrConsumerGroupNotFound is when there is no consumer group for a handler.
+var ErrConsumerGroupNotFound = errors.New("consumer group not found")
+
+// EventBus is a Redis streams event bus that delegates handling of published
+// events to all matching registered handlers, in order of registration.
+//
+// Messages are acked (XACK) when the event is handled. If the handler fails
+// the rest of the read messages are left unhandled and the failed message is
+// kept in the pending entries of the consumer, which are read again and
+// redelivered with an exponential backoff before any new messages, to keep
+// the order of the events. A message that still fails after the max number of
+// retries is handled by the dead letter handler, if set, or dropped with an
+// error, and then acked. Messages that can not be decoded are acked, as they
+// would fail again. Note that pending messages are redelivered to the same
+// consumer, which is named by the client ID.
 type EventBus struct {
 	appID        string
 	clientID     string
@@ -44,8 +58,14 @@ type EventBus struct {
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
 	codec        eh.EventCodec
+	maxRetries   int
+	deadLetter   eh.EventHandler
 }
 
+// DefaultMaxRetries is the default number of times a failed message is
+// redelivered before it is dead lettered or dropped.
+const DefaultMaxRetries = 10
+
 // NewEventBus creates an EventBus, with optional settings.
 func NewEventBus(addr, appID, clientID string, options ...Option) (*EventBus, error) {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -59,6 +79,7 @@ func NewEventBus(addr, appID, clientID string, options ...Option) (*EventBus, er
 		cctx:       ctx,
 		cancel:     cancel,
 		codec:      &json.EventCodec{},
+		maxRetries: DefaultMaxRetries,
 	}
 
 	// Apply configuration options.
@@ -109,6 +130,32 @@ func WithRedisOptions(opts *redis.Options) Option {
 	}
 }
 
+// WithMaxRetries sets the number of times a failed message is redelivered
+// before it is dead lettered or dropped, the default is DefaultMaxRetries.
+func WithMaxRetries(n int) Option {
+	return func(b *EventBus) error {
+		if n < 0 {
+			return fmt.Errorf("max retries must not be negative: %d", n)
+		}
+
+		b.maxRetries = n
+
+		return nil
+	}
+}
+
+// WithDeadLetter handles the events that still fail after the max number of
+// retries with another handler, for example to store them for a later replay,
+// instead of dropping them. If the dead letter handler fails the message is
+// kept pending and redelivered.
+func WithDeadLetter(h eh.EventHandler) Option {
+	return func(b *EventBus) error {
+		b.deadLetter = h
+
+		return nil
+	}
+}
+
 // HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
 func (b *EventBus) HandlerType() eh.EventHandlerType {
 	return "eventbus"
@@ -185,6 +232,40 @@ func (b *EventBus) AddHandler(ctx context.Context, m eh.EventMatcher, h eh.Event
 	return nil
 }
 
+// ConsumerLag returns the number of events in the stream that are not yet
+// delivered to the consumer group of a handler, the latest position of the
+// stream minus the position of the group. Events that are delivered but not
+// yet acked are not counted. The lag is counted by reading the undelivered
+// part of the stream, it should be polled sparingly for large lags.
+func (b *EventBus) ConsumerLag(ctx context.Context, t eh.EventHandlerType) (int64, error) {
+	groupName := fmt.Sprintf("%s_%s", b.appID, t)
+
+	groups, err := b.client.XInfoGroups(ctx, b.streamName).Result()
+	if err != nil {
+		return 0, fmt.Errorf("could not get consumer groups: %w", err)
+	}
+
+	for _, g := range groups {
+		if g.Name != groupName {
+			continue
+		}
+
+		msgs, err := b.client.XRange(ctx, b.streamName, g.LastDeliveredID, "+").Result()
+		if err != nil {
+			return 0, fmt.Errorf("could not get undelivered events: %w", err)
+		}
+
+		lag := int64(len(msgs))
+		if lag > 0 && msgs[0].ID == g.LastDeliveredID {
+			lag--
+		}
+
+		return lag, nil
+	}
+
+	return 0, ErrConsumerGroupNotFound
+}
+
 // Errors implements the Errors method of the eventhorizon.EventBus interface.
 func (b *EventBus) Errors() <-chan error {
 	return b.errCh
@@ -202,13 +283,25 @@ func (b *EventBus) Close() error {
 func (b *EventBus) handle(m eh.EventMatcher, h eh.EventHandler, groupName string) {
 	defer b.wg.Done()
 
-	handler := b.handler(m, h, groupName)
+	handler := b.handler(m, h)
+
+	// Read the pending messages of the consumer again after a message failed
+	// to be handled, instead of new messages. The failed message is retried
+	// with a backoff until the max number of retries.
+	redeliver := false
+	failedID := ""
+	bo := backoff.NewBackoff()
 
 	for {
+		id := ">"
+		if redeliver {
+			id = "0"
+		}
+
 		streams, err := b.client.XReadGroup(b.cctx, &redis.XReadGroupArgs{
 			Group:    groupName,
 			Consumer: groupName + "_" + b.clientID,
-			Streams:  []string{b.streamName, ">"},
+			Streams:  []string{b.streamName, id},
 		}).Result()
 		if errors.Is(err, context.Canceled) {
 			break
@@ -226,21 +319,57 @@ func (b *EventBus) handle(m eh.EventMatcher, h eh.EventHandler, groupName string
 			continue
 		}
 
-		// Handle all messages from group read.
+		// Handle the messages from group read in order, up to the first failed
+		// message which is kept pending together with the rest.
+		redeliver = false
+
+	messages:
 		for _, stream := range streams {
 			if stream.Stream != b.streamName {
 				continue
 			}
 
 			for _, msg := range stream.Messages {
-				handler(b.cctx, &msg)
+				if msg.ID != failedID {
+					failedID = ""
+
+					bo.Reset()
+				}
+
+				if !handler(b.cctx, &msg, bo.Attempt() >= b.maxRetries) {
+					failedID = msg.ID
+					redeliver = true
+
+					break messages
+				}
+
+				if err := b.client.XAck(b.cctx, b.streamName, groupName, msg.ID).Err(); err != nil {
+					err = fmt.Errorf("could not ack message: %w", err)
+					select {
+					case b.errCh <- &eh.EventBusError{Err: err}:
+					default:
+						log.Printf("eventhorizon: missed error in Redis event bus: %s", err)
+					}
+				}
+			}
+		}
+
+		// Wait before redelivering the failed message.
+		if redeliver {
+			if err := bo.Wait(b.cctx); err != nil {
+				return
 			}
 		}
 	}
 }
 
-func (b *EventBus) handler(m eh.EventMatcher, h eh.EventHandler, groupName string) func(ctx context.Context, msg *redis.XMessage) {
-	return func(ctx context.Context, msg *redis.XMessage) {
+// handler returns a func that handles a message and returns true if the
+// message should be acked, or false if it should be redelivered because the
+// event could not be handled. Messages that can not be decoded are acked, as
+// they would fail again. On the last retry a failed event is handled by the
+// dead letter handler, if set, or dropped.
+func (b *EventBus) handler(m eh.EventMatcher, h eh.EventHandler) func(ctx context.Context, msg *redis.XMessage, last bool) bool {
+	return func(ctx context.Context, msg *redis.XMessage, last bool) bool {
 		data, ok := msg.Values[dataKey].(string)
 		if !ok {
 			err := fmt.Errorf("event data is of incorrect type %T", msg.Values[dataKey])
@@ -250,8 +379,7 @@ func (b *EventBus) handler(m eh.EventMatcher, h eh.EventHandler, groupName strin
 				log.Printf("eventhorizon: missed error in Redis event bus: %s", err)
 			}
 
-			// TODO: Nack if possible.
-			return
+			return true
 		}
 
 		event, ctx, err := b.codec.UnmarshalEvent(ctx, []byte(data))
@@ -263,22 +391,12 @@ func (b *EventBus) handler(m eh.EventMatcher, h eh.EventHandler, groupName strin
 				log.Printf("eventhorizon: missed error in Redis event bus: %s", err)
 			}
 
-			// TODO: Nack if possible.
-			return
+			return true
 		}
 
 		// Ignore non-matching events.
 		if !m.Match(event) {
-			if _, err := b.client.XAck(ctx, b.streamName, groupName, msg.ID).Result(); err != nil {
-				err = fmt.Errorf("could not ack non-matching event: %w", err)
-				select {
-				case b.errCh <- &eh.EventBusError{Err: err, Ctx: ctx, Event: event}:
-				default:
-					log.Printf("eventhorizon: missed error in Redis event bus: %s", err)
-				}
-			}
-
-			return
+			return true
 		}
 
 		// Handle the event if it did match.
@@ -290,18 +408,26 @@ func (b *EventBus) handler(m eh.EventMatcher, h eh.EventHandler, groupName strin
 				log.Printf("eventhorizon: missed error in Redis event bus: %s", err)
 			}
 
-			// TODO: Nack if possible.
-			return
-		}
+			if !last {
+				return false
+			}
 
-		_, err = b.client.XAck(ctx, b.streamName, groupName, msg.ID).Result()
-		if err != nil {
-			err = fmt.Errorf("could not ack handled event: %w", err)
-			select {
-			case b.errCh <- &eh.EventBusError{Err: err, Ctx: ctx, Event: event}:
-			default:
-				log.Printf("eventhorizon: missed error in Redis event bus: %s", err)
+			if b.deadLetter == nil {
+				return true
+			}
+
+			if err := b.deadLetter.HandleEvent(ctx, event); err != nil {
+				err = fmt.Errorf("could not dead letter event (%s): %w", b.deadLetter.HandlerType(), err)
+				select {
+				case b.errCh <- &eh.EventBusError{Err: err, Ctx: ctx, Event: event}:
+				default:
+					log.Printf("eventhorizon: missed error in Redis event bus: %s", err)
+				}
+
+				return false
 			}
 		}
+
+		return true
 	}
 }
@@ -15,8 +15,10 @@
 package redis
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -25,7 +27,10 @@ import (
 	"github.com/go-redis/redis/v8"
 
 	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/codec/json"
 	"github.com/looplab/eventhorizon/eventbus"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
 )
 
 func TestAddHandlerIntegration(t *testing.T) {
@@ -60,6 +65,209 @@ func TestEventBusIntegration(t *testing.T) {
 	eventbus.AcceptanceTest(t, bus1, bus2, time.Second)
 }
 
+func TestEventBusRedeliveryIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	bus, appID, err := newTestEventBus("")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	t.Logf("using stream: %s_events", appID)
+
+	eventbus.RedeliveryAcceptanceTest(t, bus, time.Second)
+}
+
+func TestEventBus_HandlerAck(t *testing.T) {
+	b := &EventBus{
+		codec: &json.EventCodec{},
+		errCh: make(chan error, 10),
+	}
+	ctx := context.Background()
+	h := mocks.NewEventHandler("handler")
+	handler := b.handler(eh.MatchEvents{mocks.EventType}, h)
+
+	newMsg := func(event eh.Event) *redis.XMessage {
+		data, err := b.codec.MarshalEvent(ctx, event)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		return &redis.XMessage{ID: "1-0", Values: map[string]interface{}{dataKey: string(data)}}
+	}
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+
+	// Handled events are acked.
+	if !handler(ctx, newMsg(event), false) {
+		t.Error("the handled event should be acked")
+	}
+
+	if len(h.Events) != 1 {
+		t.Error("the event should be handled:", h.Events)
+	}
+
+	// Failed events are not acked, to be redelivered.
+	handlerErr := errors.New("handler error")
+	h.Err = handlerErr
+
+	if handler(ctx, newMsg(event), false) {
+		t.Error("the failed event should not be acked")
+	}
+
+	select {
+	case err := <-b.Errors():
+		if !errors.Is(err, handlerErr) {
+			t.Error("there should be a handler error:", err)
+		}
+	default:
+		t.Error("there should be a handler error")
+	}
+
+	h.Err = nil
+
+	// Non-matching events are acked without handling.
+	otherEvent := eh.NewEvent(mocks.EventOtherType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+	if !handler(ctx, newMsg(otherEvent), false) {
+		t.Error("the non-matching event should be acked")
+	}
+
+	// Messages that can not be decoded are acked, as they would fail again.
+	if !handler(ctx, &redis.XMessage{ID: "1-0", Values: map[string]interface{}{dataKey: "invalid"}}, false) {
+		t.Error("the invalid message should be acked")
+	}
+
+	select {
+	case err := <-b.Errors():
+		if err == nil {
+			t.Error("there should be a decode error")
+		}
+	default:
+		t.Error("there should be a decode error")
+	}
+
+	if len(h.Events) != 1 {
+		t.Error("only the first event should be handled:", h.Events)
+	}
+
+	// Drain the errors of the previous messages.
+	for len(b.errCh) > 0 {
+		<-b.errCh
+	}
+
+	// Events failing on the last retry are dropped without a dead letter handler.
+	h.Err = handlerErr
+
+	if !handler(ctx, newMsg(event), true) {
+		t.Error("the dropped event should be acked")
+	}
+
+	<-b.Errors()
+
+	// Events failing on the last retry are handled by the dead letter handler.
+	deadLetter := mocks.NewEventHandler("dead letter")
+	b.deadLetter = deadLetter
+
+	if !handler(ctx, newMsg(event), true) {
+		t.Error("the dead lettered event should be acked")
+	}
+
+	<-b.Errors()
+
+	if len(deadLetter.Events) != 1 {
+		t.Error("the event should be dead lettered:", deadLetter.Events)
+	}
+
+	// Events failing in the dead letter handler are redelivered.
+	deadLetter.Err = errors.New("dead letter error")
+
+	if handler(ctx, newMsg(event), true) {
+		t.Error("the event failing in the dead letter handler should not be acked")
+	}
+
+	<-b.Errors()
+
+	select {
+	case err := <-b.Errors():
+		if !errors.Is(err, deadLetter.Err) {
+			t.Error("there should be a dead letter error:", err)
+		}
+	default:
+		t.Error("there should be a dead letter error")
+	}
+}
+
+func TestConsumerLagIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	b, appID, err := newTestEventBus("")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	bus, ok := b.(*EventBus)
+	if !ok {
+		t.Fatal("the bus should be a Redis event bus")
+	}
+	defer bus.Close()
+
+	ctx := context.Background()
+	h := mocks.NewEventHandler("lagging")
+
+	if _, err := bus.ConsumerLag(ctx, h.HandlerType()); !errors.Is(err, ErrConsumerGroupNotFound) {
+		t.Error("there should be a consumer group not found error:", err)
+	}
+
+	// Create the consumer group without consuming, as if the handler is down.
+	groupName := fmt.Sprintf("%s_%s", appID, h.HandlerType())
+	if err := bus.client.XGroupCreateMkStream(ctx, bus.streamName, groupName, "$").Err(); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+			eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+		if err := bus.HandleEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	lag, err := bus.ConsumerLag(ctx, h.HandlerType())
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if lag != 3 {
+		t.Error("the lag should be 3:", lag)
+	}
+
+	// The lag decreases as the handler catches up.
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, h); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if !h.Wait(time.Second) {
+			t.Fatal("the handler should handle the event")
+		}
+	}
+
+	lag, err = bus.ConsumerLag(ctx, h.HandlerType())
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if lag != 0 {
+		t.Error("the lag should be 0:", lag)
+	}
+}
+
 func TestEventBusLoadtest(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
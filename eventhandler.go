@@ -25,6 +25,13 @@ import (
 // ErrMissingEvent is when there is no event to be handled.
 var ErrMissingEvent = errors.New("missing event")
 
+// ErrBackpressure is returned (optionally wrapped) by event handlers that can
+// not keep up and want the event to be delivered again later, instead of it
+// being handled as a failure. Event busses that support flow control slow
+// down the delivery to the handler, which in turn slows down the publishers,
+// see for example local.WithBackpressure.
+var ErrBackpressure = errors.New("backpressure")
+
 // EventHandlerType is the type of an event handler, used as its unique identifier.
 type EventHandlerType string
 
@@ -0,0 +1,250 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+const (
+	// DefaultMaxSize is the default max number of events in a batch.
+	DefaultMaxSize = 100
+	// DefaultMaxWait is the default max time to wait before flushing a batch.
+	DefaultMaxWait = time.Second
+)
+
+// ErrClosed is when an event is handled after the handler is closed.
+var ErrClosed = errors.New("batch handler closed")
+
+// BatchHandler handles events in batches, for example a projector that writes
+// to an analytical store with bulk inserts.
+type BatchHandler interface {
+	// HandlerType is the type of the handler.
+	HandlerType() eh.EventHandlerType
+	// HandleBatch handles a batch of events, in the order they were handled.
+	HandleBatch(ctx context.Context, events []eh.Event) error
+}
+
+// EventHandler is an event handler that accumulates events and handles them in
+// batches with a BatchHandler. The events are handled in order, both within
+// and across batches.
+//
+// A batch is flushed when it has reached the max size, by the HandleEvent call
+// that filled it and returning the error of the batch. Batches that are not
+// filled are flushed in the background when the first event has waited for the
+// max wait, with any error sent on the error channel, see Errors. Remaining
+// events are flushed by Close, which should be called on shutdown.
+type EventHandler struct {
+	handler BatchHandler
+	maxSize int
+	maxWait time.Duration
+	errCh   chan *Error
+
+	// flushMu is held while taking and handling a batch, to handle the
+	// batches in order.
+	flushMu sync.Mutex
+
+	mu     sync.Mutex
+	batch  []eh.Event
+	timer  *time.Timer
+	gen    int
+	closed bool
+}
+
+var _ = eh.EventHandler(&EventHandler{})
+
+// NewEventHandler creates a new EventHandler that handles batches with h.
+func NewEventHandler(h BatchHandler, options ...Option) *EventHandler {
+	b := &EventHandler{
+		handler: h,
+		maxSize: DefaultMaxSize,
+		maxWait: DefaultMaxWait,
+		errCh:   make(chan *Error, 100),
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(b)
+	}
+
+	return b
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*EventHandler)
+
+// WithMaxSize sets the max number of events in a batch, DefaultMaxSize by default.
+func WithMaxSize(n int) Option {
+	return func(h *EventHandler) {
+		if n > 0 {
+			h.maxSize = n
+		}
+	}
+}
+
+// WithMaxWait sets the max time to wait for a batch to be filled before it is
+// flushed, DefaultMaxWait by default. Use 0 to only flush full batches.
+func WithMaxWait(d time.Duration) Option {
+	return func(h *EventHandler) {
+		h.maxWait = d
+	}
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (h *EventHandler) HandlerType() eh.EventHandlerType {
+	return h.handler.HandlerType()
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (h *EventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	h.mu.Lock()
+
+	if h.closed {
+		h.mu.Unlock()
+
+		return ErrClosed
+	}
+
+	h.batch = append(h.batch, event)
+
+	if len(h.batch) == 1 && h.maxWait > 0 {
+		gen := h.gen
+		h.timer = time.AfterFunc(h.maxWait, func() {
+			h.flushAfterWait(gen)
+		})
+	}
+
+	full := len(h.batch) >= h.maxSize
+	h.mu.Unlock()
+
+	if !full {
+		return nil
+	}
+
+	return h.Flush(ctx)
+}
+
+// Flush handles the accumulated events as a batch, if any.
+func (h *EventHandler) Flush(ctx context.Context) error {
+	h.flushMu.Lock()
+	defer h.flushMu.Unlock()
+
+	return h.handle(ctx, h.take())
+}
+
+// Close flushes the remaining events and stops handling new events.
+func (h *EventHandler) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+
+	return h.Flush(context.Background())
+}
+
+// Errors returns an error channel where errors from batches flushed in the
+// background are sent.
+func (h *EventHandler) Errors() <-chan *Error {
+	return h.errCh
+}
+
+// flushAfterWait flushes the batch started in generation gen, if not already
+// flushed.
+func (h *EventHandler) flushAfterWait(gen int) {
+	h.flushMu.Lock()
+	defer h.flushMu.Unlock()
+
+	h.mu.Lock()
+	if h.gen != gen {
+		h.mu.Unlock()
+
+		return
+	}
+	h.mu.Unlock()
+
+	ctx := context.Background()
+	events := h.take()
+
+	if err := h.handle(ctx, events); err != nil {
+		select {
+		case h.errCh <- &Error{Err: err, Ctx: ctx, Events: events}:
+		default:
+			log.Printf("eventhorizon: missed error in batch handler %s: %s", h.HandlerType(), err)
+		}
+	}
+}
+
+// take takes the accumulated events, starting a new batch.
+func (h *EventHandler) take() []eh.Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := h.batch
+	h.batch = nil
+	h.gen++
+
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+
+	return events
+}
+
+func (h *EventHandler) handle(ctx context.Context, events []eh.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := h.handler.HandleBatch(ctx, events); err != nil {
+		return fmt.Errorf("could not handle batch of %d events: %w", len(events), err)
+	}
+
+	return nil
+}
+
+// Error is an error from a batch flushed in the background.
+type Error struct {
+	// Err is the error that happened when handling the batch.
+	Err error
+	// Ctx is the context used when the error happened.
+	Ctx context.Context
+	// Events is the batch of events handled when the error happened.
+	Events []eh.Event
+}
+
+// Error implements the Error method of the error interface.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Cause implements the github.com/pkg/errors Unwrap method.
+func (e *Error) Cause() error {
+	return e.Unwrap()
+}
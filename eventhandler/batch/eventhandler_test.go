@@ -0,0 +1,229 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestEventHandler_MaxSize(t *testing.T) {
+	bh := newBatchHandler()
+	h := NewEventHandler(bh, WithMaxSize(3), WithMaxWait(0))
+
+	if h.HandlerType() != "batch" {
+		t.Error("the handler type should be correct:", h.HandlerType())
+	}
+
+	ctx := context.Background()
+	events := newEvents(7)
+
+	for _, e := range events {
+		if err := h.HandleEvent(ctx, e); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	batches := bh.Batches()
+	if len(batches) != 2 || len(batches[0]) != 3 || len(batches[1]) != 3 {
+		t.Fatal("there should be two full batches:", batches)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	batches = bh.Batches()
+	if len(batches) != 3 || len(batches[2]) != 1 {
+		t.Fatal("the remaining event should be flushed on close:", batches)
+	}
+
+	checkOrder(t, batches, events)
+
+	if err := h.HandleEvent(ctx, newEvents(1)[0]); !errors.Is(err, ErrClosed) {
+		t.Error("there should be a closed error:", err)
+	}
+}
+
+func TestEventHandler_MaxWait(t *testing.T) {
+	bh := newBatchHandler()
+	h := NewEventHandler(bh, WithMaxSize(100), WithMaxWait(20*time.Millisecond))
+
+	ctx := context.Background()
+	events := newEvents(4)
+
+	for _, e := range events[:2] {
+		if err := h.HandleEvent(ctx, e); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	if !bh.Wait(time.Second) {
+		t.Fatal("the batch should be flushed after the max wait")
+	}
+
+	for _, e := range events[2:] {
+		if err := h.HandleEvent(ctx, e); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	if !bh.Wait(time.Second) {
+		t.Fatal("the batch should be flushed after the max wait")
+	}
+
+	batches := bh.Batches()
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 2 {
+		t.Fatal("there should be two batches:", batches)
+	}
+
+	checkOrder(t, batches, events)
+
+	if err := h.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(bh.Batches()) != 2 {
+		t.Error("there should be no empty batch on close:", bh.Batches())
+	}
+}
+
+func TestEventHandler_Errors(t *testing.T) {
+	handlerErr := errors.New("batch error")
+	bh := newBatchHandler()
+	bh.err = handlerErr
+	h := NewEventHandler(bh, WithMaxSize(2), WithMaxWait(20*time.Millisecond))
+
+	ctx := context.Background()
+	events := newEvents(3)
+
+	// Full batches return the error.
+	if err := h.HandleEvent(ctx, events[0]); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := h.HandleEvent(ctx, events[1]); !errors.Is(err, handlerErr) {
+		t.Error("there should be a batch error:", err)
+	}
+
+	// Batches flushed after the max wait send the error.
+	if err := h.HandleEvent(ctx, events[2]); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	select {
+	case err := <-h.Errors():
+		if !errors.Is(err, handlerErr) {
+			t.Error("there should be a batch error:", err)
+		}
+
+		if len(err.Events) != 1 || err.Events[0] != events[2] {
+			t.Error("the error should have the events of the batch:", err.Events)
+		}
+	case <-time.After(time.Second):
+		t.Error("there should be an error")
+	}
+}
+
+func newEvents(n int) []eh.Event {
+	id := uuid.New()
+	events := make([]eh.Event, n)
+
+	for i := range events {
+		events[i] = eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+			eh.ForAggregate(mocks.AggregateType, id, i+1))
+	}
+
+	return events
+}
+
+func checkOrder(t *testing.T, batches [][]eh.Event, events []eh.Event) {
+	t.Helper()
+
+	var handled []eh.Event
+	for _, b := range batches {
+		handled = append(handled, b...)
+	}
+
+	if len(handled) != len(events) {
+		t.Fatal("all events should be handled:", handled)
+	}
+
+	for i, e := range handled {
+		if e != events[i] {
+			t.Error("the events should be handled in order:", i, e)
+		}
+	}
+}
+
+// batchHandler records the handled batches.
+type batchHandler struct {
+	mu      sync.Mutex
+	batches [][]eh.Event
+	err     error
+	recv    chan struct{}
+}
+
+func newBatchHandler() *batchHandler {
+	return &batchHandler{
+		recv: make(chan struct{}, 10),
+	}
+}
+
+func (h *batchHandler) HandlerType() eh.EventHandlerType {
+	return "batch"
+}
+
+func (h *batchHandler) HandleBatch(ctx context.Context, events []eh.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.err != nil {
+		return h.err
+	}
+
+	h.batches = append(h.batches, events)
+
+	select {
+	case h.recv <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (h *batchHandler) Batches() [][]eh.Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([][]eh.Event(nil), h.batches...)
+}
+
+// Wait waits for a batch to be handled, or times out.
+func (h *batchHandler) Wait(timeout time.Duration) bool {
+	select {
+	case <-h.recv:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
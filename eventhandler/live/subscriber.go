@@ -0,0 +1,205 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package live contains a helper for read models with live updates, which
+// loads the current state of an entity and subscribes to the following events
+// of its aggregate in one call, for example to push updates to a UI.
+package live
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/middleware/eventhandler/observer"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// DefaultBufferSize is the default number of events buffered per subscription.
+const DefaultBufferSize = 100
+
+// Subscriber loads entities from a read repo and subscribes to the events of
+// their aggregates from an event bus.
+type Subscriber struct {
+	repo       eh.ReadRepo
+	subs       map[uuid.UUID]map[*subscription]struct{}
+	subsMu     sync.Mutex
+	bufferSize int
+}
+
+var _ = eh.EventHandler(&Subscriber{})
+
+// subscription is the state of a single subscription, guarded by the mutex of
+// the subscriber.
+type subscription struct {
+	ch      chan eh.Event
+	ready   bool
+	version int
+	pending []eh.Event
+	closed  bool
+}
+
+// NewSubscriber creates a new Subscriber and adds it to the event bus as an
+// observer of all events, to let every instance of an app receive the events.
+func NewSubscriber(ctx context.Context, repo eh.ReadRepo, bus eh.EventBus, options ...Option) (*Subscriber, error) {
+	s := &Subscriber{
+		repo:       repo,
+		subs:       map[uuid.UUID]map[*subscription]struct{}{},
+		bufferSize: DefaultBufferSize,
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(s)
+	}
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, observer.Middleware(s)); err != nil {
+		return nil, fmt.Errorf("could not add subscriber: %w", err)
+	}
+
+	return s, nil
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Subscriber)
+
+// WithBufferSize sets the number of events buffered per subscription.
+func WithBufferSize(size int) Option {
+	return func(s *Subscriber) {
+		s.bufferSize = size
+	}
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (s *Subscriber) HandlerType() eh.EventHandlerType {
+	return "live"
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (s *Subscriber) HandleEvent(ctx context.Context, event eh.Event) error {
+	if event == nil {
+		return eh.ErrMissingEvent
+	}
+
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	for sub := range s.subs[event.AggregateID()] {
+		s.deliver(event.AggregateID(), sub, event)
+	}
+
+	return nil
+}
+
+// Subscribe returns the current entity for an ID and a channel of the
+// following events of its aggregate. The subscription starts before loading
+// the entity and if the entity is versioned (see eventhorizon.Versionable) any
+// events already included in the entity are skipped, no events are lost or
+// duplicated at the handoff. Entities that are not versioned can receive events
+// that are already included.
+//
+// The channel is closed when the context is done, or when more events than the
+// buffer size are not yet received, in which case the subscription should be
+// started again to get the current state.
+func (s *Subscriber) Subscribe(ctx context.Context, id uuid.UUID) (eh.Entity, <-chan eh.Event, error) {
+	sub := &subscription{
+		ch: make(chan eh.Event, s.bufferSize),
+	}
+
+	s.subsMu.Lock()
+	if s.subs[id] == nil {
+		s.subs[id] = map[*subscription]struct{}{}
+	}
+	s.subs[id][sub] = struct{}{}
+	s.subsMu.Unlock()
+
+	entity, err := s.repo.Find(ctx, id)
+	if err != nil {
+		s.subsMu.Lock()
+		s.remove(id, sub)
+		s.subsMu.Unlock()
+
+		return nil, nil, err
+	}
+
+	s.subsMu.Lock()
+	if v, ok := entity.(eh.Versionable); ok {
+		sub.version = v.AggregateVersion()
+	}
+
+	sub.ready = true
+	pending := sub.pending
+	sub.pending = nil
+
+	for _, event := range pending {
+		s.deliver(id, sub, event)
+	}
+	s.subsMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.subsMu.Lock()
+		s.remove(id, sub)
+		s.subsMu.Unlock()
+	}()
+
+	return entity, sub.ch, nil
+}
+
+// deliver delivers an event to a subscription, or keeps it until the entity
+// is loaded. Must be called with the lock held.
+func (s *Subscriber) deliver(id uuid.UUID, sub *subscription, event eh.Event) {
+	if sub.closed {
+		return
+	}
+
+	if !sub.ready {
+		sub.pending = append(sub.pending, event)
+
+		return
+	}
+
+	// Skip events already included in the entity.
+	if event.Version() <= sub.version {
+		return
+	}
+
+	select {
+	case sub.ch <- event:
+	default:
+		// Close subscriptions that are falling behind.
+		s.remove(id, sub)
+	}
+}
+
+// remove removes and closes a subscription. Must be called with the lock held.
+func (s *Subscriber) remove(id uuid.UUID, sub *subscription) {
+	if sub.closed {
+		return
+	}
+
+	sub.closed = true
+	close(sub.ch)
+
+	delete(s.subs[id], sub)
+
+	if len(s.subs[id]) == 0 {
+		delete(s.subs, id)
+	}
+}
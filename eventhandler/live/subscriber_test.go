@@ -0,0 +1,196 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package live
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventbus/local"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestSubscriber(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := memory.NewRepo()
+	repo.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	id := uuid.New()
+	if err := repo.Save(ctx, &mocks.Model{ID: id, Version: 1, Content: "content"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	bus := local.NewEventBus()
+	defer bus.Close()
+
+	s, err := NewSubscriber(ctx, repo, bus)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	entity, updates, err := s.Subscribe(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if m, ok := entity.(*mocks.Model); !ok || m.Content != "content" {
+		t.Error("the entity should be correct:", entity)
+	}
+
+	// The already included event is skipped, the other aggregate is ignored.
+	for _, event := range []eh.Event{newEvent(id, 1), newEvent(uuid.New(), 2), newEvent(id, 2)} {
+		if err := bus.HandleEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	select {
+	case event := <-updates:
+		if event.AggregateID() != id || event.Version() != 2 {
+			t.Error("the update should be correct:", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("there should be an update")
+	}
+
+	// Closed when the context is done.
+	cancel()
+
+	select {
+	case event, ok := <-updates:
+		if ok {
+			t.Error("there should be no more updates:", event)
+		}
+	case <-time.After(time.Second):
+		t.Error("the updates should be closed")
+	}
+}
+
+func TestSubscriber_Handoff(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	repo := &hookedRepo{
+		Repo: &mocks.Repo{
+			Entity: &mocks.Model{ID: id, Version: 1},
+		},
+	}
+	s, err := NewSubscriber(ctx, repo, &mocks.EventBus{})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Events are handled while loading the entity, both before and after it
+	// was projected.
+	repo.hook = func() {
+		for _, event := range []eh.Event{newEvent(id, 1), newEvent(id, 2)} {
+			if err := s.HandleEvent(ctx, event); err != nil {
+				t.Error("there should be no error:", err)
+			}
+		}
+	}
+
+	_, updates, err := s.Subscribe(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := s.HandleEvent(ctx, newEvent(id, 3)); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	for _, v := range []int{2, 3} {
+		if event := <-updates; event.Version() != v {
+			t.Error("the update should be correct:", event)
+		}
+	}
+
+	select {
+	case event := <-updates:
+		t.Error("there should be no more updates:", event)
+	default:
+	}
+}
+
+func TestSubscriber_Errors(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	repo := &mocks.Repo{
+		LoadErr: eh.ErrEntityNotFound,
+	}
+
+	s, err := NewSubscriber(ctx, repo, &mocks.EventBus{}, WithBufferSize(1))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, _, err := s.Subscribe(ctx, id); !errors.Is(err, eh.ErrEntityNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	if len(s.subs) != 0 {
+		t.Error("the subscription should be removed:", s.subs)
+	}
+
+	// Subscriptions falling behind are closed.
+	repo.LoadErr = nil
+	repo.Entity = &mocks.Model{ID: id}
+
+	_, updates, err := s.Subscribe(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for v := 1; v <= 2; v++ {
+		if err := s.HandleEvent(ctx, newEvent(id, v)); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	if event := <-updates; event.Version() != 1 {
+		t.Error("the update should be correct:", event)
+	}
+
+	if event, ok := <-updates; ok {
+		t.Error("the updates should be closed:", event)
+	}
+}
+
+func newEvent(id uuid.UUID, version int) eh.Event {
+	return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, id, version))
+}
+
+// hookedRepo calls a hook before finding an entity.
+type hookedRepo struct {
+	*mocks.Repo
+	hook func()
+}
+
+func (r *hookedRepo) Find(ctx context.Context, id uuid.UUID) (eh.Entity, error) {
+	if r.hook != nil {
+		r.hook()
+	}
+
+	return r.Repo.Find(ctx, id)
+}
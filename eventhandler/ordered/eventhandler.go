@@ -0,0 +1,153 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ordered contains an event handler that runs a group of event
+// handlers in a deterministic order, by priority.
+package ordered
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// EventHandler runs a group of event handlers sequentially in order of their
+// priority, highest first. Handlers with the same priority run in order of
+// registration. It should be added to an event bus as a single handler, which
+// makes it possible to for example run a deduplication guard before a projector.
+//
+// By default the first failing handler aborts the handling, and the handlers
+// with lower priority are not run. Use WithContinueOnError to run all handlers.
+type EventHandler struct {
+	handlerType     eh.EventHandlerType
+	handlers        []entry
+	handlersMu      sync.RWMutex
+	continueOnError bool
+}
+
+var _ = eh.EventHandler(&EventHandler{})
+
+// entry is a handler in the group.
+type entry struct {
+	eh.EventHandler
+	matcher  eh.EventMatcher
+	priority int
+}
+
+// NewEventHandler creates a new EventHandler.
+func NewEventHandler(handlerType eh.EventHandlerType, options ...Option) *EventHandler {
+	h := &EventHandler{
+		handlerType: handlerType,
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(h)
+	}
+
+	return h
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*EventHandler)
+
+// WithContinueOnError runs all handlers even if a handler with higher priority
+// fails, the first error is returned.
+func WithContinueOnError() Option {
+	return func(h *EventHandler) {
+		h.continueOnError = true
+	}
+}
+
+// AddHandler adds a handler for events matching the matcher, with a priority.
+// Handlers with higher priority run first.
+func (h *EventHandler) AddHandler(ctx context.Context, m eh.EventMatcher, handler eh.EventHandler, priority int) error {
+	if m == nil {
+		return eh.ErrMissingMatcher
+	}
+
+	if handler == nil {
+		return eh.ErrMissingHandler
+	}
+
+	h.handlersMu.Lock()
+	defer h.handlersMu.Unlock()
+
+	for _, existing := range h.handlers {
+		if existing.HandlerType() == handler.HandlerType() {
+			return eh.ErrHandlerAlreadyAdded
+		}
+	}
+
+	// Use a new slice to not modify it while handling events.
+	handlers := make([]entry, len(h.handlers), len(h.handlers)+1)
+	copy(handlers, h.handlers)
+	handlers = append(handlers, entry{
+		EventHandler: handler,
+		matcher:      m,
+		priority:     priority,
+	})
+
+	// Keep the handlers sorted, with registration order for equal priorities.
+	sort.SliceStable(handlers, func(i, j int) bool {
+		return handlers[i].priority > handlers[j].priority
+	})
+
+	h.handlers = handlers
+
+	return nil
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (h *EventHandler) HandlerType() eh.EventHandlerType {
+	return h.handlerType
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (h *EventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	if event == nil {
+		return eh.ErrMissingEvent
+	}
+
+	h.handlersMu.RLock()
+	handlers := h.handlers
+	h.handlersMu.RUnlock()
+
+	var firstErr error
+
+	for _, e := range handlers {
+		if !e.matcher.Match(event) {
+			continue
+		}
+
+		if err := e.HandleEvent(ctx, event); err != nil {
+			err = fmt.Errorf("could not handle event (%s): %w", e.HandlerType(), err)
+			if !h.continueOnError {
+				return err
+			}
+
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
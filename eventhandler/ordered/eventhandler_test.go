@@ -0,0 +1,173 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ordered
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestEventHandler(t *testing.T) {
+	ctx := context.Background()
+	rec := &recorder{}
+	h := NewEventHandler("group")
+
+	if h.HandlerType() != "group" {
+		t.Error("the handler type should be correct:", h.HandlerType())
+	}
+
+	// Added out of order, "b" and "c" have the same priority.
+	for _, a := range []struct {
+		name     string
+		priority int
+	}{
+		{"b", 5},
+		{"a", 10},
+		{"d", -1},
+		{"c", 5},
+	} {
+		if err := h.AddHandler(ctx, eh.MatchAll{}, rec.handler(a.name, nil), a.priority); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// Only matching handlers are run.
+	if err := h.AddHandler(ctx, eh.MatchEvents{"other"}, rec.handler("e", nil), 100); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := h.HandleEvent(ctx, newEvent()); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if expected := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(rec.order, expected) {
+		t.Error("the handlers should run in priority order:", rec.order)
+	}
+
+	if err := h.HandleEvent(ctx, nil); !errors.Is(err, eh.ErrMissingEvent) {
+		t.Error("there should be a missing event error:", err)
+	}
+}
+
+func TestEventHandler_Errors(t *testing.T) {
+	ctx := context.Background()
+	handlerErr := errors.New("handler error")
+
+	// A failing handler aborts the lower priority handlers.
+	rec := &recorder{}
+	h := NewEventHandler("group")
+	addHandlers(t, h, rec, handlerErr)
+
+	if err := h.HandleEvent(ctx, newEvent()); !errors.Is(err, handlerErr) {
+		t.Error("there should be a handler error:", err)
+	}
+
+	if expected := []string{"first", "failing"}; !reflect.DeepEqual(rec.order, expected) {
+		t.Error("the lower priority handlers should not run:", rec.order)
+	}
+
+	// All handlers run when continuing on errors.
+	rec = &recorder{}
+	h = NewEventHandler("group", WithContinueOnError())
+	addHandlers(t, h, rec, handlerErr)
+
+	if err := h.HandleEvent(ctx, newEvent()); !errors.Is(err, handlerErr) {
+		t.Error("there should be a handler error:", err)
+	}
+
+	if expected := []string{"first", "failing", "last"}; !reflect.DeepEqual(rec.order, expected) {
+		t.Error("all handlers should run:", rec.order)
+	}
+}
+
+func TestEventHandler_AddHandlerErrors(t *testing.T) {
+	ctx := context.Background()
+	h := NewEventHandler("group")
+
+	if err := h.AddHandler(ctx, nil, mocks.NewEventHandler("a"), 0); !errors.Is(err, eh.ErrMissingMatcher) {
+		t.Error("there should be a missing matcher error:", err)
+	}
+
+	if err := h.AddHandler(ctx, eh.MatchAll{}, nil, 0); !errors.Is(err, eh.ErrMissingHandler) {
+		t.Error("there should be a missing handler error:", err)
+	}
+
+	if err := h.AddHandler(ctx, eh.MatchAll{}, mocks.NewEventHandler("a"), 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := h.AddHandler(ctx, eh.MatchAll{}, mocks.NewEventHandler("a"), 1); !errors.Is(err, eh.ErrHandlerAlreadyAdded) {
+		t.Error("there should be a handler already added error:", err)
+	}
+}
+
+func addHandlers(t *testing.T, h *EventHandler, rec *recorder, err error) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if err := h.AddHandler(ctx, eh.MatchAll{}, rec.handler("last", nil), 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := h.AddHandler(ctx, eh.MatchAll{}, rec.handler("failing", err), 1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := h.AddHandler(ctx, eh.MatchAll{}, rec.handler("first", nil), 2); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+}
+
+func newEvent() eh.Event {
+	return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+}
+
+// recorder records the order handlers are run in.
+type recorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *recorder) handler(name string, err error) eh.EventHandler {
+	return &recordingHandler{name: name, recorder: r, err: err}
+}
+
+type recordingHandler struct {
+	name     string
+	recorder *recorder
+	err      error
+}
+
+func (h *recordingHandler) HandlerType() eh.EventHandlerType {
+	return eh.EventHandlerType(h.name)
+}
+
+func (h *recordingHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	h.recorder.mu.Lock()
+	h.recorder.order = append(h.recorder.order, h.name)
+	h.recorder.mu.Unlock()
+
+	return h.err
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrNotProjectionAggregate is when an AggregateProjector projects onto an
+// entity that is not a ProjectionAggregate.
+var ErrNotProjectionAggregate = errors.New("entity is not a projection aggregate")
+
+// ProjectionAggregate is a read model that is projected from the events of an
+// aggregate in the same way as an aggregate applies its events, which makes it
+// deterministically rebuildable from the events. It is versioned like the
+// aggregate, and should be projected with the WithCompareAndSet option to not
+// let concurrent projectors overwrite each other.
+type ProjectionAggregate interface {
+	eh.Entity
+	eh.Versionable
+
+	// SetEntityID sets the ID of a newly created projection.
+	SetEntityID(uuid.UUID)
+	// SetAggregateVersion sets the version after an event has been applied.
+	SetAggregateVersion(int)
+	// ApplyEvent applies an event to the projection.
+	ApplyEvent(context.Context, eh.Event) error
+}
+
+// ProjectionAggregateBase is a base to embed in ProjectionAggregates, which
+// stores the ID and version. The version is stored in the "version" field, as
+// used by the compare and set of the MongoDB repo.
+type ProjectionAggregateBase struct {
+	ID      uuid.UUID `json:"id"      bson:"_id"`
+	Version int       `json:"version" bson:"version"`
+}
+
+// EntityID implements the EntityID method of the eventhorizon.Entity interface.
+func (b *ProjectionAggregateBase) EntityID() uuid.UUID {
+	return b.ID
+}
+
+// SetEntityID implements the SetEntityID method of the ProjectionAggregate interface.
+func (b *ProjectionAggregateBase) SetEntityID(id uuid.UUID) {
+	b.ID = id
+}
+
+// AggregateVersion implements the AggregateVersion method of the
+// eventhorizon.Versionable interface.
+func (b *ProjectionAggregateBase) AggregateVersion() int {
+	return b.Version
+}
+
+// SetAggregateVersion implements the SetAggregateVersion method of the
+// ProjectionAggregate interface.
+func (b *ProjectionAggregateBase) SetAggregateVersion(v int) {
+	b.Version = v
+}
+
+// AggregateProjector is a Projector for ProjectionAggregates, which applies
+// the events to the projection and increments its version.
+type AggregateProjector struct {
+	projectorType Type
+}
+
+var _ = Projector(&AggregateProjector{})
+
+// NewAggregateProjector creates a new AggregateProjector.
+func NewAggregateProjector(t Type) *AggregateProjector {
+	return &AggregateProjector{
+		projectorType: t,
+	}
+}
+
+// ProjectorType implements the ProjectorType method of the Projector interface.
+func (p *AggregateProjector) ProjectorType() Type {
+	return p.projectorType
+}
+
+// Project implements the Project method of the Projector interface.
+func (p *AggregateProjector) Project(ctx context.Context, event eh.Event, entity eh.Entity) (eh.Entity, error) {
+	projection, ok := entity.(ProjectionAggregate)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotProjectionAggregate, entity)
+	}
+
+	if projection.EntityID() == uuid.Nil {
+		projection.SetEntityID(event.AggregateID())
+	}
+
+	if err := projection.ApplyEvent(ctx, event); err != nil {
+		return nil, err
+	}
+
+	projection.SetAggregateVersion(event.Version())
+
+	return projection, nil
+}
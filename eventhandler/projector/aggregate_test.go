@@ -0,0 +1,216 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestAggregateProjector(t *testing.T) {
+	ctx := context.Background()
+	repo := newListRepo()
+	h := NewEventHandler(NewAggregateProjector("list"), repo, WithCompareAndSet())
+	h.SetEntityFactory(func() eh.Entity { return &listProjection{} })
+
+	id := uuid.New()
+	events := listEvents(id, 3)
+
+	for _, event := range events {
+		if err := h.HandleEvent(ctx, event); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	// Duplicate events are ignored.
+	if err := h.HandleEvent(ctx, events[1]); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	assertList(t, repo, id, 3)
+
+	// Only projection aggregates can be projected.
+	p := NewAggregateProjector("list")
+	if _, err := p.Project(ctx, events[0], &mocks.SimpleModel{}); !errors.Is(err, ErrNotProjectionAggregate) {
+		t.Error("there should be a not projection aggregate error:", err)
+	}
+}
+
+func TestAggregateProjector_StaleWriter(t *testing.T) {
+	ctx := context.Background()
+	inner := newListRepo()
+	repo := &racingRepo{Repo: inner}
+
+	stale := NewEventHandler(NewAggregateProjector("list"), repo, WithCompareAndSet())
+	stale.SetEntityFactory(func() eh.Entity { return &listProjection{} })
+
+	other := NewEventHandler(NewAggregateProjector("list"), inner, WithCompareAndSet())
+	other.SetEntityFactory(func() eh.Entity { return &listProjection{} })
+
+	id := uuid.New()
+	events := listEvents(id, 2)
+
+	// The other projector saves both events after the stale projector has
+	// loaded the entity, but before it saves.
+	repo.beforeSave = func() {
+		for _, event := range events {
+			if err := other.HandleEvent(ctx, event); err != nil {
+				t.Error("there should be no error:", err)
+			}
+		}
+	}
+
+	// The stale projector retries and finds the event already projected.
+	if err := stale.HandleEvent(ctx, events[0]); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if repo.conflicts != 1 {
+		t.Error("there should be a conflict:", repo.conflicts)
+	}
+
+	assertList(t, inner, id, 2)
+}
+
+func TestAggregateProjector_ConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	repo := newListRepo()
+	id := uuid.New()
+	events := listEvents(id, 20)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		h := NewEventHandler(NewAggregateProjector("list"), repo, WithCompareAndSet())
+		h.SetEntityFactory(func() eh.Entity { return &listProjection{} })
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for _, event := range events {
+				if err := h.HandleEvent(ctx, event); err != nil {
+					t.Error("there should be no error:", err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assertList(t, repo, id, 20)
+}
+
+func TestEventHandler_CompareAndSetNotSupported(t *testing.T) {
+	repo := &mocks.Repo{
+		LoadErr: &eh.RepoError{Err: eh.ErrEntityNotFound},
+	}
+	h := NewEventHandler(NewAggregateProjector("list"), repo, WithCompareAndSet())
+	h.SetEntityFactory(func() eh.Entity { return &listProjection{} })
+
+	if err := h.HandleEvent(context.Background(), listEvents(uuid.New(), 1)[0]); !errors.Is(err, eh.ErrCompareAndSetNotSupported) {
+		t.Error("there should be a not supported error:", err)
+	}
+}
+
+func newListRepo() *memory.Repo {
+	repo := memory.NewRepo()
+	repo.SetEntityFactory(func() eh.Entity { return &listProjection{} })
+
+	return repo
+}
+
+func listEvents(id uuid.UUID, n int) []eh.Event {
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	events := make([]eh.Event, n)
+
+	for i := range events {
+		events[i] = eh.NewEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprint("item", i+1)}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, i+1))
+	}
+
+	return events
+}
+
+func assertList(t *testing.T, repo eh.ReadRepo, id uuid.UUID, n int) {
+	t.Helper()
+
+	entity, err := repo.Find(context.Background(), id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := &listProjection{
+		ProjectionAggregateBase: ProjectionAggregateBase{ID: id, Version: n},
+	}
+	for i := 0; i < n; i++ {
+		expected.Items = append(expected.Items, fmt.Sprint("item", i+1))
+	}
+
+	if !reflect.DeepEqual(entity, expected) {
+		t.Error("the projection should be correct:", entity)
+	}
+}
+
+// listProjection is a projection of the content of all events.
+type listProjection struct {
+	ProjectionAggregateBase
+
+	Items []string `json:"items"`
+}
+
+func (p *listProjection) ApplyEvent(ctx context.Context, event eh.Event) error {
+	data, ok := event.Data().(*mocks.EventData)
+	if !ok {
+		return errors.New("invalid event data")
+	}
+
+	p.Items = append(p.Items, data.Content)
+
+	return nil
+}
+
+// racingRepo runs a func before the first save, to simulate a concurrent writer.
+type racingRepo struct {
+	*memory.Repo
+
+	beforeSave func()
+	conflicts  int
+}
+
+func (r *racingRepo) SaveIfVersion(ctx context.Context, entity eh.Entity, expectedVersion int) error {
+	if r.beforeSave != nil {
+		r.beforeSave()
+		r.beforeSave = nil
+	}
+
+	err := r.Repo.SaveIfVersion(ctx, entity, expectedVersion)
+	if errors.Is(err, eh.ErrEntityVersionConflict) {
+		r.conflicts++
+	}
+
+	return err
+}
@@ -105,9 +105,14 @@ type EventHandler struct {
 	useWait                bool
 	useRetryOnce           bool
 	useIrregularVersioning bool
+	useCompareAndSet       bool
 	entityLookupFn         func(eh.Event) uuid.UUID
 }
 
+// maxConflictRetries is the max number of retries for projections that
+// conflict with concurrent writes when using WithCompareAndSet.
+const maxConflictRetries = 10
+
 var _ = eh.EventHandler(&EventHandler{})
 
 // NewEventHandler creates a new EventHandler.
@@ -152,6 +157,17 @@ func WithIrregularVersioning() Option {
 	}
 }
 
+// WithCompareAndSet saves the projected entities with optimistic concurrency,
+// using the eventhorizon.CompareAndSetRepo interface of the repo, to not let
+// concurrent projectors overwrite each others changes. Entities are only saved
+// if the stored version is the same as when loaded, otherwise the projection is
+// retried with the updated entity. Requires versioned entities.
+func WithCompareAndSet() Option {
+	return func(h *EventHandler) {
+		h.useCompareAndSet = true
+	}
+}
+
 // WithEntityLookup can be used to provide an alternative ID (from the aggregate ID)
 // for fetching the projected entity. The lookup func can for example extract
 // another field from the event or use a static ID for some singleton-like projections.
@@ -182,8 +198,10 @@ func (h *EventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
 		}
 	}
 
-	// Used to retry once in case of a version mismatch.
+	// Used to retry once in case of a version mismatch, and to retry projections
+	// conflicting with concurrent writes.
 	triedOnce := false
+	conflicts := 0
 retryOnce:
 
 	findCtx := ctx
@@ -280,6 +298,8 @@ retryOnce:
 		}
 	}
 
+	loadedVersion := entityVersion
+
 	// Run the projection, which will possibly increment the version.
 	newEntity, err := h.projector.Project(ctx, event, entity)
 	if err != nil {
@@ -319,7 +339,22 @@ retryOnce:
 			}
 		}
 
-		if err := h.repo.Save(ctx, newEntity); err != nil {
+		if h.useCompareAndSet {
+			err := h.saveIfVersion(ctx, newEntity, loadedVersion)
+			if errors.Is(err, eh.ErrEntityVersionConflict) && conflicts < maxConflictRetries {
+				conflicts++
+
+				goto retryOnce
+			} else if err != nil {
+				return &Error{
+					Err:           fmt.Errorf("could not save: %w", err),
+					Projector:     h.projector.ProjectorType().String(),
+					Event:         event,
+					EntityID:      id,
+					EntityVersion: entityVersion,
+				}
+			}
+		} else if err := h.repo.Save(ctx, newEntity); err != nil {
 			return &Error{
 				Err:           fmt.Errorf("could not save: %w", err),
 				Projector:     h.projector.ProjectorType().String(),
@@ -343,6 +378,20 @@ retryOnce:
 	return nil
 }
 
+// saveIfVersion saves an entity if the stored entity has the expected version.
+func (h *EventHandler) saveIfVersion(ctx context.Context, entity eh.Entity, expectedVersion int) error {
+	if _, ok := entity.(eh.Versionable); !ok {
+		return eh.ErrEntityHasNoVersion
+	}
+
+	repo, ok := h.repo.(eh.CompareAndSetRepo)
+	if !ok {
+		return eh.ErrCompareAndSetNotSupported
+	}
+
+	return repo.SaveIfVersion(ctx, entity, expectedVersion)
+}
+
 // SetEntityFactory sets a factory function that creates concrete entity types.
 func (h *EventHandler) SetEntityFactory(f func() eh.Entity) {
 	h.factoryFn = f
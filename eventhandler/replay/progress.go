@@ -0,0 +1,147 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"time"
+)
+
+// Progress is the progress of a replay, see WithProgress.
+type Progress struct {
+	// Position is the position in the event store of the last streamed event,
+	// which is the global position when replaying in the order the events were
+	// saved, and the number of streamed events with WithTimestampOrder.
+	Position int
+	// Processed is the number of replayed events that have been handled.
+	Processed int
+	// Rate is the average number of events processed per second.
+	Rate float64
+	// EstimatedCompletion is the estimated time when the replay is done, or
+	// zero if unknown, see WithProgressTotal.
+	EstimatedCompletion time.Time
+	// Err is the error that stopped the replay, only set if Done.
+	Err error
+	// Done is true for the final progress, sent when the replay is done.
+	Done bool
+}
+
+// WithProgress sends the progress of the replay on ch, for example to show
+// live progress of a projection rebuild in a dashboard. The progress is sent
+// at most once per interval while replaying, and is dropped if ch is not ready
+// to receive, to not slow down the replay. A final progress with Done set and
+// any error is always sent when the replay is done, after which ch is closed.
+// Note that the final progress blocks the replay until it is received, so ch
+// should be buffered or read until it is closed.
+func WithProgress(ch chan<- Progress, interval time.Duration) Option {
+	return func(r *handler) {
+		r.progress = &progress{
+			ch:       ch,
+			interval: interval,
+			now:      time.Now,
+		}
+	}
+}
+
+// WithProgressTotal sets the expected position of the last event in the event
+// store, used to estimate the completion time of the progress sent with
+// WithProgress. Must be used after WithProgress.
+func WithProgressTotal(total int) Option {
+	return func(r *handler) {
+		if r.progress != nil {
+			r.progress.total = total
+		}
+	}
+}
+
+// progress tracks and sends the progress of a replay.
+type progress struct {
+	ch       chan<- Progress
+	interval time.Duration
+	total    int
+
+	start     time.Time
+	lastSent  time.Time
+	position  int
+	processed int
+
+	// now is used for timing, can be replaced in tests.
+	now func() time.Time
+}
+
+// started starts the timing of the replay.
+func (p *progress) started() {
+	p.start = p.now()
+	p.lastSent = p.start
+}
+
+// handled counts a handled event.
+func (p *progress) handled() {
+	p.processed++
+}
+
+// streamed updates the position of the last streamed event, and sends the
+// progress if the interval has passed.
+func (p *progress) streamed(position int) {
+	p.position = position
+
+	now := p.now()
+	if now.Sub(p.lastSent) < p.interval {
+		return
+	}
+
+	p.lastSent = now
+
+	select {
+	case p.ch <- p.current(now):
+	default:
+	}
+}
+
+// done sends the final progress and closes the channel.
+func (p *progress) done(err error) {
+	current := p.current(p.now())
+	current.Err = err
+	current.Done = true
+
+	p.ch <- current
+	close(p.ch)
+}
+
+func (p *progress) current(now time.Time) Progress {
+	current := Progress{
+		Position:  p.position,
+		Processed: p.processed,
+	}
+
+	elapsed := now.Sub(p.start)
+	if elapsed <= 0 {
+		return current
+	}
+
+	current.Rate = float64(p.processed) / elapsed.Seconds()
+
+	// Estimate the completion from the rate of streamed events, which includes
+	// the events that are not matched.
+	if p.total > 0 && p.position > 0 {
+		remaining := p.total - p.position
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		current.EstimatedCompletion = now.Add(time.Duration(float64(elapsed) * float64(remaining) / float64(p.position)))
+	}
+
+	return current
+}
@@ -0,0 +1,315 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrNotStreamable is when the event store can not stream all events.
+var ErrNotStreamable = errors.New("event store is not streamable")
+
+// ErrReplayFailed is returned to the bus for live events received after the
+// replay failed.
+var ErrReplayFailed = errors.New("replay failed")
+
+// ErrBufferFull is when more live events than the limit set with
+// WithBufferLimit are received during the replay.
+var ErrBufferFull = errors.New("buffer of live events is full")
+
+// ReplayThenSubscribe replays all historical events matching m from the store
+// to the handler, and then switches to live delivery from the bus, without
+// gaps or duplicates around the switch. The store must implement the
+// eventhorizon.EventStoreStreamer interface.
+//
+// The handler is added to the bus before replaying, and live events received
+// during the replay are buffered. When the replay is done the buffered events
+// are handled, after which live events are handled directly. Events are
+// deduplicated by aggregate version, any event with a version that is already
+// handled for its aggregate is skipped. This requires keeping the last handled
+// version of each aggregate in memory.
+//
+// Replaying stops at the first error from the handler, which is returned. An
+// error when handling the buffered events is returned after switching to live
+// delivery, errors when handling live events are returned to the bus.
+//
+// If the replay fails or the context is done before it is finished, the
+// buffered events are dropped and ErrReplayFailed is returned to the bus for
+// all following live events, as the handler can not be removed from the bus.
+// The buffer is unbounded by default, use WithBufferLimit to fail the replay
+// when too many live events are received during it.
+//
+// The replay can be throttled with WithRateLimit or WithAdaptiveRateLimit, and
+// stops when the context is done. Use WithTimestampOrder to replay the events
+// of all aggregates in timestamp order instead of the order they were saved.
+// The progress of the replay can be followed with WithProgress.
+func ReplayThenSubscribe(ctx context.Context, store eh.EventStore, bus eh.EventBus, h eh.EventHandler, m eh.EventMatcher, options ...Option) error {
+	if h == nil {
+		return eh.ErrMissingHandler
+	}
+
+	if m == nil {
+		return eh.ErrMissingMatcher
+	}
+
+	r := &handler{
+		EventHandler: h,
+		versions:     map[uuid.UUID]int{},
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(r)
+	}
+
+	var stream func(ctx context.Context, f func(ctx context.Context, position int, event eh.Event) error) error
+
+	if r.byTime {
+		streamer, ok := store.(eh.EventStoreTimeStreamer)
+		if !ok {
+			return ErrNotStreamable
+		}
+
+		// Use the number of streamed events as position.
+		stream = func(ctx context.Context, f func(ctx context.Context, position int, event eh.Event) error) error {
+			position := 0
+
+			return streamer.StreamAllByTime(ctx, func(ctx context.Context, event eh.Event) error {
+				position++
+
+				return f(ctx, position, event)
+			})
+		}
+	} else {
+		streamer, ok := store.(eh.EventStoreStreamer)
+		if !ok {
+			return ErrNotStreamable
+		}
+
+		stream = func(ctx context.Context, f func(ctx context.Context, position int, event eh.Event) error) error {
+			return streamer.StreamAll(ctx, 0, f)
+		}
+	}
+
+	// Start buffering live events before replaying, to not miss any events
+	// saved during the replay.
+	if err := bus.AddHandler(ctx, m, r); err != nil {
+		return fmt.Errorf("could not subscribe: %w", err)
+	}
+
+	if r.progress != nil {
+		r.progress.started()
+	}
+
+	err := stream(ctx, func(ctx context.Context, position int, event eh.Event) error {
+		if m.Match(event) {
+			if err := r.replay(ctx, event); err != nil {
+				return err
+			}
+
+			if r.progress != nil {
+				r.progress.handled()
+			}
+		}
+
+		if r.progress != nil {
+			r.progress.streamed(position)
+		}
+
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("could not replay events: %w", err)
+		r.fail(err)
+	} else {
+		err = r.goLive()
+	}
+
+	if r.progress != nil {
+		r.progress.done(err)
+	}
+
+	return err
+}
+
+// replay handles a historical event.
+func (r *handler) replay(ctx context.Context, event eh.Event) error {
+	if r.throttle != nil {
+		// Wait without holding the lock, to keep buffering live events.
+		if err := r.throttle.wait(ctx); err != nil {
+			return err
+		}
+
+		start := r.throttle.now()
+		defer func() {
+			r.throttle.handled(r.throttle.now().Sub(start))
+		}()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Stop if the buffer of live events is full.
+	if r.err != nil {
+		return r.err
+	}
+
+	// Events are not deduplicated during a replay in timestamp order, where
+	// the versions of an aggregate can be out of order if their timestamps
+	// are.
+	if r.byTime {
+		return r.handleEvent(ctx, event)
+	}
+
+	return r.handle(ctx, event)
+}
+
+// WithTimestampOrder replays the historical events of all aggregates ordered by
+// timestamp, with ties broken by aggregate ID and version, for example for a
+// projection of a timeline across aggregates. The store must implement the
+// eventhorizon.EventStoreTimeStreamer interface. Live events are handled in the
+// order they are received from the bus.
+func WithTimestampOrder() Option {
+	return func(r *handler) {
+		r.byTime = true
+	}
+}
+
+// WithBufferLimit limits the number of live events buffered during the replay
+// to n. The replay fails with ErrBufferFull if more live events are received.
+func WithBufferLimit(n int) Option {
+	return func(r *handler) {
+		r.bufferLimit = n
+	}
+}
+
+// handler buffers live events until the replay is done.
+type handler struct {
+	eh.EventHandler
+
+	mu          sync.Mutex
+	live        bool
+	err         error
+	buffer      []bufferedEvent
+	bufferLimit int
+	versions    map[uuid.UUID]int
+	throttle    *throttle
+	byTime      bool
+	progress    *progress
+}
+
+type bufferedEvent struct {
+	ctx   context.Context
+	event eh.Event
+}
+
+// InnerHandler implements EventHandlerChain
+func (r *handler) InnerHandler() eh.EventHandler {
+	return r.EventHandler
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (r *handler) HandleEvent(ctx context.Context, event eh.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return ErrReplayFailed
+	}
+
+	if !r.live {
+		if r.bufferLimit > 0 && len(r.buffer) >= r.bufferLimit {
+			r.err = ErrBufferFull
+			r.buffer = nil
+
+			return ErrReplayFailed
+		}
+
+		r.buffer = append(r.buffer, bufferedEvent{ctx, event})
+
+		return nil
+	}
+
+	return r.handle(ctx, event)
+}
+
+// fail drops the buffered events and fails all following live events.
+func (r *handler) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = err
+	}
+
+	r.buffer = nil
+}
+
+// goLive handles the buffered events and switches to live delivery.
+func (r *handler) goLive() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return fmt.Errorf("could not replay events: %w", r.err)
+	}
+
+	var firstErr error
+
+	for _, e := range r.buffer {
+		if err := r.handle(e.ctx, e.event); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("could not handle buffered event: %w", err)
+		}
+	}
+
+	r.buffer = nil
+	r.live = true
+
+	return firstErr
+}
+
+// handle handles an event if its version is not already handled, must be
+// called with the lock held.
+func (r *handler) handle(ctx context.Context, event eh.Event) error {
+	id := event.AggregateID()
+	if id != uuid.Nil && event.Version() <= r.versions[id] {
+		return nil
+	}
+
+	return r.handleEvent(ctx, event)
+}
+
+// handleEvent handles an event and keeps the highest handled version of its
+// aggregate, must be called with the lock held.
+func (r *handler) handleEvent(ctx context.Context, event eh.Event) error {
+	if err := r.EventHandler.HandleEvent(ctx, event); err != nil {
+		return err
+	}
+
+	if id := event.AggregateID(); id != uuid.Nil && event.Version() > r.versions[id] {
+		r.versions[id] = event.Version()
+	}
+
+	return nil
+}
@@ -0,0 +1,588 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventbus/local"
+	"github.com/looplab/eventhorizon/eventstore/memory"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestReplayThenSubscribe(t *testing.T) {
+	ctx := context.Background()
+
+	bus := &hookedEventBus{EventBus: local.NewEventBus()}
+	defer bus.Close()
+
+	store, err := memory.NewEventStore(memory.WithEventHandler(bus))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+
+	// Historical events, published before subscribing.
+	save(t, store, id, 1)
+	save(t, store, id, 2)
+
+	// An event saved after subscribing but before replaying is both replayed
+	// and received live.
+	bus.afterAdd = func() {
+		save(t, store, id, 3)
+	}
+
+	// An event saved during the replay is only received live.
+	h := &recordingHandler{onEvent: func(event eh.Event) {
+		if event.Version() == 1 {
+			save(t, store, id, 4)
+		}
+	}}
+
+	if err := ReplayThenSubscribe(ctx, store, bus, h, eh.MatchAll{}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// An event saved after the replay is only received live.
+	save(t, store, id, 5)
+
+	if !h.waitFor(5, time.Second) {
+		t.Fatal("all events should be handled:", h.versions())
+	}
+
+	// Wait for any late duplicates.
+	time.Sleep(50 * time.Millisecond)
+
+	versions := h.versions()
+	if len(versions) != 5 {
+		t.Fatal("there should be no duplicates:", versions)
+	}
+
+	for i, v := range versions {
+		if v != i+1 {
+			t.Error("the events should be handled in order without gaps:", versions)
+
+			break
+		}
+	}
+}
+
+func TestReplayThenSubscribe_Errors(t *testing.T) {
+	ctx := context.Background()
+	bus := local.NewEventBus()
+
+	defer bus.Close()
+
+	if err := ReplayThenSubscribe(ctx, &mocks.EventStore{}, bus,
+		&recordingHandler{}, eh.MatchAll{}); !errors.Is(err, ErrNotStreamable) {
+		t.Error("there should be a not streamable error:", err)
+	}
+
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	save(t, store, uuid.New(), 1)
+
+	handlerErr := errors.New("handler error")
+	h := &recordingHandler{err: handlerErr}
+
+	if err := ReplayThenSubscribe(ctx, store, bus, h, eh.MatchAll{}); !errors.Is(err, handlerErr) {
+		t.Error("there should be a handler error:", err)
+	}
+}
+
+func TestReplayThenSubscribe_Failed(t *testing.T) {
+	ctx := context.Background()
+	bus := local.NewEventBus()
+
+	defer bus.Close()
+
+	store, err := memory.NewEventStore(memory.WithEventHandler(bus))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	save(t, store, id, 1)
+
+	handlerErr := errors.New("handler error")
+	h := &recordingHandler{err: handlerErr}
+
+	if err := ReplayThenSubscribe(ctx, store, bus, h, eh.MatchAll{}); !errors.Is(err, handlerErr) {
+		t.Error("there should be a handler error:", err)
+	}
+
+	// Live events after a failed replay are returned to the bus.
+	h.err = nil
+
+	save(t, store, id, 2)
+
+	// The local bus does not wrap the handler errors.
+	select {
+	case err := <-bus.Errors():
+		if !strings.Contains(err.Error(), ErrReplayFailed.Error()) {
+			t.Error("there should be a replay failed error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("there should be a replay failed error")
+	}
+
+	if versions := h.versions(); len(versions) != 0 {
+		t.Error("no events should be handled:", versions)
+	}
+}
+
+func TestReplayThenSubscribe_BufferLimit(t *testing.T) {
+	ctx := context.Background()
+
+	bus := &hookedEventBus{EventBus: local.NewEventBus()}
+	defer bus.Close()
+
+	store, err := memory.NewEventStore(memory.WithEventHandler(bus))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	save(t, store, id, 1)
+
+	// More live events than the limit are received before the replay is done.
+	bus.afterAdd = func() {
+		save(t, store, id, 2)
+		save(t, store, id, 3)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := ReplayThenSubscribe(ctx, store, bus, &recordingHandler{}, eh.MatchAll{},
+		WithBufferLimit(1)); !errors.Is(err, ErrBufferFull) {
+		t.Error("there should be a buffer full error:", err)
+	}
+}
+
+func TestReplayThenSubscribe_TimestampOrder(t *testing.T) {
+	ctx := context.Background()
+	bus := local.NewEventBus()
+
+	defer bus.Close()
+
+	store, err := memory.NewEventStore(memory.WithEventHandler(bus))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Two aggregates with interleaved timestamps, saved one aggregate at a time.
+	id1, id2 := uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	saveAt := func(id uuid.UUID, version int, at time.Duration) {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp.Add(at),
+			eh.ForAggregate(mocks.AggregateType, id, version))
+		if err := store.Save(ctx, []eh.Event{event}, version-1); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	saveAt(id1, 1, 1*time.Second)
+	saveAt(id1, 2, 3*time.Second)
+	saveAt(id1, 3, 5*time.Second)
+	saveAt(id2, 1, 2*time.Second)
+	saveAt(id2, 2, 4*time.Second)
+
+	var (
+		mu      sync.Mutex
+		handled []string
+	)
+
+	h := &recordingHandler{onEvent: func(event eh.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		name := "a"
+		if event.AggregateID() == id2 {
+			name = "b"
+		}
+
+		handled = append(handled, fmt.Sprintf("%s%d", name, event.Version()))
+	}}
+
+	if err := ReplayThenSubscribe(ctx, store, bus, h, eh.MatchAll{}, WithTimestampOrder()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Live events are handled after the replayed events.
+	saveAt(id2, 3, 6*time.Second)
+
+	if !h.waitFor(6, time.Second) {
+		t.Fatal("all events should be handled:", h.versions())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := []string{"a1", "b1", "a2", "b2", "a3", "b3"}
+	if !reflect.DeepEqual(handled, expected) {
+		t.Error("the events should be handled in timestamp order:", handled)
+	}
+
+	if err := ReplayThenSubscribe(ctx, &mocks.EventStore{}, bus,
+		&recordingHandler{}, eh.MatchAll{}, WithTimestampOrder()); !errors.Is(err, ErrNotStreamable) {
+		t.Error("there should be a not streamable error:", err)
+	}
+}
+
+func TestReplayThenSubscribe_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	bus := local.NewEventBus()
+
+	defer bus.Close()
+
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	for i := 1; i <= 20; i++ {
+		save(t, store, id, i)
+	}
+
+	clock := &fakeClock{now: time.Now()}
+
+	var handledAt []time.Time
+
+	h := &recordingHandler{onEvent: func(event eh.Event) {
+		handledAt = append(handledAt, clock.Now())
+	}}
+
+	if err := ReplayThenSubscribe(ctx, store, bus, h, eh.MatchAll{},
+		WithRateLimit(10), withClock(clock)); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(handledAt) != 20 {
+		t.Fatal("all events should be replayed:", len(handledAt))
+	}
+
+	for i := 1; i < len(handledAt); i++ {
+		if d := handledAt[i].Sub(handledAt[i-1]); d < 100*time.Millisecond {
+			t.Error("the events should be replayed at the rate limit:", i, d)
+		}
+	}
+
+	elapsed := handledAt[len(handledAt)-1].Sub(handledAt[0])
+	if rate := float64(len(handledAt)-1) / elapsed.Seconds(); rate > 10 {
+		t.Error("the replay rate should be within the limit:", rate)
+	}
+}
+
+func TestReplayThenSubscribe_RateLimitCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := local.NewEventBus()
+
+	defer bus.Close()
+
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	save(t, store, id, 1)
+	save(t, store, id, 2)
+
+	// Cancel while waiting to replay the second event.
+	h := &recordingHandler{onEvent: func(event eh.Event) {
+		cancel()
+	}}
+
+	start := time.Now()
+
+	if err := ReplayThenSubscribe(ctx, store, bus, h, eh.MatchAll{},
+		WithRateLimit(0.1)); !errors.Is(err, context.Canceled) {
+		t.Error("there should be a context canceled error:", err)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Error("the replay should stop when the context is canceled")
+	}
+
+	if versions := h.versions(); len(versions) != 1 {
+		t.Error("only the first event should be replayed:", versions)
+	}
+}
+
+func TestReplayThenSubscribe_Progress(t *testing.T) {
+	ctx := context.Background()
+	bus := local.NewEventBus()
+
+	defer bus.Close()
+
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	id := uuid.New()
+	for i := 1; i <= 20; i++ {
+		save(t, store, id, i)
+	}
+
+	// The events are replayed every 100ms, with progress every 500ms.
+	clock := &fakeClock{now: time.Now()}
+	ch := make(chan Progress, 10)
+
+	if err := ReplayThenSubscribe(ctx, store, bus, &recordingHandler{}, eh.MatchAll{},
+		WithRateLimit(10), WithProgress(ch, 500*time.Millisecond), WithProgressTotal(20),
+		withClock(clock)); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var progress []Progress
+	for p := range ch {
+		progress = append(progress, p)
+	}
+
+	if len(progress) != 4 {
+		t.Fatal("there should be progress at the interval and when done:", progress)
+	}
+
+	for i, p := range progress[:3] {
+		if p.Done || p.Err != nil {
+			t.Error("the progress should not be done:", p)
+		}
+
+		if n := 6 + 5*i; p.Position != n || p.Processed != n {
+			t.Error("the progress should be sent at the interval:", p)
+		}
+
+		// The first event is replayed at the start.
+		if rate := float64(p.Processed) / (float64(p.Processed-1) * 0.1); math.Abs(p.Rate-rate) > 0.001 {
+			t.Error("the rate should be correct:", p.Rate, rate)
+		}
+
+		if !p.EstimatedCompletion.After(clock.Now().Add(-2 * time.Second)) {
+			t.Error("the completion should be estimated:", p.EstimatedCompletion)
+		}
+	}
+
+	// 6 events at 0-500ms give a completion at 500ms + 14 * 500ms / 6.
+	expected := clock.Now().Add(-1400*time.Millisecond + 14*500*time.Millisecond/6)
+	if d := progress[0].EstimatedCompletion.Sub(expected); d < -time.Millisecond || d > time.Millisecond {
+		t.Error("the completion should be estimated from the rate:", d)
+	}
+
+	final := progress[3]
+	if !final.Done || final.Err != nil || final.Position != 20 || final.Processed != 20 {
+		t.Error("the final progress should be sent:", final)
+	}
+}
+
+func TestReplayThenSubscribe_ProgressError(t *testing.T) {
+	ctx := context.Background()
+	bus := local.NewEventBus()
+
+	defer bus.Close()
+
+	store, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	save(t, store, uuid.New(), 1)
+
+	handlerErr := errors.New("handler error")
+	ch := make(chan Progress, 1)
+
+	if err := ReplayThenSubscribe(ctx, store, bus, &recordingHandler{err: handlerErr}, eh.MatchAll{},
+		WithProgress(ch, time.Second)); !errors.Is(err, handlerErr) {
+		t.Error("there should be a handler error:", err)
+	}
+
+	final, ok := <-ch
+	if !ok || !final.Done || !errors.Is(final.Err, handlerErr) {
+		t.Error("the final progress should have the error:", final)
+	}
+
+	if _, ok := <-ch; ok {
+		t.Error("the channel should be closed")
+	}
+}
+
+func TestThrottle_Adaptive(t *testing.T) {
+	th := newThrottle(100, 100*time.Millisecond)
+
+	// Slow handling halves the rate.
+	for i := 0; i < 3; i++ {
+		th.handled(200 * time.Millisecond)
+	}
+
+	if th.rate != 12.5 {
+		t.Error("the rate should be lowered:", th.rate)
+	}
+
+	// Fast handling increases the rate again, up to the max rate.
+	th.handled(50 * time.Millisecond)
+
+	if th.rate != 22.5 {
+		t.Error("the rate should be increased:", th.rate)
+	}
+
+	for i := 0; i < 20; i++ {
+		th.handled(0)
+	}
+
+	if th.rate != 100 {
+		t.Error("the rate should be the max rate:", th.rate)
+	}
+
+	// The rate has a lower bound.
+	for i := 0; i < 20; i++ {
+		th.handled(time.Second)
+	}
+
+	if th.rate != minAdaptiveRate {
+		t.Error("the rate should be the min rate:", th.rate)
+	}
+
+	// Not adaptive without a target.
+	th = newThrottle(100, 0)
+	th.handled(time.Second)
+
+	if th.rate != 100 {
+		t.Error("the rate should not be changed:", th.rate)
+	}
+}
+
+func save(t *testing.T, store eh.EventStore, id uuid.UUID, version int) {
+	t.Helper()
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, id, version))
+	if err := store.Save(context.Background(), []eh.Event{event}, version-1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+}
+
+// hookedEventBus calls a hook after adding a handler.
+type hookedEventBus struct {
+	eh.EventBus
+	afterAdd func()
+}
+
+func (b *hookedEventBus) AddHandler(ctx context.Context, m eh.EventMatcher, h eh.EventHandler) error {
+	if err := b.EventBus.AddHandler(ctx, m, h); err != nil {
+		return err
+	}
+
+	if b.afterAdd != nil {
+		b.afterAdd()
+	}
+
+	return nil
+}
+
+// recordingHandler records the versions of handled events.
+type recordingHandler struct {
+	onEvent func(eh.Event)
+	err     error
+
+	mu      sync.Mutex
+	handled []int
+}
+
+func (h *recordingHandler) HandlerType() eh.EventHandlerType {
+	return "recording"
+}
+
+func (h *recordingHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	if h.err != nil {
+		return h.err
+	}
+
+	h.mu.Lock()
+	h.handled = append(h.handled, event.Version())
+	h.mu.Unlock()
+
+	if h.onEvent != nil {
+		h.onEvent(event)
+	}
+
+	return nil
+}
+
+func (h *recordingHandler) versions() []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]int{}, h.handled...)
+}
+
+func (h *recordingHandler) waitFor(n int, d time.Duration) bool {
+	for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+		if len(h.versions()) >= n {
+			return true
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return false
+}
+
+// fakeClock is a clock that advances only when sleeping.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.now = c.now.Add(d)
+
+	return nil
+}
+
+// withClock uses a fake clock for the throttle and progress, must be after the
+// rate limit and progress.
+func withClock(c *fakeClock) Option {
+	return func(r *handler) {
+		r.throttle.now = c.Now
+		r.throttle.sleep = c.sleep
+
+		if r.progress != nil {
+			r.progress.now = c.Now
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"context"
+	"time"
+)
+
+// Option is an option setter used to configure replaying.
+type Option func(*handler)
+
+// WithRateLimit limits the replay of historical events to at most rate events
+// per second, for example to rebuild a projection without overloading the
+// database of the read model. Live events are not limited.
+func WithRateLimit(rate float64) Option {
+	return func(r *handler) {
+		r.throttle = newThrottle(rate, 0)
+	}
+}
+
+// WithAdaptiveRateLimit limits the replay of historical events to at most
+// maxRate events per second, and adapts the rate to the latency of handling the
+// events. The rate is halved each time handling an event takes longer than the
+// target latency, and is increased again by a tenth of maxRate for each event
+// handled within the target, which keeps the latency of the database of the
+// read model close to the target. The rate is never lowered below one event
+// per second. Live events are not limited.
+func WithAdaptiveRateLimit(maxRate float64, target time.Duration) Option {
+	return func(r *handler) {
+		r.throttle = newThrottle(maxRate, target)
+	}
+}
+
+// minAdaptiveRate is the lowest rate used by the adaptive rate limit.
+const minAdaptiveRate = 1.0
+
+// throttle spaces the replayed events to a rate.
+type throttle struct {
+	rate    float64
+	maxRate float64
+	target  time.Duration
+	next    time.Time
+
+	// now and sleep are used for timing, can be replaced in tests.
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newThrottle(rate float64, target time.Duration) *throttle {
+	return &throttle{
+		rate:    rate,
+		maxRate: rate,
+		target:  target,
+		now:     time.Now,
+		sleep:   sleep,
+	}
+}
+
+// wait waits until the next event can be handled, or until the context is
+// done. Time that is not used, for example when the handler is slow, is not
+// saved up for bursts.
+func (t *throttle) wait(ctx context.Context) error {
+	if t.rate <= 0 {
+		return ctx.Err()
+	}
+
+	now := t.now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+
+	if d := t.next.Sub(now); d > 0 {
+		if err := t.sleep(ctx, d); err != nil {
+			return err
+		}
+	} else if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.next = t.next.Add(time.Duration(float64(time.Second) / t.rate))
+
+	return nil
+}
+
+// handled adapts the rate to the latency of handling an event, if an adaptive
+// rate limit is used.
+func (t *throttle) handled(latency time.Duration) {
+	if t.target <= 0 || t.maxRate <= 0 {
+		return
+	}
+
+	if latency > t.target {
+		t.rate /= 2
+		if t.rate < minAdaptiveRate {
+			t.rate = minAdaptiveRate
+		}
+
+		if t.rate > t.maxRate {
+			t.rate = t.maxRate
+		}
+
+		return
+	}
+
+	t.rate += t.maxRate / 10
+	if t.rate > t.maxRate {
+		t.rate = t.maxRate
+	}
+}
+
+// sleep sleeps for a duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
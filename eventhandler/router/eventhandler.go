@@ -0,0 +1,124 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package router contains an event handler that routes events to callbacks by
+// event type, as an alternative to a type switch in HandleEvent.
+package router
+
+import (
+	"context"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// EventHandler routes events to the callback registered for the event type,
+// or to the fallback for unregistered event types. Events without a callback
+// or fallback are ignored.
+//
+//	h := router.NewEventHandler("invitations").
+//		OnEvent(InviteCreatedEvent, handleCreated).
+//		OnEvent(InviteAcceptedEvent, handleAccepted)
+//	bus.AddHandler(ctx, h.Matcher(), h)
+type EventHandler struct {
+	handlerType eh.EventHandlerType
+	callbacks   map[eh.EventType]func(context.Context, eh.Event) error
+	fallback    func(context.Context, eh.Event) error
+	mu          sync.RWMutex
+}
+
+var _ = eh.EventHandler(&EventHandler{})
+
+// NewEventHandler creates a new EventHandler.
+func NewEventHandler(handlerType eh.EventHandlerType) *EventHandler {
+	return &EventHandler{
+		handlerType: handlerType,
+		callbacks:   map[eh.EventType]func(context.Context, eh.Event) error{},
+	}
+}
+
+// OnEvent registers a callback for an event type. It panics if the callback is
+// nil or a callback is already registered for the event type.
+func (h *EventHandler) OnEvent(eventType eh.EventType, f func(context.Context, eh.Event) error) *EventHandler {
+	if f == nil {
+		panic("eventhorizon: missing callback for event type: " + eventType.String())
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.callbacks[eventType]; ok {
+		panic("eventhorizon: callback already registered for event type: " + eventType.String())
+	}
+
+	h.callbacks[eventType] = f
+
+	return h
+}
+
+// OnOtherEvents registers a fallback callback for event types without a
+// registered callback, replacing any previous fallback.
+func (h *EventHandler) OnOtherEvents(f func(context.Context, eh.Event) error) *EventHandler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.fallback = f
+
+	return h
+}
+
+// Matcher returns a matcher for the event types with a registered callback, or
+// a matcher for all events if there is a fallback.
+func (h *EventHandler) Matcher() eh.EventMatcher {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.fallback != nil {
+		return eh.MatchAll{}
+	}
+
+	m := make(eh.MatchEvents, 0, len(h.callbacks))
+	for eventType := range h.callbacks {
+		m = append(m, eventType)
+	}
+
+	return m
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (h *EventHandler) HandlerType() eh.EventHandlerType {
+	return h.handlerType
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (h *EventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	if event == nil {
+		return &eh.EventHandlerError{
+			Err: eh.ErrMissingEvent,
+		}
+	}
+
+	h.mu.RLock()
+	f, ok := h.callbacks[event.EventType()]
+	if !ok {
+		f = h.fallback
+	}
+	h.mu.RUnlock()
+
+	if f == nil {
+		return nil
+	}
+
+	return f(ctx, event)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestEventHandler(t *testing.T) {
+	var handled, other []eh.Event
+
+	h := NewEventHandler("router").
+		OnEvent(mocks.EventType, func(ctx context.Context, event eh.Event) error {
+			handled = append(handled, event)
+
+			return nil
+		}).
+		OnEvent(mocks.EventOtherType, func(ctx context.Context, event eh.Event) error {
+			other = append(other, event)
+
+			return nil
+		})
+
+	if h.HandlerType() != "router" {
+		t.Error("the handler type should be correct:", h.HandlerType())
+	}
+
+	ctx := context.Background()
+	event := newEvent(mocks.EventType)
+	otherEvent := newEvent(mocks.EventOtherType)
+
+	for _, e := range []eh.Event{event, otherEvent} {
+		if err := h.HandleEvent(ctx, e); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	if !reflect.DeepEqual(handled, []eh.Event{event}) {
+		t.Error("the event should be routed:", handled)
+	}
+
+	if !reflect.DeepEqual(other, []eh.Event{otherEvent}) {
+		t.Error("the other event should be routed:", other)
+	}
+
+	// Unregistered event types are ignored without a fallback.
+	if err := h.HandleEvent(ctx, newEvent("unregistered")); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(handled) != 1 || len(other) != 1 {
+		t.Error("the unregistered event should not be routed:", handled, other)
+	}
+
+	m := h.Matcher()
+	if !m.Match(event) || !m.Match(otherEvent) || m.Match(newEvent("unregistered")) {
+		t.Error("the matcher should match the registered event types:", m)
+	}
+
+	if err := h.HandleEvent(ctx, nil); !errors.Is(err, eh.ErrMissingEvent) {
+		t.Error("there should be a missing event error:", err)
+	}
+}
+
+func TestEventHandler_Fallback(t *testing.T) {
+	var handled, fallback []eh.Event
+
+	handlerErr := errors.New("handler error")
+	h := NewEventHandler("router").
+		OnEvent(mocks.EventType, func(ctx context.Context, event eh.Event) error {
+			handled = append(handled, event)
+
+			return handlerErr
+		}).
+		OnOtherEvents(func(ctx context.Context, event eh.Event) error {
+			fallback = append(fallback, event)
+
+			return nil
+		})
+
+	ctx := context.Background()
+	event := newEvent(mocks.EventType)
+	otherEvent := newEvent(mocks.EventOtherType)
+
+	if err := h.HandleEvent(ctx, event); !errors.Is(err, handlerErr) {
+		t.Error("the handler error should be returned:", err)
+	}
+
+	if err := h.HandleEvent(ctx, otherEvent); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(handled, []eh.Event{event}) {
+		t.Error("the event should be routed:", handled)
+	}
+
+	if !reflect.DeepEqual(fallback, []eh.Event{otherEvent}) {
+		t.Error("the unregistered event should be routed to the fallback:", fallback)
+	}
+
+	if _, ok := h.Matcher().(eh.MatchAll); !ok {
+		t.Error("the matcher should match all events:", h.Matcher())
+	}
+}
+
+func TestEventHandler_OnEventPanics(t *testing.T) {
+	h := NewEventHandler("router").
+		OnEvent(mocks.EventType, func(ctx context.Context, event eh.Event) error {
+			return nil
+		})
+
+	for name, f := range map[string]func(context.Context, eh.Event) error{
+		"duplicate": func(ctx context.Context, event eh.Event) error { return nil },
+		"nil":       nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("there should be a panic")
+				}
+			}()
+
+			h.OnEvent(mocks.EventType, f)
+		})
+	}
+}
+
+func newEvent(eventType eh.EventType) eh.Event {
+	return eh.NewEvent(eventType, nil, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrReadRepoNotFound is when a saga queries a read repo that it was not
+// constructed with.
+var ErrReadRepoNotFound = errors.New("read repo not found")
+
+// ReadModels is a set of named read repos, to be embedded in sagas that need to
+// query read models before deciding which commands to issue:
+//
+//	type OrderSaga struct {
+//		*saga.ReadModels
+//	}
+//
+//	func (s *OrderSaga) RunSaga(ctx context.Context, event eh.Event, h eh.CommandHandler) error {
+//		customer, err := s.Find(ctx, "customers", customerID)
+//		...
+//	}
+//
+// Note that read models are eventually consistent: the projection of the event
+// being handled (or of commands just issued) may not have been saved yet when
+// querying. Use a context from version.NewContextWithMinVersionWait() with a
+// version repo to wait for a specific version of an entity if needed.
+type ReadModels struct {
+	repos map[string]eh.ReadRepo
+}
+
+// NewReadModels creates a new ReadModels from read repos by name.
+func NewReadModels(repos map[string]eh.ReadRepo) *ReadModels {
+	r := &ReadModels{
+		repos: map[string]eh.ReadRepo{},
+	}
+
+	for name, repo := range repos {
+		r.repos[name] = repo
+	}
+
+	return r
+}
+
+// ReadRepo returns a read repo by name.
+func (r *ReadModels) ReadRepo(name string) (eh.ReadRepo, error) {
+	repo, ok := r.repos[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrReadRepoNotFound, name)
+	}
+
+	return repo, nil
+}
+
+// Find finds an entity by ID in a read repo. Returns an error that matches
+// eventhorizon.ErrEntityNotFound if the entity does not exist.
+func (r *ReadModels) Find(ctx context.Context, name string, id uuid.UUID) (eh.Entity, error) {
+	repo, err := r.ReadRepo(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.Find(ctx, id)
+}
+
+// FindAll finds all entities in a read repo.
+func (r *ReadModels) FindAll(ctx context.Context, name string) ([]eh.Entity, error) {
+	repo, err := r.ReadRepo(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.FindAll(ctx)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestReadModels(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepo()
+	repo.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	vipID := uuid.New()
+	if err := repo.Save(ctx, &mocks.Model{ID: vipID, Content: "vip"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	commandHandler := &mocks.CommandHandler{}
+	saga := &ReadModelSaga{
+		ReadModels: NewReadModels(map[string]eh.ReadRepo{
+			"customers": repo,
+		}),
+	}
+	h := NewEventHandler(saga, commandHandler)
+
+	// Branch on an existing read model.
+	if err := h.HandleEvent(ctx, newEvent(vipID)); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Branch on a missing read model.
+	otherID := uuid.New()
+	if err := h.HandleEvent(ctx, newEvent(otherID)); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	expected := []eh.Command{
+		&mocks.Command{ID: vipID, Content: "vip discount"},
+		&mocks.Command{ID: otherID, Content: "no discount"},
+	}
+	if !reflect.DeepEqual(commandHandler.Commands, expected) {
+		t.Error("the commands should be correct:", commandHandler.Commands)
+	}
+
+	entities, err := saga.FindAll(ctx, "customers")
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(entities) != 1 {
+		t.Error("there should be one entity:", entities)
+	}
+
+	if _, err := saga.Find(ctx, "missing", vipID); !errors.Is(err, ErrReadRepoNotFound) {
+		t.Error("there should be a read repo not found error:", err)
+	}
+
+	if _, err := saga.FindAll(ctx, "missing"); !errors.Is(err, ErrReadRepoNotFound) {
+		t.Error("there should be a read repo not found error:", err)
+	}
+}
+
+func newEvent(id uuid.UUID) eh.Event {
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 1))
+}
+
+// ReadModelSaga gives a discount to VIP customers.
+type ReadModelSaga struct {
+	*ReadModels
+}
+
+func (s *ReadModelSaga) SagaType() Type {
+	return "ReadModelSaga"
+}
+
+func (s *ReadModelSaga) RunSaga(ctx context.Context, event eh.Event, h eh.CommandHandler) error {
+	content := "no discount"
+
+	entity, err := s.Find(ctx, "customers", event.AggregateID())
+	if err == nil {
+		if m, ok := entity.(*mocks.Model); ok && m.Content == "vip" {
+			content = "vip discount"
+		}
+	} else if !errors.Is(err, eh.ErrEntityNotFound) {
+		return err
+	}
+
+	return h.HandleCommand(ctx, &mocks.Command{ID: event.AggregateID(), Content: content})
+}
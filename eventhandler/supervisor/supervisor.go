@@ -0,0 +1,439 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/backoff"
+)
+
+var (
+	// ErrMissingName is when a projector is added without a name.
+	ErrMissingName = errors.New("missing name")
+	// ErrMissingProjector is when a projector is added without a handler.
+	ErrMissingProjector = errors.New("missing projector")
+	// ErrMissingSubscription is when a projector is added without an event bus
+	// or matcher.
+	ErrMissingSubscription = errors.New("missing subscription")
+	// ErrProjectorAlreadyAdded is when a projector with the same name is added twice.
+	ErrProjectorAlreadyAdded = errors.New("projector already added")
+	// ErrProjectorNotFound is when there is no projector with a name.
+	ErrProjectorNotFound = errors.New("projector not found")
+	// ErrAlreadyRunning is when the supervisor is run twice.
+	ErrAlreadyRunning = errors.New("supervisor already running")
+	// ErrProjectorPanicked is when a projector panicked while handling an event.
+	ErrProjectorPanicked = errors.New("projector panicked")
+	// ErrProjectorStopped is when a projector receives an event while stopped.
+	ErrProjectorStopped = errors.New("projector stopped")
+)
+
+// State is the state of a supervised projector.
+type State int
+
+const (
+	// Stopped is when the projector is not handling events.
+	Stopped State = iota
+	// Running is when the projector is handling events.
+	Running
+	// Restarting is when the projector is waiting to be restarted after a panic.
+	Restarting
+)
+
+// String returns the string representation of a state.
+func (s State) String() string {
+	switch s {
+	case Stopped:
+		return "stopped"
+	case Running:
+		return "running"
+	case Restarting:
+		return "restarting"
+	default:
+		return "unknown"
+	}
+}
+
+// Status is a snapshot of the status of a supervised projector.
+type Status struct {
+	// Name is the name of the projector.
+	Name string
+	// State is the current state of the projector.
+	State State
+	// LastError is the last error (or panic) when handling an event.
+	LastError error
+	// EventsProcessed is the number of successfully handled events.
+	EventsProcessed int
+	// Restarts is the number of restarts after panics.
+	Restarts int
+}
+
+// Supervisor manages the lifecycle of a set of named projectors (or any other
+// event handlers), each with a subscription of an event bus and a matcher.
+//
+// When run, all projectors are added to their event buses. A projector that
+// panics while handling an event is restarted after a backoff, which doubles
+// for each consecutive panic. The event is not retried but the panic is
+// returned as an error to the event bus. A projector can also be stopped and
+// started by name. Events received while it is stopped are not handled and
+// ErrProjectorStopped is returned to the event bus, which redelivers them if
+// it supports redelivery. With other event buses the events are lost and the
+// projection must be rebuilt after starting the projector again.
+type Supervisor struct {
+	projectors map[string]*projector
+	mu         sync.RWMutex
+	running    bool
+	wg         sync.WaitGroup
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// NewSupervisor creates a new Supervisor.
+func NewSupervisor(options ...Option) *Supervisor {
+	s := &Supervisor{
+		projectors: map[string]*projector{},
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(s)
+	}
+
+	return s
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Supervisor)
+
+// WithRestartBackoff sets the backoff used when restarting a projector after a
+// panic, starting with min and doubling for each consecutive panic up to max.
+func WithRestartBackoff(min, max time.Duration) Option {
+	return func(s *Supervisor) {
+		s.minBackoff = min
+		s.maxBackoff = max
+	}
+}
+
+// Add adds a named projector and its subscription, the event bus and matcher
+// used to receive events. Projectors must be added before running.
+func (s *Supervisor) Add(name string, h eh.EventHandler, bus eh.EventBus, m eh.EventMatcher) error {
+	if name == "" {
+		return ErrMissingName
+	}
+
+	if h == nil {
+		return ErrMissingProjector
+	}
+
+	if bus == nil || m == nil {
+		return ErrMissingSubscription
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return ErrAlreadyRunning
+	}
+
+	if _, ok := s.projectors[name]; ok {
+		return ErrProjectorAlreadyAdded
+	}
+
+	s.projectors[name] = &projector{
+		EventHandler: h,
+		name:         name,
+		bus:          bus,
+		matcher:      m,
+		supervisor:   s,
+		wakeup:       make(chan struct{}),
+		restartBackoff: backoff.NewBackoff(
+			backoff.WithBase(s.minBackoff),
+			backoff.WithMax(s.maxBackoff),
+			backoff.WithJitter(0),
+		),
+	}
+
+	return nil
+}
+
+// Run starts all projectors and blocks until the context is cancelled, after
+// which all projectors are stopped and any events being handled are waited for.
+// The supervisor can be run again after Run has returned. Event buses can't
+// remove handlers, so the projectors are only added to their event buses the
+// first time and stay added while stopped between runs.
+func (s *Supervisor) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+
+		return ErrAlreadyRunning
+	}
+
+	s.running = true
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	names := make([]string, 0, len(s.projectors))
+	for name := range s.projectors {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	s.mu.Unlock()
+
+	for _, name := range names {
+		p := s.projectors[name]
+		p.setState(Running)
+
+		if p.added {
+			continue
+		}
+
+		if err := p.bus.AddHandler(ctx, p.matcher, p); err != nil {
+			s.stopAll()
+
+			return fmt.Errorf("could not add projector '%s': %w", name, err)
+		}
+
+		p.added = true
+	}
+
+	<-ctx.Done()
+
+	s.stopAll()
+
+	return nil
+}
+
+// Start starts a stopped projector by name.
+func (s *Supervisor) Start(name string) error {
+	p, err := s.projector(name)
+	if err != nil {
+		return err
+	}
+
+	p.setState(Running)
+
+	return nil
+}
+
+// Stop stops a projector by name, any events received while stopped are
+// returned to the event bus with ErrProjectorStopped.
+func (s *Supervisor) Stop(name string) error {
+	p, err := s.projector(name)
+	if err != nil {
+		return err
+	}
+
+	p.setState(Stopped)
+
+	return nil
+}
+
+// Status returns a snapshot of the status of all projectors, sorted by name.
+func (s *Supervisor) Status() []Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := make([]Status, 0, len(s.projectors))
+	for _, p := range s.projectors {
+		status = append(status, p.status())
+	}
+
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Name < status[j].Name
+	})
+
+	return status
+}
+
+func (s *Supervisor) projector(name string) (*projector, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.projectors[name]
+	if !ok {
+		return nil, ErrProjectorNotFound
+	}
+
+	return p, nil
+}
+
+// stopAll stops all projectors and waits for events being handled.
+func (s *Supervisor) stopAll() {
+	s.mu.RLock()
+	for _, p := range s.projectors {
+		p.setState(Stopped)
+	}
+	s.mu.RUnlock()
+
+	s.wg.Wait()
+}
+
+// projector is a supervised event handler, added to the event bus in place
+// of the projector.
+type projector struct {
+	eh.EventHandler
+
+	name       string
+	bus        eh.EventBus
+	matcher    eh.EventMatcher
+	supervisor *Supervisor
+	added      bool
+
+	mu             sync.Mutex
+	state          State
+	lastErr        error
+	processed      int
+	restarts       int
+	restartBackoff *backoff.Backoff
+	restartAt      time.Time
+	wakeup         chan struct{}
+}
+
+// InnerHandler implements EventHandlerChain
+func (p *projector) InnerHandler() eh.EventHandler {
+	return p.EventHandler
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (p *projector) HandleEvent(ctx context.Context, event eh.Event) error {
+	if err := p.waitForRunning(ctx); err != nil {
+		return err
+	}
+	defer p.supervisor.wg.Done()
+
+	err := p.handle(ctx, event)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case errors.Is(err, ErrProjectorPanicked):
+		p.lastErr = err
+		p.restarts++
+
+		if p.state == Running {
+			p.state = Restarting
+			p.restartAt = time.Now().Add(p.restartBackoff.Next())
+		}
+	case err != nil:
+		p.lastErr = err
+	default:
+		p.processed++
+		p.restartBackoff.Reset()
+	}
+
+	return err
+}
+
+// handle handles an event, recovering from any panic.
+func (p *projector) handle(ctx context.Context, event eh.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %s: %v", ErrProjectorPanicked, p.name, r)
+		}
+	}()
+
+	return p.EventHandler.HandleEvent(ctx, event)
+}
+
+// waitForRunning waits for a restarting projector to be restarted, returns
+// ErrProjectorStopped if the projector is stopped or the error of the context
+// if it is done. When returning nil the handling is added to the wait group of
+// the supervisor, while the state is locked to not race with stopping.
+func (p *projector) waitForRunning(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		state := p.state
+		wait := time.Until(p.restartAt)
+		wakeup := p.wakeup
+
+		if state == Restarting && wait <= 0 {
+			p.state = Running
+			state = Running
+		}
+
+		if state == Running {
+			p.supervisor.wg.Add(1)
+		}
+		p.mu.Unlock()
+
+		switch state {
+		case Running:
+			return nil
+		case Stopped:
+			return fmt.Errorf("%w: %s", ErrProjectorStopped, p.name)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-wakeup:
+			t.Stop()
+		case <-ctx.Done():
+			t.Stop()
+
+			return ctx.Err()
+		}
+	}
+}
+
+// setState sets the state and wakes up any handling waiting for a restart.
+func (p *projector) setState(state State) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state = state
+	p.restartBackoff.Reset()
+
+	close(p.wakeup)
+	p.wakeup = make(chan struct{})
+}
+
+func (p *projector) status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.state
+	if state == Restarting && !time.Now().Before(p.restartAt) {
+		state = Running
+	}
+
+	return Status{
+		Name:            p.name,
+		State:           state,
+		LastError:       p.lastErr,
+		EventsProcessed: p.processed,
+		Restarts:        p.restarts,
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventbus/local"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestSupervisor(t *testing.T) {
+	bus := &addedEventBus{EventBus: local.NewEventBus()}
+	bus.added.Add(2)
+
+	s := NewSupervisor(WithRestartBackoff(50*time.Millisecond, time.Second))
+
+	panicking := &panickingHandler{
+		EventHandler: mocks.NewEventHandler("panicking"),
+		panics:       1,
+	}
+	if err := s.Add("panicking", panicking, bus, eh.MatchAll{}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	other := mocks.NewEventHandler("other")
+	if err := s.Add("other", other, bus, eh.MatchAll{}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := s.Add("other", other, bus, eh.MatchAll{}); !errors.Is(err, ErrProjectorAlreadyAdded) {
+		t.Error("there should be a projector already added error:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	bus.added.Wait()
+
+	// The first event panics in one of the projectors.
+	if err := bus.HandleEvent(ctx, newEvent()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !other.Wait(time.Second) {
+		t.Error("the other projector should handle the event")
+	}
+
+	select {
+	case err := <-bus.Errors():
+		// NOTE: The local event bus does not wrap the handler errors.
+		if !strings.Contains(err.Error(), ErrProjectorPanicked.Error()) {
+			t.Error("there should be a panic error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("there should be a panic error")
+	}
+
+	waitForProcessed(s, "other", 1)
+
+	status := statusByName(s)
+	if status["panicking"].Restarts != 1 || !errors.Is(status["panicking"].LastError, ErrProjectorPanicked) {
+		t.Error("the panicking projector should be restarted:", status["panicking"])
+	}
+
+	if status["other"].State != Running || status["other"].EventsProcessed != 1 {
+		t.Error("the other projector should be running:", status["other"])
+	}
+
+	// The panicking projector is restarted after the backoff.
+	if err := bus.HandleEvent(ctx, newEvent()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !panicking.Wait(time.Second) {
+		t.Error("the restarted projector should handle the event")
+	}
+
+	if !other.Wait(time.Second) {
+		t.Error("the other projector should handle the event")
+	}
+
+	waitForProcessed(s, "panicking", 1)
+	waitForProcessed(s, "other", 2)
+
+	status = statusByName(s)
+	if status["panicking"].State != Running || status["panicking"].EventsProcessed != 1 {
+		t.Error("the restarted projector should be running:", status["panicking"])
+	}
+
+	if status["other"].EventsProcessed != 2 {
+		t.Error("the other projector should have handled both events:", status["other"])
+	}
+
+	// Stopped projectors return events to the bus.
+	if err := s.Stop("other"); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := bus.HandleEvent(ctx, newEvent()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !panicking.Wait(time.Second) {
+		t.Error("the restarted projector should handle the event")
+	}
+
+	if other.Wait(50 * time.Millisecond) {
+		t.Error("the stopped projector should not handle the event")
+	}
+
+	select {
+	case err := <-bus.Errors():
+		if !strings.Contains(err.Error(), ErrProjectorStopped.Error()) {
+			t.Error("there should be a projector stopped error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("there should be a projector stopped error")
+	}
+
+	if err := s.Start("other"); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := bus.HandleEvent(ctx, newEvent()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !other.Wait(time.Second) {
+		t.Error("the started projector should handle the event")
+	}
+
+	if err := s.Stop("missing"); !errors.Is(err, ErrProjectorNotFound) {
+		t.Error("there should be a projector not found error:", err)
+	}
+
+	// Shut down.
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the supervisor should shut down")
+	}
+
+	for name, st := range statusByName(s) {
+		if st.State != Stopped {
+			t.Error("the projector should be stopped:", name, st.State)
+		}
+	}
+
+	if err := bus.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestSupervisor_RestartBackoff(t *testing.T) {
+	s := NewSupervisor(WithRestartBackoff(10*time.Millisecond, 50*time.Millisecond))
+	h := &panickingHandler{
+		EventHandler: mocks.NewEventHandler("panicking"),
+		panics:       3,
+	}
+
+	if err := s.Add("panicking", h, &mocks.EventBus{}, eh.MatchAll{}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.Run(ctx)
+
+	p, err := s.projector("panicking")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Wait for the projector to be running.
+	for i := 0; i < 100 && statusByName(s)["panicking"].State != Running; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	expected := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+	}
+
+	for i, d := range expected {
+		start := time.Now()
+
+		if err := p.HandleEvent(ctx, newEvent()); !errors.Is(err, ErrProjectorPanicked) {
+			t.Error("there should be a panic error:", err)
+		}
+
+		if i > 0 {
+			if elapsed := time.Since(start); elapsed < expected[i-1] {
+				t.Error("the restart should wait for the backoff:", elapsed)
+			}
+		}
+
+		p.mu.Lock()
+		if backoff := p.restartAt.Sub(time.Now()); backoff > d || backoff < d-10*time.Millisecond {
+			t.Error("the backoff should be correct:", backoff, d)
+		}
+		p.mu.Unlock()
+	}
+
+	// The backoff is reset after a successful handling.
+	if err := p.HandleEvent(ctx, newEvent()); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	p.mu.Lock()
+	if p.restartBackoff.Attempt() != 0 {
+		t.Error("the backoff should be reset:", p.restartBackoff.Attempt())
+	}
+	p.mu.Unlock()
+}
+
+func TestSupervisor_RunAgain(t *testing.T) {
+	bus := &addedEventBus{EventBus: local.NewEventBus()}
+	bus.added.Add(1)
+
+	s := NewSupervisor()
+	h := mocks.NewEventHandler("handler")
+
+	if err := s.Add("handler", h, bus, eh.MatchAll{}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	bus.added.Wait()
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Events are returned to the bus between runs.
+	if err := bus.HandleEvent(context.Background(), newEvent()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	select {
+	case err := <-bus.Errors():
+		if !strings.Contains(err.Error(), ErrProjectorStopped.Error()) {
+			t.Error("there should be a projector stopped error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("there should be a projector stopped error")
+	}
+
+	// The second run does not add the projector to the bus again.
+	ctx, cancel = context.WithCancel(context.Background())
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	for i := 0; i < 100 && statusByName(s)["handler"].State != Running; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := bus.HandleEvent(ctx, newEvent()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !h.Wait(time.Second) {
+		t.Error("the projector should handle the event")
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := bus.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestSupervisor_AddErrors(t *testing.T) {
+	s := NewSupervisor()
+	h := mocks.NewEventHandler("handler")
+	bus := &mocks.EventBus{}
+
+	if err := s.Add("", h, bus, eh.MatchAll{}); !errors.Is(err, ErrMissingName) {
+		t.Error("there should be a missing name error:", err)
+	}
+
+	if err := s.Add("name", nil, bus, eh.MatchAll{}); !errors.Is(err, ErrMissingProjector) {
+		t.Error("there should be a missing projector error:", err)
+	}
+
+	if err := s.Add("name", h, nil, eh.MatchAll{}); !errors.Is(err, ErrMissingSubscription) {
+		t.Error("there should be a missing subscription error:", err)
+	}
+
+	if err := s.Add("name", h, bus, nil); !errors.Is(err, ErrMissingSubscription) {
+		t.Error("there should be a missing subscription error:", err)
+	}
+}
+
+func newEvent() eh.Event {
+	return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+}
+
+func statusByName(s *Supervisor) map[string]Status {
+	status := map[string]Status{}
+	for _, st := range s.Status() {
+		status[st.Name] = st
+	}
+
+	return status
+}
+
+// waitForProcessed waits for a projector to have processed a number of events,
+// which is counted after the handler has been called.
+func waitForProcessed(s *Supervisor, name string, n int) {
+	for i := 0; i < 100 && statusByName(s)[name].EventsProcessed < n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// panickingHandler panics for the first events.
+type panickingHandler struct {
+	*mocks.EventHandler
+
+	mu     sync.Mutex
+	panics int
+}
+
+func (h *panickingHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	h.mu.Lock()
+	if h.panics > 0 {
+		h.panics--
+		h.mu.Unlock()
+
+		panic("projector error")
+	}
+	h.mu.Unlock()
+
+	return h.EventHandler.HandleEvent(ctx, event)
+}
+
+// addedEventBus signals when handlers are added.
+type addedEventBus struct {
+	eh.EventBus
+	added sync.WaitGroup
+}
+
+func (b *addedEventBus) AddHandler(ctx context.Context, m eh.EventMatcher, h eh.EventHandler) error {
+	defer b.added.Done()
+
+	return b.EventBus.AddHandler(ctx, m, h)
+}
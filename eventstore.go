@@ -38,6 +38,153 @@ type EventStore interface {
 	Close() error
 }
 
+// EventStoreBatchLoader is an event store that can load the events of an
+// aggregate in batches, to bound the memory used when loading aggregates with
+// many events.
+type EventStoreBatchLoader interface {
+	// LoadInBatches calls f with the events from version for the aggregate id,
+	// in version order and in batches of at most batchSize events. Loading stops
+	// at the first error from f. Returns ErrAggregateNotFound if the aggregate
+	// has no events.
+	LoadInBatches(ctx context.Context, id uuid.UUID, version, batchSize int, f func(ctx context.Context, events []Event) error) error
+}
+
+// EventStoreReverseLoader is an event store that can load the most recent
+// events of an aggregate, for example to show the recent activity of an
+// aggregate or to find its latest event without loading all events.
+type EventStoreReverseLoader interface {
+	// LoadReverse loads the most recent events for the aggregate id, newest
+	// first and at most limit events, or all events if limit is below 1. Returns
+	// ErrAggregateNotFound if the aggregate has no events.
+	LoadReverse(ctx context.Context, id uuid.UUID, limit int) ([]Event, error)
+}
+
+// EventStoreStreamer is an event store that can stream all events of all
+// aggregates in a global order, for example to migrate events to another store.
+type EventStoreStreamer interface {
+	// StreamAll calls f with all events in global order, starting after the
+	// position from (use 0 to stream all events). The position of each event can
+	// be used to resume streaming. Streaming stops at the first error from f.
+	StreamAll(ctx context.Context, from int, f func(ctx context.Context, position int, event Event) error) error
+}
+
+// EventStoreTimeStreamer is an event store that can stream all events of all
+// aggregates ordered by timestamp, for example to replay events in the order
+// they happened for a timeline across aggregates.
+type EventStoreTimeStreamer interface {
+	// StreamAllByTime calls f with all events ordered by timestamp, with ties
+	// broken by aggregate ID and version. Streaming stops at the first error
+	// from f.
+	StreamAllByTime(ctx context.Context, f func(ctx context.Context, event Event) error) error
+}
+
+// EventStoreMetadataFinder is an event store that can find events of all
+// aggregates by a metadata value, for example a correlation ID.
+type EventStoreMetadataFinder interface {
+	// FindByMetadata finds all events with a metadata key set to value, in
+	// global order. Returns an empty list if no events are found.
+	FindByMetadata(ctx context.Context, key string, value interface{}) ([]Event, error)
+}
+
+// EventStoreFollower is an event store that can push the events of a single
+// aggregate as they are saved, for example for a live view of the aggregate,
+// without subscribing to all events on an event bus.
+type EventStoreFollower interface {
+	// Follow returns a channel with the events of the aggregate that are saved
+	// after the call. The channel is closed when the context is done or if
+	// following fails.
+	Follow(ctx context.Context, id uuid.UUID) (<-chan Event, error)
+}
+
+// EventStoreAggregateLister is an event store that can list the IDs of all
+// aggregates of a type, for example to rebuild projections per aggregate.
+type EventStoreAggregateLister interface {
+	// AggregateIDs returns the IDs of all aggregates of a type. Returns an empty
+	// list if there are no aggregates of the type.
+	AggregateIDs(ctx context.Context, aggregateType AggregateType) ([]uuid.UUID, error)
+
+	// StreamAggregateIDs calls f with the ID of each aggregate of a type, without
+	// keeping all IDs in memory. Streaming stops at the first error from f.
+	StreamAggregateIDs(ctx context.Context, aggregateType AggregateType, f func(ctx context.Context, id uuid.UUID) error) error
+}
+
+// EventStoreTransactor is an event store that can run operations in a
+// transaction, for example to save events and update a read model that share
+// the same database atomically.
+type EventStoreTransactor interface {
+	// WithTransaction calls f in a transaction, which is committed if f returns
+	// nil and otherwise rolled back. All operations should use the context
+	// passed to f to be part of the transaction. Note that f can be called
+	// multiple times if the transaction is retried.
+	WithTransaction(ctx context.Context, f func(context.Context) error) error
+}
+
+// ErrTransactionsNotSupported is when an event store can not run operations in
+// a transaction.
+var ErrTransactionsNotSupported = errors.New("transactions not supported")
+
+// EventStoreBatchSaver is an event store that can save the events of many
+// aggregates in a batch, with fewer round trips than saving each aggregate, for
+// example when importing events.
+type EventStoreBatchSaver interface {
+	// SaveBatch saves the events of each aggregate in the batch. The returned
+	// errors are the results of each aggregate, in the same order as the batch
+	// and nil for saved aggregates. By default an aggregate that can not be
+	// saved, for example because of a version conflict, does not stop the other
+	// aggregates from being saved, see WithAtomicBatch. The error is for failing
+	// to save the batch as a whole.
+	SaveBatch(ctx context.Context, batch []EventBatch, options ...BatchSaveOption) ([]error, error)
+}
+
+// EventBatch is the events of an aggregate to save in a batch, see Save for
+// the events and original version.
+type EventBatch struct {
+	Events          []Event
+	OriginalVersion int
+}
+
+// BatchSaveOption is an option setter used to configure saving of batches.
+type BatchSaveOption func(*BatchSaveConfig)
+
+// BatchSaveConfig is the configuration of saving a batch, used by event stores.
+type BatchSaveConfig struct {
+	// Atomic is true if either all or no aggregates should be saved.
+	Atomic bool
+}
+
+// NewBatchSaveConfig creates a BatchSaveConfig from the options.
+func NewBatchSaveConfig(options ...BatchSaveOption) BatchSaveConfig {
+	var c BatchSaveConfig
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(&c)
+	}
+
+	return c
+}
+
+// WithAtomicBatch saves either all or none of the aggregates of a batch. If
+// any aggregate can not be saved, ErrBatchAborted is returned and the results
+// of the other aggregates are ErrBatchAborted.
+func WithAtomicBatch() BatchSaveOption {
+	return func(c *BatchSaveConfig) {
+		c.Atomic = true
+	}
+}
+
+var (
+	// ErrBatchAborted is when an atomic batch is not saved because one of its
+	// aggregates could not be saved.
+	ErrBatchAborted = errors.New("batch aborted")
+	// ErrAggregateAlreadyInBatch is when the events of an aggregate are in a
+	// batch more than once.
+	ErrAggregateAlreadyInBatch = errors.New("aggregate already in batch")
+)
+
 // SnapshotStore is an interface for snapshot store.
 type SnapshotStore interface {
 	LoadSnapshot(ctx context.Context, id uuid.UUID) (*Snapshot, error)
@@ -0,0 +1,279 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// BatchSaveAcceptanceTest is the acceptance test that all implementations of
+// EventStoreBatchSaver should pass. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStoreBatchSaver(t *testing.T) {
+//		store := NewEventStore()
+//		eventstore.BatchSaveAcceptanceTest(t, store, context.Background())
+//	}
+func BatchSaveAcceptanceTest(t *testing.T, store interface {
+	eh.EventStore
+	eh.EventStoreBatchSaver
+}, ctx context.Context) {
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvents := func(id uuid.UUID, from, to int) []eh.Event {
+		var events []eh.Event
+		for v := from; v <= to; v++ {
+			events = append(events, eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, v)))
+		}
+
+		return events
+	}
+
+	checkVersion := func(id uuid.UUID, version int) {
+		t.Helper()
+
+		events, err := store.Load(ctx, id)
+		if version == 0 {
+			if !errors.Is(err, eh.ErrAggregateNotFound) {
+				t.Error("there should be an aggregate not found error:", err)
+			}
+
+			return
+		}
+
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if len(events) != version || events[len(events)-1].Version() != version {
+			t.Errorf("the aggregate should be at version %d: %v", version, events)
+		}
+	}
+
+	// Save new aggregates.
+	id1, id2 := uuid.New(), uuid.New()
+
+	results, err := store.SaveBatch(ctx, []eh.EventBatch{
+		{Events: newEvents(id1, 1, 2), OriginalVersion: 0},
+		{Events: newEvents(id2, 1, 1), OriginalVersion: 0},
+	})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(results) != 2 || results[0] != nil || results[1] != nil {
+		t.Error("all aggregates should be saved:", results)
+	}
+
+	checkVersion(id1, 2)
+	checkVersion(id2, 1)
+
+	// Mixed batch, failing aggregates should not fail the others.
+	id3, id4 := uuid.New(), uuid.New()
+
+	results, err = store.SaveBatch(ctx, []eh.EventBatch{
+		{Events: newEvents(id1, 3, 3), OriginalVersion: 2},
+		{Events: newEvents(id2, 1, 1), OriginalVersion: 0},
+		{Events: newEvents(id3, 2, 2), OriginalVersion: 0},
+		{Events: newEvents(id4, 1, 3), OriginalVersion: 0},
+		{Events: newEvents(id4, 4, 4), OriginalVersion: 3},
+		{Events: nil},
+	})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(results) != 6 {
+		t.Fatal("there should be a result for each aggregate:", results)
+	}
+
+	if results[0] != nil || results[3] != nil {
+		t.Error("the aggregates should be saved:", results)
+	}
+
+	eventStoreErr := &eh.EventStoreError{}
+	if !errors.As(results[1], &eventStoreErr) || !errors.Is(results[1], eh.ErrEventConflictFromOtherSave) {
+		t.Error("there should be a conflict error:", results[1])
+	}
+
+	if !errors.Is(results[2], eh.ErrIncorrectEventVersion) {
+		t.Error("there should be an incorrect version error:", results[2])
+	}
+
+	if !errors.Is(results[4], eh.ErrAggregateAlreadyInBatch) {
+		t.Error("there should be an already in batch error:", results[4])
+	}
+
+	if !errors.Is(results[5], eh.ErrMissingEvents) {
+		t.Error("there should be a missing events error:", results[5])
+	}
+
+	checkVersion(id1, 3)
+	checkVersion(id2, 1)
+	checkVersion(id3, 0)
+	checkVersion(id4, 3)
+
+	// Atomic batch, a failing aggregate aborts the batch.
+	id5 := uuid.New()
+
+	results, err = store.SaveBatch(ctx, []eh.EventBatch{
+		{Events: newEvents(id5, 1, 1), OriginalVersion: 0},
+		{Events: newEvents(id1, 3, 3), OriginalVersion: 2},
+	}, eh.WithAtomicBatch())
+	if !errors.Is(err, eh.ErrBatchAborted) {
+		t.Error("there should be a batch aborted error:", err)
+	}
+
+	if len(results) != 2 || !errors.Is(results[0], eh.ErrBatchAborted) ||
+		!errors.Is(results[1], eh.ErrEventConflictFromOtherSave) {
+		t.Error("the results should be correct:", results)
+	}
+
+	checkVersion(id5, 0)
+	checkVersion(id1, 3)
+
+	// Atomic batch without errors.
+	results, err = store.SaveBatch(ctx, []eh.EventBatch{
+		{Events: newEvents(id5, 1, 1), OriginalVersion: 0},
+		{Events: newEvents(id1, 4, 4), OriginalVersion: 3},
+	}, eh.WithAtomicBatch())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(results) != 2 || results[0] != nil || results[1] != nil {
+		t.Error("all aggregates should be saved:", results)
+	}
+
+	checkVersion(id5, 1)
+	checkVersion(id1, 4)
+}
+
+// BatchSaveBenchmark benchmarks saving new aggregates in batches of a size.
+func BatchSaveBenchmark(b *testing.B, store eh.EventStoreBatchSaver, size int) {
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		batch := make([]eh.EventBatch, size)
+		for i := range batch {
+			batch[i] = eh.EventBatch{
+				Events: []eh.Event{eh.NewEvent(mocks.EventType,
+					&mocks.EventData{Content: "event1"}, time.Now(),
+					eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))},
+			}
+		}
+
+		results, err := store.SaveBatch(ctx, batch)
+		if err != nil {
+			b.Fatal("could not save batch:", err)
+		}
+
+		for _, err := range results {
+			if err != nil {
+				b.Error("could not save aggregate:", err)
+			}
+		}
+	}
+}
+
+// BatchLoadAcceptanceTest is the acceptance test that all implementations of
+// EventStoreBatchLoader should pass. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStoreBatchLoader(t *testing.T) {
+//		store := NewEventStore()
+//		eventstore.BatchLoadAcceptanceTest(t, store, context.Background())
+//	}
+func BatchLoadAcceptanceTest(t *testing.T, store interface {
+	eh.EventStore
+	eh.EventStoreBatchLoader
+}, ctx context.Context) {
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	var events []eh.Event
+	for v := 1; v <= 10; v++ {
+		events = append(events, eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, v)))
+	}
+
+	if err := store.Save(ctx, events, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Load from a version in batches.
+	var (
+		loaded []eh.Event
+		sizes  []int
+	)
+
+	if err := store.LoadInBatches(ctx, id, 3, 3, func(ctx context.Context, batch []eh.Event) error {
+		loaded = append(loaded, batch...)
+		sizes = append(sizes, len(batch))
+
+		return nil
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 2 {
+		t.Error("the batches should be correct:", sizes)
+	}
+
+	expected := events[2:]
+	if len(loaded) != len(expected) {
+		t.Fatal("the events should be loaded:", loaded)
+	}
+
+	for i, event := range loaded {
+		if err := eh.CompareEvents(event, expected[i], eh.IgnorePositionMetadata()); err != nil {
+			t.Error("the event should be correct:", i, err)
+		}
+	}
+
+	// Stop loading on errors.
+	loadErr := errors.New("load error")
+	count := 0
+
+	if err := store.LoadInBatches(ctx, id, 1, 3, func(ctx context.Context, batch []eh.Event) error {
+		count++
+
+		return loadErr
+	}); !errors.Is(err, loadErr) {
+		t.Error("there should be a load error:", err)
+	}
+
+	if count != 1 {
+		t.Error("the loading should stop on the first error:", count)
+	}
+
+	// Missing aggregates.
+	if err := store.LoadInBatches(ctx, uuid.New(), 1, 3, func(ctx context.Context, batch []eh.Event) error {
+		t.Error("there should be no batches:", batch)
+
+		return nil
+	}); !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("there should be a aggregate not found error:", err)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// AggregateListerAcceptanceTest is the acceptance test that all implementations
+// of EventStoreAggregateLister should pass. It should manually be called from a
+// test case in each implementation:
+//
+//	func TestEventStoreAggregateLister(t *testing.T) {
+//		store := NewEventStore()
+//		eventstore.AggregateListerAcceptanceTest(t, store, context.Background())
+//	}
+func AggregateListerAcceptanceTest(t *testing.T, store interface {
+	eh.EventStore
+	eh.EventStoreAggregateLister
+}, ctx context.Context) {
+	// Use unique aggregate types, the store may already have events.
+	aggregateType := eh.AggregateType("Lister" + uuid.New().String())
+	otherType := eh.AggregateType("ListerOther" + uuid.New().String())
+
+	// No aggregates of the type.
+	ids, err := store.AggregateIDs(ctx, aggregateType)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(ids) != 0 {
+		t.Error("there should be no IDs:", ids)
+	}
+
+	// Save two aggregates with multiple events, and one of another type.
+	id1, id2, id3 := uuid.New(), uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvent := func(at eh.AggregateType, id uuid.UUID, version int) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(at, id, version))
+	}
+
+	if err := store.Save(ctx, []eh.Event{
+		newEvent(aggregateType, id1, 1),
+		newEvent(aggregateType, id1, 2),
+	}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(otherType, id3, 1)}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(aggregateType, id2, 1)}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(aggregateType, id1, 3)}, 2); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := []uuid.UUID{id1, id2}
+	sortIDs(expected)
+
+	ids, err = store.AggregateIDs(ctx, aggregateType)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	sortIDs(ids)
+
+	if !equalIDs(ids, expected) {
+		t.Error("the IDs should be correct:", ids)
+	}
+
+	ids, err = store.AggregateIDs(ctx, otherType)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !equalIDs(ids, []uuid.UUID{id3}) {
+		t.Error("the IDs of the other type should be correct:", ids)
+	}
+
+	// Stream the IDs.
+	ids = nil
+
+	if err := store.StreamAggregateIDs(ctx, aggregateType, func(ctx context.Context, id uuid.UUID) error {
+		ids = append(ids, id)
+
+		return nil
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	sortIDs(ids)
+
+	if !equalIDs(ids, expected) {
+		t.Error("the streamed IDs should be correct:", ids)
+	}
+
+	// Stop streaming on errors.
+	streamErr := errors.New("stream error")
+	calls := 0
+
+	if err := store.StreamAggregateIDs(ctx, aggregateType, func(ctx context.Context, id uuid.UUID) error {
+		calls++
+
+		return streamErr
+	}); !errors.Is(err, streamErr) {
+		t.Error("there should be a stream error:", err)
+	}
+
+	if calls != 1 {
+		t.Error("the streaming should stop at the first error:", calls)
+	}
+}
+
+func sortIDs(ids []uuid.UUID) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+}
+
+func equalIDs(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -15,8 +15,11 @@
 package memory
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"sync"
 
 	"github.com/jinzhu/copier"
@@ -29,6 +32,7 @@ import (
 // memory and not persisted. Useful for testing and experimenting.
 type EventStore struct {
 	db           map[uuid.UUID]aggregateRecord
+	all          []eventRef
 	dbMu         sync.RWMutex
 	eventHandler eh.EventHandler
 }
@@ -162,6 +166,7 @@ func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersio
 		}
 
 		s.db[id] = aggregate
+		s.appendRefs(dbEvents)
 	} else {
 		// Increment aggregate version on insert of new event record, and
 		// only insert if version of aggregate is matching (ie not changed
@@ -182,6 +187,7 @@ func (s *EventStore) save(ctx context.Context, events []eh.Event, originalVersio
 			aggregate.Events = append(aggregate.Events, dbEvents...)
 
 			s.db[id] = aggregate
+			s.appendRefs(dbEvents)
 		}
 	}
 
@@ -207,9 +213,9 @@ func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([
 		}
 	}
 
-	events := make([]eh.Event, len(aggregate.Events))
+	events := make([]eh.Event, 0, len(aggregate.Events))
 
-	for i, event := range aggregate.Events {
+	for _, event := range aggregate.Events {
 		if event.Version() < version {
 			continue
 		}
@@ -226,12 +232,301 @@ func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([
 			}
 		}
 
-		events[i] = e
+		events = append(events, e)
 	}
 
 	return events, nil
 }
 
+// LoadReverse implements the LoadReverse method of the
+// eventhorizon.EventStoreReverseLoader interface.
+func (s *EventStore) LoadReverse(ctx context.Context, id uuid.UUID, limit int) ([]eh.Event, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+
+	aggregate, ok := s.db[id]
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err:         eh.ErrAggregateNotFound,
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	if limit < 1 || limit > len(aggregate.Events) {
+		limit = len(aggregate.Events)
+	}
+
+	events := make([]eh.Event, 0, limit)
+
+	for i := len(aggregate.Events) - 1; i >= len(aggregate.Events)-limit; i-- {
+		e, err := copyEvent(ctx, aggregate.Events[i])
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not copy event: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    aggregate.Events[i].AggregateType(),
+				AggregateID:      id,
+				AggregateVersion: aggregate.Events[i].Version(),
+				Events:           events,
+			}
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// LoadInBatches implements the LoadInBatches method of the
+// eventhorizon.EventStoreBatchLoader interface. The events of each batch are
+// copied when the batch is loaded.
+func (s *EventStore) LoadInBatches(ctx context.Context, id uuid.UUID, version, batchSize int, f func(ctx context.Context, events []eh.Event) error) error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	s.dbMu.RLock()
+	aggregate, ok := s.db[id]
+
+	var stored []eh.Event
+	if ok {
+		for _, event := range aggregate.Events {
+			if event.Version() >= version {
+				stored = append(stored, event)
+			}
+		}
+	}
+	s.dbMu.RUnlock()
+
+	if !ok {
+		return &eh.EventStoreError{
+			Err:         eh.ErrAggregateNotFound,
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	for len(stored) > 0 {
+		n := batchSize
+		if n > len(stored) {
+			n = len(stored)
+		}
+
+		batch := make([]eh.Event, n)
+
+		for i, event := range stored[:n] {
+			e, err := copyEvent(ctx, event)
+			if err != nil {
+				return &eh.EventStoreError{
+					Err:              fmt.Errorf("could not copy event: %w", err),
+					Op:               eh.EventStoreOpLoad,
+					AggregateType:    event.AggregateType(),
+					AggregateID:      id,
+					AggregateVersion: event.Version(),
+				}
+			}
+
+			batch[i] = e
+		}
+
+		if err := f(ctx, batch); err != nil {
+			return err
+		}
+
+		stored = stored[n:]
+	}
+
+	return nil
+}
+
+// StreamAll implements the StreamAll method of the eventhorizon.EventStoreStreamer
+// interface. Events are streamed in the order they were saved.
+func (s *EventStore) StreamAll(ctx context.Context, from int, f func(ctx context.Context, position int, event eh.Event) error) error {
+	if from < 0 {
+		from = 0
+	}
+
+	// Copy the events to not hold the lock while calling f.
+	s.dbMu.RLock()
+
+	var events []eh.Event
+
+	if from < len(s.all) {
+		events = make([]eh.Event, 0, len(s.all)-from)
+
+		for _, ref := range s.all[from:] {
+			event := s.db[ref.aggregateID].Events[ref.version-1]
+
+			e, err := copyEvent(ctx, event)
+			if err != nil {
+				s.dbMu.RUnlock()
+
+				return &eh.EventStoreError{
+					Err:              fmt.Errorf("could not copy event: %w", err),
+					Op:               eh.EventStoreOpLoad,
+					AggregateType:    event.AggregateType(),
+					AggregateID:      ref.aggregateID,
+					AggregateVersion: ref.version,
+				}
+			}
+
+			events = append(events, e)
+		}
+	}
+	s.dbMu.RUnlock()
+
+	for i, e := range events {
+		if err := f(ctx, from+i+1, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StreamAllByTime implements the StreamAllByTime method of the
+// eventhorizon.EventStoreTimeStreamer interface, by sorting all events.
+func (s *EventStore) StreamAllByTime(ctx context.Context, f func(ctx context.Context, event eh.Event) error) error {
+	// Copy the events to not hold the lock while calling f.
+	s.dbMu.RLock()
+
+	events := make([]eh.Event, 0, len(s.all))
+
+	for _, ref := range s.all {
+		event := s.db[ref.aggregateID].Events[ref.version-1]
+
+		e, err := copyEvent(ctx, event)
+		if err != nil {
+			s.dbMu.RUnlock()
+
+			return &eh.EventStoreError{
+				Err:              fmt.Errorf("could not copy event: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      ref.aggregateID,
+				AggregateVersion: ref.version,
+			}
+		}
+
+		events = append(events, e)
+	}
+	s.dbMu.RUnlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if !a.Timestamp().Equal(b.Timestamp()) {
+			return a.Timestamp().Before(b.Timestamp())
+		}
+
+		idA, idB := a.AggregateID(), b.AggregateID()
+		if c := bytes.Compare(idA[:], idB[:]); c != 0 {
+			return c < 0
+		}
+
+		return a.Version() < b.Version()
+	})
+
+	for _, e := range events {
+		if err := f(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindByMetadata implements the FindByMetadata method of the
+// eventhorizon.EventStoreMetadataFinder interface, by scanning all events.
+// Values are compared with reflect.DeepEqual.
+func (s *EventStore) FindByMetadata(ctx context.Context, key string, value interface{}) ([]eh.Event, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+
+	events := []eh.Event{}
+
+	for _, ref := range s.all {
+		event := s.db[ref.aggregateID].Events[ref.version-1]
+
+		if v, ok := event.Metadata()[key]; !ok || !reflect.DeepEqual(v, value) {
+			continue
+		}
+
+		e, err := copyEvent(ctx, event)
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not copy event: %w", err),
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    event.AggregateType(),
+				AggregateID:      ref.aggregateID,
+				AggregateVersion: ref.version,
+			}
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// AggregateIDs implements the AggregateIDs method of the
+// eventhorizon.EventStoreAggregateLister interface. The IDs are returned in
+// the order the aggregates were created.
+func (s *EventStore) AggregateIDs(ctx context.Context, aggregateType eh.AggregateType) ([]uuid.UUID, error) {
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+
+	ids := []uuid.UUID{}
+
+	for _, ref := range s.all {
+		if ref.version != 1 {
+			continue
+		}
+
+		if a, ok := s.db[ref.aggregateID]; ok && len(a.Events) > 0 &&
+			a.Events[0].AggregateType() == aggregateType {
+			ids = append(ids, ref.aggregateID)
+		}
+	}
+
+	return ids, nil
+}
+
+// StreamAggregateIDs implements the StreamAggregateIDs method of the
+// eventhorizon.EventStoreAggregateLister interface. The IDs are streamed in the
+// order the aggregates were created, as of the start of the streaming.
+func (s *EventStore) StreamAggregateIDs(ctx context.Context, aggregateType eh.AggregateType, f func(ctx context.Context, id uuid.UUID) error) error {
+	ids, err := s.AggregateIDs(ctx, aggregateType)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := f(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendRefs adds saved events to the global order, must be called with the
+// lock held.
+func (s *EventStore) appendRefs(events []eh.Event) {
+	for _, e := range events {
+		s.all = append(s.all, eventRef{
+			aggregateID: e.AggregateID(),
+			version:     e.Version(),
+		})
+	}
+}
+
+// eventRef is a reference to an event in the global order.
+type eventRef struct {
+	aggregateID uuid.UUID
+	version     int
+}
+
 type aggregateRecord struct {
 	AggregateID uuid.UUID
 	Version     int
@@ -239,6 +534,13 @@ type aggregateRecord struct {
 	// Snapshot    eh.Aggregate
 }
 
+// OrderingGuarantee implements the OrderingGuarantee method of the
+// eventhorizon.OrderingReporter interface. All events are streamed in the
+// order they were saved.
+func (s *EventStore) OrderingGuarantee() eh.OrderingGuarantee {
+	return eh.GlobalOrdering
+}
+
 // Close implements the Close method of the eventhorizon.EventStore interface.
 func (s *EventStore) Close() error {
 	return nil
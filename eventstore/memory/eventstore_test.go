@@ -21,6 +21,7 @@ import (
 
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/eventstore"
+	"github.com/looplab/eventhorizon/eventstore/storetest"
 	"github.com/looplab/eventhorizon/mocks"
 	"github.com/looplab/eventhorizon/uuid"
 )
@@ -42,6 +43,78 @@ func TestEventStore(t *testing.T) {
 	}
 }
 
+func TestEventStoreConformance(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	storetest.Run(t, store, context.Background())
+}
+
+func TestEventStoreStreamer(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.StreamAcceptanceTest(t, store, context.Background())
+}
+
+func TestEventStoreTimeStreamer(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.TimeStreamAcceptanceTest(t, store, context.Background())
+}
+
+func TestEventStoreBatchLoader(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.BatchLoadAcceptanceTest(t, store, context.Background())
+}
+
+func TestEventStoreMetadataFinder(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.MetadataAcceptanceTest(t, store, context.Background())
+}
+
+func TestEventStoreAggregateLister(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.AggregateListerAcceptanceTest(t, store, context.Background())
+}
+
+func TestEventStoreReverseLoader(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.ReverseLoaderAcceptanceTest(t, store, context.Background())
+}
+
+func TestEventStoreOrdering(t *testing.T) {
+	store, err := NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	eventstore.OrderingAcceptanceTest(t, store, context.Background())
+}
+
 func TestWithEventHandler(t *testing.T) {
 	h := &mocks.EventBus{}
 
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// MetadataAcceptanceTest is the acceptance test that all implementations of
+// EventStoreMetadataFinder should pass. It should manually be called from a
+// test case in each implementation:
+//
+//	func TestEventStoreMetadataFinder(t *testing.T) {
+//		store := NewEventStore()
+//		eventstore.MetadataAcceptanceTest(t, store, context.Background())
+//	}
+func MetadataAcceptanceTest(t *testing.T, store interface {
+	eh.EventStore
+	eh.EventStoreMetadataFinder
+}, ctx context.Context) {
+	// Use unique correlation IDs, the store may already have events.
+	correlationID, otherID := uuid.New().String(), uuid.New().String()
+
+	// Save events for two aggregates sharing a correlation ID, and one other.
+	id1, id2, id3 := uuid.New(), uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvent := func(content string, id uuid.UUID, version int, correlationID string) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: content}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, version),
+			eh.WithMetadata(map[string]interface{}{"correlation_id": correlationID}))
+	}
+	event1 := newEvent("event1", id1, 1, correlationID)
+	event2 := newEvent("event2", id2, 1, correlationID)
+	event3 := newEvent("event3", id3, 1, otherID)
+	event4 := newEvent("event4", id1, 2, correlationID)
+
+	if err := store.Save(ctx, []eh.Event{event1}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{event2}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{event3}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{event4}, 1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Find all events across aggregates, in order.
+	events, err := store.FindByMetadata(ctx, "correlation_id", correlationID)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := []eh.Event{event1, event2, event4}
+	if len(events) != len(expected) {
+		t.Fatal("there should be the events with the correlation ID:", events)
+	}
+
+	for i, event := range events {
+		if err := eh.CompareEvents(event, expected[i], eh.IgnorePositionMetadata()); err != nil {
+			t.Error("the event should be correct:", err)
+		}
+	}
+
+	// Find other events.
+	events, err = store.FindByMetadata(ctx, "correlation_id", otherID)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 1 || events[0].AggregateID() != id3 {
+		t.Error("there should be the event with the other correlation ID:", events)
+	}
+
+	// No events found.
+	events, err = store.FindByMetadata(ctx, "correlation_id", uuid.New().String())
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 0 {
+		t.Error("there should be no events:", events)
+	}
+}
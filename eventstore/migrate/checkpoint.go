@@ -0,0 +1,73 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Checkpoint stores the position of a migration, to be able to resume it.
+type Checkpoint interface {
+	// Load loads the saved position, or 0 if there is none.
+	Load(ctx context.Context) (int, error)
+	// Save saves the position.
+	Save(ctx context.Context, position int) error
+}
+
+// FileCheckpoint is a Checkpoint stored in a file.
+type FileCheckpoint struct {
+	path string
+}
+
+var _ = Checkpoint(&FileCheckpoint{})
+
+// NewFileCheckpoint creates a new FileCheckpoint stored at path.
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{path: path}
+}
+
+// Load implements the Load method of the Checkpoint interface.
+func (c *FileCheckpoint) Load(ctx context.Context) (int, error) {
+	b, err := ioutil.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	position, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+
+	return position, nil
+}
+
+// Save implements the Save method of the Checkpoint interface. The file is
+// replaced atomically to not be corrupted if interrupted.
+func (c *FileCheckpoint) Save(ctx context.Context, position int) error {
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(position)), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path)
+}
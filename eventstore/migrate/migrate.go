@@ -0,0 +1,198 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migrate migrates all events from one event store to another, for
+// example when moving from the memory event store to MongoDB.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+var (
+	// ErrNotStreamable is when the source event store can not stream all events.
+	ErrNotStreamable = errors.New("source event store is not streamable")
+	// ErrAggregateExists is when an aggregate being migrated already has events
+	// in the destination event store.
+	ErrAggregateExists = errors.New("aggregate already exists in destination")
+)
+
+// Progress is the progress of a migration.
+type Progress struct {
+	// Position is the position in the source of the last migrated event.
+	Position int
+	// Migrated is the number of events migrated.
+	Migrated int
+	// Skipped is the number of events skipped because their aggregate already
+	// existed in the destination.
+	Skipped int
+}
+
+// Option is an option setter used to configure a migration.
+type Option func(*migration)
+
+// WithCheckpoint uses a checkpoint to be able to resume an interrupted
+// migration. The position of the source is saved after each migrated event,
+// and the migration is started after the saved position.
+func WithCheckpoint(c Checkpoint) Option {
+	return func(m *migration) {
+		m.checkpoint = c
+	}
+}
+
+// WithProgress calls f with the progress after each migrated (or skipped) event.
+func WithProgress(f func(Progress)) Option {
+	return func(m *migration) {
+		m.progressFunc = f
+	}
+}
+
+// WithSkipExisting skips the events of aggregates that already exist in the
+// destination, the default is to fail with ErrAggregateExists.
+func WithSkipExisting() Option {
+	return func(m *migration) {
+		m.skipExisting = true
+	}
+}
+
+// Migrate migrates all events from the source to the destination event store.
+// The source must implement eventhorizon.EventStoreStreamer, the events are
+// migrated in its global order and saved with their versions, timestamps and
+// metadata preserved.
+func Migrate(ctx context.Context, src, dst eh.EventStore, options ...Option) error {
+	streamer, ok := src.(eh.EventStoreStreamer)
+	if !ok {
+		return ErrNotStreamable
+	}
+
+	m := &migration{
+		dst:      dst,
+		versions: map[uuid.UUID]int{},
+		skipped:  map[uuid.UUID]struct{}{},
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(m)
+	}
+
+	if m.checkpoint != nil {
+		var err error
+		if m.progress.Position, err = m.checkpoint.Load(ctx); err != nil {
+			return fmt.Errorf("could not load checkpoint: %w", err)
+		}
+	}
+
+	if err := streamer.StreamAll(ctx, m.progress.Position, m.migrate); err != nil {
+		return fmt.Errorf("could not migrate events: %w", err)
+	}
+
+	return nil
+}
+
+type migration struct {
+	dst          eh.EventStore
+	checkpoint   Checkpoint
+	progressFunc func(Progress)
+	skipExisting bool
+
+	progress Progress
+	// versions are the versions in the destination of migrated aggregates.
+	versions map[uuid.UUID]int
+	skipped  map[uuid.UUID]struct{}
+}
+
+func (m *migration) migrate(ctx context.Context, position int, event eh.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	migrate, err := m.shouldMigrate(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	if migrate {
+		if err := m.dst.Save(ctx, []eh.Event{event}, event.Version()-1); err != nil {
+			return fmt.Errorf("could not save event %s: %w", event, err)
+		}
+
+		m.versions[event.AggregateID()] = event.Version()
+		m.progress.Migrated++
+	} else {
+		m.progress.Skipped++
+	}
+
+	m.progress.Position = position
+
+	if m.checkpoint != nil {
+		if err := m.checkpoint.Save(ctx, position); err != nil {
+			return fmt.Errorf("could not save checkpoint: %w", err)
+		}
+	}
+
+	if m.progressFunc != nil {
+		m.progressFunc(m.progress)
+	}
+
+	return nil
+}
+
+// shouldMigrate checks if an event should be migrated. The first time an
+// aggregate is seen the destination is checked for existing events, which is
+// only allowed when resuming the migration of the aggregate.
+func (m *migration) shouldMigrate(ctx context.Context, event eh.Event) (bool, error) {
+	id := event.AggregateID()
+
+	if _, ok := m.skipped[id]; ok {
+		return false, nil
+	}
+
+	if _, ok := m.versions[id]; ok {
+		return true, nil
+	}
+
+	version := 0
+
+	events, err := m.dst.Load(ctx, id)
+	if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+		return false, fmt.Errorf("could not load destination aggregate: %w", err)
+	}
+
+	if len(events) > 0 {
+		version = events[len(events)-1].Version()
+	}
+
+	if version == event.Version()-1 {
+		m.versions[id] = version
+
+		return true, nil
+	}
+
+	if m.skipExisting {
+		m.skipped[id] = struct{}{}
+
+		return false, nil
+	}
+
+	return false, fmt.Errorf("%w: %s(%s, v%d)", ErrAggregateExists, event.AggregateType(), id, version)
+}
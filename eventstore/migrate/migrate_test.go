@@ -0,0 +1,224 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventstore/memory"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	src, ids := newSourceStore(t)
+
+	dst, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var progress []Progress
+	if err := Migrate(ctx, src, dst, WithProgress(func(p Progress) {
+		progress = append(progress, p)
+	})); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	assertMigrated(t, src, dst, ids)
+
+	if len(progress) != 5 || progress[4] != (Progress{Position: 5, Migrated: 5}) {
+		t.Error("the progress should be correct:", progress)
+	}
+}
+
+func TestMigrate_Resume(t *testing.T) {
+	ctx := context.Background()
+	src, ids := newSourceStore(t)
+
+	dst, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	checkpoint := NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint"))
+
+	// Interrupt the migration after three events.
+	saveErr := errors.New("save error")
+	failing := &failingStore{EventStore: dst, failAfter: 3, err: saveErr}
+
+	if err := Migrate(ctx, src, failing, WithCheckpoint(checkpoint)); !errors.Is(err, saveErr) {
+		t.Error("there should be a save error:", err)
+	}
+
+	if position, err := checkpoint.Load(ctx); err != nil || position != 3 {
+		t.Error("the checkpoint should be saved:", position, err)
+	}
+
+	// Resume the migration.
+	var last Progress
+	if err := Migrate(ctx, src, dst, WithCheckpoint(checkpoint), WithProgress(func(p Progress) {
+		last = p
+	})); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if last != (Progress{Position: 5, Migrated: 2}) {
+		t.Error("the progress should be correct:", last)
+	}
+
+	assertMigrated(t, src, dst, ids)
+}
+
+func TestMigrate_Existing(t *testing.T) {
+	ctx := context.Background()
+	src, ids := newSourceStore(t)
+
+	dst, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The first aggregate already exists in the destination.
+	existing, err := src.Load(ctx, ids[0])
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := dst.Save(ctx, existing[:1], 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := Migrate(ctx, src, dst); !errors.Is(err, ErrAggregateExists) {
+		t.Error("there should be an aggregate exists error:", err)
+	}
+
+	var last Progress
+	if err := Migrate(ctx, src, dst, WithSkipExisting(), WithProgress(func(p Progress) {
+		last = p
+	})); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if last != (Progress{Position: 5, Migrated: 2, Skipped: 3}) {
+		t.Error("the progress should be correct:", last)
+	}
+
+	assertMigrated(t, src, dst, ids[1:])
+
+	events, err := dst.Load(ctx, ids[0])
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 1 {
+		t.Error("the existing aggregate should not be changed:", events)
+	}
+}
+
+func TestMigrate_NotStreamable(t *testing.T) {
+	if err := Migrate(context.Background(), &mocks.EventStore{}, &mocks.EventStore{}); !errors.Is(err, ErrNotStreamable) {
+		t.Error("there should be a not streamable error:", err)
+	}
+}
+
+// newSourceStore creates a memory event store with interleaved events for two
+// aggregates.
+func newSourceStore(t *testing.T) (*memory.EventStore, []uuid.UUID) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	src, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	for i, s := range []struct {
+		id      uuid.UUID
+		version int
+	}{
+		{ids[0], 1},
+		{ids[1], 1},
+		{ids[0], 2},
+		{ids[1], 2},
+		{ids[0], 3},
+	} {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"},
+			timestamp.Add(time.Duration(i)*time.Microsecond),
+			eh.ForAggregate(mocks.AggregateType, s.id, s.version),
+			eh.WithMetadata(map[string]interface{}{"num": i}),
+		)
+		if err := src.Save(ctx, []eh.Event{event}, s.version-1); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	return src, ids
+}
+
+func assertMigrated(t *testing.T, src, dst eh.EventStore, ids []uuid.UUID) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	for _, id := range ids {
+		expected, err := src.Load(ctx, id)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		events, err := dst.Load(ctx, id)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if len(events) != len(expected) {
+			t.Fatal("the migrated events should be correct:", events)
+		}
+
+		for i, event := range events {
+			if err := eh.CompareEvents(event, expected[i]); err != nil {
+				t.Error("the migrated event should be correct:", err)
+			}
+		}
+	}
+}
+
+// failingStore fails saving after a number of saves.
+type failingStore struct {
+	eh.EventStore
+	failAfter int
+	err       error
+}
+
+func (s *failingStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	if s.failAfter == 0 {
+		return s.err
+	}
+
+	s.failAfter--
+
+	return s.EventStore.Save(ctx, events, originalVersion)
+}
@@ -16,9 +16,13 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
 
 	// Register uuid.UUID as BSON type.
 	_ "github.com/looplab/eventhorizon/codec/bson"
@@ -60,27 +64,50 @@ func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
 		return err
 	}
 
-	// Find and replace the event.
-	if r, err := s.aggregates.UpdateOne(ctx,
+	// Move large event data out of the aggregate document.
+	dbEvents := []evt{*e}
+
+	dataIDs, err := s.externalizeData(ctx, dbEvents)
+	if err != nil {
+		s.removeData(ctx, dataIDs)
+
+		return &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpReplace,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: av,
+			Events:           []eh.Event{event},
+		}
+	}
+
+	// Find and replace the event, returning the replaced event.
+	var aggregate aggregateRecord
+	if err := s.aggregates.FindOneAndUpdate(ctx,
 		bson.M{
 			"_id":            event.AggregateID(),
 			"events.version": event.Version(),
 		},
 		bson.M{
-			"$set": bson.M{"events.$": *e},
+			"$set": bson.M{"events.$": dbEvents[0]},
 		},
-	); err != nil {
+		mongoOptions.FindOneAndUpdate().SetProjection(bson.M{"events.$": 1}),
+	).Decode(&aggregate); errors.Is(err, mongo.ErrNoDocuments) {
+		s.removeData(ctx, dataIDs)
+
 		return &eh.EventStoreError{
-			Err:              err,
+			Err:              eh.ErrEventNotFound,
 			Op:               eh.EventStoreOpReplace,
 			AggregateType:    at,
 			AggregateID:      id,
 			AggregateVersion: av,
 			Events:           []eh.Event{event},
 		}
-	} else if r.MatchedCount == 0 {
+	} else if err != nil {
+		s.removeData(ctx, dataIDs)
+
 		return &eh.EventStoreError{
-			Err:              eh.ErrEventNotFound,
+			Err:              err,
 			Op:               eh.EventStoreOpReplace,
 			AggregateType:    at,
 			AggregateID:      id,
@@ -89,6 +116,13 @@ func (s *EventStore) Replace(ctx context.Context, event eh.Event) error {
 		}
 	}
 
+	// Remove any data of the replaced event from GridFS.
+	for _, e := range aggregate.Events {
+		if e.DataRef != nil {
+			s.removeData(ctx, []primitive.ObjectID{*e.DataRef})
+		}
+	}
+
 	return nil
 }
 
@@ -122,5 +156,19 @@ func (s *EventStore) Clear(ctx context.Context) error {
 		}
 	}
 
+	if s.largeDataThreshold > 0 {
+		bucket, err := s.dataBucket(ctx)
+		if err == nil {
+			err = bucket.Drop()
+		}
+
+		if err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not drop event data: %w", err),
+				Op:  eh.EventStoreOpClear,
+			}
+		}
+	}
+
 	return nil
 }
@@ -16,10 +16,12 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
@@ -30,12 +32,25 @@ import (
 	_ "github.com/looplab/eventhorizon/codec/bson"
 
 	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mongoutils"
 	"github.com/looplab/eventhorizon/uuid"
 )
 
 // EventStore implements an eventhorizon.EventStore for MongoDB using a single
 // collection with one document per aggregate/stream which holds its events
 // as values.
+//
+// The event data is stored as a nested BSON document in the "data" field of
+// each event, which makes it possible to query data fields directly in
+// MongoDB, for example {"events.data.status": "failed"}.
+//
+// As all events of an aggregate are stored in one document the history of an
+// aggregate is limited by the 16MB document size of MongoDB. Saving events that
+// would exceed the limit returns a *mongoutils.DocumentTooLargeError, with the
+// aggregate ID and size. Aggregates with long histories should be snapshotted
+// and compacted, or migrated to the mongodb_v2 event store which stores one
+// document per event (see the eventstore/migrate package). Events with large
+// data can be stored with the data in GridFS, see WithLargeDataInGridFS.
 type EventStore struct {
 	client                *mongo.Client
 	clientOwnership       clientOwnership
@@ -43,6 +58,7 @@ type EventStore struct {
 	aggregates            *mongo.Collection
 	eventHandlerAfterSave eh.EventHandler
 	eventHandlerInTX      eh.EventHandler
+	largeDataThreshold    int
 }
 
 type clientOwnership int
@@ -220,6 +236,55 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		dbEvents[i] = *e
 	}
 
+	// Move large event data out of the aggregate document, and remove it again
+	// if the events are not saved.
+	dataIDs, err := s.externalizeData(ctx, dbEvents)
+
+	saved := false
+	defer func() {
+		if !saved {
+			s.removeData(ctx, dataIDs)
+		}
+	}()
+
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	// Check the size of the events up front, a single document can not hold
+	// them if they are too large.
+	size, err := eventsSize(dbEvents)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	if err := mongoutils.CheckDocumentSize(id, size); err != nil {
+		return &eh.EventStoreError{
+			Err:              err,
+			Op:               eh.EventStoreOpSave,
+			AggregateType:    at,
+			AggregateID:      id,
+			AggregateVersion: originalVersion,
+			Events:           events,
+		}
+	}
+
+	fencingToken, fenced := FencingTokenFromContext(ctx)
+
 	// Run the operation in a transaction if using an outbox, otherwise it's not needed.
 	saveEvents := func(ctx mongo.SessionContext) error {
 		// Either insert a new aggregate or append to an existing.
@@ -229,25 +294,53 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 				Version:     len(dbEvents),
 				Events:      dbEvents,
 			}
-			if _, err := s.aggregates.InsertOne(ctx, aggregate); err != nil {
+			if fenced {
+				aggregate.FencingToken = &fencingToken
+			}
+
+			if _, err := s.aggregates.InsertOne(ctx, aggregate); isDocumentTooLarge(err) {
+				return s.documentTooLargeError(ctx, id, size)
+			} else if err != nil {
 				return fmt.Errorf("could not insert events (new): %w", err)
 			}
 		} else {
 			// Increment aggregate version on insert of new event record, and
 			// only insert if version of aggregate is matching (ie not changed
 			// since loading the aggregate).
-			if r, err := s.aggregates.UpdateOne(ctx,
-				bson.M{
-					"_id":     id,
-					"version": originalVersion,
-				},
-				bson.M{
-					"$push": bson.M{"events": bson.M{"$each": dbEvents}},
-					"$inc":  bson.M{"version": len(dbEvents)},
-				},
-			); err != nil {
+			filter := bson.M{
+				"_id":     id,
+				"version": originalVersion,
+			}
+			update := bson.M{
+				"$push": bson.M{"events": bson.M{"$each": dbEvents}},
+				"$inc":  bson.M{"version": len(dbEvents)},
+			}
+
+			// Only append with the latest fencing token, and raise the fence.
+			if fenced {
+				filter["$or"] = bson.A{
+					bson.M{"fencing_token": nil},
+					bson.M{"fencing_token": bson.M{"$lte": fencingToken}},
+				}
+				update["$set"] = bson.M{"fencing_token": fencingToken}
+			}
+
+			if r, err := s.aggregates.UpdateOne(ctx, filter, update); isDocumentTooLarge(err) {
+				return s.documentTooLargeError(ctx, id, size)
+			} else if err != nil {
 				return fmt.Errorf("could not insert events (update): %w", err)
 			} else if r.MatchedCount == 0 {
+				if fenced {
+					if n, err := s.aggregates.CountDocuments(ctx, bson.M{
+						"_id":           id,
+						"fencing_token": bson.M{"$gt": fencingToken},
+					}); err != nil {
+						return fmt.Errorf("could not check fencing token: %w", err)
+					} else if n > 0 {
+						return ErrStaleFencingToken
+					}
+				}
+
 				return eh.ErrEventConflictFromOtherSave
 			}
 		}
@@ -255,8 +348,35 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		return nil
 	}
 
-	// Run the operation in a transaction if using an outbox, otherwise it's not needed.
-	if s.eventHandlerInTX != nil {
+	// Run the operation in the transaction of WithTransaction if used, or in a
+	// new transaction if using an outbox, otherwise it's not needed.
+	if sess := mongo.SessionFromContext(ctx); sess != nil {
+		if err := saveEvents(mongo.NewSessionContext(ctx, sess)); err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		if s.eventHandlerInTX != nil {
+			for _, e := range events {
+				if err := s.eventHandlerInTX.HandleEvent(ctx, e); err != nil {
+					return &eh.EventStoreError{
+						Err:              fmt.Errorf("could not handle event in transaction: %w", err),
+						Op:               eh.EventStoreOpSave,
+						AggregateType:    at,
+						AggregateID:      id,
+						AggregateVersion: originalVersion,
+						Events:           events,
+					}
+				}
+			}
+		}
+	} else if s.eventHandlerInTX != nil {
 		sess, err := s.client.StartSession(nil)
 		if err != nil {
 			return &eh.EventStoreError{
@@ -307,6 +427,17 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		}
 	}
 
+	saved = true
+
+	// Handle the events after the commit when saved using WithTransaction, and
+	// remove the uploaded data if the transaction is aborted.
+	if tx, ok := ctx.Value(transactionKey).(*transaction); ok && tx.store == s {
+		tx.events = append(tx.events, events...)
+		tx.dataIDs = append(tx.dataIDs, dataIDs...)
+
+		return nil
+	}
+
 	// Let the optional event handler handle the events.
 	if s.eventHandlerAfterSave != nil {
 		for _, e := range events {
@@ -328,9 +459,19 @@ func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error)
 }
 
 // LoadFrom loads all events from version for the aggregate id from the store.
+// The read preference can be set with NewContextWithReadPreference.
 func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	aggregates, err := readCollection(ctx, s.aggregates)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("could not set read preference: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
 	var aggregate aggregateRecord
-	if err := s.aggregates.FindOne(ctx, bson.M{"_id": id}).Decode(&aggregate); err != nil {
+	if err := aggregates.FindOne(ctx, bson.M{"_id": id}).Decode(&aggregate); err != nil {
 		// Translate to our own not found error.
 		if err == mongo.ErrNoDocuments {
 			err = eh.ErrAggregateNotFound
@@ -343,59 +484,210 @@ func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([
 		}
 	}
 
-	events := make([]eh.Event, len(aggregate.Events))
+	events := make([]eh.Event, 0, len(aggregate.Events))
 
-	for i, e := range aggregate.Events {
+	for _, e := range aggregate.Events {
 		if e.Version < version {
 			continue
 		}
 
-		// Create an event of the correct type and decode from raw BSON.
-		if len(e.RawData) > 0 {
-			var err error
-			if e.data, err = eh.CreateEventData(e.EventType); err != nil {
-				return nil, &eh.EventStoreError{
-					Err:              fmt.Errorf("could not create event data: %w", err),
-					Op:               eh.EventStoreOpLoad,
-					AggregateType:    e.AggregateType,
-					AggregateID:      id,
-					AggregateVersion: e.Version,
-					Events:           events,
-				}
+		event, err := s.newEvent(ctx, e)
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      id,
+				AggregateVersion: e.Version,
+				Events:           events,
 			}
+		}
 
-			if err := bson.Unmarshal(e.RawData, e.data); err != nil {
-				return nil, &eh.EventStoreError{
-					Err:              fmt.Errorf("could not unmarshal event data: %w", err),
-					Op:               eh.EventStoreOpLoad,
-					AggregateType:    e.AggregateType,
-					AggregateID:      id,
-					AggregateVersion: e.Version,
-					Events:           events,
-				}
-			}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// LoadReverse implements the LoadReverse method of the
+// eventhorizon.EventStoreReverseLoader interface, using a slice projection to
+// only fetch the most recent events from the aggregate document. The read
+// preference can be set with NewContextWithReadPreference.
+func (s *EventStore) LoadReverse(ctx context.Context, id uuid.UUID, limit int) ([]eh.Event, error) {
+	aggregates, err := readCollection(ctx, s.aggregates)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("could not set read preference: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	opts := mongoOptions.FindOne()
+	if limit > 0 {
+		opts.SetProjection(bson.M{"events": bson.M{"$slice": -limit}})
+	}
+
+	var aggregate aggregateRecord
+	if err := aggregates.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&aggregate); err != nil {
+		// Translate to our own not found error.
+		if err == mongo.ErrNoDocuments {
+			err = eh.ErrAggregateNotFound
+		}
 
-			e.RawData = nil
+		return nil, &eh.EventStoreError{
+			Err:         err,
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
 		}
+	}
+
+	events := make([]eh.Event, 0, len(aggregate.Events))
 
-		event := eh.NewEvent(
-			e.EventType,
-			e.data,
-			e.Timestamp,
-			eh.ForAggregate(
-				e.AggregateType,
-				e.AggregateID,
-				e.Version,
-			),
-			eh.WithMetadata(e.Metadata),
-		)
+	for i := len(aggregate.Events) - 1; i >= 0; i-- {
+		e := aggregate.Events[i]
+
+		event, err := s.newEvent(ctx, e)
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      id,
+				AggregateVersion: e.Version,
+				Events:           events,
+			}
+		}
 
-		events[i] = event
+		events = append(events, event)
 	}
 
 	return events, nil
 }
 
+// newEvent creates an event from an event record, loading any data stored in
+// GridFS.
+func (s *EventStore) newEvent(ctx context.Context, e evt) (eh.Event, error) {
+	if e.DataRef != nil {
+		var err error
+		if e.RawData, err = s.loadData(ctx, *e.DataRef); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create an event of the correct type and decode from raw BSON.
+	if len(e.RawData) > 0 {
+		var err error
+		if e.data, err = eh.CreateEventData(e.EventType); err != nil {
+			return nil, fmt.Errorf("could not create event data: %w", err)
+		}
+
+		if err := bson.Unmarshal(e.RawData, e.data); err != nil {
+			return nil, fmt.Errorf("could not unmarshal event data: %w", err)
+		}
+
+		e.RawData = nil
+	}
+
+	return eh.NewEvent(
+		eh.ResolveEventType(e.EventType),
+		e.data,
+		e.Timestamp,
+		eh.ForAggregate(
+			e.AggregateType,
+			e.AggregateID,
+			e.Version,
+		),
+		eh.WithMetadata(e.Metadata),
+	), nil
+}
+
+// AggregateIDs implements the AggregateIDs method of the
+// eventhorizon.EventStoreAggregateLister interface, using a distinct query.
+func (s *EventStore) AggregateIDs(ctx context.Context, aggregateType eh.AggregateType) ([]uuid.UUID, error) {
+	values, err := s.aggregates.Distinct(ctx, "_id", bson.M{"events.aggregate_type": aggregateType})
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not find aggregate IDs: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(values))
+
+	for _, v := range values {
+		// The IDs are stored as strings by the BSON codec.
+		str, ok := v.(string)
+		if !ok {
+			return nil, &eh.EventStoreError{
+				Err: fmt.Errorf("aggregate ID is of incorrect type %T", v),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		id, err := uuid.Parse(str)
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err: fmt.Errorf("could not parse aggregate ID: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// StreamAggregateIDs implements the StreamAggregateIDs method of the
+// eventhorizon.EventStoreAggregateLister interface, using a cursor over the
+// aggregate documents.
+func (s *EventStore) StreamAggregateIDs(ctx context.Context, aggregateType eh.AggregateType, f func(ctx context.Context, id uuid.UUID) error) error {
+	cursor, err := s.aggregates.Find(ctx,
+		bson.M{"events.aggregate_type": aggregateType},
+		mongoOptions.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not find aggregate IDs: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var r struct {
+			ID uuid.UUID `bson:"_id"`
+		}
+		if err := cursor.Decode(&r); err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not decode aggregate ID: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		if err := f(ctx, r.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not find aggregate IDs: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return nil
+}
+
+// OrderingGuarantee implements the OrderingGuarantee method of the
+// eventhorizon.OrderingReporter interface. The events are stored in one
+// document per aggregate, there is no global order between aggregates.
+func (s *EventStore) OrderingGuarantee() eh.OrderingGuarantee {
+	return eh.PerAggregateOrdering
+}
+
 // Close implements the Close method of the eventhorizon.EventStore interface.
 func (s *EventStore) Close() error {
 	if s.clientOwnership == externalClient {
@@ -406,11 +698,69 @@ func (s *EventStore) Close() error {
 	return s.client.Disconnect(context.Background())
 }
 
+// Server error codes for too large documents.
+const (
+	errCodeDocumentTooLarge = 10334
+	errCodeUpdateTooLarge   = 17419
+)
+
+// isDocumentTooLarge checks if an error is a too large document error from MongoDB.
+func isDocumentTooLarge(err error) bool {
+	var serverErr mongo.ServerError
+
+	return errors.As(err, &serverErr) &&
+		(serverErr.HasErrorCode(errCodeDocumentTooLarge) ||
+			serverErr.HasErrorCode(errCodeUpdateTooLarge))
+}
+
+// documentTooLargeError creates an error for when appending events of size
+// bytes would make the aggregate document too large. The current size of the
+// document is added if it can be fetched, which requires MongoDB 4.4.
+func (s *EventStore) documentTooLargeError(ctx context.Context, id uuid.UUID, size int) error {
+	cursor, err := s.aggregates.Aggregate(ctx, mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"_id": id}}},
+		bson.D{{Key: "$project", Value: bson.M{"size": bson.M{"$bsonSize": "$$ROOT"}}}},
+	})
+	if err == nil {
+		defer cursor.Close(ctx)
+
+		var doc struct {
+			Size int `bson:"size"`
+		}
+		if cursor.Next(ctx) && cursor.Decode(&doc) == nil {
+			size += doc.Size
+		}
+	}
+
+	return &mongoutils.DocumentTooLargeError{
+		AggregateID: id,
+		Size:        size,
+	}
+}
+
+// eventsSize returns the total BSON size of the event records.
+func eventsSize(events []evt) (int, error) {
+	size := 0
+
+	for _, e := range events {
+		b, err := bson.Marshal(e)
+		if err != nil {
+			return 0, fmt.Errorf("could not marshal event: %w", err)
+		}
+
+		size += len(b)
+	}
+
+	return size, nil
+}
+
 // aggregateRecord is the Database representation of an aggregate.
 type aggregateRecord struct {
 	AggregateID uuid.UUID `bson:"_id"`
 	Version     int       `bson:"version"`
 	Events      []evt     `bson:"events"`
+	// FencingToken is the latest fencing token used when saving events.
+	FencingToken *int64 `bson:"fencing_token,omitempty"`
 	// Type        string        `bson:"type"`
 	// Snapshot    bson.Raw      `bson:"snapshot"`
 }
@@ -420,6 +770,7 @@ type aggregateRecord struct {
 type evt struct {
 	EventType     eh.EventType           `bson:"event_type"`
 	RawData       bson.Raw               `bson:"data,omitempty"`
+	DataRef       *primitive.ObjectID    `bson:"data_ref,omitempty"`
 	data          eh.EventData           `bson:"-"`
 	Timestamp     time.Time              `bson:"timestamp"`
 	AggregateType eh.AggregateType       `bson:"aggregate_type"`
@@ -18,13 +18,20 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/eventstore"
+	"github.com/looplab/eventhorizon/eventstore/storetest"
 	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/mongoutils"
+	mongoRepo "github.com/looplab/eventhorizon/repo/mongodb"
 	"github.com/looplab/eventhorizon/uuid"
 )
 
@@ -62,11 +69,236 @@ func TestEventStoreIntegration(t *testing.T) {
 
 	eventstore.AcceptanceTest(t, store, context.Background())
 
+	eventstore.OrderingAcceptanceTest(t, store, context.Background())
+
+	storetest.Run(t, store, context.Background())
+
+	eventstore.AggregateListerAcceptanceTest(t, store, context.Background())
+
+	eventstore.ReverseLoaderAcceptanceTest(t, store, context.Background())
+
 	if err := store.Close(); err != nil {
 		t.Error("there should be no error:", err)
 	}
 }
 
+func TestEventStore_DocumentTooLarge(t *testing.T) {
+	// NOTE: The size is checked before using the DB.
+	store := &EventStore{}
+	id := uuid.New()
+
+	// A synthetic event that is larger than the max document size.
+	event := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: strings.Repeat("a", mongoutils.MaxDocumentSize)},
+		time.Now(), eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	err := store.Save(context.Background(), []eh.Event{event}, 0)
+	if !errors.Is(err, mongoutils.ErrDocumentTooLarge) {
+		t.Fatal("there should be a document too large error:", err)
+	}
+
+	var sizeErr *mongoutils.DocumentTooLargeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatal("the error should be a DocumentTooLargeError:", err)
+	}
+
+	if sizeErr.AggregateID != id {
+		t.Error("the error should have the aggregate ID:", sizeErr.AggregateID)
+	}
+
+	if sizeErr.Size <= mongoutils.MaxDocumentSize {
+		t.Error("the error should have the document size:", sizeErr.Size)
+	}
+}
+
+func TestFencingTokenIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	store, err := NewEventStore(url, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	ctx := context.Background()
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvent := func(version int) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, version))
+	}
+
+	// The first writer saves with epoch 2.
+	if err := store.Save(NewContextWithFencingToken(ctx, 2), []eh.Event{newEvent(1)}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// A stale writer with epoch 1 is rejected, even with a correct version.
+	if err := store.Save(NewContextWithFencingToken(ctx, 1), []eh.Event{newEvent(2)}, 1); !errors.Is(err, ErrStaleFencingToken) {
+		t.Error("there should be a stale fencing token error:", err)
+	}
+
+	// The same epoch can continue to save.
+	if err := store.Save(NewContextWithFencingToken(ctx, 2), []eh.Event{newEvent(2)}, 1); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// A new writer with epoch 3 raises the fence.
+	if err := store.Save(NewContextWithFencingToken(ctx, 3), []eh.Event{newEvent(3)}, 2); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := store.Save(NewContextWithFencingToken(ctx, 2), []eh.Event{newEvent(4)}, 3); !errors.Is(err, ErrStaleFencingToken) {
+		t.Error("there should be a stale fencing token error:", err)
+	}
+
+	// The version is still checked with a valid epoch.
+	if err := store.Save(NewContextWithFencingToken(ctx, 3), []eh.Event{newEvent(3)}, 2); !errors.Is(err, eh.ErrEventConflictFromOtherSave) {
+		t.Error("there should be a version conflict error:", err)
+	}
+
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 3 {
+		t.Error("only the events with a valid epoch should be saved:", events)
+	}
+}
+
+func TestWithTransactionIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	eventHandler := mocks.NewEventHandler("handler")
+
+	store, err := NewEventStore(url, db, WithEventHandler(eventHandler))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	// The read repo uses the same client as the event store.
+	repo, err := mongoRepo.NewRepoWithClient(store.client, db, "models")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	repo.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	ctx := context.Background()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// Create the collection outside of the transactions.
+	if err := repo.Save(ctx, &mocks.Model{ID: uuid.New(), CreatedAt: timestamp}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The event and the model are committed together.
+	id := uuid.New()
+	if err := store.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.Save(ctx, &mocks.Model{ID: id, Content: "model", CreatedAt: timestamp}); err != nil {
+			return err
+		}
+
+		return store.Save(ctx, []eh.Event{
+			eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, 1)),
+		}, 0)
+	}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := repo.Find(ctx, id); err != nil {
+		t.Error("the model should be saved:", err)
+	}
+
+	if events, err := store.Load(ctx, id); err != nil || len(events) != 1 {
+		t.Error("the event should be saved:", events, err)
+	}
+
+	if len(eventHandler.Events) != 1 {
+		t.Error("the committed event should be handled:", eventHandler.Events)
+	}
+
+	// The event and the model are rolled back together.
+	id = uuid.New()
+	rollbackErr := errors.New("rollback")
+
+	if err := store.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.Save(ctx, &mocks.Model{ID: id, Content: "model", CreatedAt: timestamp}); err != nil {
+			return err
+		}
+
+		if err := store.Save(ctx, []eh.Event{
+			eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, 1)),
+		}, 0); err != nil {
+			return err
+		}
+
+		return rollbackErr
+	}); !errors.Is(err, rollbackErr) {
+		t.Error("there should be a rollback error:", err)
+	}
+
+	if _, err := repo.Find(ctx, id); !errors.Is(err, eh.ErrEntityNotFound) {
+		t.Error("the model should not be saved:", err)
+	}
+
+	if events, err := store.Load(ctx, id); !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("the event should not be saved:", events, err)
+	}
+
+	if len(eventHandler.Events) != 1 {
+		t.Error("the rolled back event should not be handled:", eventHandler.Events)
+	}
+}
+
 func TestWithCollectionNameIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -117,6 +349,148 @@ func TestWithCollectionNameIntegration(t *testing.T) {
 	}
 }
 
+func TestLargeDataInGridFSIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	url := os.Getenv("MONGODB_ADDR")
+	if url == "" {
+		url = "localhost:27017"
+	}
+
+	url = "mongodb://" + url
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	if _, err := NewEventStore(url, db, WithLargeDataInGridFS(0)); err == nil {
+		t.Error("there should be an invalid threshold error")
+	}
+
+	store, err := NewEventStore(url, db, WithLargeDataInGridFS(1024))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	ctx := context.Background()
+	id := uuid.New()
+
+	// An event larger than the max document size, and a small event.
+	large := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: strings.Repeat("a", mongoutils.MaxDocumentSize+1)},
+		time.Now(), eh.ForAggregate(mocks.AggregateType, id, 1))
+	small := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: "small"},
+		time.Now(), eh.ForAggregate(mocks.AggregateType, id, 2))
+
+	if err := store.Save(ctx, []eh.Event{large, small}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Only the large data is stored in GridFS.
+	var aggregate aggregateRecord
+	if err := store.aggregates.FindOne(ctx, bson.M{"_id": id}).Decode(&aggregate); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(aggregate.Events) != 2 {
+		t.Fatal("there should be two events:", len(aggregate.Events))
+	}
+
+	if aggregate.Events[0].DataRef == nil || len(aggregate.Events[0].RawData) != 0 {
+		t.Error("the large data should be stored in GridFS")
+	}
+
+	if aggregate.Events[1].DataRef != nil || len(aggregate.Events[1].RawData) == 0 {
+		t.Error("the small data should be stored in the document")
+	}
+
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatal("there should be two events:", len(events))
+	}
+
+	for i, expected := range []eh.Event{large, small} {
+		if err := eh.CompareEvents(events[i], expected, eh.IgnoreTimestamp()); err != nil {
+			t.Error("the event should be correct:", i, err)
+		}
+	}
+
+	// Data of events that are not saved is removed.
+	conflicting := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: strings.Repeat("b", 2048)},
+		time.Now(), eh.ForAggregate(mocks.AggregateType, id, 2))
+
+	if err := store.Save(ctx, []eh.Event{conflicting}, 1); !errors.Is(err, eh.ErrEventConflictFromOtherSave) {
+		t.Error("there should be a conflict error:", err)
+	}
+
+	bucket, err := store.dataBucket(ctx)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if n, err := bucket.GetFilesCollection().CountDocuments(ctx, bson.M{}); err != nil {
+		t.Error("there should be no error:", err)
+	} else if n != 1 {
+		t.Error("only the data of the saved event should be stored:", n)
+	}
+
+	// Data of events saved in an aborted transaction is removed.
+	txErr := errors.New("transaction error")
+	if err := store.WithTransaction(ctx, func(txCtx context.Context) error {
+		event := eh.NewEvent(mocks.EventType,
+			&mocks.EventData{Content: strings.Repeat("c", 2048)},
+			time.Now(), eh.ForAggregate(mocks.AggregateType, id, 3))
+		if err := store.Save(txCtx, []eh.Event{event}, 2); err != nil {
+			return err
+		}
+
+		return txErr
+	}); !errors.Is(err, txErr) {
+		t.Error("there should be a transaction error:", err)
+	}
+
+	if n, err := bucket.GetFilesCollection().CountDocuments(ctx, bson.M{}); err != nil {
+		t.Error("there should be no error:", err)
+	} else if n != 1 {
+		t.Error("the data of the aborted event should be removed:", n)
+	}
+
+	// Data of replaced events is removed.
+	replaced := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: strings.Repeat("d", 2048)},
+		time.Now(), eh.ForAggregate(mocks.AggregateType, id, 1))
+	if err := store.Replace(ctx, replaced); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n, err := bucket.GetFilesCollection().CountDocuments(ctx, bson.M{}); err != nil {
+		t.Error("there should be no error:", err)
+	} else if n != 1 {
+		t.Error("only the data of the replacing event should be stored:", n)
+	}
+
+	if err := store.Clear(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
 func TestWithEventHandlerIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
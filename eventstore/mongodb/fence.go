@@ -0,0 +1,50 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStaleFencingToken is when events are saved with a fencing token that is
+// older than the token of a previous save for the aggregate.
+var ErrStaleFencingToken = errors.New("stale fencing token")
+
+type contextKey int
+
+const (
+	fencingTokenKey contextKey = iota
+	transactionKey
+	readPreferenceKey
+)
+
+// NewContextWithFencingToken sets a fencing token on the context, used when
+// saving events. The token should be a monotonically increasing epoch, for
+// example from a lease of the aggregate ownership. Saving events with a token
+// that is older than the last token used for the aggregate fails with
+// ErrStaleFencingToken, even if the version is correct. This protects against
+// two writers that both think they own an aggregate during a failover.
+// Saving events without a token does not check or update the fence.
+func NewContextWithFencingToken(ctx context.Context, token int64) context.Context {
+	return context.WithValue(ctx, fencingTokenKey, token)
+}
+
+// FencingTokenFromContext returns the fencing token from the context.
+func FencingTokenFromContext(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fencingTokenKey).(int64)
+
+	return token, ok
+}
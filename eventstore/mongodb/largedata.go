@@ -0,0 +1,144 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WithLargeDataInGridFS stores the data of events that is larger than
+// threshold bytes in GridFS instead of in the aggregate document, which keeps
+// events with large binary data from filling up the document size limit. The
+// event keeps a reference to the data in the "data_ref" field, and the data is
+// loaded transparently with the event. The GridFS bucket is named after the
+// events collection with a "_data" suffix.
+//
+// Note that the externalized data can not be queried in MongoDB, and that data
+// uploaded for events that are not saved, for example because of a version
+// conflict or an aborted WithTransaction, and data of replaced events is
+// removed on a best effort basis. Events saved in a transaction of another
+// store sharing the session are not tracked, their data is kept if aborted.
+func WithLargeDataInGridFS(threshold int) Option {
+	return func(s *EventStore) error {
+		if threshold < 1 {
+			return fmt.Errorf("invalid large data threshold: %d", threshold)
+		}
+
+		s.largeDataThreshold = threshold
+
+		return nil
+	}
+}
+
+// dataBucket returns a GridFS bucket for the externalized event data, with the
+// deadline of the context. A new bucket is used for each operation as the
+// deadlines are set on the bucket.
+func (s *EventStore) dataBucket(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(s.db,
+		mongoOptions.GridFSBucket().SetName(s.aggregates.Name()+"_data"))
+	if err != nil {
+		return nil, fmt.Errorf("could not create GridFS bucket: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := bucket.SetReadDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("could not set read deadline: %w", err)
+		}
+
+		if err := bucket.SetWriteDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("could not set write deadline: %w", err)
+		}
+	}
+
+	return bucket, nil
+}
+
+// externalizeData uploads the data of the events larger than the threshold to
+// GridFS and replaces it with a reference. Returns the IDs of the uploaded
+// files, also when failing to be able to remove them.
+func (s *EventStore) externalizeData(ctx context.Context, events []evt) ([]primitive.ObjectID, error) {
+	if s.largeDataThreshold == 0 {
+		return nil, nil
+	}
+
+	var (
+		bucket *gridfs.Bucket
+		ids    []primitive.ObjectID
+	)
+
+	for i := range events {
+		e := &events[i]
+		if len(e.RawData) <= s.largeDataThreshold {
+			continue
+		}
+
+		if bucket == nil {
+			var err error
+			if bucket, err = s.dataBucket(ctx); err != nil {
+				return ids, err
+			}
+		}
+
+		name := fmt.Sprintf("%s/%d", e.AggregateID, e.Version)
+
+		id, err := bucket.UploadFromStream(name, bytes.NewReader(e.RawData))
+		if err != nil {
+			return ids, fmt.Errorf("could not upload event data: %w", err)
+		}
+
+		ids = append(ids, id)
+		e.RawData = nil
+		e.DataRef = &id
+	}
+
+	return ids, nil
+}
+
+// removeData removes uploaded event data, ignoring any errors.
+func (s *EventStore) removeData(ctx context.Context, ids []primitive.ObjectID) {
+	if len(ids) == 0 {
+		return
+	}
+
+	bucket, err := s.dataBucket(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, id := range ids {
+		_ = bucket.Delete(id)
+	}
+}
+
+// loadData loads the externalized data of an event.
+func (s *EventStore) loadData(ctx context.Context, id primitive.ObjectID) ([]byte, error) {
+	bucket, err := s.dataBucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := bucket.DownloadToStream(id, &buf); err != nil {
+		return nil, fmt.Errorf("could not download event data: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// transaction keeps the events saved by a store to handle after the commit,
+// and the IDs of the event data uploaded to GridFS to remove if the
+// transaction is aborted.
+type transaction struct {
+	store   *EventStore
+	events  []eh.Event
+	dataIDs []primitive.ObjectID
+}
+
+// WithTransaction implements the WithTransaction method of the
+// eventhorizon.EventStoreTransactor interface.
+//
+// All events saved with the context passed to f are saved in one MongoDB
+// transaction, which also includes operations of other stores using the same
+// client, for example a repo/mongodb read repository. The events are handled by
+// the event handler (set with WithEventHandler) after the commit. Calls with a
+// context that already has a session are run in that session.
+//
+// Event data stored in GridFS (see WithLargeDataInGridFS) is uploaded outside
+// of the transaction, and is removed if the transaction is aborted or retried.
+func (s *EventStore) WithTransaction(ctx context.Context, f func(context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return f(ctx)
+	}
+
+	sess, err := s.client.StartSession(nil)
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %w", err)
+	}
+
+	defer sess.EndSession(ctx)
+
+	var (
+		tx *transaction
+		// Event data uploaded in aborted tries of the transaction.
+		abortedDataIDs []primitive.ObjectID
+	)
+
+	if _, err := sess.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+		// Reset the events if the transaction is retried.
+		if tx != nil {
+			abortedDataIDs = append(abortedDataIDs, tx.dataIDs...)
+		}
+
+		tx = &transaction{store: s}
+
+		return nil, f(mongo.NewSessionContext(context.WithValue(txCtx, transactionKey, tx), txCtx))
+	}); err != nil {
+		// Keep the data if the transaction may have been committed.
+		var serverErr mongo.ServerError
+		if tx != nil && !(errors.As(err, &serverErr) &&
+			serverErr.HasErrorLabel("UnknownTransactionCommitResult")) {
+			abortedDataIDs = append(abortedDataIDs, tx.dataIDs...)
+		}
+
+		s.removeData(ctx, abortedDataIDs)
+
+		return err
+	}
+
+	s.removeData(ctx, abortedDataIDs)
+
+	// Let the optional event handler handle the committed events.
+	if s.eventHandlerAfterSave != nil {
+		for _, e := range tx.events {
+			if err := s.eventHandlerAfterSave.HandleEvent(ctx, e); err != nil {
+				return &eh.EventHandlerError{
+					Err:   err,
+					Event: e,
+				}
+			}
+		}
+	}
+
+	return nil
+}
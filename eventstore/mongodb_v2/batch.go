@@ -0,0 +1,338 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongoOptions "go.mongodb.org/mongo-driver/mongo/options"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// SaveBatch implements the SaveBatch method of the
+// eventhorizon.EventStoreBatchSaver interface.
+//
+// The batch is saved in one transaction with a fixed number of operations,
+// independent of the number of aggregates: the versions of all aggregates are
+// checked with one query, and all events and streams are written with one bulk
+// write each. Aggregates are saved in the order of the batch, with the global
+// positions of their events in that order.
+func (s *EventStore) SaveBatch(ctx context.Context, batch []eh.EventBatch, options ...eh.BatchSaveOption) ([]error, error) {
+	config := eh.NewBatchSaveConfig(options...)
+	results := make([]error, len(batch))
+
+	// Build the event records of all aggregates up front.
+	records := make([][]interface{}, len(batch))
+	seen := map[uuid.UUID]struct{}{}
+
+	for i, b := range batch {
+		dbEvents, err := s.newEvts(ctx, b.Events, b.OriginalVersion)
+		if err != nil {
+			results[i] = err
+
+			continue
+		}
+
+		id := b.Events[0].AggregateID()
+		if _, ok := seen[id]; ok {
+			results[i] = batchError(eh.ErrAggregateAlreadyInBatch, b)
+
+			continue
+		}
+
+		seen[id] = struct{}{}
+		records[i] = dbEvents
+	}
+
+	if config.Atomic && hasErrors(results) {
+		return abortBatch(results), eh.ErrBatchAborted
+	}
+
+	var saved []error
+
+	saveBatch := func(txCtx mongo.SessionContext) error {
+		// Reset the results if the transaction is retried.
+		saved = make([]error, len(results))
+		copy(saved, results)
+
+		if err := s.checkBatchVersions(txCtx, batch, saved); err != nil {
+			return err
+		}
+
+		if config.Atomic && hasErrors(saved) {
+			return eh.ErrBatchAborted
+		}
+
+		return s.saveBatch(txCtx, batch, records, saved)
+	}
+
+	// Run the operation in the transaction of WithTransaction if used,
+	// otherwise in a new transaction.
+	if sess := mongo.SessionFromContext(ctx); sess != nil {
+		if err := saveBatch(mongo.NewSessionContext(ctx, sess)); err != nil {
+			return batchFailed(saved, err)
+		}
+	} else {
+		sess, err := s.client.StartSession(nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not start transaction: %w", err)
+		}
+
+		defer sess.EndSession(ctx)
+
+		if _, err := sess.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+			return nil, saveBatch(txCtx)
+		}); err != nil {
+			return batchFailed(saved, err)
+		}
+	}
+
+	for i, b := range batch {
+		if saved[i] != nil {
+			continue
+		}
+
+		// Handle the events after the commit when saved using WithTransaction.
+		if tx, ok := ctx.Value(transactionKey).(*transaction); ok && tx.store == s {
+			tx.events = append(tx.events, b.Events...)
+
+			continue
+		}
+
+		// Let the optional event handler handle the events.
+		if s.eventHandlerAfterSave != nil {
+			for _, e := range b.Events {
+				if err := s.eventHandlerAfterSave.HandleEvent(ctx, e); err != nil {
+					saved[i] = &eh.EventHandlerError{
+						Err:   err,
+						Event: e,
+					}
+
+					break
+				}
+			}
+		}
+	}
+
+	return saved, nil
+}
+
+// checkBatchVersions checks the current versions of all aggregates of the batch
+// without errors, setting a conflict error for aggregates with other versions.
+func (s *EventStore) checkBatchVersions(ctx context.Context, batch []eh.EventBatch, results []error) error {
+	var ids []uuid.UUID
+
+	for i, b := range batch {
+		if results[i] == nil {
+			ids = append(ids, b.Events[0].AggregateID())
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	cursor, err := s.streams.Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		mongoOptions.Find().SetProjection(bson.M{"version": 1}),
+	)
+	if err != nil {
+		return fmt.Errorf("could not find streams: %w", err)
+	}
+
+	var streams []stream
+	if err := cursor.All(ctx, &streams); err != nil {
+		return fmt.Errorf("could not decode streams: %w", err)
+	}
+
+	versions := make(map[uuid.UUID]int, len(streams))
+	for _, strm := range streams {
+		versions[strm.ID] = strm.Version
+	}
+
+	for i, b := range batch {
+		if results[i] != nil {
+			continue
+		}
+
+		if versions[b.Events[0].AggregateID()] != b.OriginalVersion {
+			results[i] = batchError(eh.ErrEventConflictFromOtherSave, b)
+		}
+	}
+
+	return nil
+}
+
+// saveBatch saves the events and streams of all aggregates of the batch
+// without errors.
+func (s *EventStore) saveBatch(ctx context.Context, batch []eh.EventBatch, records [][]interface{}, results []error) error {
+	var count int
+
+	for i := range batch {
+		if results[i] == nil {
+			count += len(records[i])
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	// Fetch and increment global version in the all-stream.
+	r := s.streams.FindOneAndUpdate(ctx,
+		bson.M{"_id": "$all"},
+		bson.M{"$inc": bson.M{"position": count}},
+	)
+	if r.Err() != nil {
+		return fmt.Errorf("could not increment global position: %w", r.Err())
+	}
+
+	allStream := struct {
+		Position int
+	}{}
+	if err := r.Decode(&allStream); err != nil {
+		return fmt.Errorf("could not decode global position: %w", err)
+	}
+
+	var (
+		dbEvents      []interface{}
+		streamWrites  []mongo.WriteModel
+		inserts       int
+		position      = allStream.Position
+		handledEvents []eh.Event
+	)
+
+	for i, b := range batch {
+		if results[i] != nil {
+			continue
+		}
+
+		var last *evt
+
+		for _, e := range records[i] {
+			event, ok := e.(*evt)
+			if !ok {
+				return fmt.Errorf("event is of incorrect type %T", e)
+			}
+
+			position++
+			event.Position = position
+			// Also store the position in the event metadata.
+			event.Metadata["position"] = event.Position
+			last = event
+		}
+
+		dbEvents = append(dbEvents, records[i]...)
+		handledEvents = append(handledEvents, b.Events...)
+
+		if b.OriginalVersion == 0 {
+			streamWrites = append(streamWrites, mongo.NewInsertOneModel().SetDocument(&stream{
+				ID:            last.AggregateID,
+				Position:      last.Position,
+				AggregateType: last.AggregateType,
+				Version:       last.Version,
+				UpdatedAt:     last.Timestamp,
+			}))
+			inserts++
+		} else {
+			streamWrites = append(streamWrites, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{
+					"_id":     last.AggregateID,
+					"version": b.OriginalVersion,
+				}).
+				SetUpdate(bson.M{
+					"$set": bson.M{
+						"position":   last.Position,
+						"updated_at": last.Timestamp,
+					},
+					"$inc": bson.M{"version": len(records[i])},
+				}))
+		}
+	}
+
+	// Store events.
+	if _, err := s.events.InsertMany(ctx, dbEvents); err != nil {
+		return fmt.Errorf("could not insert events: %w", err)
+	}
+
+	// Update the streams, a stream that is not matched has been changed
+	// since the versions were checked.
+	res, err := s.streams.BulkWrite(ctx, streamWrites)
+	if err != nil {
+		return fmt.Errorf("could not write streams: %w", err)
+	}
+
+	if int(res.InsertedCount) != inserts || int(res.MatchedCount) != len(streamWrites)-inserts {
+		return eh.ErrEventConflictFromOtherSave
+	}
+
+	if s.eventHandlerInTX != nil {
+		for _, e := range handledEvents {
+			if err := s.eventHandlerInTX.HandleEvent(ctx, e); err != nil {
+				return fmt.Errorf("could not handle event in transaction: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// batchError creates an event store error for an aggregate of a batch.
+func batchError(err error, b eh.EventBatch) error {
+	return &eh.EventStoreError{
+		Err:              err,
+		Op:               eh.EventStoreOpSave,
+		AggregateType:    b.Events[0].AggregateType(),
+		AggregateID:      b.Events[0].AggregateID(),
+		AggregateVersion: b.OriginalVersion,
+		Events:           b.Events,
+	}
+}
+
+// batchFailed returns the results of a failed batch.
+func batchFailed(results []error, err error) ([]error, error) {
+	if errors.Is(err, eh.ErrBatchAborted) {
+		return abortBatch(results), eh.ErrBatchAborted
+	}
+
+	return nil, fmt.Errorf("could not save batch: %w", err)
+}
+
+// abortBatch sets the results of all aggregates without errors to aborted.
+func abortBatch(results []error) []error {
+	for i, err := range results {
+		if err == nil {
+			results[i] = eh.ErrBatchAborted
+		}
+	}
+
+	return results
+}
+
+func hasErrors(results []error) bool {
+	for _, err := range results {
+		if err != nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/looplab/eventhorizon/mongoutils"
@@ -43,6 +44,12 @@ import (
 // EventStore is an eventhorizon.EventStore for MongoDB, using one collection
 // for all events and another to keep track of all aggregates/streams. It also
 // keeps track of the global position of events, stored as metadata.
+//
+// The event data is stored as a nested BSON document in the "data" field of
+// each event, not as opaque bytes, which makes it possible to query and index
+// data fields directly in MongoDB, for example {"data.status": "failed"}.
+// Fields use the BSON names of the event data (lowercased field names unless
+// tagged).
 type EventStore struct {
 	client                  *mongo.Client
 	clientOwnership         clientOwnership
@@ -52,6 +59,7 @@ type EventStore struct {
 	eventHandlerAfterSave   eh.EventHandler
 	eventHandlerInTX        eh.EventHandler
 	skipNonRegisteredEvents bool
+	metadataIndexes         []string
 }
 
 type clientOwnership int
@@ -119,6 +127,26 @@ func newEventStoreWithClient(client *mongo.Client, clientOwnership clientOwnersh
 		return nil, fmt.Errorf("could not ensure events index: %w", err)
 	}
 
+	// Used to load the most recent events of aggregates, see LoadReverse.
+	if _, err := s.events.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "aggregate_id", Value: 1},
+			{Key: "version", Value: -1},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("could not ensure events version index: %w", err)
+	}
+
+	if _, err := s.events.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "timestamp", Value: 1},
+			{Key: "aggregate_id", Value: 1},
+			{Key: "version", Value: 1},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("could not ensure events timestamp index: %w", err)
+	}
+
 	if _, err := s.snapshots.Indexes().CreateOne(ctx, mongo.IndexModel{
 		Keys: bson.M{"aggregate_id": 1},
 	}); err != nil {
@@ -131,6 +159,14 @@ func newEventStoreWithClient(client *mongo.Client, clientOwnership clientOwnersh
 		return nil, fmt.Errorf("could not ensure snapshot version index: %w", err)
 	}
 
+	for _, key := range s.metadataIndexes {
+		if _, err := s.events.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.M{"metadata." + key: 1},
+		}); err != nil {
+			return nil, fmt.Errorf("could not ensure events metadata index for '%s': %w", key, err)
+		}
+	}
+
 	// Make sure the $all stream exists.
 	if err := s.streams.FindOne(ctx, bson.M{
 		"_id": "$all",
@@ -223,95 +259,47 @@ func WithSnapshotCollectionName(snapshotColl string) Option {
 	}
 }
 
-// Save implements the Save method of the eventhorizon.EventStore interface.
-func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
-	if len(events) == 0 {
-		return &eh.EventStoreError{
-			Err: eh.ErrMissingEvents,
-			Op:  eh.EventStoreOpSave,
-		}
-	}
-
-	dbEvents := make([]interface{}, len(events))
-	id := events[0].AggregateID()
-	at := events[0].AggregateType()
-
-	// Build all event records, with incrementing versions starting from the
-	// original aggregate version.
-	for i, event := range events {
-		// Only accept events belonging to the same aggregate.
-		if event.AggregateID() != id {
-			return &eh.EventStoreError{
-				Err:              eh.ErrMismatchedEventAggregateIDs,
-				Op:               eh.EventStoreOpSave,
-				AggregateType:    at,
-				AggregateID:      id,
-				AggregateVersion: originalVersion,
-				Events:           events,
-			}
-		}
-
-		if event.AggregateType() != at {
-			return &eh.EventStoreError{
-				Err:              eh.ErrMismatchedEventAggregateTypes,
-				Op:               eh.EventStoreOpSave,
-				AggregateType:    at,
-				AggregateID:      id,
-				AggregateVersion: originalVersion,
-				Events:           events,
-			}
-		}
-
-		// Only accept events that apply to the correct aggregate version.
-		if event.Version() != originalVersion+i+1 {
-			return &eh.EventStoreError{
-				Err:              eh.ErrIncorrectEventVersion,
-				Op:               eh.EventStoreOpSave,
-				AggregateType:    at,
-				AggregateID:      id,
-				AggregateVersion: originalVersion,
-				Events:           events,
+// WithMetadataIndexes creates indexes for metadata keys of events, used to find
+// events by metadata with FindByMetadata.
+func WithMetadataIndexes(keys ...string) Option {
+	return func(s *EventStore) error {
+		for _, key := range keys {
+			if key == "" || strings.ContainsAny(key, ".$") {
+				return fmt.Errorf("invalid metadata index key: '%s'", key)
 			}
 		}
 
-		// Create the event record for the DB.
-		e, err := newEvt(ctx, event)
-		if err != nil {
-			return err
-		}
+		s.metadataIndexes = append(s.metadataIndexes, keys...)
 
-		dbEvents[i] = e
+		return nil
 	}
+}
 
-	sess, err := s.client.StartSession(nil)
+// Save implements the Save method of the eventhorizon.EventStore interface.
+func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	dbEvents, err := s.newEvts(ctx, events, originalVersion)
 	if err != nil {
-		return &eh.EventStoreError{
-			Err:              fmt.Errorf("could not start transaction: %w", err),
-			Op:               eh.EventStoreOpSave,
-			AggregateType:    at,
-			AggregateID:      id,
-			AggregateVersion: originalVersion,
-			Events:           events,
-		}
+		return err
 	}
 
-	defer sess.EndSession(ctx)
+	id := events[0].AggregateID()
+	at := events[0].AggregateType()
 
-	if _, err := sess.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+	saveEvents := func(txCtx mongo.SessionContext) error {
 		// Fetch and increment global version in the all-stream.
 		r := s.streams.FindOneAndUpdate(txCtx,
 			bson.M{"_id": "$all"},
 			bson.M{"$inc": bson.M{"position": len(dbEvents)}},
 		)
 		if r.Err() != nil {
-			return nil, fmt.Errorf("could not increment global position: %w", r.Err())
+			return fmt.Errorf("could not increment global position: %w", r.Err())
 		}
 
 		allStream := struct {
 			Position int
 		}{}
 		if err := r.Decode(&allStream); err != nil {
-			return nil, fmt.Errorf("could not decode global position: %w", err)
+			return fmt.Errorf("could not decode global position: %w", err)
 		}
 
 		// Use the global position as ID for the stored events.
@@ -320,7 +308,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		for i, e := range dbEvents {
 			event, ok := e.(*evt)
 			if !ok {
-				return nil, fmt.Errorf("event is of incorrect type %T", e)
+				return fmt.Errorf("event is of incorrect type %T", e)
 			}
 
 			event.Position = allStream.Position + i + 1
@@ -342,7 +330,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		// Store events.
 		insert, err := s.events.InsertMany(txCtx, dbEvents)
 		if err != nil {
-			return nil, fmt.Errorf("could not insert events: %w", err)
+			return fmt.Errorf("could not insert events: %w", err)
 		}
 
 		// Check that all inserted events got the requested ID (position),
@@ -350,7 +338,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		for _, e := range dbEvents {
 			event, ok := e.(*evt)
 			if !ok {
-				return nil, fmt.Errorf("event is of incorrect type %T", e)
+				return fmt.Errorf("event is of incorrect type %T", e)
 			}
 
 			found := false
@@ -363,7 +351,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 			}
 
 			if !found {
-				return nil, fmt.Errorf("inserted event %s at pos %d not found",
+				return fmt.Errorf("inserted event %s at pos %d not found",
 					event.AggregateID, event.Position)
 			}
 		}
@@ -371,7 +359,7 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 		// Update the stream.
 		if originalVersion == 0 {
 			if _, err := s.streams.InsertOne(txCtx, strm); err != nil {
-				return nil, fmt.Errorf("could not insert stream: %w", err)
+				return fmt.Errorf("could not insert stream: %w", err)
 			}
 		} else {
 			if r, err := s.streams.UpdateOne(txCtx,
@@ -387,32 +375,72 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 					"$inc": bson.M{"version": len(dbEvents)},
 				},
 			); err != nil {
-				return nil, fmt.Errorf("could not update stream: %w", err)
+				return fmt.Errorf("could not update stream: %w", err)
 			} else if r.MatchedCount == 0 {
-				return nil, eh.ErrEventConflictFromOtherSave
+				return eh.ErrEventConflictFromOtherSave
 			}
 		}
 
 		if s.eventHandlerInTX != nil {
 			for _, e := range events {
 				if err := s.eventHandlerInTX.HandleEvent(txCtx, e); err != nil {
-					return nil, fmt.Errorf("could not handle event in transaction: %w", err)
+					return fmt.Errorf("could not handle event in transaction: %w", err)
 				}
 			}
 		}
 
-		return nil, nil
-	}); err != nil {
-		return &eh.EventStoreError{
-			Err:              err,
-			Op:               eh.EventStoreOpSave,
-			AggregateType:    at,
-			AggregateID:      id,
-			AggregateVersion: originalVersion,
-			Events:           events,
+		return nil
+	}
+
+	// Run the operation in the transaction of WithTransaction if used,
+	// otherwise in a new transaction.
+	if sess := mongo.SessionFromContext(ctx); sess != nil {
+		if err := saveEvents(mongo.NewSessionContext(ctx, sess)); err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+	} else {
+		sess, err := s.client.StartSession(nil)
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:              fmt.Errorf("could not start transaction: %w", err),
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		defer sess.EndSession(ctx)
+
+		if _, err := sess.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+			return nil, saveEvents(txCtx)
+		}); err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
 		}
 	}
 
+	// Handle the events after the commit when saved using WithTransaction.
+	if tx, ok := ctx.Value(transactionKey).(*transaction); ok && tx.store == s {
+		tx.events = append(tx.events, events...)
+
+		return nil
+	}
+
 	// Let the optional event handler handle the events.
 	if s.eventHandlerAfterSave != nil {
 		for _, e := range events {
@@ -428,9 +456,107 @@ func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersio
 	return nil
 }
 
+// newEvts checks that there are events for the same aggregate with incrementing
+// versions from the original version, and creates the event records.
+func (s *EventStore) newEvts(ctx context.Context, events []eh.Event, originalVersion int) ([]interface{}, error) {
+	if len(events) == 0 {
+		return nil, &eh.EventStoreError{
+			Err: eh.ErrMissingEvents,
+			Op:  eh.EventStoreOpSave,
+		}
+	}
+
+	dbEvents := make([]interface{}, len(events))
+	id := events[0].AggregateID()
+	at := events[0].AggregateType()
+
+	// Build all event records, with incrementing versions starting from the
+	// original aggregate version.
+	for i, event := range events {
+		// Only accept events belonging to the same aggregate.
+		if event.AggregateID() != id {
+			return nil, &eh.EventStoreError{
+				Err:              eh.ErrMismatchedEventAggregateIDs,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		if event.AggregateType() != at {
+			return nil, &eh.EventStoreError{
+				Err:              eh.ErrMismatchedEventAggregateTypes,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		// Only accept events that apply to the correct aggregate version.
+		if event.Version() != originalVersion+i+1 {
+			return nil, &eh.EventStoreError{
+				Err:              eh.ErrIncorrectEventVersion,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		// Create the event record for the DB.
+		e, err := newEvt(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+
+		// Check the size of the event up front, as each event is a document.
+		b, err := bson.Marshal(e)
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              fmt.Errorf("could not marshal event: %w", err),
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		if err := mongoutils.CheckDocumentSize(id, len(b)); err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpSave,
+				AggregateType:    at,
+				AggregateID:      id,
+				AggregateVersion: originalVersion,
+				Events:           events,
+			}
+		}
+
+		dbEvents[i] = e
+	}
+
+	return dbEvents, nil
+}
+
 // Load implements the Load method of the eventhorizon.EventStore interface.
+// The read preference can be set with NewContextWithReadPreference.
 func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
-	cursor, err := s.events.Find(ctx, bson.M{"aggregate_id": id})
+	events, err := readCollection(ctx, s.events)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("could not set read preference: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	cursor, err := events.Find(ctx, bson.M{"aggregate_id": id})
 	if err != nil {
 		return nil, &eh.EventStoreError{
 			Err:         fmt.Errorf("could not find event: %w", err),
@@ -443,8 +569,52 @@ func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error)
 }
 
 // LoadFrom implements LoadFrom method of the eventhorizon.SnapshotStore interface.
+// The read preference can be set with NewContextWithReadPreference.
 func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
-	cursor, err := s.events.Find(ctx, bson.M{"aggregate_id": id, "version": bson.M{"$gte": version}})
+	events, err := readCollection(ctx, s.events)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("could not set read preference: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	cursor, err := events.Find(ctx, bson.M{"aggregate_id": id, "version": bson.M{"$gte": version}})
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("could not find event: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	return s.loadFromCursor(ctx, id, cursor)
+}
+
+// LoadReverse implements the LoadReverse method of the
+// eventhorizon.EventStoreReverseLoader interface, using a descending query on
+// the aggregate ID and version index. The read preference can be set with
+// NewContextWithReadPreference.
+func (s *EventStore) LoadReverse(ctx context.Context, id uuid.UUID, limit int) ([]eh.Event, error) {
+	events, err := readCollection(ctx, s.events)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("could not set read preference: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	opts := options.Find().SetSort(bson.D{
+		{Key: "aggregate_id", Value: 1},
+		{Key: "version", Value: -1},
+	})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := events.Find(ctx, bson.M{"aggregate_id": id}, opts)
 	if err != nil {
 		return nil, &eh.EventStoreError{
 			Err:         fmt.Errorf("could not find event: %w", err),
@@ -456,6 +626,98 @@ func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([
 	return s.loadFromCursor(ctx, id, cursor)
 }
 
+// LoadInBatches implements the LoadInBatches method of the
+// eventhorizon.EventStoreBatchLoader interface, using the batch size for the
+// cursor to only keep a batch of events in memory at a time. The read
+// preference can be set with NewContextWithReadPreference.
+func (s *EventStore) LoadInBatches(ctx context.Context, id uuid.UUID, version, batchSize int, f func(ctx context.Context, events []eh.Event) error) error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	events, err := readCollection(ctx, s.events)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("could not set read preference: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	cursor, err := events.Find(ctx,
+		bson.M{"aggregate_id": id, "version": bson.M{"$gte": version}},
+		options.Find().
+			SetSort(bson.M{"version": 1}).
+			SetBatchSize(int32(batchSize)),
+	)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("could not find event: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+	defer cursor.Close(ctx)
+
+	batch := make([]eh.Event, 0, batchSize)
+	loaded := 0
+
+	for cursor.Next(ctx) {
+		var e evt
+		if err := cursor.Decode(&e); err != nil {
+			return &eh.EventStoreError{
+				Err:         fmt.Errorf("could not decode event: %w", err),
+				Op:          eh.EventStoreOpLoad,
+				AggregateID: id,
+			}
+		}
+
+		event, err := e.event()
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      id,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		batch = append(batch, event)
+		loaded++
+
+		if len(batch) == batchSize {
+			if err := f(ctx, batch); err != nil {
+				return err
+			}
+
+			batch = make([]eh.Event, 0, batchSize)
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("could not load events: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	if loaded == 0 {
+		return &eh.EventStoreError{
+			Err:         eh.ErrAggregateNotFound,
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	if len(batch) > 0 {
+		return f(ctx, batch)
+	}
+
+	return nil
+}
+
 func (s *EventStore) loadFromCursor(ctx context.Context, id uuid.UUID, cursor *mongo.Cursor) ([]eh.Event, error) {
 	var events []eh.Event
 
@@ -470,45 +732,18 @@ func (s *EventStore) loadFromCursor(ctx context.Context, id uuid.UUID, cursor *m
 			}
 		}
 
-		// Create an event of the correct type and decode from raw BSON.
-		if len(e.RawData) > 0 {
-			var err error
-			if e.data, err = eh.CreateEventData(e.EventType); err != nil {
-				return nil, &eh.EventStoreError{
-					Err:              fmt.Errorf("could not create event data: %w", err),
-					Op:               eh.EventStoreOpLoad,
-					AggregateType:    e.AggregateType,
-					AggregateID:      id,
-					AggregateVersion: e.Version,
-					Events:           events,
-				}
-			}
-
-			if err := bson.Unmarshal(e.RawData, e.data); err != nil {
-				return nil, &eh.EventStoreError{
-					Err:              fmt.Errorf("could not unmarshal event data: %w", err),
-					Op:               eh.EventStoreOpLoad,
-					AggregateType:    e.AggregateType,
-					AggregateID:      id,
-					AggregateVersion: e.Version,
-					Events:           events,
-				}
+		event, err := e.event()
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      id,
+				AggregateVersion: e.Version,
+				Events:           events,
 			}
-
-			e.RawData = nil
 		}
 
-		event := eh.NewEvent(
-			e.EventType,
-			e.data,
-			e.Timestamp,
-			eh.ForAggregate(
-				e.AggregateType,
-				e.AggregateID,
-				e.Version,
-			),
-			eh.WithMetadata(e.Metadata),
-		)
 		events = append(events, event)
 	}
 
@@ -523,6 +758,161 @@ func (s *EventStore) loadFromCursor(ctx context.Context, id uuid.UUID, cursor *m
 	return events, nil
 }
 
+// FindByMetadata implements the FindByMetadata method of the
+// eventhorizon.EventStoreMetadataFinder interface. The metadata key should be
+// indexed with WithMetadataIndexes, or all events will be scanned.
+func (s *EventStore) FindByMetadata(ctx context.Context, key string, value interface{}) ([]eh.Event, error) {
+	cursor, err := s.events.Find(ctx,
+		bson.M{"metadata." + key: value},
+		options.Find().SetSort(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not find events: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+	defer cursor.Close(ctx)
+
+	events := []eh.Event{}
+
+	for cursor.Next(ctx) {
+		var e evt
+		if err := cursor.Decode(&e); err != nil {
+			return nil, &eh.EventStoreError{
+				Err: fmt.Errorf("could not decode event: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		event, err := e.event()
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      e.AggregateID,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not find events: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return events, nil
+}
+
+// StreamAll implements the StreamAll method of the eventhorizon.EventStoreStreamer
+// interface. Events are streamed in the order of their global position.
+func (s *EventStore) StreamAll(ctx context.Context, from int, f func(ctx context.Context, position int, event eh.Event) error) error {
+	cursor, err := s.events.Find(ctx,
+		bson.M{"_id": bson.M{"$gt": from}},
+		options.Find().SetSort(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not find events: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var e evt
+		if err := cursor.Decode(&e); err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not decode event: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		event, err := e.event()
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      e.AggregateID,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		if err := f(ctx, e.Position, event); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not stream events: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return nil
+}
+
+// StreamAllByTime implements the StreamAllByTime method of the
+// eventhorizon.EventStoreTimeStreamer interface, sorting the events by
+// timestamp, aggregate ID and version on the server.
+func (s *EventStore) StreamAllByTime(ctx context.Context, f func(ctx context.Context, event eh.Event) error) error {
+	cursor, err := s.events.Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{
+			{Key: "timestamp", Value: 1},
+			{Key: "aggregate_id", Value: 1},
+			{Key: "version", Value: 1},
+		}),
+	)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not find events: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var e evt
+		if err := cursor.Decode(&e); err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not decode event: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		event, err := e.event()
+		if err != nil {
+			return &eh.EventStoreError{
+				Err:              err,
+				Op:               eh.EventStoreOpLoad,
+				AggregateType:    e.AggregateType,
+				AggregateID:      e.AggregateID,
+				AggregateVersion: e.Version,
+			}
+		}
+
+		if err := f(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not stream events: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return nil
+}
+
 func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
 	result := s.snapshots.FindOne(ctx, bson.M{"aggregate_id": id}, options.FindOne().SetSort(bson.M{"version": -1}))
 	if err := result.Err(); err != nil {
@@ -626,6 +1016,92 @@ func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh
 	return nil
 }
 
+// AggregateIDs implements the AggregateIDs method of the
+// eventhorizon.EventStoreAggregateLister interface, using a distinct query.
+func (s *EventStore) AggregateIDs(ctx context.Context, aggregateType eh.AggregateType) ([]uuid.UUID, error) {
+	values, err := s.events.Distinct(ctx, "aggregate_id", bson.M{"aggregate_type": aggregateType})
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err: fmt.Errorf("could not find aggregate IDs: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(values))
+
+	for _, v := range values {
+		// The IDs are stored as strings by the BSON codec.
+		str, ok := v.(string)
+		if !ok {
+			return nil, &eh.EventStoreError{
+				Err: fmt.Errorf("aggregate ID is of incorrect type %T", v),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		id, err := uuid.Parse(str)
+		if err != nil {
+			return nil, &eh.EventStoreError{
+				Err: fmt.Errorf("could not parse aggregate ID: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// StreamAggregateIDs implements the StreamAggregateIDs method of the
+// eventhorizon.EventStoreAggregateLister interface, using a cursor over the
+// streams.
+func (s *EventStore) StreamAggregateIDs(ctx context.Context, aggregateType eh.AggregateType, f func(ctx context.Context, id uuid.UUID) error) error {
+	cursor, err := s.streams.Find(ctx,
+		bson.M{"aggregate_type": aggregateType},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not find aggregate IDs: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var r struct {
+			ID uuid.UUID `bson:"_id"`
+		}
+		if err := cursor.Decode(&r); err != nil {
+			return &eh.EventStoreError{
+				Err: fmt.Errorf("could not decode aggregate ID: %w", err),
+				Op:  eh.EventStoreOpLoad,
+			}
+		}
+
+		if err := f(ctx, r.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return &eh.EventStoreError{
+			Err: fmt.Errorf("could not find aggregate IDs: %w", err),
+			Op:  eh.EventStoreOpLoad,
+		}
+	}
+
+	return nil
+}
+
+// OrderingGuarantee implements the OrderingGuarantee method of the
+// eventhorizon.OrderingReporter interface. All events are streamed in the
+// order of their global position.
+func (s *EventStore) OrderingGuarantee() eh.OrderingGuarantee {
+	return eh.GlobalOrdering
+}
+
 // Close implements the Close method of the eventhorizon.EventStore interface.
 func (s *EventStore) Close() error {
 	if s.clientOwnership == externalClient {
@@ -701,6 +1177,35 @@ type evt struct {
 	Metadata      map[string]interface{} `bson:"metadata"`
 }
 
+// event creates an event from the evt, decoding the event data from raw BSON.
+func (e *evt) event() (eh.Event, error) {
+	// Create an event of the correct type and decode from raw BSON.
+	if len(e.RawData) > 0 {
+		var err error
+		if e.data, err = eh.CreateEventData(e.EventType); err != nil {
+			return nil, fmt.Errorf("could not create event data: %w", err)
+		}
+
+		if err := bson.Unmarshal(e.RawData, e.data); err != nil {
+			return nil, fmt.Errorf("could not unmarshal event data: %w", err)
+		}
+
+		e.RawData = nil
+	}
+
+	return eh.NewEvent(
+		eh.ResolveEventType(e.EventType),
+		e.data,
+		e.Timestamp,
+		eh.ForAggregate(
+			e.AggregateType,
+			e.AggregateID,
+			e.Version,
+		),
+		eh.WithMetadata(e.Metadata),
+	), nil
+}
+
 // newEvt returns a new evt for an event.
 func newEvt(_ context.Context, event eh.Event) (*evt, error) {
 	e := &evt{
@@ -18,14 +18,20 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/eventstore"
+	"github.com/looplab/eventhorizon/eventstore/storetest"
 	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/mongoutils"
 	"github.com/looplab/eventhorizon/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestEventStoreIntegration(t *testing.T) {
@@ -51,7 +57,7 @@ func TestEventStoreIntegration(t *testing.T) {
 
 	t.Log("using DB:", db)
 
-	store, err := NewEventStore(url, db)
+	store, err := NewEventStore(url, db, WithMetadataIndexes("correlation_id"))
 	if err != nil {
 		t.Fatal("there should be no error:", err)
 	}
@@ -64,11 +70,117 @@ func TestEventStoreIntegration(t *testing.T) {
 
 	eventstore.SnapshotAcceptanceTest(t, store, context.Background())
 
+	eventstore.StreamAcceptanceTest(t, store, context.Background())
+
+	eventstore.TimeStreamAcceptanceTest(t, store, context.Background())
+
+	eventstore.BatchLoadAcceptanceTest(t, store, context.Background())
+
+	eventstore.MetadataAcceptanceTest(t, store, context.Background())
+
+	eventstore.OrderingAcceptanceTest(t, store, context.Background())
+
+	storetest.Run(t, store, context.Background())
+
+	eventstore.AggregateListerAcceptanceTest(t, store, context.Background())
+
+	eventstore.ReverseLoaderAcceptanceTest(t, store, context.Background())
+
+	eventstore.BatchSaveAcceptanceTest(t, store, context.Background())
+
 	if err := store.Close(); err != nil {
 		t.Error("there should be no error:", err)
 	}
 }
 
+func TestEventStore_DocumentTooLarge(t *testing.T) {
+	// NOTE: The size is checked before using the DB.
+	store := &EventStore{}
+	id := uuid.New()
+
+	// A synthetic event that is larger than the max document size.
+	event := eh.NewEvent(mocks.EventType,
+		&mocks.EventData{Content: strings.Repeat("a", mongoutils.MaxDocumentSize)},
+		time.Now(), eh.ForAggregate(mocks.AggregateType, id, 1))
+
+	err := store.Save(context.Background(), []eh.Event{event}, 0)
+	if !errors.Is(err, mongoutils.ErrDocumentTooLarge) {
+		t.Fatal("there should be a document too large error:", err)
+	}
+
+	var sizeErr *mongoutils.DocumentTooLargeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatal("the error should be a DocumentTooLargeError:", err)
+	}
+
+	if sizeErr.AggregateID != id {
+		t.Error("the error should have the aggregate ID:", sizeErr.AggregateID)
+	}
+
+	if sizeErr.Size <= mongoutils.MaxDocumentSize {
+		t.Error("the error should have the document size:", sizeErr.Size)
+	}
+}
+
+func TestEventDataQueryIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	store, err := NewEventStore(url, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	ctx := context.Background()
+	id1, id2 := uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	failed := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "failed"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 1))
+	if err := store.Save(ctx, []eh.Event{failed}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ok := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "ok"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 1))
+	if err := store.Save(ctx, []eh.Event{ok}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Query into a field of the event data.
+	cursor, err := store.events.Find(ctx, bson.M{"data.content": "failed"})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	var events []evt
+	if err := cursor.All(ctx, &events); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatal("there should be one event:", events)
+	}
+
+	event, err := events[0].event()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := eh.CompareEvents(event, failed, eh.IgnorePositionMetadata()); err != nil {
+		t.Error("the event should be correct:", err)
+	}
+
+	if _, isTyped := event.Data().(*mocks.EventData); !isTyped {
+		t.Error("the event data should be typed:", event.Data())
+	}
+}
+
 func TestWithCollectionNamesIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -182,7 +294,7 @@ func TestWithSnapshotCollectionNamesIntegration(t *testing.T) {
 	}
 }
 
-func makeDB(t *testing.T) (string, string) {
+func makeDB(t testing.TB) (string, string) {
 	// Use MongoDB in Docker with fallback to localhost.
 	url := os.Getenv("MONGODB_ADDR")
 	if url == "" {
@@ -296,6 +408,82 @@ func TestWithEventHandlerIntegration(t *testing.T) {
 	}
 }
 
+func TestFollowIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	store, err := NewEventStore(url, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id1 := uuid.New()
+	id2 := uuid.New()
+
+	events, err := store.Follow(ctx, id1)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := eh.NewEventForAggregate(mocks.EventType, &mocks.EventData{Content: "event1"},
+		timestamp, mocks.AggregateType, id1, 1)
+	other := eh.NewEventForAggregate(mocks.EventType, &mocks.EventData{Content: "other"},
+		timestamp, mocks.AggregateType, id2, 1)
+	event2 := eh.NewEventForAggregate(mocks.EventType, &mocks.EventData{Content: "event2"},
+		timestamp, mocks.AggregateType, id1, 2)
+
+	if err := store.Save(ctx, []eh.Event{event1}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{other}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{event2}, 1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Only the events of the followed aggregate should be received, in order.
+	for _, expected := range []eh.Event{event1, event2} {
+		select {
+		case event := <-events:
+			if err := eh.CompareEvents(event, expected, eh.IgnorePositionMetadata()); err != nil {
+				t.Error("the followed event was incorrect:", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("there should be a followed event")
+		}
+	}
+
+	select {
+	case event := <-events:
+		t.Error("there should be no other followed event:", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The channel should be closed when the context is done.
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("the channel should be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the channel should be closed")
+	}
+}
+
 func BenchmarkEventStore(b *testing.B) {
 	// Use MongoDB in Docker with fallback to localhost.
 	addr := os.Getenv("MONGODB_ADDR")
@@ -328,3 +516,20 @@ func BenchmarkEventStore(b *testing.B) {
 
 	eventstore.Benchmark(b, store)
 }
+
+func BenchmarkSaveBatch(b *testing.B) {
+	url, db := makeDB(b)
+
+	store, err := NewEventStore(url, db)
+	if err != nil {
+		b.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	for _, size := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("batch of %d", size), func(b *testing.B) {
+			eventstore.BatchSaveBenchmark(b, store, size)
+		})
+	}
+}
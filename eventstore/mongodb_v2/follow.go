@@ -0,0 +1,82 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// Follow implements the Follow method of the eventhorizon.EventStoreFollower
+// interface, using a change stream of the events inserted for the aggregate.
+// Change streams require MongoDB to run as a replica set. The change stream is
+// closed when the context is done.
+func (s *EventStore) Follow(ctx context.Context, id uuid.UUID) (<-chan eh.Event, error) {
+	stream, err := s.events.Watch(ctx, mongo.Pipeline{bson.D{{Key: "$match", Value: bson.M{
+		"operationType":             "insert",
+		"fullDocument.aggregate_id": id,
+	}}}})
+	if err != nil {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("could not watch events: %w", err),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	ch := make(chan eh.Event)
+
+	go func() {
+		defer close(ch)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			var change struct {
+				FullDocument evt `bson:"fullDocument"`
+			}
+			if err := stream.Decode(&change); err != nil {
+				log.Printf("eventhorizon: could not decode followed event for %s: %s", id, err)
+
+				return
+			}
+
+			event, err := change.FullDocument.event()
+			if err != nil {
+				log.Printf("eventhorizon: could not create followed event for %s: %s", id, err)
+
+				return
+			}
+
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			log.Printf("eventhorizon: could not follow events for %s: %s", id, err)
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// NewContextWithReadPreference sets a read preference on the context, used
+// when loading events. Reads that can tolerate stale events, for example for
+// reporting, can use readpref.SecondaryPreferred() to offload the primary of a
+// replica set, while loading aggregates to handle commands should use the
+// default primary reads.
+func NewContextWithReadPreference(ctx context.Context, rp *readpref.ReadPref) context.Context {
+	return context.WithValue(ctx, readPreferenceKey, rp)
+}
+
+// ReadPreferenceFromContext returns the read preference from the context.
+func ReadPreferenceFromContext(ctx context.Context) (*readpref.ReadPref, bool) {
+	rp, ok := ctx.Value(readPreferenceKey).(*readpref.ReadPref)
+
+	return rp, ok && rp != nil
+}
+
+// readCollection returns the collection to read from, using the read
+// preference from the context if set.
+func readCollection(ctx context.Context, c *mongo.Collection) (*mongo.Collection, error) {
+	opts := readOptions(ctx)
+	if opts == nil {
+		return c, nil
+	}
+
+	return c.Clone(opts)
+}
+
+// readOptions returns the collection options for the read preference from the
+// context, or nil if not set.
+func readOptions(ctx context.Context) *options.CollectionOptions {
+	rp, ok := ReadPreferenceFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	return options.Collection().SetReadPreference(rp)
+}
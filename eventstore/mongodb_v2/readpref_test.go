@@ -0,0 +1,121 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestReadPreference(t *testing.T) {
+	ctx := context.Background()
+
+	// Default to the read preference of the client (primary).
+	if opts := readOptions(ctx); opts != nil {
+		t.Error("there should be no read options:", opts)
+	}
+
+	if _, ok := ReadPreferenceFromContext(NewContextWithReadPreference(ctx, nil)); ok {
+		t.Error("there should be no read preference")
+	}
+
+	for _, rp := range []*readpref.ReadPref{
+		readpref.Primary(),
+		readpref.PrimaryPreferred(),
+		readpref.SecondaryPreferred(),
+		readpref.Nearest(),
+	} {
+		opts := readOptions(NewContextWithReadPreference(ctx, rp))
+		if opts == nil || opts.ReadPreference != rp {
+			t.Error("the read preference should be set:", rp)
+		}
+	}
+}
+
+func TestReadPreferenceIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	store, err := NewEventStore(url, db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer store.Close()
+
+	ctx := context.Background()
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	events := []eh.Event{
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 1)),
+		eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, 2)),
+	}
+
+	if err := store.Save(ctx, events, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// A secondary is used when available, otherwise the primary.
+	readCtx := NewContextWithReadPreference(ctx, readpref.SecondaryPreferred())
+
+	loaded, err := store.Load(readCtx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(loaded) != 2 {
+		t.Error("there should be two events:", loaded)
+	}
+
+	loaded, err = store.LoadFrom(readCtx, id, 1)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(loaded) != 2 {
+		t.Error("there should be two events:", loaded)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb_v2
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+type contextKey int
+
+const (
+	transactionKey contextKey = iota
+	readPreferenceKey
+)
+
+// transaction keeps the events saved by a store to handle after the commit.
+type transaction struct {
+	store  *EventStore
+	events []eh.Event
+}
+
+// WithTransaction implements the WithTransaction method of the
+// eventhorizon.EventStoreTransactor interface.
+//
+// All events saved with the context passed to f are saved in one MongoDB
+// transaction, which also includes operations of other stores using the same
+// client, for example a repo/mongodb read repository. The events are handled by
+// the event handler (set with WithEventHandler) after the commit. Calls with a
+// context that already has a session are run in that session.
+func (s *EventStore) WithTransaction(ctx context.Context, f func(context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return f(ctx)
+	}
+
+	sess, err := s.client.StartSession(nil)
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %w", err)
+	}
+
+	defer sess.EndSession(ctx)
+
+	var tx *transaction
+
+	if _, err := sess.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+		// Reset the events if the transaction is retried.
+		tx = &transaction{store: s}
+
+		return nil, f(mongo.NewSessionContext(context.WithValue(txCtx, transactionKey, tx), txCtx))
+	}); err != nil {
+		return err
+	}
+
+	// Let the optional event handler handle the committed events.
+	if s.eventHandlerAfterSave != nil {
+		for _, e := range tx.events {
+			if err := s.eventHandlerAfterSave.HandleEvent(ctx, e); err != nil {
+				return &eh.EventHandlerError{
+					Err:   err,
+					Event: e,
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// OrderingAcceptanceTest is the acceptance test that all implementations of
+// EventStore should pass for the ordering guarantee they report, see
+// eventhorizon.OrderingReporter. A store with global ordering must also
+// implement EventStoreStreamer. It should manually be called from a test case
+// in each implementation:
+//
+//	func TestEventStoreOrdering(t *testing.T) {
+//		store := NewEventStore()
+//		eventstore.OrderingAcceptanceTest(t, store, context.Background())
+//	}
+func OrderingAcceptanceTest(t *testing.T, store eh.EventStore, ctx context.Context) {
+	ordering := eh.OrderingOf(store)
+
+	t.Log("ordering guarantee:", ordering)
+
+	// Find the last position for stores with global ordering.
+	streamer, _ := store.(eh.EventStoreStreamer)
+	if ordering == eh.GlobalOrdering && streamer == nil {
+		t.Fatal("the store should implement EventStoreStreamer for global ordering")
+	}
+
+	last := 0
+
+	if streamer != nil {
+		if err := streamer.StreamAll(ctx, 0, func(ctx context.Context, position int, event eh.Event) error {
+			last = position
+
+			return nil
+		}); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// Save events interleaved for a few aggregates, in separate saves.
+	const numAggregates, numVersions = 3, 5
+
+	ids := make([]uuid.UUID, numAggregates)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	var saved []eh.Event
+
+	for v := 1; v <= numVersions; v++ {
+		for _, id := range ids {
+			event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, v))
+			if err := store.Save(ctx, []eh.Event{event}, v-1); err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			saved = append(saved, event)
+		}
+	}
+
+	// Load the events of each aggregate.
+	for _, id := range ids {
+		events, err := store.Load(ctx, id)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if len(events) != numVersions {
+			t.Fatal("all events should be loaded:", len(events))
+		}
+
+		if ordering < eh.PerAggregateOrdering {
+			continue
+		}
+
+		for i, event := range events {
+			if event.Version() != i+1 {
+				t.Error("the events should be loaded in version order:", event)
+			}
+		}
+	}
+
+	if ordering != eh.GlobalOrdering {
+		return
+	}
+
+	// Stream the events of all aggregates.
+	var streamed []eh.Event
+
+	if err := streamer.StreamAll(ctx, last, func(ctx context.Context, position int, event eh.Event) error {
+		streamed = append(streamed, event)
+
+		return nil
+	}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(streamed) != len(saved) {
+		t.Fatal("all events should be streamed:", len(streamed))
+	}
+
+	for i, event := range streamed {
+		if event.AggregateID() != saved[i].AggregateID() ||
+			event.Version() != saved[i].Version() {
+			t.Error("the events should be streamed in the saved order:", i, event)
+		}
+	}
+}
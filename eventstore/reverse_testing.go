@@ -0,0 +1,101 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ReverseLoaderAcceptanceTest is the acceptance test that all implementations
+// of EventStoreReverseLoader should pass. It should manually be called from a
+// test case in each implementation:
+//
+//	func TestEventStoreReverseLoader(t *testing.T) {
+//		store := NewEventStore()
+//		eventstore.ReverseLoaderAcceptanceTest(t, store, context.Background())
+//	}
+func ReverseLoaderAcceptanceTest(t *testing.T, store interface {
+	eh.EventStore
+	eh.EventStoreReverseLoader
+}, ctx context.Context) {
+	id := uuid.New()
+
+	// No events.
+	if _, err := store.LoadReverse(ctx, id, 10); !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("there should be an aggregate not found error:", err)
+	}
+
+	// Save events in multiple batches, and events of another aggregate.
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvent := func(id uuid.UUID, version int) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, version))
+	}
+
+	var events []eh.Event
+	for i := 1; i <= 5; i++ {
+		events = append(events, newEvent(id, i))
+	}
+
+	if err := store.Save(ctx, events[:3], 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(uuid.New(), 1)}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, events[3:], 3); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	testCases := map[string]struct {
+		limit    int
+		versions []int
+	}{
+		"limited":        {2, []int{5, 4}},
+		"single":         {1, []int{5}},
+		"all":            {5, []int{5, 4, 3, 2, 1}},
+		"above count":    {10, []int{5, 4, 3, 2, 1}},
+		"without limit":  {0, []int{5, 4, 3, 2, 1}},
+		"negative limit": {-1, []int{5, 4, 3, 2, 1}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			loaded, err := store.LoadReverse(ctx, id, tc.limit)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			if len(loaded) != len(tc.versions) {
+				t.Fatal("the limit should cap the events:", len(loaded))
+			}
+
+			for i, e := range loaded {
+				if err := eh.CompareEvents(e, events[tc.versions[i]-1]); err != nil {
+					t.Error("the events should be newest first:", i, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/namespace"
+)
+
+var (
+	// ErrMissingEventStore is when there is no event store to wrap.
+	ErrMissingEventStore = errors.New("missing event store")
+	// ErrEventTypeNotAllowed is when an event type is denied or not allowed.
+	ErrEventTypeNotAllowed = errors.New("event type not allowed")
+)
+
+// EventTypeError is an error for an event that is not allowed to be saved.
+type EventTypeError struct {
+	// Err is the error.
+	Err error
+	// EventType is the event type that is not allowed.
+	EventType eh.EventType
+}
+
+// Error implements the Error method of the errors.Error interface.
+func (e *EventTypeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.EventType)
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *EventTypeError) Unwrap() error {
+	return e.Err
+}
+
+// EventStore wraps an eventhorizon.EventStore and checks the event types of
+// all saved events against an allowlist and a denylist, to not persist
+// unregistered or deprecated event types by mistake. If the allowlist is empty
+// all event types that are not denied are allowed.
+type EventStore struct {
+	eh.EventStore
+	allowed map[eh.EventType]struct{}
+	denied  map[eh.EventType]struct{}
+	skipped map[string]struct{}
+}
+
+// NewEventStore creates a new EventStore wrapping an event store.
+func NewEventStore(eventStore eh.EventStore, options ...Option) (*EventStore, error) {
+	if eventStore == nil {
+		return nil, ErrMissingEventStore
+	}
+
+	s := &EventStore{
+		EventStore: eventStore,
+		allowed:    map[eh.EventType]struct{}{},
+		denied:     map[eh.EventType]struct{}{},
+		skipped:    map[string]struct{}{},
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(s)
+	}
+
+	return s, nil
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*EventStore)
+
+// WithAllowedEventTypes adds event types to the allowlist. When the allowlist
+// is used only event types in it can be saved.
+func WithAllowedEventTypes(eventTypes ...eh.EventType) Option {
+	return func(s *EventStore) {
+		for _, t := range eventTypes {
+			s.allowed[t] = struct{}{}
+		}
+	}
+}
+
+// WithDeniedEventTypes adds event types to the denylist, which can never be
+// saved. The denylist takes precedence over the allowlist.
+func WithDeniedEventTypes(eventTypes ...eh.EventType) Option {
+	return func(s *EventStore) {
+		for _, t := range eventTypes {
+			s.denied[t] = struct{}{}
+		}
+	}
+}
+
+// WithSkippedNamespaces skips the check for saves in the namespaces, as set
+// in the context by the namespace package.
+func WithSkippedNamespaces(namespaces ...string) Option {
+	return func(s *EventStore) {
+		for _, ns := range namespaces {
+			s.skipped[ns] = struct{}{}
+		}
+	}
+}
+
+// Save implements the Save method of the eventhorizon.EventStore interface.
+func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	if _, ok := s.skipped[namespace.FromContext(ctx)]; !ok {
+		for _, e := range events {
+			if err := s.check(e.EventType()); err != nil {
+				return &eh.EventStoreError{
+					Err:              err,
+					Op:               eh.EventStoreOpSave,
+					AggregateType:    e.AggregateType(),
+					AggregateID:      e.AggregateID(),
+					AggregateVersion: originalVersion,
+					Events:           events,
+				}
+			}
+		}
+	}
+
+	return s.EventStore.Save(ctx, events, originalVersion)
+}
+
+func (s *EventStore) check(eventType eh.EventType) error {
+	if _, ok := s.denied[eventType]; ok {
+		return &EventTypeError{Err: ErrEventTypeNotAllowed, EventType: eventType}
+	}
+
+	if len(s.allowed) > 0 {
+		if _, ok := s.allowed[eventType]; !ok {
+			return &EventTypeError{Err: ErrEventTypeNotAllowed, EventType: eventType}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventstore/memory"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/namespace"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestEventStore(t *testing.T) {
+	if _, err := NewEventStore(nil); !errors.Is(err, ErrMissingEventStore) {
+		t.Error("there should be a missing event store error:", err)
+	}
+
+	baseStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewEventStore(baseStore,
+		WithAllowedEventTypes(mocks.EventType, mocks.EventOtherType),
+		WithDeniedEventTypes(mocks.EventOtherType),
+		WithSkippedNamespaces("migration"),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	id := uuid.New()
+
+	// Allowed event type.
+	if err := store.Save(ctx, []eh.Event{newEvent(mocks.EventType, id, 1)}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Denied event type, also in the allowlist.
+	err = store.Save(ctx, []eh.Event{newEvent(mocks.EventOtherType, id, 2)}, 1)
+	if !errors.Is(err, ErrEventTypeNotAllowed) {
+		t.Error("there should be an event type not allowed error:", err)
+	}
+
+	var typeErr *EventTypeError
+	if !errors.As(err, &typeErr) || typeErr.EventType != mocks.EventOtherType {
+		t.Error("there should be an event type error:", err)
+	}
+
+	// Event type not in the allowlist, for the whole save.
+	err = store.Save(ctx, []eh.Event{
+		newEvent(mocks.EventType, id, 2),
+		newEvent("Unknown", id, 3),
+	}, 1)
+	if !errors.Is(err, ErrEventTypeNotAllowed) {
+		t.Error("there should be an event type not allowed error:", err)
+	}
+
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 1 {
+		t.Error("only the allowed event should be saved:", events)
+	}
+
+	// Skipped namespace.
+	nsCtx := namespace.NewContext(ctx, "migration")
+	if err := store.Save(nsCtx, []eh.Event{newEvent(mocks.EventOtherType, id, 1)}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestEventStore_DenyOnly(t *testing.T) {
+	baseStore, err := memory.NewEventStore()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	store, err := NewEventStore(baseStore, WithDeniedEventTypes(mocks.EventOtherType))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+	id := uuid.New()
+
+	if err := store.Save(ctx, []eh.Event{newEvent("Unknown", id, 1)}, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(mocks.EventOtherType, id, 2)}, 1); !errors.Is(err, ErrEventTypeNotAllowed) {
+		t.Error("there should be an event type not allowed error:", err)
+	}
+}
+
+func newEvent(eventType eh.EventType, id uuid.UUID, version int) eh.Event {
+	return eh.NewEvent(eventType, nil, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, id, version))
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharding
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+var (
+	// ErrNoShards is when an event store is created without shards.
+	ErrNoShards = errors.New("no shards")
+	// ErrNotSupportedByShard is when an operation is not supported by all shards.
+	ErrNotSupportedByShard = errors.New("not supported by shard")
+)
+
+// EventStore is an event store that spreads the aggregates over multiple event
+// stores (shards) by a hash of the aggregate ID, to spread the writes of a
+// single store, for example MongoDB stores using different collections (see
+// mongodb_v2.WithCollectionNames) or databases. All events of an aggregate are
+// saved in and loaded from the same shard.
+//
+// The shard of an aggregate depends on the number of shards, which can not be
+// changed without moving the events of existing aggregates, for example with
+// the eventstore/migrate package.
+//
+// Reads of all aggregates are done in all shards and merged, if supported by
+// all shards: StreamAllByTime, AggregateIDs and StreamAggregateIDs. Snapshots
+// are saved in the shard of the aggregate, if supported by the shard. StreamAll
+// and FindByMetadata are not supported as there is no global order of the
+// saved events across the shards.
+type EventStore struct {
+	shards []eh.EventStore
+}
+
+// NewEventStore creates an event store over the shards. The order of the shards
+// must be the same each time the store is created.
+func NewEventStore(shards ...eh.EventStore) (*EventStore, error) {
+	if len(shards) == 0 {
+		return nil, ErrNoShards
+	}
+
+	for i, s := range shards {
+		if s == nil {
+			return nil, fmt.Errorf("missing shard %d: %w", i, ErrNoShards)
+		}
+	}
+
+	return &EventStore{
+		shards: shards,
+	}, nil
+}
+
+// Shard returns the index of the shard of an aggregate ID, using an FNV-1a hash
+// of the ID.
+func (s *EventStore) Shard(id uuid.UUID) int {
+	h := fnv.New32a()
+	h.Write(id[:])
+
+	return int(h.Sum32() % uint32(len(s.shards)))
+}
+
+// Save implements the Save method of the eventhorizon.EventStore interface.
+func (s *EventStore) Save(ctx context.Context, events []eh.Event, originalVersion int) error {
+	if len(events) == 0 {
+		return &eh.EventStoreError{
+			Err: eh.ErrMissingEvents,
+			Op:  eh.EventStoreOpSave,
+		}
+	}
+
+	return s.shards[s.Shard(events[0].AggregateID())].Save(ctx, events, originalVersion)
+}
+
+// Load implements the Load method of the eventhorizon.EventStore interface.
+func (s *EventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
+	return s.shards[s.Shard(id)].Load(ctx, id)
+}
+
+// LoadFrom implements LoadFrom method of the eventhorizon.EventStore interface.
+func (s *EventStore) LoadFrom(ctx context.Context, id uuid.UUID, version int) ([]eh.Event, error) {
+	return s.shards[s.Shard(id)].LoadFrom(ctx, id, version)
+}
+
+// LoadReverse implements the LoadReverse method of the
+// eventhorizon.EventStoreReverseLoader interface.
+func (s *EventStore) LoadReverse(ctx context.Context, id uuid.UUID, limit int) ([]eh.Event, error) {
+	i := s.Shard(id)
+
+	rl, ok := s.shards[i].(eh.EventStoreReverseLoader)
+	if !ok {
+		return nil, &eh.EventStoreError{
+			Err:         fmt.Errorf("reverse loading in shard %d: %w", i, ErrNotSupportedByShard),
+			Op:          eh.EventStoreOpLoad,
+			AggregateID: id,
+		}
+	}
+
+	return rl.LoadReverse(ctx, id, limit)
+}
+
+// LoadSnapshot implements the LoadSnapshot method of the
+// eventhorizon.SnapshotStore interface. There is no snapshot if the shard does
+// not support snapshots.
+func (s *EventStore) LoadSnapshot(ctx context.Context, id uuid.UUID) (*eh.Snapshot, error) {
+	ss, ok := s.shards[s.Shard(id)].(eh.SnapshotStore)
+	if !ok {
+		return nil, nil
+	}
+
+	return ss.LoadSnapshot(ctx, id)
+}
+
+// SaveSnapshot implements the SaveSnapshot method of the
+// eventhorizon.SnapshotStore interface.
+func (s *EventStore) SaveSnapshot(ctx context.Context, id uuid.UUID, snapshot eh.Snapshot) error {
+	i := s.Shard(id)
+
+	ss, ok := s.shards[i].(eh.SnapshotStore)
+	if !ok {
+		return &eh.EventStoreError{
+			Err:         fmt.Errorf("snapshots in shard %d: %w", i, ErrNotSupportedByShard),
+			Op:          eh.EventStoreOpSaveSnapshot,
+			AggregateID: id,
+		}
+	}
+
+	return ss.SaveSnapshot(ctx, id, snapshot)
+}
+
+// StreamAllByTime implements the StreamAllByTime method of the
+// eventhorizon.EventStoreTimeStreamer interface, by merging the streams of all
+// shards ordered by timestamp, with ties broken by aggregate ID and version.
+func (s *EventStore) StreamAllByTime(ctx context.Context, f func(ctx context.Context, event eh.Event) error) error {
+	streamers := make([]eh.EventStoreTimeStreamer, len(s.shards))
+
+	for i, shard := range s.shards {
+		streamer, ok := shard.(eh.EventStoreTimeStreamer)
+		if !ok {
+			return fmt.Errorf("streaming by time in shard %d: %w", i, ErrNotSupportedByShard)
+		}
+
+		streamers[i] = streamer
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Stream each shard in the background, one event at a time.
+	var wg sync.WaitGroup
+
+	streams := make([]chan eh.Event, len(streamers))
+	errs := make([]error, len(streamers))
+
+	for i, streamer := range streamers {
+		streams[i] = make(chan eh.Event)
+
+		wg.Add(1)
+
+		go func(i int, streamer eh.EventStoreTimeStreamer) {
+			defer wg.Done()
+			defer close(streams[i])
+
+			errs[i] = streamer.StreamAllByTime(ctx, func(ctx context.Context, event eh.Event) error {
+				select {
+				case streams[i] <- event:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}(i, streamer)
+	}
+
+	// Merge the streams by always handling the first of the next events.
+	heads := make([]eh.Event, len(streams))
+	for i := range streams {
+		heads[i] = <-streams[i]
+	}
+
+	var err error
+
+	for {
+		next := -1
+
+		for i, e := range heads {
+			if e != nil && (next < 0 || before(e, heads[next])) {
+				next = i
+			}
+		}
+
+		if next < 0 {
+			break
+		}
+
+		if err = f(ctx, heads[next]); err != nil {
+			break
+		}
+
+		heads[next] = <-streams[next]
+	}
+
+	cancel()
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("could not stream shard %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// AggregateIDs implements the AggregateIDs method of the
+// eventhorizon.EventStoreAggregateLister interface.
+func (s *EventStore) AggregateIDs(ctx context.Context, aggregateType eh.AggregateType) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+
+	if err := s.StreamAggregateIDs(ctx, aggregateType, func(ctx context.Context, id uuid.UUID) error {
+		ids = append(ids, id)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// StreamAggregateIDs implements the StreamAggregateIDs method of the
+// eventhorizon.EventStoreAggregateLister interface, by streaming the IDs of
+// each shard in turn.
+func (s *EventStore) StreamAggregateIDs(ctx context.Context, aggregateType eh.AggregateType, f func(ctx context.Context, id uuid.UUID) error) error {
+	for i, shard := range s.shards {
+		lister, ok := shard.(eh.EventStoreAggregateLister)
+		if !ok {
+			return fmt.Errorf("listing aggregates in shard %d: %w", i, ErrNotSupportedByShard)
+		}
+
+		if err := lister.StreamAggregateIDs(ctx, aggregateType, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close implements the Close method of the eventhorizon.EventStore interface,
+// by closing all shards.
+func (s *EventStore) Close() error {
+	var errs []error
+
+	for i, shard := range s.shards {
+		if err := shard.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("could not close shard %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// before returns true if the event a is before b by timestamp, aggregate ID and
+// version.
+func before(a, b eh.Event) bool {
+	if !a.Timestamp().Equal(b.Timestamp()) {
+		return a.Timestamp().Before(b.Timestamp())
+	}
+
+	ida, idb := a.AggregateID(), b.AggregateID()
+	if c := bytes.Compare(ida[:], idb[:]); c != 0 {
+		return c < 0
+	}
+
+	return a.Version() < b.Version()
+}
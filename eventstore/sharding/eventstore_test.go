@@ -0,0 +1,215 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharding
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventstore"
+	"github.com/looplab/eventhorizon/eventstore/memory"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestNewEventStore(t *testing.T) {
+	if _, err := NewEventStore(); !errors.Is(err, ErrNoShards) {
+		t.Error("there should be a no shards error:", err)
+	}
+
+	if _, err := NewEventStore(newShards(t, 1)[0], nil); !errors.Is(err, ErrNoShards) {
+		t.Error("there should be a no shards error:", err)
+	}
+}
+
+func TestEventStore(t *testing.T) {
+	store := newEventStore(t, 3)
+
+	eventstore.AcceptanceTest(t, store, context.Background())
+
+	if err := store.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestEventStoreTimeStreamer(t *testing.T) {
+	eventstore.TimeStreamAcceptanceTest(t, newEventStore(t, 3), context.Background())
+}
+
+func TestEventStoreReverseLoader(t *testing.T) {
+	eventstore.ReverseLoaderAcceptanceTest(t, newEventStore(t, 3), context.Background())
+}
+
+func TestEventStoreAggregateLister(t *testing.T) {
+	eventstore.AggregateListerAcceptanceTest(t, newEventStore(t, 3), context.Background())
+}
+
+func TestEventStore_Sharding(t *testing.T) {
+	ctx := context.Background()
+	shards := newShards(t, 3)
+
+	store, err := NewEventStore(shards...)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Save two events for each aggregate, with the same timestamp for all
+	// aggregates to also merge by aggregate ID and version.
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	ids := make([]uuid.UUID, 50)
+	used := map[int]int{}
+
+	for i := range ids {
+		ids[i] = uuid.New()
+		used[store.Shard(ids[i])]++
+
+		for v := 1; v <= 2; v++ {
+			e := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, ids[i], v))
+			if err := store.Save(ctx, []eh.Event{e}, v-1); err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+		}
+	}
+
+	if len(used) != len(shards) {
+		t.Error("all shards should be used:", used)
+	}
+
+	// The events of each aggregate are only in the shard of the aggregate,
+	// which is the same for a new store over the same shards.
+	other, err := NewEventStore(shards...)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for _, id := range ids {
+		shard := store.Shard(id)
+		if other.Shard(id) != shard {
+			t.Error("the shard should be the same for a new store:", id)
+		}
+
+		for i, s := range shards {
+			events, err := s.Load(ctx, id)
+			if i == shard && (err != nil || len(events) != 2) {
+				t.Error("the events should be in the shard of the aggregate:", id, err, events)
+			} else if i != shard && !errors.Is(err, eh.ErrAggregateNotFound) {
+				t.Error("the events should not be in other shards:", id, err, events)
+			}
+		}
+
+		if events, err := store.LoadFrom(ctx, id, 2); err != nil || len(events) != 1 || events[0].Version() != 2 {
+			t.Error("the events should be loaded from the shard:", id, err, events)
+		}
+	}
+
+	// Streaming by time covers all shards, in order.
+	var streamed []eh.Event
+
+	if err := store.StreamAllByTime(ctx, func(ctx context.Context, event eh.Event) error {
+		streamed = append(streamed, event)
+
+		return nil
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(streamed) != 2*len(ids) {
+		t.Error("all events should be streamed:", len(streamed))
+	}
+
+	for i := 1; i < len(streamed); i++ {
+		if !before(streamed[i-1], streamed[i]) {
+			t.Error("the events should be streamed in order:", streamed[i-1], streamed[i])
+		}
+	}
+
+	// Streaming stops at the first error.
+	streamErr := errors.New("stream error")
+	n := 0
+
+	if err := store.StreamAllByTime(ctx, func(ctx context.Context, event eh.Event) error {
+		n++
+
+		return streamErr
+	}); !errors.Is(err, streamErr) || n != 1 {
+		t.Error("there should be a stream error:", err, n)
+	}
+
+	listed, err := store.AggregateIDs(ctx, mocks.AggregateType)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(listed) != len(ids) {
+		t.Error("all aggregates should be listed:", len(listed))
+	}
+}
+
+func TestEventStore_NotSupportedByShard(t *testing.T) {
+	store, err := NewEventStore(newShards(t, 1)[0], &mocks.EventStore{})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	ctx := context.Background()
+
+	if err := store.StreamAllByTime(ctx, func(ctx context.Context, event eh.Event) error {
+		return nil
+	}); !errors.Is(err, ErrNotSupportedByShard) {
+		t.Error("there should be a not supported error:", err)
+	}
+
+	if _, err := store.AggregateIDs(ctx, mocks.AggregateType); !errors.Is(err, ErrNotSupportedByShard) {
+		t.Error("there should be a not supported error:", err)
+	}
+
+	// Use an aggregate in the mocked shard.
+	id := uuid.New()
+	for store.Shard(id) != 1 {
+		id = uuid.New()
+	}
+
+	if _, err := store.LoadReverse(ctx, id, 1); !errors.Is(err, ErrNotSupportedByShard) {
+		t.Error("there should be a not supported error:", err)
+	}
+}
+
+func newShards(t *testing.T, n int) []eh.EventStore {
+	shards := make([]eh.EventStore, n)
+
+	for i := range shards {
+		s, err := memory.NewEventStore()
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		shards[i] = s
+	}
+
+	return shards
+}
+
+func newEventStore(t *testing.T, n int) *EventStore {
+	store, err := NewEventStore(newShards(t, n)...)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	return store
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storetest is a conformance test kit for implementations of
+// eventhorizon.EventStore. Run it from a test case of the implementation to
+// check that it behaves like the built-in event stores:
+//
+//	func TestEventStore(t *testing.T) {
+//		store, err := NewEventStore()
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//
+//		storetest.Run(t, store, context.Background())
+//	}
+//
+// Each contract is also exported as its own function, for stores that only
+// fulfill some of them. All contracts use new aggregate IDs and can be run
+// against a store that already has events.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventstore"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// Run runs all contracts of the kit against the store as subtests.
+func Run(t *testing.T, store eh.EventStore, ctx context.Context) {
+	t.Run("SaveAndLoad", func(t *testing.T) {
+		SaveAndLoad(t, store, ctx)
+	})
+	t.Run("ConcurrencyConflict", func(t *testing.T) {
+		ConcurrencyConflict(t, store, ctx)
+	})
+	t.Run("LoadFrom", func(t *testing.T) {
+		LoadFrom(t, store, ctx)
+	})
+	t.Run("Ordering", func(t *testing.T) {
+		Ordering(t, store, ctx)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		NotFound(t, store, ctx)
+	})
+}
+
+// SaveAndLoad checks that saved events are loaded with their type, data,
+// metadata and aggregate, and that invalid saves are rejected with an
+// eh.EventStoreError: saving no events (eh.ErrMissingEvents), events for other
+// aggregate IDs or types in the same save (eh.ErrMismatchedEventAggregateIDs
+// and eh.ErrMismatchedEventAggregateTypes) and events with versions not
+// following the original version (eh.ErrIncorrectEventVersion).
+func SaveAndLoad(t *testing.T, store eh.EventStore, ctx context.Context) {
+	eventstore.AcceptanceTest(t, store, ctx)
+}
+
+// ConcurrencyConflict checks the optimistic concurrency of saves. A save with
+// an original version that is older than the version in the store fails with
+// eh.ErrEventConflictFromOtherSave, and of many concurrent saves for the same
+// version exactly one succeeds. Failed saves must not store any events.
+func ConcurrencyConflict(t *testing.T, store eh.EventStore, ctx context.Context) {
+	id := uuid.New()
+
+	if err := store.Save(ctx, []eh.Event{newEvent(id, 1, "event1")}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{newEvent(id, 2, "event2")}, 1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Save based on a stale version.
+	err := store.Save(ctx, []eh.Event{newEvent(id, 2, "stale")}, 1)
+	if !isEventStoreError(err) || !errors.Is(err, eh.ErrEventConflictFromOtherSave) {
+		t.Error("there should be a conflict error:", err)
+	}
+
+	// Save the next version concurrently.
+	const numSaves = 5
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, numSaves)
+	)
+
+	for i := 0; i < numSaves; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			errs[i] = store.Save(ctx, []eh.Event{newEvent(id, 3, "concurrent")}, 2)
+		}(i)
+	}
+
+	wg.Wait()
+
+	saved := 0
+
+	for _, err := range errs {
+		if err == nil {
+			saved++
+		} else if !isEventStoreError(err) {
+			t.Error("there should be an event store error:", err)
+		}
+	}
+
+	if saved != 1 {
+		t.Error("exactly one concurrent save should succeed:", saved)
+	}
+
+	events, err := store.Load(ctx, id)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	expected := []eh.Event{
+		newEvent(id, 1, "event1"),
+		newEvent(id, 2, "event2"),
+		newEvent(id, 3, "concurrent"),
+	}
+
+	compareEvents(t, events, expected)
+}
+
+// LoadFrom checks that loading from a version returns only the events from
+// that version, in version order. Loading from a version after the last
+// version returns no events, and either no error or eh.ErrAggregateNotFound.
+func LoadFrom(t *testing.T, store eh.EventStore, ctx context.Context) {
+	id := uuid.New()
+
+	var saved []eh.Event
+
+	for v := 1; v <= 5; v++ {
+		event := newEvent(id, v, "event")
+		if err := store.Save(ctx, []eh.Event{event}, v-1); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		saved = append(saved, event)
+	}
+
+	for _, from := range []int{1, 3, 5} {
+		events, err := store.LoadFrom(ctx, id, from)
+		if err != nil {
+			t.Error("there should be no error:", err)
+
+			continue
+		}
+
+		compareEvents(t, events, saved[from-1:])
+	}
+
+	events, err := store.LoadFrom(ctx, id, 6)
+	if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 0 {
+		t.Error("there should be no loaded events:", events)
+	}
+}
+
+// Ordering checks the ordering guarantee reported by the store, see
+// eh.OrderingReporter. Stores without a report must load the events of each
+// aggregate, stores with per aggregate ordering must load them in version
+// order and stores with global ordering must also implement
+// eh.EventStoreStreamer and stream events in the order they were saved.
+func Ordering(t *testing.T, store eh.EventStore, ctx context.Context) {
+	eventstore.OrderingAcceptanceTest(t, store, ctx)
+}
+
+// NotFound checks that loading the events of an aggregate without events
+// fails with an eh.EventStoreError wrapping eh.ErrAggregateNotFound, for both
+// Load and LoadFrom, and returns no events.
+func NotFound(t *testing.T, store eh.EventStore, ctx context.Context) {
+	id := uuid.New()
+
+	events, err := store.Load(ctx, id)
+	if !isEventStoreError(err) || !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	if len(events) != 0 {
+		t.Error("there should be no loaded events:", events)
+	}
+
+	events, err = store.LoadFrom(ctx, id, 1)
+	if !isEventStoreError(err) || !errors.Is(err, eh.ErrAggregateNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	if len(events) != 0 {
+		t.Error("there should be no loaded events:", events)
+	}
+}
+
+var timestamp = time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+func newEvent(id uuid.UUID, version int, content string) eh.Event {
+	return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: content}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, version))
+}
+
+func isEventStoreError(err error) bool {
+	var eventStoreErr *eh.EventStoreError
+
+	return errors.As(err, &eventStoreErr)
+}
+
+func compareEvents(t *testing.T, events, expected []eh.Event) {
+	t.Helper()
+
+	if len(events) != len(expected) {
+		t.Errorf("incorrect number of loaded events: %d (should be %d)", len(events), len(expected))
+
+		return
+	}
+
+	for i, event := range events {
+		if err := eh.CompareEvents(event, expected[i],
+			eh.IgnorePositionMetadata(),
+		); err != nil {
+			t.Error("the event was incorrect:", err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// StreamAcceptanceTest is the acceptance test that all implementations of
+// EventStoreStreamer should pass. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStoreStreamer(t *testing.T) {
+//		store := NewEventStore()
+//		eventstore.StreamAcceptanceTest(t, store, context.Background())
+//	}
+func StreamAcceptanceTest(t *testing.T, store interface {
+	eh.EventStore
+	eh.EventStoreStreamer
+}, ctx context.Context) {
+	// Find the last position, the store may already have events.
+	last := 0
+	if err := store.StreamAll(ctx, 0, func(ctx context.Context, position int, event eh.Event) error {
+		if position <= last {
+			t.Error("the positions should be increasing:", position, last)
+		}
+
+		last = position
+
+		return nil
+	}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Save events interleaved for two aggregates.
+	id1, id2 := uuid.New(), uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 1))
+	event2 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id2, 1))
+	event3 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event3"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 2))
+	event4 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event4"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id1, 3))
+
+	if err := store.Save(ctx, []eh.Event{event1}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{event2}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{event3, event4}, 1); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Stream all new events.
+	var (
+		events    []eh.Event
+		positions []int
+	)
+
+	if err := store.StreamAll(ctx, last, func(ctx context.Context, position int, event eh.Event) error {
+		events = append(events, event)
+		positions = append(positions, position)
+
+		return nil
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	expected := []eh.Event{event1, event2, event3, event4}
+	if len(events) != len(expected) {
+		t.Fatal("there should be the saved events:", events)
+	}
+
+	for i, event := range events {
+		if err := eh.CompareEvents(event, expected[i], eh.IgnorePositionMetadata()); err != nil {
+			t.Error("the event should be correct:", err)
+		}
+
+		if i > 0 && positions[i] <= positions[i-1] {
+			t.Error("the positions should be increasing:", positions)
+		}
+	}
+
+	// Resume streaming from a position.
+	events = nil
+
+	if err := store.StreamAll(ctx, positions[1], func(ctx context.Context, position int, event eh.Event) error {
+		events = append(events, event)
+
+		return nil
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(events) != 2 || events[0].AggregateID() != id1 || events[0].Version() != 2 {
+		t.Error("the events after the position should be streamed:", events)
+	}
+
+	// Stop streaming on errors.
+	streamErr := errors.New("stream error")
+	count := 0
+
+	if err := store.StreamAll(ctx, last, func(ctx context.Context, position int, event eh.Event) error {
+		count++
+
+		return streamErr
+	}); !errors.Is(err, streamErr) {
+		t.Error("there should be a stream error:", err)
+	}
+
+	if count != 1 {
+		t.Error("the streaming should stop on the first error:", count)
+	}
+}
+
+// TimeStreamAcceptanceTest is the acceptance test that all implementations of
+// EventStoreTimeStreamer should pass. It should manually be called from a test
+// case in each implementation:
+//
+//	func TestEventStoreTimeStreamer(t *testing.T) {
+//		store := NewEventStore()
+//		eventstore.TimeStreamAcceptanceTest(t, store, context.Background())
+//	}
+func TimeStreamAcceptanceTest(t *testing.T, store interface {
+	eh.EventStore
+	eh.EventStoreTimeStreamer
+}, ctx context.Context) {
+	// Save events for two aggregates with interleaved timestamps, and a tie
+	// between the aggregates for the last events.
+	id1, id2 := uuid.New(), uuid.New()
+	if bytes.Compare(id1[:], id2[:]) > 0 {
+		id1, id2 = id2, id1
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	newEvent := func(id uuid.UUID, version int, at time.Duration) eh.Event {
+		return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp.Add(at),
+			eh.ForAggregate(mocks.AggregateType, id, version))
+	}
+
+	event1 := newEvent(id1, 1, 2*time.Second)
+	event2 := newEvent(id1, 2, 4*time.Second)
+	event3 := newEvent(id1, 3, 5*time.Second)
+	event4 := newEvent(id2, 1, 1*time.Second)
+	event5 := newEvent(id2, 2, 3*time.Second)
+	event6 := newEvent(id2, 3, 5*time.Second)
+
+	if err := store.Save(ctx, []eh.Event{event1, event2, event3}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := store.Save(ctx, []eh.Event{event4, event5, event6}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Stream the events of the aggregates, the store may have other events.
+	var events []eh.Event
+
+	if err := store.StreamAllByTime(ctx, func(ctx context.Context, event eh.Event) error {
+		if id := event.AggregateID(); id == id1 || id == id2 {
+			events = append(events, event)
+		}
+
+		return nil
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	expected := []eh.Event{event4, event1, event5, event2, event3, event6}
+	if len(events) != len(expected) {
+		t.Fatal("there should be the saved events:", events)
+	}
+
+	for i, event := range events {
+		if err := eh.CompareEvents(event, expected[i], eh.IgnorePositionMetadata()); err != nil {
+			t.Error("the event should be in timestamp order:", i, err)
+		}
+	}
+
+	// Stop streaming on errors.
+	streamErr := errors.New("stream error")
+	count := 0
+
+	if err := store.StreamAllByTime(ctx, func(ctx context.Context, event eh.Event) error {
+		count++
+
+		return streamErr
+	}); !errors.Is(err, streamErr) {
+		t.Error("there should be a stream error:", err)
+	}
+
+	if count != 1 {
+		t.Error("the streaming should stop on the first error:", count)
+	}
+}
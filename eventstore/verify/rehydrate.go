@@ -0,0 +1,126 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/aggregatestore/events"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrNotListable is when the event store can not list the aggregates of a type.
+var ErrNotListable = errors.New("event store can not list aggregates")
+
+// RehydrateError is an error when applying an event to an aggregate in a dry
+// run, see DryRunRehydrate.
+type RehydrateError struct {
+	// Err is the error from applying the event.
+	Err error
+	// AggregateType of the aggregate.
+	AggregateType eh.AggregateType
+	// AggregateID of the aggregate.
+	AggregateID uuid.UUID
+	// Version of the event that could not be applied.
+	Version int
+}
+
+// Error implements the Error method of the errors.Error interface.
+func (e *RehydrateError) Error() string {
+	return fmt.Sprintf("could not apply event v%d to %s(%s): %s",
+		e.Version, e.AggregateType, e.AggregateID, e.Err)
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *RehydrateError) Unwrap() error {
+	return e.Err
+}
+
+// DryRunRehydrate loads all events of an aggregate and applies them to a fresh
+// aggregate created from the registered factory, without using snapshots or
+// persisting anything, for example to check that stored aggregates can still
+// be loaded by new code before migrating event schemas. The first event that
+// can not be applied is returned as a *RehydrateError. Aggregates without
+// events are not an error.
+func DryRunRehydrate(ctx context.Context, store eh.EventStore, aggregateType eh.AggregateType, id uuid.UUID) error {
+	agg, err := eh.CreateAggregate(aggregateType, id)
+	if err != nil {
+		return fmt.Errorf("could not create aggregate: %w", err)
+	}
+
+	a, ok := agg.(events.VersionedAggregate)
+	if !ok {
+		return events.ErrAggregateNotVersioned
+	}
+
+	evts, err := store.Load(ctx, id)
+	if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+		return fmt.Errorf("could not load events: %w", err)
+	}
+
+	for _, e := range evts {
+		if e.AggregateType() != aggregateType {
+			err = events.ErrMismatchedEventType
+		} else {
+			err = a.ApplyEvent(ctx, e)
+		}
+
+		if err != nil {
+			return &RehydrateError{
+				Err:           err,
+				AggregateType: aggregateType,
+				AggregateID:   id,
+				Version:       e.Version(),
+			}
+		}
+
+		a.SetAggregateVersion(e.Version())
+	}
+
+	return nil
+}
+
+// DryRunRehydrateStore does a dry run of rehydrating all aggregates of a type,
+// see DryRunRehydrate, and returns the error of each aggregate that failed.
+// The store must implement eventhorizon.EventStoreAggregateLister. Errors that
+// are not from applying events, for example when loading, stop the dry run.
+func DryRunRehydrateStore(ctx context.Context, store eh.EventStore, aggregateType eh.AggregateType) (map[uuid.UUID]error, error) {
+	lister, ok := store.(eh.EventStoreAggregateLister)
+	if !ok {
+		return nil, ErrNotListable
+	}
+
+	failed := map[uuid.UUID]error{}
+
+	if err := lister.StreamAggregateIDs(ctx, aggregateType, func(ctx context.Context, id uuid.UUID) error {
+		err := DryRunRehydrate(ctx, store, aggregateType, id)
+
+		var rErr *RehydrateError
+		if errors.As(err, &rErr) {
+			failed[id] = err
+
+			return nil
+		}
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("could not rehydrate aggregates: %w", err)
+	}
+
+	return failed, nil
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/aggregatestore/events"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestDryRunRehydrate(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+
+	store := &mocks.EventStore{Events: []eh.Event{
+		newCounterEvent(id, 1, IncrementedEvent),
+		newCounterEvent(id, 2, IncrementedEvent),
+	}}
+
+	if err := DryRunRehydrate(ctx, store, CounterAggregateType, id); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// An event that can not be applied by the current code.
+	store.Events = append(store.Events,
+		newCounterEvent(id, 3, "Decremented"),
+		newCounterEvent(id, 4, IncrementedEvent),
+	)
+
+	err := DryRunRehydrate(ctx, store, CounterAggregateType, id)
+
+	var rErr *RehydrateError
+	if !errors.As(err, &rErr) || rErr.Version != 3 || rErr.AggregateID != id {
+		t.Error("there should be a rehydrate error for the event:", err)
+	}
+
+	if !errors.Is(err, ErrUnknownEvent) {
+		t.Error("the error should wrap the apply error:", err)
+	}
+
+	// Nothing is persisted.
+	if len(store.Events) != 4 {
+		t.Error("there should be no new events:", store.Events)
+	}
+
+	// No events.
+	if err := DryRunRehydrate(ctx, &mocks.EventStore{}, CounterAggregateType, id); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := DryRunRehydrate(ctx, store, "Unregistered", id); !errors.Is(err, eh.ErrAggregateNotRegistered) {
+		t.Error("there should be a not registered error:", err)
+	}
+
+	loadErr := errors.New("load error")
+	if err := DryRunRehydrate(ctx, &mocks.EventStore{Err: loadErr}, CounterAggregateType, id); !errors.Is(err, loadErr) {
+		t.Error("there should be a load error:", err)
+	}
+}
+
+func TestDryRunRehydrateStore(t *testing.T) {
+	ctx := context.Background()
+	id1 := uuid.New()
+	id2 := uuid.New()
+	id3 := uuid.New()
+
+	store := &listingEventStore{EventStore: &mocks.EventStore{}}
+	store.Events = []eh.Event{
+		newCounterEvent(id1, 1, IncrementedEvent),
+		newCounterEvent(id2, 1, IncrementedEvent),
+		newCounterEvent(id2, 2, "Decremented"),
+		newCounterEvent(id3, 1, "Decremented"),
+		newCounterEvent(id1, 2, IncrementedEvent),
+	}
+
+	failed, err := DryRunRehydrateStore(ctx, store, CounterAggregateType)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	versions := map[uuid.UUID]int{}
+	for id, err := range failed {
+		var rErr *RehydrateError
+		if errors.As(err, &rErr) {
+			versions[id] = rErr.Version
+		}
+	}
+
+	if !reflect.DeepEqual(versions, map[uuid.UUID]int{id2: 2, id3: 1}) {
+		t.Error("the failing aggregates should be correct:", failed)
+	}
+
+	if _, err := DryRunRehydrateStore(ctx, &mocks.EventStore{}, CounterAggregateType); !errors.Is(err, ErrNotListable) {
+		t.Error("there should be a not listable error:", err)
+	}
+}
+
+const (
+	CounterAggregateType eh.AggregateType = "RehydrateCounter"
+	IncrementedEvent     eh.EventType     = "Incremented"
+)
+
+var ErrUnknownEvent = errors.New("unknown event")
+
+func init() {
+	eh.RegisterAggregate(func(id uuid.UUID) eh.Aggregate {
+		return &Counter{
+			AggregateBase: events.NewAggregateBase(CounterAggregateType, id),
+		}
+	})
+}
+
+// Counter is an aggregate that only knows how to apply incremented events.
+type Counter struct {
+	*events.AggregateBase
+	count int
+}
+
+func (a *Counter) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	return nil
+}
+
+func (a *Counter) ApplyEvent(ctx context.Context, event eh.Event) error {
+	if event.EventType() != IncrementedEvent {
+		return ErrUnknownEvent
+	}
+
+	a.count++
+
+	return nil
+}
+
+func newCounterEvent(id uuid.UUID, version int, eventType eh.EventType) eh.Event {
+	return eh.NewEvent(eventType, nil, time.Now(),
+		eh.ForAggregate(CounterAggregateType, id, version))
+}
+
+// listingEventStore lists the aggregates of the mocked store and loads the
+// events of each aggregate.
+type listingEventStore struct {
+	*mocks.EventStore
+}
+
+func (s *listingEventStore) Load(ctx context.Context, id uuid.UUID) ([]eh.Event, error) {
+	var result []eh.Event
+
+	for _, e := range s.Events {
+		if e.AggregateID() == id {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *listingEventStore) AggregateIDs(ctx context.Context, aggregateType eh.AggregateType) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+
+	err := s.StreamAggregateIDs(ctx, aggregateType, func(ctx context.Context, id uuid.UUID) error {
+		ids = append(ids, id)
+
+		return nil
+	})
+
+	return ids, err
+}
+
+func (s *listingEventStore) StreamAggregateIDs(ctx context.Context, aggregateType eh.AggregateType, f func(ctx context.Context, id uuid.UUID) error) error {
+	seen := map[uuid.UUID]bool{}
+
+	for _, e := range s.Events {
+		if e.AggregateType() != aggregateType || seen[e.AggregateID()] {
+			continue
+		}
+
+		seen[e.AggregateID()] = true
+
+		if err := f(ctx, e.AggregateID()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verify checks the version sequences of aggregates in an event store,
+// for example to detect silent corruption from a buggy writer in monitoring,
+// and that the aggregates can still be rehydrated by the current code.
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrNotStreamable is when the event store can not stream all events.
+var ErrNotStreamable = errors.New("event store is not streamable")
+
+// VerifyAggregate checks the versions of the events of an aggregate and returns
+// the gaps in the sequence, the versions that are missing or duplicated, in
+// ascending order. Returns no gaps if the versions are 1, 2, 3 and so on.
+func VerifyAggregate(ctx context.Context, store eh.EventStore, id uuid.UUID) ([]int, error) {
+	events, err := store.Load(ctx, id)
+	if err != nil && !errors.Is(err, eh.ErrAggregateNotFound) {
+		return nil, fmt.Errorf("could not load events: %w", err)
+	}
+
+	versions := make([]int, len(events))
+	for i, e := range events {
+		versions[i] = e.Version()
+	}
+
+	sort.Ints(versions)
+
+	s := &sequence{next: 1}
+	for _, v := range versions {
+		s.add(v)
+	}
+
+	return s.gaps, nil
+}
+
+// VerifyStore checks the versions of all aggregates in the event store and
+// returns the gaps in the sequence of each aggregate with gaps, see
+// VerifyAggregate. The store must implement eventhorizon.EventStoreStreamer and
+// the events of each aggregate are expected in version order when streamed, an
+// event out of order is reported as a duplicate.
+func VerifyStore(ctx context.Context, store eh.EventStore) (map[uuid.UUID][]int, error) {
+	streamer, ok := store.(eh.EventStoreStreamer)
+	if !ok {
+		return nil, ErrNotStreamable
+	}
+
+	sequences := map[uuid.UUID]*sequence{}
+
+	if err := streamer.StreamAll(ctx, 0, func(ctx context.Context, position int, event eh.Event) error {
+		if event.AggregateID() == uuid.Nil {
+			return nil
+		}
+
+		s, ok := sequences[event.AggregateID()]
+		if !ok {
+			s = &sequence{next: 1}
+			sequences[event.AggregateID()] = s
+		}
+
+		s.add(event.Version())
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not stream events: %w", err)
+	}
+
+	gaps := map[uuid.UUID][]int{}
+
+	for id, s := range sequences {
+		if len(s.gaps) > 0 {
+			sort.Ints(s.gaps)
+			gaps[id] = s.gaps
+		}
+	}
+
+	return gaps, nil
+}
+
+// sequence keeps track of the gaps in a sequence of versions.
+type sequence struct {
+	next int
+	gaps []int
+}
+
+// add adds the next version in ascending order.
+func (s *sequence) add(version int) {
+	switch {
+	case version < s.next:
+		// Only report each duplicate once.
+		if n := len(s.gaps); n == 0 || s.gaps[n-1] != version {
+			s.gaps = append(s.gaps, version)
+		}
+	case version > s.next:
+		for v := s.next; v < version; v++ {
+			s.gaps = append(s.gaps, v)
+		}
+
+		s.next = version + 1
+	default:
+		s.next++
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestVerifyAggregate(t *testing.T) {
+	id := uuid.New()
+
+	testCases := map[string]struct {
+		versions []int
+		gaps     []int
+	}{
+		"no events": {
+			nil,
+			nil,
+		},
+		"no gaps": {
+			[]int{1, 2, 3},
+			nil,
+		},
+		"gapped": {
+			[]int{1, 2, 4, 7},
+			[]int{3, 5, 6},
+		},
+		"duplicates": {
+			[]int{1, 2, 2, 2, 3},
+			[]int{2},
+		},
+		"unordered": {
+			[]int{3, 1, 2},
+			nil,
+		},
+		"gapped with duplicates": {
+			[]int{2, 2, 4},
+			[]int{1, 2, 3},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			store := &mocks.EventStore{}
+			for _, v := range tc.versions {
+				store.Events = append(store.Events, newEvent(id, v))
+			}
+
+			gaps, err := VerifyAggregate(context.Background(), store, id)
+			if err != nil {
+				t.Error("there should be no error:", err)
+			}
+
+			if !reflect.DeepEqual(gaps, tc.gaps) {
+				t.Error("the gaps should be correct:", gaps, tc.gaps)
+			}
+		})
+	}
+
+	loadErr := errors.New("load error")
+	if _, err := VerifyAggregate(context.Background(), &mocks.EventStore{Err: loadErr}, id); !errors.Is(err, loadErr) {
+		t.Error("there should be a load error:", err)
+	}
+}
+
+func TestVerifyStore(t *testing.T) {
+	id1 := uuid.New()
+	id2 := uuid.New()
+	id3 := uuid.New()
+
+	store := &streamingEventStore{EventStore: &mocks.EventStore{}}
+	store.Events = []eh.Event{
+		newEvent(id1, 1),
+		newEvent(id2, 1),
+		newEvent(id1, 2),
+		newEvent(id3, 1),
+		newEvent(id1, 4),
+		newEvent(id2, 1),
+		newEvent(id3, 2),
+		newEvent(id2, 2),
+	}
+
+	gaps, err := VerifyStore(context.Background(), store)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	expected := map[uuid.UUID][]int{
+		id1: {3},
+		id2: {1},
+	}
+	if !reflect.DeepEqual(gaps, expected) {
+		t.Error("the gaps should be correct:", gaps)
+	}
+
+	if _, err := VerifyStore(context.Background(), &mocks.EventStore{}); !errors.Is(err, ErrNotStreamable) {
+		t.Error("there should be a not streamable error:", err)
+	}
+}
+
+func newEvent(id uuid.UUID, version int) eh.Event {
+	return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, id, version))
+}
+
+// streamingEventStore streams the events of the mocked store in order.
+type streamingEventStore struct {
+	*mocks.EventStore
+}
+
+func (s *streamingEventStore) StreamAll(ctx context.Context, from int, f func(ctx context.Context, position int, event eh.Event) error) error {
+	for i, e := range s.Events[from:] {
+		if err := f(ctx, from+i+1, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/codec/json"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrMissingEntityFactory is when a repo of a client has no entity factory.
+var ErrMissingEntityFactory = errors.New("missing entity factory")
+
+// Client is a client of a Server, for example to handle commands and query read
+// models in another service. Errors from the server are returned as gRPC status
+// errors, see the google.golang.org/grpc/status package.
+type Client struct {
+	conn         grpc.ClientConnInterface
+	commandCodec eh.CommandCodec
+	entityCodec  eh.EntityCodec
+}
+
+// NewClient creates a Client using a gRPC connection. Commands and entities are
+// marshaled as JSON unless other codecs are set with WithClientCommandCodec and
+// WithClientEntityCodec.
+func NewClient(conn grpc.ClientConnInterface, options ...ClientOption) *Client {
+	c := &Client{
+		conn:         conn,
+		commandCodec: &json.CommandCodec{},
+		entityCodec:  &json.EntityCodec{},
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(c)
+	}
+
+	return c
+}
+
+// ClientOption is an option setter used to configure creation of clients.
+type ClientOption func(*Client)
+
+// WithClientCommandCodec sets the codec used to marshal commands.
+func WithClientCommandCodec(codec eh.CommandCodec) ClientOption {
+	return func(c *Client) {
+		c.commandCodec = codec
+	}
+}
+
+// WithClientEntityCodec sets the codec used to unmarshal entities.
+func WithClientEntityCodec(codec eh.EntityCodec) ClientOption {
+	return func(c *Client) {
+		c.entityCodec = codec
+	}
+}
+
+// HandleCommand implements the HandleCommand method of the
+// eventhorizon.CommandHandler interface, by sending the command and the
+// supported parts of the context to the server.
+func (c *Client) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	b, err := c.commandCodec.MarshalCommand(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("could not encode command: %w", err)
+	}
+
+	return c.conn.Invoke(ctx, handleCommandMethod, wrapperspb.Bytes(b), &emptypb.Empty{})
+}
+
+// CommandTypes returns the command types registered in the server.
+func (c *Client) CommandTypes(ctx context.Context) ([]eh.CommandType, error) {
+	l := &structpb.ListValue{}
+	if err := c.conn.Invoke(ctx, commandTypesMethod, &emptypb.Empty{}, l); err != nil {
+		return nil, err
+	}
+
+	types := make([]eh.CommandType, 0, len(l.GetValues()))
+	for _, v := range l.GetValues() {
+		types = append(types, eh.CommandType(v.GetStringValue()))
+	}
+
+	return types, nil
+}
+
+// Repo returns a read repo for the repo with the name in the server. Entities
+// are created with the factory before they are unmarshaled.
+func (c *Client) Repo(name string, factory func() eh.Entity) eh.ReadRepo {
+	return &repo{
+		client:  c,
+		name:    name,
+		factory: factory,
+	}
+}
+
+// repo is a read repo querying a repo in the server.
+type repo struct {
+	client  *Client
+	name    string
+	factory func() eh.Entity
+}
+
+// InnerRepo implements the InnerRepo method of the eventhorizon.ReadRepo interface.
+func (r *repo) InnerRepo(ctx context.Context) eh.ReadRepo {
+	return nil
+}
+
+// Find implements the Find method of the eventhorizon.ReadRepo interface.
+func (r *repo) Find(ctx context.Context, id uuid.UUID) (eh.Entity, error) {
+	if r.factory == nil {
+		return nil, &eh.RepoError{
+			Err:      ErrMissingEntityFactory,
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	q, err := structpb.NewStruct(map[string]interface{}{
+		"repo": r.name,
+		"id":   id.String(),
+	})
+	if err != nil {
+		return nil, &eh.RepoError{
+			Err:      fmt.Errorf("could not encode query: %w", err),
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	b := &wrapperspb.BytesValue{}
+	if err := r.client.conn.Invoke(ctx, findMethod, q, b); err != nil {
+		if status.Code(err) == codes.NotFound {
+			err = eh.ErrEntityNotFound
+		}
+
+		return nil, &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	entity := r.factory()
+	if err := r.client.entityCodec.UnmarshalEntity(ctx, b.GetValue(), entity); err != nil {
+		return nil, &eh.RepoError{
+			Err:      fmt.Errorf("could not decode entity: %w", err),
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	return entity, nil
+}
+
+// FindAll implements the FindAll method of the eventhorizon.ReadRepo interface.
+func (r *repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
+	if r.factory == nil {
+		return nil, &eh.RepoError{
+			Err: ErrMissingEntityFactory,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	q, err := structpb.NewStruct(map[string]interface{}{
+		"repo": r.name,
+	})
+	if err != nil {
+		return nil, &eh.RepoError{
+			Err: fmt.Errorf("could not encode query: %w", err),
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := r.client.conn.NewStream(ctx, &queryServiceDesc.Streams[0], findAllMethod)
+	if err != nil {
+		return nil, &eh.RepoError{
+			Err: err,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	if err := stream.SendMsg(q); err != nil {
+		return nil, &eh.RepoError{
+			Err: err,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return nil, &eh.RepoError{
+			Err: err,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	result := []eh.Entity{}
+
+	for {
+		b := &wrapperspb.BytesValue{}
+		if err := stream.RecvMsg(b); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, &eh.RepoError{
+				Err: err,
+				Op:  eh.RepoOpFindAll,
+			}
+		}
+
+		entity := r.factory()
+		if err := r.client.entityCodec.UnmarshalEntity(ctx, b.GetValue(), entity); err != nil {
+			return nil, &eh.RepoError{
+				Err: fmt.Errorf("could not decode entity: %w", err),
+				Op:  eh.RepoOpFindAll,
+			}
+		}
+
+		result = append(result, entity)
+	}
+
+	return result, nil
+}
+
+// Close implements the Close method of the eventhorizon.ReadRepo interface. The
+// connection is not closed as it is owned by the caller.
+func (r *repo) Close() error {
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutils
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/codec/json"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// Server is a gRPC server for commands and queries. Commands are dispatched to
+// a command handler and queries to named read repos. Commands must be
+// registered with eventhorizon.RegisterCommand().
+type Server struct {
+	commandHandler eh.CommandHandler
+	repos          map[string]eh.ReadRepo
+	commandCodec   eh.CommandCodec
+	entityCodec    eh.EntityCodec
+}
+
+// NewServer creates a Server. Commands and entities are marshaled as JSON
+// unless other codecs are set with WithCommandCodec and WithEntityCodec.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		repos:        map[string]eh.ReadRepo{},
+		commandCodec: &json.CommandCodec{},
+		entityCodec:  &json.EntityCodec{},
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(s)
+	}
+
+	return s
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Server)
+
+// WithCommandHandler serves the command service with the command handler.
+func WithCommandHandler(h eh.CommandHandler) Option {
+	return func(s *Server) {
+		s.commandHandler = h
+	}
+}
+
+// WithRepo serves the query service with the repo, queried by its name.
+func WithRepo(name string, repo eh.ReadRepo) Option {
+	return func(s *Server) {
+		s.repos[name] = repo
+	}
+}
+
+// WithCommandCodec sets the codec used to unmarshal commands, which must match
+// the codec of the clients.
+func WithCommandCodec(c eh.CommandCodec) Option {
+	return func(s *Server) {
+		s.commandCodec = c
+	}
+}
+
+// WithEntityCodec sets the codec used to marshal entities, which must match
+// the codec of the clients.
+func WithEntityCodec(c eh.EntityCodec) Option {
+	return func(s *Server) {
+		s.entityCodec = c
+	}
+}
+
+// Register registers the command service, if there is a command handler, and
+// the query service, if there are repos, with a gRPC server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	if s.commandHandler != nil {
+		r.RegisterService(&commandServiceDesc, s)
+	}
+
+	if len(s.repos) > 0 {
+		r.RegisterService(&queryServiceDesc, s)
+	}
+}
+
+func (s *Server) handleCommand(ctx context.Context, in *wrapperspb.BytesValue) (*emptypb.Empty, error) {
+	// NOTE: Use a new context when handling, else it will be cancelled with
+	// the request which will cause projectors etc to fail if they run async in
+	// goroutines past the request. The context values marshaled with the
+	// command by the client are used instead.
+	cmd, cmdCtx, err := s.commandCodec.UnmarshalCommand(context.Background(), in.GetValue())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "could not decode command: "+err.Error())
+	}
+
+	cmdCtx = eh.StartProcessing(cmdCtx)
+
+	if err := s.commandHandler.HandleCommand(cmdCtx, cmd); err != nil {
+		var retryErr eh.RetryableCommandError
+
+		switch {
+		case errors.As(err, &retryErr):
+			return nil, status.Error(codes.ResourceExhausted, "could not handle command: "+err.Error())
+		case errors.Is(err, eh.ErrCommandInternal):
+			// Don't leak the details of recovered panics.
+			return nil, status.Error(codes.Internal, "could not handle command: "+eh.ErrCommandInternal.Error())
+		case errors.Is(err, eh.ErrAggregateNotFound):
+			return nil, status.Error(codes.NotFound, "could not handle command: "+err.Error())
+		}
+
+		return nil, status.Error(codes.InvalidArgument, "could not handle command: "+err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) commandTypes(ctx context.Context, in *emptypb.Empty) (*structpb.ListValue, error) {
+	var types []string
+	for t := range eh.RegisteredCommands() {
+		types = append(types, t.String())
+	}
+
+	sort.Strings(types)
+
+	l := &structpb.ListValue{}
+	for _, t := range types {
+		l.Values = append(l.Values, structpb.NewStringValue(t))
+	}
+
+	return l, nil
+}
+
+func (s *Server) find(ctx context.Context, in *structpb.Struct) (*wrapperspb.BytesValue, error) {
+	repo, err := s.repo(in)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(in.GetFields()["id"].GetStringValue())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "could not parse ID: "+err.Error())
+	}
+
+	entity, err := repo.Find(ctx, id)
+	if errors.Is(err, eh.ErrEntityNotFound) {
+		return nil, status.Error(codes.NotFound, "could not find item")
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, "could not find item: "+err.Error())
+	}
+
+	b, err := s.entityCodec.MarshalEntity(ctx, entity)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "could not encode result: "+err.Error())
+	}
+
+	return wrapperspb.Bytes(b), nil
+}
+
+func (s *Server) findAll(in *structpb.Struct, stream grpc.ServerStream) error {
+	repo, err := s.repo(in)
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+
+	entities, err := repo.FindAll(ctx)
+	if err != nil {
+		return status.Error(codes.Internal, "could not find items: "+err.Error())
+	}
+
+	for _, entity := range entities {
+		b, err := s.entityCodec.MarshalEntity(ctx, entity)
+		if err != nil {
+			return status.Error(codes.Internal, "could not encode result: "+err.Error())
+		}
+
+		if err := stream.SendMsg(wrapperspb.Bytes(b)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repo returns the repo named by the "repo" field of a query.
+func (s *Server) repo(in *structpb.Struct) (eh.ReadRepo, error) {
+	name := in.GetFields()["repo"].GetStringValue()
+
+	repo, ok := s.repos[name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown repo: "+name)
+	}
+
+	return repo, nil
+}
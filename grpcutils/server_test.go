@@ -0,0 +1,219 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func init() {
+	eh.RegisterCommand(func() eh.Command { return &mocks.Command{} })
+}
+
+func TestServer(t *testing.T) {
+	h := &mocks.CommandHandler{}
+
+	repo := memory.NewRepo()
+	repo.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	ctx := context.Background()
+
+	m1 := &mocks.Model{ID: uuid.New(), Version: 1, Content: "model1"}
+	m2 := &mocks.Model{ID: uuid.New(), Version: 1, Content: "model2"}
+
+	for _, m := range []*mocks.Model{m1, m2} {
+		if err := repo.Save(ctx, m); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	conn := newConn(t, NewServer(
+		WithCommandHandler(h),
+		WithRepo("models", repo),
+	))
+	c := NewClient(conn)
+
+	// Commands are handled with the context values.
+	cmd := &mocks.Command{ID: uuid.New(), Content: "command1"}
+	if err := c.HandleCommand(mocks.WithContextOne(ctx, "one"), cmd); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(h.Commands, []eh.Command{cmd}) {
+		t.Error("the command should be correct:", h.Commands)
+	}
+
+	if val, ok := mocks.ContextOne(h.Context); !ok || val != "one" {
+		t.Error("the context should be correct:", val)
+	}
+
+	if _, ok := eh.ProcessingStartFromContext(h.Context); !ok {
+		t.Error("the processing should be started")
+	}
+
+	h.Err = eh.ErrAggregateNotFound
+	if err := c.HandleCommand(ctx, cmd); status.Code(err) != codes.NotFound {
+		t.Error("there should be a not found error:", err)
+	}
+
+	h.Err = errors.New("command error")
+	if err := c.HandleCommand(ctx, cmd); status.Code(err) != codes.InvalidArgument {
+		t.Error("there should be an invalid argument error:", err)
+	}
+
+	types, err := c.CommandTypes(ctx)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(types, []eh.CommandType{mocks.CommandType}) {
+		t.Error("the command types should be correct:", types)
+	}
+
+	// Queries are done in the named repo.
+	r := c.Repo("models", func() eh.Entity { return &mocks.Model{} })
+
+	entity, err := r.Find(ctx, m1.ID)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(entity, m1) {
+		t.Error("the entity should be correct:", entity)
+	}
+
+	if _, err := r.Find(ctx, uuid.New()); !errors.Is(err, eh.ErrEntityNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	entities, err := r.FindAll(ctx)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(entities) != 2 {
+		t.Error("there should be two entities:", entities)
+	}
+
+	for _, e := range entities {
+		if !reflect.DeepEqual(e, m1) && !reflect.DeepEqual(e, m2) {
+			t.Error("the entity should be correct:", e)
+		}
+	}
+
+	if _, err := c.Repo("other", func() eh.Entity { return &mocks.Model{} }).FindAll(ctx); status.Code(errors.Unwrap(err)) != codes.NotFound {
+		t.Error("there should be a not found error:", err)
+	}
+
+	if _, err := c.Repo("models", nil).Find(ctx, m1.ID); !errors.Is(err, ErrMissingEntityFactory) {
+		t.Error("there should be a missing entity factory error:", err)
+	}
+}
+
+func TestServer_Reflection(t *testing.T) {
+	conn := newConn(t, NewServer(
+		WithCommandHandler(&mocks.CommandHandler{}),
+		WithRepo("models", memory.NewRepo()),
+	))
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+	defer stream.CloseSend()
+
+	if err := stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	res, err := stream.Recv()
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	services := map[string]bool{}
+	for _, s := range res.GetListServicesResponse().GetService() {
+		services[s.GetName()] = true
+	}
+
+	if !services[CommandServiceName] || !services[QueryServiceName] {
+		t.Error("the services should be listed:", services)
+	}
+
+	// The services are described by the registered proto file.
+	for _, name := range []string{CommandServiceName, QueryServiceName} {
+		if err := stream.Send(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: name,
+			},
+		}); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if len(res.GetFileDescriptorResponse().GetFileDescriptorProto()) == 0 {
+			t.Error("there should be a file descriptor:", name, res.GetErrorResponse())
+		}
+	}
+}
+
+// newConn serves a server over an in-memory connection, with reflection.
+func newConn(t *testing.T, s *Server) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+
+	srv := grpc.NewServer()
+	s.Register(srv)
+	reflection.Register(srv)
+
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
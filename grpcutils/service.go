@@ -0,0 +1,217 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcutils exposes commands and queries as gRPC services, with the
+// same command and query surface as httputils. The messages use the well known
+// protobuf types, with commands and entities marshaled by the command and
+// entity codecs, so no generated code is needed by clients. The services are
+// registered in the protobuf registry and can be listed with gRPC server
+// reflection.
+//
+// The services are described by the following proto definition:
+//
+//	syntax = "proto3";
+//
+//	package eventhorizon;
+//
+//	service CommandService {
+//	  // HandleCommand handles a command marshaled by the command codec.
+//	  rpc HandleCommand(google.protobuf.BytesValue) returns (google.protobuf.Empty);
+//	  // CommandTypes lists the registered command types.
+//	  rpc CommandTypes(google.protobuf.Empty) returns (google.protobuf.ListValue);
+//	}
+//
+//	service QueryService {
+//	  // Find finds the entity with the "id" in the "repo" of the request,
+//	  // marshaled by the entity codec.
+//	  rpc Find(google.protobuf.Struct) returns (google.protobuf.BytesValue);
+//	  // FindAll streams all entities in the "repo" of the request, marshaled
+//	  // by the entity codec.
+//	  rpc FindAll(google.protobuf.Struct) returns (stream google.protobuf.BytesValue);
+//	}
+package grpcutils
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// CommandServiceName is the full name of the command service.
+	CommandServiceName = "eventhorizon.CommandService"
+	// QueryServiceName is the full name of the query service.
+	QueryServiceName = "eventhorizon.QueryService"
+)
+
+// protoFile is the name of the proto file describing the services.
+const protoFile = "eventhorizon/grpcutils/eventhorizon.proto"
+
+const (
+	handleCommandMethod = "/" + CommandServiceName + "/HandleCommand"
+	commandTypesMethod  = "/" + CommandServiceName + "/CommandTypes"
+	findMethod          = "/" + QueryServiceName + "/Find"
+	findAllMethod       = "/" + QueryServiceName + "/FindAll"
+)
+
+func init() {
+	// Register the proto definition of the services for server reflection.
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String(protoFile),
+		Package: proto.String("eventhorizon"),
+		Dependency: []string{
+			"google/protobuf/empty.proto",
+			"google/protobuf/struct.proto",
+			"google/protobuf/wrappers.proto",
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("CommandService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					method("HandleCommand", &wrapperspb.BytesValue{}, &emptypb.Empty{}, false),
+					method("CommandTypes", &emptypb.Empty{}, &structpb.ListValue{}, false),
+				},
+			},
+			{
+				Name: proto.String("QueryService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					method("Find", &structpb.Struct{}, &wrapperspb.BytesValue{}, false),
+					method("FindAll", &structpb.Struct{}, &wrapperspb.BytesValue{}, true),
+				},
+			},
+		},
+		Syntax: proto.String("proto3"),
+	}
+
+	f, err := protodesc.NewFile(fd, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(fmt.Sprintf("eventhorizon: could not create proto file: %s", err))
+	}
+
+	if err := protoregistry.GlobalFiles.RegisterFile(f); err != nil {
+		panic(fmt.Sprintf("eventhorizon: could not register proto file: %s", err))
+	}
+}
+
+func method(name string, in, out proto.Message, serverStreaming bool) *descriptorpb.MethodDescriptorProto {
+	return &descriptorpb.MethodDescriptorProto{
+		Name:            proto.String(name),
+		InputType:       proto.String("." + string(in.ProtoReflect().Descriptor().FullName())),
+		OutputType:      proto.String("." + string(out.ProtoReflect().Descriptor().FullName())),
+		ServerStreaming: proto.Bool(serverStreaming),
+	}
+}
+
+// commandService is the handler type of the command service.
+type commandService interface {
+	handleCommand(context.Context, *wrapperspb.BytesValue) (*emptypb.Empty, error)
+	commandTypes(context.Context, *emptypb.Empty) (*structpb.ListValue, error)
+}
+
+var commandServiceDesc = grpc.ServiceDesc{
+	ServiceName: CommandServiceName,
+	HandlerType: (*commandService)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "HandleCommand",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &wrapperspb.BytesValue{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+
+				return intercept(ctx, srv, in, handleCommandMethod, interceptor, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(commandService).handleCommand(ctx, req.(*wrapperspb.BytesValue))
+				})
+			},
+		},
+		{
+			MethodName: "CommandTypes",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &emptypb.Empty{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+
+				return intercept(ctx, srv, in, commandTypesMethod, interceptor, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(commandService).commandTypes(ctx, req.(*emptypb.Empty))
+				})
+			},
+		},
+	},
+	Metadata: protoFile,
+}
+
+// queryService is the handler type of the query service.
+type queryService interface {
+	find(context.Context, *structpb.Struct) (*wrapperspb.BytesValue, error)
+	findAll(*structpb.Struct, grpc.ServerStream) error
+}
+
+var queryServiceDesc = grpc.ServiceDesc{
+	ServiceName: QueryServiceName,
+	HandlerType: (*queryService)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Find",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &structpb.Struct{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+
+				return intercept(ctx, srv, in, findMethod, interceptor, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(queryService).find(ctx, req.(*structpb.Struct))
+				})
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "FindAll",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				in := &structpb.Struct{}
+				if err := stream.RecvMsg(in); err != nil {
+					return err
+				}
+
+				return srv.(queryService).findAll(in, stream)
+			},
+			ServerStreams: true,
+		},
+	},
+	Metadata: protoFile,
+}
+
+// intercept calls the handler, through the interceptor if there is one.
+func intercept(ctx context.Context, srv, in interface{}, fullMethod string, interceptor grpc.UnaryServerInterceptor, handler grpc.UnaryHandler) (interface{}, error) {
+	if interceptor == nil {
+		return handler(ctx, in)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: fullMethod,
+	}
+
+	return interceptor(ctx, in, info, handler)
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// DefaultClientTimeout is the timeout of the default HTTP client used by
+// CommandClient.
+const DefaultClientTimeout = 30 * time.Second
+
+// maxErrorSize is the max size of error messages read from responses.
+const maxErrorSize = 4096
+
+// CommandClient is a command handler that sends commands to a remote
+// CommandHandler over HTTP, for example to handle commands in another service.
+// Responses with an error status are returned as an *Error with the status and
+// message of the response.
+type CommandClient struct {
+	url    string
+	client *http.Client
+}
+
+// NewCommandClient creates a CommandClient which posts commands to the URL.
+// A default HTTP client with DefaultClientTimeout is used unless another one is
+// set with WithHTTPClient or WithTransport.
+func NewCommandClient(url string, options ...ClientOption) *CommandClient {
+	c := &CommandClient{
+		url: url,
+		client: &http.Client{
+			Timeout: DefaultClientTimeout,
+		},
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(c)
+	}
+
+	return c
+}
+
+// ClientOption is an option setter used to configure creation of clients.
+type ClientOption func(*CommandClient)
+
+// WithHTTPClient sets the HTTP client used to send commands, for example to
+// configure TLS, timeouts and proxies.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *CommandClient) {
+		c.client = client
+	}
+}
+
+// WithTransport sets the transport of the default HTTP client, for example to
+// add mTLS, retries or connection pooling at the transport layer.
+func WithTransport(t http.RoundTripper) ClientOption {
+	return func(c *CommandClient) {
+		c.client = &http.Client{
+			Transport: t,
+			Timeout:   DefaultClientTimeout,
+		}
+	}
+}
+
+// HandleCommand implements the HandleCommand method of the
+// eventhorizon.CommandHandler interface.
+func (c *CommandClient) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	b, err := json.Marshal(cmd)
+	if err != nil {
+		return fmt.Errorf("could not marshal command: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send command: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		// Drain the body to reuse the connection.
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorSize))
+
+	return &Error{
+		Status:  resp.StatusCode,
+		Message: strings.TrimSpace(string(msg)),
+		Header:  resp.Header,
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestCommandClient(t *testing.T) {
+	h := &mocks.CommandHandler{}
+	srv := httptest.NewServer(CommandHandler(h, mocks.CommandType))
+	defer srv.Close()
+
+	c := NewCommandClient(srv.URL)
+	if c.client.Timeout != DefaultClientTimeout {
+		t.Error("the default client should have a timeout:", c.client.Timeout)
+	}
+
+	cmd := &mocks.Command{ID: uuid.New(), Content: "content"}
+	if err := c.HandleCommand(context.Background(), cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(h.Commands, []eh.Command{cmd}) {
+		t.Error("the command should be handled remotely:", h.Commands)
+	}
+
+	// Errors are returned with the status of the response.
+	h.Err = errors.New("command error")
+
+	err := c.HandleCommand(context.Background(), cmd)
+
+	var httpErr *Error
+	if !errors.As(err, &httpErr) || httpErr.Status != http.StatusBadRequest ||
+		httpErr.Message != "could not handle command: command error" {
+		t.Error("there should be a HTTP error:", err)
+	}
+}
+
+func TestCommandClient_Transport(t *testing.T) {
+	h := &mocks.CommandHandler{}
+	srv := httptest.NewServer(CommandHandler(h, mocks.CommandType))
+	defer srv.Close()
+
+	transport := &countingTransport{RoundTripper: http.DefaultTransport}
+	cmd := &mocks.Command{ID: uuid.New(), Content: "content"}
+
+	c := NewCommandClient(srv.URL, WithTransport(transport))
+	if err := c.HandleCommand(context.Background(), cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n := atomic.LoadInt32(&transport.requests); n != 1 {
+		t.Error("the transport should be used:", n)
+	}
+
+	// A custom client.
+	c = NewCommandClient(srv.URL, WithHTTPClient(&http.Client{Transport: transport}))
+	if err := c.HandleCommand(context.Background(), cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n := atomic.LoadInt32(&transport.requests); n != 2 {
+		t.Error("the transport of the client should be used:", n)
+	}
+
+	if len(h.Commands) != 2 {
+		t.Error("the commands should be handled:", h.Commands)
+	}
+}
+
+// countingTransport counts the outbound requests.
+type countingTransport struct {
+	http.RoundTripper
+	requests int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+
+	return t.RoundTripper.RoundTrip(r)
+}
@@ -15,55 +15,314 @@
 package httputils
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/namespace"
+)
+
+// MaxCommandSize is the maximum size in bytes of a gzip compressed command body,
+// after it has been decompressed. Used to guard against decompression bombs.
+// Uncompressed bodies are not limited, use http.MaxBytesReader for that.
+var MaxCommandSize int64 = 1 << 20
+
+// errCommandTooLarge is when a decompressed command body exceeds MaxCommandSize.
+var errCommandTooLarge = errors.New("command too large")
+
+const (
+	// CommandVersionHeader is the request header with the schema version of
+	// the command sent by the client.
+	CommandVersionHeader = "X-Command-Version"
+	// SupportedCommandVersionsHeader is the response header with the range of
+	// supported schema versions, when the version is not supported.
+	SupportedCommandVersionsHeader = "X-Supported-Command-Versions"
 )
 
 // CommandHandler is a HTTP handler for eventhorizon.Commands. Commands must be
 // registered with eventhorizon.RegisterCommand(). It expects a POST with a JSON
-// body that will be unmarshaled into the command.
+// body that will be unmarshaled into the command. The body can optionally be
+// compressed with gzip, indicated by the "Content-Encoding: gzip" header.
+// Commands rejected with an eventhorizon.RetryableCommandError, as by the rate
+// limit middleware, are responded to with "429 Too Many Requests" and a
+// "Retry-After" header, and eventhorizon.ErrCommandInternal errors, as for
+// panics recovered by the recovery middleware, with "500 Internal Server Error".
+//
+// The client can declare the schema version of the command with the
+// CommandVersionHeader. Versions that are not supported, as registered with
+// eventhorizon.RegisterCommandVersions, are responded to with
+// "415 Unsupported Media Type" and the supported versions in the body and the
+// SupportedCommandVersionsHeader.
+//
+// Commands are created in the namespace of the request context, see
+// namespace.RegisterCommand, and handled in the same namespace. Processing is
+// started when the request is received, see eventhorizon.ProcessingElapsed.
 func CommandHandler(commandHandler eh.CommandHandler, commandType eh.CommandType) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return CommandErrorHandler(commandHandler, commandType)
+}
+
+// CommandErrorHandler is a CommandHandler which returns any errors as an
+// *Error, to be written by error handling middleware.
+func CommandErrorHandler(commandHandler eh.CommandHandler, commandType eh.CommandType) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		r = r.WithContext(eh.StartProcessing(r.Context()))
+
 		if r.Method != "POST" {
-			http.Error(w, "unsupported method: "+r.Method, http.StatusMethodNotAllowed)
+			return &Error{
+				Status:  http.StatusMethodNotAllowed,
+				Message: "unsupported method: " + r.Method,
+			}
+		}
+
+		cmd, err := namespace.CreateCommand(r.Context(), commandType)
+		if err != nil {
+			return &Error{
+				Status:  http.StatusBadRequest,
+				Message: "could not create command: " + err.Error(),
+				Err:     err,
+			}
+		}
 
-			return
+		if err := checkCommandVersion(r, commandType); err != nil {
+			return err
 		}
 
-		cmd, err := eh.CreateCommand(commandType)
+		b, err := readCommandBody(r)
 		if err != nil {
-			http.Error(w, "could not create command: "+err.Error(), http.StatusBadRequest)
+			return err
+		}
 
-			return
+		if err := decodeAndHandleCommand(r, commandHandler, cmd, b); err != nil {
+			return err
 		}
 
-		b, err := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+
+		return nil
+	}
+}
+
+// DefaultCommandTypeField is the JSON field used by CommandTypeRouter to
+// select the command type if no other field is set.
+const DefaultCommandTypeField = "type"
+
+// CommandTypeRouter is a HTTP handler for multiple types of eventhorizon.Commands
+// on the same endpoint. The command type is read from the typeField of the JSON
+// body (DefaultCommandTypeField if empty) and the rest of the body is handled
+// as in CommandHandler. Unknown command types are responded to with
+// "400 Bad Request". Commands are created in the namespace of the request
+// context, as in CommandHandler.
+func CommandTypeRouter(commandHandler eh.CommandHandler, typeField string) http.Handler {
+	return CommandTypeErrorRouter(commandHandler, typeField)
+}
+
+// CommandTypeErrorRouter is a CommandTypeRouter which returns any errors as an
+// *Error, to be written by error handling middleware.
+func CommandTypeErrorRouter(commandHandler eh.CommandHandler, typeField string) ErrorHandler {
+	if typeField == "" {
+		typeField = DefaultCommandTypeField
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		r = r.WithContext(eh.StartProcessing(r.Context()))
+
+		if r.Method != "POST" {
+			return &Error{
+				Status:  http.StatusMethodNotAllowed,
+				Message: "unsupported method: " + r.Method,
+			}
+		}
+
+		b, err := readCommandBody(r)
 		if err != nil {
-			http.Error(w, "could not read command: "+err.Error(), http.StatusBadRequest)
+			return err
+		}
 
-			return
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return &Error{
+				Status:  http.StatusBadRequest,
+				Message: "could not decode command: " + err.Error(),
+				Err:     err,
+			}
 		}
 
-		if err := json.Unmarshal(b, &cmd); err != nil {
-			http.Error(w, "could not decode command: "+err.Error(), http.StatusBadRequest)
+		var commandType eh.CommandType
+		if raw, ok := fields[typeField]; !ok {
+			return &Error{
+				Status:  http.StatusBadRequest,
+				Message: "could not decode command: missing field: " + typeField,
+			}
+		} else if err := json.Unmarshal(raw, &commandType); err != nil {
+			return &Error{
+				Status:  http.StatusBadRequest,
+				Message: "could not decode command type: " + err.Error(),
+				Err:     err,
+			}
+		}
 
-			return
+		cmd, err := namespace.CreateCommand(r.Context(), commandType)
+		if err != nil {
+			return &Error{
+				Status:  http.StatusBadRequest,
+				Message: "could not create command: " + err.Error(),
+				Err:     err,
+			}
 		}
 
-		// NOTE: Use a new context when handling, else it will be cancelled with
-		// the HTTP request which will cause projectors etc to fail if they run
-		// async in goroutines past the request.
-		ctx := context.Background()
-		if err := commandHandler.HandleCommand(ctx, cmd); err != nil {
-			http.Error(w, "could not handle command: "+err.Error(), http.StatusBadRequest)
+		if err := checkCommandVersion(r, commandType); err != nil {
+			return err
+		}
 
-			return
+		if err := decodeAndHandleCommand(r, commandHandler, cmd, b); err != nil {
+			return err
 		}
 
 		w.WriteHeader(http.StatusOK)
-	})
+
+		return nil
+	}
+}
+
+// checkCommandVersion checks the schema version in the CommandVersionHeader,
+// if any, against the supported versions of the command type.
+func checkCommandVersion(r *http.Request, commandType eh.CommandType) error {
+	h := r.Header.Get(CommandVersionHeader)
+	if h == "" {
+		return nil
+	}
+
+	version, err := strconv.Atoi(h)
+	if err != nil {
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Message: "invalid command version: " + h,
+			Err:     err,
+		}
+	}
+
+	var versionErr *eh.CommandVersionError
+	if err := eh.CheckCommandVersion(commandType, version); errors.As(err, &versionErr) {
+		return &Error{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: "could not handle command: " + err.Error(),
+			Header:  http.Header{SupportedCommandVersionsHeader: []string{versionErr.Supported.String()}},
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// readCommandBody reads the body of a command request, see readCommand.
+func readCommandBody(r *http.Request) ([]byte, error) {
+	b, err := readCommand(r)
+	if errors.Is(err, errCommandTooLarge) {
+		return nil, &Error{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: "could not read command: " + err.Error(),
+			Err:     err,
+		}
+	} else if err != nil {
+		return nil, &Error{
+			Status:  http.StatusBadRequest,
+			Message: "could not read command: " + err.Error(),
+			Err:     err,
+		}
+	}
+
+	return b, nil
+}
+
+// decodeAndHandleCommand decodes the JSON body into the command and handles it.
+func decodeAndHandleCommand(r *http.Request, commandHandler eh.CommandHandler, cmd eh.Command, b []byte) error {
+	if err := json.Unmarshal(b, &cmd); err != nil {
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Message: "could not decode command: " + err.Error(),
+			Err:     err,
+		}
+	}
+
+	// NOTE: Use a new context when handling, else it will be cancelled with
+	// the HTTP request which will cause projectors etc to fail if they run
+	// async in goroutines past the request. The namespace and the processing
+	// start of the request are kept for the handling.
+	ctx := namespace.NewContext(context.Background(), namespace.FromContext(r.Context()))
+	if start, ok := eh.ProcessingStartFromContext(r.Context()); ok {
+		ctx = eh.NewContextWithProcessingStart(ctx, start)
+	}
+	if err := commandHandler.HandleCommand(ctx, cmd); err != nil {
+		var retryErr eh.RetryableCommandError
+		if errors.As(err, &retryErr) {
+			return &Error{
+				Status:  http.StatusTooManyRequests,
+				Message: "could not handle command: " + err.Error(),
+				Header:  http.Header{"Retry-After": []string{retryAfter(retryErr.RetryAfter())}},
+				Err:     err,
+			}
+		}
+
+		// Don't leak the details of recovered panics.
+		if errors.Is(err, eh.ErrCommandInternal) {
+			return &Error{
+				Status:  http.StatusInternalServerError,
+				Message: "could not handle command: " + eh.ErrCommandInternal.Error(),
+				Err:     err,
+			}
+		}
+
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Message: "could not handle command: " + err.Error(),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// readCommand reads the (optionally gzip compressed) body of a request. The
+// decompressed body of a compressed request is limited to MaxCommandSize bytes.
+func readCommand(r *http.Request) ([]byte, error) {
+	if !strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		return ioutil.ReadAll(r.Body)
+	}
+
+	gr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	// Read one extra byte to detect bodies that are too large.
+	b, err := ioutil.ReadAll(io.LimitReader(gr, MaxCommandSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(b)) > MaxCommandSize {
+		return nil, errCommandTooLarge
+	}
+
+	return b, nil
+}
+
+// retryAfter formats a duration as the seconds of a Retry-After header, rounded
+// up to at least one second.
+func retryAfter(d time.Duration) string {
+	secs := int64((d + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+
+	return strconv.FormatInt(secs, 10)
 }
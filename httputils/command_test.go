@@ -0,0 +1,433 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/middleware/commandhandler/ratelimit"
+	"github.com/looplab/eventhorizon/middleware/commandhandler/recovery"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/namespace"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func init() {
+	eh.RegisterCommand(func() eh.Command { return &mocks.Command{} })
+	eh.RegisterCommand(func() eh.Command { return &mocks.CommandOther{} })
+}
+
+func TestCommandHandler(t *testing.T) {
+	h := &mocks.CommandHandler{}
+	handler := CommandHandler(h, mocks.CommandType)
+
+	id := uuid.New()
+	body := `{"ID":"` + id.String() + `","Content":"content"}`
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	expected := []eh.Command{&mocks.Command{ID: id, Content: "content"}}
+	if !reflect.DeepEqual(h.Commands, expected) {
+		t.Error("the command should be correct:", h.Commands)
+	}
+
+	// Processing is started when receiving the request.
+	if _, ok := eh.ProcessingStartFromContext(h.Context); !ok {
+		t.Error("the processing should be started:", h.Context)
+	}
+}
+
+func TestCommandHandlerRateLimited(t *testing.T) {
+	h := &mocks.CommandHandler{
+		Err: fmt.Errorf("could not handle: %w", &ratelimit.Error{
+			Key:   "key",
+			Delay: 1500 * time.Millisecond,
+		}),
+	}
+	handler := CommandHandler(h, mocks.CommandType)
+
+	body := `{"ID":"` + uuid.New().String() + `","Content":"content"}`
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Error("the retry after header should be correct:", retryAfter)
+	}
+}
+
+func TestCommandHandlerPanic(t *testing.T) {
+	panicking := eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+		panic("secret details")
+	})
+	h := eh.UseCommandHandlerMiddleware(panicking,
+		recovery.NewMiddleware(recovery.WithLogger(nil)))
+	handler := CommandHandler(h, mocks.CommandType)
+
+	body := `{"ID":"` + uuid.New().String() + `","Content":"content"}`
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	if strings.Contains(w.Body.String(), "secret details") {
+		t.Error("the panic should not be leaked:", w.Body.String())
+	}
+}
+
+func TestCommandHandlerGzip(t *testing.T) {
+	h := &mocks.CommandHandler{}
+	handler := CommandHandler(h, mocks.CommandType)
+
+	id := uuid.New()
+	body := gzipBytes(t, []byte(`{"ID":"`+id.String()+`","Content":"content"}`))
+	r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Error("the status should be correct:", w.Code, w.Body.String())
+	}
+
+	expected := []eh.Command{&mocks.Command{ID: id, Content: "content"}}
+	if !reflect.DeepEqual(h.Commands, expected) {
+		t.Error("the command should be correct:", h.Commands)
+	}
+
+	// Invalid gzip data.
+	h = &mocks.CommandHandler{}
+	handler = CommandHandler(h, mocks.CommandType)
+	r = httptest.NewRequest("POST", "/", strings.NewReader("not gzip"))
+	r.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	if len(h.Commands) != 0 {
+		t.Error("there should be no commands handled:", h.Commands)
+	}
+}
+
+func TestCommandHandlerGzipBomb(t *testing.T) {
+	h := &mocks.CommandHandler{}
+	handler := CommandHandler(h, mocks.CommandType)
+
+	content := strings.Repeat("a", int(MaxCommandSize))
+	body := gzipBytes(t, []byte(`{"ID":"`+uuid.New().String()+`","Content":"`+content+`"}`))
+
+	if int64(len(body)) >= MaxCommandSize {
+		t.Fatal("the compressed body should be smaller than the max size:", len(body))
+	}
+
+	r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	if len(h.Commands) != 0 {
+		t.Error("there should be no commands handled:", h.Commands)
+	}
+
+	// Uncompressed bodies are not limited.
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`{"ID":"`+uuid.New().String()+`","Content":"`+content+`"}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	if len(h.Commands) != 1 {
+		t.Error("the command should be handled:", len(h.Commands))
+	}
+}
+
+func TestCommandHandlerVersion(t *testing.T) {
+	eh.RegisterCommandVersions(mocks.CommandType, 2, 3)
+	defer eh.UnregisterCommandVersions(mocks.CommandType)
+
+	testCases := map[string]struct {
+		version string
+		status  int
+	}{
+		"no version":          {"", http.StatusOK},
+		"supported version":   {"2", http.StatusOK},
+		"unsupported version": {"4", http.StatusUnsupportedMediaType},
+		"old version":         {"1", http.StatusUnsupportedMediaType},
+		"invalid version":     {"v2", http.StatusBadRequest},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			h := &mocks.CommandHandler{}
+			handler := CommandHandler(h, mocks.CommandType)
+
+			body := `{"ID":"` + uuid.New().String() + `","Content":"content"}`
+			r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+			if tc.version != "" {
+				r.Header.Set(CommandVersionHeader, tc.version)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Error("the status should be correct:", w.Code, w.Body.String())
+			}
+
+			if tc.status == http.StatusOK && len(h.Commands) != 1 {
+				t.Error("the command should be handled:", h.Commands)
+			} else if tc.status != http.StatusOK && len(h.Commands) != 0 {
+				t.Error("the command should not be handled:", h.Commands)
+			}
+
+			if tc.status == http.StatusUnsupportedMediaType {
+				if v := w.Header().Get(SupportedCommandVersionsHeader); v != "2-3" {
+					t.Error("the supported versions header should be correct:", v)
+				}
+
+				if !strings.Contains(w.Body.String(), "supported: 2-3") {
+					t.Error("the body should have the supported versions:", w.Body.String())
+				}
+			}
+		})
+	}
+
+	// Also checked when routing by type.
+	h := &mocks.CommandHandler{}
+	handler := CommandTypeRouter(h, "")
+
+	body := `{"type":"Command","ID":"` + uuid.New().String() + `","Content":"content"}`
+	r := httptest.NewRequest("POST", "/commands", strings.NewReader(body))
+	r.Header.Set(CommandVersionHeader, "4")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnsupportedMediaType || len(h.Commands) != 0 {
+		t.Error("the command should not be handled:", w.Code, h.Commands)
+	}
+}
+
+func TestCommandTypeRouter(t *testing.T) {
+	h := &mocks.CommandHandler{}
+	handler := CommandTypeRouter(h, "")
+
+	id1, id2 := uuid.New(), uuid.New()
+	bodies := []string{
+		`{"type":"Command","ID":"` + id1.String() + `","Content":"content1"}`,
+		`{"type":"CommandOther","ID":"` + id2.String() + `","Content":"content2"}`,
+	}
+
+	for _, body := range bodies {
+		r := httptest.NewRequest("POST", "/commands", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Error("the status should be correct:", w.Code, w.Body.String())
+		}
+	}
+
+	expected := []eh.Command{
+		&mocks.Command{ID: id1, Content: "content1"},
+		&mocks.CommandOther{ID: id2, Content: "content2"},
+	}
+	if !reflect.DeepEqual(h.Commands, expected) {
+		t.Error("the commands should be correct:", h.Commands)
+	}
+
+	// Custom type field.
+	h = &mocks.CommandHandler{}
+	handler = CommandTypeRouter(h, "command")
+
+	body := `{"command":"CommandOther","ID":"` + id1.String() + `","Content":"content"}`
+	r := httptest.NewRequest("POST", "/commands", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Error("the status should be correct:", w.Code, w.Body.String())
+	}
+
+	expected = []eh.Command{&mocks.CommandOther{ID: id1, Content: "content"}}
+	if !reflect.DeepEqual(h.Commands, expected) {
+		t.Error("the commands should be correct:", h.Commands)
+	}
+}
+
+func TestCommandTypeRouterNamespace(t *testing.T) {
+	namespace.RegisterCommand("ns1", func() eh.Command { return &namespaceCommand1{} })
+	defer namespace.UnregisterCommand("ns1", namespaceCommandType)
+
+	namespace.RegisterCommand("ns2", func() eh.Command { return &namespaceCommand2{} })
+	defer namespace.UnregisterCommand("ns2", namespaceCommandType)
+
+	h := &mocks.CommandHandler{}
+	handler := CommandTypeRouter(h, "")
+
+	id := uuid.New()
+	body := `{"type":"NamespaceCommand","ID":"` + id.String() + `"}`
+
+	for _, ns := range []string{"ns1", "ns2"} {
+		r := httptest.NewRequest("POST", "/commands", strings.NewReader(body))
+		r = r.WithContext(namespace.NewContext(r.Context(), ns))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Error("the status should be correct:", w.Code, w.Body.String())
+		}
+
+		if ctxNS := namespace.FromContext(h.Context); ctxNS != ns {
+			t.Error("the command should be handled in the namespace:", ctxNS)
+		}
+	}
+
+	expected := []eh.Command{
+		&namespaceCommand1{ID: id},
+		&namespaceCommand2{ID: id},
+	}
+	if !reflect.DeepEqual(h.Commands, expected) {
+		t.Error("the commands should be correct:", h.Commands)
+	}
+
+	// Not registered in the default namespace.
+	r := httptest.NewRequest("POST", "/commands", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("the status should be correct:", w.Code, w.Body.String())
+	}
+}
+
+const namespaceCommandType eh.CommandType = "NamespaceCommand"
+
+type namespaceCommand1 struct {
+	ID uuid.UUID
+}
+
+func (c *namespaceCommand1) AggregateID() uuid.UUID          { return c.ID }
+func (c *namespaceCommand1) AggregateType() eh.AggregateType { return mocks.AggregateType }
+func (c *namespaceCommand1) CommandType() eh.CommandType     { return namespaceCommandType }
+
+type namespaceCommand2 struct {
+	ID uuid.UUID
+}
+
+func (c *namespaceCommand2) AggregateID() uuid.UUID          { return c.ID }
+func (c *namespaceCommand2) AggregateType() eh.AggregateType { return mocks.AggregateType }
+func (c *namespaceCommand2) CommandType() eh.CommandType     { return namespaceCommandType }
+
+func TestCommandTypeRouterErrors(t *testing.T) {
+	testCases := map[string]struct {
+		method string
+		body   string
+		status int
+	}{
+		"unknown type": {
+			"POST",
+			`{"type":"Unknown","Content":"content"}`,
+			http.StatusBadRequest,
+		},
+		"missing type": {
+			"POST",
+			`{"Content":"content"}`,
+			http.StatusBadRequest,
+		},
+		"invalid type": {
+			"POST",
+			`{"type":42,"Content":"content"}`,
+			http.StatusBadRequest,
+		},
+		"invalid body": {
+			"POST",
+			`not json`,
+			http.StatusBadRequest,
+		},
+		"invalid method": {
+			"GET",
+			``,
+			http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			h := &mocks.CommandHandler{}
+			handler := CommandTypeRouter(h, "type")
+
+			r := httptest.NewRequest(tc.method, "/commands", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Error("the status should be correct:", w.Code, w.Body.String())
+			}
+
+			if len(h.Commands) != 0 {
+				t.Error("there should be no commands handled:", h.Commands)
+			}
+		})
+	}
+}
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(b); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := gw.Close(); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	return buf.Bytes()
+}
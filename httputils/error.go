@@ -0,0 +1,73 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorHandler is a HTTP handler that returns errors instead of writing them
+// to the response, which makes it possible to wrap it with middleware that
+// logs, measures or renders the errors. Errors returned by the handlers in
+// this package are of type *Error. The ServeHTTP method writes errors as the
+// handlers in this package do by default, using WriteError.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP implements the ServeHTTP method of the http.Handler interface.
+func (h ErrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		WriteError(w, err)
+	}
+}
+
+// Error is an error with the HTTP status and message to respond with.
+type Error struct {
+	// Status is the HTTP status code.
+	Status int
+	// Message is the message to respond with, safe to show to clients.
+	Message string
+	// Header is any extra headers to respond with.
+	Header http.Header
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error implements the Error method of the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WriteError writes an error as a plain text response. An *Error is written
+// with its status, message and headers, other errors as an internal server error.
+func WriteError(w http.ResponseWriter, err error) {
+	var httpErr *Error
+	if !errors.As(err, &httpErr) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	for k, v := range httpErr.Header {
+		w.Header()[k] = v
+	}
+
+	http.Error(w, httpErr.Message, httpErr.Status)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestErrorHandler_Wrapped(t *testing.T) {
+	handlerErr := errors.New("handler error")
+	h := &mocks.CommandHandler{Err: handlerErr}
+
+	// A wrapper that observes the error and renders it as JSON.
+	var observed error
+
+	handler := jsonErrors(CommandErrorHandler(h, mocks.CommandType), &observed)
+
+	body := `{"ID":"` + uuid.New().String() + `","Content":"content"}`
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if !errors.Is(observed, handlerErr) {
+		t.Error("the error should be observed:", observed)
+	}
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	var res map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal("there should be no error:", err, w.Body.String())
+	}
+
+	if res["error"] != "could not handle command: handler error" {
+		t.Error("the error should be rendered:", res)
+	}
+
+	// Query errors.
+	repo := &mocks.Repo{LoadErr: eh.ErrEntityNotFound}
+	handler = jsonErrors(QueryErrorHandler(repo), &observed)
+
+	r = httptest.NewRequest("GET", "/"+uuid.New().String(), nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if !errors.Is(observed, eh.ErrEntityNotFound) {
+		t.Error("the error should be observed:", observed)
+	}
+
+	if w.Code != http.StatusNotFound {
+		t.Error("the status should be correct:", w.Code)
+	}
+}
+
+func TestErrorHandler_ServeHTTP(t *testing.T) {
+	handler := ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return &Error{
+			Status:  http.StatusTooManyRequests,
+			Message: "slow down",
+			Header:  http.Header{"Retry-After": []string{"2"}},
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusTooManyRequests || w.Body.String() != "slow down\n" {
+		t.Error("the error should be written:", w.Code, w.Body.String())
+	}
+
+	if w.Header().Get("Retry-After") != "2" {
+		t.Error("the header should be written:", w.Header())
+	}
+
+	// Other errors are internal server errors.
+	handler = ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("other error")
+	})
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "other error\n" {
+		t.Error("the error should be written:", w.Code, w.Body.String())
+	}
+}
+
+// jsonErrors is an error rendering middleware, recording the observed error.
+func jsonErrors(h ErrorHandler, observed *error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := h(w, r)
+		*observed = err
+
+		if err == nil {
+			return
+		}
+
+		status := http.StatusInternalServerError
+
+		var httpErr *Error
+		if errors.As(err, &httpErr) {
+			status = httpErr.Status
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	})
+}
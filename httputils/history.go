@@ -0,0 +1,137 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"path"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// AggregateHistoryHandler returns the full event history of an aggregate, for
+// inspection by support engineers. It expects a GET with the aggregate ID as
+// the last part of the path and responds with a JSON array of the events in
+// version order, each marshaled by the codec, which must produce JSON (for
+// example codec/json.EventCodec). The result is compressed with gzip if the
+// client accepts it.
+//
+// The history exposes the raw event data, so the handler responds with
+// "404 Not Found" to all requests unless enabled with WithHistoryEnabled.
+func AggregateHistoryHandler(store eh.EventStore, codec eh.EventCodec, options ...HistoryOption) http.Handler {
+	return AggregateHistoryErrorHandler(store, codec, options...)
+}
+
+// HistoryOption is an option setter used to configure AggregateHistoryHandler.
+type HistoryOption func(*historyConfig)
+
+type historyConfig struct {
+	enabled bool
+}
+
+// WithHistoryEnabled enables the AggregateHistoryHandler, which is disabled by
+// default. Typically set from a debug flag in the configuration.
+func WithHistoryEnabled(enabled bool) HistoryOption {
+	return func(c *historyConfig) {
+		c.enabled = enabled
+	}
+}
+
+// AggregateHistoryErrorHandler is an AggregateHistoryHandler which returns any
+// errors as an *Error, to be written by error handling middleware.
+func AggregateHistoryErrorHandler(store eh.EventStore, codec eh.EventCodec, options ...HistoryOption) ErrorHandler {
+	var c historyConfig
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(&c)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if !c.enabled {
+			return &Error{
+				Status:  http.StatusNotFound,
+				Message: "not found",
+			}
+		}
+
+		if r.Method != "GET" {
+			return &Error{
+				Status:  http.StatusMethodNotAllowed,
+				Message: "unsupported method: " + r.Method,
+			}
+		}
+
+		_, idStr := path.Split(r.URL.Path)
+
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return &Error{
+				Status:  http.StatusBadRequest,
+				Message: "could not parse ID: " + err.Error(),
+				Err:     err,
+			}
+		}
+
+		events, err := store.Load(r.Context(), id)
+		if errors.Is(err, eh.ErrAggregateNotFound) || (err == nil && len(events) == 0) {
+			return &Error{
+				Status:  http.StatusNotFound,
+				Message: "could not find aggregate",
+				Err:     err,
+			}
+		} else if err != nil {
+			return &Error{
+				Status:  http.StatusInternalServerError,
+				Message: "could not load events: " + err.Error(),
+				Err:     err,
+			}
+		}
+
+		var buf bytes.Buffer
+
+		buf.WriteByte('[')
+
+		for i, event := range events {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			b, err := codec.MarshalEvent(r.Context(), event)
+			if err != nil {
+				return &Error{
+					Status:  http.StatusInternalServerError,
+					Message: "could not encode event: " + err.Error(),
+					Err:     err,
+				}
+			}
+
+			buf.Write(b)
+		}
+
+		buf.WriteByte(']')
+
+		w.Header().Set("Content-Type", "application/json")
+		writeResponse(w, r, buf.Bytes())
+
+		return nil
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	jsoncodec "github.com/looplab/eventhorizon/codec/json"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestAggregateHistoryHandler(t *testing.T) {
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	store := &mocks.EventStore{
+		Events: []eh.Event{
+			eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, 1)),
+			eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, 2)),
+			eh.NewEvent(mocks.EventOtherType, nil, timestamp,
+				eh.ForAggregate(mocks.AggregateType, id, 3)),
+		},
+	}
+	codec := &jsoncodec.EventCodec{}
+	handler := AggregateHistoryHandler(store, codec, WithHistoryEnabled(true))
+
+	r := httptest.NewRequest("GET", "/"+id.String(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatal("the status should be correct:", w.Code, w.Body.String())
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Error("the content type should be correct:", ct)
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(raw) != len(store.Events) {
+		t.Fatal("there should be all events:", len(raw))
+	}
+
+	for i, b := range raw {
+		event, _, err := codec.UnmarshalEvent(context.Background(), b)
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if err := eh.CompareEvents(event, store.Events[i]); err != nil {
+			t.Error("the event should be correct:", i, err)
+		}
+	}
+}
+
+func TestAggregateHistoryHandler_Errors(t *testing.T) {
+	id := uuid.New()
+	store := &mocks.EventStore{
+		Events: []eh.Event{
+			eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, time.Now(),
+				eh.ForAggregate(mocks.AggregateType, id, 1)),
+		},
+	}
+	codec := &jsoncodec.EventCodec{}
+
+	testCases := map[string]struct {
+		handler http.Handler
+		method  string
+		path    string
+		status  int
+	}{
+		"not enabled": {
+			AggregateHistoryHandler(store, codec),
+			"GET", "/" + id.String(), http.StatusNotFound,
+		},
+		"disabled": {
+			AggregateHistoryHandler(store, codec, WithHistoryEnabled(false)),
+			"GET", "/" + id.String(), http.StatusNotFound,
+		},
+		"method": {
+			AggregateHistoryHandler(store, codec, WithHistoryEnabled(true)),
+			"POST", "/" + id.String(), http.StatusMethodNotAllowed,
+		},
+		"invalid ID": {
+			AggregateHistoryHandler(store, codec, WithHistoryEnabled(true)),
+			"GET", "/not-an-id", http.StatusBadRequest,
+		},
+		"no events": {
+			AggregateHistoryHandler(&mocks.EventStore{}, codec, WithHistoryEnabled(true)),
+			"GET", "/" + id.String(), http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			w := httptest.NewRecorder()
+			tc.handler.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Error("the status should be correct:", w.Code, w.Body.String())
+			}
+		})
+	}
+}
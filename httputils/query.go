@@ -15,10 +15,13 @@
 package httputils
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"path"
+	"strconv"
+	"strings"
 
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/uuid"
@@ -26,13 +29,21 @@ import (
 
 // QueryHandler returns one or all items from a eventhorizon.ReadRepo. If the
 // URL ends with a / it will return all items, otherwise it will try to use the
-// last part of the path as an ID to return one item.
+// last part of the path as an ID to return one item. The result is compressed
+// with gzip if the client accepts it.
 func QueryHandler(repo eh.ReadRepo) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != "GET" {
-			http.Error(w, "unsupported method: "+r.Method, http.StatusMethodNotAllowed)
+	return QueryErrorHandler(repo)
+}
 
-			return
+// QueryErrorHandler is a QueryHandler which returns any errors as an *Error,
+// to be written by error handling middleware.
+func QueryErrorHandler(repo eh.ReadRepo) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != "GET" {
+			return &Error{
+				Status:  http.StatusMethodNotAllowed,
+				Message: "unsupported method: " + r.Method,
+			}
 		}
 
 		var (
@@ -44,38 +55,93 @@ func QueryHandler(repo eh.ReadRepo) http.Handler {
 		_, idStr := path.Split(r.URL.Path)
 		if idStr == "" {
 			if data, err = repo.FindAll(r.Context()); err != nil {
-				http.Error(w, "could not find items: "+err.Error(), http.StatusInternalServerError)
-
-				return
+				return &Error{
+					Status:  http.StatusInternalServerError,
+					Message: "could not find items: " + err.Error(),
+					Err:     err,
+				}
 			}
 		} else {
 			id, err := uuid.Parse(idStr)
 			if err != nil {
-				http.Error(w, "could not parse ID: "+err.Error(), http.StatusBadRequest)
-
-				return
+				return &Error{
+					Status:  http.StatusBadRequest,
+					Message: "could not parse ID: " + err.Error(),
+					Err:     err,
+				}
 			}
 
 			if data, err = repo.Find(r.Context(), id); err != nil {
 				if errors.Is(err, eh.ErrEntityNotFound) {
-					http.Error(w, "could not find item", http.StatusNotFound)
-
-					return
+					return &Error{
+						Status:  http.StatusNotFound,
+						Message: "could not find item",
+						Err:     err,
+					}
 				}
 
-				http.Error(w, "could not find item: "+err.Error(), http.StatusInternalServerError)
-
-				return
+				return &Error{
+					Status:  http.StatusInternalServerError,
+					Message: "could not find item: " + err.Error(),
+					Err:     err,
+				}
 			}
 		}
 
 		b, err := json.Marshal(data)
 		if err != nil {
-			http.Error(w, "could not encode result: "+err.Error(), http.StatusInternalServerError)
-
-			return
+			return &Error{
+				Status:  http.StatusInternalServerError,
+				Message: "could not encode result: " + err.Error(),
+				Err:     err,
+			}
 		}
 
+		writeResponse(w, r, b)
+
+		return nil
+	}
+}
+
+// writeResponse writes a response body, compressed with gzip if the client
+// has indicated that it is accepted with the "Accept-Encoding" header.
+func writeResponse(w http.ResponseWriter, r *http.Request, b []byte) {
+	if !acceptsGzip(r) {
 		w.Write(b)
-	})
+
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	gw := gzip.NewWriter(w)
+	gw.Write(b)
+	gw.Close()
+}
+
+// acceptsGzip checks if gzip is in the "Accept-Encoding" header of a request,
+// without a quality value of 0 which means that it is not acceptable.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(enc, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), "gzip") {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if len(param) < 2 || !strings.EqualFold(param[:2], "q=") {
+				continue
+			}
+
+			if q, err := strconv.ParseFloat(param[2:], 64); err != nil || q <= 0 {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
 }
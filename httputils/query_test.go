@@ -0,0 +1,110 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestQueryHandler(t *testing.T) {
+	id := uuid.New()
+	repo := &mocks.Repo{
+		Entity: &mocks.Model{ID: id, Content: "content"},
+	}
+	handler := QueryHandler(repo)
+
+	expected, err := json.Marshal(repo.Entity)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r := httptest.NewRequest("GET", "/"+id.String(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	if enc := w.Header().Get("Content-Encoding"); enc != "" {
+		t.Error("the content encoding should be empty:", enc)
+	}
+
+	if w.Body.String() != string(expected) {
+		t.Error("the body should be correct:", w.Body.String())
+	}
+}
+
+func TestQueryHandlerGzip(t *testing.T) {
+	id := uuid.New()
+	repo := &mocks.Repo{
+		Entity: &mocks.Model{ID: id, Content: "content"},
+	}
+	handler := QueryHandler(repo)
+
+	expected, err := json.Marshal(repo.Entity)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r := httptest.NewRequest("GET", "/"+id.String(), nil)
+	r.Header.Set("Accept-Encoding", "deflate, gzip;q=0.8")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Error("the status should be correct:", w.Code)
+	}
+
+	if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Error("the content encoding should be gzip:", enc)
+	}
+
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	b, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if string(b) != string(expected) {
+		t.Error("the body should be correct:", string(b))
+	}
+
+	// Not acceptable with a quality value of 0.
+	r = httptest.NewRequest("GET", "/"+id.String(), nil)
+	r.Header.Set("Accept-Encoding", "deflate, gzip;q=0")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if enc := w.Header().Get("Content-Encoding"); enc != "" {
+		t.Error("the content should not be encoded:", enc)
+	}
+
+	if w.Body.String() != string(expected) {
+		t.Error("the body should be correct:", w.Body.String())
+	}
+}
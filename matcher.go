@@ -14,6 +14,11 @@
 
 package eventhorizon
 
+import (
+	"encoding/json"
+	"reflect"
+)
+
 // EventMatcher matches, for example on event types, aggregate types etc.
 type EventMatcher interface {
 	// Match returns true if the matcher matches an event.
@@ -75,3 +80,90 @@ func (matchers MatchAll) Match(e Event) bool {
 
 	return true
 }
+
+// MatchNot matches when the inner matcher does not match. Note that nil events
+// will match if the inner matcher does not match them. A MatchNot without an
+// inner matcher never matches.
+type MatchNot struct {
+	Matcher EventMatcher
+}
+
+// Match implements the Match method of the EventMatcher interface.
+func (m MatchNot) Match(e Event) bool {
+	if m.Matcher == nil {
+		return false
+	}
+
+	return !m.Matcher.Match(e)
+}
+
+// MatchMetadata matches events which have all of the metadata keys set to the
+// given values, nil events never match. Numbers are compared by value, so that
+// an int matches the float64 of a matcher unmarshaled from JSON, or the int32
+// and int64 of metadata decoded from BSON.
+type MatchMetadata map[string]interface{}
+
+// Match implements the Match method of the EventMatcher interface.
+func (metadata MatchMetadata) Match(e Event) bool {
+	if e == nil {
+		return false
+	}
+
+	m := e.Metadata()
+
+	for k, v := range metadata {
+		if mv, ok := m[k]; !ok || !metadataValueEqual(mv, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// metadataValueEqual compares numbers of any type by value, and other values
+// with reflect.DeepEqual.
+func metadataValueEqual(v1, v2 interface{}) bool {
+	if n1, ok := number(v1); ok {
+		if n2, ok := number(v2); ok {
+			return n1 == n2
+		}
+	}
+
+	return reflect.DeepEqual(v1, v2)
+}
+
+// number converts a numeric value to a float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownMatcher is when a matcher can not be marshaled or unmarshaled
+// because it is not one of the built-in matchers.
+var ErrUnknownMatcher = errors.New("unknown matcher")
+
+// The kinds of the built-in matchers, used when marshaling.
+const (
+	matcherKindEvents     = "events"
+	matcherKindAggregates = "aggregates"
+	matcherKindAny        = "any"
+	matcherKindAll        = "all"
+	matcherKindNot        = "not"
+	matcherKindMetadata   = "metadata"
+)
+
+// matcherJSON is the JSON representation of a matcher.
+type matcherJSON struct {
+	Kind           string                 `json:"kind"`
+	EventTypes     []EventType            `json:"event_types,omitempty"`
+	AggregateTypes []AggregateType        `json:"aggregate_types,omitempty"`
+	Matchers       []*matcherJSON         `json:"matchers,omitempty"`
+	Matcher        *matcherJSON           `json:"matcher,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// MarshalMatcher marshals one of the built-in matchers (including composite
+// matchers) to JSON, for example to send a subscription filter to a remote
+// event bus. Returns ErrUnknownMatcher for any other matcher.
+func MarshalMatcher(m EventMatcher) ([]byte, error) {
+	mj, err := newMatcherJSON(m)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(mj)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal matcher: %w", err)
+	}
+
+	return b, nil
+}
+
+// UnmarshalMatcher unmarshals a matcher that has been marshaled with
+// MarshalMatcher. Returns ErrUnknownMatcher for unknown matcher kinds.
+func UnmarshalMatcher(b []byte) (EventMatcher, error) {
+	var mj matcherJSON
+	if err := json.Unmarshal(b, &mj); err != nil {
+		return nil, fmt.Errorf("could not unmarshal matcher: %w", err)
+	}
+
+	return mj.matcher()
+}
+
+func newMatcherJSON(m EventMatcher) (*matcherJSON, error) {
+	switch m := m.(type) {
+	case MatchEvents:
+		return &matcherJSON{Kind: matcherKindEvents, EventTypes: m}, nil
+	case MatchAggregates:
+		return &matcherJSON{Kind: matcherKindAggregates, AggregateTypes: m}, nil
+	case MatchAny:
+		matchers, err := newMatcherJSONs(m)
+		if err != nil {
+			return nil, err
+		}
+
+		return &matcherJSON{Kind: matcherKindAny, Matchers: matchers}, nil
+	case MatchAll:
+		matchers, err := newMatcherJSONs(m)
+		if err != nil {
+			return nil, err
+		}
+
+		return &matcherJSON{Kind: matcherKindAll, Matchers: matchers}, nil
+	case MatchNot:
+		matcher, err := newMatcherJSON(m.Matcher)
+		if err != nil {
+			return nil, err
+		}
+
+		return &matcherJSON{Kind: matcherKindNot, Matcher: matcher}, nil
+	case MatchMetadata:
+		return &matcherJSON{Kind: matcherKindMetadata, Metadata: m}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnknownMatcher, m)
+	}
+}
+
+func newMatcherJSONs(matchers []EventMatcher) ([]*matcherJSON, error) {
+	mjs := make([]*matcherJSON, len(matchers))
+
+	for i, m := range matchers {
+		mj, err := newMatcherJSON(m)
+		if err != nil {
+			return nil, err
+		}
+
+		mjs[i] = mj
+	}
+
+	return mjs, nil
+}
+
+func (mj *matcherJSON) matcher() (EventMatcher, error) {
+	switch mj.Kind {
+	case matcherKindEvents:
+		return MatchEvents(mj.EventTypes), nil
+	case matcherKindAggregates:
+		return MatchAggregates(mj.AggregateTypes), nil
+	case matcherKindAny:
+		matchers, err := matchersFromJSON(mj.Matchers)
+		if err != nil {
+			return nil, err
+		}
+
+		return MatchAny(matchers), nil
+	case matcherKindAll:
+		matchers, err := matchersFromJSON(mj.Matchers)
+		if err != nil {
+			return nil, err
+		}
+
+		return MatchAll(matchers), nil
+	case matcherKindNot:
+		if mj.Matcher == nil {
+			return nil, fmt.Errorf("could not unmarshal matcher: missing inner matcher for '%s'", mj.Kind)
+		}
+
+		matcher, err := mj.Matcher.matcher()
+		if err != nil {
+			return nil, err
+		}
+
+		return MatchNot{Matcher: matcher}, nil
+	case matcherKindMetadata:
+		return MatchMetadata(mj.Metadata), nil
+	default:
+		return nil, fmt.Errorf("%w: kind '%s'", ErrUnknownMatcher, mj.Kind)
+	}
+}
+
+func matchersFromJSON(mjs []*matcherJSON) ([]EventMatcher, error) {
+	matchers := make([]EventMatcher, len(mjs))
+
+	for i, mj := range mjs {
+		if mj == nil {
+			return nil, errors.New("could not unmarshal matcher: missing matcher")
+		}
+
+		m, err := mj.matcher()
+		if err != nil {
+			return nil, err
+		}
+
+		matchers[i] = m
+	}
+
+	return matchers, nil
+}
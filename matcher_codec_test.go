@@ -0,0 +1,125 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMatcherCodec(t *testing.T) {
+	m := MatchAny{
+		MatchAll{
+			MatchEvents{"et1", "et2"},
+			MatchAggregates{"at"},
+			MatchNot{MatchMetadata{"user": "alice"}},
+		},
+		MatchNot{
+			MatchAny{
+				MatchEvents{"et3"},
+				MatchMetadata{"tenant": "t1", "region": "eu"},
+			},
+		},
+	}
+
+	b, err := MarshalMatcher(m)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	m2, err := UnmarshalMatcher(b)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(m2, m) {
+		t.Errorf("the matcher should be correct:\ngot:  %#v\nwant: %#v", m2, m)
+	}
+
+	// The unmarshaled matcher should behave the same.
+	e := NewEvent("et1", nil, time.Now(), ForAggregate("at", uuid.New(), 1),
+		WithMetadata(map[string]interface{}{"user": "bob"}))
+	if !m2.Match(e) {
+		t.Error("the matcher should match the event")
+	}
+
+	e = NewEvent("et1", nil, time.Now(), ForAggregate("at", uuid.New(), 1),
+		WithMetadata(map[string]interface{}{"user": "alice"}))
+	if !m2.Match(e) {
+		t.Error("the matcher should match the event")
+	}
+
+	e = NewEvent("et3", nil, time.Now(), ForAggregate("at", uuid.New(), 1),
+		WithMetadata(map[string]interface{}{"user": "alice"}))
+	if m2.Match(e) {
+		t.Error("the matcher should not match the event")
+	}
+}
+
+func TestMatcherCodecNumericMetadata(t *testing.T) {
+	b, err := MarshalMatcher(MatchMetadata{"tenant": "t1", "priority": 2})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	m, err := UnmarshalMatcher(b)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The number is unmarshaled as a float64, but should still match the int
+	// metadata of events created in process.
+	e := NewEvent("et", nil, time.Now(),
+		WithMetadata(map[string]interface{}{"tenant": "t1", "priority": 2}))
+	if !m.Match(e) {
+		t.Error("the matcher should match the event")
+	}
+
+	e = NewEvent("et", nil, time.Now(),
+		WithMetadata(map[string]interface{}{"tenant": "t1", "priority": 3}))
+	if m.Match(e) {
+		t.Error("the matcher should not match the event")
+	}
+}
+
+type customMatcher struct{}
+
+func (customMatcher) Match(Event) bool { return true }
+
+func TestMatcherCodecUnknown(t *testing.T) {
+	if _, err := MarshalMatcher(MatchAll{customMatcher{}}); !errors.Is(err, ErrUnknownMatcher) {
+		t.Error("there should be an unknown matcher error:", err)
+	}
+
+	if _, err := UnmarshalMatcher([]byte(`{"kind":"regexp"}`)); !errors.Is(err, ErrUnknownMatcher) {
+		t.Error("there should be an unknown matcher error:", err)
+	}
+
+	if _, err := UnmarshalMatcher([]byte(`{"kind":"any","matchers":[{"kind":"other"}]}`)); !errors.Is(err, ErrUnknownMatcher) {
+		t.Error("there should be an unknown matcher error:", err)
+	}
+
+	if _, err := UnmarshalMatcher([]byte(`{"kind":"not"}`)); err == nil {
+		t.Error("there should be an error")
+	}
+
+	if _, err := UnmarshalMatcher([]byte(`not json`)); err == nil {
+		t.Error("there should be an error")
+	}
+}
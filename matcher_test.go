@@ -142,3 +142,72 @@ func TestMatchAll(t *testing.T) {
 		t.Error("match any of should not match the event")
 	}
 }
+
+func TestMatchNot(t *testing.T) {
+	et := EventType("et")
+	m := MatchNot{MatchEvents{et}}
+
+	if !m.Match(nil) {
+		t.Error("match not should match nil event")
+	}
+
+	e := NewEvent(et, nil, time.Now())
+	if m.Match(e) {
+		t.Error("match not should not match the event")
+	}
+
+	e = NewEvent("not-matched", nil, time.Now())
+	if !m.Match(e) {
+		t.Error("match not should match the event")
+	}
+
+	// Without an inner matcher nothing is matched.
+	if (MatchNot{}).Match(e) {
+		t.Error("match not without a matcher should not match the event")
+	}
+}
+
+func TestMatchMetadata(t *testing.T) {
+	m := MatchMetadata{"user": "alice", "num": 1}
+
+	if m.Match(nil) {
+		t.Error("match metadata should not match nil event")
+	}
+
+	e := NewEvent("test", nil, time.Now(),
+		WithMetadata(map[string]interface{}{"user": "alice", "num": 1, "other": true}))
+	if !m.Match(e) {
+		t.Error("match metadata should match the event")
+	}
+
+	e = NewEvent("test", nil, time.Now(),
+		WithMetadata(map[string]interface{}{"user": "bob", "num": 1}))
+	if m.Match(e) {
+		t.Error("match metadata should not match the event")
+	}
+
+	e = NewEvent("test", nil, time.Now(),
+		WithMetadata(map[string]interface{}{"user": "alice"}))
+	if m.Match(e) {
+		t.Error("match metadata should not match the event")
+	}
+
+	// Numbers of other types are compared by value.
+	e = NewEvent("test", nil, time.Now(),
+		WithMetadata(map[string]interface{}{"user": "alice", "num": int64(1)}))
+	if !m.Match(e) {
+		t.Error("match metadata should match the event")
+	}
+
+	e = NewEvent("test", nil, time.Now(),
+		WithMetadata(map[string]interface{}{"user": "alice", "num": 1.5}))
+	if m.Match(e) {
+		t.Error("match metadata should not match the event")
+	}
+
+	e = NewEvent("test", nil, time.Now(),
+		WithMetadata(map[string]interface{}{"user": "alice", "num": "1"}))
+	if m.Match(e) {
+		t.Error("match metadata should not match the event")
+	}
+}
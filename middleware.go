@@ -54,3 +54,89 @@ func UseEventHandlerMiddleware(h EventHandler, middleware ...EventHandlerMiddlew
 
 	return h
 }
+
+// NamedCommandHandlerMiddleware names a middleware, to be able to list the
+// composed middleware of a handler with CommandHandlerMiddlewareChain, for
+// example to log or verify the order of the middleware.
+func NamedCommandHandlerMiddleware(name string, m CommandHandlerMiddleware) CommandHandlerMiddleware {
+	return func(h CommandHandler) CommandHandler {
+		return &namedCommandHandler{
+			CommandHandler: m(h),
+			name:           name,
+			inner:          h,
+		}
+	}
+}
+
+// CommandHandlerMiddlewareChain returns the names of the named middleware
+// wrapping a handler, from the outermost to the innermost (the order they were
+// passed to UseCommandHandlerMiddleware). The chain ends at the first handler
+// that is not wrapped by a named middleware.
+func CommandHandlerMiddlewareChain(h CommandHandler) []string {
+	var names []string
+
+	for {
+		nh, ok := h.(*namedCommandHandler)
+		if !ok {
+			return names
+		}
+
+		names = append(names, nh.name)
+		h = nh.inner
+	}
+}
+
+type namedCommandHandler struct {
+	CommandHandler
+	name  string
+	inner CommandHandler
+}
+
+// NamedEventHandlerMiddleware names a middleware, to be able to list the
+// composed middleware of a handler with EventHandlerMiddlewareChain, for
+// example to log or verify the order of the middleware. The named handler
+// implements EventHandlerChain with the handler created by the middleware as
+// inner handler.
+func NamedEventHandlerMiddleware(name string, m EventHandlerMiddleware) EventHandlerMiddleware {
+	return func(h EventHandler) EventHandler {
+		return &namedEventHandler{
+			EventHandler: m(h),
+			name:         name,
+			inner:        h,
+		}
+	}
+}
+
+// EventHandlerMiddlewareChain returns the names of the named middleware
+// wrapping a handler, from the outermost to the innermost (the order they were
+// passed to UseEventHandlerMiddleware). Unnamed middleware implementing
+// EventHandlerChain are passed through, the chain ends at the first other
+// handler.
+func EventHandlerMiddlewareChain(h EventHandler) []string {
+	var names []string
+
+	for h != nil {
+		switch c := h.(type) {
+		case *namedEventHandler:
+			names = append(names, c.name)
+			h = c.inner
+		case EventHandlerChain:
+			h = c.InnerHandler()
+		default:
+			return names
+		}
+	}
+
+	return names
+}
+
+type namedEventHandler struct {
+	EventHandler
+	name  string
+	inner EventHandler
+}
+
+// InnerHandler implements the InnerHandler method of the EventHandlerChain interface.
+func (h *namedEventHandler) InnerHandler() EventHandler {
+	return h.EventHandler
+}
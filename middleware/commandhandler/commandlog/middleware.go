@@ -0,0 +1,69 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commandlog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// NewMiddleware returns a new command log middleware that marshals every
+// command with the codec and appends it to the command log before handling it.
+// The outcome of the handling is recorded on the entry afterwards. Commands
+// that can not be logged will not be handled.
+func NewMiddleware(l eh.CommandLog, codec eh.CommandCodec) eh.CommandHandlerMiddleware {
+	return eh.CommandHandlerMiddleware(func(h eh.CommandHandler) eh.CommandHandler {
+		return eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+			data, err := codec.MarshalCommand(ctx, cmd)
+			if err != nil {
+				return fmt.Errorf("could not marshal command for the command log: %w", err)
+			}
+
+			entry := &eh.CommandLogEntry{
+				ID:            uuid.New(),
+				CommandType:   cmd.CommandType(),
+				AggregateType: cmd.AggregateType(),
+				AggregateID:   cmd.AggregateID(),
+				Data:          data,
+				Timestamp:     time.Now(),
+				Status:        eh.CommandPending,
+			}
+
+			if err := l.Append(ctx, entry); err != nil {
+				return fmt.Errorf("could not append command to the command log: %w", err)
+			}
+
+			handleErr := h.HandleCommand(ctx, cmd)
+
+			status, errMsg := eh.CommandSucceeded, ""
+			if handleErr != nil {
+				status, errMsg = eh.CommandFailed, handleErr.Error()
+			}
+
+			// The command has already been handled at this point, only log
+			// the failure to not hide the outcome from the caller.
+			if err := l.SetStatus(ctx, entry.ID, status, errMsg); err != nil {
+				log.Printf("eventhorizon: could not set status for command '%s' in the command log: %s", entry.ID, err)
+			}
+
+			return handleErr
+		})
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commandlog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/codec/json"
+	"github.com/looplab/eventhorizon/commandlog/memory"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func init() {
+	eh.RegisterCommand(func() eh.Command { return &mocks.Command{} })
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	inner := &mocks.CommandHandler{}
+	l := memory.NewCommandLog()
+	codec := &json.CommandCodec{}
+	h := eh.UseCommandHandlerMiddleware(inner, NewMiddleware(l, codec))
+
+	// Successful command.
+	cmd := &mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+	if err := h.HandleCommand(ctx, cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(inner.Commands, []eh.Command{cmd}) {
+		t.Error("the command should have been handled:", inner.Commands)
+	}
+
+	// Failed command.
+	handlerErr := errors.New("handler error")
+	inner.Err = handlerErr
+	cmdFailed := &mocks.Command{
+		ID:      uuid.New(),
+		Content: "failed",
+	}
+
+	if err := h.HandleCommand(ctx, cmdFailed); !errors.Is(err, handlerErr) {
+		t.Error("there should be a handler error:", err)
+	}
+
+	entries, err := l.Entries(ctx)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatal("there should be two entries:", len(entries))
+	}
+
+	if entries[0].Status != eh.CommandSucceeded || entries[0].Err != "" {
+		t.Error("the first command should have succeeded:", entries[0].Status, entries[0].Err)
+	}
+
+	if entries[1].Status != eh.CommandFailed || entries[1].Err != handlerErr.Error() {
+		t.Error("the second command should have failed:", entries[1].Status, entries[1].Err)
+	}
+
+	for i, c := range []*mocks.Command{cmd, cmdFailed} {
+		if entries[i].CommandType != mocks.CommandType ||
+			entries[i].AggregateType != mocks.AggregateType ||
+			entries[i].AggregateID != c.ID {
+			t.Error("the entry should be for the command:", entries[i])
+		}
+
+		logged, _, err := codec.UnmarshalCommand(ctx, entries[i].Data)
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if !reflect.DeepEqual(logged, c) {
+			t.Error("the logged command should be correct:", logged)
+		}
+	}
+}
+
+func TestMiddleware_LogError(t *testing.T) {
+	inner := &mocks.CommandHandler{}
+	h := eh.UseCommandHandlerMiddleware(inner, NewMiddleware(&failingLog{}, &json.CommandCodec{}))
+	cmd := &mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+
+	if err := h.HandleCommand(context.Background(), cmd); !errors.Is(err, errAppend) {
+		t.Error("there should be an append error:", err)
+	}
+
+	if len(inner.Commands) != 0 {
+		t.Error("the command should not have been handled:", inner.Commands)
+	}
+}
+
+var errAppend = errors.New("append error")
+
+type failingLog struct {
+	memory.CommandLog
+}
+
+func (l *failingLog) Append(ctx context.Context, entry *eh.CommandLogEntry) error {
+	return errAppend
+}
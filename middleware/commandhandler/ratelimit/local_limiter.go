@@ -0,0 +1,81 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LocalLimiter is an in-memory token bucket limiter. Each key has a bucket
+// that holds up to burst tokens and is refilled with one token per interval.
+type LocalLimiter struct {
+	burst    float64
+	interval time.Duration
+	buckets  map[string]*bucket
+	mu       sync.Mutex
+
+	// now is used to get the current time, can be replaced in tests.
+	now func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLocalLimiter creates a new LocalLimiter allowing bursts of up to burst
+// commands per key, refilled with one command per interval.
+func NewLocalLimiter(burst int, interval time.Duration) *LocalLimiter {
+	return &LocalLimiter{
+		burst:    float64(burst),
+		interval: interval,
+		buckets:  map[string]*bucket{},
+		now:      time.Now,
+	}
+}
+
+// Allow implements the Allow method of the Limiter interface.
+func (l *LocalLimiter) Allow(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	// Refill the bucket for the time passed since the last take.
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(l.interval)
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) * float64(l.interval)), nil
+	}
+
+	b.tokens--
+
+	return 0, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// ErrRateLimited is when a command is rejected because the rate limit for its
+// key is exceeded. The returned error is always an *Error, which can be used
+// to get the time until the command can be retried.
+var ErrRateLimited = errors.New("rate limited")
+
+// Limiter is a rate limiter for keys. LocalLimiter is an in-memory limiter,
+// distributed limiters (for example backed by Redis) can be used to share the
+// limits between multiple instances.
+type Limiter interface {
+	// Allow takes a token for the key if one is available and returns zero.
+	// If the limit is exceeded it returns the duration until a token will be
+	// available, or an error if it was not possible to check the limit.
+	Allow(ctx context.Context, key string) (time.Duration, error)
+}
+
+// KeyFunc returns the key to rate limit a command by. Commands with an empty
+// key are not rate limited.
+type KeyFunc func(ctx context.Context, cmd eh.Command) string
+
+// AggregateKey rate limits commands per aggregate.
+func AggregateKey(ctx context.Context, cmd eh.Command) string {
+	return cmd.AggregateID().String()
+}
+
+// NewMiddleware returns a new middleware that rate limits commands by the key
+// returned from a key func, for example the aggregate ID or a principal from
+// the context. Commands exceeding the limit are rejected with an *Error.
+func NewMiddleware(l Limiter, key KeyFunc) eh.CommandHandlerMiddleware {
+	return eh.CommandHandlerMiddleware(func(h eh.CommandHandler) eh.CommandHandler {
+		return eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+			k := key(ctx, cmd)
+			if k == "" {
+				return h.HandleCommand(ctx, cmd)
+			}
+
+			retryAfter, err := l.Allow(ctx, k)
+			if err != nil {
+				return fmt.Errorf("could not check rate limit: %w", err)
+			}
+
+			if retryAfter > 0 {
+				return &Error{Key: k, Delay: retryAfter}
+			}
+
+			return h.HandleCommand(ctx, cmd)
+		})
+	})
+}
+
+// Error is a rate limit error, which wraps ErrRateLimited.
+type Error struct {
+	// Key is the key that was rate limited.
+	Key string
+	// Delay is the duration until the command can be retried.
+	Delay time.Duration
+}
+
+var _ = eh.RetryableCommandError(&Error{})
+
+// Error implements the Error method of the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrRateLimited, e.Delay)
+}
+
+// RetryAfter implements the RetryAfter method of the
+// eventhorizon.RetryableCommandError interface.
+func (e *Error) RetryAfter() time.Duration {
+	return e.Delay
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *Error) Unwrap() error {
+	return ErrRateLimited
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	now := time.Now()
+	limiter := NewLocalLimiter(2, time.Second)
+	limiter.now = func() time.Time { return now }
+
+	inner := &mocks.CommandHandler{}
+	m := NewMiddleware(limiter, AggregateKey)
+	h := eh.UseCommandHandlerMiddleware(inner, m)
+
+	ctx := context.Background()
+	cmd := mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+
+	// A burst up to the limit is allowed.
+	for i := 0; i < 2; i++ {
+		if err := h.HandleCommand(ctx, cmd); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	// Commands beyond the limit are rejected.
+	err := h.HandleCommand(ctx, cmd)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Error("there should be a rate limited error:", err)
+	}
+
+	var rlErr *Error
+	if !errors.As(err, &rlErr) || rlErr.Delay != time.Second || rlErr.Key != cmd.ID.String() {
+		t.Error("the rate limit error should be correct:", rlErr)
+	}
+
+	// Other keys are not affected.
+	otherCmd := mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+	if err := h.HandleCommand(ctx, otherCmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Partially refilled.
+	now = now.Add(500 * time.Millisecond)
+
+	err = h.HandleCommand(ctx, cmd)
+	if !errors.As(err, &rlErr) || rlErr.Delay != 500*time.Millisecond {
+		t.Error("the rate limit error should be correct:", err)
+	}
+
+	// Recovers after the refill interval.
+	now = now.Add(500 * time.Millisecond)
+
+	if err := h.HandleCommand(ctx, cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if err := h.HandleCommand(ctx, cmd); !errors.Is(err, ErrRateLimited) {
+		t.Error("there should be a rate limited error:", err)
+	}
+
+	// The bucket is never refilled above the burst.
+	now = now.Add(time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if err := h.HandleCommand(ctx, cmd); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	if err := h.HandleCommand(ctx, cmd); !errors.Is(err, ErrRateLimited) {
+		t.Error("there should be a rate limited error:", err)
+	}
+
+	if len(inner.Commands) != 6 {
+		t.Error("the handled commands should be correct:", len(inner.Commands))
+	}
+}
+
+func TestMiddleware_EmptyKey(t *testing.T) {
+	limiter := NewLocalLimiter(1, time.Hour)
+	inner := &mocks.CommandHandler{}
+	m := NewMiddleware(limiter, func(ctx context.Context, cmd eh.Command) string {
+		return ""
+	})
+	h := eh.UseCommandHandlerMiddleware(inner, m)
+
+	for i := 0; i < 3; i++ {
+		if err := h.HandleCommand(context.Background(), mocks.Command{ID: uuid.New(), Content: "content"}); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+}
+
+func TestMiddleware_LimiterError(t *testing.T) {
+	limiterErr := errors.New("limiter error")
+	inner := &mocks.CommandHandler{}
+	m := NewMiddleware(errorLimiter{limiterErr}, AggregateKey)
+	h := eh.UseCommandHandlerMiddleware(inner, m)
+
+	if err := h.HandleCommand(context.Background(), mocks.Command{ID: uuid.New(), Content: "content"}); !errors.Is(err, limiterErr) {
+		t.Error("there should be a limiter error:", err)
+	}
+
+	if len(inner.Commands) != 0 {
+		t.Error("the command should not be handled")
+	}
+}
+
+type errorLimiter struct {
+	err error
+}
+
+func (l errorLimiter) Allow(ctx context.Context, key string) (time.Duration, error) {
+	return 0, l.err
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// ErrInternal is when a command handler panicked while handling a command. It
+// is the same as eventhorizon.ErrCommandInternal, which transports check for.
+var ErrInternal = eh.ErrCommandInternal
+
+// Logger is used to log recovered panics, satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// NewMiddleware returns a new middleware that recovers from panics in command
+// handlers, logs them and returns them as an *Error which wraps ErrInternal.
+// A stack trace is captured and logged by default.
+func NewMiddleware(options ...Option) eh.CommandHandlerMiddleware {
+	m := &middleware{
+		logger: log.Default(),
+		stack:  true,
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(m)
+	}
+
+	return eh.CommandHandlerMiddleware(func(h eh.CommandHandler) eh.CommandHandler {
+		return eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = m.recovered(cmd, r)
+				}
+			}()
+
+			return h.HandleCommand(ctx, cmd)
+		})
+	})
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*middleware)
+
+// WithLogger uses a logger for the recovered panics, use nil to not log.
+func WithLogger(l Logger) Option {
+	return func(m *middleware) {
+		m.logger = l
+	}
+}
+
+// WithoutStackTrace disables capturing of stack traces, for performance.
+func WithoutStackTrace() Option {
+	return func(m *middleware) {
+		m.stack = false
+	}
+}
+
+type middleware struct {
+	logger Logger
+	stack  bool
+}
+
+func (m *middleware) recovered(cmd eh.Command, r interface{}) error {
+	err := &Error{
+		Panic:       r,
+		CommandType: cmd.CommandType(),
+	}
+
+	if m.stack {
+		err.Stack = debug.Stack()
+	}
+
+	if m.logger != nil {
+		if err.Stack != nil {
+			m.logger.Printf("eventhorizon: recovered from panic in command handler (%s): %v\n%s",
+				cmd.CommandType(), r, err.Stack)
+		} else {
+			m.logger.Printf("eventhorizon: recovered from panic in command handler (%s): %v",
+				cmd.CommandType(), r)
+		}
+	}
+
+	return err
+}
+
+// Error is a recovered panic in a command handler.
+type Error struct {
+	// Panic is the recovered value.
+	Panic interface{}
+	// CommandType is the type of the command that was handled.
+	CommandType eh.CommandType
+	// Stack is the stack trace of the panic, if captured.
+	Stack []byte
+}
+
+// Error implements the Error method of the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: panic in command handler (%s): %v", ErrInternal, e.CommandType, e.Panic)
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *Error) Unwrap() error {
+	return ErrInternal
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	inner := &mocks.CommandHandler{}
+	h := eh.UseCommandHandlerMiddleware(inner, NewMiddleware(WithLogger(nil)))
+	cmd := mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+
+	if err := h.HandleCommand(context.Background(), cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(inner.Commands) != 1 {
+		t.Error("the command should have been handled:", inner.Commands)
+	}
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	logger := &recordingLogger{}
+	h := eh.UseCommandHandlerMiddleware(eh.CommandHandlerFunc(panickingHandler), NewMiddleware(WithLogger(logger)))
+	cmd := mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+
+	err := h.HandleCommand(context.Background(), cmd)
+	if !errors.Is(err, ErrInternal) {
+		t.Fatal("there should be an internal error:", err)
+	}
+
+	var panicErr *Error
+	if !errors.As(err, &panicErr) {
+		t.Fatal("the error should be a recovered panic:", err)
+	}
+
+	if panicErr.Panic != "handler error" || panicErr.CommandType != mocks.CommandType {
+		t.Error("the panic should be correct:", panicErr)
+	}
+
+	if !strings.Contains(string(panicErr.Stack), "panickingHandler") {
+		t.Error("the stack trace should be captured:", string(panicErr.Stack))
+	}
+
+	if len(logger.lines) != 1 ||
+		!strings.Contains(logger.lines[0], "handler error") ||
+		!strings.Contains(logger.lines[0], "panickingHandler") {
+		t.Error("the panic should be logged with a stack trace:", logger.lines)
+	}
+}
+
+func TestMiddleware_PanicWithoutStackTrace(t *testing.T) {
+	logger := &recordingLogger{}
+	h := eh.UseCommandHandlerMiddleware(eh.CommandHandlerFunc(panickingHandler),
+		NewMiddleware(WithLogger(logger), WithoutStackTrace()))
+	cmd := mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+
+	err := h.HandleCommand(context.Background(), cmd)
+
+	var panicErr *Error
+	if !errors.As(err, &panicErr) {
+		t.Fatal("the error should be a recovered panic:", err)
+	}
+
+	if panicErr.Stack != nil {
+		t.Error("there should be no stack trace:", string(panicErr.Stack))
+	}
+
+	if len(logger.lines) != 1 || strings.Contains(logger.lines[0], "panickingHandler") {
+		t.Error("the panic should be logged without a stack trace:", logger.lines)
+	}
+}
+
+func panickingHandler(ctx context.Context, cmd eh.Command) error {
+	panic("handler error")
+}
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transaction
+
+import (
+	"context"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// NewMiddleware returns a new middleware that handles each command in a
+// transaction of the transactor, for example a MongoDB event store. All events
+// saved and read models updated by the command handler with the context of
+// the transaction are committed if the handler succeeds, and rolled back if it
+// returns an error or panics. A panic is propagated after the rollback, use the
+// recovery middleware outside of this middleware to recover from it.
+func NewMiddleware(t eh.EventStoreTransactor) eh.CommandHandlerMiddleware {
+	return eh.CommandHandlerMiddleware(func(h eh.CommandHandler) eh.CommandHandler {
+		return eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+			var p *panicked
+
+			err := t.WithTransaction(ctx, func(ctx context.Context) error {
+				p = nil
+
+				return handle(ctx, h, cmd, &p)
+			})
+			if p != nil {
+				panic(p.value)
+			}
+
+			return err
+		})
+	})
+}
+
+// panicked is a recovered panic, which rolls back the transaction.
+type panicked struct {
+	value interface{}
+}
+
+// Error implements the Error method of the error interface.
+func (p *panicked) Error() string {
+	return fmt.Sprintf("panic in command handler: %v", p.value)
+}
+
+// handle handles the command, recovering from a panic as an error.
+func handle(ctx context.Context, h eh.CommandHandler, cmd eh.Command, p **panicked) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			*p = &panicked{value: r}
+			err = *p
+		}
+	}()
+
+	return h.HandleCommand(ctx, cmd)
+}
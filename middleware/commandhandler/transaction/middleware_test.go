@@ -0,0 +1,140 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := &transactionalStore{EventStore: &mocks.EventStore{}, repo: &mocks.Repo{}}
+	h := eh.UseCommandHandlerMiddleware(writingHandler(store, nil), NewMiddleware(store))
+	cmd := mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+
+	if err := h.HandleCommand(context.Background(), cmd); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if store.committed != 1 || len(store.Events) != 1 || store.repo.Entity == nil {
+		t.Error("the transaction should be committed:", store.committed, store.Events)
+	}
+
+	if store.Context == nil || store.Context.Value(transactionKey{}) == nil {
+		t.Error("the events should be saved in the transaction")
+	}
+}
+
+func TestMiddleware_Error(t *testing.T) {
+	store := &transactionalStore{EventStore: &mocks.EventStore{}, repo: &mocks.Repo{}}
+	handlerErr := errors.New("handler error")
+	h := eh.UseCommandHandlerMiddleware(writingHandler(store, handlerErr), NewMiddleware(store))
+	cmd := mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+
+	if err := h.HandleCommand(context.Background(), cmd); !errors.Is(err, handlerErr) {
+		t.Error("there should be a handler error:", err)
+	}
+
+	if store.rolledBack != 1 || len(store.Events) != 0 || store.repo.Entity != nil {
+		t.Error("nothing should be persisted:", store.rolledBack, store.Events, store.repo.Entity)
+	}
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	store := &transactionalStore{EventStore: &mocks.EventStore{}, repo: &mocks.Repo{}}
+	inner := writingHandler(store, nil)
+	h := eh.UseCommandHandlerMiddleware(eh.CommandHandlerFunc(func(ctx context.Context, cmd eh.Command) error {
+		if err := inner(ctx, cmd); err != nil {
+			return err
+		}
+
+		panic("handler panic")
+	}), NewMiddleware(store))
+	cmd := mocks.Command{
+		ID:      uuid.New(),
+		Content: "content",
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "handler panic" {
+				t.Error("the panic should be propagated:", r)
+			}
+		}()
+
+		_ = h.HandleCommand(context.Background(), cmd)
+	}()
+
+	if store.rolledBack != 1 || len(store.Events) != 0 || store.repo.Entity != nil {
+		t.Error("nothing should be persisted:", store.rolledBack, store.Events, store.repo.Entity)
+	}
+}
+
+// writingHandler saves an event and updates a read model before returning err.
+func writingHandler(store *transactionalStore, err error) eh.CommandHandlerFunc {
+	return func(ctx context.Context, cmd eh.Command) error {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+			eh.ForAggregate(mocks.AggregateType, cmd.AggregateID(), 1))
+		if err := store.Save(ctx, []eh.Event{event}, 0); err != nil {
+			return err
+		}
+
+		if err := store.repo.Save(ctx, &mocks.Model{ID: cmd.AggregateID()}); err != nil {
+			return err
+		}
+
+		return err
+	}
+}
+
+type transactionKey struct{}
+
+// transactionalStore is an event store that simulates transactions by
+// removing the saved events and read model on errors.
+type transactionalStore struct {
+	*mocks.EventStore
+	repo       *mocks.Repo
+	committed  int
+	rolledBack int
+}
+
+func (s *transactionalStore) WithTransaction(ctx context.Context, f func(context.Context) error) error {
+	n := len(s.Events)
+	entity := s.repo.Entity
+
+	if err := f(context.WithValue(ctx, transactionKey{}, true)); err != nil {
+		s.Events = s.Events[:n]
+		s.repo.Entity = entity
+		s.rolledBack++
+
+		return err
+	}
+
+	s.committed++
+
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// ErrOpen is returned for events that are not handled because the circuit
+// breaker is open.
+var ErrOpen = errors.New("circuit breaker open")
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// Closed is when events are handled.
+	Closed State = iota
+	// Open is when events are not handled, after too many failures.
+	Open
+	// HalfOpen is when a single event is handled after the cooldown, to test
+	// if the handler has recovered.
+	HalfOpen
+)
+
+// String returns the string representation of a state.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// NewMiddleware returns a new circuit breaker middleware, for handlers calling
+// downstream services that can be down, like external APIs. The circuit breaker
+// of a handler opens after threshold consecutive failures, after which events
+// are not handled and ErrOpen is returned until the cooldown has passed, to not
+// back up the event bus with retries. After the cooldown it is half-open and a
+// single event is handled to test if the handler has recovered, which closes
+// the circuit breaker on success and opens it again on failure.
+//
+// Each handler has its own circuit breaker. The time is read with
+// eventhorizon.Now, using the clock of the context.
+func NewMiddleware(threshold int, cooldown time.Duration, options ...Option) eh.EventHandlerMiddleware {
+	return eh.EventHandlerMiddleware(func(h eh.EventHandler) eh.EventHandler {
+		b := &eventHandler{
+			EventHandler: h,
+			threshold:    threshold,
+			cooldown:     cooldown,
+		}
+
+		for _, option := range options {
+			if option == nil {
+				continue
+			}
+
+			option(b)
+		}
+
+		return b
+	})
+}
+
+// Option is an option setter used to configure the middleware.
+type Option func(*eventHandler)
+
+// WithDeadLetter handles the events that are not handled while the circuit
+// breaker is open with another handler, for example to store them for a later
+// replay, instead of returning ErrOpen.
+func WithDeadLetter(h eh.EventHandler) Option {
+	return func(b *eventHandler) {
+		b.deadLetter = h
+	}
+}
+
+// WithStateChangeHook calls f when the state of the circuit breaker of a
+// handler changes, for example to record metrics or log state transitions. It
+// must be safe for concurrent use.
+func WithStateChangeHook(f func(ctx context.Context, handlerType eh.EventHandlerType, from, to State)) Option {
+	return func(b *eventHandler) {
+		b.onStateChange = f
+	}
+}
+
+type eventHandler struct {
+	eh.EventHandler
+	threshold     int
+	cooldown      time.Duration
+	deadLetter    eh.EventHandler
+	onStateChange func(ctx context.Context, handlerType eh.EventHandlerType, from, to State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	testing  bool
+	// generation is incremented on each state change, to ignore the results
+	// of events that were allowed in a previous state.
+	generation int
+}
+
+// InnerHandler implements EventHandlerChain
+func (h *eventHandler) InnerHandler() eh.EventHandler {
+	return h.EventHandler
+}
+
+// State returns the current state of the circuit breaker.
+func (h *eventHandler) State() State {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.state
+}
+
+// HandleEvent implements the HandleEvent method of the EventHandler.
+func (h *eventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	generation, ok := h.allow(ctx)
+	if !ok {
+		if h.deadLetter != nil {
+			return h.deadLetter.HandleEvent(ctx, event)
+		}
+
+		return ErrOpen
+	}
+
+	err := h.EventHandler.HandleEvent(ctx, event)
+	h.record(ctx, generation, err)
+
+	return err
+}
+
+// allow returns true if an event should be handled, moving an open circuit
+// breaker to half-open after the cooldown. The returned generation must be
+// passed when recording the result.
+func (h *eventHandler) allow(ctx context.Context) (int, bool) {
+	h.mu.Lock()
+
+	allowed := false
+	from := h.state
+
+	switch h.state {
+	case Closed:
+		allowed = true
+	case Open:
+		if !eh.Now(ctx).Before(h.openedAt.Add(h.cooldown)) {
+			h.setState(HalfOpen)
+			h.testing = true
+			allowed = true
+		}
+	case HalfOpen:
+		// Only handle a single event at a time when testing.
+		if !h.testing {
+			h.testing = true
+			allowed = true
+		}
+	}
+
+	to := h.state
+	generation := h.generation
+	h.mu.Unlock()
+
+	h.stateChanged(ctx, from, to)
+
+	return generation, allowed
+}
+
+// record records the result of a handled event. Results of events allowed in a
+// previous generation are ignored, for example a success of a slow event that
+// was handled while closed must not close the circuit breaker after it has
+// been opened by other failures.
+func (h *eventHandler) record(ctx context.Context, generation int, err error) {
+	h.mu.Lock()
+
+	if generation != h.generation {
+		h.mu.Unlock()
+
+		return
+	}
+
+	from := h.state
+
+	if err == nil {
+		h.failures = 0
+		h.setState(Closed)
+	} else {
+		h.failures++
+
+		if h.state == HalfOpen || h.failures >= h.threshold {
+			h.setState(Open)
+			h.openedAt = eh.Now(ctx)
+		}
+	}
+
+	h.testing = false
+	to := h.state
+	h.mu.Unlock()
+
+	h.stateChanged(ctx, from, to)
+}
+
+// setState sets the state and starts a new generation if it changed, must be
+// called with the lock held.
+func (h *eventHandler) setState(state State) {
+	if state != h.state {
+		h.state = state
+		h.generation++
+	}
+}
+
+func (h *eventHandler) stateChanged(ctx context.Context, from, to State) {
+	if from != to && h.onStateChange != nil {
+		h.onStateChange(ctx, h.HandlerType(), from, to)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	now := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	ctx := eh.NewContextWithClock(context.Background(), eh.ClockFunc(func() time.Time { return now }))
+
+	var transitions []string
+
+	m := NewMiddleware(3, time.Minute, WithStateChangeHook(
+		func(ctx context.Context, handlerType eh.EventHandlerType, from, to State) {
+			transitions = append(transitions, string(handlerType)+": "+from.String()+" -> "+to.String())
+		},
+	))
+	inner := &countingHandler{}
+	h := eh.UseEventHandlerMiddleware(inner, m)
+
+	b, ok := h.(*eventHandler)
+	if !ok {
+		t.Fatal("the handler should be a circuit breaker")
+	}
+
+	downErr := errors.New("downstream down")
+	handle := func(err error) error {
+		inner.err = err
+
+		return h.HandleEvent(ctx, newEvent())
+	}
+
+	// Successes reset the consecutive failures.
+	handle(downErr)
+	handle(downErr)
+
+	if err := handle(nil); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	handle(downErr)
+	handle(downErr)
+
+	if b.State() != Closed {
+		t.Error("the circuit breaker should be closed:", b.State())
+	}
+
+	// Opens after 3 consecutive failures.
+	if err := handle(downErr); !errors.Is(err, downErr) {
+		t.Error("there should be a downstream error:", err)
+	}
+
+	if b.State() != Open {
+		t.Error("the circuit breaker should be open:", b.State())
+	}
+
+	// Events are not handled while open.
+	calls := inner.calls
+	if err := handle(nil); !errors.Is(err, ErrOpen) {
+		t.Error("there should be an open error:", err)
+	}
+
+	now = now.Add(59 * time.Second)
+	if err := handle(nil); !errors.Is(err, ErrOpen) {
+		t.Error("there should be an open error:", err)
+	}
+
+	if inner.calls != calls {
+		t.Error("the events should not be handled:", inner.calls)
+	}
+
+	// Half-open after the cooldown, opens again on a failure.
+	now = now.Add(time.Second)
+	if err := handle(downErr); !errors.Is(err, downErr) {
+		t.Error("there should be a downstream error:", err)
+	}
+
+	if b.State() != Open {
+		t.Error("the circuit breaker should be open:", b.State())
+	}
+
+	if err := handle(nil); !errors.Is(err, ErrOpen) {
+		t.Error("there should be an open error:", err)
+	}
+
+	// Closes on a success after the cooldown.
+	now = now.Add(time.Minute)
+	if err := handle(nil); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if b.State() != Closed {
+		t.Error("the circuit breaker should be closed:", b.State())
+	}
+
+	expected := []string{
+		"counting: closed -> open",
+		"counting: open -> half-open",
+		"counting: half-open -> open",
+		"counting: open -> half-open",
+		"counting: half-open -> closed",
+	}
+	if !reflect.DeepEqual(transitions, expected) {
+		t.Error("the transitions should be correct:", transitions)
+	}
+}
+
+func TestMiddleware_HalfOpenSingleEvent(t *testing.T) {
+	now := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	ctx := eh.NewContextWithClock(context.Background(), eh.ClockFunc(func() time.Time { return now }))
+
+	inner := &countingHandler{err: errors.New("downstream down")}
+	h := NewMiddleware(1, time.Minute)(inner)
+
+	h.HandleEvent(ctx, newEvent())
+
+	// Block the event testing the handler, other events are not handled.
+	now = now.Add(time.Minute)
+	inner.err = nil
+	inner.block = make(chan struct{})
+
+	done := make(chan error)
+	go func() {
+		done <- h.HandleEvent(ctx, newEvent())
+	}()
+
+	for h.(*eventHandler).State() != HalfOpen {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := h.HandleEvent(ctx, newEvent()); !errors.Is(err, ErrOpen) {
+		t.Error("there should be an open error:", err)
+	}
+
+	close(inner.block)
+
+	if err := <-done; err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if h.(*eventHandler).State() != Closed {
+		t.Error("the circuit breaker should be closed:", h.(*eventHandler).State())
+	}
+}
+
+func TestMiddleware_StaleResult(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	downErr := errors.New("downstream down")
+
+	h := NewMiddleware(1, time.Minute)(handlerFunc(func(ctx context.Context, event eh.Event) error {
+		if event.EventType() == "slow" {
+			close(started)
+			<-release
+
+			return nil
+		}
+
+		return downErr
+	}))
+
+	ctx := context.Background()
+
+	// A slow event is allowed while closed.
+	done := make(chan error)
+	go func() {
+		done <- h.HandleEvent(ctx, eh.NewEvent("slow", nil, time.Now()))
+	}()
+
+	<-started
+
+	// Another event opens the circuit breaker while the slow one is handled.
+	if err := h.HandleEvent(ctx, newEvent()); !errors.Is(err, downErr) {
+		t.Error("there should be a handler error:", err)
+	}
+
+	if h.(*eventHandler).State() != Open {
+		t.Error("the circuit breaker should be open:", h.(*eventHandler).State())
+	}
+
+	// The success of the slow event should not close it again.
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if h.(*eventHandler).State() != Open {
+		t.Error("the circuit breaker should still be open:", h.(*eventHandler).State())
+	}
+
+	if err := h.HandleEvent(ctx, newEvent()); !errors.Is(err, ErrOpen) {
+		t.Error("there should be an open error:", err)
+	}
+}
+
+func TestMiddleware_DeadLetter(t *testing.T) {
+	inner := &countingHandler{err: errors.New("downstream down")}
+	deadLetter := mocks.NewEventHandler("dead letter")
+	h := NewMiddleware(1, time.Minute, WithDeadLetter(deadLetter))(inner)
+
+	ctx := context.Background()
+	h.HandleEvent(ctx, newEvent())
+
+	event := newEvent()
+	if err := h.HandleEvent(ctx, event); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(deadLetter.Events, []eh.Event{event}) {
+		t.Error("the event should be dead lettered:", deadLetter.Events)
+	}
+
+	if inner.calls != 1 {
+		t.Error("the event should not be handled:", inner.calls)
+	}
+}
+
+func newEvent() eh.Event {
+	return eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+}
+
+// countingHandler counts the handled events and fails with err.
+type countingHandler struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+	block chan struct{}
+}
+
+func (h *countingHandler) HandlerType() eh.EventHandlerType {
+	return "counting"
+}
+
+func (h *countingHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	if h.block != nil {
+		<-h.block
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.calls++
+
+	return h.err
+}
+
+// handlerFunc is an event handler calling a func.
+type handlerFunc func(context.Context, eh.Event) error
+
+func (f handlerFunc) HandlerType() eh.EventHandlerType {
+	return "func"
+}
+
+func (f handlerFunc) HandleEvent(ctx context.Context, event eh.Event) error {
+	return f(ctx, event)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadatacontext
+
+import (
+	"context"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// NewMiddleware returns a new middleware that adds values from the metadata of
+// events to the context of the handler, for example the correlation ID or the
+// namespace of a tenant, so that repos and logs used by the handler are scoped.
+// The values are unmarshaled as context values by the funcs registered with
+// eh.RegisterContextUnmarshaler.
+//
+// The metadata keys to add are the keys, or if none are provided the keys
+// registered with eh.RegisterContextMetadataKeys, which are the keys added as
+// metadata when the events are created. Values already in the context are
+// replaced.
+func NewMiddleware(keys ...string) eh.EventHandlerMiddleware {
+	return eh.EventHandlerMiddleware(func(h eh.EventHandler) eh.EventHandler {
+		return &eventHandler{h, keys}
+	})
+}
+
+type eventHandler struct {
+	eh.EventHandler
+	keys []string
+}
+
+// InnerHandler implements EventHandlerChain
+func (h *eventHandler) InnerHandler() eh.EventHandler {
+	return h.EventHandler
+}
+
+// HandleEvent implements the HandleEvent method of the EventHandler.
+func (h *eventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	if len(h.keys) == 0 {
+		return h.EventHandler.HandleEvent(eh.ContextFromMetadata(ctx, event.Metadata()), event)
+	}
+
+	vals := map[string]interface{}{}
+
+	for _, key := range h.keys {
+		if val, ok := event.Metadata()[key]; ok {
+			vals[key] = val
+		}
+	}
+
+	if len(vals) > 0 {
+		ctx = eh.UnmarshalContext(ctx, vals)
+	}
+
+	return h.EventHandler.HandleEvent(ctx, event)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadatacontext
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/namespace"
+)
+
+func TestMiddleware(t *testing.T) {
+	inner := mocks.NewEventHandler("test")
+	h := eh.UseEventHandlerMiddleware(inner, NewMiddleware("context_one", "eh_namespace"))
+
+	if _, ok := h.(eh.EventHandlerChain); !ok {
+		t.Error("handler is not an EventHandlerChain")
+	}
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.WithMetadata(map[string]interface{}{
+			"context_one":  "correlation",
+			"eh_namespace": "tenant",
+			"other":        "value",
+		}),
+	)
+
+	if err := h.HandleEvent(context.Background(), event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if val, ok := mocks.ContextOne(inner.Context); !ok || val != "correlation" {
+		t.Error("the context value should be correct:", val)
+	}
+
+	if ns := namespace.FromContext(inner.Context); ns != "tenant" {
+		t.Error("the namespace should be correct:", ns)
+	}
+
+	// Events without the metadata keep the context.
+	ctx := namespace.NewContext(context.Background(), "other")
+	event = eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now())
+
+	if err := h.HandleEvent(ctx, event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, ok := mocks.ContextOne(inner.Context); ok {
+		t.Error("there should be no context value")
+	}
+
+	if ns := namespace.FromContext(inner.Context); ns != "other" {
+		t.Error("the namespace should be correct:", ns)
+	}
+}
+
+func TestMiddleware_RegisteredKeys(t *testing.T) {
+	eh.RegisterContextMetadataKeys("context_one")
+
+	inner := mocks.NewEventHandler("test")
+	h := eh.UseEventHandlerMiddleware(inner, NewMiddleware())
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now(),
+		eh.WithContextMetadata(mocks.WithContextOne(context.Background(), "correlation")),
+		eh.WithMetadata(map[string]interface{}{"eh_namespace": "tenant"}),
+	)
+
+	if err := h.HandleEvent(context.Background(), event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if val, ok := mocks.ContextOne(inner.Context); !ok || val != "correlation" {
+		t.Error("the context value should be correct:", val)
+	}
+
+	// Only registered keys are added.
+	if ns := namespace.FromContext(inner.Context); ns != namespace.DefaultNamespace {
+		t.Error("the namespace should not be added:", ns)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// Recorder is the hook that is called with the outcome of each handled event,
+// for example to increment labeled counters in a metrics system. It must be
+// safe for concurrent use.
+type Recorder interface {
+	// RecordEvent records that a handler has handled an event of a type, err
+	// is nil if the event was handled successfully.
+	RecordEvent(ctx context.Context, handlerType eh.EventHandlerType, eventType eh.EventType, err error)
+}
+
+// RecorderFunc is a function that can be used as a Recorder.
+type RecorderFunc func(ctx context.Context, handlerType eh.EventHandlerType, eventType eh.EventType, err error)
+
+// RecordEvent implements the RecordEvent method of the Recorder interface.
+func (f RecorderFunc) RecordEvent(ctx context.Context, handlerType eh.EventHandlerType, eventType eh.EventType, err error) {
+	f(ctx, handlerType, eventType, err)
+}
+
+// NewMiddleware returns a new middleware that records the outcome of all
+// events handled by a handler, labeled by the handler type and the event type.
+// Use it on the handlers added to an event bus to get per handler success and
+// failure counts instead of bus wide counts.
+func NewMiddleware(r Recorder) eh.EventHandlerMiddleware {
+	return eh.EventHandlerMiddleware(func(h eh.EventHandler) eh.EventHandler {
+		return &eventHandler{h, r}
+	})
+}
+
+type eventHandler struct {
+	eh.EventHandler
+	recorder Recorder
+}
+
+// InnerHandler implements EventHandlerChain
+func (h *eventHandler) InnerHandler() eh.EventHandler {
+	return h.EventHandler
+}
+
+// HandleEvent implements the HandleEvent method of the EventHandler.
+func (h *eventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	err := h.EventHandler.HandleEvent(ctx, event)
+	h.recorder.RecordEvent(ctx, h.HandlerType(), event.EventType(), err)
+
+	return err
+}
+
+// Count is the number of succeeded and failed events of a type for a handler.
+type Count struct {
+	HandlerType eh.EventHandlerType
+	EventType   eh.EventType
+	Succeeded   int
+	Failed      int
+}
+
+// Counters is a Recorder that counts the succeeded and failed events in memory,
+// for example to expose the counts on a status endpoint or to read them in
+// tests.
+type Counters struct {
+	counts   map[counterKey]*Count
+	countsMu sync.RWMutex
+}
+
+type counterKey struct {
+	handlerType eh.EventHandlerType
+	eventType   eh.EventType
+}
+
+// NewCounters creates new Counters.
+func NewCounters() *Counters {
+	return &Counters{
+		counts: map[counterKey]*Count{},
+	}
+}
+
+// RecordEvent implements the RecordEvent method of the Recorder interface.
+func (c *Counters) RecordEvent(ctx context.Context, handlerType eh.EventHandlerType, eventType eh.EventType, err error) {
+	c.countsMu.Lock()
+	defer c.countsMu.Unlock()
+
+	key := counterKey{handlerType, eventType}
+
+	count, ok := c.counts[key]
+	if !ok {
+		count = &Count{HandlerType: handlerType, EventType: eventType}
+		c.counts[key] = count
+	}
+
+	if err != nil {
+		count.Failed++
+	} else {
+		count.Succeeded++
+	}
+}
+
+// Count returns the count of a handler and event type.
+func (c *Counters) Count(handlerType eh.EventHandlerType, eventType eh.EventType) Count {
+	c.countsMu.RLock()
+	defer c.countsMu.RUnlock()
+
+	if count, ok := c.counts[counterKey{handlerType, eventType}]; ok {
+		return *count
+	}
+
+	return Count{HandlerType: handlerType, EventType: eventType}
+}
+
+// Counts returns all counts, sorted by handler type and event type.
+func (c *Counters) Counts() []Count {
+	c.countsMu.RLock()
+	counts := make([]Count, 0, len(c.counts))
+
+	for _, count := range c.counts {
+		counts = append(counts, *count)
+	}
+	c.countsMu.RUnlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].HandlerType != counts[j].HandlerType {
+			return counts[i].HandlerType < counts[j].HandlerType
+		}
+
+		return counts[i].EventType < counts[j].EventType
+	})
+
+	return counts
+}
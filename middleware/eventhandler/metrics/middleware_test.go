@@ -0,0 +1,172 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/eventbus/local"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	counters := NewCounters()
+	m := NewMiddleware(counters)
+
+	flaky := eh.UseEventHandlerMiddleware(&failingHandler{
+		EventHandler: mocks.NewEventHandler("flaky"),
+		failOn:       mocks.EventOtherType,
+	}, m)
+	healthy := eh.UseEventHandlerMiddleware(mocks.NewEventHandler("healthy"), m)
+
+	if _, ok := flaky.(eh.EventHandlerChain); !ok {
+		t.Error("handler is not an EventHandlerChain")
+	}
+
+	if flaky.HandlerType() != "flaky" {
+		t.Error("the handler type should be correct:", flaky.HandlerType())
+	}
+
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		for _, h := range []eh.EventHandler{flaky, healthy} {
+			if err := h.HandleEvent(ctx, newEvent(mocks.EventType)); err != nil {
+				t.Error("there should be no error:", err)
+			}
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := flaky.HandleEvent(ctx, newEvent(mocks.EventOtherType)); !errors.Is(err, errHandler) {
+			t.Error("there should be a handler error:", err)
+		}
+
+		if err := healthy.HandleEvent(ctx, newEvent(mocks.EventOtherType)); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	expected := []Count{
+		{HandlerType: "flaky", EventType: mocks.EventType, Succeeded: 3},
+		{HandlerType: "flaky", EventType: mocks.EventOtherType, Failed: 2},
+		{HandlerType: "healthy", EventType: mocks.EventType, Succeeded: 3},
+		{HandlerType: "healthy", EventType: mocks.EventOtherType, Succeeded: 2},
+	}
+	if counts := counters.Counts(); !reflect.DeepEqual(counts, expected) {
+		t.Error("the counts should be correct:", counts)
+	}
+
+	if c := counters.Count("flaky", mocks.EventOtherType); c.Failed != 2 || c.Succeeded != 0 {
+		t.Error("the count should be correct:", c)
+	}
+
+	if c := counters.Count("unknown", mocks.EventType); c != (Count{HandlerType: "unknown", EventType: mocks.EventType}) {
+		t.Error("the count should be empty:", c)
+	}
+}
+
+func TestMiddleware_EventBus(t *testing.T) {
+	counters := NewCounters()
+
+	var (
+		mu       sync.Mutex
+		recorded int
+	)
+
+	// Count the recorded events to know when both are handled.
+	recorder := RecorderFunc(func(ctx context.Context, handlerType eh.EventHandlerType, eventType eh.EventType, err error) {
+		counters.RecordEvent(ctx, handlerType, eventType, err)
+
+		mu.Lock()
+		recorded++
+		mu.Unlock()
+	})
+
+	bus := local.NewEventBus()
+	defer bus.Close()
+
+	ctx := context.Background()
+	h := &failingHandler{EventHandler: mocks.NewEventHandler("flaky"), failOn: mocks.EventOtherType}
+
+	if err := bus.AddHandler(ctx, eh.MatchAll{}, eh.UseEventHandlerMiddleware(h, NewMiddleware(recorder))); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for _, eventType := range []eh.EventType{mocks.EventOtherType, mocks.EventType} {
+		if err := bus.HandleEvent(ctx, newEvent(eventType)); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	select {
+	case err := <-bus.Errors():
+		// NOTE: The local event bus does not wrap the handler errors.
+		if !strings.Contains(err.Error(), errHandler.Error()) {
+			t.Error("there should be a handler error:", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("there should be an error")
+	}
+
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		done := recorded == 2
+		mu.Unlock()
+
+		if done {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	expected := []Count{
+		{HandlerType: "flaky", EventType: mocks.EventType, Succeeded: 1},
+		{HandlerType: "flaky", EventType: mocks.EventOtherType, Failed: 1},
+	}
+	if counts := counters.Counts(); !reflect.DeepEqual(counts, expected) {
+		t.Error("the counts should be correct:", counts)
+	}
+}
+
+var errHandler = errors.New("handler error")
+
+// failingHandler fails on events of a type.
+type failingHandler struct {
+	eh.EventHandler
+	failOn eh.EventType
+}
+
+func (h *failingHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	if event.EventType() == h.failOn {
+		return errHandler
+	}
+
+	return h.EventHandler.HandleEvent(ctx, event)
+}
+
+func newEvent(eventType eh.EventType) eh.Event {
+	return eh.NewEvent(eventType, nil, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+var (
+	// ErrFieldNotFound is when the field of a redaction can not be found in the
+	// event data.
+	ErrFieldNotFound = errors.New("field not found")
+	// ErrInvalidRedaction is when a redacted value can not be set on the field.
+	ErrInvalidRedaction = errors.New("invalid redacted value")
+)
+
+// Redactor returns the redacted value of a field, or nil to set the field to
+// its zero value.
+type Redactor func(value interface{}) interface{}
+
+// Clear is a redactor that sets the field to its zero value.
+func Clear(interface{}) interface{} {
+	return nil
+}
+
+// Mask is a redactor that replaces string fields with the mask, other fields
+// are set to their zero value.
+func Mask(mask string) Redactor {
+	return func(value interface{}) interface{} {
+		if _, ok := value.(string); ok {
+			return mask
+		}
+
+		return nil
+	}
+}
+
+// NewMiddleware returns a new redaction middleware that handles a redacted copy
+// of events, for example when publishing events containing PII to a sink that
+// should not see it. Use it on the handler of the sink, the original event (as
+// saved in the event store) is never changed.
+//
+// Events without any redactions for their type are handled as is. If a field
+// can not be redacted the event is not handled and an error is returned, to
+// never let the unredacted value through.
+func NewMiddleware(options ...Option) eh.EventHandlerMiddleware {
+	m := &middleware{
+		redactions: map[eh.EventType][]redaction{},
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(m)
+	}
+
+	return eh.EventHandlerMiddleware(func(h eh.EventHandler) eh.EventHandler {
+		return &eventHandler{h, m}
+	})
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*middleware)
+
+// WithRedaction redacts a field of the data of events of a type. The path is
+// the dot separated names of the fields, using either the Go or JSON field names
+// of structs or the keys of maps, for example "Customer.email".
+func WithRedaction(eventType eh.EventType, path string, r Redactor) Option {
+	return func(m *middleware) {
+		m.redactions[eventType] = append(m.redactions[eventType], redaction{
+			path:     path,
+			redactor: r,
+		})
+	}
+}
+
+type middleware struct {
+	redactions map[eh.EventType][]redaction
+}
+
+type redaction struct {
+	path     string
+	redactor Redactor
+}
+
+type eventHandler struct {
+	eh.EventHandler
+	m *middleware
+}
+
+// InnerHandler implements EventHandlerChain
+func (h *eventHandler) InnerHandler() eh.EventHandler {
+	return h.EventHandler
+}
+
+// HandleEvent implements the HandleEvent method of the EventHandler.
+func (h *eventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	redactions := h.m.redactions[event.EventType()]
+	if len(redactions) == 0 {
+		return h.EventHandler.HandleEvent(ctx, event)
+	}
+
+	clone := eh.CloneEvent(event)
+	if clone.Data() == nil {
+		return fmt.Errorf("could not redact %s: %w", event.EventType(), ErrFieldNotFound)
+	}
+
+	// Redact a settable copy of the data, which could be a value.
+	data := reflect.New(reflect.TypeOf(clone.Data())).Elem()
+	data.Set(reflect.ValueOf(clone.Data()))
+
+	for _, r := range redactions {
+		if err := redactValue(data, strings.Split(r.path, "."), r.redactor); err != nil {
+			return fmt.Errorf("could not redact '%s' of %s: %w", r.path, event.EventType(), err)
+		}
+	}
+
+	redacted := eh.NewEvent(clone.EventType(), data.Interface(), clone.Timestamp(),
+		eh.ForAggregate(clone.AggregateType(), clone.AggregateID(), clone.Version()),
+		eh.WithMetadata(clone.Metadata()),
+	)
+
+	return h.EventHandler.HandleEvent(ctx, redacted)
+}
+
+// redactValue redacts the field at the path in the settable value.
+func redactValue(v reflect.Value, path []string, r Redactor) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return ErrFieldNotFound
+		}
+
+		return redactValue(v.Elem(), path, r)
+	case reflect.Interface:
+		if v.IsNil() {
+			return ErrFieldNotFound
+		}
+
+		// Values in interfaces can not be set, redact a copy.
+		e := reflect.New(v.Elem().Type()).Elem()
+		e.Set(v.Elem())
+
+		if err := redactValue(e, path, r); err != nil {
+			return err
+		}
+
+		v.Set(e)
+
+		return nil
+	case reflect.Struct:
+		f, ok := structField(v, path[0])
+		if !ok {
+			return ErrFieldNotFound
+		}
+
+		if len(path) == 1 {
+			return setRedacted(f, r)
+		}
+
+		return redactValue(f, path[1:], r)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return ErrFieldNotFound
+		}
+
+		key := reflect.ValueOf(path[0]).Convert(v.Type().Key())
+
+		original := v.MapIndex(key)
+		if !original.IsValid() {
+			return ErrFieldNotFound
+		}
+
+		// Map values can not be set, redact a copy.
+		e := reflect.New(original.Type()).Elem()
+		e.Set(original)
+
+		var err error
+		if len(path) == 1 {
+			err = setRedacted(e, r)
+		} else {
+			err = redactValue(e, path[1:], r)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		v.SetMapIndex(key, e)
+
+		return nil
+	}
+
+	return ErrFieldNotFound
+}
+
+// structField returns the exported field with the Go or JSON name.
+func structField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Name == name || (tag != "" && tag == name) {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// setRedacted sets the redacted value of the field.
+func setRedacted(f reflect.Value, r Redactor) error {
+	redacted := r(f.Interface())
+	if redacted == nil {
+		f.Set(reflect.Zero(f.Type()))
+
+		return nil
+	}
+
+	rv := reflect.ValueOf(redacted)
+
+	switch {
+	case rv.Type().AssignableTo(f.Type()):
+		f.Set(rv)
+	case rv.Kind() == f.Kind() && rv.Type().ConvertibleTo(f.Type()):
+		f.Set(rv.Convert(f.Type()))
+	default:
+		return fmt.Errorf("%w: %T is not assignable to %s", ErrInvalidRedaction, redacted, f.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := &mocks.EventStore{}
+	sink := mocks.NewEventHandler("sink")
+	h := eh.UseEventHandlerMiddleware(sink, NewMiddleware(
+		WithRedaction(CustomerCreatedEvent, "email", Clear),
+		WithRedaction(CustomerCreatedEvent, "Address.Street", Mask("***")),
+	))
+
+	if _, ok := h.(eh.EventHandlerChain); !ok {
+		t.Error("handler is not an EventHandlerChain")
+	}
+
+	ctx := context.Background()
+	event := eh.NewEvent(CustomerCreatedEvent, &CustomerData{
+		Name:    "Jane",
+		Email:   "jane@example.com",
+		Address: &Address{Street: "Main Street 1", City: "Springfield"},
+	}, time.Now(), eh.ForAggregate(mocks.AggregateType, uuid.New(), 1),
+		eh.WithMetadata(map[string]interface{}{"num": 42}))
+
+	if err := store.Save(ctx, []eh.Event{event}, 0); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := h.HandleEvent(ctx, event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The sink should never see the PII fields.
+	if len(sink.Events) != 1 {
+		t.Fatal("there should be one handled event:", sink.Events)
+	}
+
+	handled, ok := sink.Events[0].Data().(*CustomerData)
+	if !ok {
+		t.Fatal("the handled event data should be correct:", sink.Events[0].Data())
+	}
+
+	if handled.Name != "Jane" || handled.Email != "" || handled.Address.Street != "***" || handled.Address.City != "Springfield" {
+		t.Error("the handled event data should be redacted:", handled, handled.Address)
+	}
+
+	if e := sink.Events[0]; e.AggregateID() != event.AggregateID() ||
+		e.Version() != event.Version() || e.Metadata()["num"] != 42 {
+		t.Error("the handled event should be correct:", e)
+	}
+
+	// The store should retain the PII fields.
+	stored, ok := store.Events[0].Data().(*CustomerData)
+	if !ok {
+		t.Fatal("the stored event data should be correct:", store.Events[0].Data())
+	}
+
+	if stored.Email != "jane@example.com" || stored.Address.Street != "Main Street 1" {
+		t.Error("the stored event data should not be redacted:", stored, stored.Address)
+	}
+}
+
+func TestMiddleware_OtherEvents(t *testing.T) {
+	sink := mocks.NewEventHandler("sink")
+	h := eh.UseEventHandlerMiddleware(sink, NewMiddleware(
+		WithRedaction(CustomerCreatedEvent, "email", Clear),
+	))
+
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, time.Now())
+	if err := h.HandleEvent(context.Background(), event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(sink.Events) != 1 || sink.Events[0] != event {
+		t.Error("the event should be handled as is:", sink.Events)
+	}
+}
+
+func TestMiddleware_Maps(t *testing.T) {
+	sink := mocks.NewEventHandler("sink")
+	h := eh.UseEventHandlerMiddleware(sink, NewMiddleware(
+		WithRedaction(CustomerCreatedEvent, "customer.email", Mask("***")),
+	))
+
+	data := map[string]interface{}{
+		"customer": map[string]interface{}{
+			"name":  "Jane",
+			"email": "jane@example.com",
+		},
+	}
+
+	event := eh.NewEvent(CustomerCreatedEvent, mapData(data), time.Now())
+	if err := h.HandleEvent(context.Background(), event); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	handled := sink.Events[0].Data().(mapData)["customer"].(map[string]interface{})
+	if handled["email"] != "***" || handled["name"] != "Jane" {
+		t.Error("the handled event data should be redacted:", handled)
+	}
+
+	if data["customer"].(map[string]interface{})["email"] != "jane@example.com" {
+		t.Error("the original event data should not be redacted:", data)
+	}
+}
+
+func TestMiddleware_Errors(t *testing.T) {
+	testCases := map[string]struct {
+		path     string
+		redactor Redactor
+		data     eh.EventData
+		err      error
+	}{
+		"missing field": {
+			"Phone",
+			Clear,
+			&CustomerData{},
+			ErrFieldNotFound,
+		},
+		"nil pointer": {
+			"Address.Street",
+			Clear,
+			&CustomerData{},
+			ErrFieldNotFound,
+		},
+		"nil data": {
+			"email",
+			Clear,
+			nil,
+			ErrFieldNotFound,
+		},
+		"invalid value": {
+			"email",
+			func(interface{}) interface{} { return 42 },
+			&CustomerData{Email: "jane@example.com"},
+			ErrInvalidRedaction,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			sink := mocks.NewEventHandler("sink")
+			h := eh.UseEventHandlerMiddleware(sink, NewMiddleware(
+				WithRedaction(CustomerCreatedEvent, tc.path, tc.redactor),
+			))
+
+			event := eh.NewEvent(CustomerCreatedEvent, tc.data, time.Now())
+			if err := h.HandleEvent(context.Background(), event); !errors.Is(err, tc.err) {
+				t.Error("there should be an error:", err)
+			}
+
+			if len(sink.Events) != 0 {
+				t.Error("the event should not be handled:", sink.Events)
+			}
+		})
+	}
+}
+
+const CustomerCreatedEvent eh.EventType = "CustomerCreated"
+
+type CustomerData struct {
+	Name    string
+	Email   string `json:"email"`
+	Address *Address
+}
+
+type Address struct {
+	Street string
+	City   string
+}
+
+type mapData map[string]interface{}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// Sampler selects the events to handle, it must be safe for concurrent use.
+type Sampler func(eh.Event) bool
+
+// EveryNth is a sampler that selects the first event and then every Nth event.
+func EveryNth(n int) Sampler {
+	var (
+		mu    sync.Mutex
+		count int
+	)
+
+	return func(eh.Event) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		selected := n <= 1 || count%n == 0
+		count++
+
+		return selected
+	}
+}
+
+// RateLimited is a sampler that selects at most one event per interval, the
+// first event after the previous selected one.
+func RateLimited(interval time.Duration) Sampler {
+	var (
+		mu   sync.Mutex
+		next time.Time
+	)
+
+	return func(eh.Event) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if now.Before(next) {
+			return false
+		}
+
+		next = now.Add(interval)
+
+		return true
+	}
+}
+
+// ByPredicate is a sampler that selects the events for which f returns true.
+func ByPredicate(f func(eh.Event) bool) Sampler {
+	return Sampler(f)
+}
+
+// NewMiddleware returns a new sampling middleware that only handles the events
+// selected by the sampler, for example for projectors of statistical views that
+// don't need all events. All other events are skipped and treated as handled.
+// Note that the sampler is shared by all handlers using the middleware.
+func NewMiddleware(s Sampler) eh.EventHandlerMiddleware {
+	return eh.EventHandlerMiddleware(func(h eh.EventHandler) eh.EventHandler {
+		return &eventHandler{h, s}
+	})
+}
+
+type eventHandler struct {
+	eh.EventHandler
+	sampler Sampler
+}
+
+// InnerHandler implements EventHandlerChain
+func (h *eventHandler) InnerHandler() eh.EventHandler {
+	return h.EventHandler
+}
+
+// HandleEvent implements the HandleEvent method of the EventHandler.
+func (h *eventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	if !h.sampler(event) {
+		return nil
+	}
+
+	return h.EventHandler.HandleEvent(ctx, event)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestMiddleware_EveryNth(t *testing.T) {
+	inner := mocks.NewEventHandler("test")
+	h := eh.UseEventHandlerMiddleware(inner, NewMiddleware(EveryNth(3)))
+
+	if _, ok := h.(eh.EventHandlerChain); !ok {
+		t.Error("handler is not an EventHandlerChain")
+	}
+
+	events := newEvents(7)
+	for _, e := range events {
+		if err := h.HandleEvent(context.Background(), e); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	expected := []eh.Event{events[0], events[3], events[6]}
+	if !reflect.DeepEqual(inner.Events, expected) {
+		t.Error("every third event should be handled:", inner.Events)
+	}
+}
+
+func TestMiddleware_RateLimited(t *testing.T) {
+	inner := mocks.NewEventHandler("test")
+	h := eh.UseEventHandlerMiddleware(inner, NewMiddleware(RateLimited(100*time.Millisecond)))
+
+	events := newEvents(4)
+	for _, e := range events[:3] {
+		if err := h.HandleEvent(context.Background(), e); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	if !reflect.DeepEqual(inner.Events, events[:1]) {
+		t.Error("only the first event should be handled:", inner.Events)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if err := h.HandleEvent(context.Background(), events[3]); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	expected := []eh.Event{events[0], events[3]}
+	if !reflect.DeepEqual(inner.Events, expected) {
+		t.Error("the event after the interval should be handled:", inner.Events)
+	}
+}
+
+func TestMiddleware_ByPredicate(t *testing.T) {
+	inner := mocks.NewEventHandler("test")
+	h := eh.UseEventHandlerMiddleware(inner, NewMiddleware(ByPredicate(func(e eh.Event) bool {
+		return e.Version()%2 == 0
+	})))
+
+	events := newEvents(4)
+	for _, e := range events {
+		if err := h.HandleEvent(context.Background(), e); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	expected := []eh.Event{events[1], events[3]}
+	if !reflect.DeepEqual(inner.Events, expected) {
+		t.Error("the even versions should be handled:", inner.Events)
+	}
+}
+
+func newEvents(n int) []eh.Event {
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	events := make([]eh.Event, n)
+	for i := range events {
+		events[i] = eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, i+1))
+	}
+
+	return events
+}
@@ -84,3 +84,98 @@ func TestEventHandlerMiddleware(t *testing.T) {
 		t.Log(order)
 	}
 }
+
+func TestCommandHandlerMiddlewareChain(t *testing.T) {
+	order := []string{}
+	middleware := func(s string) CommandHandlerMiddleware {
+		return CommandHandlerMiddleware(func(h CommandHandler) CommandHandler {
+			return CommandHandlerFunc(func(ctx context.Context, cmd Command) error {
+				order = append(order, s)
+
+				return h.HandleCommand(ctx, cmd)
+			})
+		})
+	}
+	handler := func(ctx context.Context, cmd Command) error {
+		return nil
+	}
+	h := UseCommandHandlerMiddleware(CommandHandlerFunc(handler),
+		NamedCommandHandlerMiddleware("tracing", middleware("tracing")),
+		NamedCommandHandlerMiddleware("validation", middleware("validation")),
+		NamedCommandHandlerMiddleware("logging", middleware("logging")),
+	)
+
+	if chain := CommandHandlerMiddlewareChain(h); !reflect.DeepEqual(chain, []string{"tracing", "validation", "logging"}) {
+		t.Error("the chain should be correct:", chain)
+	}
+
+	h.HandleCommand(context.Background(), MiddlewareTestCommand{})
+
+	if !reflect.DeepEqual(order, []string{"tracing", "validation", "logging"}) {
+		t.Error("the order of middleware should be correct:", order)
+	}
+
+	// The chain ends at an unnamed middleware.
+	h = UseCommandHandlerMiddleware(CommandHandlerFunc(handler),
+		NamedCommandHandlerMiddleware("tracing", middleware("tracing")),
+		middleware("unnamed"),
+		NamedCommandHandlerMiddleware("validation", middleware("validation")),
+	)
+
+	if chain := CommandHandlerMiddlewareChain(h); !reflect.DeepEqual(chain, []string{"tracing"}) {
+		t.Error("the chain should be correct:", chain)
+	}
+
+	if chain := CommandHandlerMiddlewareChain(CommandHandlerFunc(handler)); len(chain) != 0 {
+		t.Error("there should be no chain:", chain)
+	}
+}
+
+func TestEventHandlerMiddlewareChain(t *testing.T) {
+	order := []string{}
+	middleware := func(s string) EventHandlerMiddleware {
+		return EventHandlerMiddleware(func(h EventHandler) EventHandler {
+			return EventHandlerFunc(func(ctx context.Context, e Event) error {
+				order = append(order, s)
+
+				return h.HandleEvent(ctx, e)
+			})
+		})
+	}
+	// An unnamed middleware that can be traversed.
+	chained := EventHandlerMiddleware(func(h EventHandler) EventHandler {
+		return &chainedEventHandler{EventHandler: h}
+	})
+	handler := EventHandlerFunc(func(ctx context.Context, e Event) error {
+		return nil
+	})
+	h := UseEventHandlerMiddleware(handler,
+		NamedEventHandlerMiddleware("tracing", middleware("tracing")),
+		chained,
+		NamedEventHandlerMiddleware("validation", middleware("validation")),
+		NamedEventHandlerMiddleware("logging", middleware("logging")),
+	)
+
+	if chain := EventHandlerMiddlewareChain(h); !reflect.DeepEqual(chain, []string{"tracing", "validation", "logging"}) {
+		t.Error("the chain should be correct:", chain)
+	}
+
+	// The handler type is the one of the handler created by the middleware.
+	if named := NamedEventHandlerMiddleware("chained", chained)(handler); named.HandlerType() != handler.HandlerType() {
+		t.Error("the handler type should be correct:", named.HandlerType())
+	}
+
+	h.HandleEvent(context.Background(), NewEvent("test", nil, time.Now()))
+
+	if !reflect.DeepEqual(order, []string{"tracing", "validation", "logging"}) {
+		t.Error("the order of middleware should be correct:", order)
+	}
+}
+
+type chainedEventHandler struct {
+	EventHandler
+}
+
+func (h *chainedEventHandler) InnerHandler() EventHandler {
+	return h.EventHandler
+}
@@ -572,7 +572,7 @@ func init() {
 		if val, ok := ContextOne(ctx); ok {
 			vals[contextKeyOneStr] = val
 		}
-	})
+	}, contextKeyOneStr)
 	eh.RegisterContextUnmarshaler(func(ctx context.Context, vals map[string]interface{}) context.Context {
 		if val, ok := vals[contextKeyOneStr].(string); ok {
 			return WithContextOne(ctx, val)
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongoutils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// MaxDocumentSize is the maximum size of a BSON document in MongoDB, 16MB.
+const MaxDocumentSize = 16 * 1024 * 1024
+
+// ErrDocumentTooLarge is when a document exceeds the maximum document size.
+var ErrDocumentTooLarge = errors.New("document too large")
+
+// DocumentTooLargeError is when saving events for an aggregate would exceed the
+// maximum document size of MongoDB. Large single events should be split up or
+// moved out of the event data. Aggregates with long histories stored as a
+// single document should be snapshotted and compacted, or moved to a store with
+// one document per event.
+type DocumentTooLargeError struct {
+	// AggregateID is the ID of the aggregate with the too large document.
+	AggregateID uuid.UUID
+	// Size is the size of the document in bytes.
+	Size int
+}
+
+// Error implements the Error method of the errors.Error interface.
+func (e *DocumentTooLargeError) Error() string {
+	return fmt.Sprintf("%s: aggregate %s is %d bytes (max %d bytes)",
+		ErrDocumentTooLarge, e.AggregateID, e.Size, MaxDocumentSize)
+}
+
+// Unwrap implements the errors.Unwrap method.
+func (e *DocumentTooLargeError) Unwrap() error {
+	return ErrDocumentTooLarge
+}
+
+// CheckDocumentSize checks if a document of size bytes for an aggregate fits in
+// the maximum document size, returns a *DocumentTooLargeError if not.
+func CheckDocumentSize(id uuid.UUID, size int) error {
+	if size > MaxDocumentSize {
+		return &DocumentTooLargeError{
+			AggregateID: id,
+			Size:        size,
+		}
+	}
+
+	return nil
+}
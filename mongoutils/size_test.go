@@ -0,0 +1,44 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongoutils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestCheckDocumentSize(t *testing.T) {
+	id := uuid.New()
+
+	if err := CheckDocumentSize(id, MaxDocumentSize); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	err := CheckDocumentSize(id, MaxDocumentSize+1)
+	if !errors.Is(err, ErrDocumentTooLarge) {
+		t.Error("there should be a document too large error:", err)
+	}
+
+	var sizeErr *DocumentTooLargeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatal("the error should be a DocumentTooLargeError:", err)
+	}
+
+	if sizeErr.AggregateID != id || sizeErr.Size != MaxDocumentSize+1 {
+		t.Error("the error should have the aggregate ID and size:", sizeErr)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// RegisterCommand registers a command factory for a type in a namespace, to
+// be able to use the same command types in multiple namespaces, for example
+// for multiple bounded contexts. Registering in the DefaultNamespace is the
+// same as using eventhorizon.RegisterCommand.
+func RegisterCommand(ns string, factory func() eh.Command) {
+	if ns == DefaultNamespace {
+		eh.RegisterCommand(factory)
+
+		return
+	}
+
+	cmd := factory()
+	if cmd == nil {
+		panic("eventhorizon: created command is nil")
+	}
+
+	commandType := cmd.CommandType()
+	if commandType == eh.CommandType("") {
+		panic("eventhorizon: attempt to register empty command type")
+	}
+
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+
+	if _, ok := commands[ns][commandType]; ok {
+		panic(fmt.Sprintf("eventhorizon: registering duplicate types for %q in namespace %q", commandType, ns))
+	}
+
+	if commands[ns] == nil {
+		commands[ns] = map[eh.CommandType]func() eh.Command{}
+	}
+
+	commands[ns][commandType] = factory
+}
+
+// UnregisterCommand removes the registration of the command factory for a type
+// in a namespace. Unregistering in the DefaultNamespace is the same as using
+// eventhorizon.UnregisterCommand.
+func UnregisterCommand(ns string, commandType eh.CommandType) {
+	if ns == DefaultNamespace {
+		eh.UnregisterCommand(commandType)
+
+		return
+	}
+
+	if commandType == eh.CommandType("") {
+		panic("eventhorizon: attempt to unregister empty command type")
+	}
+
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+
+	if _, ok := commands[ns][commandType]; !ok {
+		panic(fmt.Sprintf("eventhorizon: unregister of non-registered type %q in namespace %q", commandType, ns))
+	}
+
+	delete(commands[ns], commandType)
+
+	if len(commands[ns]) == 0 {
+		delete(commands, ns)
+	}
+}
+
+// CreateCommand creates a command of a type using the factory registered in
+// the namespace of the context with RegisterCommand. Commands that are not
+// registered in the namespace are created from the global registry of
+// eventhorizon.RegisterCommand, which is the registry of the DefaultNamespace.
+func CreateCommand(ctx context.Context, commandType eh.CommandType) (eh.Command, error) {
+	ns := FromContext(ctx)
+
+	commandsMu.RLock()
+	factory, ok := commands[ns][commandType]
+	commandsMu.RUnlock()
+
+	if ok {
+		return factory(), nil
+	}
+
+	return eh.CreateCommand(commandType)
+}
+
+var (
+	commands   = map[string]map[eh.CommandType]func() eh.Command{}
+	commandsMu sync.RWMutex
+)
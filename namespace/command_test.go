@@ -0,0 +1,124 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestCommands(t *testing.T) {
+	RegisterCommand("billing", func() eh.Command { return &billingCreate{} })
+	defer UnregisterCommand("billing", createCommand)
+
+	RegisterCommand("shipping", func() eh.Command { return &shippingCreate{} })
+	defer UnregisterCommand("shipping", createCommand)
+
+	RegisterCommand(DefaultNamespace, func() eh.Command { return &defaultCreate{} })
+	defer UnregisterCommand(DefaultNamespace, createCommand)
+
+	ctx := context.Background()
+
+	cmd, err := CreateCommand(NewContext(ctx, "billing"), createCommand)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if _, ok := cmd.(*billingCreate); !ok {
+		t.Errorf("the command should be from the namespace: %T", cmd)
+	}
+
+	cmd, err = CreateCommand(NewContext(ctx, "shipping"), createCommand)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if _, ok := cmd.(*shippingCreate); !ok {
+		t.Errorf("the command should be from the namespace: %T", cmd)
+	}
+
+	// The default namespace uses the global registry.
+	cmd, err = CreateCommand(ctx, createCommand)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if _, ok := cmd.(*defaultCreate); !ok {
+		t.Errorf("the command should be from the default namespace: %T", cmd)
+	}
+
+	if cmd, err := eh.CreateCommand(createCommand); err != nil {
+		t.Error("there should be no error:", err)
+	} else if _, ok := cmd.(*defaultCreate); !ok {
+		t.Errorf("the command should be from the global registry: %T", cmd)
+	}
+
+	// Other namespaces fall back to the global registry.
+	cmd, err = CreateCommand(NewContext(ctx, "other"), createCommand)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if _, ok := cmd.(*defaultCreate); !ok {
+		t.Errorf("the command should be from the default namespace: %T", cmd)
+	}
+
+	if _, err := CreateCommand(NewContext(ctx, "billing"), "unknown"); !errors.Is(err, eh.ErrCommandNotRegistered) {
+		t.Error("there should be a command not registered error:", err)
+	}
+}
+
+func TestCommands_Duplicate(t *testing.T) {
+	RegisterCommand("billing", func() eh.Command { return &billingCreate{} })
+	defer UnregisterCommand("billing", createCommand)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("there should be a panic")
+		}
+	}()
+
+	RegisterCommand("billing", func() eh.Command { return &billingCreate{} })
+}
+
+const createCommand eh.CommandType = "Create"
+
+type billingCreate struct {
+	ID uuid.UUID
+}
+
+func (c *billingCreate) AggregateID() uuid.UUID          { return c.ID }
+func (c *billingCreate) AggregateType() eh.AggregateType { return "Invoice" }
+func (c *billingCreate) CommandType() eh.CommandType     { return createCommand }
+
+type shippingCreate struct {
+	ID uuid.UUID
+}
+
+func (c *shippingCreate) AggregateID() uuid.UUID          { return c.ID }
+func (c *shippingCreate) AggregateType() eh.AggregateType { return "Shipment" }
+func (c *shippingCreate) CommandType() eh.CommandType     { return createCommand }
+
+type defaultCreate struct {
+	ID uuid.UUID
+}
+
+func (c *defaultCreate) AggregateID() uuid.UUID          { return c.ID }
+func (c *defaultCreate) AggregateType() eh.AggregateType { return "Default" }
+func (c *defaultCreate) CommandType() eh.CommandType     { return createCommand }
@@ -33,7 +33,7 @@ func init() {
 		if ns, ok := ctx.Value(namespaceKey).(string); ok {
 			vals[namespaceKeyStr] = ns
 		}
-	})
+	}, namespaceKeyStr)
 	eh.RegisterContextUnmarshaler(func(ctx context.Context, vals map[string]interface{}) context.Context {
 		if ns, ok := vals[namespaceKeyStr].(string); ok {
 			ctx = NewContext(ctx, ns)
@@ -0,0 +1,62 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventhorizon
+
+// OrderingGuarantee is the order in which an event bus delivers events to a
+// handler, or in which an event store loads or streams saved events.
+type OrderingGuarantee int
+
+const (
+	// NoOrdering is when events can be in any order, also for an aggregate.
+	NoOrdering OrderingGuarantee = iota
+	// PerAggregateOrdering is when events of an aggregate are in version order,
+	// but events of different aggregates can be in any order.
+	PerAggregateOrdering
+	// GlobalOrdering is when all events are in the order they were published
+	// or saved, which also implies per aggregate ordering.
+	GlobalOrdering
+)
+
+// String returns the string representation of an ordering guarantee.
+func (o OrderingGuarantee) String() string {
+	switch o {
+	case NoOrdering:
+		return "none"
+	case PerAggregateOrdering:
+		return "per-aggregate"
+	case GlobalOrdering:
+		return "global"
+	default:
+		return "unknown"
+	}
+}
+
+// OrderingReporter is an event bus or event store that reports its ordering
+// guarantee. Use the eventbus.OrderingAcceptanceTest and
+// eventstore.OrderingAcceptanceTest to verify the reported guarantee.
+type OrderingReporter interface {
+	// OrderingGuarantee returns the ordering guarantee.
+	OrderingGuarantee() OrderingGuarantee
+}
+
+// OrderingOf returns the ordering guarantee reported by an event bus or event
+// store, or NoOrdering if it does not report one.
+func OrderingOf(v interface{}) OrderingGuarantee {
+	if r, ok := v.(OrderingReporter); ok {
+		return r.OrderingGuarantee()
+	}
+
+	return NoOrdering
+}
@@ -16,6 +16,7 @@ package eventhorizon
 
 import (
 	"context"
+	"errors"
 )
 
 // Outbox is an outbox for events. It ensures that all handled events get handled
@@ -42,6 +43,19 @@ type Outbox interface {
 	Errors() <-chan error
 }
 
+// OutboxFlusher is an outbox that can handle all pending events at once, for
+// example to minimize the delivery latency when shutting down gracefully.
+type OutboxFlusher interface {
+	// Flush handles all events in the outbox that are not already being
+	// handled, using the added handlers. It returns when done or with an
+	// ErrOutboxPartialFlush if the context is done before all events are handled.
+	// Errors from the handlers are sent on the error channel as usual.
+	Flush(context.Context) error
+}
+
+// ErrOutboxPartialFlush is when not all events could be flushed in time.
+var ErrOutboxPartialFlush = errors.New("partial flush")
+
 // OutboxError is an error in the outbox.
 type OutboxError struct {
 	// Err is the error.
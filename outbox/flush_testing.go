@@ -0,0 +1,99 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// FlushAcceptanceTest is the acceptance test that all implementations of
+// OutboxFlusher should pass. The outbox must not be started, to only handle
+// events when flushing. It should manually be called from a test case in each
+// implementation:
+//
+//   func TestOutboxFlush(t *testing.T) {
+//       o := NewOutbox()
+//       outbox.FlushAcceptanceTest(t, o, context.Background())
+//   }
+//
+func FlushAcceptanceTest(t *testing.T, o interface {
+	eh.Outbox
+	eh.OutboxFlusher
+}, ctx context.Context) {
+	handler := mocks.NewEventHandler("flush_handler")
+	if err := o.AddHandler(ctx, eh.MatchAll{}, handler); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event1 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event1"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+	event2 := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event2"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+
+	for _, event := range []eh.Event{event1, event2} {
+		if err := o.HandleEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	// Flush with a cancelled context.
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if err := o.Flush(cancelledCtx); !errors.Is(err, eh.ErrOutboxPartialFlush) {
+		t.Error("there should be a partial flush error:", err)
+	}
+
+	handler.RLock()
+	if len(handler.Events) != 0 {
+		t.Error("there should be no handled events:", handler.Events)
+	}
+	handler.RUnlock()
+
+	// Flush all pending events.
+	if err := o.Flush(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	handler.RLock()
+	handled := map[uuid.UUID]bool{}
+	for _, event := range handler.Events {
+		handled[event.AggregateID()] = true
+	}
+
+	if len(handler.Events) != 2 || !handled[event1.AggregateID()] || !handled[event2.AggregateID()] {
+		t.Error("the pending events should be handled:", handler.Events)
+	}
+	handler.RUnlock()
+
+	// Flush again without pending events.
+	if err := o.Flush(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	handler.RLock()
+	if len(handler.Events) != 2 {
+		t.Error("the events should only be handled once:", handler.Events)
+	}
+	handler.RUnlock()
+}
@@ -207,6 +207,13 @@ func (o *Outbox) processWithWatch(ctx context.Context) error {
 		case r := <-o.watchCh:
 			o.dbMu.Lock()
 
+			// Skip events that are already handled, for example by a flush.
+			if _, ok := o.db[r.ID]; !ok {
+				o.dbMu.Unlock()
+
+				continue
+			}
+
 			// Use a new context to let processing finish when canceled.
 			if err := o.processOutboxEvent(context.Background(), r, time.Now()); err != nil {
 				err = fmt.Errorf("could not process outbox event: %w", err)
@@ -248,6 +255,33 @@ func (o *Outbox) processFullOutbox(ctx context.Context) error {
 	return nil
 }
 
+// Flush implements the Flush method of the eventhorizon.OutboxFlusher interface.
+func (o *Outbox) Flush(ctx context.Context) error {
+	o.processingMu.Lock()
+	defer o.processingMu.Unlock()
+
+	o.dbMu.Lock()
+	defer o.dbMu.Unlock()
+
+	now := time.Now()
+
+	for _, r := range o.db {
+		if err := ctx.Err(); err != nil {
+			return &eh.OutboxError{
+				Err: fmt.Errorf("%w: %d events not handled: %s", eh.ErrOutboxPartialFlush, len(o.db), err),
+				Ctx: ctx,
+			}
+		}
+
+		// Use a new context to let processing finish when canceled.
+		if err := o.processOutboxEvent(context.Background(), r, now); err != nil {
+			return fmt.Errorf("could not process outbox event: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (o *Outbox) processOutboxEvent(ctx context.Context, r *outboxDoc, now time.Time) error {
 	event := r.Event
 
@@ -41,6 +41,19 @@ func TestOutbox(t *testing.T) {
 	}
 }
 
+func TestOutboxFlush(t *testing.T) {
+	o, err := NewOutbox()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outbox.FlushAcceptanceTest(t, o, context.Background())
+
+	if err := o.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
 func BenchmarkOutbox(b *testing.B) {
 	// Shorter sweeps for testing.
 	PeriodicSweepInterval = 1 * time.Second
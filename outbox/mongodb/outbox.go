@@ -39,6 +39,7 @@ type Outbox struct {
 	outbox          *mongo.Collection
 	handlers        []*matcherHandler
 	handlersByType  map[eh.EventHandlerType]*matcherHandler
+	paused          map[eh.EventHandlerType]bool
 	handlersMu      sync.RWMutex
 	errCh           chan error
 	watchToken      string
@@ -94,6 +95,7 @@ func newOutboxWithClient(client *mongo.Client, clientOwnership clientOwnership,
 		clientOwnership: clientOwnership,
 		outbox:          client.Database(dbName).Collection("outbox"),
 		handlersByType:  map[eh.EventHandlerType]*matcherHandler{},
+		paused:          map[eh.EventHandlerType]bool{},
 		errCh:           make(chan error, 100),
 		cctx:            ctx,
 		cancel:          cancel,
@@ -176,14 +178,83 @@ func (o *Outbox) AddHandler(_ context.Context, m eh.EventMatcher, h eh.EventHand
 	return nil
 }
 
-// Returns an added handler and matcher for a handler type.
-func (o *Outbox) handler(handlerType string) (*matcherHandler, bool) {
+// Returns an added handler and matcher for a handler type, and if it is paused.
+func (o *Outbox) handler(handlerType string) (*matcherHandler, bool, bool) {
 	o.handlersMu.RLock()
 	defer o.handlersMu.RUnlock()
 
 	mh, ok := o.handlersByType[eh.EventHandlerType(handlerType)]
 
-	return mh, ok
+	return mh, o.paused[eh.EventHandlerType(handlerType)], ok
+}
+
+// Pause implements the Pause method of the eventhorizon.EventBusPauser
+// interface. Events are left in the outbox for the paused handler, note that
+// other outboxes using the same collection can still handle them.
+func (o *Outbox) Pause(ctx context.Context, t eh.EventHandlerType) error {
+	o.handlersMu.Lock()
+	defer o.handlersMu.Unlock()
+
+	if _, ok := o.handlersByType[t]; !ok {
+		return eh.ErrHandlerNotAdded
+	}
+
+	o.paused[t] = true
+
+	return nil
+}
+
+// Resume implements the Resume method of the eventhorizon.EventBusPauser
+// interface. The events left in the outbox for the handler while paused are
+// handled in order before returning, or until the context is done.
+func (o *Outbox) Resume(ctx context.Context, t eh.EventHandlerType) error {
+	o.handlersMu.Lock()
+	if _, ok := o.handlersByType[t]; !ok {
+		o.handlersMu.Unlock()
+
+		return eh.ErrHandlerNotAdded
+	}
+
+	delete(o.paused, t)
+	o.handlersMu.Unlock()
+
+	o.processingMu.Lock()
+	defer o.processingMu.Unlock()
+
+	now := time.Now()
+
+	filter := bson.M{
+		"handlers": t.String(),
+		"taken_at": nil,
+	}
+	if o.watchToken != "" {
+		filter["watch_token"] = o.watchToken
+	}
+
+	cur, err := o.outbox.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return fmt.Errorf("could not find paused outbox events: %w", err)
+	}
+
+	defer cur.Close(context.Background())
+
+	for cur.Next(ctx) {
+		var r outboxDoc
+		if err := cur.Decode(&r); err != nil {
+			return fmt.Errorf("could not unmarshal outbox event: %w", err)
+		}
+
+		// Use a new context to let processing finish when canceled.
+		if err := o.processOutboxEvent(context.Background(), &r, now); err != nil {
+			return fmt.Errorf("could not process outbox event: %w", err)
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		return fmt.Errorf("could not find paused outbox events: %w", err)
+	}
+
+	return nil
 }
 
 // outboxDoc is the DB representation of an outbox entry.
@@ -385,6 +456,67 @@ func (o *Outbox) processFullOutbox(ctx context.Context) error {
 	return cur.Close(ctx)
 }
 
+// Flush implements the Flush method of the eventhorizon.OutboxFlusher interface.
+// Events that are currently being handled, also by other outboxes using the
+// same collection, are not flushed.
+func (o *Outbox) Flush(ctx context.Context) error {
+	o.processingMu.Lock()
+	defer o.processingMu.Unlock()
+
+	// Keep track of the query time to avoid data races later when taking items.
+	now := time.Now()
+
+	// Take non-started events and started but non-finished events after 15 sec.
+	filter := bson.M{"$or": bson.A{
+		bson.M{"taken_at": nil},
+		bson.M{"taken_at": bson.M{"$lt": now.Add(-PeriodicSweepAge)}},
+	}}
+	if o.watchToken != "" {
+		filter["watch_token"] = o.watchToken
+	}
+
+	cur, err := o.outbox.Find(ctx, filter)
+	if err != nil {
+		return o.flushError(ctx, fmt.Errorf("could not find outbox events: %w", err))
+	}
+
+	defer cur.Close(context.Background())
+
+	for cur.Next(ctx) {
+		var r outboxDoc
+		if err := cur.Decode(&r); err != nil {
+			return fmt.Errorf("could not unmarshal outbox event: %w", err)
+		}
+
+		// Use a new context to let processing finish when canceled.
+		if err := o.processOutboxEvent(context.Background(), &r, now); err != nil {
+			return fmt.Errorf("could not process outbox event: %w", err)
+		}
+
+		if err := ctx.Err(); err != nil {
+			break
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		return o.flushError(ctx, fmt.Errorf("could not find outbox events: %w", err))
+	}
+
+	return o.flushError(ctx, nil)
+}
+
+// flushError returns a partial flush error if the context is done, or else err.
+func (o *Outbox) flushError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &eh.OutboxError{
+			Err: fmt.Errorf("%w: %s", eh.ErrOutboxPartialFlush, ctxErr),
+			Ctx: ctx,
+		}
+	}
+
+	return err
+}
+
 // The current time is passed to avoid data races between concurrent sweeps where
 // the fetch time and taken time could differ.
 func (o *Outbox) processOutboxEvent(ctx context.Context, r *outboxDoc, now time.Time) error {
@@ -414,15 +546,25 @@ func (o *Outbox) processOutboxEvent(ctx context.Context, r *outboxDoc, now time.
 		return nil
 	}
 
-	var processedHandlers []interface{}
+	var (
+		processedHandlers []interface{}
+		pausedHandlers    bool
+	)
 
 	// Process all handlers without returning handler errors.
 	for _, handlerType := range r.Handlers {
-		mh, ok := o.handler(handlerType)
+		mh, paused, ok := o.handler(handlerType)
 		if !ok {
 			continue
 		}
 
+		// Leave the event for paused handlers, to be handled when resumed.
+		if paused {
+			pausedHandlers = true
+
+			continue
+		}
+
 		if !mh.Match(event) {
 			continue
 		}
@@ -449,10 +591,20 @@ func (o *Outbox) processOutboxEvent(ctx context.Context, r *outboxDoc, now time.
 				Event: event,
 			}
 		}
-	} else if len(processedHandlers) > 0 {
+	} else if len(processedHandlers) > 0 || pausedHandlers {
+		update := bson.M{}
+		if len(processedHandlers) > 0 {
+			update["$pullAll"] = bson.M{"handlers": bson.A(processedHandlers)}
+		}
+
+		// Release the event to be taken again when the handlers are resumed.
+		if pausedHandlers {
+			update["$unset"] = bson.M{"taken_at": ""}
+		}
+
 		if res, err := o.outbox.UpdateOne(ctx,
 			bson.M{"_id": r.ID},
-			bson.M{"$pullAll": bson.M{"handlers": bson.A(processedHandlers)}},
+			update,
 		); err != nil {
 			return &eh.OutboxError{
 				Err:   fmt.Errorf("could not set outbox event as hadeled: %w", err),
@@ -55,6 +55,46 @@ func TestOutboxIntegration(t *testing.T) {
 	}
 }
 
+func TestOutboxFlushIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	o, err := NewOutbox(url, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outbox.FlushAcceptanceTest(t, o, context.Background())
+
+	if err := o.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestOutboxPauseIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	url, db := makeDB(t)
+
+	o, err := NewOutbox(url, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o.Start()
+
+	outbox.PauseAcceptanceTest(t, o, context.Background())
+
+	if err := o.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
 func TestWithCollectionNameIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
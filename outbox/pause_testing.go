@@ -0,0 +1,111 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// PauseAcceptanceTest is the acceptance test that all implementations of
+// EventBusPauser for outboxes should pass. The outbox must be started. It
+// should manually be called from a test case in each implementation:
+//
+//	func TestOutboxPause(t *testing.T) {
+//	    o := NewOutbox()
+//	    o.Start()
+//	    outbox.PauseAcceptanceTest(t, o, context.Background())
+//	}
+func PauseAcceptanceTest(t *testing.T, o interface {
+	eh.Outbox
+	eh.EventBusPauser
+}, ctx context.Context) {
+	paused := mocks.NewEventHandler("paused_handler")
+	if err := o.AddHandler(ctx, eh.MatchAll{}, paused); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	other := mocks.NewEventHandler("other_handler")
+	if err := o.AddHandler(ctx, eh.MatchAll{}, other); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := o.Pause(ctx, paused.HandlerType()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	var events []eh.Event
+
+	for i := 1; i <= 3; i++ {
+		event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: fmt.Sprint("event", i)}, timestamp,
+			eh.ForAggregate(mocks.AggregateType, id, i))
+		if err := o.HandleEvent(ctx, event); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		events = append(events, event)
+	}
+
+	// Other handlers are not paused.
+	for i := range events {
+		if !other.Wait(5 * time.Second) {
+			t.Fatal("the other handler should handle the event:", i)
+		}
+	}
+
+	if paused.Wait(500 * time.Millisecond) {
+		t.Error("the paused handler should not handle events")
+	}
+
+	if err := o.Resume(ctx, paused.HandlerType()); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for i := range events {
+		if !paused.Wait(5 * time.Second) {
+			t.Fatal("the resumed handler should handle the event:", i)
+		}
+	}
+
+	paused.RLock()
+	if len(paused.Events) != len(events) {
+		t.Error("all events should be handled once:", paused.Events)
+	}
+
+	for i, event := range paused.Events {
+		if err := eh.CompareEvents(event, events[i]); err != nil {
+			t.Error("the events should be handled in order:", i, err)
+		}
+	}
+	paused.RUnlock()
+
+	if err := o.Pause(ctx, "unknown"); !errors.Is(err, eh.ErrHandlerNotAdded) {
+		t.Error("there should be a handler not added error:", err)
+	}
+
+	if err := o.Resume(ctx, "unknown"); !errors.Is(err, eh.ErrHandlerNotAdded) {
+		t.Error("there should be a handler not added error:", err)
+	}
+}
@@ -52,6 +52,78 @@ type ReadWriteRepo interface {
 	WriteRepo
 }
 
+// CompareAndSetRepo is a write repository that can save versioned entities with
+// optimistic concurrency, to not overwrite changes made by concurrent writers.
+type CompareAndSetRepo interface {
+	WriteRepo
+
+	// SaveIfVersion saves a versioned entity only if the stored entity has the
+	// expected version, or does not exist when the expected version is 0.
+	// Returns ErrEntityVersionConflict if the stored version does not match.
+	SaveIfVersion(ctx context.Context, entity Entity, expectedVersion int) error
+}
+
+// FilterRepo is a read repository that can find entities by a Filter.
+type FilterRepo interface {
+	ReadRepo
+
+	// FindByFilter returns all entities matching all conditions of the filter.
+	FindByFilter(context.Context, Filter) ([]Entity, error)
+}
+
+// IterateRepo is a read repository that can iterate all entities without
+// loading them all into memory, for example to export large projections.
+type IterateRepo interface {
+	ReadRepo
+
+	// Iterate returns an iterator of all entities in the repository, the values
+	// are of type Entity. The iterator must be closed.
+	Iterate(context.Context) (Iter, error)
+}
+
+// ProjectionRepo is a read repository that can find only some fields of an
+// entity, to not transfer a large entity when only a few fields are needed.
+type ProjectionRepo interface {
+	ReadRepo
+
+	// FindProjected returns the requested fields of an entity, keyed by the
+	// field names. The field names are the names of the stored fields (nested
+	// fields can be separated by dots), as in a Filter. Fields that are missing
+	// in the stored entity are not included.
+	FindProjected(ctx context.Context, id uuid.UUID, fields []string) (map[string]interface{}, error)
+}
+
+// Filter is a simple query used to find entities in a FilterRepo. The keys are
+// the names of the stored fields (nested fields can be separated by dots) and
+// the values are either a value which the field must be equal to, or one of the
+// conditions FilterIn, FilterRange or FilterText. An entity must match all of
+// the conditions.
+//
+// The names of the stored fields are the names used by the encoding of the
+// repo, for example JSON in the memory repo and BSON in the MongoDB repo.
+// Entities that are filtered in different repos must use the same names in all
+// encodings, as with the json and bson tags of mocks.Model.
+//
+// A condition on a missing field never matches, not even equality with nil
+// which only matches fields that are stored as null.
+type Filter map[string]interface{}
+
+// FilterIn is a filter condition that matches if a field is equal to any of
+// the values.
+type FilterIn []interface{}
+
+// FilterRange is a filter condition that matches if a field is within a range,
+// both ends are inclusive and a nil end is unbounded. Supports numbers, strings
+// and times.
+type FilterRange struct {
+	Min, Max interface{}
+}
+
+// FilterText is a filter condition that matches if a field contains any of the
+// words in the text, ignoring case. Repos can require the field to be indexed
+// for text search, and can search all of the text indexed fields instead.
+type FilterText string
+
 // Iter is a stateful iterator object that when called Next() readies the next
 // value that can be retrieved from Value(). Enables incremental object retrieval
 // from repos that support it. You must call Close() on each Iter even when
@@ -70,6 +142,12 @@ var (
 	ErrEntityHasNoVersion = errors.New("entity has no version")
 	// ErrIncorrectEntityVersion is when an entity has an incorrect version.
 	ErrIncorrectEntityVersion = errors.New("incorrect entity version")
+	// ErrEntityVersionConflict is when an entity could not be saved because
+	// the stored entity has been changed by another writer.
+	ErrEntityVersionConflict = errors.New("entity version conflict")
+	// ErrCompareAndSetNotSupported is when a repo wrapping another repo is used
+	// as a CompareAndSetRepo, but the wrapped repo does not support it.
+	ErrCompareAndSetNotSupported = errors.New("compare and set not supported")
 )
 
 // RepoOperation is the operation done when an error happened.
@@ -17,7 +17,9 @@ package repo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -174,3 +176,381 @@ func AcceptanceTest(t *testing.T, repo eh.ReadWriteRepo, ctx context.Context) {
 		t.Error("there should be a ErrEntityNotFound error:", err)
 	}
 }
+
+// FilterAcceptanceTest is the acceptance test that all implementations of
+// FilterRepo should pass. The repo should be empty and any text search index
+// must be created on the "content" field before calling it:
+//
+//   func TestFilterRepo(t *testing.T) {
+//       store := NewRepo()
+//       repo.FilterAcceptanceTest(t, store, context.Background())
+//   }
+//
+func FilterAcceptanceTest(t *testing.T, r interface {
+	eh.FilterRepo
+	eh.WriteRepo
+}, ctx context.Context) {
+	entity1 := &mocks.Model{
+		ID:        uuid.New(),
+		Version:   1,
+		Content:   "The quick brown fox",
+		CreatedAt: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+	}
+	entity2 := &mocks.Model{
+		ID:        uuid.New(),
+		Version:   2,
+		Content:   "A lazy dog",
+		CreatedAt: time.Date(2009, time.November, 11, 23, 0, 0, 0, time.UTC),
+	}
+	entity3 := &mocks.Model{
+		ID:        uuid.New(),
+		Version:   3,
+		Content:   "A brown dog",
+		CreatedAt: time.Date(2009, time.November, 12, 23, 0, 0, 0, time.UTC),
+	}
+
+	// An entity stored with a null field, which the other entities are missing.
+	entity4 := &nullableModel{
+		Model: mocks.Model{
+			ID:        uuid.New(),
+			Version:   0,
+			Content:   "",
+			CreatedAt: time.Date(2009, time.November, 9, 23, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, e := range []eh.Entity{entity1, entity2, entity3, entity4} {
+		if err := r.Save(ctx, e); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}
+
+	testCases := map[string]struct {
+		filter   eh.Filter
+		expected []*mocks.Model
+	}{
+		"equality": {
+			eh.Filter{"version": 2},
+			[]*mocks.Model{entity2},
+		},
+		"in": {
+			eh.Filter{"version": eh.FilterIn{1, 3}},
+			[]*mocks.Model{entity1, entity3},
+		},
+		"range": {
+			eh.Filter{"version": eh.FilterRange{Min: 2}},
+			[]*mocks.Model{entity2, entity3},
+		},
+		"time range": {
+			eh.Filter{"created_at": eh.FilterRange{
+				Min: entity1.CreatedAt.Add(time.Hour),
+				Max: entity2.CreatedAt,
+			}},
+			[]*mocks.Model{entity2},
+		},
+		"text": {
+			eh.Filter{"content": eh.FilterText("fox")},
+			[]*mocks.Model{entity1},
+		},
+		"text ignoring case": {
+			eh.Filter{"content": eh.FilterText("DOG")},
+			[]*mocks.Model{entity2, entity3},
+		},
+		"combined": {
+			eh.Filter{
+				"version": eh.FilterRange{Max: 2},
+				"content": eh.FilterText("brown"),
+			},
+			[]*mocks.Model{entity1},
+		},
+		"no match": {
+			eh.Filter{"version": 4},
+			[]*mocks.Model{},
+		},
+		"missing field": {
+			eh.Filter{"missing": "value"},
+			[]*mocks.Model{},
+		},
+		"null": {
+			eh.Filter{"parent": nil},
+			[]*mocks.Model{&entity4.Model},
+		},
+		"null in": {
+			eh.Filter{"parent": eh.FilterIn{nil}},
+			[]*mocks.Model{&entity4.Model},
+		},
+		"null missing field": {
+			eh.Filter{"missing": nil},
+			[]*mocks.Model{},
+		},
+		"null in missing field": {
+			eh.Filter{"missing": eh.FilterIn{nil, "value"}},
+			[]*mocks.Model{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := r.FindByFilter(ctx, tc.filter)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			models := make([]*mocks.Model, 0, len(result))
+
+			for _, e := range result {
+				m, ok := e.(*mocks.Model)
+				if !ok {
+					t.Fatal("the entity should be of correct type:", e)
+				}
+
+				models = append(models, m)
+			}
+
+			// Sort by version as the order is not defined.
+			sort.Slice(models, func(i, j int) bool {
+				return models[i].Version < models[j].Version
+			})
+
+			if len(models) != len(tc.expected) {
+				t.Fatal("there should be the correct number of entities:", len(models))
+			}
+
+			for i, m := range models {
+				// Compare the time separately to ignore time zones.
+				if !m.CreatedAt.Equal(tc.expected[i].CreatedAt) {
+					t.Error("the time should be correct:", m.CreatedAt)
+				}
+
+				m.CreatedAt = tc.expected[i].CreatedAt
+
+				if !reflect.DeepEqual(m, tc.expected[i]) {
+					t.Error("the entity should be correct:", m)
+				}
+			}
+		})
+	}
+}
+
+// nullableModel is a mocks.Model stored with an extra field that can be null.
+type nullableModel struct {
+	mocks.Model `bson:",inline"`
+	Parent      *uuid.UUID `json:"parent" bson:"parent"`
+}
+
+// CompareAndSetAcceptanceTest is the acceptance test that all implementations
+// of CompareAndSetRepo should pass. It should manually be called from a test
+// case in each implementation:
+//
+//   func TestCompareAndSetRepo(t *testing.T) {
+//       store := NewRepo()
+//       repo.CompareAndSetAcceptanceTest(t, store, context.Background())
+//   }
+//
+func CompareAndSetAcceptanceTest(t *testing.T, r interface {
+	eh.ReadRepo
+	eh.CompareAndSetRepo
+}, ctx context.Context) {
+	id := uuid.New()
+
+	// Insert a new entity.
+	entity := &mocks.Model{
+		ID:      id,
+		Version: 1,
+		Content: "v1",
+	}
+	if err := r.SaveIfVersion(ctx, entity, 0); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Insert when already existing.
+	if err := r.SaveIfVersion(ctx, &mocks.Model{
+		ID:      id,
+		Version: 1,
+		Content: "other",
+	}, 0); !errors.Is(err, eh.ErrEntityVersionConflict) {
+		t.Error("there should be a version conflict error:", err)
+	}
+
+	// Update the entity.
+	entity = &mocks.Model{
+		ID:      id,
+		Version: 2,
+		Content: "v2",
+	}
+	if err := r.SaveIfVersion(ctx, entity, 1); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// Update by a stale writer.
+	if err := r.SaveIfVersion(ctx, &mocks.Model{
+		ID:      id,
+		Version: 2,
+		Content: "stale",
+	}, 1); !errors.Is(err, eh.ErrEntityVersionConflict) {
+		t.Error("there should be a version conflict error:", err)
+	}
+
+	// Update with a version ahead of the stored.
+	if err := r.SaveIfVersion(ctx, &mocks.Model{
+		ID:      id,
+		Version: 4,
+		Content: "ahead",
+	}, 3); !errors.Is(err, eh.ErrEntityVersionConflict) {
+		t.Error("there should be a version conflict error:", err)
+	}
+
+	// Only the successful writes should be stored.
+	stored, err := r.Find(ctx, id)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(stored, entity) {
+		t.Error("the entity should be correct:", stored)
+	}
+}
+
+// IterateAcceptanceTest is the acceptance test that all implementations of
+// IterateRepo should pass. It should manually be called from a test case in
+// each implementation:
+//
+//   func TestIterateRepo(t *testing.T) {
+//       store := NewRepo()
+//       repo.IterateAcceptanceTest(t, store, context.Background())
+//   }
+//
+func IterateAcceptanceTest(t *testing.T, r interface {
+	eh.WriteRepo
+	eh.IterateRepo
+}, ctx context.Context) {
+	// Save entities, the repo may already have other entities.
+	saved := map[uuid.UUID]bool{}
+
+	for i := 0; i < 100; i++ {
+		entity := &mocks.Model{
+			ID:      uuid.New(),
+			Content: "iterate",
+		}
+		if err := r.Save(ctx, entity); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		saved[entity.ID] = false
+	}
+
+	// Iterate all entities.
+	iter, err := r.Iterate(ctx)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for iter.Next(ctx) {
+		entity, ok := iter.Value().(*mocks.Model)
+		if !ok {
+			t.Fatal("the entity should be of correct type:", iter.Value())
+		}
+
+		if seen, ok := saved[entity.ID]; ok {
+			if seen {
+				t.Error("the entity should only be iterated once:", entity.ID)
+			}
+
+			saved[entity.ID] = true
+		}
+	}
+
+	if err := iter.Close(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	for id, seen := range saved {
+		if !seen {
+			t.Error("the entity should be iterated:", id)
+		}
+	}
+
+	// Cancel early.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	iter, err = r.Iterate(cancelCtx)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	count := 0
+
+	for iter.Next(cancelCtx) {
+		if count++; count == 10 {
+			cancel()
+		}
+	}
+
+	if count != 10 {
+		t.Error("the iteration should stop when cancelled:", count)
+	}
+
+	if err := iter.Close(ctx); !errors.Is(err, context.Canceled) {
+		t.Error("there should be a context canceled error:", err)
+	}
+}
+
+// ProjectionAcceptanceTest is the acceptance test that all implementations of
+// ProjectionRepo should pass. It should manually be called from a test case in
+// each implementation:
+//
+//   func TestProjectionRepo(t *testing.T) {
+//       store := NewRepo()
+//       repo.ProjectionAcceptanceTest(t, store, context.Background())
+//   }
+//
+func ProjectionAcceptanceTest(t *testing.T, r interface {
+	eh.ProjectionRepo
+	eh.WriteRepo
+}, ctx context.Context) {
+	entity := &mocks.Model{
+		ID:        uuid.New(),
+		Version:   1,
+		Content:   "content",
+		CreatedAt: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+	}
+
+	if err := r.Save(ctx, entity); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	fields, err := r.FindProjected(ctx, entity.ID, []string{"content", "version", "missing"})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Only the requested fields that exist are returned. The type of numbers
+	// differ between implementations.
+	if len(fields) != 2 {
+		t.Error("there should only be the requested fields:", fields)
+	}
+
+	if fields["content"] != "content" {
+		t.Error("the content should be correct:", fields["content"])
+	}
+
+	if fmt.Sprint(fields["version"]) != "1" {
+		t.Error("the version should be correct:", fields["version"])
+	}
+
+	// No fields.
+	fields, err = r.FindProjected(ctx, entity.ID, nil)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(fields) != 0 {
+		t.Error("there should be no fields:", fields)
+	}
+
+	// Not found.
+	if _, err := r.FindProjected(ctx, uuid.New(), []string{"content"}); !errors.Is(err, eh.ErrEntityNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+}
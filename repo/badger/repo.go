@@ -0,0 +1,401 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	eh "github.com/looplab/eventhorizon"
+	jsoncodec "github.com/looplab/eventhorizon/codec/json"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrModelNotSet is when a model factory is not set on the Repo.
+var ErrModelNotSet = errors.New("model not set")
+
+// DefaultKeyPrefix is the default prefix of the keys of the stored entities.
+const DefaultKeyPrefix = "entity/"
+
+// Repo implements a BadgerDB repository for entities, for embedded use without
+// a separate database. Entities are stored with their ID as key, after a key
+// prefix that can be used to store multiple repos in the same DB.
+type Repo struct {
+	db        *badger.DB
+	ownsDB    bool
+	prefix    []byte
+	ttl       time.Duration
+	newEntity func() eh.Entity
+	codec     eh.EntityCodec
+}
+
+// NewRepo creates a new Repo with a DB in a directory, which is closed when the
+// repo is closed.
+func NewRepo(dir string, options ...Option) (*Repo, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		return nil, fmt.Errorf("could not open DB: %w", err)
+	}
+
+	r, err := newRepoWithDB(db, true, options...)
+	if err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// NewRepoWithDB creates a new Repo with a DB, which is not closed when the repo
+// is closed.
+func NewRepoWithDB(db *badger.DB, options ...Option) (*Repo, error) {
+	return newRepoWithDB(db, false, options...)
+}
+
+func newRepoWithDB(db *badger.DB, ownsDB bool, options ...Option) (*Repo, error) {
+	if db == nil {
+		return nil, fmt.Errorf("missing DB")
+	}
+
+	r := &Repo{
+		db:     db,
+		ownsDB: ownsDB,
+		prefix: []byte(DefaultKeyPrefix),
+		codec:  &jsoncodec.EntityCodec{},
+	}
+
+	for _, option := range options {
+		if err := option(r); err != nil {
+			return nil, fmt.Errorf("error while applying option: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Repo) error
+
+// WithKeyPrefix uses a prefix for the keys of the stored entities, the default
+// is DefaultKeyPrefix. Repos sharing a DB must use different prefixes, which
+// must not be prefixes of each other.
+func WithKeyPrefix(prefix string) Option {
+	return func(r *Repo) error {
+		if prefix == "" {
+			return fmt.Errorf("missing key prefix")
+		}
+
+		r.prefix = []byte(prefix)
+
+		return nil
+	}
+}
+
+// WithEntityCodec uses a codec to marshal and unmarshal the stored entities,
+// the default is JSON.
+func WithEntityCodec(codec eh.EntityCodec) Option {
+	return func(r *Repo) error {
+		r.codec = codec
+
+		return nil
+	}
+}
+
+// WithTTL expires saved entities after a duration, using the native expiry of
+// BadgerDB. Saving an entity again restarts its TTL. Note that BadgerDB
+// expires entries with a precision of seconds.
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Repo) error {
+		if ttl <= 0 {
+			return fmt.Errorf("invalid TTL: %s", ttl)
+		}
+
+		r.ttl = ttl
+
+		return nil
+	}
+}
+
+// InnerRepo implements the InnerRepo method of the eventhorizon.ReadRepo interface.
+func (r *Repo) InnerRepo(ctx context.Context) eh.ReadRepo {
+	return nil
+}
+
+// IntoRepo tries to convert a eh.ReadRepo into a Repo by recursively looking at
+// inner repos. Returns nil if none was found.
+func IntoRepo(ctx context.Context, repo eh.ReadRepo) *Repo {
+	if repo == nil {
+		return nil
+	}
+
+	if r, ok := repo.(*Repo); ok {
+		return r
+	}
+
+	return IntoRepo(ctx, repo.InnerRepo(ctx))
+}
+
+// Find implements the Find method of the eventhorizon.ReadRepo interface.
+func (r *Repo) Find(ctx context.Context, id uuid.UUID) (eh.Entity, error) {
+	if r.newEntity == nil {
+		return nil, &eh.RepoError{
+			Err:      ErrModelNotSet,
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	var entity eh.Entity
+
+	if err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		entity, err = r.find(ctx, txn, id)
+
+		return err
+	}); err != nil {
+		return nil, &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	return entity, nil
+}
+
+// FindAll implements the FindAll method of the eventhorizon.ReadRepo interface.
+// The entities are found by a scan of the key prefix, ordered by ID.
+func (r *Repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
+	if r.newEntity == nil {
+		return nil, &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	result := []eh.Entity{}
+
+	if err := r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = r.prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(r.prefix); it.ValidForPrefix(r.prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			entity := r.newEntity()
+			if err := it.Item().Value(func(b []byte) error {
+				return r.codec.UnmarshalEntity(ctx, b, entity)
+			}); err != nil {
+				return fmt.Errorf("could not unmarshal: %w", err)
+			}
+
+			result = append(result, entity)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, &eh.RepoError{
+			Err: err,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	return result, nil
+}
+
+// Save implements the Save method of the eventhorizon.WriteRepo interface.
+func (r *Repo) Save(ctx context.Context, entity eh.Entity) error {
+	id := entity.EntityID()
+	if id == uuid.Nil {
+		return &eh.RepoError{
+			Err: fmt.Errorf("missing entity ID"),
+			Op:  eh.RepoOpSave,
+		}
+	}
+
+	if err := r.db.Update(func(txn *badger.Txn) error {
+		return r.save(ctx, txn, id, entity)
+	}); err != nil {
+		return &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		}
+	}
+
+	return nil
+}
+
+// SaveIfVersion implements the SaveIfVersion method of the
+// eventhorizon.CompareAndSetRepo interface. The version is checked and the
+// entity saved in the same transaction, which fails on a concurrent save.
+func (r *Repo) SaveIfVersion(ctx context.Context, entity eh.Entity, expectedVersion int) error {
+	if r.newEntity == nil {
+		return &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpSave,
+		}
+	}
+
+	id := entity.EntityID()
+	if id == uuid.Nil {
+		return &eh.RepoError{
+			Err: fmt.Errorf("missing entity ID"),
+			Op:  eh.RepoOpSave,
+		}
+	}
+
+	if err := r.db.Update(func(txn *badger.Txn) error {
+		version := 0
+
+		stored, err := r.find(ctx, txn, id)
+		if err != nil && !errors.Is(err, eh.ErrEntityNotFound) {
+			return err
+		} else if err == nil {
+			v, ok := stored.(eh.Versionable)
+			if !ok {
+				return eh.ErrEntityHasNoVersion
+			}
+
+			version = v.AggregateVersion()
+		}
+
+		if version != expectedVersion {
+			return eh.ErrEntityVersionConflict
+		}
+
+		return r.save(ctx, txn, id, entity)
+	}); err != nil {
+		if errors.Is(err, badger.ErrConflict) {
+			err = eh.ErrEntityVersionConflict
+		}
+
+		return &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		}
+	}
+
+	return nil
+}
+
+// Remove implements the Remove method of the eventhorizon.WriteRepo interface.
+func (r *Repo) Remove(ctx context.Context, id uuid.UUID) error {
+	if err := r.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(r.key(id)); errors.Is(err, badger.ErrKeyNotFound) {
+			return eh.ErrEntityNotFound
+		} else if err != nil {
+			return fmt.Errorf("could not get entity: %w", err)
+		}
+
+		if err := txn.Delete(r.key(id)); err != nil {
+			return fmt.Errorf("could not delete entity: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpRemove,
+			EntityID: id,
+		}
+	}
+
+	return nil
+}
+
+// find finds and unmarshals an entity in a transaction.
+func (r *Repo) find(ctx context.Context, txn *badger.Txn, id uuid.UUID) (eh.Entity, error) {
+	item, err := txn.Get(r.key(id))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, eh.ErrEntityNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get entity: %w", err)
+	}
+
+	entity := r.newEntity()
+	if err := item.Value(func(b []byte) error {
+		return r.codec.UnmarshalEntity(ctx, b, entity)
+	}); err != nil {
+		return nil, fmt.Errorf("could not unmarshal: %w", err)
+	}
+
+	return entity, nil
+}
+
+// save marshals and sets an entity in a transaction.
+func (r *Repo) save(ctx context.Context, txn *badger.Txn, id uuid.UUID, entity eh.Entity) error {
+	b, err := r.codec.MarshalEntity(ctx, entity)
+	if err != nil {
+		return fmt.Errorf("could not marshal: %w", err)
+	}
+
+	e := badger.NewEntry(r.key(id), b)
+	if r.ttl > 0 {
+		e = e.WithTTL(r.ttl)
+	}
+
+	if err := txn.SetEntry(e); err != nil {
+		return fmt.Errorf("could not set entity: %w", err)
+	}
+
+	return nil
+}
+
+// key is the key of an entity, the prefix followed by the bytes of the ID.
+func (r *Repo) key(id uuid.UUID) []byte {
+	key := make([]byte, 0, len(r.prefix)+len(id))
+	key = append(key, r.prefix...)
+
+	return append(key, id[:]...)
+}
+
+// SetEntityFactory sets a factory function that creates concrete entity types.
+func (r *Repo) SetEntityFactory(f func() eh.Entity) {
+	r.newEntity = f
+}
+
+// Clear clears the read model database, by dropping all keys with the prefix.
+func (r *Repo) Clear(ctx context.Context) error {
+	if err := r.db.DropPrefix(r.prefix); err != nil {
+		return &eh.RepoError{
+			Err: fmt.Errorf("could not drop prefix: %w", err),
+			Op:  eh.RepoOpClear,
+		}
+	}
+
+	return nil
+}
+
+// Close implements the Close method of the eventhorizon.WriteRepo interface.
+func (r *Repo) Close() error {
+	if !r.ownsDB {
+		// Don't close a DB we don't own.
+		return nil
+	}
+
+	return r.db.Close()
+}
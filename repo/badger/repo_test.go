@@ -0,0 +1,365 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package badger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestReadRepo(t *testing.T) {
+	r, err := NewRepo(t.TempDir())
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if r == nil {
+		t.Fatal("there should be a repository")
+	}
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	if r.InnerRepo(context.Background()) != nil {
+		t.Error("the inner repo should be nil")
+	}
+
+	repo.AcceptanceTest(t, r, context.Background())
+
+	if err := r.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+func TestCompareAndSetRepo(t *testing.T) {
+	r := newInMemoryRepo(t)
+
+	repo.CompareAndSetAcceptanceTest(t, r, context.Background())
+}
+
+func TestIntoRepo(t *testing.T) {
+	if r := IntoRepo(context.Background(), nil); r != nil {
+		t.Error("the repository should be nil:", r)
+	}
+
+	other := &mocks.Repo{}
+	if r := IntoRepo(context.Background(), other); r != nil {
+		t.Error("the repository should be correct:", r)
+	}
+
+	inner := newInMemoryRepo(t)
+	outer := &mocks.Repo{ParentRepo: inner}
+
+	if r := IntoRepo(context.Background(), outer); r != inner {
+		t.Error("the repository should be correct:", r)
+	}
+}
+
+func TestWithKeyPrefix(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	r1, err := NewRepoWithDB(db, WithKeyPrefix("one/"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r1.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	r2, err := NewRepoWithDB(db, WithKeyPrefix("two/"))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r2.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	if err := r1.Save(ctx, &mocks.Model{ID: uuid.New(), Content: "one"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The repos share the DB but not the entities.
+	if entities, err := r2.FindAll(ctx); err != nil || len(entities) != 0 {
+		t.Error("there should be no entities in the other repo:", entities, err)
+	}
+
+	if err := r2.Clear(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if entities, err := r1.FindAll(ctx); err != nil || len(entities) != 1 {
+		t.Error("the entity should not be cleared by the other repo:", entities, err)
+	}
+
+	// The DB is not closed by the repos.
+	if err := r1.Close(); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if db.IsClosed() {
+		t.Error("the DB should not be closed")
+	}
+
+	if _, err := NewRepoWithDB(db, WithKeyPrefix("")); err == nil {
+		t.Error("there should be an error for an empty prefix")
+	}
+}
+
+func TestWithTTL(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test waiting for the TTL")
+	}
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+	defer db.Close()
+
+	r, err := NewRepoWithDB(db, WithTTL(time.Second))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	ctx := context.Background()
+	entity := &mocks.Model{ID: uuid.New(), Content: "expiring"}
+
+	if err := r.Save(ctx, entity); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := r.Find(ctx, entity.ID); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// BadgerDB expires entries with a precision of seconds.
+	time.Sleep(2 * time.Second)
+
+	if _, err := r.Find(ctx, entity.ID); !errors.Is(err, eh.ErrEntityNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	if entities, err := r.FindAll(ctx); err != nil || len(entities) != 0 {
+		t.Error("there should be no entities:", entities, err)
+	}
+
+	if _, err := NewRepoWithDB(db, WithTTL(0)); err == nil {
+		t.Error("there should be an error for an invalid TTL")
+	}
+}
+
+func TestRecoveryAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	r, err := NewRepo(dir)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	saved := saveEntities(t, r)
+
+	if err := r.Close(); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r, err = NewRepo(dir)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+	defer r.Close()
+
+	r.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	checkEntities(t, r, ctx, saved)
+}
+
+func TestRecoveryAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	r, err := NewRepo(dir)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+	defer r.Close()
+
+	r.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	saved := saveEntities(t, r)
+
+	// Simulate a crash by copying the files of the open DB, without closing it,
+	// and open the copy.
+	crashed := t.TempDir()
+	copyDir(t, dir, crashed)
+
+	recovered, err := NewRepo(crashed)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+	defer recovered.Close()
+
+	recovered.SetEntityFactory(func() eh.Entity { return &mocks.Model{} })
+
+	checkEntities(t, recovered, ctx, saved)
+}
+
+func newInMemoryRepo(t *testing.T) *Repo {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	r, err := NewRepoWithDB(db)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	return r
+}
+
+// saveEntities saves, updates and removes entities, returning the entities
+// that should be stored.
+func saveEntities(t *testing.T, r *Repo) map[uuid.UUID]*mocks.Model {
+	t.Helper()
+
+	ctx := context.Background()
+	saved := map[uuid.UUID]*mocks.Model{}
+
+	for i := 0; i < 10; i++ {
+		entity := &mocks.Model{
+			ID:        uuid.New(),
+			Version:   1,
+			Content:   "entity",
+			CreatedAt: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+		}
+		if err := r.Save(ctx, entity); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		saved[entity.ID] = entity
+	}
+
+	for id, entity := range saved {
+		updated := *entity
+		updated.Version = 2
+		updated.Content = "updated"
+
+		if err := r.SaveIfVersion(ctx, &updated, 1); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		saved[id] = &updated
+
+		break
+	}
+
+	for id := range saved {
+		if saved[id].Version == 2 {
+			continue
+		}
+
+		if err := r.Remove(ctx, id); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		delete(saved, id)
+
+		break
+	}
+
+	return saved
+}
+
+func checkEntities(t *testing.T, r *Repo, ctx context.Context, saved map[uuid.UUID]*mocks.Model) {
+	t.Helper()
+
+	entities, err := r.FindAll(ctx)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if len(entities) != len(saved) {
+		t.Error("all entities should be recovered:", len(entities))
+	}
+
+	for _, e := range entities {
+		if !reflect.DeepEqual(e, saved[e.EntityID()]) {
+			t.Error("the entity should be recovered:", e)
+		}
+	}
+}
+
+func copyDir(t *testing.T, from, to string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == "LOCK" {
+			continue
+		}
+
+		src, err := os.Open(filepath.Join(from, entry.Name()))
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		dst, err := os.Create(filepath.Join(to, entry.Name()))
+		if err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		if _, err := io.Copy(dst, src); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+
+		src.Close()
+		dst.Close()
+	}
+}
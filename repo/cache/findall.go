@@ -0,0 +1,189 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// FindAllRepo is a middleware that caches the result of FindAll of a read
+// repository, for projections that are read often but change rarely. The cache
+// is invalidated when the repo handles an event of the aggregate type of the
+// projection, and when saving or removing entities through the repo, after
+// which the next call to FindAll refreshes it. Concurrent calls while
+// refreshing share the same call to the inner repo, and retry with their own
+// context if the context of that call is done.
+//
+// The repo should be added as an event handler to the event bus used by the
+// projector, for example with a eh.MatchAggregates for the aggregate type.
+type FindAllRepo struct {
+	eh.ReadWriteRepo
+
+	aggregateType eh.AggregateType
+	handlerType   eh.EventHandlerType
+
+	mu         sync.Mutex
+	entities   []eh.Entity
+	cached     bool
+	generation int
+	refresh    *refresh
+}
+
+// refresh is a call to FindAll of the inner repo, shared by concurrent calls.
+type refresh struct {
+	done     chan struct{}
+	entities []eh.Entity
+	err      error
+	// cancelled is set if the context of the call was done, in which case
+	// the other calls retry with their own context.
+	cancelled bool
+}
+
+// NewFindAllRepo creates a new FindAllRepo for a projection of an aggregate type.
+func NewFindAllRepo(repo eh.ReadWriteRepo, aggregateType eh.AggregateType) *FindAllRepo {
+	return &FindAllRepo{
+		ReadWriteRepo: repo,
+		aggregateType: aggregateType,
+		handlerType:   eh.EventHandlerType(fmt.Sprintf("repo-findall-cache-%s", uuid.New())),
+	}
+}
+
+// InnerRepo implements the InnerRepo method of the eventhorizon.ReadRepo interface.
+func (r *FindAllRepo) InnerRepo(ctx context.Context) eh.ReadRepo {
+	return r.ReadWriteRepo
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (r *FindAllRepo) HandlerType() eh.EventHandlerType {
+	return r.handlerType
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+// It invalidates the cache for events of the aggregate type.
+func (r *FindAllRepo) HandleEvent(ctx context.Context, event eh.Event) error {
+	if event.AggregateType() == r.aggregateType {
+		r.invalidate()
+	}
+
+	return nil
+}
+
+// FindAll implements the FindAll method of the eventhorizon.ReadRepo interface.
+func (r *FindAllRepo) FindAll(ctx context.Context) ([]eh.Entity, error) {
+	for {
+		r.mu.Lock()
+
+		if r.cached {
+			entities := append([]eh.Entity{}, r.entities...)
+			r.mu.Unlock()
+
+			return entities, nil
+		}
+
+		// Wait for a refresh that is already in flight.
+		if f := r.refresh; f != nil {
+			r.mu.Unlock()
+
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			// Retry if the refresh failed because its context was done.
+			if f.cancelled {
+				continue
+			}
+
+			if f.err != nil {
+				return nil, f.err
+			}
+
+			return append([]eh.Entity{}, f.entities...), nil
+		}
+
+		f := &refresh{done: make(chan struct{})}
+		r.refresh = f
+		generation := r.generation
+		r.mu.Unlock()
+
+		f.entities, f.err = r.ReadWriteRepo.FindAll(ctx)
+		f.cancelled = f.err != nil && ctx.Err() != nil
+
+		r.mu.Lock()
+		r.refresh = nil
+
+		// Only cache the result if it was not invalidated while refreshing.
+		if f.err == nil && generation == r.generation {
+			r.entities = f.entities
+			r.cached = true
+		}
+		r.mu.Unlock()
+
+		close(f.done)
+
+		if f.err != nil {
+			return nil, f.err
+		}
+
+		return append([]eh.Entity{}, f.entities...), nil
+	}
+}
+
+// Save implements the Save method of the eventhorizon.WriteRepo interface.
+func (r *FindAllRepo) Save(ctx context.Context, entity eh.Entity) error {
+	defer r.invalidate()
+
+	return r.ReadWriteRepo.Save(ctx, entity)
+}
+
+// SaveIfVersion implements the SaveIfVersion method of the
+// eventhorizon.CompareAndSetRepo interface, if supported by the inner repo.
+func (r *FindAllRepo) SaveIfVersion(ctx context.Context, entity eh.Entity, expectedVersion int) error {
+	cas, ok := r.ReadWriteRepo.(eh.CompareAndSetRepo)
+	if !ok {
+		return &eh.RepoError{
+			Err:      eh.ErrCompareAndSetNotSupported,
+			Op:       eh.RepoOpSave,
+			EntityID: entity.EntityID(),
+		}
+	}
+
+	defer r.invalidate()
+
+	return cas.SaveIfVersion(ctx, entity, expectedVersion)
+}
+
+// Remove implements the Remove method of the eventhorizon.WriteRepo interface.
+func (r *FindAllRepo) Remove(ctx context.Context, id uuid.UUID) error {
+	defer r.invalidate()
+
+	return r.ReadWriteRepo.Remove(ctx, id)
+}
+
+// invalidate clears the cache, and makes any refresh in flight not be cached.
+func (r *FindAllRepo) invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entities = nil
+	r.cached = false
+	r.generation++
+}
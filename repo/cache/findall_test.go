@@ -0,0 +1,268 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo"
+	"github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// NOTE: Not named "Integration" to enable running with the unit tests.
+func TestFindAllRepo(t *testing.T) {
+	baseRepo := memory.NewRepo()
+	baseRepo.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	r := NewFindAllRepo(baseRepo, mocks.AggregateType)
+	if inner := r.InnerRepo(context.Background()); inner != baseRepo {
+		t.Error("the inner repo should be correct:", inner)
+	}
+
+	repo.AcceptanceTest(t, r, context.Background())
+
+	repo.CompareAndSetAcceptanceTest(t, NewFindAllRepo(baseRepo, mocks.AggregateType), context.Background())
+}
+
+func TestFindAllRepo_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	baseRepo := memory.NewRepo()
+	baseRepo.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	inner := &countingRepo{ReadWriteRepo: baseRepo}
+	r := NewFindAllRepo(inner, mocks.AggregateType)
+
+	if err := r.Save(ctx, &mocks.Model{ID: uuid.New(), Content: "entity1"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The result is cached.
+	for i := 0; i < 3; i++ {
+		entities, err := r.FindAll(ctx)
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if len(entities) != 1 {
+			t.Error("there should be one entity:", entities)
+		}
+	}
+
+	if n := inner.calls(); n != 1 {
+		t.Error("the inner repo should be called once:", n)
+	}
+
+	// Saved by the projector without passing the cache.
+	if err := baseRepo.Save(ctx, &mocks.Model{ID: uuid.New(), Content: "entity2"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Events of other aggregate types are ignored.
+	otherEvent := eh.NewEvent(mocks.EventType, nil, time.Now(),
+		eh.ForAggregate("Other", uuid.New(), 1))
+	if err := r.HandleEvent(ctx, otherEvent); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if entities, _ := r.FindAll(ctx); len(entities) != 1 || inner.calls() != 1 {
+		t.Error("the cached result should be used:", entities)
+	}
+
+	// Events of the aggregate type invalidate the cache.
+	event := eh.NewEvent(mocks.EventType, nil, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))
+	if err := r.HandleEvent(ctx, event); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if entities, _ := r.FindAll(ctx); len(entities) != 2 || inner.calls() != 2 {
+		t.Error("the result should be refreshed:", entities)
+	}
+
+	if entities, _ := r.FindAll(ctx); len(entities) != 2 || inner.calls() != 2 {
+		t.Error("the refreshed result should be cached:", entities)
+	}
+}
+
+func TestFindAllRepo_SingleFlight(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepo{
+		ReadWriteRepo: &mocks.Repo{Entities: []eh.Entity{&mocks.Model{ID: uuid.New()}}},
+		started:       make(chan struct{}, 10),
+		release:       make(chan struct{}),
+	}
+	r := NewFindAllRepo(inner, mocks.AggregateType)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if entities, err := r.FindAll(ctx); err != nil || len(entities) != 1 {
+				t.Error("there should be one entity:", entities, err)
+			}
+		}()
+	}
+
+	<-inner.started
+	time.Sleep(10 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	if n := inner.calls(); n != 1 {
+		t.Error("the inner repo should be called once:", n)
+	}
+
+	// A result refreshed while invalidated is not cached.
+	inner.release = make(chan struct{})
+
+	if err := r.HandleEvent(ctx, eh.NewEvent(mocks.EventType, nil, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 1))); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if _, err := r.FindAll(ctx); err != nil {
+			t.Error("there should be no error:", err)
+		}
+	}()
+
+	<-inner.started
+
+	if err := r.HandleEvent(ctx, eh.NewEvent(mocks.EventType, nil, time.Now(),
+		eh.ForAggregate(mocks.AggregateType, uuid.New(), 2))); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	close(inner.release)
+	<-done
+
+	inner.release = nil
+
+	if _, err := r.FindAll(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n := inner.calls(); n != 3 {
+		t.Error("the inner repo should be called again:", n)
+	}
+}
+
+func TestFindAllRepo_SingleFlightCancelled(t *testing.T) {
+	inner := &countingRepo{
+		ReadWriteRepo: &mocks.Repo{Entities: []eh.Entity{&mocks.Model{ID: uuid.New()}}},
+		started:       make(chan struct{}, 10),
+		release:       make(chan struct{}),
+	}
+	r := NewFindAllRepo(inner, mocks.AggregateType)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+
+	go func() {
+		_, err := r.FindAll(ctx)
+		first <- err
+	}()
+
+	<-inner.started
+
+	second := make(chan error, 1)
+
+	go func() {
+		entities, err := r.FindAll(context.Background())
+		if err == nil && len(entities) != 1 {
+			t.Error("there should be one entity:", entities)
+		}
+		second <- err
+	}()
+
+	// Cancel the first call while the second is waiting for it.
+	time.Sleep(10 * time.Millisecond)
+
+	inner.mu.Lock()
+	inner.release = nil
+	inner.mu.Unlock()
+
+	cancel()
+
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Error("there should be a context canceled error:", err)
+	}
+
+	// The waiting call retries with its own context.
+	if err := <-second; err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if n := inner.calls(); n != 2 {
+		t.Error("the inner repo should be called again:", n)
+	}
+}
+
+// countingRepo counts the calls to FindAll, optionally blocking until released.
+type countingRepo struct {
+	eh.ReadWriteRepo
+	started chan struct{}
+	release chan struct{}
+
+	mu sync.Mutex
+	n  int
+}
+
+func (r *countingRepo) FindAll(ctx context.Context) ([]eh.Entity, error) {
+	r.mu.Lock()
+	r.n++
+	release := r.release
+	r.mu.Unlock()
+
+	if r.started != nil {
+		r.started <- struct{}{}
+	}
+
+	if release != nil {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return r.ReadWriteRepo.FindAll(ctx)
+}
+
+func (r *countingRepo) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.n
+}
@@ -129,6 +129,26 @@ func (r *Repo) Save(ctx context.Context, entity eh.Entity) error {
 	return r.ReadWriteRepo.Save(ctx, entity)
 }
 
+// SaveIfVersion implements the SaveIfVersion method of the
+// eventhorizon.CompareAndSetRepo interface, if supported by the inner repo.
+func (r *Repo) SaveIfVersion(ctx context.Context, entity eh.Entity, expectedVersion int) error {
+	cas, ok := r.ReadWriteRepo.(eh.CompareAndSetRepo)
+	if !ok {
+		return &eh.RepoError{
+			Err:      eh.ErrCompareAndSetNotSupported,
+			Op:       eh.RepoOpSave,
+			EntityID: entity.EntityID(),
+		}
+	}
+
+	// Bust the cache on save.
+	r.cacheMu.Lock()
+	delete(r.cache, entity.EntityID())
+	r.cacheMu.Unlock()
+
+	return cas.SaveIfVersion(ctx, entity, expectedVersion)
+}
+
 // Remove implements the Remove method of the eventhorizon.WriteRepo interface.
 func (r *Repo) Remove(ctx context.Context, id uuid.UUID) error {
 	// Bust the cache on remove.
@@ -16,12 +16,14 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/mocks"
 	"github.com/looplab/eventhorizon/repo"
 	"github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
 )
 
 // NOTE: Not named "Integration" to enable running with the unit tests.
@@ -47,6 +49,21 @@ func TestReadRepo(t *testing.T) {
 	}
 }
 
+func TestCompareAndSetRepo(t *testing.T) {
+	baseRepo := memory.NewRepo()
+	baseRepo.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.CompareAndSetAcceptanceTest(t, NewRepo(baseRepo), context.Background())
+
+	// Not supported by the inner repo.
+	r := NewRepo(&mocks.Repo{})
+	if err := r.SaveIfVersion(context.Background(), &mocks.Model{ID: uuid.New()}, 0); !errors.Is(err, eh.ErrCompareAndSetNotSupported) {
+		t.Error("there should be a not supported error:", err)
+	}
+}
+
 func TestIntoRepo(t *testing.T) {
 	if r := IntoRepo(context.Background(), nil); r != nil {
 		t.Error("the repository should be nil:", r)
@@ -0,0 +1,196 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrMissingRepos is when there are no repos to read from.
+var ErrMissingRepos = errors.New("missing repos")
+
+// ConflictResolver returns the entity to use when multiple repos contain an
+// entity with the same ID, called with the entities in the order of the repos.
+type ConflictResolver func(first, second eh.Entity) eh.Entity
+
+// FirstWins is a conflict resolver that uses the entity from the first repo,
+// which is the default.
+func FirstWins(first, second eh.Entity) eh.Entity {
+	return first
+}
+
+// LastWins is a conflict resolver that uses the entity from the last repo.
+func LastWins(first, second eh.Entity) eh.Entity {
+	return second
+}
+
+// HighestVersion is a conflict resolver that uses the entity with the highest
+// version, for entities implementing eventhorizon.Versionable. The first entity
+// is used for equal versions or if the entities are not versioned.
+func HighestVersion(first, second eh.Entity) eh.Entity {
+	f, ok := first.(eh.Versionable)
+	if !ok {
+		return first
+	}
+
+	s, ok := second.(eh.Versionable)
+	if !ok {
+		return first
+	}
+
+	if s.AggregateVersion() > f.AggregateVersion() {
+		return second
+	}
+
+	return first
+}
+
+// Repo is a read repository that merges multiple repos, for example when a
+// projection is sharded across multiple collections or databases. Entities are
+// found in the repos in order and all entities are merged by ID.
+type Repo struct {
+	repos   []eh.ReadRepo
+	resolve ConflictResolver
+}
+
+// NewRepo creates a new Repo reading from the repos in order.
+func NewRepo(repos []eh.ReadRepo, options ...Option) (*Repo, error) {
+	if len(repos) == 0 {
+		return nil, ErrMissingRepos
+	}
+
+	r := &Repo{
+		repos:   repos,
+		resolve: FirstWins,
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(r)
+	}
+
+	return r, nil
+}
+
+// Option is an option setter used to configure creation.
+type Option func(*Repo)
+
+// WithConflictResolver sets the resolver used by FindAll when multiple repos
+// contain an entity with the same ID, the default is FirstWins.
+func WithConflictResolver(f ConflictResolver) Option {
+	return func(r *Repo) {
+		r.resolve = f
+	}
+}
+
+// InnerRepo implements the InnerRepo method of the eventhorizon.ReadRepo
+// interface, it returns the first repo.
+func (r *Repo) InnerRepo(ctx context.Context) eh.ReadRepo {
+	return r.repos[0]
+}
+
+// IntoRepo tries to convert a eh.ReadRepo into a Repo by recursively looking at
+// inner repos. Returns nil if none was found.
+func IntoRepo(ctx context.Context, repo eh.ReadRepo) *Repo {
+	if repo == nil {
+		return nil
+	}
+
+	if r, ok := repo.(*Repo); ok {
+		return r
+	}
+
+	return IntoRepo(ctx, repo.InnerRepo(ctx))
+}
+
+// Find implements the Find method of the eventhorizon.ReadModel interface. The
+// repos are tried in order and the first found entity is returned.
+func (r *Repo) Find(ctx context.Context, id uuid.UUID) (eh.Entity, error) {
+	for i, repo := range r.repos {
+		entity, err := repo.Find(ctx, id)
+		if errors.Is(err, eh.ErrEntityNotFound) {
+			continue
+		} else if err != nil {
+			return nil, &eh.RepoError{
+				Err:      fmt.Errorf("could not find in repo %d: %w", i, err),
+				Op:       eh.RepoOpFind,
+				EntityID: id,
+			}
+		}
+
+		return entity, nil
+	}
+
+	return nil, &eh.RepoError{
+		Err:      eh.ErrEntityNotFound,
+		Op:       eh.RepoOpFind,
+		EntityID: id,
+	}
+}
+
+// FindAll implements the FindAll method of the eventhorizon.ReadRepo interface.
+// The entities of all repos are merged by ID, in the order they are first
+// found, using the conflict resolver for entities found in multiple repos.
+func (r *Repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
+	var (
+		result  []eh.Entity
+		indexes = map[uuid.UUID]int{}
+	)
+
+	for i, repo := range r.repos {
+		entities, err := repo.FindAll(ctx)
+		if err != nil {
+			return nil, &eh.RepoError{
+				Err: fmt.Errorf("could not find all in repo %d: %w", i, err),
+				Op:  eh.RepoOpFindAll,
+			}
+		}
+
+		for _, entity := range entities {
+			if j, ok := indexes[entity.EntityID()]; ok {
+				result[j] = r.resolve(result[j], entity)
+
+				continue
+			}
+
+			indexes[entity.EntityID()] = len(result)
+			result = append(result, entity)
+		}
+	}
+
+	return result, nil
+}
+
+// Close implements the Close method of the eventhorizon.ReadRepo interface, it
+// closes all repos and returns the first error.
+func (r *Repo) Close() error {
+	var err error
+
+	for i, repo := range r.repos {
+		if e := repo.Close(); e != nil && err == nil {
+			err = fmt.Errorf("could not close repo %d: %w", i, e)
+		}
+	}
+
+	return err
+}
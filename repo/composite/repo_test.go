@@ -0,0 +1,189 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package composite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestRepo_Find(t *testing.T) {
+	ctx := context.Background()
+	shard1, shard2 := newShard(), newShard()
+
+	r, err := NewRepo([]eh.ReadRepo{shard1, shard2})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if inner := r.InnerRepo(ctx); inner != shard1 {
+		t.Error("the inner repo should be the first repo:", inner)
+	}
+
+	if IntoRepo(ctx, r) != r {
+		t.Error("the repo should be found")
+	}
+
+	entity1 := &mocks.Model{ID: uuid.New(), Content: "shard1"}
+	entity2 := &mocks.Model{ID: uuid.New(), Content: "shard2"}
+	shared1 := &mocks.Model{ID: uuid.New(), Content: "shared in shard1"}
+	shared2 := &mocks.Model{ID: shared1.ID, Content: "shared in shard2"}
+
+	save(t, shard1, entity1, shared1)
+	save(t, shard2, entity2, shared2)
+
+	// Entities are found across the shards.
+	for _, expected := range []*mocks.Model{entity1, entity2} {
+		entity, err := r.Find(ctx, expected.ID)
+		if err != nil {
+			t.Error("there should be no error:", err)
+		}
+
+		if m, ok := entity.(*mocks.Model); !ok || m.Content != expected.Content {
+			t.Error("the entity should be correct:", entity)
+		}
+	}
+
+	// The first hit wins.
+	entity, err := r.Find(ctx, shared1.ID)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if m, ok := entity.(*mocks.Model); !ok || m.Content != shared1.Content {
+		t.Error("the entity should be from the first shard:", entity)
+	}
+
+	// Missing in all shards.
+	if _, err := r.Find(ctx, uuid.New()); !errors.Is(err, eh.ErrEntityNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	// Errors from a shard are returned.
+	repoErr := errors.New("repo error")
+
+	r, err = NewRepo([]eh.ReadRepo{shard1, &mocks.Repo{LoadErr: repoErr}})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := r.Find(ctx, uuid.New()); !errors.Is(err, repoErr) {
+		t.Error("there should be a repo error:", err)
+	}
+
+	if _, err := NewRepo(nil); !errors.Is(err, ErrMissingRepos) {
+		t.Error("there should be a missing repos error:", err)
+	}
+}
+
+func TestRepo_FindAll(t *testing.T) {
+	ctx := context.Background()
+	shard1, shard2 := newShard(), newShard()
+
+	entity1 := &mocks.Model{ID: uuid.New(), Version: 1, Content: "shard1"}
+	entity2 := &mocks.Model{ID: uuid.New(), Version: 1, Content: "shard2"}
+	shared1 := &mocks.Model{ID: uuid.New(), Version: 1, Content: "shared in shard1"}
+	shared2 := &mocks.Model{ID: shared1.ID, Version: 2, Content: "shared in shard2"}
+
+	save(t, shard1, entity1, shared1)
+	save(t, shard2, entity2, shared2)
+
+	testCases := map[string]struct {
+		options []Option
+		shared  string
+	}{
+		"first wins": {
+			nil,
+			shared1.Content,
+		},
+		"last wins": {
+			[]Option{WithConflictResolver(LastWins)},
+			shared2.Content,
+		},
+		"highest version": {
+			[]Option{WithConflictResolver(HighestVersion)},
+			shared2.Content,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewRepo([]eh.ReadRepo{shard1, shard2}, tc.options...)
+			if err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			entities, err := r.FindAll(ctx)
+			if err != nil {
+				t.Error("there should be no error:", err)
+			}
+
+			// The entities are merged and de-duplicated by ID.
+			contents := map[uuid.UUID]string{}
+			for _, e := range entities {
+				contents[e.EntityID()] = e.(*mocks.Model).Content
+			}
+
+			if len(entities) != 3 || len(contents) != 3 {
+				t.Fatal("there should be 3 entities:", entities)
+			}
+
+			if contents[entity1.ID] != entity1.Content || contents[entity2.ID] != entity2.Content {
+				t.Error("the entities should be correct:", contents)
+			}
+
+			if contents[shared1.ID] != tc.shared {
+				t.Error("the conflict should be resolved:", contents[shared1.ID])
+			}
+		})
+	}
+
+	// Errors from a shard are returned.
+	repoErr := errors.New("repo error")
+
+	r, err := NewRepo([]eh.ReadRepo{shard1, &mocks.Repo{LoadErr: repoErr}})
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if _, err := r.FindAll(ctx); !errors.Is(err, repoErr) {
+		t.Error("there should be a repo error:", err)
+	}
+}
+
+func newShard() *memory.Repo {
+	r := memory.NewRepo()
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	return r
+}
+
+func save(t *testing.T, r eh.WriteRepo, entities ...eh.Entity) {
+	t.Helper()
+
+	for _, e := range entities {
+		if err := r.Save(context.Background(), e); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	eh "github.com/looplab/eventhorizon"
+)
+
+// matchFilter matches a document, as unmarshaled from JSON, with all the
+// conditions of a filter.
+func matchFilter(doc map[string]interface{}, filter eh.Filter) (bool, error) {
+	for field, cond := range filter {
+		v, ok := lookupField(doc, field)
+		if !ok {
+			return false, nil
+		}
+
+		match, err := matchCondition(v, cond)
+		if err != nil {
+			return false, fmt.Errorf("invalid filter for field '%s': %w", field, err)
+		}
+
+		if !match {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func matchCondition(v interface{}, cond interface{}) (bool, error) {
+	switch c := cond.(type) {
+	case eh.FilterIn:
+		for _, val := range c {
+			val, err := normalize(val)
+			if err != nil {
+				return false, err
+			}
+
+			if reflect.DeepEqual(v, val) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case eh.FilterRange:
+		if c.Min != nil {
+			min, err := normalize(c.Min)
+			if err != nil {
+				return false, err
+			}
+
+			if res, ok := compare(v, min); !ok || res < 0 {
+				return false, nil
+			}
+		}
+
+		if c.Max != nil {
+			max, err := normalize(c.Max)
+			if err != nil {
+				return false, err
+			}
+
+			if res, ok := compare(v, max); !ok || res > 0 {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	case eh.FilterText:
+		s, ok := v.(string)
+		if !ok {
+			return false, nil
+		}
+
+		words := map[string]bool{}
+		for _, w := range splitWords(s) {
+			words[w] = true
+		}
+
+		for _, w := range splitWords(string(c)) {
+			if words[w] {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	default:
+		val, err := normalize(cond)
+		if err != nil {
+			return false, err
+		}
+
+		return reflect.DeepEqual(v, val), nil
+	}
+}
+
+// lookupField looks up a field in a document, nested fields are separated
+// by dots.
+func lookupField(doc map[string]interface{}, field string) (interface{}, bool) {
+	var v interface{} = doc
+
+	for _, f := range strings.Split(field, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if v, ok = m[f]; !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// normalize converts a value to the same form as a field unmarshaled from JSON.
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal value: %w", err)
+	}
+
+	var n interface{}
+	if err := json.Unmarshal(b, &n); err != nil {
+		return nil, fmt.Errorf("could not unmarshal value: %w", err)
+	}
+
+	return n, nil
+}
+
+// compare compares two normalized values, returns false if they can not be
+// compared. Strings that are valid times are compared as times.
+func compare(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+
+		ta, errA := time.Parse(time.RFC3339Nano, a)
+		tb, errB := time.Parse(time.RFC3339Nano, b)
+
+		if errA == nil && errB == nil {
+			switch {
+			case ta.Before(tb):
+				return -1, true
+			case ta.After(tb):
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+
+		return strings.Compare(a, b), true
+	default:
+		return 0, false
+	}
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
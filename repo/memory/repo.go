@@ -22,6 +22,7 @@ import (
 	"sync"
 
 	eh "github.com/looplab/eventhorizon"
+	jsoncodec "github.com/looplab/eventhorizon/codec/json"
 	"github.com/looplab/eventhorizon/uuid"
 )
 
@@ -36,17 +37,39 @@ type Repo struct {
 	// A list of all item ids, only the order is used.
 	ids       []uuid.UUID
 	factoryFn func() eh.Entity
+	codec     eh.EntityCodec
 }
 
 // NewRepo creates a new Repo.
-func NewRepo() *Repo {
+func NewRepo(options ...Option) *Repo {
 	r := &Repo{
-		db: map[uuid.UUID][]byte{},
+		db:    map[uuid.UUID][]byte{},
+		codec: &jsoncodec.EntityCodec{},
+	}
+
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+
+		option(r)
 	}
 
 	return r
 }
 
+// Option is an option setter used to configure creation.
+type Option func(*Repo)
+
+// WithEntityCodec uses a codec to marshal and unmarshal the stored entities,
+// the default is JSON. Filters and projections use the field names of the
+// stored entities, which must be JSON objects for them to work.
+func WithEntityCodec(codec eh.EntityCodec) Option {
+	return func(r *Repo) {
+		r.codec = codec
+	}
+}
+
 // InnerRepo implements the InnerRepo method of the eventhorizon.ReadRepo interface.
 func (r *Repo) InnerRepo(ctx context.Context) eh.ReadRepo {
 	return nil
@@ -91,7 +114,7 @@ func (r *Repo) Find(ctx context.Context, id uuid.UUID) (eh.Entity, error) {
 
 	// Unmarshal.
 	entity := r.factoryFn()
-	if err := json.Unmarshal(b, &entity); err != nil {
+	if err := r.codec.UnmarshalEntity(ctx, b, entity); err != nil {
 		return nil, &eh.RepoError{
 			Err:      fmt.Errorf("could not unmarshal: %w", err),
 			Op:       eh.RepoOpFind,
@@ -119,7 +142,7 @@ func (r *Repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
 	for _, id := range r.ids {
 		if b, ok := r.db[id]; ok {
 			entity := r.factoryFn()
-			if err := json.Unmarshal(b, &entity); err != nil {
+			if err := r.codec.UnmarshalEntity(ctx, b, entity); err != nil {
 				return nil, &eh.RepoError{
 					Err: fmt.Errorf("could not unmarshal: %w", err),
 					Op:  eh.RepoOpFindAll,
@@ -133,6 +156,175 @@ func (r *Repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
 	return result, nil
 }
 
+// Iterate implements the Iterate method of the eventhorizon.IterateRepo
+// interface. The iterator unmarshals one entity at a time, entities saved after
+// creating the iterator are not included and removed entities are skipped.
+func (r *Repo) Iterate(ctx context.Context) (eh.Iter, error) {
+	if r.factoryFn == nil {
+		return nil, &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	r.dbMu.RLock()
+	ids := make([]uuid.UUID, len(r.ids))
+	copy(ids, r.ids)
+	r.dbMu.RUnlock()
+
+	return &iter{
+		repo: r,
+		ids:  ids,
+	}, nil
+}
+
+// iter is an iterator of the entities in the repo.
+// The iterator is not thread safe.
+type iter struct {
+	repo   *Repo
+	ids    []uuid.UUID
+	entity eh.Entity
+	err    error
+}
+
+func (i *iter) Next(ctx context.Context) bool {
+	for len(i.ids) > 0 && i.err == nil {
+		if err := ctx.Err(); err != nil {
+			i.err = err
+
+			return false
+		}
+
+		id := i.ids[0]
+		i.ids = i.ids[1:]
+
+		i.repo.dbMu.RLock()
+		b, ok := i.repo.db[id]
+		i.repo.dbMu.RUnlock()
+
+		if !ok {
+			continue
+		}
+
+		entity := i.repo.factoryFn()
+		if err := i.repo.codec.UnmarshalEntity(ctx, b, entity); err != nil {
+			i.err = &eh.RepoError{
+				Err:      fmt.Errorf("could not unmarshal: %w", err),
+				Op:       eh.RepoOpFindAll,
+				EntityID: id,
+			}
+
+			return false
+		}
+
+		i.entity = entity
+
+		return true
+	}
+
+	return false
+}
+
+func (i *iter) Value() interface{} {
+	return i.entity
+}
+
+func (i *iter) Close(ctx context.Context) error {
+	i.ids = nil
+	i.entity = nil
+
+	return i.err
+}
+
+// FindByFilter implements the FindByFilter method of the eventhorizon.FilterRepo
+// interface. Text search is emulated by matching whole words in the field.
+func (r *Repo) FindByFilter(ctx context.Context, filter eh.Filter) ([]eh.Entity, error) {
+	if r.factoryFn == nil {
+		return nil, &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpFindQuery,
+		}
+	}
+
+	r.dbMu.RLock()
+	defer r.dbMu.RUnlock()
+
+	result := []eh.Entity{}
+
+	for _, id := range r.ids {
+		b, ok := r.db[id]
+		if !ok {
+			continue
+		}
+
+		var doc map[string]interface{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, &eh.RepoError{
+				Err: fmt.Errorf("could not unmarshal: %w", err),
+				Op:  eh.RepoOpFindQuery,
+			}
+		}
+
+		match, err := matchFilter(doc, filter)
+		if err != nil {
+			return nil, &eh.RepoError{
+				Err: err,
+				Op:  eh.RepoOpFindQuery,
+			}
+		} else if !match {
+			continue
+		}
+
+		entity := r.factoryFn()
+		if err := r.codec.UnmarshalEntity(ctx, b, entity); err != nil {
+			return nil, &eh.RepoError{
+				Err: fmt.Errorf("could not unmarshal: %w", err),
+				Op:  eh.RepoOpFindQuery,
+			}
+		}
+
+		result = append(result, entity)
+	}
+
+	return result, nil
+}
+
+// FindProjected implements the FindProjected method of the
+// eventhorizon.ProjectionRepo interface. The field names are the JSON names
+// of the stored entity and the values are as unmarshaled from JSON.
+func (r *Repo) FindProjected(ctx context.Context, id uuid.UUID, fields []string) (map[string]interface{}, error) {
+	r.dbMu.RLock()
+	defer r.dbMu.RUnlock()
+
+	b, ok := r.db[id]
+	if !ok {
+		return nil, &eh.RepoError{
+			Err:      eh.ErrEntityNotFound,
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, &eh.RepoError{
+			Err:      fmt.Errorf("could not unmarshal: %w", err),
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	projected := make(map[string]interface{}, len(fields))
+
+	for _, f := range fields {
+		if v, ok := lookupField(doc, f); ok {
+			projected[f] = v
+		}
+	}
+
+	return projected, nil
+}
+
 // Save implements the Save method of the eventhorizon.WriteRepo interface.
 func (r *Repo) Save(ctx context.Context, entity eh.Entity) error {
 	if r.factoryFn == nil {
@@ -153,8 +345,69 @@ func (r *Repo) Save(ctx context.Context, entity eh.Entity) error {
 	r.dbMu.Lock()
 	defer r.dbMu.Unlock()
 
-	// Insert entity.
-	b, err := json.Marshal(entity)
+	return r.save(ctx, id, entity)
+}
+
+// SaveIfVersion implements the SaveIfVersion method of the
+// eventhorizon.CompareAndSetRepo interface.
+func (r *Repo) SaveIfVersion(ctx context.Context, entity eh.Entity, expectedVersion int) error {
+	if r.factoryFn == nil {
+		return &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpSave,
+		}
+	}
+
+	id := entity.EntityID()
+	if id == uuid.Nil {
+		return &eh.RepoError{
+			Err: fmt.Errorf("missing entity ID"),
+			Op:  eh.RepoOpSave,
+		}
+	}
+
+	r.dbMu.Lock()
+	defer r.dbMu.Unlock()
+
+	// Check the version of the stored entity.
+	version := 0
+
+	if b, ok := r.db[id]; ok {
+		stored := r.factoryFn()
+		if err := r.codec.UnmarshalEntity(ctx, b, stored); err != nil {
+			return &eh.RepoError{
+				Err:      fmt.Errorf("could not unmarshal: %w", err),
+				Op:       eh.RepoOpSave,
+				EntityID: id,
+			}
+		}
+
+		v, ok := stored.(eh.Versionable)
+		if !ok {
+			return &eh.RepoError{
+				Err:      eh.ErrEntityHasNoVersion,
+				Op:       eh.RepoOpSave,
+				EntityID: id,
+			}
+		}
+
+		version = v.AggregateVersion()
+	}
+
+	if version != expectedVersion {
+		return &eh.RepoError{
+			Err:      eh.ErrEntityVersionConflict,
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		}
+	}
+
+	return r.save(ctx, id, entity)
+}
+
+// save inserts or updates an entity, must be called with the lock held.
+func (r *Repo) save(ctx context.Context, id uuid.UUID, entity eh.Entity) error {
+	b, err := r.codec.MarshalEntity(ctx, entity)
 	if err != nil {
 		return &eh.RepoError{
 			Err:      fmt.Errorf("could not marshal: %w", err),
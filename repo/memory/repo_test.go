@@ -16,11 +16,16 @@ package memory
 
 import (
 	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	eh "github.com/looplab/eventhorizon"
 	"github.com/looplab/eventhorizon/mocks"
 	"github.com/looplab/eventhorizon/repo"
+	"github.com/looplab/eventhorizon/uuid"
 )
 
 // NOTE: Not named "Integration" to enable running with the unit tests.
@@ -62,3 +67,170 @@ func TestIntoRepo(t *testing.T) {
 		t.Error("the repository should be correct:", r)
 	}
 }
+
+func TestFilterRepo(t *testing.T) {
+	r := NewRepo()
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.FilterAcceptanceTest(t, r, context.Background())
+}
+
+func TestCompareAndSetRepo(t *testing.T) {
+	r := NewRepo()
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.CompareAndSetAcceptanceTest(t, r, context.Background())
+}
+
+func TestIterateRepo(t *testing.T) {
+	r := NewRepo()
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.IterateAcceptanceTest(t, r, context.Background())
+}
+
+func TestProjectionRepo(t *testing.T) {
+	r := NewRepo()
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.ProjectionAcceptanceTest(t, r, context.Background())
+}
+
+func TestIterateRepo_Large(t *testing.T) {
+	r := NewRepo()
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	ctx := context.Background()
+
+	const n = 10000
+	for i := 0; i < n; i++ {
+		if err := r.Save(ctx, &mocks.Model{ID: uuid.New()}); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	iter, err := r.Iterate(ctx)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// Removed entities are skipped.
+	removed := 0
+
+	count := 0
+	for iter.Next(ctx) {
+		count++
+
+		if count == 1 {
+			all, _ := r.FindAll(ctx)
+			if err := r.Remove(ctx, all[n-1].EntityID()); err != nil {
+				t.Fatal("there should be no error:", err)
+			}
+
+			removed++
+		}
+	}
+
+	if err := iter.Close(ctx); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if count != n-removed {
+		t.Error("all entities should be iterated:", count)
+	}
+}
+
+func TestRepo_EntityCodec(t *testing.T) {
+	r := NewRepo(WithEntityCodec(&renamingCodec{}))
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	ctx := context.Background()
+	entity := &mocks.Model{
+		ID:        uuid.New(),
+		Version:   1,
+		Content:   "content",
+		CreatedAt: time.Now().Round(time.Millisecond).UTC(),
+	}
+
+	if err := r.Save(ctx, entity); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	// The field is renamed in the stored entity.
+	if b := string(r.db[entity.ID]); !strings.Contains(b, `"body":"content"`) ||
+		strings.Contains(b, `"content":`) {
+		t.Error("the stored entity should use the codec:", b)
+	}
+
+	found, err := r.Find(ctx, entity.ID)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(found, entity) {
+		t.Error("the entity should be correct:", found)
+	}
+
+	// Filters use the stored field names.
+	result, err := r.FindByFilter(ctx, eh.Filter{"body": "content"})
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(result) != 1 || !reflect.DeepEqual(result[0], entity) {
+		t.Error("the filtered entities should be correct:", result)
+	}
+
+	if err := r.SaveIfVersion(ctx, entity, 1); err != nil {
+		t.Error("there should be no error:", err)
+	}
+}
+
+// renamingCodec stores the content of a mocks.Model as "body".
+type renamingCodec struct{}
+
+type renamedModel struct {
+	ID        uuid.UUID `json:"id"`
+	Version   int       `json:"version"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *renamingCodec) MarshalEntity(ctx context.Context, entity eh.Entity) ([]byte, error) {
+	m := entity.(*mocks.Model)
+
+	return json.Marshal(renamedModel{
+		ID:        m.ID,
+		Version:   m.Version,
+		Body:      m.Content,
+		CreatedAt: m.CreatedAt,
+	})
+}
+
+func (c *renamingCodec) UnmarshalEntity(ctx context.Context, b []byte, entity eh.Entity) error {
+	var r renamedModel
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+
+	*entity.(*mocks.Model) = mocks.Model{
+		ID:        r.ID,
+		Version:   r.Version,
+		Content:   r.Body,
+		CreatedAt: r.CreatedAt,
+	}
+
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -47,6 +48,7 @@ type Repo struct {
 	clientOwnership clientOwnership
 	entities        *mongo.Collection
 	newEntity       func() eh.Entity
+	codec           eh.EntityCodec
 	connectionCheck bool
 }
 
@@ -115,6 +117,17 @@ func WithConnectionCheck(h eh.EventHandler) Option {
 	}
 }
 
+// WithEntityCodec uses a codec to marshal and unmarshal the stored entities
+// instead of the BSON encoding of the driver. The codec must marshal entities
+// to BSON documents, and queries use the field names of the stored documents.
+func WithEntityCodec(codec eh.EntityCodec) Option {
+	return func(r *Repo) error {
+		r.codec = codec
+
+		return nil
+	}
+}
+
 // InnerRepo implements the InnerRepo method of the eventhorizon.ReadRepo interface.
 func (r *Repo) InnerRepo(ctx context.Context) eh.ReadRepo {
 	return nil
@@ -145,7 +158,7 @@ func (r *Repo) Find(ctx context.Context, id uuid.UUID) (eh.Entity, error) {
 	}
 
 	entity := r.newEntity()
-	if err := r.entities.FindOne(ctx, bson.M{"_id": id.String()}).Decode(entity); err != nil {
+	if err := r.decodeEntity(ctx, r.entities.FindOne(ctx, bson.M{"_id": id.String()}), entity); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			err = eh.ErrEntityNotFound
 		}
@@ -160,6 +173,66 @@ func (r *Repo) Find(ctx context.Context, id uuid.UUID) (eh.Entity, error) {
 	return entity, nil
 }
 
+// FindProjected implements the FindProjected method of the
+// eventhorizon.ProjectionRepo interface, using a projection to only fetch the
+// requested fields. The field names are the BSON names of the stored entity.
+func (r *Repo) FindProjected(ctx context.Context, id uuid.UUID, fields []string) (map[string]interface{}, error) {
+	projection := bson.M{"_id": 1}
+	for _, f := range fields {
+		projection[f] = 1
+	}
+
+	var doc bson.M
+	if err := r.entities.FindOne(ctx, bson.M{"_id": id.String()},
+		mongoOptions.FindOne().SetProjection(projection),
+	).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			err = eh.ErrEntityNotFound
+		}
+
+		return nil, &eh.RepoError{
+			Err:      err,
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	projected := make(map[string]interface{}, len(fields))
+
+	for _, f := range fields {
+		if v, ok := lookupField(doc, f); ok {
+			projected[f] = v
+		}
+	}
+
+	return projected, nil
+}
+
+// lookupField looks up a field in a document, nested fields are separated
+// by dots.
+func lookupField(doc bson.M, field string) (interface{}, bool) {
+	var v interface{} = doc
+
+	for _, f := range strings.Split(field, ".") {
+		var ok bool
+
+		switch m := v.(type) {
+		case bson.M:
+			v, ok = m[f]
+		case map[string]interface{}:
+			v, ok = m[f]
+		case bson.D:
+			v, ok = m.Map()[f]
+		}
+
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
 // FindAll implements the FindAll method of the eventhorizon.ReadRepo interface.
 func (r *Repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
 	if r.newEntity == nil {
@@ -181,7 +254,7 @@ func (r *Repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
 
 	for cursor.Next(ctx) {
 		entity := r.newEntity()
-		if err := cursor.Decode(entity); err != nil {
+		if err := r.decodeEntity(ctx, cursor, entity); err != nil {
 			return nil, &eh.RepoError{
 				Err: fmt.Errorf("could not unmarshal: %w", err),
 				Op:  eh.RepoOpFindAll,
@@ -201,21 +274,111 @@ func (r *Repo) FindAll(ctx context.Context) ([]eh.Entity, error) {
 	return result, nil
 }
 
+// FindByFilter implements the FindByFilter method of the eventhorizon.FilterRepo
+// interface. Text search requires a text index, see CreateTextIndex, and will
+// search all text indexed fields regardless of the field used in the filter.
+func (r *Repo) FindByFilter(ctx context.Context, filter eh.Filter) ([]eh.Entity, error) {
+	if r.newEntity == nil {
+		return nil, &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpFindQuery,
+		}
+	}
+
+	cursor, err := r.entities.Find(ctx, newFilterQuery(filter))
+	if err != nil {
+		return nil, &eh.RepoError{
+			Err: fmt.Errorf("could not find: %w", err),
+			Op:  eh.RepoOpFindQuery,
+		}
+	}
+
+	result := []eh.Entity{}
+
+	for cursor.Next(ctx) {
+		entity := r.newEntity()
+		if err := r.decodeEntity(ctx, cursor, entity); err != nil {
+			return nil, &eh.RepoError{
+				Err: fmt.Errorf("could not unmarshal: %w", err),
+				Op:  eh.RepoOpFindQuery,
+			}
+		}
+
+		result = append(result, entity)
+	}
+
+	if err := cursor.Close(ctx); err != nil {
+		return nil, &eh.RepoError{
+			Err: fmt.Errorf("could not close cursor: %w", err),
+			Op:  eh.RepoOpFindQuery,
+		}
+	}
+
+	return result, nil
+}
+
+// newFilterQuery creates a MongoDB query from a filter.
+func newFilterQuery(filter eh.Filter) bson.M {
+	query := bson.M{}
+
+	for field, cond := range filter {
+		switch c := cond.(type) {
+		case eh.FilterIn:
+			// A nil value should not match missing fields, see eh.Filter.
+			query[field] = bson.M{"$in": []interface{}(c), "$exists": true}
+		case eh.FilterRange:
+			r := bson.M{}
+			if c.Min != nil {
+				r["$gte"] = c.Min
+			}
+
+			if c.Max != nil {
+				r["$lte"] = c.Max
+			}
+
+			if len(r) == 0 {
+				// Only require the field to exist.
+				r["$exists"] = true
+			}
+
+			query[field] = r
+		case eh.FilterText:
+			query["$text"] = bson.M{"$search": string(c)}
+		case nil:
+			// Only match null and not missing fields, see eh.Filter.
+			query[field] = bson.M{"$type": "null"}
+		default:
+			query[field] = cond
+		}
+	}
+
+	return query
+}
+
 // The iterator is not thread safe.
 type iter struct {
 	cursor    *mongo.Cursor
 	data      eh.Entity
 	newEntity func() eh.Entity
+	decode    func(context.Context, decoder, eh.Entity) error
 	decodeErr error
+	ctxErr    error
 }
 
 func (i *iter) Next(ctx context.Context) bool {
+	// The cursor only checks the context when fetching the next batch.
+	if err := ctx.Err(); err != nil {
+		i.ctxErr = err
+
+		return false
+	}
+
 	if !i.cursor.Next(ctx) {
 		return false
 	}
 
 	item := i.newEntity()
-	i.decodeErr = i.cursor.Decode(item)
+	i.decodeErr = i.decode(ctx, i.cursor, item)
 	i.data = item
 
 	return true
@@ -230,9 +393,42 @@ func (i *iter) Close(ctx context.Context) error {
 		return err
 	}
 
+	if i.ctxErr != nil {
+		return i.ctxErr
+	}
+
+	if err := i.cursor.Err(); err != nil {
+		return err
+	}
+
 	return i.decodeErr
 }
 
+// Iterate implements the Iterate method of the eventhorizon.IterateRepo
+// interface, backed by a cursor which fetches the entities in batches.
+func (r *Repo) Iterate(ctx context.Context) (eh.Iter, error) {
+	if r.newEntity == nil {
+		return nil, &eh.RepoError{
+			Err: ErrModelNotSet,
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	cursor, err := r.entities.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, &eh.RepoError{
+			Err: fmt.Errorf("could not find: %w", err),
+			Op:  eh.RepoOpFindAll,
+		}
+	}
+
+	return &iter{
+		cursor:    cursor,
+		newEntity: r.newEntity,
+		decode:    r.decodeEntity,
+	}, nil
+}
+
 // FindCustomIter returns a mgo cursor you can use to stream results of very large datasets.
 func (r *Repo) FindCustomIter(ctx context.Context, f func(context.Context, *mongo.Collection) (*mongo.Cursor, error)) (eh.Iter, error) {
 	if r.newEntity == nil {
@@ -260,6 +456,7 @@ func (r *Repo) FindCustomIter(ctx context.Context, f func(context.Context, *mong
 	return &iter{
 		cursor:    cursor,
 		newEntity: r.newEntity,
+		decode:    r.decodeEntity,
 	}, nil
 }
 
@@ -295,7 +492,7 @@ func (r *Repo) FindCustom(ctx context.Context, f func(context.Context, *mongo.Co
 	entity := r.newEntity()
 
 	for cursor.Next(ctx) {
-		if err := cursor.Decode(entity); err != nil {
+		if err := r.decodeEntity(ctx, cursor, entity); err != nil {
 			return nil, &eh.RepoError{
 				Err: fmt.Errorf("could not unmarshal: %w", err),
 				Op:  eh.RepoOpFindQuery,
@@ -326,7 +523,7 @@ func (r *Repo) FindOneCustom(ctx context.Context, f func(context.Context, *mongo
 	}
 
 	entity := r.newEntity()
-	if err := f(ctx, r.entities).Decode(entity); err != nil {
+	if err := r.decodeEntity(ctx, f(ctx, r.entities), entity); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			err = eh.ErrEntityNotFound
 		}
@@ -350,12 +547,21 @@ func (r *Repo) Save(ctx context.Context, entity eh.Entity) error {
 		}
 	}
 
+	doc, err := r.encodeEntity(ctx, entity)
+	if err != nil {
+		return &eh.RepoError{
+			Err:      fmt.Errorf("could not marshal: %w", err),
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		}
+	}
+
 	if _, err := r.entities.UpdateOne(ctx,
 		bson.M{
 			"_id": id.String(),
 		},
 		bson.M{
-			"$set": entity,
+			"$set": doc,
 		},
 		options.Update().SetUpsert(true),
 	); err != nil {
@@ -369,6 +575,107 @@ func (r *Repo) Save(ctx context.Context, entity eh.Entity) error {
 	return nil
 }
 
+// SaveIfVersion implements the SaveIfVersion method of the
+// eventhorizon.CompareAndSetRepo interface. The version of the entity must be
+// stored in the "version" field of the document.
+func (r *Repo) SaveIfVersion(ctx context.Context, entity eh.Entity, expectedVersion int) error {
+	id := entity.EntityID()
+	if id == uuid.Nil {
+		return &eh.RepoError{
+			Err: fmt.Errorf("missing entity ID"),
+			Op:  eh.RepoOpSave,
+		}
+	}
+
+	doc, err := r.encodeEntity(ctx, entity)
+	if err != nil {
+		return &eh.RepoError{
+			Err:      fmt.Errorf("could not marshal: %w", err),
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		}
+	}
+
+	// Insert new entities only if they don't exist, else update only if the
+	// version matches. Both are atomic on the document.
+	var res *mongo.UpdateResult
+
+	if expectedVersion == 0 {
+		res, err = r.entities.UpdateOne(ctx,
+			bson.M{
+				"_id": id.String(),
+			},
+			bson.M{
+				"$setOnInsert": doc,
+			},
+			options.Update().SetUpsert(true),
+		)
+	} else {
+		res, err = r.entities.UpdateOne(ctx,
+			bson.M{
+				"_id":     id.String(),
+				"version": expectedVersion,
+			},
+			bson.M{
+				"$set": doc,
+			},
+		)
+	}
+
+	if err != nil {
+		return &eh.RepoError{
+			Err:      fmt.Errorf("could not save/update: %w", err),
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		}
+	}
+
+	if (expectedVersion == 0 && res.UpsertedCount == 0) ||
+		(expectedVersion != 0 && res.MatchedCount == 0) {
+		return &eh.RepoError{
+			Err:      eh.ErrEntityVersionConflict,
+			Op:       eh.RepoOpSave,
+			EntityID: id,
+		}
+	}
+
+	return nil
+}
+
+// decoder is a cursor or single result to decode an entity from.
+type decoder interface {
+	Decode(v interface{}) error
+}
+
+// decodeEntity decodes an entity using the codec, if set.
+func (r *Repo) decodeEntity(ctx context.Context, d decoder, entity eh.Entity) error {
+	if r.codec == nil {
+		return d.Decode(entity)
+	}
+
+	var raw bson.Raw
+	if err := d.Decode(&raw); err != nil {
+		return err
+	}
+
+	return r.codec.UnmarshalEntity(ctx, raw, entity)
+}
+
+// encodeEntity encodes an entity using the codec, if set, as a document to
+// store.
+func (r *Repo) encodeEntity(ctx context.Context, entity eh.Entity) (interface{}, error) {
+	if r.codec == nil {
+		return entity, nil
+	}
+
+	b, err := r.codec.MarshalEntity(ctx, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.Raw(b), nil
+}
+
 // Remove implements the Remove method of the eventhorizon.WriteRepo interface.
 func (r *Repo) Remove(ctx context.Context, id uuid.UUID) error {
 	if r, err := r.entities.DeleteOne(ctx, bson.M{"_id": id.String()}); err != nil {
@@ -409,6 +716,22 @@ func (r *Repo) CreateIndex(ctx context.Context, field string) error {
 	return nil
 }
 
+// CreateTextIndex creates a text index for one or more fields, used for text
+// search with eh.FilterText. A collection can only have one text index.
+func (r *Repo) CreateTextIndex(ctx context.Context, fields ...string) error {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+	}
+
+	index := mongo.IndexModel{Keys: keys}
+	if _, err := r.entities.Indexes().CreateOne(ctx, index); err != nil {
+		return fmt.Errorf("could not create text index: %s", err)
+	}
+
+	return nil
+}
+
 // SetEntityFactory sets a factory function that creates concrete entity types.
 func (r *Repo) SetEntityFactory(f func() eh.Entity) {
 	r.newEntity = f
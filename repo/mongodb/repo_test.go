@@ -205,6 +205,158 @@ func extraRepoTests(t *testing.T, r *Repo) {
 	}
 }
 
+func TestFilterRepoIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	r, err := NewRepo(url, db, "mocks.Model")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer r.Close()
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	if err := r.CreateTextIndex(context.Background(), "content"); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	repo.FilterAcceptanceTest(t, r, context.Background())
+}
+
+func TestCompareAndSetRepoIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	r, err := NewRepo(url, db, "mocks.Model")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer r.Close()
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.CompareAndSetAcceptanceTest(t, r, context.Background())
+}
+
+func TestIterateRepoIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	r, err := NewRepo(url, db, "mocks.Model")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer r.Close()
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.IterateAcceptanceTest(t, r, context.Background())
+}
+
+func TestProjectionRepoIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	r, err := NewRepo(url, db, "mocks.Model")
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer r.Close()
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.ProjectionAcceptanceTest(t, r, context.Background())
+}
+
 func TestIntoRepoIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -248,3 +400,110 @@ func TestIntoRepoIntegration(t *testing.T) {
 		t.Error("the repository should be correct:", r)
 	}
 }
+
+func TestEntityCodecIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	// Use MongoDB in Docker with fallback to localhost.
+	addr := os.Getenv("MONGODB_ADDR")
+	if addr == "" {
+		addr = "localhost:27017"
+	}
+
+	url := "mongodb://" + addr
+
+	// Get a random DB name.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	db := "test-" + hex.EncodeToString(b)
+
+	t.Log("using DB:", db)
+
+	r, err := NewRepo(url, db, "mocks.Model", WithEntityCodec(&renamingCodec{}))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	defer r.Close()
+
+	r.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	ctx := context.Background()
+
+	repo.AcceptanceTest(t, r, ctx)
+	repo.CompareAndSetAcceptanceTest(t, r, ctx)
+
+	entity := &mocks.Model{
+		ID:        uuid.New(),
+		Content:   "content",
+		CreatedAt: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+	}
+	if err := r.Save(ctx, entity); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	// The field is renamed in the stored document.
+	var doc bson.M
+	if err := r.Collection(ctx, func(ctx context.Context, c *mongo.Collection) error {
+		return c.FindOne(ctx, bson.M{"_id": entity.ID.String()}).Decode(&doc)
+	}); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if _, ok := doc["content"]; ok || doc["body"] != "content" {
+		t.Error("the stored document should use the codec:", doc)
+	}
+
+	found, err := r.Find(ctx, entity.ID)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if !reflect.DeepEqual(found, entity) {
+		t.Error("the entity should be correct:", found)
+	}
+}
+
+// renamingCodec stores the content of a mocks.Model as "body".
+type renamingCodec struct{}
+
+type renamedModel struct {
+	ID        uuid.UUID `bson:"_id"`
+	Version   int       `bson:"version"`
+	Body      string    `bson:"body"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+func (c *renamingCodec) MarshalEntity(ctx context.Context, entity eh.Entity) ([]byte, error) {
+	m := entity.(*mocks.Model)
+
+	return bson.Marshal(renamedModel{
+		ID:        m.ID,
+		Version:   m.Version,
+		Body:      m.Content,
+		CreatedAt: m.CreatedAt,
+	})
+}
+
+func (c *renamingCodec) UnmarshalEntity(ctx context.Context, b []byte, entity eh.Entity) error {
+	var r renamedModel
+	if err := bson.Unmarshal(b, &r); err != nil {
+		return err
+	}
+
+	*entity.(*mocks.Model) = mocks.Model{
+		ID:        r.ID,
+		Version:   r.Version,
+		Content:   r.Body,
+		CreatedAt: r.CreatedAt.UTC(),
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrIncorrectEntityType is when an entity is not of the type of a Typed repo.
+var ErrIncorrectEntityType = errors.New("incorrect entity type")
+
+// Typed is a read repository which returns entities as *T instead of
+// eh.Entity, by wrapping an untyped read repository. The inner repository
+// must store entities of type *T, any other type results in an
+// ErrIncorrectEntityType.
+type Typed[T any] struct {
+	repo eh.ReadRepo
+}
+
+// NewTyped creates a new Typed repo.
+func NewTyped[T any](repo eh.ReadRepo) *Typed[T] {
+	return &Typed[T]{
+		repo: repo,
+	}
+}
+
+// InnerRepo returns the untyped inner read repository.
+func (r *Typed[T]) InnerRepo(ctx context.Context) eh.ReadRepo {
+	return r.repo
+}
+
+// Find returns an entity for an ID.
+func (r *Typed[T]) Find(ctx context.Context, id uuid.UUID) (*T, error) {
+	entity, err := r.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := interface{}(entity).(*T)
+	if !ok {
+		return nil, &eh.RepoError{
+			Err:      incorrectEntityTypeError[T](entity),
+			Op:       eh.RepoOpFind,
+			EntityID: id,
+		}
+	}
+
+	return t, nil
+}
+
+// FindAll returns all entities in the repository.
+func (r *Typed[T]) FindAll(ctx context.Context) ([]*T, error) {
+	entities, err := r.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*T, 0, len(entities))
+
+	for _, entity := range entities {
+		t, ok := interface{}(entity).(*T)
+		if !ok {
+			return nil, &eh.RepoError{
+				Err:      incorrectEntityTypeError[T](entity),
+				Op:       eh.RepoOpFindAll,
+				EntityID: entity.EntityID(),
+			}
+		}
+
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func incorrectEntityTypeError[T any](entity eh.Entity) error {
+	return fmt.Errorf("%w: %T (should be %T)", ErrIncorrectEntityType, entity, new(T))
+}
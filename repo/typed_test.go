@@ -0,0 +1,109 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestTyped(t *testing.T) {
+	ctx := context.Background()
+
+	inner := memory.NewRepo()
+	inner.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	r := NewTyped[mocks.Model](inner)
+	if r.InnerRepo(ctx) != inner {
+		t.Error("the inner repo should be correct:", r.InnerRepo(ctx))
+	}
+
+	entity1 := &mocks.Model{ID: uuid.New(), Content: "entity1"}
+	entity2 := &mocks.Model{ID: uuid.New(), Content: "entity2"}
+
+	for _, entity := range []*mocks.Model{entity1, entity2} {
+		if err := inner.Save(ctx, entity); err != nil {
+			t.Fatal("there should be no error:", err)
+		}
+	}
+
+	model, err := r.Find(ctx, entity1.ID)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if model == nil || model.Content != "entity1" {
+		t.Error("the entity should be correct:", model)
+	}
+
+	if _, err := r.Find(ctx, uuid.New()); !errors.Is(err, eh.ErrEntityNotFound) {
+		t.Error("there should be a not found error:", err)
+	}
+
+	models, err := r.FindAll(ctx)
+	if err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(models) != 2 {
+		t.Error("there should be two entities:", models)
+	}
+}
+
+func TestTyped_IncorrectType(t *testing.T) {
+	ctx := context.Background()
+
+	inner := memory.NewRepo()
+	inner.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	id := uuid.New()
+	if err := inner.Save(ctx, &mocks.Model{ID: id, Content: "entity"}); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	r := NewTyped[mocks.SimpleModel](inner)
+
+	var repoErr *eh.RepoError
+
+	model, err := r.Find(ctx, id)
+	if !errors.Is(err, ErrIncorrectEntityType) || !errors.As(err, &repoErr) {
+		t.Error("there should be an incorrect entity type error:", err)
+	} else if repoErr.EntityID != id {
+		t.Error("the error should have the entity ID:", repoErr.EntityID)
+	}
+
+	if model != nil {
+		t.Error("there should be no entity:", model)
+	}
+
+	models, err := r.FindAll(ctx)
+	if !errors.Is(err, ErrIncorrectEntityType) {
+		t.Error("there should be an incorrect entity type error:", err)
+	}
+
+	if models != nil {
+		t.Error("there should be no entities:", models)
+	}
+}
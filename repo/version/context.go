@@ -31,7 +31,7 @@ func init() {
 		if v, ok := ctx.Value(minVersionKey).(int); ok {
 			vals[minVersionKeyStr] = v
 		}
-	})
+	}, minVersionKeyStr)
 
 	eh.RegisterContextUnmarshaler(func(ctx context.Context, vals map[string]interface{}) context.Context {
 		if v, ok := vals[minVersionKeyStr].(int); ok {
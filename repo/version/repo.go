@@ -56,6 +56,21 @@ func IntoRepo(ctx context.Context, repo eh.ReadRepo) *Repo {
 	return IntoRepo(ctx, repo.InnerRepo(ctx))
 }
 
+// SaveIfVersion implements the SaveIfVersion method of the
+// eventhorizon.CompareAndSetRepo interface, if supported by the inner repo.
+func (r *Repo) SaveIfVersion(ctx context.Context, entity eh.Entity, expectedVersion int) error {
+	cas, ok := r.ReadWriteRepo.(eh.CompareAndSetRepo)
+	if !ok {
+		return &eh.RepoError{
+			Err:      eh.ErrCompareAndSetNotSupported,
+			Op:       eh.RepoOpSave,
+			EntityID: entity.EntityID(),
+		}
+	}
+
+	return cas.SaveIfVersion(ctx, entity, expectedVersion)
+}
+
 // Find implements the Find method of the eventhorizon.ReadModel interface.
 // If the context contains a min version set by WithMinVersion it will only
 // return an item if its version is at least min version. If a timeout or
@@ -295,6 +295,21 @@ func extraRepoTests(t *testing.T, r *Repo, baseRepo *memory.Repo) {
 	}
 }
 
+func TestCompareAndSetRepo(t *testing.T) {
+	baseRepo := memory.NewRepo()
+	baseRepo.SetEntityFactory(func() eh.Entity {
+		return &mocks.Model{}
+	})
+
+	repo.CompareAndSetAcceptanceTest(t, NewRepo(baseRepo), context.Background())
+
+	// Not supported by the inner repo.
+	r := NewRepo(&mocks.Repo{})
+	if err := r.SaveIfVersion(context.Background(), &mocks.Model{ID: uuid.New()}, 0); !errors.Is(err, eh.ErrCompareAndSetNotSupported) {
+		t.Error("there should be a not supported error:", err)
+	}
+}
+
 func TestIntoRepo(t *testing.T) {
 	if r := IntoRepo(context.Background(), nil); r != nil {
 		t.Error("the repository should be nil:", r)
@@ -0,0 +1,141 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/aggregatestore/events"
+)
+
+// AggregateTest is a Given/When/Then test of an aggregate. The aggregate is
+// created using the factory registered for the aggregate type of the command,
+// and must implement the events.VersionedAggregate interface.
+type AggregateTest struct {
+	t              testing.TB
+	given          []eh.Event
+	cmd            eh.Command
+	compareOptions []eh.CompareOption
+	clock          eh.Clock
+}
+
+// Given starts an aggregate test with the events that should be applied to
+// the aggregate before handling the command.
+func Given(t testing.TB, events ...eh.Event) *AggregateTest {
+	return &AggregateTest{
+		t:              t,
+		given:          events,
+		compareOptions: []eh.CompareOption{eh.IgnoreTimestamp()},
+	}
+}
+
+// When sets the command to handle by the aggregate.
+func (a *AggregateTest) When(cmd eh.Command) *AggregateTest {
+	a.cmd = cmd
+
+	return a
+}
+
+// CompareTimestamps also compares the timestamps of the produced and expected
+// events, which are ignored by default.
+func (a *AggregateTest) CompareTimestamps() *AggregateTest {
+	a.compareOptions = nil
+
+	return a
+}
+
+// WithClock adds a clock on the context used when handling the command, for
+// aggregates that use eh.Now for the timestamps of their events.
+func (a *AggregateTest) WithClock(c eh.Clock) *AggregateTest {
+	a.clock = c
+
+	return a
+}
+
+// Then handles the command and checks that the aggregate produced exactly the
+// expected events.
+func (a *AggregateTest) Then(expected ...eh.Event) {
+	a.t.Helper()
+
+	produced, err := a.handle()
+	if err != nil {
+		a.t.Error("there should be no error:", err)
+
+		return
+	}
+
+	if len(produced) != len(expected) {
+		a.t.Errorf("there should be %d events: %v", len(expected), produced)
+
+		return
+	}
+
+	for i, e := range produced {
+		if err := eh.CompareEvents(e, expected[i], a.compareOptions...); err != nil {
+			a.t.Errorf("the event %d should be correct: %s", i, err)
+		}
+	}
+}
+
+// ThenError handles the command and checks that the expected error is
+// returned, as matched by errors.Is.
+func (a *AggregateTest) ThenError(expected error) {
+	a.t.Helper()
+
+	if _, err := a.handle(); !errors.Is(err, expected) {
+		a.t.Errorf("there should be an error: %v (should be %v)", err, expected)
+	}
+}
+
+// handle creates the aggregate, applies the given events and handles the
+// command, returning the produced events.
+func (a *AggregateTest) handle() ([]eh.Event, error) {
+	if err := eh.CheckCommand(a.cmd); err != nil {
+		return nil, err
+	}
+
+	agg, err := eh.CreateAggregate(a.cmd.AggregateType(), a.cmd.AggregateID())
+	if err != nil {
+		return nil, err
+	}
+
+	va, ok := agg.(events.VersionedAggregate)
+	if !ok {
+		return nil, events.ErrAggregateNotVersioned
+	}
+
+	ctx := context.Background()
+	if a.clock != nil {
+		ctx = eh.NewContextWithClock(ctx, a.clock)
+	}
+
+	for _, e := range a.given {
+		if err := va.ApplyEvent(ctx, e); err != nil {
+			return nil, fmt.Errorf("could not apply given event %s: %w", e, err)
+		}
+
+		va.SetAggregateVersion(e.Version())
+	}
+
+	if err := va.HandleCommand(ctx, a.cmd); err != nil {
+		return nil, err
+	}
+
+	return va.UncommittedEvents(), nil
+}
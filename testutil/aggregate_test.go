@@ -0,0 +1,271 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/aggregatestore/events"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestAggregateTest_Then(t *testing.T) {
+	id := uuid.New()
+
+	// No given events.
+	Given(t).When(
+		&Increment{ID: id, Amount: 2},
+	).Then(
+		incremented(id, 1, 2),
+	)
+
+	// With given events.
+	Given(t,
+		incremented(id, 1, 2),
+		incremented(id, 2, 3),
+	).When(
+		&Increment{ID: id, Amount: 4},
+	).Then(
+		incremented(id, 3, 4),
+	)
+
+	// Multiple produced events.
+	Given(t,
+		incremented(id, 1, 2),
+	).When(
+		&Increment{ID: id, Amount: 3, Times: 2},
+	).Then(
+		incremented(id, 2, 3),
+		incremented(id, 3, 3),
+	)
+}
+
+func TestAggregateTest_ThenError(t *testing.T) {
+	id := uuid.New()
+
+	Given(t,
+		incremented(id, 1, 9),
+	).When(
+		&Increment{ID: id, Amount: 2},
+	).ThenError(ErrTooLarge)
+
+	// The command is checked before handling.
+	Given(t).When(
+		&Increment{Amount: 2},
+	).ThenError(eh.ErrMissingAggregateID)
+
+	// Errors when applying the given events.
+	Given(t,
+		eh.NewEvent("Unknown", nil, time.Now(), eh.ForAggregate(CounterAggregateType, id, 1)),
+	).When(
+		&Increment{ID: id, Amount: 2},
+	).ThenError(ErrUnknownEvent)
+}
+
+func TestAggregateTest_CompareTimestamps(t *testing.T) {
+	id := uuid.New()
+
+	Given(t).When(
+		&Increment{ID: id, Amount: 2, At: timestamp},
+	).CompareTimestamps().Then(
+		incremented(id, 1, 2),
+	)
+
+	ft := &fakeT{TB: t}
+	Given(ft).When(
+		&Increment{ID: id, Amount: 2, At: timestamp.Add(time.Second)},
+	).CompareTimestamps().Then(
+		incremented(id, 1, 2),
+	)
+
+	if !ft.failed {
+		t.Error("the test should fail on different timestamps")
+	}
+}
+
+func TestAggregateTest_WithClock(t *testing.T) {
+	id := uuid.New()
+
+	Given(t).When(
+		&Increment{ID: id, Amount: 2},
+	).WithClock(eh.ClockFunc(func() time.Time { return timestamp })).CompareTimestamps().Then(
+		incremented(id, 1, 2),
+	)
+}
+
+func TestAggregateTest_Failures(t *testing.T) {
+	id := uuid.New()
+
+	testCases := map[string]func(testing.TB){
+		"incorrect event": func(t testing.TB) {
+			Given(t).When(
+				&Increment{ID: id, Amount: 2},
+			).Then(
+				incremented(id, 1, 3),
+			)
+		},
+		"incorrect version": func(t testing.TB) {
+			Given(t).When(
+				&Increment{ID: id, Amount: 2},
+			).Then(
+				incremented(id, 2, 2),
+			)
+		},
+		"missing event": func(t testing.TB) {
+			Given(t).When(
+				&Increment{ID: id, Amount: 2},
+			).Then()
+		},
+		"unexpected error": func(t testing.TB) {
+			Given(t, incremented(id, 1, 9)).When(
+				&Increment{ID: id, Amount: 2},
+			).Then(
+				incremented(id, 2, 2),
+			)
+		},
+		"missing error": func(t testing.TB) {
+			Given(t).When(
+				&Increment{ID: id, Amount: 2},
+			).ThenError(ErrTooLarge)
+		},
+		"incorrect error": func(t testing.TB) {
+			Given(t, incremented(id, 1, 9)).When(
+				&Increment{ID: id, Amount: 2},
+			).ThenError(eh.ErrMissingAggregateID)
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ft := &fakeT{TB: t}
+			tc(ft)
+
+			if !ft.failed {
+				t.Error("the test should fail")
+			}
+		})
+	}
+}
+
+// fakeT records failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Error(args ...interface{}) {
+	t.failed = true
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+}
+
+var timestamp = time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+const (
+	CounterAggregateType eh.AggregateType = "Counter"
+	IncrementCommand     eh.CommandType   = "Increment"
+	IncrementedEvent     eh.EventType     = "Incremented"
+)
+
+var (
+	ErrTooLarge     = errors.New("counter too large")
+	ErrUnknownEvent = errors.New("unknown event")
+)
+
+func init() {
+	eh.RegisterAggregate(func(id uuid.UUID) eh.Aggregate {
+		return &Counter{
+			AggregateBase: events.NewAggregateBase(CounterAggregateType, id),
+		}
+	})
+}
+
+type Increment struct {
+	ID     uuid.UUID
+	Amount int
+	Times  int       `eh:"optional"`
+	At     time.Time `eh:"optional"`
+}
+
+func (c Increment) AggregateID() uuid.UUID          { return c.ID }
+func (c Increment) AggregateType() eh.AggregateType { return CounterAggregateType }
+func (c Increment) CommandType() eh.CommandType     { return IncrementCommand }
+
+type IncrementedData struct {
+	Amount int
+}
+
+func incremented(id uuid.UUID, version, amount int) eh.Event {
+	return eh.NewEvent(IncrementedEvent, &IncrementedData{Amount: amount}, timestamp,
+		eh.ForAggregate(CounterAggregateType, id, version))
+}
+
+// Counter is an aggregate that can count up to 10.
+type Counter struct {
+	*events.AggregateBase
+	count int
+}
+
+func (a *Counter) HandleCommand(ctx context.Context, cmd eh.Command) error {
+	switch cmd := cmd.(type) {
+	case *Increment:
+		times := cmd.Times
+		if times == 0 {
+			times = 1
+		}
+
+		if a.count+cmd.Amount*times > 10 {
+			return ErrTooLarge
+		}
+
+		at := cmd.At
+		if at.IsZero() {
+			at = eh.Now(ctx)
+		}
+
+		for i := 0; i < times; i++ {
+			a.AppendEvent(IncrementedEvent, &IncrementedData{Amount: cmd.Amount}, at)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown command: %s", cmd.CommandType())
+}
+
+func (a *Counter) ApplyEvent(ctx context.Context, event eh.Event) error {
+	switch event.EventType() {
+	case IncrementedEvent:
+		data, ok := event.Data().(*IncrementedData)
+		if !ok {
+			return errors.New("invalid event data")
+		}
+
+		a.count += data.Amount
+
+		return nil
+	}
+
+	return ErrUnknownEvent
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testutil contains helpers for testing domains built with Event
+// Horizon.
+//
+// Aggregates can be tested in a Given/When/Then style, where the given events
+// are applied to a new aggregate before the command is handled, and the events
+// produced by the aggregate are compared with the expected events:
+//
+//	func TestUserAggregate(t *testing.T) {
+//	    id := uuid.New()
+//
+//	    testutil.Given(t,
+//	        eh.NewEvent(UserCreatedEvent, &UserCreatedData{Name: "Alice"}, time.Now(),
+//	            eh.ForAggregate(UserAggregateType, id, 1)),
+//	    ).When(
+//	        &RenameUser{ID: id, Name: "Bob"},
+//	    ).Then(
+//	        eh.NewEvent(UserRenamedEvent, &UserRenamedData{Name: "Bob"}, time.Now(),
+//	            eh.ForAggregate(UserAggregateType, id, 2)),
+//	    )
+//	}
+//
+// Errors returned when handling the command can be checked with ThenError:
+//
+//	testutil.Given(t).When(&RenameUser{ID: id, Name: "Bob"}).ThenError(ErrUserNotCreated)
+//
+// End to end tests can dispatch a command and wait for the produced events to
+// be handled by a projector with a Settler, which is used as the event handler
+// of the event store and publishes the events on the event bus:
+//
+//	settler := testutil.NewSettler(commandBus, eventBus, userRepo)
+//	eventStore, err := memory.NewEventStore(memory.WithEventHandler(settler))
+//	...
+//	err = settler.AddProjector(ctx, eh.MatchEvents{UserCreatedEvent, UserRenamedEvent}, userProjector)
+//	...
+//	entity, err := settler.DispatchAndSettle(ctx, &RenameUser{ID: id, Name: "Bob"}, userProjector.HandlerType())
+package testutil
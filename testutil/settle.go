@@ -0,0 +1,241 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+// ErrProjectorNotAdded is when settling on a projector that is not added to
+// the Settler.
+var ErrProjectorNotAdded = errors.New("projector not added")
+
+// Settler dispatches commands and waits until the events produced by each
+// command are handled by a projector, for deterministic assertions in end to end
+// tests without sleeping.
+//
+// The Settler must be used as the event handler of the event store, in place of
+// the event bus which it forwards the events to, to record the events produced
+// when handling a command. The projectors must be added to the event bus with
+// AddProjector, to record when they have handled the events.
+type Settler struct {
+	commandHandler eh.CommandHandler
+	bus            eh.EventBus
+	repo           eh.ReadRepo
+
+	dispatchMu sync.Mutex
+
+	mu         sync.Mutex
+	recording  bool
+	produced   []eh.Event
+	projectors map[eh.EventHandlerType]*settledProjector
+	changed    chan struct{}
+}
+
+// settledProjector is the matcher and handled events of a projector.
+type settledProjector struct {
+	matcher eh.EventMatcher
+	handled map[settledEvent]error
+}
+
+// settledEvent identifies an event across an event bus.
+type settledEvent struct {
+	eventType   eh.EventType
+	aggregateID uuid.UUID
+	version     int
+}
+
+func newSettledEvent(event eh.Event) settledEvent {
+	return settledEvent{event.EventType(), event.AggregateID(), event.Version()}
+}
+
+var _ = eh.EventHandler(&Settler{})
+
+// NewSettler creates a new Settler that dispatches commands to the command
+// handler (or command bus), publishes events on the event bus and finds the
+// resulting entities in the repo.
+func NewSettler(commandHandler eh.CommandHandler, bus eh.EventBus, repo eh.ReadRepo) *Settler {
+	return &Settler{
+		commandHandler: commandHandler,
+		bus:            bus,
+		repo:           repo,
+		projectors:     map[eh.EventHandlerType]*settledProjector{},
+		changed:        make(chan struct{}),
+	}
+}
+
+// HandlerType implements the HandlerType method of the eventhorizon.EventHandler interface.
+func (s *Settler) HandlerType() eh.EventHandlerType {
+	return "settler"
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+// It records the events produced by a dispatched command and publishes them on
+// the event bus.
+func (s *Settler) HandleEvent(ctx context.Context, event eh.Event) error {
+	s.mu.Lock()
+	if s.recording {
+		s.produced = append(s.produced, event)
+	}
+	s.mu.Unlock()
+
+	return s.bus.HandleEvent(ctx, event)
+}
+
+// AddProjector adds a projector (or any other event handler) to the event bus
+// with a matcher, and records the events it handles.
+func (s *Settler) AddProjector(ctx context.Context, m eh.EventMatcher, h eh.EventHandler) error {
+	if m == nil {
+		return eh.ErrMissingMatcher
+	}
+
+	if h == nil {
+		return eh.ErrMissingHandler
+	}
+
+	s.mu.Lock()
+	if _, ok := s.projectors[h.HandlerType()]; ok {
+		s.mu.Unlock()
+
+		return eh.ErrHandlerAlreadyAdded
+	}
+
+	s.projectors[h.HandlerType()] = &settledProjector{
+		matcher: m,
+		handled: map[settledEvent]error{},
+	}
+	s.mu.Unlock()
+
+	return s.bus.AddHandler(ctx, m, &settledHandler{h, s})
+}
+
+// DispatchAndSettle handles the command and waits until all events produced
+// while handling it, and matched by the projector, are handled by the projector.
+// It then returns the entity with the ID of the aggregate of the command from
+// the repo. Errors from the projector when handling the events are returned.
+//
+// Use a context with a timeout to not wait forever, for example if an event is
+// lost. Commands are dispatched one at a time.
+func (s *Settler) DispatchAndSettle(ctx context.Context, cmd eh.Command, projector eh.EventHandlerType) (eh.Entity, error) {
+	s.mu.Lock()
+	p, ok := s.projectors[projector]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProjectorNotAdded, projector)
+	}
+
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	s.mu.Lock()
+	s.recording = true
+	s.mu.Unlock()
+
+	err := s.commandHandler.HandleCommand(ctx, cmd)
+
+	s.mu.Lock()
+	produced := s.produced
+	s.recording = false
+	s.produced = nil
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not handle command: %w", err)
+	}
+
+	if err := s.settle(ctx, p, produced); err != nil {
+		return nil, err
+	}
+
+	entity, err := s.repo.Find(ctx, cmd.AggregateID())
+	if err != nil {
+		return nil, fmt.Errorf("could not find entity: %w", err)
+	}
+
+	return entity, nil
+}
+
+// settle waits until the matching events are handled by the projector.
+func (s *Settler) settle(ctx context.Context, p *settledProjector, events []eh.Event) error {
+	for {
+		s.mu.Lock()
+		done := true
+
+		for _, event := range events {
+			if !p.matcher.Match(event) {
+				continue
+			}
+
+			err, ok := p.handled[newSettledEvent(event)]
+			if !ok {
+				done = false
+
+				break
+			}
+
+			if err != nil {
+				s.mu.Unlock()
+
+				return fmt.Errorf("could not project event %s: %w", event, err)
+			}
+		}
+
+		changed := s.changed
+		s.mu.Unlock()
+
+		if done {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("could not settle: %w", ctx.Err())
+		}
+	}
+}
+
+// settledHandler records the events handled by a projector.
+type settledHandler struct {
+	eh.EventHandler
+	s *Settler
+}
+
+// InnerHandler implements EventHandlerChain
+func (h *settledHandler) InnerHandler() eh.EventHandler {
+	return h.EventHandler
+}
+
+// HandleEvent implements the HandleEvent method of the eventhorizon.EventHandler interface.
+func (h *settledHandler) HandleEvent(ctx context.Context, event eh.Event) error {
+	err := h.EventHandler.HandleEvent(ctx, event)
+
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+
+	h.s.projectors[h.HandlerType()].handled[newSettledEvent(event)] = err
+
+	close(h.s.changed)
+	h.s.changed = make(chan struct{})
+
+	return err
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/aggregatestore/events"
+	"github.com/looplab/eventhorizon/commandhandler/aggregate"
+	"github.com/looplab/eventhorizon/commandhandler/bus"
+	"github.com/looplab/eventhorizon/eventbus/local"
+	"github.com/looplab/eventhorizon/eventhandler/projector"
+	"github.com/looplab/eventhorizon/eventstore/memory"
+	repomemory "github.com/looplab/eventhorizon/repo/memory"
+	"github.com/looplab/eventhorizon/uuid"
+)
+
+func TestSettler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	settler, eventBus := newSettler(t)
+	defer eventBus.Close()
+
+	id := uuid.New()
+
+	entity, err := settler.DispatchAndSettle(ctx, &Increment{ID: id, Amount: 2}, counterProjectorHandlerType)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if c, ok := entity.(*CounterModel); !ok || c.Count != 2 || c.Version != 1 {
+		t.Error("the entity should be projected:", entity)
+	}
+
+	// Multiple events from one command.
+	entity, err = settler.DispatchAndSettle(ctx, &Increment{ID: id, Amount: 3, Times: 2}, counterProjectorHandlerType)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if c, ok := entity.(*CounterModel); !ok || c.Count != 8 || c.Version != 3 {
+		t.Error("the entity should be projected:", entity)
+	}
+}
+
+func TestSettler_Errors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	settler, eventBus := newSettler(t)
+	defer eventBus.Close()
+
+	id := uuid.New()
+
+	if _, err := settler.DispatchAndSettle(ctx, &Increment{ID: id, Amount: 2}, "unknown"); !errors.Is(err, ErrProjectorNotAdded) {
+		t.Error("there should be a projector not added error:", err)
+	}
+
+	if _, err := settler.DispatchAndSettle(ctx, &Increment{ID: id, Amount: 11}, counterProjectorHandlerType); !errors.Is(err, ErrTooLarge) {
+		t.Error("there should be a command error:", err)
+	}
+
+	if _, err := settler.DispatchAndSettle(ctx, &Increment{ID: id, Amount: 5}, counterProjectorHandlerType); !errors.Is(err, errProjection) {
+		t.Error("there should be a projection error:", err)
+	}
+
+	if err := settler.AddProjector(ctx, eh.MatchAll{}, projector.NewEventHandler(&CounterProjector{}, repomemory.NewRepo())); !errors.Is(err, eh.ErrHandlerAlreadyAdded) {
+		t.Error("there should be a handler already added error:", err)
+	}
+}
+
+// newSettler sets up a domain with a command bus, an event store publishing on
+// an event bus through the settler and a projector.
+func newSettler(t *testing.T) (*Settler, *local.EventBus) {
+	t.Helper()
+
+	commandBus := bus.NewCommandHandler()
+	eventBus := local.NewEventBus()
+	repo := repomemory.NewRepo()
+	repo.SetEntityFactory(func() eh.Entity { return &CounterModel{} })
+	settler := NewSettler(commandBus, eventBus, repo)
+
+	eventStore, err := memory.NewEventStore(memory.WithEventHandler(settler))
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	aggregateStore, err := events.NewAggregateStore(eventStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	commandHandler, err := aggregate.NewCommandHandler(CounterAggregateType, aggregateStore)
+	if err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	if err := commandBus.SetHandler(commandHandler, IncrementCommand); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	p := projector.NewEventHandler(&CounterProjector{}, repo)
+	p.SetEntityFactory(func() eh.Entity { return &CounterModel{} })
+
+	if err := settler.AddProjector(context.Background(), eh.MatchEvents{IncrementedEvent}, p); err != nil {
+		t.Fatal("there should be no error:", err)
+	}
+
+	return settler, eventBus
+}
+
+func init() {
+	eh.RegisterEventData(IncrementedEvent, func() eh.EventData { return &IncrementedData{} })
+}
+
+const counterProjectorHandlerType = eh.EventHandlerType("projector_" + CounterProjectorType)
+
+const CounterProjectorType projector.Type = "Counter"
+
+var errProjection = errors.New("projection error")
+
+// CounterModel is a read model of a counter.
+type CounterModel struct {
+	ID      uuid.UUID
+	Version int
+	Count   int
+}
+
+func (m *CounterModel) EntityID() uuid.UUID   { return m.ID }
+func (m *CounterModel) AggregateVersion() int { return m.Version }
+
+// CounterProjector projects counters, but fails on increments of 5.
+type CounterProjector struct{}
+
+func (p *CounterProjector) ProjectorType() projector.Type { return CounterProjectorType }
+
+func (p *CounterProjector) Project(ctx context.Context, event eh.Event, entity eh.Entity) (eh.Entity, error) {
+	m, ok := entity.(*CounterModel)
+	if !ok {
+		return nil, errors.New("invalid model")
+	}
+
+	data, ok := event.Data().(*IncrementedData)
+	if !ok {
+		return nil, errors.New("invalid event data")
+	}
+
+	if data.Amount == 5 {
+		return nil, errProjection
+	}
+
+	m.ID = event.AggregateID()
+	m.Version = event.Version()
+	m.Count += data.Amount
+
+	return m, nil
+}
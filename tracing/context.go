@@ -60,7 +60,7 @@ func RegisterContext() {
 
 			vals[tracingSpanKeyStr] = string(js)
 		}
-	})
+	}, tracingSpanKeyStr)
 	eh.RegisterContextUnmarshaler(func(ctx context.Context, vals map[string]interface{}) context.Context {
 		if js, ok := vals[tracingSpanKeyStr].(string); ok {
 			tracer := opentracing.GlobalTracer()
@@ -23,15 +23,49 @@ import (
 	"github.com/opentracing/opentracing-go/ext"
 )
 
-// NewEventHandlerMiddleware returns an event handler middleware that adds tracing spans.
-func NewEventHandlerMiddleware() eh.EventHandlerMiddleware {
+// DefaultCorrelationIDKey is the default metadata key of the correlation ID
+// that is added as a tag to the spans of handled events.
+const DefaultCorrelationIDKey = "correlation_id"
+
+// NewEventHandlerMiddleware returns an event handler middleware that adds tracing
+// spans. The spans are tagged with the event type, aggregate type, aggregate ID,
+// version and the correlation ID from the metadata of the event, if any, to be
+// able to search for the handling of events in traces.
+func NewEventHandlerMiddleware(options ...EventHandlerOption) eh.EventHandlerMiddleware {
 	return eh.EventHandlerMiddleware(func(h eh.EventHandler) eh.EventHandler {
-		return &eventHandler{h}
+		handler := &eventHandler{
+			EventHandler:     h,
+			correlationIDKey: DefaultCorrelationIDKey,
+		}
+
+		for _, option := range options {
+			if option == nil {
+				continue
+			}
+
+			option(handler)
+		}
+
+		return handler
 	})
 }
 
+// EventHandlerOption is an option setter used to configure the event handler
+// middleware.
+type EventHandlerOption func(*eventHandler)
+
+// WithCorrelationIDKey sets the metadata key of the correlation ID, for systems
+// that use another key than DefaultCorrelationIDKey. An empty key disables the
+// correlation ID tag.
+func WithCorrelationIDKey(key string) EventHandlerOption {
+	return func(h *eventHandler) {
+		h.correlationIDKey = key
+	}
+}
+
 type eventHandler struct {
 	eh.EventHandler
+	correlationIDKey string
 }
 
 // InnerHandler implements MiddlewareChain
@@ -54,6 +88,12 @@ func (h *eventHandler) HandleEvent(ctx context.Context, event eh.Event) error {
 	sp.SetTag("eh.aggregate_id", event.AggregateID())
 	sp.SetTag("eh.version", event.Version())
 
+	if h.correlationIDKey != "" {
+		if id, ok := event.Metadata()[h.correlationIDKey]; ok {
+			sp.SetTag("eh.correlation_id", id)
+		}
+	}
+
 	sp.Finish()
 
 	return err
@@ -0,0 +1,108 @@
+// Copyright (c) 2021 - The Event Horizon authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	eh "github.com/looplab/eventhorizon"
+	"github.com/looplab/eventhorizon/mocks"
+	"github.com/looplab/eventhorizon/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestEventHandlerMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	prev := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+
+	defer opentracing.SetGlobalTracer(prev)
+
+	id := uuid.New()
+	timestamp := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	event := eh.NewEvent(mocks.EventType, &mocks.EventData{Content: "event"}, timestamp,
+		eh.ForAggregate(mocks.AggregateType, id, 3),
+		eh.WithMetadata(map[string]interface{}{"correlation_id": "abc"}))
+
+	inner := mocks.NewEventHandler("test")
+	h := eh.UseEventHandlerMiddleware(inner, NewEventHandlerMiddleware())
+
+	if err := h.HandleEvent(context.Background(), event); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	if len(inner.Events) != 1 {
+		t.Error("the event should be handled:", inner.Events)
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatal("there should be one span:", len(spans))
+	}
+
+	if spans[0].OperationName != "test.Event(Event)" {
+		t.Error("the operation name should be correct:", spans[0].OperationName)
+	}
+
+	expected := map[string]interface{}{
+		"eh.event_type":     mocks.EventType,
+		"eh.aggregate_type": mocks.AggregateType,
+		"eh.aggregate_id":   id,
+		"eh.version":        3,
+		"eh.correlation_id": "abc",
+	}
+	for k, v := range expected {
+		if tag := spans[0].Tag(k); tag != v {
+			t.Errorf("the %s tag should be correct: %v (should be %v)", k, tag, v)
+		}
+	}
+
+	// Use another metadata key, events without it have no correlation ID tag.
+	tracer.Reset()
+
+	h = eh.UseEventHandlerMiddleware(inner, NewEventHandlerMiddleware(WithCorrelationIDKey("trace_group")))
+
+	if err := h.HandleEvent(context.Background(), event); err != nil {
+		t.Error("there should be no error:", err)
+	}
+
+	spans = tracer.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatal("there should be one span:", len(spans))
+	}
+
+	if _, ok := spans[0].Tags()["eh.correlation_id"]; ok {
+		t.Error("there should be no correlation ID tag:", spans[0].Tags())
+	}
+
+	// Errors are logged on the span.
+	tracer.Reset()
+
+	handlerErr := errors.New("handler error")
+	inner.Err = handlerErr
+
+	if err := h.HandleEvent(context.Background(), event); !errors.Is(err, handlerErr) {
+		t.Error("there should be a handler error:", err)
+	}
+
+	spans = tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].Tag("error") != true {
+		t.Error("the span should have an error:", spans)
+	}
+}
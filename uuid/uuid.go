@@ -2,7 +2,12 @@
 // Forks of Event Horizon can re-implement this package with a UUID library of choice.
 package uuid
 
-import "github.com/google/uuid"
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/google/uuid"
+)
 
 // UUID is an alias type for github.com/google/uuid.UUID.
 type UUID = uuid.UUID
@@ -10,9 +15,47 @@ type UUID = uuid.UUID
 // Nil is an empty UUID.
 var Nil = UUID(uuid.Nil)
 
-// New creates a new UUID.
+var (
+	newFunc   = newRandom
+	newFuncMu sync.RWMutex
+)
+
+// New creates a new UUID, using the function set with SetNewFunc.
 func New() UUID {
-	return UUID(uuid.New())
+	newFuncMu.RLock()
+	f := newFunc
+	newFuncMu.RUnlock()
+
+	return f()
+}
+
+// SetNewFunc sets the function used to create new UUIDs, for example to create
+// deterministic UUIDs in tests. Use nil to restore the default random UUIDs.
+// The function must be safe for concurrent use.
+func SetNewFunc(f func() UUID) {
+	if f == nil {
+		f = newRandom
+	}
+
+	newFuncMu.Lock()
+	defer newFuncMu.Unlock()
+
+	newFunc = f
+}
+
+// NewSeededFunc returns a function that creates a deterministic sequence of
+// (version 4) UUIDs from a seed, to be used with SetNewFunc in tests.
+func NewSeededFunc(seed int64) func() UUID {
+	r := rand.New(rand.NewSource(seed))
+
+	var mu sync.Mutex
+
+	return func() UUID {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return UUID(uuid.Must(uuid.NewRandomFromReader(r)))
+	}
 }
 
 // Parse parses a UUID from a string, or returns an error.
@@ -26,3 +69,7 @@ func Parse(s string) (UUID, error) {
 func MustParse(s string) UUID {
 	return UUID(uuid.MustParse(s))
 }
+
+func newRandom() UUID {
+	return UUID(uuid.New())
+}
@@ -0,0 +1,65 @@
+package uuid
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSetNewFunc(t *testing.T) {
+	SetNewFunc(NewSeededFunc(42))
+	first := []UUID{New(), New(), New()}
+
+	SetNewFunc(NewSeededFunc(42))
+	defer SetNewFunc(nil)
+
+	for i, expected := range first {
+		if id := New(); id != expected {
+			t.Error("the id should be predictable:", i, id, expected)
+		}
+	}
+
+	if first[0] == first[1] {
+		t.Error("the ids should be unique:", first)
+	}
+
+	if first[0].Version() != 4 {
+		t.Error("the id should be a version 4 UUID:", first[0].Version())
+	}
+
+	// Concurrent use.
+	var wg sync.WaitGroup
+
+	ids := make(chan UUID, 100)
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			ids <- New()
+		}()
+	}
+
+	wg.Wait()
+	close(ids)
+
+	seen := map[UUID]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Error("the ids should be unique:", id)
+		}
+
+		seen[id] = true
+	}
+
+	// Restore the default.
+	SetNewFunc(nil)
+
+	SetNewFunc(NewSeededFunc(42))
+	seeded := New()
+	SetNewFunc(nil)
+
+	if id := New(); id == seeded || id == Nil {
+		t.Error("the id should be random:", id)
+	}
+}